@@ -0,0 +1,246 @@
+// Package admin runs cache maintenance jobs on the servers of a
+// memcache.Client: listing the keys with lru_crawler metadump, deleting the
+// keys matching a predicate, and reporting the usage of the slab classes and
+// the distribution of the item sizes.
+//
+//	adm := admin.New(client, servers, admin.Options{})
+//
+//	progress, err := adm.DeleteMatching(ctx, func(k meta.KeyMetadata) bool {
+//		return strings.HasPrefix(k.Key, "session:")
+//	}, memcache.DeleteStreamOptions{Rate: 10000})
+//
+// A metadump walks the whole cache of a server: it runs on a connection of
+// its own, not on the pools of the client, and takes as long as the cache is
+// large. Its order follows the LRU, so a key moved meanwhile can be listed
+// twice, or not at all.
+package admin
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"net"
+	"time"
+
+	"github.com/pior/memcache"
+	"github.com/pior/memcache/meta"
+)
+
+// Options configures an Admin.
+type Options struct {
+	// Dialer dials the connections of the metadumps. Set it to the
+	// Config.Dialer of the client, e.g. for TLS.
+	// Default: net.Dialer
+	Dialer memcache.Dialer
+
+	// Timeout bounds the dial, the request and each read of a metadump, not
+	// the whole dump.
+	// Default: 2s
+	Timeout time.Duration
+}
+
+const defaultTimeout = 2 * time.Second
+
+// Admin runs maintenance jobs on the servers of a client. It is safe for
+// concurrent use.
+type Admin struct {
+	client  *memcache.Client
+	servers memcache.Servers
+	opts    Options
+}
+
+// New returns an Admin for client and its servers, those given to
+// memcache.NewClient.
+func New(client *memcache.Client, servers memcache.Servers, opts Options) *Admin {
+	if opts.Dialer == nil {
+		opts.Dialer = &net.Dialer{}
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = defaultTimeout
+	}
+	return &Admin{client: client, servers: servers, opts: opts}
+}
+
+// Key is an item listed by a metadump, with the server it is stored on.
+type Key struct {
+	meta.KeyMetadata
+	Server string
+}
+
+// Metadump returns an iterator over the items of the servers, one server
+// after the other, listed with lru_crawler metadump for the given slab
+// classes, or all of them when no class is given.
+//
+// The iteration stops at the first error, yielded with the server failing.
+// A crawler already running on the server fails with a meta.ServerError
+// ("BUSY"). Breaking out of the loop closes the connection of the dump.
+func (a *Admin) Metadump(ctx context.Context, classes ...int) iter.Seq2[Key, error] {
+	return func(yield func(Key, error) bool) {
+		for _, addr := range a.serverList() {
+			if !a.metadump(ctx, addr, classes, yield) {
+				return
+			}
+		}
+	}
+}
+
+// metadump yields the items of a server, and reports whether the iteration
+// goes on.
+func (a *Admin) metadump(ctx context.Context, addr string, classes []int, yield func(Key, error) bool) bool {
+	fail := func(err error) bool {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			err = ctxErr
+		}
+		yield(Key{Server: addr}, fmt.Errorf("admin: metadump of %s: %w", addr, err))
+		return false
+	}
+
+	dialCtx, cancel := context.WithTimeout(ctx, a.opts.Timeout)
+	conn, err := a.opts.Dialer.DialContext(dialCtx, "tcp", addr)
+	cancel()
+	if err != nil {
+		return fail(err)
+	}
+	defer conn.Close()
+
+	// Interrupts the read in progress when ctx is done.
+	stop := context.AfterFunc(ctx, func() { _ = conn.SetDeadline(time.Now()) })
+	defer stop()
+
+	if err := conn.SetWriteDeadline(time.Now().Add(a.opts.Timeout)); err != nil {
+		return fail(err)
+	}
+	if err := meta.WriteRequest(conn, meta.Metadump(classes...)); err != nil {
+		return fail(err)
+	}
+
+	r := bufio.NewReader(&idleTimeoutReader{ctx: ctx, conn: conn, timeout: a.opts.Timeout})
+	for item, err := range meta.ReadMetadump(r) {
+		if err != nil {
+			return fail(err)
+		}
+		if !yield(Key{KeyMetadata: item, Server: addr}, nil) {
+			return false
+		}
+	}
+	return true
+}
+
+// serverList returns the servers, each once.
+func (a *Admin) serverList() []string {
+	var list []string
+	seen := make(map[string]bool)
+	for _, addr := range a.servers.List() {
+		if !seen[addr] {
+			seen[addr] = true
+			list = append(list, addr)
+		}
+	}
+	return list
+}
+
+// idleTimeoutReader renews the read deadline of conn before each read, until
+// ctx is done.
+type idleTimeoutReader struct {
+	ctx     context.Context
+	conn    net.Conn
+	timeout time.Duration
+}
+
+func (r *idleTimeoutReader) Read(b []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	if err := r.conn.SetReadDeadline(time.Now().Add(r.timeout)); err != nil {
+		return 0, err
+	}
+	return r.conn.Read(b)
+}
+
+// DeleteMatching deletes the items for which match returns true, listed by
+// a metadump of the servers, e.g. to purge the keys of a retired feature or a
+// tenant. The deletions are streamed as the keys are listed, with
+// memcache.BatchCommands.MultiDeleteStreamWithOptions and opts.
+//
+// It returns the counts of the keys processed, and the errors of the
+// metadump and of the deletions, joined.
+func (a *Admin) DeleteMatching(ctx context.Context, match func(meta.KeyMetadata) bool, opts memcache.DeleteStreamOptions) (memcache.DeleteStreamProgress, error) {
+	scanCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	keys := make(chan string, 1024)
+	scanned := make(chan error, 1)
+	go func() {
+		defer close(keys)
+		for item, err := range a.Metadump(scanCtx) {
+			if err != nil {
+				scanned <- err
+				return
+			}
+			if !match(item.KeyMetadata) {
+				continue
+			}
+			select {
+			case keys <- item.Key:
+			case <-scanCtx.Done():
+				scanned <- scanCtx.Err()
+				return
+			}
+		}
+		scanned <- nil
+	}()
+
+	progress, err := memcache.NewBatchCommands(a.client).MultiDeleteStreamWithOptions(ctx, keys, opts)
+	if ctx.Err() != nil {
+		// The deletions stopped, failing with the error of ctx.
+		cancel()
+		<-scanned
+		return progress, err
+	}
+	return progress, errors.Join(<-scanned, err)
+}
+
+// SizeHistogram is the distribution of the item sizes, as reported by
+// metadump: the key, the value and the item header.
+type SizeHistogram struct {
+	// Bounds are the upper bounds of the buckets, in bytes, increasing.
+	Bounds []int
+
+	// Counts are the numbers of items per bucket: Counts[i] counts the items
+	// larger than Bounds[i-1] up to Bounds[i], and the last one the items
+	// larger than the last bound.
+	Counts []int64
+
+	Items int64
+	Bytes int64
+}
+
+// DefaultSizeBounds are the bounds of SizeHistogram without bounds: powers of
+// two from 64 bytes to 1MB, the default maximum item size.
+var DefaultSizeBounds = []int{64, 128, 256, 512, 1 << 10, 2 << 10, 4 << 10, 8 << 10, 16 << 10, 32 << 10, 64 << 10, 128 << 10, 256 << 10, 512 << 10, 1 << 20}
+
+// SizeHistogram returns the distribution of the sizes of the items of the
+// servers, listed by a metadump, in the buckets delimited by bounds, or by
+// DefaultSizeBounds when no bound is given.
+func (a *Admin) SizeHistogram(ctx context.Context, bounds ...int) (*SizeHistogram, error) {
+	if len(bounds) == 0 {
+		bounds = DefaultSizeBounds
+	}
+	h := &SizeHistogram{Bounds: bounds, Counts: make([]int64, len(bounds)+1)}
+
+	for item, err := range a.Metadump(ctx) {
+		if err != nil {
+			return nil, err
+		}
+		i := 0
+		for i < len(bounds) && item.Size > bounds[i] {
+			i++
+		}
+		h.Counts[i]++
+		h.Items++
+		h.Bytes += int64(item.Size)
+	}
+	return h, nil
+}
@@ -0,0 +1,93 @@
+package admin
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/pior/memcache"
+	"github.com/pior/memcache/memcachetest"
+	"github.com/pior/memcache/meta"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestAdmin(t *testing.T, items ...memcache.Item) (*Admin, *memcache.Client) {
+	srv := memcachetest.NewServer(t)
+	servers := memcache.StaticServers(srv.Addr)
+	client := memcache.NewClient(servers, memcache.Config{})
+	t.Cleanup(client.Close)
+
+	for _, item := range items {
+		require.NoError(t, client.Set(context.Background(), item))
+	}
+	return New(client, servers, Options{}), client
+}
+
+func TestAdmin_Metadump(t *testing.T) {
+	adm, _ := newTestAdmin(t,
+		memcache.Item{Key: "a", Value: []byte("1")},
+		memcache.Item{Key: "b", Value: []byte("22")},
+	)
+
+	var keys []Key
+	for key, err := range adm.Metadump(context.Background()) {
+		require.NoError(t, err)
+		keys = append(keys, key)
+	}
+
+	require.Len(t, keys, 2)
+	assert.Equal(t, "a", keys[0].Key)
+	assert.Equal(t, 50, keys[0].Size)
+	assert.Equal(t, "b", keys[1].Key)
+	assert.Equal(t, adm.servers.List()[0], keys[1].Server)
+}
+
+func TestAdmin_Metadump_DialError(t *testing.T) {
+	client := memcache.NewClient(memcache.StaticServers("127.0.0.1:1"), memcache.Config{})
+	t.Cleanup(client.Close)
+	adm := New(client, memcache.StaticServers("127.0.0.1:1"), Options{})
+
+	for key, err := range adm.Metadump(context.Background()) {
+		require.ErrorContains(t, err, "admin: metadump of 127.0.0.1:1: ")
+		assert.Equal(t, "127.0.0.1:1", key.Server)
+	}
+}
+
+func TestAdmin_DeleteMatching(t *testing.T) {
+	adm, client := newTestAdmin(t,
+		memcache.Item{Key: "session:1", Value: []byte("x")},
+		memcache.Item{Key: "session:2", Value: []byte("x")},
+		memcache.Item{Key: "user:1", Value: []byte("x")},
+	)
+	ctx := context.Background()
+
+	progress, err := adm.DeleteMatching(ctx, func(k meta.KeyMetadata) bool {
+		return strings.HasPrefix(k.Key, "session:")
+	}, memcache.DeleteStreamOptions{})
+
+	require.NoError(t, err)
+	assert.Equal(t, memcache.DeleteStreamProgress{Deleted: 2}, progress)
+
+	for key, found := range map[string]bool{"session:1": false, "session:2": false, "user:1": true} {
+		exists, err := client.Exists(ctx, key)
+		require.NoError(t, err)
+		assert.Equal(t, found, exists, key)
+	}
+}
+
+func TestAdmin_SizeHistogram(t *testing.T) {
+	adm, _ := newTestAdmin(t,
+		memcache.Item{Key: "a", Value: []byte("1")},        // 50 bytes
+		memcache.Item{Key: "b", Value: make([]byte, 100)},  // 149 bytes
+		memcache.Item{Key: "c", Value: make([]byte, 1000)}, // 1049 bytes
+		memcache.Item{Key: "d", Value: make([]byte, 2000)}, // 2049 bytes
+	)
+
+	h, err := adm.SizeHistogram(context.Background(), 64, 1024)
+
+	require.NoError(t, err)
+	assert.Equal(t, []int64{1, 1, 2}, h.Counts)
+	assert.Equal(t, int64(4), h.Items)
+	assert.Equal(t, int64(50+149+1049+2049), h.Bytes)
+}
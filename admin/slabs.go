@@ -0,0 +1,109 @@
+package admin
+
+import (
+	"context"
+	"slices"
+	"strconv"
+
+	"github.com/pior/memcache/meta"
+)
+
+// SlabClass is the usage of a slab class of a server, from "stats slabs" and
+// "stats items".
+type SlabClass struct {
+	Class      int
+	ChunkSize  int // bytes
+	TotalPages int // pages of memory assigned to the class
+	UsedChunks int64
+	FreeChunks int64
+
+	Items       int64 // items stored
+	Evicted     int64 // items evicted to store new ones
+	OutOfMemory int64 // stores failing for lack of memory
+}
+
+// ServerSlabs is the usage of the slab classes of a server.
+type ServerSlabs struct {
+	Addr    string
+	Classes []SlabClass // by class id
+	Error   error       // if the stats request failed
+}
+
+// SlabStats returns the usage of the slab classes of each server of the
+// client, e.g. to find a class starved of memory, evicting while others have
+// free chunks. As with memcache.Client.Stats, the errors of the servers are
+// returned in ServerSlabs.Error.
+func (a *Admin) SlabStats(ctx context.Context) ([]ServerSlabs, error) {
+	slabs, err := a.client.Stats(ctx, "slabs")
+	if err != nil {
+		return nil, err
+	}
+	items, err := a.client.Stats(ctx, "items")
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]ServerSlabs, len(slabs))
+	for i, s := range slabs {
+		results[i] = ServerSlabs{Addr: s.Addr, Error: s.Error}
+		if s.Error != nil {
+			continue
+		}
+		var itemStats map[string]string
+		for _, it := range items {
+			if it.Addr == s.Addr {
+				itemStats, results[i].Error = it.Stats, it.Error
+			}
+		}
+		results[i].Classes = slabClasses(s.Stats, itemStats)
+	}
+	return results, nil
+}
+
+// slabClasses merges the per class stats of "stats slabs" and "stats items".
+func slabClasses(slabs, items map[string]string) []SlabClass {
+	slabsByClass := byClass(slabs)
+	itemsByClass := byClass(items)
+
+	var ids []int
+	for id := range slabsByClass {
+		ids = append(ids, id)
+	}
+	for id := range itemsByClass {
+		if _, ok := slabsByClass[id]; !ok {
+			ids = append(ids, id)
+		}
+	}
+	slices.Sort(ids)
+
+	classes := make([]SlabClass, len(ids))
+	for i, id := range ids {
+		s, it := slabsByClass[id], itemsByClass[id]
+		classes[i] = SlabClass{
+			Class:       id,
+			ChunkSize:   int(parseInt(s["chunk_size"])),
+			TotalPages:  int(parseInt(s["total_pages"])),
+			UsedChunks:  parseInt(s["used_chunks"]),
+			FreeChunks:  parseInt(s["free_chunks"]),
+			Items:       parseInt(it["number"]),
+			Evicted:     parseInt(it["evicted"]),
+			OutOfMemory: parseInt(it["outofmemory"]),
+		}
+	}
+	return classes
+}
+
+// byClass groups the per class stats by class id, as meta.Stats.ByClass.
+func byClass(stats map[string]string) map[int]map[string]string {
+	lines := make(meta.Stats, 0, len(stats))
+	for name, value := range stats {
+		lines = append(lines, meta.Stat{Name: name, Value: value})
+	}
+	return lines.ByClass()
+}
+
+// parseInt parses a stat, missing or invalid stats being zero.
+func parseInt(s string) int64 {
+	n, _ := strconv.ParseInt(s, 10, 64)
+	return n
+}
@@ -0,0 +1,96 @@
+package admin
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"testing"
+
+	"github.com/pior/memcache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// serveStats starts a server answering the stats requests with responses, by
+// request line.
+func serveStats(t *testing.T, responses map[string]string) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				for {
+					line, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					resp, ok := responses[line]
+					if !ok {
+						resp = "ERROR\r\n"
+					}
+					if _, err := conn.Write([]byte(resp)); err != nil {
+						return
+					}
+				}
+			}()
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func TestAdmin_SlabStats(t *testing.T) {
+	addr := serveStats(t, map[string]string{
+		"stats slabs\r\n": "STAT 1:chunk_size 96\r\n" +
+			"STAT 1:total_pages 1\r\n" +
+			"STAT 1:used_chunks 10\r\n" +
+			"STAT 1:free_chunks 10912\r\n" +
+			"STAT 12:chunk_size 944\r\n" +
+			"STAT 12:total_pages 3\r\n" +
+			"STAT 12:used_chunks 3330\r\n" +
+			"STAT 12:free_chunks 0\r\n" +
+			"STAT active_slabs 2\r\n" +
+			"STAT total_malloced 4194304\r\n" +
+			"END\r\n",
+		"stats items\r\n": "STAT items:1:number 10\r\n" +
+			"STAT items:12:number 3330\r\n" +
+			"STAT items:12:evicted 42\r\n" +
+			"STAT items:12:outofmemory 1\r\n" +
+			"END\r\n",
+	})
+	servers := memcache.StaticServers(addr)
+	client := memcache.NewClient(servers, memcache.Config{})
+	t.Cleanup(client.Close)
+
+	stats, err := New(client, servers, Options{}).SlabStats(context.Background())
+
+	require.NoError(t, err)
+	require.Len(t, stats, 1)
+	require.NoError(t, stats[0].Error)
+	assert.Equal(t, addr, stats[0].Addr)
+	assert.Equal(t, []SlabClass{
+		{Class: 1, ChunkSize: 96, TotalPages: 1, UsedChunks: 10, FreeChunks: 10912, Items: 10},
+		{Class: 12, ChunkSize: 944, TotalPages: 3, UsedChunks: 3330, Items: 3330, Evicted: 42, OutOfMemory: 1},
+	}, stats[0].Classes)
+}
+
+func TestAdmin_SlabStats_ServerError(t *testing.T) {
+	addr := serveStats(t, nil)
+	servers := memcache.StaticServers(addr)
+	client := memcache.NewClient(servers, memcache.Config{})
+	t.Cleanup(client.Close)
+
+	stats, err := New(client, servers, Options{}).SlabStats(context.Background())
+
+	require.NoError(t, err)
+	require.Len(t, stats, 1)
+	assert.Error(t, stats[0].Error)
+	assert.Empty(t, stats[0].Classes)
+}
@@ -0,0 +1,48 @@
+//go:build !race
+
+package memcache
+
+import (
+	"fmt"
+	"testing"
+)
+
+// allocBudgets are the maximum allocations per operation of sizedOps, at any
+// value size. They hold a margin over the current counts: a test failing
+// here is a regression of the serialization or of the buffer pooling, to fix
+// or to account for by raising the budget, with the benchmark results
+// (BenchmarkClientValueSizes) in the commit message.
+var allocBudgets = map[string]float64{
+	"Get":             8,
+	"Set":             4,
+	"MultiGet_10keys": 96,
+}
+
+// TestClient_AllocBudgets fails when an operation allocates beyond its budget.
+// It is skipped with the race detector, which allocates on its own.
+func TestClient_AllocBudgets(t *testing.T) {
+	for _, op := range sizedOps {
+		budget, ok := allocBudgets[op.name]
+		if !ok {
+			t.Fatalf("no allocation budget for %s", op.name)
+		}
+
+		for _, size := range benchValueSizes {
+			t.Run(fmt.Sprintf("%s_%dB", op.name, size), func(t *testing.T) {
+				do := op.setup(t, size)
+				if err := do(); err != nil { // dials, and fills the buffer pools
+					t.Fatal(err)
+				}
+
+				allocs := testing.AllocsPerRun(100, func() {
+					if err := do(); err != nil {
+						t.Fatal(err)
+					}
+				})
+				if allocs > budget {
+					t.Errorf("%s of %d bytes allocated %v times per run, budget %v", op.name, size, allocs, budget)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,268 @@
+package memcache
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pior/memcache/meta"
+)
+
+// maxAsyncBatch bounds the number of async writes sent in a batch.
+const maxAsyncBatch = 100
+
+// asyncFlushTimeout bounds the flush of a batch of async writes without
+// Config.BatchTimeout or Timeout: a hung server must not block the pump, and
+// Close, forever.
+const asyncFlushTimeout = 5 * time.Second
+
+// asyncCloseTimeout bounds the time Close waits for the queued async writes
+// to be sent.
+const asyncCloseTimeout = 10 * time.Second
+
+// asyncWrite is a write queued by SetAsync or DeleteAsync.
+type asyncWrite struct {
+	op  string // "set", "delete" or "add" (backfill), as in StatusError.Op
+	req *meta.Request
+}
+
+// asyncWriter queues the async writes for the pump, a background goroutine
+// sending them in batches.
+type asyncWriter struct {
+	mu     sync.RWMutex // held for writing to close the queue
+	closed bool
+	queue  chan asyncWrite
+
+	start sync.Once     // starts the pump on the first async write
+	done  chan struct{} // closed when the pump returned
+
+	flushTimeout time.Duration // asyncFlushTimeout
+	closeTimeout time.Duration // asyncCloseTimeout
+
+	// ctx is the parent of the flushes, canceled when Close stops waiting.
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// SetAsync queues a set and returns without waiting for the server: for
+// write-behind caching, where latency matters more than confirmation. The
+// write is sent by a background goroutine, batched with the other async
+// writes, with the quiet flag so that only its failure gets a response.
+//
+// SetAsync fails only when the write can't be queued: a value larger than
+// Config.MaxItemSize, an invalid key, a full queue (ErrAsyncQueueFull) or a
+// closed client. The failures of the queued writes are reported to
+// Config.AsyncErrorHandler.
+//
+// The write is not bound to ctx: it is sent even if ctx is canceled after
+// SetAsync returned. Close sends the queued writes before closing the pools,
+// waiting for them for up to 10s; each batch is bounded by
+// Config.BatchTimeout or Timeout, or 5s without them.
+func (c *Client) SetAsync(ctx context.Context, item Item) error {
+	req := meta.NewRequest(meta.CmdSet, item.Key, item.Value)
+	if exptime := item.TTL.Expiration(); exptime != 0 {
+		req.AddTTL(exptime)
+	}
+	if item.Flags != 0 {
+		req.AddClientFlags(item.Flags)
+	}
+	if err := c.checkItemSize(req); err != nil {
+		return err
+	}
+	return c.enqueueAsync(ctx, asyncWrite{op: "set", req: req})
+}
+
+// DeleteAsync queues a delete and returns without waiting for the server,
+// like SetAsync. Deleting a missing key is not a failure.
+func (c *Client) DeleteAsync(ctx context.Context, key string) error {
+	return c.enqueueAsync(ctx, asyncWrite{op: "delete", req: meta.NewRequest(meta.CmdDelete, key, nil)})
+}
+
+func (c *Client) enqueueAsync(ctx context.Context, w asyncWrite) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	// An invalid key would fail the whole batch of its server.
+	if err := meta.ValidateKey(w.req.Key, false); err != nil {
+		return err
+	}
+
+	c.async.mu.RLock()
+	defer c.async.mu.RUnlock()
+	if c.async.closed {
+		return ErrClientClosed
+	}
+	c.async.start.Do(func() { go c.asyncPump() })
+
+	select {
+	case c.async.queue <- w:
+		return nil
+	default:
+		return ErrAsyncQueueFull
+	}
+}
+
+// closeAsync stops accepting async writes and waits for the pump to send
+// the queued ones, for up to closeTimeout. The writes left then fail with
+// context.Canceled, once the flush in progress ended (at its timeout at the
+// latest).
+func (c *Client) closeAsync() {
+	c.async.mu.Lock()
+	c.async.closed = true
+	c.async.mu.Unlock()
+
+	// Without an async write, the pump never started: nothing to wait for.
+	c.async.start.Do(func() { close(c.async.done) })
+
+	timer := time.NewTimer(c.async.closeTimeout)
+	defer timer.Stop()
+	select {
+	case <-c.async.done:
+	case <-timer.C:
+		c.async.cancel()
+		<-c.async.done
+	}
+	c.async.cancel()
+}
+
+// asyncPump sends the queued writes until the client is closed, then sends
+// the writes left in the queue.
+func (c *Client) asyncPump() {
+	defer close(c.async.done)
+
+	for {
+		select {
+		case w := <-c.async.queue:
+			c.flushAsync(c.collectAsync(w))
+		case <-c.stopBackground:
+			for {
+				select {
+				case w := <-c.async.queue:
+					c.flushAsync(c.collectAsync(w))
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// collectAsync returns a batch of the first write and the writes already
+// queued behind it.
+func (c *Client) collectAsync(first asyncWrite) []asyncWrite {
+	writes := []asyncWrite{first}
+	for len(writes) < maxAsyncBatch {
+		select {
+		case w := <-c.async.queue:
+			writes = append(writes, w)
+		default:
+			return writes
+		}
+	}
+	return writes
+}
+
+// flushAsync sends a batch of writes, concurrently to their servers.
+func (c *Client) flushAsync(writes []asyncWrite) {
+	byServer := make(map[string][]asyncWrite)
+	for _, w := range writes {
+		addr, err := c.selectServerForKey(w.req.Key)
+		if err != nil {
+			c.asyncFailed(w.req.Key, err)
+			continue
+		}
+		byServer[addr] = append(byServer[addr], w)
+	}
+
+	var wg sync.WaitGroup
+	for addr, writes := range byServer {
+		wg.Go(func() { c.flushAsyncServer(addr, writes) })
+	}
+	wg.Wait()
+}
+
+// flushAsyncServer sends the writes of a server. With the quiet flag, only
+// the failures get a response, matched to their write by an opaque token.
+// The pipelined mode doesn't support the quiet flag: every write gets a
+// response, matched by position.
+func (c *Client) flushAsyncServer(addr string, writes []asyncWrite) {
+	sp, err := c.getPoolForServer(addr)
+	if err != nil {
+		for _, w := range writes {
+			c.asyncFailed(w.req.Key, err)
+		}
+		return
+	}
+
+	quiet := sp.pipelines == nil
+	reqs := make([]*meta.Request, len(writes))
+	for i, w := range writes {
+		reqs[i] = w.req
+		if quiet {
+			w.req.AddQuiet().AddOpaque(strconv.Itoa(i))
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(c.async.ctx, c.asyncTimeout())
+	defer cancel()
+	responses, err := sp.ExecuteBatch(ctx, reqs)
+	if err != nil {
+		for _, w := range writes {
+			c.asyncFailed(w.req.Key, err)
+		}
+		return
+	}
+
+	for i, resp := range responses {
+		if quiet {
+			i = -1
+			if token, ok := resp.Opaque(); ok {
+				i, _ = strconv.Atoi(string(token))
+			}
+		}
+		if i < 0 || i >= len(writes) {
+			// A protocol error response carries no opaque token.
+			if resp.HasError() {
+				c.asyncFailed("", resp.Error)
+			}
+			continue
+		}
+
+		w := writes[i]
+		switch {
+		case resp.HasError():
+			c.asyncFailed(w.req.Key, resp.Error)
+		case resp.Status == meta.StatusHD:
+		case resp.Status == meta.StatusNF && w.op == "delete":
+		case resp.Status == meta.StatusNS && w.op == "add": // the item exists
+		default:
+			c.asyncFailed(w.req.Key, &StatusError{Op: w.op, Key: w.req.Key, Status: resp.Status})
+		}
+	}
+}
+
+// asyncTimeout returns the timeout of the flush of a batch: BatchTimeout,
+// Timeout, or flushTimeout without them.
+func (c *Client) asyncTimeout() time.Duration {
+	switch {
+	case c.config.BatchTimeout > 0:
+		return c.config.BatchTimeout
+	case c.config.Timeout > 0:
+		return c.config.Timeout
+	default:
+		return c.async.flushTimeout
+	}
+}
+
+// asyncFailed reports the failure of an async write.
+func (c *Client) asyncFailed(key string, err error) {
+	if c.config.AsyncErrorHandler != nil {
+		c.config.AsyncErrorHandler(key, err)
+		return
+	}
+	if c.config.Logger != nil {
+		c.config.Logger.LogAttrs(context.Background(), slog.LevelWarn, "memcache: async write failed", slog.Any("error", err))
+	}
+}
@@ -0,0 +1,167 @@
+package memcache
+
+import (
+	"context"
+	"net"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pior/memcache/internal/testutils"
+	"github.com/pior/memcache/meta"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// asyncFailure is a failure reported to Config.AsyncErrorHandler.
+type asyncFailure struct {
+	key string
+	err error
+}
+
+func newAsyncTestClient(t *testing.T, config Config, dialer Dialer) (*Client, *[]asyncFailure) {
+	var mu sync.Mutex
+	failures := &[]asyncFailure{}
+	config.AsyncErrorHandler = func(key string, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		*failures = append(*failures, asyncFailure{key, err})
+	}
+	config.Dialer = dialer
+	client := NewClient(StaticServers("localhost:11211"), config)
+	t.Cleanup(client.Close)
+	return client, failures
+}
+
+func TestClient_SetAsync(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("quiet writes", func(t *testing.T) {
+		mock := testutils.NewConnectionMock("MN\r\n")
+		client, failures := newAsyncTestClient(t, Config{}, &mockDialer{conn: mock})
+
+		require.NoError(t, client.SetAsync(ctx, Item{Key: "k1", Value: []byte("v1"), TTL: ExpiresIn(time.Minute)}))
+		client.Close() // sends the queued write
+
+		assert.Equal(t, "ms k1 2 T60 q O0\r\nv1\r\nmn\r\n", mock.GetWrittenRequest())
+		assert.Empty(t, *failures)
+	})
+
+	t.Run("failures are reported", func(t *testing.T) {
+		mock := testutils.NewConnectionMock("NS O1\r\nSERVER_ERROR out of memory\r\nMN\r\n")
+		client, failures := newAsyncTestClient(t, Config{}, &mockDialer{conn: mock})
+
+		// Queued before the pump reads them: a single batch.
+		client.async.start.Do(func() {})
+		require.NoError(t, client.SetAsync(ctx, Item{Key: "k1", Value: []byte("v1")}))
+		require.NoError(t, client.SetAsync(ctx, Item{Key: "k2", Value: []byte("v2")}))
+		require.NoError(t, client.DeleteAsync(ctx, "k3"))
+		go client.asyncPump()
+		client.Close()
+
+		assert.Equal(t, "ms k1 2 q O0\r\nv1\r\nms k2 2 q O1\r\nv2\r\nmd k3 q O2\r\nmn\r\n", mock.GetWrittenRequest())
+		require.Len(t, *failures, 2)
+		assert.Equal(t, "k2", (*failures)[0].key)
+		assert.Equal(t, &StatusError{Op: "set", Key: "k2", Status: meta.StatusNS}, (*failures)[0].err)
+		assert.Empty(t, (*failures)[1].key, "a protocol error has no opaque token")
+		assert.Error(t, (*failures)[1].err)
+	})
+
+	t.Run("connection failure", func(t *testing.T) {
+		dialErr := &net.OpError{Op: "dial", Err: assert.AnError}
+		client, failures := newAsyncTestClient(t, Config{}, &mockDialer{error: dialErr})
+
+		require.NoError(t, client.SetAsync(ctx, Item{Key: "k1", Value: []byte("v1")}))
+		require.NoError(t, client.DeleteAsync(ctx, "k2"))
+		client.Close()
+
+		require.Len(t, *failures, 2)
+		assert.ErrorIs(t, (*failures)[0].err, assert.AnError)
+		assert.ElementsMatch(t, []string{"k1", "k2"}, []string{(*failures)[0].key, (*failures)[1].key})
+	})
+
+	t.Run("rejected writes", func(t *testing.T) {
+		mock := testutils.NewConnectionMock()
+		client, _ := newAsyncTestClient(t, Config{MaxItemSize: 4}, &mockDialer{conn: mock})
+
+		assert.ErrorIs(t, client.SetAsync(ctx, Item{Key: "k1", Value: []byte("too large")}), ErrValueTooLarge)
+		var keyErr *meta.InvalidKeyError
+		assert.ErrorAs(t, client.DeleteAsync(ctx, "bad key"), &keyErr)
+
+		canceled, cancel := context.WithCancel(ctx)
+		cancel()
+		assert.ErrorIs(t, client.DeleteAsync(canceled, "k1"), context.Canceled)
+
+		client.Close()
+		assert.ErrorIs(t, client.DeleteAsync(ctx, "k1"), ErrClientClosed)
+		assert.Empty(t, mock.GetWrittenRequest())
+	})
+
+	t.Run("queue full", func(t *testing.T) {
+		mock := testutils.NewConnectionMock("MN\r\n")
+		client, _ := newAsyncTestClient(t, Config{AsyncQueueSize: 2}, &mockDialer{conn: mock})
+
+		// The pump is not running: the writes stay in the queue.
+		client.async.start.Do(func() {})
+		require.NoError(t, client.DeleteAsync(ctx, "k1"))
+		require.NoError(t, client.DeleteAsync(ctx, "k2"))
+		assert.ErrorIs(t, client.DeleteAsync(ctx, "k3"), ErrAsyncQueueFull)
+
+		go client.asyncPump()
+		client.Close()
+		assert.Equal(t, "md k1 q O0\r\nmd k2 q O1\r\nmn\r\n", mock.GetWrittenRequest())
+	})
+}
+
+func TestClient_SetAsync_HungServer(t *testing.T) {
+	ctx := context.Background()
+	addr := newHungServer(t)
+
+	t.Run("flushes are bounded without Timeout", func(t *testing.T) {
+		var failed sync.WaitGroup
+		failed.Add(1)
+		client := NewClient(StaticServers(addr), Config{
+			AsyncErrorHandler: func(key string, err error) {
+				assert.Equal(t, "k1", key)
+				assert.ErrorIs(t, err, os.ErrDeadlineExceeded)
+				failed.Done()
+			},
+		})
+		t.Cleanup(client.Close)
+		client.async.flushTimeout = 20 * time.Millisecond
+
+		require.NoError(t, client.SetAsync(ctx, Item{Key: "k1", Value: []byte("v1")}))
+		failed.Wait()
+	})
+
+	t.Run("close is bounded", func(t *testing.T) {
+		var mu sync.Mutex
+		failures := map[string]error{}
+		client := NewClient(StaticServers(addr), Config{
+			AsyncErrorHandler: func(key string, err error) {
+				mu.Lock()
+				defer mu.Unlock()
+				failures[key] = err
+			},
+		})
+		client.async.flushTimeout = 100 * time.Millisecond
+		client.async.closeTimeout = 20 * time.Millisecond
+
+		require.NoError(t, client.SetAsync(ctx, Item{Key: "k1", Value: []byte("v1")}))
+		require.Eventually(t, func() bool {
+			sp, err := client.getPoolForServer(addr)
+			return err == nil && sp.pending.Load() == 1
+		}, time.Second, time.Millisecond)
+		require.NoError(t, client.SetAsync(ctx, Item{Key: "k2", Value: []byte("v2")}))
+
+		// The flush in flight ends at its deadline, the writes queued behind
+		// it are abandoned.
+		client.Close()
+
+		mu.Lock()
+		defer mu.Unlock()
+		assert.ErrorIs(t, failures["k1"], os.ErrDeadlineExceeded)
+		assert.ErrorIs(t, failures["k2"], context.Canceled)
+	})
+}
@@ -2,6 +2,7 @@ package memcache
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"github.com/pior/memcache/meta"
@@ -24,7 +25,11 @@ func NewBatchCommands(executor BatchExecutor) *BatchCommands {
 
 // MultiGet retrieves multiple items in a single batch operation.
 // Returns items in the same order as the keys, with Found=false for missing items.
-func (b *BatchCommands) MultiGet(ctx context.Context, keys []string) ([]Item, error) {
+//
+// When the keys span several servers and only some of them fail, MultiGet
+// returns the items of the healthy servers along with a *PartialError: the
+// keys of the failed servers are returned with Found=false.
+func (b *BatchCommands) MultiGet(ctx context.Context, keys []string, opts ...CallOption) ([]Item, error) {
 	if len(keys) == 0 {
 		return nil, nil
 	}
@@ -32,12 +37,14 @@ func (b *BatchCommands) MultiGet(ctx context.Context, keys []string) ([]Item, er
 	// Build batch requests
 	reqs := make([]*meta.Request, len(keys))
 	for i, key := range keys {
-		reqs[i] = meta.NewRequest(meta.CmdGet, key, nil).AddReturnValue()
+		reqs[i] = meta.NewRequest(meta.CmdGet, key, nil).AddReturnValue().AddReturnClientFlags()
 	}
 
 	// Execute batch
+	ctx = applyCallOptions(ctx, reqs, opts)
 	responses, err := b.executor.ExecuteBatch(ctx, reqs)
-	if err != nil {
+	var partial *PartialError
+	if err != nil && !errors.As(err, &partial) {
 		return nil, err
 	}
 	if len(responses) != len(keys) {
@@ -49,6 +56,10 @@ func (b *BatchCommands) MultiGet(ctx context.Context, keys []string) ([]Item, er
 	for i, resp := range responses {
 		key := keys[i]
 
+		if resp == nil { // its server failed: reported by the partial error
+			items[i] = Item{Key: key, Found: false}
+			continue
+		}
 		if resp.HasError() {
 			return nil, resp.Error
 		}
@@ -61,17 +72,24 @@ func (b *BatchCommands) MultiGet(ctx context.Context, keys []string) ([]Item, er
 				Value: resp.Data,
 				Found: true,
 			}
+			items[i].Flags, _ = resp.ClientFlags()
 		} else {
-			return nil, fmt.Errorf("unexpected response status for key %s: %s", key, resp.Status)
+			return nil, &StatusError{Op: "get", Key: key, Status: resp.Status}
 		}
 	}
 
+	if partial != nil {
+		return items, partial
+	}
 	return items, nil
 }
 
 // MultiSet stores multiple items in a single batch operation.
 // Returns error on first failure.
-func (b *BatchCommands) MultiSet(ctx context.Context, items []Item) error {
+//
+// When the items span several servers and only some of them fail, the error
+// is a *PartialError: the items of the healthy servers were stored.
+func (b *BatchCommands) MultiSet(ctx context.Context, items []Item, opts ...CallOption) error {
 	if len(items) == 0 {
 		return nil
 	}
@@ -83,10 +101,14 @@ func (b *BatchCommands) MultiSet(ctx context.Context, items []Item) error {
 		if exptime := item.TTL.Expiration(); exptime != 0 {
 			req.AddTTL(exptime)
 		}
+		if item.Flags != 0 {
+			req.AddClientFlags(item.Flags)
+		}
 		reqs[i] = req
 	}
 
 	// Execute batch
+	ctx = applyCallOptions(ctx, reqs, opts)
 	responses, err := b.executor.ExecuteBatch(ctx, reqs)
 	if err != nil {
 		return err
@@ -102,7 +124,7 @@ func (b *BatchCommands) MultiSet(ctx context.Context, items []Item) error {
 		}
 
 		if !resp.IsSuccess() {
-			return fmt.Errorf("set failed for key %s with status: %s", items[i].Key, resp.Status)
+			return &StatusError{Op: "set", Key: items[i].Key, Status: resp.Status}
 		}
 	}
 
@@ -111,7 +133,10 @@ func (b *BatchCommands) MultiSet(ctx context.Context, items []Item) error {
 
 // MultiDelete removes multiple items in a single batch operation.
 // Returns error on first failure.
-func (b *BatchCommands) MultiDelete(ctx context.Context, keys []string) error {
+//
+// When the keys span several servers and only some of them fail, the error is
+// a *PartialError: the keys of the healthy servers were deleted.
+func (b *BatchCommands) MultiDelete(ctx context.Context, keys []string, opts ...CallOption) error {
 	if len(keys) == 0 {
 		return nil
 	}
@@ -123,6 +148,7 @@ func (b *BatchCommands) MultiDelete(ctx context.Context, keys []string) error {
 	}
 
 	// Execute batch
+	ctx = applyCallOptions(ctx, reqs, opts)
 	responses, err := b.executor.ExecuteBatch(ctx, reqs)
 	if err != nil {
 		return err
@@ -139,7 +165,7 @@ func (b *BatchCommands) MultiDelete(ctx context.Context, keys []string) error {
 
 		// Delete is successful even if key doesn't exist
 		if resp.Status != meta.StatusHD && resp.Status != meta.StatusNF {
-			return fmt.Errorf("delete failed for key %s with status: %s", keys[i], resp.Status)
+			return &StatusError{Op: "delete", Key: keys[i], Status: resp.Status}
 		}
 	}
 
@@ -2,6 +2,10 @@ package memcache
 
 import (
 	"context"
+	"errors"
+	"net"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -20,19 +24,20 @@ func newBatchTestClient(t *testing.T, responses ...string) (*BatchCommands, *tes
 
 func TestBatchCommands_MultiGet(t *testing.T) {
 	t.Run("hits and misses in order", func(t *testing.T) {
-		bc, mock := newBatchTestClient(t, "VA 2\r\nv1\r\n", "EN\r\n", "VA 2\r\nv3\r\n", "MN\r\n")
+		bc, mock := newBatchTestClient(t, "VA 2 f7\r\nv1\r\n", "EN\r\n", "VA 2\r\nv3\r\n", "MN\r\n")
 
 		items, err := bc.MultiGet(context.Background(), []string{"k1", "k2", "k3"})
 		require.NoError(t, err)
 		require.Len(t, items, 3)
 
 		assert.Equal(t, "v1", string(items[0].Value))
+		assert.Equal(t, uint32(7), items[0].Flags)
 		assert.True(t, items[0].Found)
 		assert.False(t, items[1].Found)
 		assert.Equal(t, "k2", items[1].Key)
 		assert.Equal(t, "v3", string(items[2].Value))
 
-		assert.Equal(t, "mg k1 v\r\nmg k2 v\r\nmg k3 v\r\nmn\r\n", mock.GetWrittenRequest())
+		assert.Equal(t, "mg k1 v f\r\nmg k2 v f\r\nmg k3 v f\r\nmn\r\n", mock.GetWrittenRequest())
 	})
 
 	t.Run("empty keys", func(t *testing.T) {
@@ -49,6 +54,65 @@ func TestBatchCommands_MultiGet(t *testing.T) {
 		var serverErr *meta.ServerError
 		require.ErrorAs(t, err, &serverErr)
 	})
+
+	t.Run("partial failure keeps the items of the healthy servers", func(t *testing.T) {
+		client, mock := newPartialTestClient(t, "VA 2\r\nv1\r\n", "EN\r\n", "MN\r\n")
+
+		items, err := NewBatchCommands(client).MultiGet(context.Background(), []string{"good1", "bad1", "good2", "bad2"})
+
+		var partial *PartialError
+		require.ErrorAs(t, err, &partial)
+		require.ErrorIs(t, err, errPartialDial)
+		require.Len(t, partial.Failures, 1)
+		assert.Equal(t, "bad:11211", partial.Failures[0].Server)
+		assert.Equal(t, []int{1, 3}, partial.Failures[0].Indices)
+		assert.Equal(t, []bool{false, true, false, true},
+			[]bool{partial.Failed(0), partial.Failed(1), partial.Failed(2), partial.Failed(3)})
+		assert.ErrorContains(t, err, "batch failed on 1 server(s): bad:11211: ")
+
+		require.Len(t, items, 4)
+		assert.Equal(t, Item{Key: "good1", Value: []byte("v1"), Found: true}, items[0])
+		assert.Equal(t, Item{Key: "bad1"}, items[1])
+		assert.Equal(t, Item{Key: "good2"}, items[2])
+		assert.Equal(t, Item{Key: "bad2"}, items[3])
+		assert.Equal(t, "mg good1 v f\r\nmg good2 v f\r\nmn\r\n", mock.GetWrittenRequest())
+	})
+
+	t.Run("all servers failing is not a partial failure", func(t *testing.T) {
+		client, _ := newPartialTestClient(t)
+
+		items, err := NewBatchCommands(client).MultiGet(context.Background(), []string{"bad1", "bad2"})
+
+		require.ErrorIs(t, err, errPartialDial)
+		var partial *PartialError
+		assert.False(t, errors.As(err, &partial))
+		assert.Nil(t, items)
+	})
+}
+
+var errPartialDial = errors.New("connection refused")
+
+// newPartialTestClient creates a client on two servers: the keys starting
+// with "bad" go to a server that can't be dialed, the others to a mock
+// connection.
+func newPartialTestClient(t *testing.T, responses ...string) (*Client, *testutils.ConnectionMock) {
+	mock := testutils.NewConnectionMock(responses...)
+	client := NewClient(StaticServers("good:11211", "bad:11211"), Config{
+		ServerSelector: func(key string, serverCount int) int {
+			if strings.HasPrefix(key, "bad") {
+				return 1
+			}
+			return 0
+		},
+		Dialer: DialerFunc(func(ctx context.Context, network, address string) (net.Conn, error) {
+			if address == "bad:11211" {
+				return nil, errPartialDial
+			}
+			return mock, nil
+		}),
+	})
+	t.Cleanup(client.Close)
+	return client, mock
 }
 
 func TestBatchCommands_MultiSet(t *testing.T) {
@@ -57,10 +121,10 @@ func TestBatchCommands_MultiSet(t *testing.T) {
 
 		items := []Item{
 			{Key: "k1", Value: []byte("v1"), TTL: ExpiresIn(time.Minute)},
-			{Key: "k2", Value: []byte("v2")},
+			{Key: "k2", Value: []byte("v2"), Flags: 3},
 		}
 		require.NoError(t, bc.MultiSet(context.Background(), items))
-		assert.Equal(t, "ms k1 2 T60\r\nv1\r\nms k2 2\r\nv2\r\nmn\r\n", mock.GetWrittenRequest())
+		assert.Equal(t, "ms k1 2 T60\r\nv1\r\nms k2 2 F3\r\nv2\r\nmn\r\n", mock.GetWrittenRequest())
 	})
 
 	t.Run("not stored fails with key in error", func(t *testing.T) {
@@ -102,3 +166,82 @@ func TestBatchCommands_MultiDelete(t *testing.T) {
 		require.NoError(t, bc.MultiDelete(context.Background(), nil))
 	})
 }
+
+func TestBatchCommands_MaxConcurrency(t *testing.T) {
+	var inflight, peak, dials atomic.Int32
+	client := NewClient(StaticServers("s0:11211", "s1:11211", "s2:11211", "s3:11211"), Config{
+		ServerSelector: func(key string, serverCount int) int {
+			return int(key[len(key)-1]-'0') % serverCount
+		},
+		Dialer: DialerFunc(func(ctx context.Context, network, address string) (net.Conn, error) {
+			dials.Add(1)
+			n := inflight.Add(1)
+			defer inflight.Add(-1)
+			for p := peak.Load(); n > p && !peak.CompareAndSwap(p, n); p = peak.Load() {
+			}
+			time.Sleep(10 * time.Millisecond)
+			return nil, errPartialDial
+		}),
+	})
+	t.Cleanup(client.Close)
+	batch := NewBatchCommands(client)
+
+	_, err := batch.MultiGet(context.Background(), []string{"k0", "k1", "k2", "k3"}, WithBatchOptions(BatchOptions{MaxConcurrency: 2}))
+
+	require.ErrorIs(t, err, errPartialDial)
+	assert.Equal(t, int32(4), dials.Load(), "every server is sent its part")
+	assert.Equal(t, int32(2), peak.Load(), "at most 2 servers at once")
+}
+
+func TestBatchCommands_MaxBatchSize(t *testing.T) {
+	mock := testutils.NewConnectionMock("VA 1\r\na\r\n", "EN\r\n", "MN\r\n", "VA 1\r\nc\r\n", "MN\r\n")
+	client := NewClient(StaticServers("localhost:11211"), Config{
+		Dialer:       &mockDialer{conn: mock},
+		MaxSize:      1, // the sub-batches share the connection, in turn
+		MaxBatchSize: 2,
+	})
+	t.Cleanup(client.Close)
+	batch := NewBatchCommands(client)
+
+	items, err := batch.MultiGet(context.Background(), []string{"a", "b", "c"}, WithBatchOptions(BatchOptions{MaxConcurrency: 1}))
+
+	require.NoError(t, err)
+	require.Len(t, items, 3)
+	assert.Equal(t, "a", string(items[0].Value))
+	assert.False(t, items[1].Found)
+	assert.Equal(t, "c", string(items[2].Value))
+	assertRequest(t, mock, "mg a v f\r\nmg b v f\r\nmn\r\nmg c v f\r\nmn\r\n")
+}
+
+func TestBatchCommands_VerifyBatchResponses(t *testing.T) {
+	mock := testutils.NewConnectionMock("VA 1 O0\r\na\r\n", "EN O1\r\n", "MN\r\n")
+	client := NewClient(StaticServers("localhost:11211"), Config{
+		Dialer:               &mockDialer{conn: mock},
+		VerifyBatchResponses: true,
+	})
+	t.Cleanup(client.Close)
+
+	items, err := NewBatchCommands(client).MultiGet(context.Background(), []string{"a", "b"})
+
+	require.NoError(t, err)
+	require.Len(t, items, 2)
+	assert.Equal(t, "a", string(items[0].Value))
+	assert.False(t, items[1].Found)
+	assertRequest(t, mock, "mg a v f O0\r\nmg b v f O1\r\nmn\r\n")
+}
+
+func TestBatchCommands_VerifyBatchResponses_Mismatch(t *testing.T) {
+	mock := testutils.NewConnectionMock("VA 1 O1\r\nb\r\n", "VA 1 O0\r\na\r\n", "MN\r\n")
+	client := NewClient(StaticServers("localhost:11211"), Config{
+		Dialer:               &mockDialer{conn: mock},
+		VerifyBatchResponses: true,
+	})
+	t.Cleanup(client.Close)
+
+	_, err := NewBatchCommands(client).MultiGet(context.Background(), []string{"a", "b"})
+
+	require.ErrorIs(t, err, ErrResponseMismatch)
+	assert.Eventually(t, func() bool {
+		return client.PoolMetrics()[0].Conns.DestroyedConns == 1
+	}, time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,67 @@
+package memcache
+
+import (
+	"bufio"
+	"io"
+	"sync"
+)
+
+// defaultMaxRetainedBufferSize is the default of Config.MaxRetainedBufferSize.
+const defaultMaxRetainedBufferSize = 64 << 10
+
+// connBuffers recycles the bufio readers and writers of the closed pooled
+// connections, for the connections created next. It is shared by all the
+// clients, with a pool per buffer size: the buffers of a new connection have
+// the size it asks for.
+var connBuffers bufferPool
+
+type bufferPool struct {
+	readers sync.Map // size -> *sync.Pool of *bufio.Reader
+	writers sync.Map // size -> *sync.Pool of *bufio.Writer
+}
+
+func (p *bufferPool) sizePool(m *sync.Map, size int) *sync.Pool {
+	if pool, ok := m.Load(size); ok {
+		return pool.(*sync.Pool)
+	}
+	pool, _ := m.LoadOrStore(size, &sync.Pool{})
+	return pool.(*sync.Pool)
+}
+
+// getReader returns a reader of size bytes reading from r.
+func (p *bufferPool) getReader(r io.Reader, size int) *bufio.Reader {
+	if br, ok := p.sizePool(&p.readers, size).Get().(*bufio.Reader); ok {
+		br.Reset(r)
+		return br
+	}
+	return bufio.NewReaderSize(r, size)
+}
+
+// getWriter returns a writer of size bytes writing to w.
+func (p *bufferPool) getWriter(w io.Writer, size int) *bufio.Writer {
+	if bw, ok := p.sizePool(&p.writers, size).Get().(*bufio.Writer); ok {
+		bw.Reset(w)
+		return bw
+	}
+	return bufio.NewWriterSize(w, size)
+}
+
+// putReader recycles a reader no longer used, unless it is larger than
+// maxSize.
+func (p *bufferPool) putReader(br *bufio.Reader, maxSize int) {
+	if br.Size() > maxSize {
+		return
+	}
+	br.Reset(nil) // drop the reference to the connection
+	p.sizePool(&p.readers, br.Size()).Put(br)
+}
+
+// putWriter recycles a writer no longer used, unless it is larger than
+// maxSize.
+func (p *bufferPool) putWriter(bw *bufio.Writer, maxSize int) {
+	if bw.Size() > maxSize {
+		return
+	}
+	bw.Reset(nil)
+	p.sizePool(&p.writers, bw.Size()).Put(bw)
+}
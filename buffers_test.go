@@ -0,0 +1,74 @@
+package memcache
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBufferPool(t *testing.T) {
+	var pool bufferPool
+
+	br := pool.getReader(strings.NewReader("first"), 512)
+	assert.Equal(t, 512, br.Size())
+	_, err := io.ReadAll(br)
+	require.NoError(t, err)
+	pool.putReader(br, 1024)
+
+	// A recycled reader reads from its new source only.
+	br = pool.getReader(strings.NewReader("second"), 512)
+	data, err := io.ReadAll(br)
+	require.NoError(t, err)
+	assert.Equal(t, "second", string(data))
+
+	var out bytes.Buffer
+	bw := pool.getWriter(io.Discard, 512)
+	_, _ = bw.WriteString("pending") // dropped with the closed connection
+	pool.putWriter(bw, 1024)
+
+	bw = pool.getWriter(&out, 512)
+	_, _ = bw.WriteString("hello")
+	require.NoError(t, bw.Flush())
+	assert.Equal(t, "hello", out.String())
+}
+
+func TestConnection_CloseRecyclesBuffers(t *testing.T) {
+	client, server := net.Pipe()
+	defer server.Close()
+
+	conn := newPooledConnection(client, Config{MaxRetainedBufferSize: defaultMaxRetainedBufferSize})
+	require.NotNil(t, conn.Reader)
+	require.NoError(t, conn.Close())
+	assert.Nil(t, conn.Reader, "the reader should be recycled")
+	assert.Nil(t, conn.Writer, "the writer should be recycled")
+	_ = conn.Close() // a second Close doesn't recycle the buffers twice
+
+	// Without recycling, as for the pipelined connections.
+	client, server2 := net.Pipe()
+	defer server2.Close()
+	conn = newPooledConnection(client, Config{MaxRetainedBufferSize: -1})
+	require.NoError(t, conn.Close())
+	assert.NotNil(t, conn.Reader)
+}
+
+func TestConnection_BufferSizes(t *testing.T) {
+	client, server := net.Pipe()
+	defer server.Close()
+
+	conn := newPooledConnection(client, Config{})
+	assert.Equal(t, defaultBufferSize, conn.Reader.Size())
+	assert.Equal(t, defaultBufferSize, conn.Writer.Size())
+	_ = conn.Close()
+
+	client, server2 := net.Pipe()
+	defer server2.Close()
+	conn = newPooledConnection(client, Config{ReadBufferSize: 64 << 10, WriteBufferSize: 1024})
+	assert.Equal(t, 64<<10, conn.Reader.Size())
+	assert.Equal(t, 1024, conn.Writer.Size())
+	_ = conn.Close()
+}
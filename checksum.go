@@ -0,0 +1,92 @@
+package memcache
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"log/slog"
+
+	"github.com/pior/memcache/meta"
+)
+
+// checksumSize is the size of the checksum appended to the values.
+const checksumSize = 4
+
+// checksumTable is the CRC-32C (Castagnoli) table, hardware accelerated on
+// the common platforms.
+var checksumTable = crc32.MakeTable(crc32.Castagnoli)
+
+// errChecksumAppend rejects the appends and prepends with
+// Config.ValueChecksums: the checksum covers the whole value.
+var errChecksumAppend = errors.New("memcache: append and prepend are not supported with value checksums")
+
+// sealRequest returns req with the checksum appended to its value: a copy of
+// req, unless it stores no value.
+func sealRequest(req *meta.Request) (*meta.Request, error) {
+	if req.Command != meta.CmdSet {
+		return req, nil
+	}
+	// The server reads the mode tokens case-insensitively.
+	if mode, ok := req.GetFlagToken(meta.FlagMode); ok &&
+		(bytes.EqualFold(mode, []byte(meta.ModeAppend)) || bytes.EqualFold(mode, []byte(meta.ModePrepend))) {
+		return nil, errChecksumAppend
+	}
+
+	data := make([]byte, len(req.Data), len(req.Data)+checksumSize)
+	copy(data, req.Data)
+	data = binary.BigEndian.AppendUint32(data, crc32.Checksum(req.Data, checksumTable))
+
+	sealed := req.Clone()
+	sealed.Data = data
+	return sealed, nil
+}
+
+// sealBatch returns the requests of a batch with the checksums appended to
+// their values (see sealRequest).
+func sealBatch(reqs []*meta.Request) ([]*meta.Request, error) {
+	sealed := make([]*meta.Request, len(reqs))
+	for i, req := range reqs {
+		var err error
+		if sealed[i], err = sealRequest(req); err != nil {
+			return nil, err
+		}
+	}
+	return sealed, nil
+}
+
+// openResponse verifies and removes the checksum of the value of resp, the
+// response of req. A value with a wrong checksum fails the request with
+// ErrChecksumMismatch in resp.Error, like a protocol error, and is counted
+// and logged.
+func (sp *ServerPool) openResponse(ctx context.Context, req *meta.Request, resp *meta.Response) {
+	if req.Command != meta.CmdGet || resp.Status != meta.StatusVA {
+		return
+	}
+
+	data, ok := openValue(resp.Data)
+	if ok {
+		resp.Data = data
+		return
+	}
+
+	size := len(resp.Data)
+	resp.Data = nil
+	resp.Error = ErrChecksumMismatch
+	sp.checksumFailures.Add(1)
+	if sp.logger != nil {
+		sp.logger.LogAttrs(ctx, slog.LevelError, "memcache: checksum mismatch",
+			slog.String("server", sp.addr), slog.Int("size", size))
+	}
+}
+
+// openValue returns the value without its checksum, and whether the checksum
+// matches.
+func openValue(data []byte) ([]byte, bool) {
+	if len(data) < checksumSize {
+		return data, false
+	}
+	value, sum := data[:len(data)-checksumSize], data[len(data)-checksumSize:]
+	return value, crc32.Checksum(value, checksumTable) == binary.BigEndian.Uint32(sum)
+}
@@ -0,0 +1,147 @@
+package memcache
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"hash/crc32"
+	"log/slog"
+	"testing"
+
+	"github.com/pior/memcache/internal/testutils"
+	"github.com/pior/memcache/meta"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sealed returns value followed by its checksum, as stored with
+// Config.ValueChecksums.
+func sealed(value string) string {
+	sum := crc32.Checksum([]byte(value), crc32.MakeTable(crc32.Castagnoli))
+	return string(binary.BigEndian.AppendUint32([]byte(value), sum))
+}
+
+func newChecksumTestClient(t *testing.T, logs *bytes.Buffer, responses ...string) (*Client, *testutils.ConnectionMock) {
+	mock := testutils.NewConnectionMock(responses...)
+	client := NewClient(StaticServers("localhost:11211"), Config{
+		Dialer:         &mockDialer{conn: mock},
+		ValueChecksums: true,
+		Logger:         slog.New(slog.NewTextHandler(logs, nil)),
+	})
+	t.Cleanup(client.Close)
+	return client, mock
+}
+
+func TestValueChecksums(t *testing.T) {
+	var logs bytes.Buffer
+	client, mock := newChecksumTestClient(t, &logs,
+		"HD\r\n",
+		"VA 9 f3\r\n"+sealed("hello")+"\r\n",
+		"VA 4 f0\r\n"+sealed("")+"\r\n",
+		"EN\r\n",
+	)
+	ctx := context.Background()
+
+	value := []byte("hello")
+	require.NoError(t, client.Set(ctx, Item{Key: "a", Value: value, Flags: 3}))
+	assert.Equal(t, "hello", string(value), "the value of the caller is unchanged")
+
+	item, err := client.Get(ctx, "a")
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(item.Value))
+	assert.Equal(t, uint32(3), item.Flags)
+
+	item, err = client.Get(ctx, "empty")
+	require.NoError(t, err)
+	assert.True(t, item.Found)
+	assert.Empty(t, item.Value)
+
+	item, err = client.Get(ctx, "missing")
+	require.NoError(t, err)
+	assert.False(t, item.Found)
+
+	assertRequest(t, mock, "ms a 9 F3\r\n"+sealed("hello")+"\r\nmg a v f\r\nmg empty v f\r\nmg missing v f\r\n")
+	assert.Zero(t, client.PoolMetrics()[0].ChecksumFailures)
+	assert.Empty(t, logs.String())
+}
+
+func TestValueChecksums_Mismatch(t *testing.T) {
+	corrupted := []byte(sealed("hello"))
+	corrupted[1] ^= 0x20
+
+	var logs bytes.Buffer
+	client, _ := newChecksumTestClient(t, &logs,
+		"VA 9 f0\r\n"+string(corrupted)+"\r\n",
+		"VA 5 f0\r\nhello\r\n",
+		"VA 2 f0\r\n15\r\n",
+	)
+	ctx := context.Background()
+
+	_, err := client.Get(ctx, "a")
+	require.ErrorIs(t, err, ErrChecksumMismatch)
+
+	_, err = client.Get(ctx, "a")
+	require.ErrorIs(t, err, ErrChecksumMismatch, "a value written without checksum")
+
+	_, err = client.Get(ctx, "a")
+	require.ErrorIs(t, err, ErrChecksumMismatch, "a value shorter than a checksum")
+
+	assert.Equal(t, uint64(3), client.PoolMetrics()[0].ChecksumFailures)
+	assert.Contains(t, logs.String(), "level=ERROR msg=\"memcache: checksum mismatch\" server=localhost:11211 size=9")
+}
+
+func TestValueChecksums_Batch(t *testing.T) {
+	var logs bytes.Buffer
+	client, mock := newChecksumTestClient(t, &logs,
+		"HD\r\n", "HD\r\n", "MN\r\n",
+		"VA 5 f0\r\n"+sealed("x")+"\r\n", "EN\r\n", "MN\r\n",
+		"VA 5 f0\r\n"+sealed("x")+"\r\n", "VA 1 f0\r\ny\r\n", "MN\r\n",
+	)
+	batch := NewBatchCommands(client)
+	ctx := context.Background()
+
+	require.NoError(t, batch.MultiSet(ctx, []Item{{Key: "a", Value: []byte("x")}, {Key: "b", Value: []byte("y")}}))
+
+	items, err := batch.MultiGet(ctx, []string{"a", "b"})
+	require.NoError(t, err)
+	assert.Equal(t, "x", string(items[0].Value))
+	assert.False(t, items[1].Found)
+
+	_, err = batch.MultiGet(ctx, []string{"a", "b"})
+	require.ErrorIs(t, err, ErrChecksumMismatch)
+
+	assert.Equal(t, uint64(1), client.PoolMetrics()[0].ChecksumFailures)
+	assert.Contains(t, mock.GetWrittenRequest(),
+		"ms a 5\r\n"+sealed("x")+"\r\nms b 5\r\n"+sealed("y")+"\r\nmn\r\n")
+}
+
+func TestValueChecksums_AppendRejected(t *testing.T) {
+	var logs bytes.Buffer
+	client, mock := newChecksumTestClient(t, &logs)
+
+	for _, mode := range []string{meta.ModeAppend, meta.ModePrepend, "a", "p"} {
+		req := meta.NewRequest(meta.CmdSet, "a", []byte("x")).AddMode(mode)
+		_, err := client.Execute(context.Background(), req)
+		require.ErrorIs(t, err, errChecksumAppend, mode)
+	}
+	assert.Empty(t, mock.GetWrittenRequest())
+}
+
+func TestValueChecksums_MaxItemSize(t *testing.T) {
+	mock := testutils.NewConnectionMock("HD\r\n")
+	client := NewClient(StaticServers("localhost:11211"), Config{
+		Dialer:         &mockDialer{conn: mock},
+		ValueChecksums: true,
+		MaxItemSize:    8,
+	})
+	t.Cleanup(client.Close)
+	ctx := context.Background()
+
+	// The checksum is part of the stored size.
+	err := client.Set(ctx, Item{Key: "a", Value: []byte("12345")})
+	require.ErrorIs(t, err, ErrValueTooLarge)
+	require.ErrorContains(t, err, "9 bytes, limit is 8")
+
+	require.NoError(t, client.Set(ctx, Item{Key: "a", Value: []byte("1234")}))
+	assertRequest(t, mock, "ms a 8\r\n"+sealed("1234")+"\r\n")
+}
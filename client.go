@@ -2,8 +2,12 @@ package memcache
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log/slog"
 	"net"
+	"slices"
+	"strings"
 	"sync"
 	"time"
 
@@ -12,16 +16,91 @@ import (
 )
 
 // Dialer establishes the network connections used by the client's pools.
-// *net.Dialer satisfies this interface.
+// *net.Dialer and *tls.Dialer satisfy this interface; use DialerFunc for a
+// plain function (SOCKS proxies, tunnels, latency injection in tests, ...).
 type Dialer interface {
 	DialContext(ctx context.Context, network, address string) (net.Conn, error)
 }
 
+// DialerFunc adapts a function to the Dialer interface, like http.HandlerFunc.
+type DialerFunc func(ctx context.Context, network, address string) (net.Conn, error)
+
+// DialContext calls f(ctx, network, address).
+func (f DialerFunc) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	return f(ctx, network, address)
+}
+
 type Item struct {
 	Key   string
 	Value []byte
 	TTL   TTL
 	Found bool // indicates whether the key was found in cache
+
+	// Flags are opaque client flags stored with the item, e.g. to tag its
+	// encoding or compression. They are written by Set, Add and MultiSet, and
+	// read back by Get and MultiGet, compatibly with other memcache clients.
+	Flags uint32
+
+	// Metadata returned by GetWithOptions when requested with GetOptions.
+	// With ReturnTTL, TTL holds the remaining time to live.
+
+	CAS        uint64        // compare-and-swap value (ReturnCAS)
+	LastAccess time.Duration // time since the last access (ReturnLastAccess)
+	Size       int           // value size in bytes (ReturnSize)
+	Hit        bool          // whether the item was fetched before (ReturnHit)
+}
+
+// GetOptions selects the item metadata returned by GetWithOptions.
+type GetOptions struct {
+	ReturnCAS        bool
+	ReturnTTL        bool
+	ReturnLastAccess bool
+	ReturnSize       bool
+	ReturnHit        bool
+}
+
+// DeleteOptions controls how DeleteWithOptions removes an item.
+type DeleteOptions struct {
+	// CAS, when not zero, deletes the item only if its CAS value matches:
+	// a mismatch fails with ErrCASConflict.
+	CAS uint64
+
+	// Invalidate marks the item as stale instead of removing it: readers get
+	// the stale value and a single one is asked to recache it (see
+	// meta.Request.AddInvalidate).
+	Invalidate bool
+
+	// StaleTTL updates the TTL of an invalidated item, bounding how long the
+	// stale value is served. Zero keeps the current TTL.
+	StaleTTL time.Duration
+}
+
+// IncrementMode is the direction of the arithmetic operation of
+// IncrementWithOptions.
+type IncrementMode int
+
+const (
+	ModeIncrement IncrementMode = iota // add the delta (default)
+	ModeDecrement                      // subtract the delta, stopping at 0
+)
+
+// IncrementOptions controls the arithmetic operation of IncrementWithOptions.
+type IncrementOptions struct {
+	// Delta is the amount added to (or subtracted from) the counter.
+	Delta uint64
+
+	// Mode selects increment (default) or decrement.
+	Mode IncrementMode
+
+	// AutoCreate creates a missing counter with the Initial value, expiring
+	// as AutoCreateTTL. The new counter is returned as is: Delta isn't applied.
+	AutoCreate    bool
+	Initial       uint64
+	AutoCreateTTL TTL
+
+	// TTL, when set, updates the TTL of an existing counter. NoTTL leaves it
+	// unchanged.
+	TTL TTL
 }
 
 // Config holds configuration for the memcache client connection pool.
@@ -31,12 +110,39 @@ type Config struct {
 	// Required: must be > 0.
 	MaxSize int32
 
+	// MinSize is the number of connections each server pool establishes
+	// ahead of demand: pools are created and filled in the background when
+	// the client is created, and topped up after each health check, so a
+	// deploy or a pruning doesn't turn into a burst of dial latency.
+	// Default: 0 (connections are created on demand).
+	// Capped at MaxSize.
+	MinSize int32
+
+	// AcquireTimeout bounds the time spent waiting for a connection when the
+	// pool is exhausted. Operations that can't get a connection in time fail
+	// with ErrPoolExhausted.
+	// Zero means no limit: callers wait up to their context deadline.
+	AcquireTimeout time.Duration
+
+	// MaxWaitQueue is the maximum number of callers waiting for a connection
+	// when all MaxSize connections are in use. Callers beyond it fail
+	// immediately with ErrPoolExhausted.
+	// Zero means no limit.
+	MaxWaitQueue int32
+
 	// MaxConnLifetime is the maximum duration a connection can be reused.
 	// Enforced when a connection is returned to the pool after an operation,
 	// and by the health check loop for idle connections.
 	// Zero means no limit.
 	MaxConnLifetime time.Duration
 
+	// MaxConnLifetimeJitter adds a random duration, up to this value, to the
+	// MaxConnLifetime of each connection. Connections created together (at
+	// startup, or after a server restart) then expire over a window instead
+	// of reconnecting all at once.
+	// Zero means no jitter.
+	MaxConnLifetimeJitter time.Duration
+
 	// MaxConnIdleTime is the maximum duration a connection can be idle before being closed.
 	// Zero means no limit.
 	MaxConnIdleTime time.Duration
@@ -45,6 +151,14 @@ type Config struct {
 	// Zero disables health checks.
 	HealthCheckInterval time.Duration
 
+	// ReapInterval is how often idle connections are checked against
+	// MaxConnIdleTime and MaxConnLifetime, independently of the health
+	// checks. Unlike health checks, reaping doesn't ping the connections, so
+	// it can run often to close idle connections promptly.
+	// Zero disables the reaper: idle connections are then only pruned by
+	// the health checks.
+	ReapInterval time.Duration
+
 	// Timeout is the per-operation timeout for memcache operations (read/write).
 	// It acts as an upper bound on every operation: the effective deadline is the
 	// earlier of the context deadline and now+Timeout. A context deadline sooner
@@ -55,8 +169,18 @@ type Config struct {
 	// Zero means no cap — the operation is bounded only by the context (not
 	// recommended for production).
 	// Recommended: 100ms-1s depending on your latency requirements.
+	// A single call can override it with WithTimeout.
 	Timeout time.Duration
 
+	// ReadTimeout, WriteTimeout and BatchTimeout replace Timeout for a class
+	// of operations, when their latency objectives differ: ReadTimeout for
+	// gets (mg), WriteTimeout for stores, deletes and arithmetic (ms, md, ma),
+	// and BatchTimeout for the responses of a batch (ExecuteBatch, MultiGet,
+	// ...). Zero uses Timeout, including a Timeout set with PerServer.
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+	BatchTimeout time.Duration
+
 	// ConnectTimeout is the timeout for establishing new connections.
 	// This includes TCP handshake and TLS handshake if applicable.
 	// If zero, uses Timeout value.
@@ -64,7 +188,7 @@ type Config struct {
 	ConnectTimeout time.Duration
 
 	// Dialer is used to create new connections. If nil, a default
-	// net.Dialer is used.
+	// net.Dialer is used. A function can be used with DialerFunc.
 	//
 	// To connect over TLS (memcached running with --enable-ssl), set a
 	// *tls.Dialer, which satisfies this interface:
@@ -79,19 +203,258 @@ type Config struct {
 	// selects its config based on the address.
 	Dialer Dialer
 
+	// Protocol is the protocol dialect spoken with the servers: ProtocolText
+	// for the servers and proxies without the meta commands.
+	// Default: ProtocolMeta
+	Protocol Protocol
+
+	// ProxyProtocol sends a PROXY protocol v2 header on each new connection,
+	// with the local and server addresses of the connection, for the servers
+	// behind a load balancer requiring it. With a *tls.Dialer, the header is
+	// sent before the TLS handshake; with another Dialer, after DialContext.
+	// Default: false
+	ProxyProtocol bool
+
+	// PipelineConns enables the pipelined mode when > 0: each server gets
+	// exactly PipelineConns connections, shared by all operations. Requests
+	// are written as they are issued and the responses are matched to them
+	// in order, so many operations are in flight on each connection. Useful
+	// with connection-limited servers (managed services, sidecars).
+	//
+	// In this mode the pool settings (MaxSize, MinSize, AcquireTimeout,
+	// MaxWaitQueue, connection lifetime and idle time) and the health checks
+	// don't apply, and requests can't use the quiet flag. Timeout bounds each
+	// operation from the time its request is written, and bounds the write
+	// itself; without Timeout, the write and the responses are bounded by the
+	// context deadline, which then breaks the shared connection when it
+	// expires. A broken connection fails its operations in flight and is
+	// re-established on next use.
+	// Default: 0 (pooled mode).
+	PipelineConns int32
+
 	// NewPool is the connection pool factory function.
 	// If nil, uses the puddle-based pool.
 	NewPool func(constructor func(ctx context.Context) (*Connection, error), maxSize int32) (Pool, error)
 
 	// ServerSelector picks which server to use for a key.
 	// Receives the key and current server count, and return the selected server index.
-	// The default implementation uses Jump Hash for consistent server selection.
+	// If nil, HashServerSelector is used, which defaults to Jump Hash for
+	// consistent server selection.
 	ServerSelector ServerSelector
 
+	// HashServerSelector picks which server to use for a key from the hash of
+	// the key (see KeyHash), for the selectors that hash keys themselves: the
+	// client hashes each key once, without any conversion. It is used when
+	// ServerSelector is nil.
+	// Default: JumpServerSelector, which is equivalent to DefaultServerSelector.
+	HashServerSelector HashServerSelector
+
+	// KeyHash is the hash of the keys given to HashServerSelector, e.g.
+	// FNV1aKeyHash, CRC32KeyHash, KetamaKeyHash or Murmur3KeyHash to place
+	// the keys as another client sharing the servers.
+	// Default: HashKey (xxh3)
+	KeyHash KeyHash
+
+	// MigrationWindow enables the dual-read migration mode when > 0: for
+	// MigrationWindow after the server list changed, a get missing on the
+	// server of its key is retried on the server of the key in the previous
+	// server list, and a hit there is copied to the new server (an async add,
+	// see SetAsync). Growing or shrinking the cluster then doesn't translate
+	// into a storm of misses for the keys that moved.
+	//
+	// It applies to the gets executed one by one (Get, GetWithOptions, ...),
+	// not to the batches. The change is detected by the first operation
+	// seeing the new server list.
+	// Default: 0 (disabled)
+	MigrationWindow time.Duration
+
+	// VerifyBatchResponses adds an opaque token to each request of a batch
+	// and verifies that the responses carry the tokens of their requests:
+	// defense in depth against the responses of a desynchronized connection
+	// being attributed to the wrong requests. A mismatch fails the batch
+	// with ErrResponseMismatch and closes the connection. The requests that
+	// have an opaque token are verified with theirs in any case. Not
+	// available with ProtocolText.
+	// Default: false
+	VerifyBatchResponses bool
+
+	// CoalesceGets shares the gets in flight between the identical gets
+	// (same key and flags) issued meanwhile by the goroutines of the
+	// process: a hot key costs a single network request at a time, and its
+	// response is copied to each caller. Unlike a GetOrSet-style loader, it
+	// applies to the plain gets executed one by one (Get, GetWithOptions,
+	// Execute), not to the batches nor to the gets with the vivify, recache
+	// or quiet flags.
+	//
+	// A get may then return the response of a get sent before a write of
+	// the key made by the same caller: the gets made with a WriteSession
+	// are not coalesced, to read their writes. Nor are the gets with their
+	// own timeout (WithTimeout).
+	// Default: false
+	CoalesceGets bool
+
+	// VerifyOpaque adds an opaque token to every request, single ones as
+	// well as batches (see VerifyBatchResponses), and verifies that each
+	// response carries the token of its request, to detect a desynchronized
+	// connection as soon as a response is attributed to the wrong request.
+	// A mismatch fails the operation with ErrResponseMismatch, closes the
+	// connection, is logged with Logger at error level and is counted in
+	// PoolMetrics.ResponseMismatches. The cost is a few bytes per request
+	// and response. Not available with ProtocolText.
+	// Default: false
+	VerifyOpaque bool
+
+	// ValueChecksums appends a CRC-32C checksum of the value to the stored
+	// values (4 bytes), and verifies it on the values read, to detect a
+	// corruption anywhere between the writer and the reader: a value with a
+	// wrong checksum fails the get with ErrChecksumMismatch, is logged with
+	// Logger at error level and is counted in PoolMetrics.ChecksumFailures.
+	// The checksum is transparent to the callers, except for the sizes
+	// reported by the server and checked against MaxItemSize, which
+	// include it.
+	//
+	// Every client writing the keys must enable it: a value written without
+	// a checksum fails the verification. This includes the counters
+	// (Increment, Decrement), which can't be read with Get. Append and
+	// prepend are rejected.
+	// Default: false
+	ValueChecksums bool
+
+	// MaxBatchSize splits the part of a batch sent to a server (ExecuteBatch,
+	// MultiGet, MultiSet, ...) into sub-batches of up to MaxBatchSize
+	// requests, pipelined on separate connections, to bound the pipelining
+	// depth and the memory of a connection. The responses are merged
+	// transparently.
+	// Default: 0 (no limit)
+	MaxBatchSize int
+
+	// UDP enables the UDP transport for the small gets, with a fallback to
+	// TCP. See UDPConfig: it requires a *net.Dialer, without ProxyProtocol.
+	// If nil, every operation goes through TCP.
+	UDP *UDPConfig
+
+	// AutoEject removes the persistently failing servers from the server
+	// list for a cool-down period. See AutoEjectPolicy.
+	// If nil, the servers are never ejected.
+	AutoEject *AutoEjectPolicy
+
 	// CircuitBreakerSettings configures the circuit breaker for each server pool.
 	// If nil, no circuit breaker is used.
 	// The Name field in the settings will be overridden with the server address.
 	CircuitBreakerSettings *gobreaker.Settings
+
+	// Shedding enables load shedding: under pressure, a server rejects the
+	// operations of low priority (see WithPriority) with ErrShed.
+	// If nil, no operation is shed.
+	Shedding *SheddingPolicy
+
+	// Hooks observe every operation sent to a server, for metrics, logging
+	// or tracing. See Hook.
+	Hooks []Hook
+
+	// CollectOpMetrics enables per-operation latency histograms (p50, p90,
+	// p99) and hit/miss counters, reported in PoolMetrics.Ops.
+	// The overhead is two clock reads and a few atomic increments per
+	// operation.
+	CollectOpMetrics bool
+
+	// Logger receives the client logs. If nil, nothing is logged.
+	Logger *slog.Logger
+
+	// SlowOpThreshold enables the slow operation log: operations taking at
+	// least this long are logged at warn level with Logger, with their
+	// server, duration and status.
+	// Zero disables it.
+	SlowOpThreshold time.Duration
+
+	// SlowOpKeys controls how keys appear in the slow operation log.
+	// Default: KeyLogNone (keys are omitted).
+	SlowOpKeys KeyLogMode
+
+	// MaxItemSize rejects stores (Set, Add, ms requests) whose value is
+	// larger, with ErrValueTooLarge, before anything is sent. Set it to the
+	// item size limit of the servers (memcached -I option, 1 MiB by default)
+	// so an oversized value fails locally instead of with a SERVER_ERROR
+	// "object too large for cache". The server limit also counts the key and
+	// the item header (about 50 bytes), so leave some margin.
+	// Zero disables the check.
+	MaxItemSize int
+
+	// ReadBufferSize and WriteBufferSize are the sizes of the bufio buffers of
+	// each connection. Small buffers suit small values at a high rate: they
+	// stay in the CPU caches. Large buffers suit large values: a value larger
+	// than the buffer is read and written in several chunks.
+	// Default: 4KB
+	ReadBufferSize  int
+	WriteBufferSize int
+
+	// MaxRetainedBufferSize bounds the size of the bufio buffers recycled
+	// when a connection is closed: they are reused by the connections created
+	// next, by all the clients, instead of being reallocated. Larger buffers
+	// are left to the garbage collector.
+	// Default: 64KB
+	// Negative disables the recycling.
+	MaxRetainedBufferSize int
+
+	// AsyncQueueSize is the number of async writes (SetAsync, DeleteAsync)
+	// that can wait to be sent. Beyond it, the async writes fail with
+	// ErrAsyncQueueFull.
+	// Default: 1024
+	AsyncQueueSize int
+
+	// AsyncErrorHandler is called with the failures of the async writes, from
+	// a background goroutine. key is empty when a failure can't be attributed
+	// to a write (a protocol error response).
+	// If nil, the failures are logged with Logger.
+	AsyncErrorHandler func(key string, err error)
+
+	// PerServer overrides the pool settings for specific server addresses,
+	// e.g. a larger pool for a server holding hot keys, or a longer
+	// ConnectTimeout for a remote one. Keys are server addresses, as
+	// returned by Servers.List.
+	PerServer map[string]PoolOverrides
+}
+
+// PoolOverrides holds the pool settings that can be set per server with
+// Config.PerServer. Zero fields inherit the Config value.
+type PoolOverrides struct {
+	MaxSize        int32
+	MinSize        int32
+	AcquireTimeout time.Duration
+	MaxWaitQueue   int32
+	Timeout        time.Duration
+	ConnectTimeout time.Duration
+}
+
+// forServer returns the configuration of the pool of a server: the client
+// configuration with the server overrides applied.
+func (c Config) forServer(addr string) Config {
+	if o, ok := c.PerServer[addr]; ok {
+		if o.MaxSize > 0 {
+			c.MaxSize = o.MaxSize
+		}
+		if o.MinSize > 0 {
+			c.MinSize = o.MinSize
+		}
+		if o.AcquireTimeout > 0 {
+			c.AcquireTimeout = o.AcquireTimeout
+		}
+		if o.MaxWaitQueue > 0 {
+			c.MaxWaitQueue = o.MaxWaitQueue
+		}
+		if o.Timeout > 0 {
+			c.Timeout = o.Timeout
+		}
+		if o.ConnectTimeout > 0 {
+			c.ConnectTimeout = o.ConnectTimeout
+		}
+	}
+
+	c.MinSize = min(c.MinSize, c.MaxSize)
+	if c.ConnectTimeout == 0 {
+		c.ConnectTimeout = c.Timeout
+	}
+	return c
 }
 
 // Client is a memcache client that implements the Querier interface using a connection pool.
@@ -107,9 +470,16 @@ type Client struct {
 
 	config Config
 
-	// Health check management
-	stopHealthCheck chan struct{}
-	closeOnce       sync.Once
+	// Background goroutines (health checks, reaper, async writes) management
+	stopBackground chan struct{}
+	closeOnce      sync.Once
+
+	inflight  inflightOps
+	async     asyncWriter
+	migration serverMigration
+	stateSubs stateSubscribers
+	ejections serverEjections
+	coalescer getCoalescer
 }
 
 var _ Querier = (*Client)(nil)
@@ -126,11 +496,11 @@ func NewClient(servers Servers, config Config) *Client {
 	if config.MaxSize <= 0 {
 		config.MaxSize = 10
 	}
-	if config.ConnectTimeout == 0 {
-		config.ConnectTimeout = config.Timeout
+	if config.ServerSelector == nil && config.HashServerSelector == nil {
+		config.HashServerSelector = JumpServerSelector
 	}
-	if config.ServerSelector == nil {
-		config.ServerSelector = DefaultServerSelector
+	if config.KeyHash == nil {
+		config.KeyHash = HashKey
 	}
 	if config.Dialer == nil {
 		config.Dialer = &net.Dialer{}
@@ -138,13 +508,35 @@ func NewClient(servers Servers, config Config) *Client {
 	if config.NewPool == nil {
 		config.NewPool = NewPuddlePool
 	}
+	if config.MaxRetainedBufferSize == 0 {
+		config.MaxRetainedBufferSize = defaultMaxRetainedBufferSize
+	}
+	if config.AsyncQueueSize <= 0 {
+		config.AsyncQueueSize = 1024
+	}
+	if config.Logger != nil && config.SlowOpThreshold > 0 {
+		// Clip so the caller's slice is never appended to in place.
+		config.Hooks = append(slices.Clip(config.Hooks), &slowOpHook{
+			logger:    config.Logger,
+			threshold: config.SlowOpThreshold,
+			keys:      config.SlowOpKeys,
+		})
+	}
 
 	client := &Client{
-		servers:         servers,
-		pools:           make(map[string]*ServerPool),
-		config:          config,
-		stopHealthCheck: make(chan struct{}),
+		servers:        servers,
+		pools:          make(map[string]*ServerPool),
+		config:         config,
+		stopBackground: make(chan struct{}),
+		inflight:       inflightOps{idle: make(chan struct{}, 1)},
+		async: asyncWriter{
+			queue:        make(chan asyncWrite, config.AsyncQueueSize),
+			done:         make(chan struct{}),
+			flushTimeout: asyncFlushTimeout,
+			closeTimeout: asyncCloseTimeout,
+		},
 	}
+	client.async.ctx, client.async.cancel = context.WithCancel(context.Background())
 
 	// Initialize embedded Commands with execute function
 	client.Commands = NewCommands(client)
@@ -153,18 +545,77 @@ func NewClient(servers Servers, config Config) *Client {
 	if config.HealthCheckInterval > 0 {
 		go client.healthCheckLoop()
 	}
+	if config.ReapInterval > 0 {
+		go client.reapLoop()
+	}
+
+	// Warm up: creating the pools starts filling them to MinSize.
+	for _, addr := range servers.List() {
+		if config.forServer(addr).MinSize > 0 {
+			_, _ = client.getPoolForServer(addr)
+		}
+	}
 
 	return client
 }
 
 func (c *Client) Execute(ctx context.Context, req *meta.Request) (*meta.Response, error) {
+	if err := c.begin(); err != nil {
+		return nil, err
+	}
+	defer c.end()
+
+	if err := c.checkItemSize(req); err != nil {
+		return nil, err
+	}
+
 	sp, err := c.getPoolForKey(req.Key)
 	if err != nil {
 		return nil, err
 	}
+	if c.config.CoalesceGets && isCoalescedCall(ctx, req) {
+		return c.coalescer.do(ctx, sp, req, func() (*meta.Response, error) {
+			return c.executeOn(ctx, sp, req)
+		})
+	}
+	return c.executeOn(ctx, sp, req)
+}
+
+// executeOn executes req on sp, the server of its key.
+func (c *Client) executeOn(ctx context.Context, sp *ServerPool, req *meta.Request) (*meta.Response, error) {
+	if c.config.MigrationWindow > 0 && isMigrationRead(req) {
+		return c.executeMigrationRead(ctx, sp, req)
+	}
 	return sp.Execute(ctx, req)
 }
 
+// WithRawConnection calls fn with a pooled connection of the server of key,
+// for custom meta sequences the commands don't cover: several requests on the
+// same connection, debug commands, ... The connection is exclusive to fn and
+// is returned to the pool when fn returns, through the circuit breaker and
+// the hooks of the server like any operation (OpRaw).
+//
+// fn must leave the connection at a request boundary, every response read.
+// When fn returns an error, it counts as a failure of the server for the
+// circuit breaker, and the connection is closed unless the error tells it
+// can be reused (see meta.ShouldCloseConnection): return nil for the outcomes
+// that are not failures, like a miss.
+//
+// The connection must not be used after fn returns. It is not available in
+// pipelined mode (Config.PipelineConns), where connections are shared.
+func (c *Client) WithRawConnection(ctx context.Context, key string, fn func(conn *Connection) error) error {
+	if err := c.begin(); err != nil {
+		return err
+	}
+	defer c.end()
+
+	sp, err := c.getPoolForKey(key)
+	if err != nil {
+		return err
+	}
+	return sp.WithConnection(ctx, key, fn)
+}
+
 // ExecuteBatch executes multiple requests with automatic server routing.
 // Requests are grouped by server and executed concurrently using pipelined requests.
 // Returns responses in the same order as requests.
@@ -173,17 +624,43 @@ func (c *Client) Execute(ctx context.Context, req *meta.Request) (*meta.Response
 // to produce a response: requests using the quiet flag are rejected. Use
 // Connection.ExecuteBatch directly for quiet pipelining.
 //
-// If any server batch fails, an error is returned and the responses are
-// discarded, including those from servers that succeeded.
+// If the batches of all the servers fail, the responses are nil and the error
+// is that of a server. If only some fail, the responses of the healthy servers
+// are returned, with nil responses for the requests of the failed servers, and
+// a *PartialError detailing the failures.
 func (c *Client) ExecuteBatch(ctx context.Context, reqs []*meta.Request) ([]*meta.Response, error) {
+	return c.ExecuteBatchWithOptions(ctx, reqs, batchOptions(ctx))
+}
+
+// BatchOptions tunes the execution of a batch spanning several servers.
+type BatchOptions struct {
+	// MaxConcurrency bounds the number of servers sent their part of the
+	// batch at once, and so the number of goroutines of the batch.
+	// Default: 0, every server at once.
+	MaxConcurrency int
+}
+
+// ExecuteBatchWithOptions is ExecuteBatch with the execution tuned by opts.
+// The requests are grouped by server, each group is pipelined on a connection
+// of its server, and the groups are executed concurrently, up to
+// opts.MaxConcurrency at once.
+func (c *Client) ExecuteBatchWithOptions(ctx context.Context, reqs []*meta.Request, opts BatchOptions) ([]*meta.Response, error) {
 	if len(reqs) == 0 {
 		return nil, nil
 	}
 
+	if err := c.begin(); err != nil {
+		return nil, err
+	}
+	defer c.end()
+
 	for _, req := range reqs {
 		if req.HasFlag(meta.FlagQuiet) {
 			return nil, fmt.Errorf("memcache: quiet flag is not supported in ExecuteBatch: responses are matched to requests by position")
 		}
+		if err := c.checkItemSize(req); err != nil {
+			return nil, err
+		}
 	}
 
 	// Group requests by server
@@ -193,17 +670,20 @@ func (c *Client) ExecuteBatch(ctx context.Context, reqs []*meta.Request) ([]*met
 		indices    []int // original indices in reqs slice
 	}
 
-	serverBatches := make(map[string]*serverBatch)
+	// The batches of each server hold up to MaxBatchSize requests.
+	var serverBatches []*serverBatch
+	open := make(map[string]*serverBatch) // the batch being filled per server
 	for i, req := range reqs {
 		addr, err := c.selectServerForKey(req.Key)
 		if err != nil {
 			return nil, err
 		}
 
-		batch, exists := serverBatches[addr]
-		if !exists {
+		batch, exists := open[addr]
+		if !exists || (c.config.MaxBatchSize > 0 && len(batch.reqs) == c.config.MaxBatchSize) {
 			batch = &serverBatch{serverAddr: addr}
-			serverBatches[addr] = batch
+			open[addr] = batch
+			serverBatches = append(serverBatches, batch)
 		}
 		batch.reqs = append(batch.reqs, req)
 		batch.indices = append(batch.indices, i)
@@ -212,66 +692,117 @@ func (c *Client) ExecuteBatch(ctx context.Context, reqs []*meta.Request) ([]*met
 	// Prepare result slice
 	results := make([]*meta.Response, len(reqs))
 
-	// Execute batches concurrently per server
 	var wg sync.WaitGroup
-	errChan := make(chan error, len(serverBatches))
-
-	for _, batch := range serverBatches {
-		wg.Add(1)
-		go func(b *serverBatch) {
-			defer wg.Done()
-
-			// Get pool for this server
-			sp, err := c.getPoolForServer(b.serverAddr)
-			if err != nil {
-				errChan <- err
+	var mu sync.Mutex
+	var failures []ServerFailure
+	failed := 0
+	fail := func(b *serverBatch, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		failed++
+		for i := range failures {
+			if failures[i].Server == b.serverAddr { // another batch of the server
+				failures[i].Indices = append(failures[i].Indices, b.indices...)
 				return
 			}
+		}
+		failures = append(failures, ServerFailure{Server: b.serverAddr, Indices: slices.Clone(b.indices), Err: err})
+	}
 
-			// Execute batch using ServerPool.ExecuteBatch
-			responses, err := sp.ExecuteBatch(ctx, b.reqs)
-			if err != nil {
-				errChan <- err
-				return
-			}
+	run := func(b *serverBatch) {
+		// Get pool for this server
+		sp, err := c.getPoolForServer(b.serverAddr)
+		if err != nil {
+			fail(b, err)
+			return
+		}
 
-			// Without quiet flags, Connection.ExecuteBatch guarantees one
-			// response per request; this is a defensive check so a bug can
-			// never surface as nil responses to the caller.
-			if len(responses) != len(b.indices) {
-				errChan <- &OpError{
-					Op:     OpBatch,
-					Server: b.serverAddr,
-					Err:    fmt.Errorf("received %d responses for %d requests", len(responses), len(b.indices)),
-				}
-				return
-			}
+		// Execute batch using ServerPool.ExecuteBatch
+		responses, err := sp.ExecuteBatch(ctx, b.reqs)
+		if err != nil {
+			fail(b, err)
+			return
+		}
 
-			for i, resp := range responses {
-				results[b.indices[i]] = resp
+		// Without quiet flags, Connection.ExecuteBatch guarantees one
+		// response per request; this is a defensive check so a bug can
+		// never surface as nil responses to the caller.
+		if len(responses) != len(b.indices) {
+			fail(b, &OpError{
+				Op:     OpBatch,
+				Server: b.serverAddr,
+				Err:    fmt.Errorf("received %d responses for %d requests", len(responses), len(b.indices)),
+			})
+			return
+		}
+
+		for i, resp := range responses {
+			results[b.indices[i]] = resp
+		}
+	}
+
+	// A goroutine per batch, or MaxConcurrency goroutines taking the batches
+	// in turn.
+	queue := make(chan *serverBatch, len(serverBatches))
+	for _, batch := range serverBatches {
+		queue <- batch
+	}
+	close(queue)
+
+	workers := len(serverBatches)
+	if opts.MaxConcurrency > 0 {
+		workers = min(workers, opts.MaxConcurrency)
+	}
+	for range workers {
+		wg.Go(func() {
+			for b := range queue {
+				run(b)
 			}
-		}(batch)
+		})
 	}
 
 	wg.Wait()
-	close(errChan)
 
-	// Check for errors
-	if err := <-errChan; err != nil {
-		return nil, err
+	switch {
+	case len(failures) == 0:
+		return results, nil
+	case failed == len(serverBatches):
+		return nil, failures[0].Err
+	}
+	slices.SortFunc(failures, func(a, b ServerFailure) int { return strings.Compare(a.Server, b.Server) })
+	for _, f := range failures {
+		slices.Sort(f.Indices) // merged from the batches of the server
 	}
+	return results, &PartialError{Failures: failures}
+}
 
-	return results, nil
+// checkItemSize enforces Config.MaxItemSize on a store request. The size
+// stored includes the checksum (Config.ValueChecksums).
+func (c *Client) checkItemSize(req *meta.Request) error {
+	if c.config.MaxItemSize <= 0 || req.Command != meta.CmdSet {
+		return nil
+	}
+	size := len(req.Data)
+	if c.config.ValueChecksums {
+		size += checksumSize
+	}
+	if size > c.config.MaxItemSize {
+		return fmt.Errorf("%w: %d bytes, limit is %d", ErrValueTooLarge, size, c.config.MaxItemSize)
+	}
+	return nil
 }
 
 // Close closes the client and destroys all connections in all pools.
-// It is safe to call multiple times. Operations issued after Close fail.
+// It is safe to call multiple times. Operations issued after Close fail with
+// ErrClientClosed; the operations in flight fail too: see Shutdown to let them
+// finish. The queued async writes are sent first, for up to 10s (see SetAsync).
 func (c *Client) Close() {
+	c.inflight.closing.Store(true)
 	c.closeOnce.Do(func() {
-		// Stop health check goroutine if running
-		if c.config.HealthCheckInterval > 0 {
-			close(c.stopHealthCheck)
-		}
+		// Stop the background goroutines, waiting for the queued async
+		// writes to be sent.
+		close(c.stopBackground)
+		c.closeAsync()
 
 		// Close all pools
 		c.mu.Lock()
@@ -279,15 +810,30 @@ func (c *Client) Close() {
 
 		c.closed = true
 		for _, sp := range c.pools {
-			sp.pool.Close()
+			sp.close()
 		}
 	})
 }
 
 // selectServerForKey picks the server address for a given key.
-// Uses the configured SelectServer function with the current server list.
+// Uses the configured ServerSelector, or HashServerSelector, with the current
+// server list. A key of a server ejected by Config.AutoEject is selected
+// again among the servers not ejected: the other keys stay in place.
 func (c *Client) selectServerForKey(key string) (string, error) {
 	servers := c.servers.List()
+	if c.config.MigrationWindow > 0 {
+		c.migration.observe(servers, c.config.MigrationWindow)
+	}
+	live := c.liveServers(servers)
+	addr, err := c.selectServer(key, servers)
+	if err != nil || len(live) == len(servers) || slices.Contains(live, addr) {
+		return addr, err
+	}
+	return c.selectServer(key, live)
+}
+
+// selectServer picks the server address for a key in a server list.
+func (c *Client) selectServer(key string, servers []string) (string, error) {
 	if len(servers) == 0 {
 		return "", ErrNoServers
 	}
@@ -295,7 +841,12 @@ func (c *Client) selectServerForKey(key string) (string, error) {
 		return servers[0], nil
 	}
 
-	bucket := c.config.ServerSelector(key, len(servers))
+	var bucket int
+	if c.config.ServerSelector != nil {
+		bucket = c.config.ServerSelector(key, len(servers))
+	} else {
+		bucket = c.config.HashServerSelector(c.config.KeyHash(key), len(servers))
+	}
 	if bucket < 0 || bucket >= len(servers) {
 		return "", fmt.Errorf("selected server index out of range")
 	}
@@ -319,7 +870,7 @@ func (c *Client) healthCheckLoop() {
 
 	for {
 		select {
-		case <-c.stopHealthCheck:
+		case <-c.stopBackground:
 			return
 		case <-ticker.C:
 			c.checkAllPools()
@@ -327,17 +878,63 @@ func (c *Client) healthCheckLoop() {
 	}
 }
 
-// checkAllPools runs health checks on all existing pools
-func (c *Client) checkAllPools() {
+// reapLoop periodically closes the idle connections exceeding their idle time
+// or lifetime.
+func (c *Client) reapLoop() {
+	ticker := time.NewTicker(c.config.ReapInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.stopBackground:
+			return
+		case <-ticker.C:
+			for _, sp := range c.allPools() {
+				reapPool(sp)
+				c.fillPool(sp)
+			}
+		}
+	}
+}
+
+// allPools returns a snapshot of the existing pools.
+func (c *Client) allPools() []*ServerPool {
 	c.mu.RLock()
+	defer c.mu.RUnlock()
+
 	pools := make([]*ServerPool, 0, len(c.pools))
 	for _, sp := range c.pools {
 		pools = append(pools, sp)
 	}
-	c.mu.RUnlock()
+	return pools
+}
 
-	for _, sp := range pools {
-		c.checkPoolConnections(sp.pool)
+// checkAllPools runs health checks on all existing pools
+func (c *Client) checkAllPools() {
+	for _, sp := range c.allPools() {
+		c.checkPoolConnections(sp)
+		c.fillPool(sp)
+	}
+}
+
+// reapPool destroys the idle connections of a pool that exceeded their idle
+// time or lifetime, and returns the others to the pool untouched.
+func reapPool(sp *ServerPool) {
+	now := time.Now()
+	for _, res := range sp.pool.AcquireAllIdle() {
+		if !sp.prune(res, now) {
+			res.ReleaseUnused()
+		}
+	}
+}
+
+// fillPool creates idle connections until the pool holds MinSize connections.
+// It stops at the first error: the next health check will try again.
+func (c *Client) fillPool(sp *ServerPool) {
+	for sp.pool.Metrics().TotalConns < sp.settings.Load().minSize {
+		if err := sp.pool.CreateIdle(context.Background()); err != nil {
+			return
+		}
 	}
 }
 
@@ -346,24 +943,17 @@ func (c *Client) checkAllPools() {
 const healthCheckPingTimeout = 5 * time.Second
 
 // checkPoolConnections checks all idle connections in a pool and destroys those that are stale or unhealthy.
-func (c *Client) checkPoolConnections(pool Pool) {
+func (c *Client) checkPoolConnections(sp *ServerPool) {
 	now := time.Now()
 
-	pingTimeout := c.config.Timeout
+	pingTimeout := sp.settings.Load().timeout
 	if pingTimeout <= 0 {
 		pingTimeout = healthCheckPingTimeout
 	}
 
-	for _, res := range pool.AcquireAllIdle() {
-		// Check max connection lifetime
-		if c.config.MaxConnLifetime > 0 && now.Sub(res.CreationTime()) > c.config.MaxConnLifetime {
-			res.Destroy()
-			continue
-		}
-
-		// Check max idle time
-		if c.config.MaxConnIdleTime > 0 && res.IdleDuration() > c.config.MaxConnIdleTime {
-			res.Destroy()
+	for _, res := range sp.pool.AcquireAllIdle() {
+		// Check max connection lifetime and max idle time
+		if sp.prune(res, now) {
 			continue
 		}
 
@@ -407,12 +997,20 @@ func (c *Client) getPoolForServer(addr string) (*ServerPool, error) {
 	}
 
 	// Create new pool
-	sp, err := NewServerPool(addr, c.config)
+	sp, err := NewServerPool(addr, c.config.forServer(addr))
 	if err != nil {
 		return nil, err
 	}
 
+	sp.health.notify = c.stateSubs.notify
+	if c.config.AutoEject != nil {
+		sp.health.failed = func(failures int, err error) { c.serverFailed(sp, failures, err) }
+	}
 	c.pools[addr] = sp
+
+	if sp.settings.Load().minSize > 0 {
+		go c.fillPool(sp)
+	}
 	return sp, nil
 }
 
@@ -440,7 +1038,12 @@ type ServerStats struct {
 // Returns a slice of ServerStats, one per server.
 // Individual server errors are returned in ServerStats.Error, not as a Go error.
 func (c *Client) Stats(ctx context.Context, args ...string) ([]ServerStats, error) {
-	servers := c.servers.List()
+	if err := c.begin(); err != nil {
+		return nil, err
+	}
+	defer c.end()
+
+	servers := uniqueServers(c.servers.List())
 	if len(servers) == 0 {
 		return nil, ErrNoServers
 	}
@@ -463,32 +1066,90 @@ func (c *Client) Stats(ctx context.Context, args ...string) ([]ServerStats, erro
 				return
 			}
 
-			// Acquire connection
-			res, err := sp.pool.Acquire(ctx)
-			if err != nil {
-				results[idx].Error = sp.wrapErr(OpStats, "", err)
-				return
-			}
+			results[idx].Stats, results[idx].Error = sp.ExecuteStats(ctx, args...)
+		}(i, addr)
+	}
+
+	wg.Wait()
+	return results, nil
+}
+
+// PingResult is the result of a ping to a single memcache server.
+type PingResult struct {
+	Addr  string // Server address
+	Error error  // Error if the server didn't answer the ping
+}
+
+// Ping checks every configured server with a noop (mn) request, so a bad node
+// is detected before a key hashes to it. The pools of the servers that weren't
+// used yet are created.
+// Returns a slice of PingResult, one per server, in the order of the server
+// list. Individual server errors are returned in PingResult.Error, not as a Go
+// error.
+func (c *Client) Ping(ctx context.Context) ([]PingResult, error) {
+	if err := c.begin(); err != nil {
+		return nil, err
+	}
+	defer c.end()
 
-			conn := res.Value()
+	servers := uniqueServers(c.servers.List())
+	if len(servers) == 0 {
+		return nil, ErrNoServers
+	}
 
-			// Execute stats command
-			stats, err := conn.ExecuteStats(ctx, args...)
+	results := make([]PingResult, len(servers))
+	var wg sync.WaitGroup
+	wg.Add(len(servers))
+
+	for i, addr := range servers {
+		go func(idx int, serverAddr string) {
+			defer wg.Done()
+
+			results[idx].Addr = serverAddr
+
+			sp, err := c.getPoolForServer(serverAddr)
 			if err != nil {
-				if meta.ShouldCloseConnection(err) {
-					res.Destroy()
-				} else {
-					sp.release(res)
-				}
-				results[idx].Error = sp.wrapErr(OpStats, "", err)
+				results[idx].Error = err
 				return
 			}
 
-			results[idx].Stats = stats
-			sp.release(res)
+			resp, err := sp.Execute(ctx, meta.NoOp())
+			switch {
+			case err != nil:
+				results[idx].Error = err
+			case resp.HasError():
+				results[idx].Error = resp.Error
+			case resp.Status != meta.StatusMN:
+				results[idx].Error = &StatusError{Op: "ping", Status: resp.Status}
+			}
 		}(i, addr)
 	}
 
 	wg.Wait()
 	return results, nil
 }
+
+// Connect verifies every configured server, typically at startup, so that a
+// misconfiguration fails the boot instead of the first requests: it dials the
+// servers (with the TLS handshake of a TLS Dialer) and sends them a noop, as
+// Ping does. It returns nil when all the servers answered, otherwise the
+// failures joined (see errors.Join), each an *OpError naming its server.
+func (c *Client) Connect(ctx context.Context) error {
+	results, err := c.Ping(ctx)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, r := range results {
+		if r.Error == nil {
+			continue
+		}
+		var opErr *OpError
+		if !errors.As(r.Error, &opErr) {
+			r.Error = &OpError{Op: string(meta.CmdNoOp), Server: r.Addr, Err: r.Error}
+		}
+		errs = append(errs, r.Error)
+	}
+	return errors.Join(errs...)
+}
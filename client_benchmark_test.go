@@ -3,6 +3,7 @@ package memcache
 import (
 	"context"
 	"fmt"
+	"strings"
 	"testing"
 	"time"
 
@@ -55,6 +56,20 @@ func BenchmarkClient(b *testing.B) {
 		}
 	})
 
+	b.Run("PreparedGet", func(b *testing.B) {
+		client := newBenchmarkClient(b,
+			"VA 5\r\n",
+			"hello\r\n",
+		)
+		hot := client.PrepareGet("testkey")
+
+		for b.Loop() {
+			if _, err := hot.Do(ctx); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
 	b.Run("Get_Miss", func(b *testing.B) {
 		client := newBenchmarkClient(b, "EN\r\n")
 
@@ -460,3 +475,69 @@ func BenchmarkClient(b *testing.B) {
 		}
 	})
 }
+
+// benchValueSizes are the value sizes of BenchmarkClientValueSizes and of the
+// allocation budgets (TestClient_AllocBudgets).
+var benchValueSizes = []int{16, 1 << 10, 16 << 10, 256 << 10}
+
+// sizedOps are the operations measured at each value size. setup returns
+// the operation, on a client whose mock answers it with values of size bytes.
+var sizedOps = []struct {
+	name  string
+	setup func(tb testing.TB, size int) func() error
+}{
+	{"Get", func(tb testing.TB, size int) func() error {
+		mockConn := testutils.NewConnectionMock(fmt.Sprintf("VA %d\r\n%s\r\n", size, strings.Repeat("x", size)))
+		mockConn.EnableCycling()
+		client := newTestClient(tb, mockConn)
+
+		return func() error {
+			_, err := client.Get(ctx, "testkey")
+			return err
+		}
+	}},
+	{"Set", func(tb testing.TB, size int) func() error {
+		mockConn := testutils.NewConnectionMock("HD\r\n")
+		mockConn.EnableCycling()
+		client := newTestClient(tb, mockConn)
+		item := Item{Key: "testkey", Value: make([]byte, size)}
+
+		return func() error {
+			return client.Set(ctx, item)
+		}
+	}},
+	{"MultiGet_10keys", func(tb testing.TB, size int) func() error {
+		hit := fmt.Sprintf("VA %d\r\n%s\r\n", size, strings.Repeat("x", size))
+		mockConn := testutils.NewConnectionMock(strings.Repeat(hit, 10) + "MN\r\n")
+		mockConn.EnableCycling()
+		batchCmd := NewBatchCommands(newTestClient(tb, mockConn))
+		keys := []string{"k1", "k2", "k3", "k4", "k5", "k6", "k7", "k8", "k9", "k10"}
+
+		return func() error {
+			_, err := batchCmd.MultiGet(ctx, keys)
+			return err
+		}
+	}},
+}
+
+// BenchmarkClientValueSizes measures Get, Set and MultiGet at several value
+// sizes, with their allocations:
+//
+//	go test -bench=BenchmarkClientValueSizes -run=^$ .
+func BenchmarkClientValueSizes(b *testing.B) {
+	for _, op := range sizedOps {
+		for _, size := range benchValueSizes {
+			b.Run(fmt.Sprintf("%s_%dB", op.name, size), func(b *testing.B) {
+				do := op.setup(b, size)
+				b.ReportAllocs()
+				b.SetBytes(int64(size))
+
+				for b.Loop() {
+					if err := do(); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
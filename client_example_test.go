@@ -103,3 +103,19 @@ func ExampleNewClient_tls() {
 
 	_ = client.Set(context.Background(), memcache.Item{Key: "user:123", Value: []byte("John")})
 }
+
+// Example injecting a custom dialer: any function with the DialContext
+// signature can be used with DialerFunc, here to add latency in tests.
+func ExampleDialerFunc() {
+	var d net.Dialer
+
+	client := memcache.NewClient(memcache.StaticServers("localhost:11211"), memcache.Config{
+		Dialer: memcache.DialerFunc(func(ctx context.Context, network, address string) (net.Conn, error) {
+			time.Sleep(10 * time.Millisecond)
+			return d.DialContext(ctx, network, address)
+		}),
+	})
+	defer client.Close()
+
+	_ = client.Set(context.Background(), memcache.Item{Key: "user:123", Value: []byte("John")})
+}
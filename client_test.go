@@ -3,12 +3,16 @@ package memcache
 import (
 	"bytes"
 	"context"
+	"errors"
+	"fmt"
 	"net"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/pior/memcache/internal/testutils"
+	"github.com/pior/memcache/meta"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -57,7 +61,17 @@ func TestClient_Get_Success(t *testing.T) {
 	assert.Equal(t, "testkey", item.Key)
 	assert.Equal(t, []byte("hello"), item.Value)
 	assert.True(t, item.Found)
-	assertRequest(t, mockConn, "mg testkey v\r\n")
+	assertRequest(t, mockConn, "mg testkey v f\r\n")
+}
+
+func TestClient_Get_ClientFlags(t *testing.T) {
+	mockConn := testutils.NewConnectionMock("VA 5 f42\r\nhello\r\n")
+	client := newTestClient(t, mockConn)
+
+	item, err := client.Get(context.Background(), "testkey")
+
+	require.NoError(t, err)
+	assert.Equal(t, uint32(42), item.Flags)
 }
 
 func TestClient_Get_Miss(t *testing.T) {
@@ -69,7 +83,7 @@ func TestClient_Get_Miss(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, "testkey", item.Key)
 	assert.False(t, item.Found)
-	assertRequest(t, mockConn, "mg testkey v\r\n")
+	assertRequest(t, mockConn, "mg testkey v f\r\n")
 }
 
 func TestClient_Get_EmptyValue(t *testing.T) {
@@ -81,7 +95,7 @@ func TestClient_Get_EmptyValue(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, []byte{}, item.Value)
 	assert.True(t, item.Found)
-	assertRequest(t, mockConn, "mg testkey v\r\n")
+	assertRequest(t, mockConn, "mg testkey v f\r\n")
 }
 
 func TestClient_Get_ServerError(t *testing.T) {
@@ -92,7 +106,7 @@ func TestClient_Get_ServerError(t *testing.T) {
 
 	require.Error(t, err)
 	assert.Contains(t, err.Error(), "SERVER_ERROR")
-	assertRequest(t, mockConn, "mg testkey v\r\n")
+	assertRequest(t, mockConn, "mg testkey v f\r\n")
 }
 
 func TestClient_Get_ClientError(t *testing.T) {
@@ -103,7 +117,7 @@ func TestClient_Get_ClientError(t *testing.T) {
 
 	require.Error(t, err)
 	assert.Contains(t, err.Error(), "CLIENT_ERROR")
-	assertRequest(t, mockConn, "mg testkey v\r\n")
+	assertRequest(t, mockConn, "mg testkey v f\r\n")
 }
 
 func TestClient_Get_UnexpectedStatus(t *testing.T) {
@@ -112,9 +126,207 @@ func TestClient_Get_UnexpectedStatus(t *testing.T) {
 
 	_, err := client.Get(context.Background(), "testkey")
 
-	require.Error(t, err)
-	assert.Contains(t, err.Error(), "unexpected response status")
-	assertRequest(t, mockConn, "mg testkey v\r\n")
+	var statusErr *StatusError
+	require.ErrorAs(t, err, &statusErr)
+	assert.Equal(t, "get", statusErr.Op)
+	assert.Equal(t, meta.StatusNS, statusErr.Status)
+	assertRequest(t, mockConn, "mg testkey v f\r\n")
+}
+
+func TestClient_GetWithOptions(t *testing.T) {
+	mockConn := testutils.NewConnectionMock("VA 5 c42 t60 f7 l3 s5 h1\r\nhello\r\n")
+	client := newTestClient(t, mockConn)
+
+	item, err := client.GetWithOptions(context.Background(), "testkey", GetOptions{
+		ReturnCAS:        true,
+		ReturnTTL:        true,
+		ReturnLastAccess: true,
+		ReturnSize:       true,
+		ReturnHit:        true,
+	})
+
+	require.NoError(t, err)
+	assertRequest(t, mockConn, "mg testkey v f c t l s h\r\n")
+	assert.Equal(t, Item{
+		Key:        "testkey",
+		Value:      []byte("hello"),
+		TTL:        ExpiresIn(60 * time.Second),
+		Found:      true,
+		CAS:        42,
+		Flags:      7,
+		LastAccess: 3 * time.Second,
+		Size:       5,
+		Hit:        true,
+	}, item)
+}
+
+func TestClient_GetWithOptions_NoExpiration(t *testing.T) {
+	mockConn := testutils.NewConnectionMock("VA 1 t-1\r\nx\r\n")
+	client := newTestClient(t, mockConn)
+
+	item, err := client.GetWithOptions(context.Background(), "testkey", GetOptions{ReturnTTL: true})
+
+	require.NoError(t, err)
+	assertRequest(t, mockConn, "mg testkey v f t\r\n")
+	assert.Equal(t, NoTTL, item.TTL)
+}
+
+func TestClient_GetWithOptions_Expiring(t *testing.T) {
+	mockConn := testutils.NewConnectionMock("VA 1 t0\r\nx\r\n")
+	client := newTestClient(t, mockConn)
+
+	item, err := client.GetWithOptions(context.Background(), "testkey", GetOptions{ReturnTTL: true})
+
+	require.NoError(t, err)
+	assert.Equal(t, ExpiresIn(time.Second), item.TTL, "expiring within the second")
+}
+
+func TestClient_Update_Expiring(t *testing.T) {
+	mockConn := testutils.NewConnectionMock("VA 1 c5 t0\r\n1\r\n", "HD\r\n")
+	client := newTestClient(t, mockConn)
+
+	err := client.Update(context.Background(), "testkey", func(old []byte) ([]byte, error) {
+		return []byte("2"), nil
+	})
+
+	require.NoError(t, err)
+	// The write-back keeps the item expiring, instead of storing it without
+	// expiration.
+	assertRequest(t, mockConn, "mg testkey v f c t\r\nms testkey 1 C5 T1\r\n2\r\n")
+}
+
+func TestClient_GetIfNotModified(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("not modified", func(t *testing.T) {
+		mockConn := testutils.NewConnectionMock("HD c42 f7 s5\r\n")
+		client := newTestClient(t, mockConn)
+
+		item, modified, err := client.GetIfNotModified(ctx, "testkey", 42)
+
+		require.NoError(t, err)
+		assertRequest(t, mockConn, "mg testkey c f s\r\n")
+		assert.False(t, modified)
+		assert.Equal(t, Item{Key: "testkey", Found: true, CAS: 42, Flags: 7, Size: 5}, item)
+	})
+
+	t.Run("modified", func(t *testing.T) {
+		mockConn := testutils.NewConnectionMock("HD c43 f7 s5\r\n", "VA 5 c43 f7\r\nworld\r\n")
+		client := newTestClient(t, mockConn)
+
+		item, modified, err := client.GetIfNotModified(ctx, "testkey", 42)
+
+		require.NoError(t, err)
+		assertRequest(t, mockConn, "mg testkey c f s\r\nmg testkey v f c\r\n")
+		assert.True(t, modified)
+		assert.Equal(t, Item{Key: "testkey", Value: []byte("world"), Found: true, CAS: 43, Flags: 7}, item)
+	})
+
+	t.Run("removed", func(t *testing.T) {
+		mockConn := testutils.NewConnectionMock("EN\r\n")
+		client := newTestClient(t, mockConn)
+
+		item, modified, err := client.GetIfNotModified(ctx, "testkey", 42)
+
+		require.NoError(t, err)
+		assert.True(t, modified)
+		assert.False(t, item.Found)
+	})
+}
+
+func TestClient_TTL(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("hit", func(t *testing.T) {
+		mockConn := testutils.NewConnectionMock("HD t60\r\n")
+		client := newTestClient(t, mockConn)
+
+		ttl, found, err := client.TTL(ctx, "testkey")
+
+		require.NoError(t, err)
+		assertRequest(t, mockConn, "mg testkey t\r\n")
+		assert.True(t, found)
+		assert.Equal(t, 60*time.Second, ttl)
+	})
+
+	t.Run("no expiration", func(t *testing.T) {
+		mockConn := testutils.NewConnectionMock("HD t-1\r\n")
+		client := newTestClient(t, mockConn)
+
+		ttl, found, err := client.TTL(ctx, "testkey")
+
+		require.NoError(t, err)
+		assert.True(t, found)
+		assert.Equal(t, -time.Second, ttl)
+	})
+
+	t.Run("miss", func(t *testing.T) {
+		mockConn := testutils.NewConnectionMock("EN\r\n")
+		client := newTestClient(t, mockConn)
+
+		ttl, found, err := client.TTL(ctx, "testkey")
+
+		require.NoError(t, err)
+		assert.False(t, found)
+		assert.Zero(t, ttl)
+	})
+}
+
+func TestClient_Exists(t *testing.T) {
+	mockConn := testutils.NewConnectionMock("HD\r\n", "EN\r\n")
+	client := newTestClient(t, mockConn)
+
+	exists, err := client.Exists(context.Background(), "testkey")
+	require.NoError(t, err)
+	assert.True(t, exists)
+
+	exists, err = client.Exists(context.Background(), "testkey", WithNoLRUBump())
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	assertRequest(t, mockConn, "mg testkey u\r\nmg testkey u\r\n")
+}
+
+func TestClient_Metadata(t *testing.T) {
+	mockConn := testutils.NewConnectionMock("HD f7 c42 t60 l5 s1048576\r\n", "EN\r\n")
+	client := newTestClient(t, mockConn)
+
+	item, err := client.Metadata(context.Background(), "testkey")
+	require.NoError(t, err)
+	assert.Equal(t, Item{
+		Key:        "testkey",
+		Found:      true,
+		Flags:      7,
+		CAS:        42,
+		TTL:        ExpiresIn(60 * time.Second),
+		LastAccess: 5 * time.Second,
+		Size:       1048576,
+	}, item)
+
+	item, err = client.Metadata(context.Background(), "testkey")
+	require.NoError(t, err)
+	assert.False(t, item.Found)
+
+	assertRequest(t, mockConn, "mg testkey f c t l s u\r\nmg testkey f c t l s u\r\n")
+}
+
+func TestClient_PrepareGet(t *testing.T) {
+	mockConn := testutils.NewConnectionMock("VA 5 c42\r\nhello\r\n", "EN\r\n")
+	client := newTestClient(t, mockConn)
+
+	hot := client.PrepareGetWithOptions("testkey", GetOptions{ReturnCAS: true})
+	item, err := hot.Do(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, Item{Key: "testkey", Value: []byte("hello"), Found: true, CAS: 42}, item)
+
+	item, err = hot.Do(context.Background())
+	require.NoError(t, err)
+	assert.False(t, item.Found)
+	assertRequest(t, mockConn, "mg testkey v f c\r\nmg testkey v f c\r\n")
+
+	_, err = client.PrepareGet("bad key").Do(context.Background())
+	var keyErr *meta.InvalidKeyError
+	assert.ErrorAs(t, err, &keyErr)
 }
 
 // =============================================================================
@@ -149,6 +361,16 @@ func TestClient_Set_Success_WithTTL(t *testing.T) {
 	assertRequest(t, mockConn, "ms key 5 T60\r\nvalue\r\n")
 }
 
+func TestClient_Set_ClientFlags(t *testing.T) {
+	mockConn := testutils.NewConnectionMock("HD\r\n")
+	client := newTestClient(t, mockConn)
+
+	err := client.Set(context.Background(), Item{Key: "key", Value: []byte("value"), Flags: 42})
+
+	require.NoError(t, err)
+	assertRequest(t, mockConn, "ms key 5 F42\r\nvalue\r\n")
+}
+
 func TestClient_Set_EmptyValue(t *testing.T) {
 	mockConn := testutils.NewConnectionMock("HD\r\n")
 	client := newTestClient(t, mockConn)
@@ -205,7 +427,7 @@ func TestClient_Set_NotStored(t *testing.T) {
 		Value: []byte("value"),
 	})
 
-	require.Error(t, err)
+	require.ErrorIs(t, err, ErrNotStored)
 	assert.Contains(t, err.Error(), "set failed with status: NS")
 }
 
@@ -289,6 +511,7 @@ func TestClient_Add_AlreadyExists(t *testing.T) {
 	})
 
 	require.ErrorIs(t, err, ErrNotStored)
+	require.ErrorIs(t, err, ErrKeyExists)
 	assert.Contains(t, err.Error(), "key already exists")
 }
 
@@ -306,6 +529,16 @@ func TestClient_Add_WithTTL(t *testing.T) {
 	assertRequest(t, mockConn, "ms key 5 ME T60\r\nvalue\r\n")
 }
 
+func TestClient_Add_ClientFlags(t *testing.T) {
+	mockConn := testutils.NewConnectionMock("HD\r\n")
+	client := newTestClient(t, mockConn)
+
+	err := client.Add(context.Background(), Item{Key: "key", Value: []byte("value"), Flags: 1 << 31})
+
+	require.NoError(t, err)
+	assertRequest(t, mockConn, "ms key 5 ME F2147483648\r\nvalue\r\n")
+}
+
 func TestClient_Add_ServerError(t *testing.T) {
 	mockConn := testutils.NewConnectionMock("SERVER_ERROR out of memory\r\n")
 	client := newTestClient(t, mockConn)
@@ -319,6 +552,30 @@ func TestClient_Add_ServerError(t *testing.T) {
 	assert.Contains(t, err.Error(), "SERVER_ERROR")
 }
 
+func TestClient_MaxItemSize(t *testing.T) {
+	mockConn := testutils.NewConnectionMock("HD\r\n")
+	client := NewClient(StaticServers("localhost:11211"), Config{
+		Dialer:      &mockDialer{conn: mockConn},
+		MaxItemSize: 4,
+	})
+	t.Cleanup(client.Close)
+	ctx := context.Background()
+
+	err := client.Set(ctx, Item{Key: "key", Value: []byte("12345")})
+	require.ErrorIs(t, err, ErrValueTooLarge)
+
+	err = client.Add(ctx, Item{Key: "key", Value: []byte("12345")})
+	require.ErrorIs(t, err, ErrValueTooLarge)
+
+	_, err = client.ExecuteBatch(ctx, []*meta.Request{meta.Set("key", []byte("12345"))})
+	require.ErrorIs(t, err, ErrValueTooLarge)
+
+	assertRequest(t, mockConn, "") // nothing was sent
+
+	require.NoError(t, client.Set(ctx, Item{Key: "key", Value: []byte("1234")}))
+	assertRequest(t, mockConn, "ms key 4\r\n1234\r\n")
+}
+
 // =============================================================================
 // Delete Tests
 // =============================================================================
@@ -349,10 +606,33 @@ func TestClient_Delete_UnexpectedStatus(t *testing.T) {
 
 	err := client.Delete(context.Background(), "key")
 
-	require.Error(t, err)
+	require.ErrorIs(t, err, ErrNotStored)
 	assert.Contains(t, err.Error(), "delete failed with status: NS")
 }
 
+func TestStatusError_Is(t *testing.T) {
+	tests := []struct {
+		status meta.StatusType
+		want   error
+	}{
+		{meta.StatusNS, ErrNotStored},
+		{meta.StatusEX, ErrCASConflict},
+		{meta.StatusNF, ErrNotFound},
+	}
+	for _, tt := range tests {
+		err := fmt.Errorf("wrapped: %w", &StatusError{Op: "set", Status: tt.status})
+		assert.ErrorIs(t, err, tt.want, "status %s", tt.status)
+		for _, other := range []error{ErrNotStored, ErrCASConflict, ErrNotFound} {
+			if other != tt.want {
+				assert.NotErrorIs(t, err, other, "status %s", tt.status)
+			}
+		}
+	}
+
+	assert.NotErrorIs(t, &StatusError{Op: "get", Status: meta.StatusHD}, ErrNotStored)
+	assert.Equal(t, "memcache: set failed for key k with status: EX", (&StatusError{Op: "set", Key: "k", Status: meta.StatusEX}).Error())
+}
+
 func TestClient_Delete_ServerError(t *testing.T) {
 	mockConn := testutils.NewConnectionMock("SERVER_ERROR out of memory\r\n")
 	client := newTestClient(t, mockConn)
@@ -363,6 +643,29 @@ func TestClient_Delete_ServerError(t *testing.T) {
 	assert.Contains(t, err.Error(), "SERVER_ERROR")
 }
 
+func TestClient_DeleteWithOptions_CAS(t *testing.T) {
+	mockConn := testutils.NewConnectionMock("EX\r\n")
+	client := newTestClient(t, mockConn)
+
+	err := client.DeleteWithOptions(context.Background(), "key", DeleteOptions{CAS: 123})
+
+	require.ErrorIs(t, err, ErrCASConflict)
+	assertRequest(t, mockConn, "md key C123\r\n")
+}
+
+func TestClient_DeleteWithOptions_Invalidate(t *testing.T) {
+	mockConn := testutils.NewConnectionMock("HD\r\n")
+	client := newTestClient(t, mockConn)
+
+	err := client.DeleteWithOptions(context.Background(), "key", DeleteOptions{
+		Invalidate: true,
+		StaleTTL:   30 * time.Second,
+	})
+
+	require.NoError(t, err)
+	assertRequest(t, mockConn, "md key I T30\r\n")
+}
+
 // =============================================================================
 // Increment Tests - Positive Delta
 // =============================================================================
@@ -464,6 +767,37 @@ func TestClient_Increment_LargeNegativeDelta(t *testing.T) {
 	assertRequest(t, mockConn, "ma key v D1000000 MD J0 N0\r\n")
 }
 
+func TestClient_IncrementWithOptions(t *testing.T) {
+	mockConn := testutils.NewConnectionMock("VA 2 c99\r\n10\r\n")
+	client := newTestClient(t, mockConn)
+
+	value, cas, err := client.IncrementWithOptions(context.Background(), "key", IncrementOptions{
+		Delta:         2,
+		Mode:          ModeDecrement,
+		AutoCreate:    true,
+		Initial:       10,
+		AutoCreateTTL: ExpiresIn(time.Minute),
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, uint64(10), value)
+	assert.Equal(t, uint64(99), cas)
+	assertRequest(t, mockConn, "ma key v D2 MD J10 N60 c\r\n")
+}
+
+func TestClient_IncrementWithOptions_NotFound(t *testing.T) {
+	mockConn := testutils.NewConnectionMock("NF\r\n")
+	client := newTestClient(t, mockConn)
+
+	_, _, err := client.IncrementWithOptions(context.Background(), "key", IncrementOptions{
+		Delta: 1,
+		TTL:   ExpiresIn(time.Minute),
+	})
+
+	require.ErrorIs(t, err, ErrNotFound)
+	assertRequest(t, mockConn, "ma key v D1 T60 c\r\n")
+}
+
 // =============================================================================
 // Increment Tests - Error Cases
 // =============================================================================
@@ -512,6 +846,73 @@ func TestClient_Increment_ClientError_NonNumeric(t *testing.T) {
 // Multi-Pool Tests
 // =============================================================================
 
+func TestClient_Ping(t *testing.T) {
+	mockConn := testutils.NewConnectionMock("MN\r\n")
+	dialErr := errors.New("connection refused")
+	client := NewClient(StaticServers("good:11211", "bad:11211"), Config{
+		Dialer: DialerFunc(func(ctx context.Context, network, address string) (net.Conn, error) {
+			if address == "bad:11211" {
+				return nil, dialErr
+			}
+			return mockConn, nil
+		}),
+	})
+	t.Cleanup(client.Close)
+
+	results, err := client.Ping(context.Background())
+
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	assert.Equal(t, "good:11211", results[0].Addr)
+	assert.NoError(t, results[0].Error)
+	assert.Equal(t, "bad:11211", results[1].Addr)
+	assert.ErrorIs(t, results[1].Error, dialErr)
+	assertRequest(t, mockConn, "mn\r\n")
+	assert.Len(t, client.PoolMetrics(), 2, "pools are created for untouched servers")
+}
+
+func TestClient_Connect(t *testing.T) {
+	dialErr := errors.New("connection refused")
+	client := NewClient(StaticServers("good:11211", "bad:11211"), Config{
+		Dialer: DialerFunc(func(ctx context.Context, network, address string) (net.Conn, error) {
+			if address == "bad:11211" {
+				return nil, dialErr
+			}
+			return testutils.NewConnectionMock("MN\r\n"), nil
+		}),
+	})
+	t.Cleanup(client.Close)
+
+	err := client.Connect(context.Background())
+
+	require.ErrorIs(t, err, dialErr)
+	var opErr *OpError
+	require.ErrorAs(t, err, &opErr)
+	assert.Equal(t, "bad:11211", opErr.Server)
+	assert.NotContains(t, err.Error(), "good:11211")
+
+	t.Run("all servers up", func(t *testing.T) {
+		client := newTestClient(t, testutils.NewConnectionMock("MN\r\n"))
+		require.NoError(t, client.Connect(context.Background()))
+	})
+}
+
+func TestClient_Ping_WeightedServers(t *testing.T) {
+	dialErr := errors.New("connection refused")
+	client := NewClient(WeightedServers(
+		WeightedServer{Addr: "server1:11211", Weight: 2},
+		WeightedServer{Addr: "server2:11211"},
+	), Config{Dialer: &mockDialer{error: dialErr}})
+	t.Cleanup(client.Close)
+
+	results, err := client.Ping(context.Background())
+
+	require.NoError(t, err)
+	require.Len(t, results, 2, "one result per server, whatever its weight")
+	assert.Equal(t, "server1:11211", results[0].Addr)
+	assert.Equal(t, "server2:11211", results[1].Addr)
+}
+
 func TestClient_MultiPool_LazyPoolCreation(t *testing.T) {
 	// Test that pools are created lazily only when keys are accessed
 	servers := StaticServers("server1:11211", "server2:11211", "server3:11211")
@@ -648,3 +1049,154 @@ func TestClient_MultiPool_CustomSelectServer(t *testing.T) {
 	assert.Len(t, allPoolMetrics, 1, "Should have only one pool since all keys go to first server")
 	assert.Equal(t, "server1:11211", allPoolMetrics[0].Addr)
 }
+
+func TestClient_DialerFunc(t *testing.T) {
+	mockConn := testutils.NewConnectionMock("EN\r\n")
+	var dialed []string
+
+	client := NewClient(StaticServers("server1:11211"), Config{
+		Dialer: DialerFunc(func(ctx context.Context, network, address string) (net.Conn, error) {
+			dialed = append(dialed, network+"://"+address)
+			return mockConn, nil
+		}),
+	})
+	t.Cleanup(client.Close)
+
+	_, err := client.Get(context.Background(), "key")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"tcp://server1:11211"}, dialed)
+}
+
+func TestClient_MinSize(t *testing.T) {
+	var mu sync.Mutex
+	dialed := map[string]int{}
+
+	client := NewClient(StaticServers("server1:11211", "server2:11211"), Config{
+		MaxSize:             5,
+		MinSize:             3,
+		HealthCheckInterval: 10 * time.Millisecond,
+		Dialer: DialerFunc(func(ctx context.Context, network, address string) (net.Conn, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			dialed[address]++
+			return testutils.NewConnectionMock("MN\r\nMN\r\nMN\r\n"), nil
+		}),
+	})
+	t.Cleanup(client.Close)
+
+	// Pools are created and filled at startup, without any operation.
+	require.Eventually(t, func() bool {
+		metrics := client.PoolMetrics()
+		return len(metrics) == 2 && metrics[0].Conns.IdleConns == 3 && metrics[1].Conns.IdleConns == 3
+	}, time.Second, 5*time.Millisecond)
+
+	// Health checks exhaust the mock responses and prune the connections:
+	// the pools are topped up again.
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return dialed["server1:11211"] > 3 && dialed["server2:11211"] > 3
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestClient_PerServer(t *testing.T) {
+	client := NewClient(StaticServers("server1:11211", "server2:11211"), Config{
+		MaxSize: 5,
+		Timeout: time.Second,
+		PerServer: map[string]PoolOverrides{
+			"server2:11211": {MaxSize: 20, MinSize: 2, ConnectTimeout: 3 * time.Second},
+		},
+		Dialer: DialerFunc(func(ctx context.Context, network, address string) (net.Conn, error) {
+			return testutils.NewConnectionMock(), nil
+		}),
+	})
+	t.Cleanup(client.Close)
+
+	// Only server2 has a MinSize: its pool is created at startup.
+	require.Eventually(t, func() bool {
+		metrics := client.PoolMetrics()
+		return len(metrics) == 1 && metrics[0].Conns.IdleConns == 2
+	}, time.Second, 5*time.Millisecond)
+
+	sp1, err := client.getPoolForServer("server1:11211")
+	require.NoError(t, err)
+	sp2, err := client.getPoolForServer("server2:11211")
+	require.NoError(t, err)
+
+	assert.Equal(t, int32(5), sp1.maxSize)
+	assert.Equal(t, int32(0), sp1.settings.Load().minSize)
+	assert.Equal(t, int32(20), sp2.maxSize)
+	assert.Equal(t, int32(2), sp2.settings.Load().minSize)
+}
+
+func TestConfig_ForServer(t *testing.T) {
+	config := Config{
+		MaxSize: 10,
+		Timeout: time.Second,
+		PerServer: map[string]PoolOverrides{
+			"slow:11211": {Timeout: 5 * time.Second, MinSize: 50},
+		},
+	}
+
+	c := config.forServer("other:11211")
+	assert.Equal(t, time.Second, c.Timeout)
+	assert.Equal(t, time.Second, c.ConnectTimeout, "ConnectTimeout defaults to Timeout")
+
+	c = config.forServer("slow:11211")
+	assert.Equal(t, 5*time.Second, c.Timeout)
+	assert.Equal(t, 5*time.Second, c.ConnectTimeout, "ConnectTimeout defaults to the overridden Timeout")
+	assert.Equal(t, int32(10), c.MinSize, "MinSize is capped at MaxSize")
+}
+
+func TestClient_WithRawConnection(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("sequence", func(t *testing.T) {
+		mockConn := testutils.NewConnectionMock("HD\r\n", "VA 5\r\nhello\r\n")
+		client := newTestClient(t, mockConn)
+
+		var resp *meta.Response
+		err := client.WithRawConnection(ctx, "testkey", func(conn *Connection) error {
+			_, err := conn.Execute(ctx, meta.NewRequest(meta.CmdSet, "testkey", []byte("hello")))
+			if err != nil {
+				return err
+			}
+			resp, err = conn.Execute(ctx, meta.NewRequest(meta.CmdGet, "testkey", nil).AddReturnValue())
+			return err
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, "hello", string(resp.Data))
+		assertRequest(t, mockConn, "ms testkey 5\r\nhello\r\nmg testkey v\r\n")
+		assert.Equal(t, int32(1), client.PoolMetrics()[0].Conns.IdleConns, "the connection should be released")
+	})
+
+	t.Run("error closes the connection", func(t *testing.T) {
+		mockConn := testutils.NewConnectionMock()
+		client := newTestClient(t, mockConn)
+		failure := errors.New("custom failure")
+
+		err := client.WithRawConnection(ctx, "testkey", func(conn *Connection) error {
+			return failure
+		})
+
+		assert.ErrorIs(t, err, failure)
+		assert.Eventually(t, func() bool {
+			return client.PoolMetrics()[0].Conns.DestroyedConns == 1
+		}, time.Second, time.Millisecond, "the connection should be destroyed")
+	})
+
+	t.Run("pipelined mode", func(t *testing.T) {
+		client := NewClient(StaticServers("localhost:11211"), Config{
+			Dialer:        &mockDialer{conn: testutils.NewConnectionMock()},
+			PipelineConns: 1,
+		})
+		defer client.Close()
+
+		err := client.WithRawConnection(ctx, "testkey", func(conn *Connection) error {
+			t.Fatal("fn should not be called")
+			return nil
+		})
+		assert.ErrorIs(t, err, errRawPipelined)
+	})
+}
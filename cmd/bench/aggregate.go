@@ -12,6 +12,15 @@ type OpResult struct {
 	OpsPerSec    float64 `json:"ops_per_sec"`
 	ItemsPerSec  float64 `json:"items_per_sec"`
 	AvgLatencyNs int64   `json:"avg_latency_ns"`
+
+	// Latency is the distribution of the latencies of all the runs. It is
+	// missing from the reports of older versions.
+	Latency *LatencyDistribution `json:"latency,omitempty"`
+
+	// AllocsPerOp and BytesPerOp are the heap allocations of the process per
+	// operation, sampled with runtime.MemStats around each run.
+	AllocsPerOp float64 `json:"allocs_per_op"`
+	BytesPerOp  float64 `json:"bytes_per_op"`
 }
 
 // BenchmarkReport is the machine-readable result of one benchmark run, suitable
@@ -12,15 +12,15 @@ import (
 
 // Client interface for both clients
 type Client interface {
-	Get(ctx context.Context, key string) (memcache.Item, error)
-	Set(ctx context.Context, item memcache.Item) error
-	Delete(ctx context.Context, key string) error
-	Increment(ctx context.Context, key string, delta int64, ttl memcache.TTL) (int64, error)
+	Get(ctx context.Context, key string, opts ...memcache.CallOption) (memcache.Item, error)
+	Set(ctx context.Context, item memcache.Item, opts ...memcache.CallOption) error
+	Delete(ctx context.Context, key string, opts ...memcache.CallOption) error
+	Increment(ctx context.Context, key string, delta int64, ttl memcache.TTL, opts ...memcache.CallOption) (int64, error)
 	Close()
 }
 
-func createClient(config Config) (Client, *memcache.BatchCommands) {
-	if config.bradfitz {
+func createClient(config Config, name string) (Client, *memcache.BatchCommands) {
+	if name == "bradfitz" {
 		bradfitzCli := bradfitz.New(config.addr)
 		bradfitzCli.MaxIdleConns = config.concurrency * 2
 		bradfitzWrapper := &bradfitzClient{bradfitzCli}
@@ -49,7 +49,7 @@ type bradfitzClient struct {
 	*bradfitz.Client
 }
 
-func (c *bradfitzClient) Get(ctx context.Context, key string) (memcache.Item, error) {
+func (c *bradfitzClient) Get(ctx context.Context, key string, _ ...memcache.CallOption) (memcache.Item, error) {
 	item, err := c.Client.Get(key)
 	if err == bradfitz.ErrCacheMiss {
 		return memcache.Item{Key: key, Found: false}, nil
@@ -64,7 +64,7 @@ func (c *bradfitzClient) Get(ctx context.Context, key string) (memcache.Item, er
 	}, nil
 }
 
-func (c *bradfitzClient) Set(ctx context.Context, item memcache.Item) error {
+func (c *bradfitzClient) Set(ctx context.Context, item memcache.Item, _ ...memcache.CallOption) error {
 	// bradfitz's Expiration uses the same encoding as TTL.Expiration:
 	// 0 for no expiration, relative seconds, or an absolute unix timestamp.
 	return c.Client.Set(&bradfitz.Item{
@@ -74,7 +74,7 @@ func (c *bradfitzClient) Set(ctx context.Context, item memcache.Item) error {
 	})
 }
 
-func (c *bradfitzClient) Delete(ctx context.Context, key string) error {
+func (c *bradfitzClient) Delete(ctx context.Context, key string, _ ...memcache.CallOption) error {
 	err := c.Client.Delete(key)
 	if err == bradfitz.ErrCacheMiss {
 		return nil // Delete is successful even if key doesn't exist
@@ -82,7 +82,7 @@ func (c *bradfitzClient) Delete(ctx context.Context, key string) error {
 	return err
 }
 
-func (c *bradfitzClient) Increment(ctx context.Context, key string, delta int64, ttl memcache.TTL) (int64, error) {
+func (c *bradfitzClient) Increment(ctx context.Context, key string, delta int64, ttl memcache.TTL, _ ...memcache.CallOption) (int64, error) {
 	var value uint64
 	var err error
 
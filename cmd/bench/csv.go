@@ -0,0 +1,49 @@
+package main
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+)
+
+// writeCSV writes the results of the reports as CSV, a row per client and
+// operation with the latency percentiles. The histogram is only in the JSON
+// report.
+func writeCSV(w io.Writer, reports ...BenchmarkReport) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{
+		"client", "operation", "items_per_op", "ops_per_sec", "items_per_sec", "avg_latency_ns",
+		"min_ns", "p50_ns", "p90_ns", "p99_ns", "p999_ns", "max_ns", "allocs_per_op", "bytes_per_op",
+	})
+
+	for _, report := range reports {
+		for _, r := range report.Results {
+			cw.Write(csvRow(report.Client, r))
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+func csvRow(client string, r OpResult) []string {
+	row := []string{
+		client,
+		r.Name,
+		strconv.Itoa(r.ItemsPerOp),
+		strconv.FormatFloat(r.OpsPerSec, 'f', 2, 64),
+		strconv.FormatFloat(r.ItemsPerSec, 'f', 2, 64),
+		strconv.FormatInt(r.AvgLatencyNs, 10),
+	}
+	if l := r.Latency; l != nil {
+		for _, ns := range []int64{l.MinNs, l.P50Ns, l.P90Ns, l.P99Ns, l.P999Ns, l.MaxNs} {
+			row = append(row, strconv.FormatInt(ns, 10))
+		}
+	} else {
+		row = append(row, "", "", "", "", "", "")
+	}
+	return append(row,
+		strconv.FormatFloat(r.AllocsPerOp, 'f', 2, 64),
+		strconv.FormatFloat(r.BytesPerOp, 'f', 0, 64),
+	)
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"math/bits"
+	"slices"
+	"time"
+)
+
+// LatencyDistribution is the distribution of the latencies of the operations
+// of a test, across all the runs.
+type LatencyDistribution struct {
+	MinNs  int64 `json:"min_ns"`
+	P50Ns  int64 `json:"p50_ns"`
+	P90Ns  int64 `json:"p90_ns"`
+	P99Ns  int64 `json:"p99_ns"`
+	P999Ns int64 `json:"p999_ns"`
+	MaxNs  int64 `json:"max_ns"`
+
+	// Histogram counts the operations per power-of-two latency bucket, from
+	// the bucket of the fastest operation to the bucket of the slowest.
+	Histogram []LatencyBucket `json:"histogram"`
+}
+
+// LatencyBucket counts the operations slower than the previous bucket, up to
+// and including UpToNs.
+type LatencyBucket struct {
+	UpToNs int64 `json:"up_to_ns"`
+	Count  int64 `json:"count"`
+}
+
+// newLatencyDistribution computes the distribution of the latencies. The
+// slice is sorted in place.
+func newLatencyDistribution(latencies []time.Duration) *LatencyDistribution {
+	if len(latencies) == 0 {
+		return nil
+	}
+	slices.Sort(latencies)
+
+	d := &LatencyDistribution{
+		MinNs:  int64(latencies[0]),
+		P50Ns:  int64(percentile(latencies, 50)),
+		P90Ns:  int64(percentile(latencies, 90)),
+		P99Ns:  int64(percentile(latencies, 99)),
+		P999Ns: int64(percentile(latencies, 99.9)),
+		MaxNs:  int64(latencies[len(latencies)-1]),
+	}
+
+	for _, l := range latencies {
+		upTo := bucketUpperBound(l)
+		if n := len(d.Histogram); n > 0 && d.Histogram[n-1].UpToNs == upTo {
+			d.Histogram[n-1].Count++
+			continue
+		}
+		d.Histogram = append(d.Histogram, LatencyBucket{UpToNs: upTo, Count: 1})
+	}
+	return d
+}
+
+// percentile returns the nearest-rank percentile p of sorted latencies.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	rank := int(p / 100 * float64(len(sorted)))
+	if rank >= len(sorted) {
+		rank = len(sorted) - 1
+	}
+	return sorted[rank]
+}
+
+// bucketUpperBound returns the smallest power of two of nanoseconds that is
+// greater than or equal to l.
+func bucketUpperBound(l time.Duration) int64 {
+	if l <= 1 {
+		return 1
+	}
+	return 1 << bits.Len64(uint64(l-1))
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewLatencyDistribution(t *testing.T) {
+	// 1µs to 1000µs, shuffled.
+	latencies := make([]time.Duration, 1000)
+	for i := range latencies {
+		latencies[i] = time.Duration((i*7919)%1000+1) * time.Microsecond
+	}
+
+	d := newLatencyDistribution(latencies)
+
+	got := []int64{d.MinNs, d.P50Ns, d.P90Ns, d.P99Ns, d.P999Ns, d.MaxNs}
+	want := []int64{1000, 501000, 901000, 991000, 1000000, 1000000}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("min, p50, p90, p99, p999, max = %v, want %v", got, want)
+	}
+
+	var total int64
+	for i, b := range d.Histogram {
+		total += b.Count
+		if i > 0 && b.UpToNs != 2*d.Histogram[i-1].UpToNs {
+			t.Errorf("bucket %d: up to %d after %d, want consecutive powers of two", i, b.UpToNs, d.Histogram[i-1].UpToNs)
+		}
+	}
+	if total != 1000 {
+		t.Errorf("histogram counts %d operations, want 1000", total)
+	}
+	if first := d.Histogram[0]; first != (LatencyBucket{UpToNs: 1024, Count: 1}) {
+		t.Errorf("first bucket = %+v", first)
+	}
+
+	if newLatencyDistribution(nil) != nil {
+		t.Error("no latencies must give no distribution")
+	}
+}
+
+func TestBucketUpperBound(t *testing.T) {
+	for l, want := range map[time.Duration]int64{0: 1, 1: 1, 2: 2, 3: 4, 1024: 1024, 1025: 2048} {
+		if got := bucketUpperBound(l); got != want {
+			t.Errorf("bucketUpperBound(%d) = %d, want %d", l, got, want)
+		}
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	report := BenchmarkReport{Client: "pior", Results: []OpResult{
+		{Name: "set", ItemsPerOp: 1, OpsPerSec: 1234.5, ItemsPerSec: 1234.5, AvgLatencyNs: 810, Latency: &LatencyDistribution{
+			MinNs: 100, P50Ns: 700, P90Ns: 900, P99Ns: 2000, P999Ns: 5000, MaxNs: 9000,
+		}, AllocsPerOp: 3.5, BytesPerOp: 128},
+		{Name: "get-miss", ItemsPerOp: 1, OpsPerSec: 10, ItemsPerSec: 10, AvgLatencyNs: 1e8},
+	}}
+
+	var b strings.Builder
+	if err := writeCSV(&b, report); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "client,operation,items_per_op,ops_per_sec,items_per_sec,avg_latency_ns,min_ns,p50_ns,p90_ns,p99_ns,p999_ns,max_ns,allocs_per_op,bytes_per_op\n" +
+		"pior,set,1,1234.50,1234.50,810,100,700,900,2000,5000,9000,3.50,128\n" +
+		"pior,get-miss,1,10.00,10.00,100000000,,,,,,,0.00,0\n"
+	if b.String() != want {
+		t.Errorf("csv =\n%s\nwant\n%s", b.String(), want)
+	}
+}
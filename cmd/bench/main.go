@@ -8,6 +8,9 @@ import (
 	"log"
 	"math/rand/v2"
 	"os"
+	"runtime"
+	"slices"
+	"strings"
 	"sync"
 	"time"
 
@@ -18,10 +21,15 @@ type Test struct {
 	Name       string
 	ItemsPerOp int // Number of items processed per operation (1 for single ops, 10 for batch-10, etc.)
 	Operation  OperationFunc
+	Raw        RawOperationFunc // Set instead of Operation for the tests of the protocol layer
 }
 
 type OperationFunc func(ctx context.Context, client Client, batchCmd *memcache.BatchCommands, uid int64, workerID int, operationID int64) error
 
+// RawOperationFunc runs an operation on a connection of the worker, without
+// the client.
+type RawOperationFunc func(conn *rawConn, uid int64, workerID int, operationID int64) error
+
 type Result struct {
 	name        string
 	count       int64
@@ -30,6 +38,9 @@ type Result struct {
 	opsPerSec   float64
 	itemsPerSec float64
 	avgLatency  time.Duration
+	latencies   []time.Duration // of each operation
+	allocsPerOp float64
+	bytesPerOp  float64
 }
 
 type Config struct {
@@ -40,6 +51,8 @@ type Config struct {
 	count       int64
 	only        string
 	runs        int
+	batchSizes  []int
+	workload    *Workload // the mixed operation, when set
 }
 
 // info writes progress and diagnostics to stderr so that stdout carries only
@@ -51,12 +64,22 @@ func info(format string, args ...any) {
 func main() {
 	config := Config{}
 	flag.StringVar(&config.addr, "addr", "127.0.0.1:11211", "memcache server address")
-	flag.BoolVar(&config.bradfitz, "bradfitz", false, "use bradfitz client implementation (default is pior)")
+	clientList := flag.String("client", "pior", "comma-separated clients to benchmark, compared side by side: pior or bradfitz")
+	flag.BoolVar(&config.bradfitz, "bradfitz", false, "same as -client bradfitz (deprecated)")
 	flag.StringVar(&config.pool, "pool", "puddle", "pool implementation for pior client: channel or puddle")
 	flag.IntVar(&config.concurrency, "concurrency", 1, "number of concurrent workers")
 	flag.Int64Var(&config.count, "count", 1_000_000, "target operation count")
 	flag.StringVar(&config.only, "only", "", "run only the specified operation (e.g., 'Set')")
 	flag.IntVar(&config.runs, "runs", 1, "repeat the suite N times; reported numbers are a trimmed mean (drop fastest+slowest)")
+	var workload Workload
+	mix := flag.String("mix", "", "run the mixed operation with this weighted mix, e.g. get=90,set=10 (get, set, delete)")
+	flag.IntVar(&workload.Keys, "keys", 100_000, "mixed operation: size of the keyspace")
+	flag.StringVar(&workload.KeyDist, "key-dist", "uniform", "mixed operation: key distribution: uniform or zipf")
+	flag.Float64Var(&workload.ZipfS, "zipf-s", 1.1, "mixed operation: zipf exponent (> 1), the larger the more skewed")
+	flag.StringVar(&workload.SizeDist, "value-dist", "fixed", "mixed operation: value size distribution: fixed or lognormal")
+	flag.IntVar(&workload.Size, "value-size", 100, "mixed operation: value size, or median of the lognormal distribution, in bytes")
+	flag.Float64Var(&workload.Sigma, "value-sigma", 1, "mixed operation: standard deviation of the log of the lognormal value sizes")
+	batchSizes := flag.String("batch-sizes", "10", "comma-separated batch sizes of the multi-get, multi-set and raw pipeline operations")
 
 	var (
 		format    string
@@ -64,7 +87,7 @@ func main() {
 		compare   string
 		threshold float64
 	)
-	flag.StringVar(&format, "format", "text", "output format: text or json")
+	flag.StringVar(&format, "format", "text", "output format: text, json or csv")
 	flag.StringVar(&baseline, "baseline", "", "compare mode: comma-separated baseline (main) JSON reports, one per round; requires -compare")
 	flag.StringVar(&compare, "compare", "", "compare mode: comma-separated current (PR) JSON reports, one per round; requires -baseline")
 	flag.Float64Var(&threshold, "threshold", 10, "compare mode: percent change to flag in the comparison table")
@@ -83,22 +106,43 @@ func main() {
 	if config.runs < 1 {
 		log.Fatalf("-runs must be >= 1")
 	}
-	if format != "text" && format != "json" {
-		log.Fatalf("invalid -format: %s (must be 'text' or 'json')", format)
+	if format != "text" && format != "json" && format != "csv" {
+		log.Fatalf("invalid -format: %s (must be 'text', 'json' or 'csv')", format)
+	}
+	var err error
+	if config.batchSizes, err = parseBatchSizes(*batchSizes); err != nil {
+		log.Fatalf("invalid -batch-sizes: %v", err)
+	}
+	if *mix != "" {
+		if workload.Mix, err = parseMix(*mix); err == nil {
+			err = workload.validate()
+		}
+		if err != nil {
+			log.Fatalf("invalid mixed operation: %v", err)
+		}
+		config.workload = &workload
 	}
 	if config.pool != "channel" && config.pool != "puddle" {
 		log.Fatalf("Invalid pool: %s (must be 'channel' or 'puddle')", config.pool)
 	}
 
-	clientName := "pior"
+	clients := strings.Split(*clientList, ",")
 	if config.bradfitz {
-		clientName = "bradfitz"
+		clients = []string{"bradfitz"}
+	}
+	for _, name := range clients {
+		if name != "pior" && name != "bradfitz" {
+			log.Fatalf("invalid -client: %s (must be 'pior' or 'bradfitz')", name)
+		}
+	}
+	if len(clients) > 1 && format == "json" {
+		log.Fatalf("-format json takes a single -client: run once per client")
 	}
 
 	info("Memcache Speed Test\n")
 	info("===================\n")
-	info("Client:      %s\n", clientName)
-	if !config.bradfitz {
+	info("Clients:     %s\n", strings.Join(clients, ", "))
+	if slices.Contains(clients, "pior") {
 		info("Pool:        %s\n", config.pool)
 	}
 	info("Server:      %s\n", config.addr)
@@ -106,14 +150,39 @@ func main() {
 	info("Runs:        %d\n", config.runs)
 	info("Target:      %s operations\n\n", formatNumber(config.count))
 
-	client, batchCmd := createClient(config)
-	defer client.Close()
+	var reports []BenchmarkReport
+	for _, name := range clients {
+		reports = append(reports, runSuite(context.Background(), config, name))
+	}
 
-	ctx := context.Background()
+	switch format {
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(reports[0]); err != nil {
+			log.Fatalf("encoding report: %v", err)
+		}
+	case "csv":
+		if err := writeCSV(os.Stdout, reports...); err != nil {
+			log.Fatalf("writing report: %v", err)
+		}
+	default:
+		if len(reports) == 1 {
+			printTextSummary(reports[0])
+		} else {
+			printClientComparison(os.Stdout, reports)
+		}
+	}
+}
+
+// runSuite runs the benchmark tests with a client.
+func runSuite(ctx context.Context, config Config, clientName string) BenchmarkReport {
+	client, batchCmd := createClient(config, clientName)
+	defer client.Close()
 
 	// Verify server is reachable before starting benchmarks.
 	preflightUID := rand.Int64N(1_000_000)
-	info("Verifying connection to %s...\n", config.addr)
+	info("Verifying connection to %s with the %s client...\n", config.addr, clientName)
 
 	testKey := fmt.Sprintf("test-%d-preflight", preflightUID)
 	err := client.Set(ctx, memcache.Item{Key: testKey, Value: []byte(testKey), TTL: memcache.ExpiresIn(1 * time.Second)})
@@ -138,7 +207,10 @@ func main() {
 		runUIDs[r] = rand.Int64N(1_000_000_000)
 	}
 
-	tests := benchmarkTests()
+	tests := benchmarkTests(config.batchSizes)
+	if config.workload != nil {
+		tests = append(tests, mixedTest(*config.workload, config.concurrency))
+	}
 
 	report := BenchmarkReport{
 		Client:      clientName,
@@ -147,7 +219,7 @@ func main() {
 		Count:       config.count,
 		Runs:        config.runs,
 	}
-	if !config.bradfitz {
+	if clientName == "pior" {
 		report.Pool = config.pool
 	}
 
@@ -155,6 +227,9 @@ func main() {
 		if config.only != "" && test.Name != config.only {
 			continue
 		}
+		if test.Raw != nil && clientName != "pior" {
+			continue // the raw tests don't use the client
+		}
 
 		info("Running: %s\n", test.Name)
 		res := runAggregated(ctx, client, batchCmd, config, runUIDs, test)
@@ -166,30 +241,28 @@ func main() {
 		report.Results = append(report.Results, res)
 	}
 
-	switch format {
-	case "json":
-		enc := json.NewEncoder(os.Stdout)
-		enc.SetIndent("", "  ")
-		if err := enc.Encode(report); err != nil {
-			log.Fatalf("encoding report: %v", err)
-		}
-	default:
-		printTextSummary(report)
-	}
-
 	printPiorClientStats(client)
+	return report
 }
 
 func printTextSummary(report BenchmarkReport) {
 	fmt.Printf("\n")
-	fmt.Printf("%-20s %12s %12s %12s %12s\n", "Operation", "Count", "Ops/sec", "Items/sec", "Avg Latency")
+	fmt.Printf("%-20s %12s %12s %12s %12s %12s %12s %12s %10s\n", "Operation", "Count", "Ops/sec", "Items/sec", "Avg Latency", "P50", "P99", "P99.9", "Allocs/op")
 	for _, result := range report.Results {
-		fmt.Printf("%-20s %12s %12s %12s %12s\n",
+		var p50, p99, p999 time.Duration
+		if result.Latency != nil {
+			p50, p99, p999 = time.Duration(result.Latency.P50Ns), time.Duration(result.Latency.P99Ns), time.Duration(result.Latency.P999Ns)
+		}
+		fmt.Printf("%-20s %12s %12s %12s %12s %12s %12s %12s %10.1f\n",
 			result.Name,
 			formatNumber(report.Count),
 			formatNumber(int64(result.OpsPerSec)),
 			formatNumber(int64(result.ItemsPerSec)),
 			formatDuration(time.Duration(result.AvgLatencyNs)),
+			formatDuration(p50),
+			formatDuration(p99),
+			formatDuration(p999),
+			result.AllocsPerOp,
 		)
 	}
 }
@@ -207,12 +280,18 @@ func runAggregated(
 	opsSamples := make([]float64, len(runUIDs))
 	itemsSamples := make([]float64, len(runUIDs))
 	latencySamples := make([]float64, len(runUIDs))
+	allocsSamples := make([]float64, len(runUIDs))
+	bytesSamples := make([]float64, len(runUIDs))
+	var latencies []time.Duration
 
 	for r, uid := range runUIDs {
 		res := runBenchmark(ctx, client, batchCmd, config, uid, test)
 		opsSamples[r] = res.opsPerSec
 		itemsSamples[r] = res.itemsPerSec
 		latencySamples[r] = float64(res.avgLatency)
+		allocsSamples[r] = res.allocsPerOp
+		bytesSamples[r] = res.bytesPerOp
+		latencies = append(latencies, res.latencies...)
 	}
 
 	return OpResult{
@@ -221,6 +300,9 @@ func runAggregated(
 		OpsPerSec:    trimmedMean(opsSamples),
 		ItemsPerSec:  trimmedMean(itemsSamples),
 		AvgLatencyNs: int64(trimmedMean(latencySamples)),
+		Latency:      newLatencyDistribution(latencies),
+		AllocsPerOp:  trimmedMean(allocsSamples),
+		BytesPerOp:   trimmedMean(bytesSamples),
 	}
 }
 
@@ -236,6 +318,13 @@ func runBenchmark(
 	var wg sync.WaitGroup
 
 	opsPerWorker := config.count / int64(config.concurrency)
+	ops := int64(config.concurrency) * opsPerWorker
+	latencies := make([]time.Duration, ops)
+
+	// The allocations of the whole process are sampled: they include the
+	// ones of the benchmark itself (keys, values), the same for every client.
+	var memBefore, memAfter runtime.MemStats
+	runtime.ReadMemStats(&memBefore)
 	start := time.Now()
 
 	for i := range config.concurrency {
@@ -243,10 +332,28 @@ func runBenchmark(
 		go func(workerID int) {
 			defer wg.Done()
 
+			var conn *rawConn
+			if test.Raw != nil {
+				var err error
+				if conn, err = dialRaw(config.addr); err != nil {
+					log.Fatalf("Operation %s failed: %v\n", test.Name, err)
+				}
+				defer conn.Close()
+			}
+
+			workerLatencies := latencies[int64(workerID)*opsPerWorker:][:opsPerWorker]
 			for j := range opsPerWorker {
-				if err := test.Operation(ctx, client, batchCmd, uid, workerID, j); err != nil {
+				opStart := time.Now()
+				var err error
+				if conn != nil {
+					err = test.Raw(conn, uid, workerID, j)
+				} else {
+					err = test.Operation(ctx, client, batchCmd, uid, workerID, j)
+				}
+				if err != nil {
 					log.Fatalf("Operation %s failed: %v\n", test.Name, err)
 				}
+				workerLatencies[j] = time.Since(opStart)
 			}
 		}(i)
 	}
@@ -254,6 +361,7 @@ func runBenchmark(
 	wg.Wait()
 
 	duration := time.Since(start)
+	runtime.ReadMemStats(&memAfter)
 	opsPerSec := float64(config.count) / duration.Seconds()
 	totalItems := config.count * int64(test.ItemsPerOp)
 	itemsPerSec := float64(totalItems) / duration.Seconds()
@@ -266,6 +374,9 @@ func runBenchmark(
 		opsPerSec:   opsPerSec,
 		itemsPerSec: itemsPerSec,
 		avgLatency:  duration / time.Duration(opsPerWorker),
+		latencies:   latencies,
+		allocsPerOp: float64(memAfter.Mallocs-memBefore.Mallocs) / float64(ops),
+		bytesPerOp:  float64(memAfter.TotalAlloc-memBefore.TotalAlloc) / float64(ops),
 	}
 }
 
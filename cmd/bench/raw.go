@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pior/memcache/meta"
+)
+
+// rawConn is a connection of a worker for the raw tests. It sends the
+// requests with the meta package directly.
+type rawConn struct {
+	conn net.Conn
+	r    *bufio.Reader
+	w    *bufio.Writer
+	resp meta.Response
+}
+
+func dialRaw(addr string) (*rawConn, error) {
+	conn, err := net.DialTimeout("tcp", addr, 5*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	return &rawConn{conn: conn, r: bufio.NewReader(conn), w: bufio.NewWriter(conn)}, nil
+}
+
+func (c *rawConn) Close() error {
+	return c.conn.Close()
+}
+
+// roundTrip writes the requests with a single flush, then reads a response
+// per request.
+func (c *rawConn) roundTrip(reqs ...*meta.Request) error {
+	for _, req := range reqs {
+		if err := meta.WriteRequest(c.w, req); err != nil {
+			return err
+		}
+	}
+	if err := c.w.Flush(); err != nil {
+		return err
+	}
+
+	for range reqs {
+		c.resp.Reset()
+		if err := meta.ReadResponse(c.r, &c.resp); err != nil {
+			return err
+		}
+		if c.resp.HasError() {
+			return c.resp.Error
+		}
+	}
+	return nil
+}
+
+// parseBatchSizes parses a comma-separated list of batch sizes.
+func parseBatchSizes(s string) ([]int, error) {
+	var sizes []int
+	for field := range strings.SplitSeq(s, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("batch size %q is not a positive integer", field)
+		}
+		sizes = append(sizes, n)
+	}
+	return sizes, nil
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"text/tabwriter"
+	"time"
+)
+
+// printClientComparison prints the results of the clients side by side, with
+// the change of each client relative to the first one. Higher throughput and
+// lower latency are better.
+func printClientComparison(w io.Writer, reports []BenchmarkReport) {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+
+	fmt.Fprint(tw, "\nOperation\t")
+	for i, r := range reports {
+		fmt.Fprintf(tw, "%s ops/sec\t", r.Client)
+		if i > 0 {
+			fmt.Fprint(tw, "Δ\t")
+		}
+	}
+	for i, r := range reports {
+		fmt.Fprintf(tw, "%s p99\t", r.Client)
+		if i > 0 {
+			fmt.Fprint(tw, "Δ\t")
+		}
+	}
+	fmt.Fprintln(tw)
+
+	// The raw operations are run with some clients only.
+	var names []string
+	for _, r := range reports {
+		for _, res := range r.Results {
+			if !slices.Contains(names, res.Name) {
+				names = append(names, res.Name)
+			}
+		}
+	}
+
+	for _, name := range names {
+		fmt.Fprintf(tw, "%s\t", name)
+
+		results := make([]*OpResult, len(reports))
+		for i, r := range reports {
+			results[i] = findResult(r, name)
+		}
+
+		for i, res := range results {
+			if res == nil {
+				fmt.Fprint(tw, "-\t")
+			} else {
+				fmt.Fprintf(tw, "%s\t", formatNumber(int64(res.OpsPerSec)))
+			}
+			if i > 0 {
+				fmt.Fprintf(tw, "%s\t", formatChange(opsPerSec(results[0]), opsPerSec(res)))
+			}
+		}
+		for i, res := range results {
+			p99 := p99Ns(res)
+			if p99 == 0 {
+				fmt.Fprint(tw, "-\t")
+			} else {
+				fmt.Fprintf(tw, "%s\t", formatDuration(time.Duration(p99)))
+			}
+			if i > 0 {
+				fmt.Fprintf(tw, "%s\t", formatChange(float64(p99Ns(results[0])), float64(p99)))
+			}
+		}
+		fmt.Fprintln(tw)
+	}
+	tw.Flush()
+}
+
+func findResult(r BenchmarkReport, name string) *OpResult {
+	for i := range r.Results {
+		if r.Results[i].Name == name {
+			return &r.Results[i]
+		}
+	}
+	return nil
+}
+
+func opsPerSec(res *OpResult) float64 {
+	if res == nil {
+		return 0
+	}
+	return res.OpsPerSec
+}
+
+func p99Ns(res *OpResult) int64 {
+	if res == nil || res.Latency == nil {
+		return 0
+	}
+	return res.Latency.P99Ns
+}
+
+// formatChange formats the change from base to v in percent, or "-" when one
+// of them is missing.
+func formatChange(base, v float64) string {
+	if base == 0 || v == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%+.1f%%", (v/base-1)*100)
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPrintClientComparison(t *testing.T) {
+	reports := []BenchmarkReport{
+		{Client: "pior", Results: []OpResult{
+			{Name: "get-hit", OpsPerSec: 20000, Latency: &LatencyDistribution{P99Ns: 100_000}},
+			{Name: "raw-get", OpsPerSec: 25000, Latency: &LatencyDistribution{P99Ns: 80_000}},
+		}},
+		{Client: "bradfitz", Results: []OpResult{
+			{Name: "get-hit", OpsPerSec: 15000, Latency: &LatencyDistribution{P99Ns: 150_000}},
+		}},
+	}
+
+	var b strings.Builder
+	printClientComparison(&b, reports)
+
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	want := [][]string{
+		{"Operation", "pior", "ops/sec", "bradfitz", "ops/sec", "Δ", "pior", "p99", "bradfitz", "p99", "Δ"},
+		{"get-hit", "20.00K", "15.00K", "-25.0%", "100.00µs", "150.00µs", "+50.0%"},
+		{"raw-get", "25.00K", "-", "-", "80.00µs", "-", "-"},
+	}
+	if len(lines) != len(want) {
+		t.Fatalf("table:\n%s", b.String())
+	}
+	for i, line := range lines {
+		if got := strings.Fields(line); strings.Join(got, " ") != strings.Join(want[i], " ") {
+			t.Errorf("line %d = %q, want %q", i, got, want[i])
+		}
+	}
+}
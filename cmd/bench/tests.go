@@ -6,15 +6,21 @@ import (
 	"time"
 
 	"github.com/pior/memcache"
+	"github.com/pior/memcache/meta"
 )
 
 // benchmarkTests returns the ordered operation suite. The order matters: some
 // operations read or delete keys written by earlier ones (get-hit after set,
 // delete-found after set), so they must run in sequence within a single run.
-func benchmarkTests() []Test {
+//
+// The batch operations run once per batch size. The raw tests (raw-get and
+// raw-pipeline-get-N) read the same keys on a dedicated connection per worker
+// with the meta package alone, measuring the protocol layer without the
+// client and its pool.
+func benchmarkTests(batchSizes []int) []Test {
 	data10kb := make([]byte, 1024*10)
 
-	return []Test{
+	tests := []Test{
 		{
 			Name:       "get-miss",
 			ItemsPerOp: 1,
@@ -36,22 +42,14 @@ func benchmarkTests() []Test {
 				})
 			},
 		},
-		{
-			Name:       "multi-set-10",
-			ItemsPerOp: 10,
-			Operation: func(ctx context.Context, client Client, batchCmd *memcache.BatchCommands, uid int64, workerID int, operationID int64) error {
-				items := make([]memcache.Item, 10)
-				for i := range 10 {
-					items[i] = memcache.Item{
-						Key:   fmt.Sprintf("test-%d-%d-%d-%d", uid, workerID, operationID, i),
-						Value: []byte("benchmark-value-0123456789"),
-						TTL:   memcache.ExpiresIn(time.Minute),
-					}
-				}
-				return batchCmd.MultiSet(ctx, items)
-			},
-		},
-		{
+	}
+
+	for _, n := range batchSizes {
+		tests = append(tests, multiSetTest(n))
+	}
+
+	tests = append(tests,
+		Test{
 			Name:       "get-hit",
 			ItemsPerOp: 1,
 			Operation: func(ctx context.Context, client Client, batchCmd *memcache.BatchCommands, uid int64, workerID int, operationID int64) error {
@@ -60,19 +58,21 @@ func benchmarkTests() []Test {
 				return err
 			},
 		},
-		{
-			Name:       "multi-get-hit-10",
-			ItemsPerOp: 10,
-			Operation: func(ctx context.Context, client Client, batchCmd *memcache.BatchCommands, uid int64, workerID int, operationID int64) error {
-				keys := make([]string, 10)
-				for i := range 10 {
-					keys[i] = fmt.Sprintf("test-%d-%d-%d-%d", uid, workerID, operationID, i)
-				}
-				_, err := batchCmd.MultiGet(ctx, keys)
-				return err
+		Test{
+			Name:       "raw-get",
+			ItemsPerOp: 1,
+			Raw: func(conn *rawConn, uid int64, workerID int, operationID int64) error {
+				return conn.roundTrip(meta.Get(fmt.Sprintf("test-%d-%d-%d", uid, workerID, operationID)).AddReturnValue())
 			},
 		},
-		{
+	)
+
+	for _, n := range batchSizes {
+		tests = append(tests, multiGetHitTest(n), rawPipelineGetTest(n))
+	}
+
+	return append(tests,
+		Test{
 			Name:       "set-10kb",
 			ItemsPerOp: 1,
 			Operation: func(ctx context.Context, client Client, batchCmd *memcache.BatchCommands, uid int64, workerID int, operationID int64) error {
@@ -84,7 +84,7 @@ func benchmarkTests() []Test {
 				})
 			},
 		},
-		{
+		Test{
 			Name:       "get-hit-10kb",
 			ItemsPerOp: 1,
 			Operation: func(ctx context.Context, client Client, batchCmd *memcache.BatchCommands, uid int64, workerID int, operationID int64) error {
@@ -93,7 +93,7 @@ func benchmarkTests() []Test {
 				return err
 			},
 		},
-		{
+		Test{
 			Name:       "delete-found",
 			ItemsPerOp: 1,
 			Operation: func(ctx context.Context, client Client, batchCmd *memcache.BatchCommands, uid int64, workerID int, operationID int64) error {
@@ -101,7 +101,7 @@ func benchmarkTests() []Test {
 				return client.Delete(ctx, key)
 			},
 		},
-		{
+		Test{
 			Name:       "delete-miss",
 			ItemsPerOp: 1,
 			Operation: func(ctx context.Context, client Client, batchCmd *memcache.BatchCommands, uid int64, workerID int, operationID int64) error {
@@ -109,7 +109,7 @@ func benchmarkTests() []Test {
 				return client.Delete(ctx, key)
 			},
 		},
-		{
+		Test{
 			Name:       "increment",
 			ItemsPerOp: 1,
 			Operation: func(ctx context.Context, client Client, batchCmd *memcache.BatchCommands, uid int64, workerID int, operationID int64) error {
@@ -118,5 +118,54 @@ func benchmarkTests() []Test {
 				return err
 			},
 		},
+	)
+}
+
+func multiSetTest(n int) Test {
+	return Test{
+		Name:       fmt.Sprintf("multi-set-%d", n),
+		ItemsPerOp: n,
+		Operation: func(ctx context.Context, client Client, batchCmd *memcache.BatchCommands, uid int64, workerID int, operationID int64) error {
+			items := make([]memcache.Item, n)
+			for i := range n {
+				items[i] = memcache.Item{
+					Key:   fmt.Sprintf("test-%d-%d-%d-%d", uid, workerID, operationID, i),
+					Value: []byte("benchmark-value-0123456789"),
+					TTL:   memcache.ExpiresIn(time.Minute),
+				}
+			}
+			return batchCmd.MultiSet(ctx, items)
+		},
+	}
+}
+
+func multiGetHitTest(n int) Test {
+	return Test{
+		Name:       fmt.Sprintf("multi-get-hit-%d", n),
+		ItemsPerOp: n,
+		Operation: func(ctx context.Context, client Client, batchCmd *memcache.BatchCommands, uid int64, workerID int, operationID int64) error {
+			keys := make([]string, n)
+			for i := range n {
+				keys[i] = fmt.Sprintf("test-%d-%d-%d-%d", uid, workerID, operationID, i)
+			}
+			_, err := batchCmd.MultiGet(ctx, keys)
+			return err
+		},
+	}
+}
+
+// rawPipelineGetTest reads the keys of multi-set-N with N pipelined mg
+// requests: one write and one flush for the batch.
+func rawPipelineGetTest(n int) Test {
+	return Test{
+		Name:       fmt.Sprintf("raw-pipeline-get-%d", n),
+		ItemsPerOp: n,
+		Raw: func(conn *rawConn, uid int64, workerID int, operationID int64) error {
+			reqs := make([]*meta.Request, n)
+			for i := range n {
+				reqs[i] = meta.Get(fmt.Sprintf("test-%d-%d-%d-%d", uid, workerID, operationID, i)).AddReturnValue()
+			}
+			return conn.roundTrip(reqs...)
+		},
 	}
 }
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBenchmarkTests(t *testing.T) {
+	var names []string
+	for _, test := range benchmarkTests([]int{10, 100}) {
+		names = append(names, test.Name)
+		if (test.Operation == nil) == (test.Raw == nil) {
+			t.Errorf("%s: exactly one of Operation and Raw must be set", test.Name)
+		}
+	}
+
+	// The readers of a batch run after its writer.
+	want := []string{
+		"get-miss", "set", "multi-set-10", "multi-set-100", "get-hit", "raw-get",
+		"multi-get-hit-10", "raw-pipeline-get-10", "multi-get-hit-100", "raw-pipeline-get-100",
+		"set-10kb", "get-hit-10kb", "delete-found", "delete-miss", "increment",
+	}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("tests = %v, want %v", names, want)
+	}
+}
+
+func TestParseBatchSizes(t *testing.T) {
+	got, err := parseBatchSizes("1, 10,100")
+	if err != nil || !reflect.DeepEqual(got, []int{1, 10, 100}) {
+		t.Errorf("parseBatchSizes = %v, %v", got, err)
+	}
+
+	for _, s := range []string{"", "0", "10,", "ten"} {
+		if _, err := parseBatchSizes(s); err == nil {
+			t.Errorf("parseBatchSizes(%q) must fail", s)
+		}
+	}
+}
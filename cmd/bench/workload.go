@@ -0,0 +1,167 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pior/memcache"
+)
+
+// maxValueSize bounds the value sizes below the default 1MB item size limit
+// of memcached, leaving room for the key and the item header.
+const maxValueSize = 1<<20 - 1024
+
+// Workload describes the mixed operation: a weighted mix of operations on a
+// keyspace, with a key distribution and a value size distribution, to
+// approximate production traffic.
+type Workload struct {
+	Mix      []MixEntry
+	Keys     int     // size of the keyspace
+	KeyDist  string  // uniform or zipf
+	ZipfS    float64 // zipf exponent, > 1: the larger, the more skewed
+	SizeDist string  // fixed or lognormal
+	Size     int     // fixed size, or median of the lognormal distribution
+	Sigma    float64 // standard deviation of the log of the lognormal sizes
+}
+
+// MixEntry is an operation of the mix with its weight.
+type MixEntry struct {
+	Op     string // get, set or delete
+	Weight int
+}
+
+// parseMix parses a mix like "get=90,set=10".
+func parseMix(s string) ([]MixEntry, error) {
+	var mix []MixEntry
+	for field := range strings.SplitSeq(s, ",") {
+		op, weight, ok := strings.Cut(strings.TrimSpace(field), "=")
+		if !ok {
+			return nil, fmt.Errorf("%q is not op=weight", field)
+		}
+		switch op {
+		case "get", "set", "delete":
+		default:
+			return nil, fmt.Errorf("unknown operation %q (get, set or delete)", op)
+		}
+		w, err := strconv.Atoi(weight)
+		if err != nil || w < 0 {
+			return nil, fmt.Errorf("weight %q of %s is not a non-negative integer", weight, op)
+		}
+		mix = append(mix, MixEntry{Op: op, Weight: w})
+	}
+	return mix, nil
+}
+
+func (w Workload) validate() error {
+	total := 0
+	for _, e := range w.Mix {
+		total += e.Weight
+	}
+	switch {
+	case total == 0:
+		return fmt.Errorf("the weights of the mix sum to zero")
+	case w.Keys < 1:
+		return fmt.Errorf("the keyspace must have at least one key")
+	case w.KeyDist != "uniform" && w.KeyDist != "zipf":
+		return fmt.Errorf("invalid key distribution %q (uniform or zipf)", w.KeyDist)
+	case w.KeyDist == "zipf" && w.ZipfS <= 1:
+		return fmt.Errorf("the zipf exponent must be > 1")
+	case w.SizeDist != "fixed" && w.SizeDist != "lognormal":
+		return fmt.Errorf("invalid value size distribution %q (fixed or lognormal)", w.SizeDist)
+	case w.Size < 1 || w.Size > maxValueSize:
+		return fmt.Errorf("the value size must be between 1 and %d", maxValueSize)
+	case w.SizeDist == "lognormal" && w.Sigma <= 0:
+		return fmt.Errorf("the lognormal sigma must be > 0")
+	}
+	return nil
+}
+
+// workloadState is the random state of a worker: math/rand sources are not
+// safe for concurrent use.
+type workloadState struct {
+	w      Workload
+	rng    *rand.Rand
+	zipf   *rand.Zipf
+	total  int
+	values []byte // sliced to the value sizes, shared by the workers
+}
+
+func newWorkloadState(w Workload, values []byte, seed1, seed2 uint64) *workloadState {
+	s := &workloadState{
+		w:      w,
+		rng:    rand.New(rand.NewPCG(seed1, seed2)),
+		values: values,
+	}
+	for _, e := range w.Mix {
+		s.total += e.Weight
+	}
+	if w.KeyDist == "zipf" {
+		s.zipf = rand.NewZipf(s.rng, w.ZipfS, 1, uint64(w.Keys-1))
+	}
+	return s
+}
+
+func (s *workloadState) op() string {
+	n := s.rng.IntN(s.total)
+	for _, e := range s.w.Mix {
+		if n < e.Weight {
+			return e.Op
+		}
+		n -= e.Weight
+	}
+	panic("unreachable")
+}
+
+// key returns the id of a key: with zipf, the smallest ids are the hottest.
+func (s *workloadState) key() int {
+	if s.zipf != nil {
+		return int(s.zipf.Uint64())
+	}
+	return s.rng.IntN(s.w.Keys)
+}
+
+func (s *workloadState) valueSize() int {
+	if s.w.SizeDist == "fixed" {
+		return s.w.Size
+	}
+	size := int(math.Exp(math.Log(float64(s.w.Size)) + s.w.Sigma*s.rng.NormFloat64()))
+	return min(max(size, 1), maxValueSize)
+}
+
+// mixedTest runs the operations of the workload. The keys are shared by the
+// workers and the runs, as the hot keys of a production cache are.
+func mixedTest(w Workload, concurrency int) Test {
+	values := bytes.Repeat([]byte{'v'}, maxValueSize)
+	states := make([]*workloadState, concurrency)
+	for i := range states {
+		states[i] = newWorkloadState(w, values, rand.Uint64(), uint64(i))
+	}
+
+	return Test{
+		Name:       "mixed",
+		ItemsPerOp: 1,
+		Operation: func(ctx context.Context, client Client, batchCmd *memcache.BatchCommands, uid int64, workerID int, operationID int64) error {
+			s := states[workerID]
+			key := fmt.Sprintf("test-mixed-%d", s.key())
+			switch s.op() {
+			case "get":
+				_, err := client.Get(ctx, key)
+				return err
+			case "set":
+				return client.Set(ctx, memcache.Item{
+					Key:   key,
+					Value: s.values[:s.valueSize()],
+					TTL:   memcache.ExpiresIn(time.Minute),
+				})
+			default:
+				return client.Delete(ctx, key)
+			}
+		},
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestParseMix(t *testing.T) {
+	mix, err := parseMix("get=90, set=9,delete=1")
+	want := []MixEntry{{"get", 90}, {"set", 9}, {"delete", 1}}
+	if err != nil || !reflect.DeepEqual(mix, want) {
+		t.Errorf("parseMix = %v, %v", mix, err)
+	}
+
+	for _, s := range []string{"get", "get=x", "get=-1", "incr=1"} {
+		if _, err := parseMix(s); err == nil {
+			t.Errorf("parseMix(%q) must fail", s)
+		}
+	}
+}
+
+func TestWorkloadState(t *testing.T) {
+	w := Workload{
+		Mix:      []MixEntry{{"get", 90}, {"set", 10}, {"delete", 0}},
+		Keys:     1000,
+		KeyDist:  "zipf",
+		ZipfS:    1.1,
+		SizeDist: "lognormal",
+		Size:     1000,
+		Sigma:    1,
+	}
+	if err := w.validate(); err != nil {
+		t.Fatal(err)
+	}
+	s := newWorkloadState(w, nil, 1, 2)
+
+	const n = 10_000
+	ops := map[string]int{}
+	var hot int
+	sizes := make([]int, n)
+	for i := range n {
+		ops[s.op()]++
+		key := s.key()
+		if key < 0 || key >= w.Keys {
+			t.Fatalf("key %d out of the keyspace", key)
+		}
+		if key < 10 {
+			hot++
+		}
+		sizes[i] = s.valueSize()
+	}
+
+	if ops["get"] < 8500 || ops["get"] > 9500 || ops["delete"] != 0 {
+		t.Errorf("ops = %v, want about 90%% of gets and no delete", ops)
+	}
+	// With s=1.1, the 10 hottest keys of 1000 get about half of the accesses;
+	// uniformly they would get 1%.
+	if hot < n/4 {
+		t.Errorf("the 10 hottest keys got %d accesses of %d, want a skewed distribution", hot, n)
+	}
+	slices.Sort(sizes)
+	if median := sizes[n/2]; median < 900 || median > 1100 {
+		t.Errorf("median value size = %d, want about 1000", median)
+	}
+	if sizes[n/10] > 500 || sizes[n*9/10] < 2000 {
+		t.Errorf("p10, p90 of the value sizes = %d, %d, want a spread distribution", sizes[n/10], sizes[n*9/10])
+	}
+}
+
+func TestWorkloadValidate(t *testing.T) {
+	valid := Workload{Mix: []MixEntry{{"get", 1}}, Keys: 10, KeyDist: "uniform", SizeDist: "fixed", Size: 100}
+	if err := valid.validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, change := range map[string]func(*Workload){
+		"no weight":  func(w *Workload) { w.Mix = []MixEntry{{"get", 0}} },
+		"keys":       func(w *Workload) { w.Keys = 0 },
+		"key dist":   func(w *Workload) { w.KeyDist = "pareto" },
+		"zipf s":     func(w *Workload) { w.KeyDist, w.ZipfS = "zipf", 1 },
+		"size dist":  func(w *Workload) { w.SizeDist = "normal" },
+		"size":       func(w *Workload) { w.Size = 2 << 20 },
+		"zero sigma": func(w *Workload) { w.SizeDist, w.Sigma = "lognormal", 0 },
+	} {
+		w := valid
+		change(&w)
+		if err := w.validate(); err == nil {
+			t.Errorf("%s: validate must fail", name)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"text/tabwriter"
+
+	"github.com/pior/memcache"
+)
+
+// runLocate prints the server each key hashes to, as the client selects it.
+func runLocate(ctx context.Context, c *cli, args []string) error {
+	tw := tabwriter.NewWriter(c.out, 0, 8, 2, ' ', 0)
+	for _, key := range args {
+		i := memcache.DefaultServerSelector(key, len(c.servers))
+		fmt.Fprintf(tw, "%s\t%s\t(server %d of %d)\n", key, c.servers[i], i+1, len(c.servers))
+	}
+	return tw.Flush()
+}
+
+// runStats prints the statistics of the servers side by side, a column per
+// server, to compare the nodes of a cluster.
+func runStats(ctx context.Context, c *cli, args []string) error {
+	flags := flag.NewFlagSet("stats", flag.ContinueOnError)
+	flags.SetOutput(c.errOut)
+	match := flags.String("match", "", "regular expression the names of the statistics must match")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() > 1 {
+		return errors.New("usage: stats [-match regexp] [group]")
+	}
+
+	var re *regexp.Regexp
+	if *match != "" {
+		var err error
+		if re, err = regexp.Compile(*match); err != nil {
+			return fmt.Errorf("invalid -match: %w", err)
+		}
+	}
+
+	results, err := c.client.Stats(ctx, flags.Args()...)
+	if err != nil {
+		return err
+	}
+
+	var failed error
+	names := make(map[string]struct{})
+	for _, result := range results {
+		if result.Error != nil {
+			fmt.Fprintf(c.errOut, "%s: %v\n", result.Addr, result.Error)
+			failed = errors.New("some servers failed")
+			continue
+		}
+		for name := range result.Stats {
+			if re == nil || re.MatchString(name) {
+				names[name] = struct{}{}
+			}
+		}
+	}
+
+	tw := tabwriter.NewWriter(c.out, 0, 8, 2, ' ', 0)
+	fmt.Fprint(tw, "STAT")
+	for _, result := range results {
+		fmt.Fprintf(tw, "\t%s", result.Addr)
+	}
+	fmt.Fprintln(tw)
+	for _, name := range slices.Sorted(maps.Keys(names)) {
+		fmt.Fprint(tw, name)
+		for _, result := range results {
+			value, ok := result.Stats[name]
+			if !ok {
+				value = "-"
+			}
+			fmt.Fprintf(tw, "\t%s", value)
+		}
+		fmt.Fprintln(tw)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	return failed
+}
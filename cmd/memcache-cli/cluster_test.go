@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/pior/memcache/memcachetest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// runCluster runs a command against several servers.
+func runCluster(t *testing.T, servers []*memcachetest.Server, args ...string) (string, error) {
+	t.Helper()
+
+	addrs := make([]string, len(servers))
+	for i, srv := range servers {
+		addrs[i] = srv.Addr
+	}
+	var stdout bytes.Buffer
+	err := run(context.Background(), append([]string{"-servers", strings.Join(addrs, ",")}, args...), strings.NewReader(""), &stdout, &bytes.Buffer{})
+	return stdout.String(), err
+}
+
+func TestLocate(t *testing.T) {
+	servers := []*memcachetest.Server{memcachetest.NewServer(t), memcachetest.NewServer(t), memcachetest.NewServer(t)}
+
+	for i := range 10 {
+		key := fmt.Sprintf("key:%d", i)
+		_, err := runCluster(t, servers, "set", key, "value")
+		require.NoError(t, err)
+
+		out, err := runCluster(t, servers, "locate", key)
+		require.NoError(t, err)
+		fields := strings.Fields(out)
+		require.Len(t, fields, 6, out)
+		assert.Equal(t, key, fields[0])
+
+		// The key is stored on the server located.
+		for _, srv := range servers {
+			out, err := runCLI(t, srv, "get", key)
+			if srv.Addr == fields[1] {
+				assert.NoError(t, err, key)
+				assert.Equal(t, "value\n", out)
+			} else {
+				assert.EqualError(t, err, "NOT_FOUND", key)
+			}
+		}
+	}
+}
+
+func TestStats(t *testing.T) {
+	servers := []*memcachetest.Server{memcachetest.NewServer(t), memcachetest.NewServer(t)}
+	_, err := runCLI(t, servers[0], "set", "a", "xy")
+	require.NoError(t, err)
+
+	out, err := runCluster(t, servers, "stats", "-match", "^(curr_items|bytes)$")
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSuffix(out, "\n"), "\n")
+	require.Len(t, lines, 3, out)
+	assert.Equal(t, []string{"STAT", servers[0].Addr, servers[1].Addr}, strings.Fields(lines[0]))
+	assert.Equal(t, []string{"bytes", "51", "0"}, strings.Fields(lines[1]))
+	assert.Equal(t, []string{"curr_items", "1", "0"}, strings.Fields(lines[2]))
+}
@@ -0,0 +1,296 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pior/memcache"
+	"github.com/pior/memcache/meta"
+)
+
+type command struct {
+	usage   string
+	help    string
+	minArgs int
+	maxArgs int // -1: unbounded
+	run     func(ctx context.Context, c *cli, args []string) error
+}
+
+var commands = map[string]command{
+	"get":       {"<key>", "print the value of an item", 1, 1, runGet},
+	"gets":      {"<key>", "print the value and the CAS value of an item", 1, 1, runGets},
+	"gat":       {"<key> <ttl>", "print the value of an item and update its TTL", 2, 2, runGat},
+	"set":       {"<key> <value> [ttl]", "store an item", 2, 3, runStore(meta.ModeSet)},
+	"add":       {"<key> <value> [ttl]", "store an item only if it doesn't exist", 2, 3, runStore(meta.ModeAdd)},
+	"replace":   {"<key> <value> [ttl]", "store an item only if it exists", 2, 3, runStore(meta.ModeReplace)},
+	"append":    {"<key> <value>", "append data to an existing item", 2, 2, runStore(meta.ModeAppend)},
+	"prepend":   {"<key> <value>", "prepend data to an existing item", 2, 2, runStore(meta.ModePrepend)},
+	"cas":       {"<key> <value> <cas> [ttl]", "store an item only if its CAS value matches", 3, 4, runCAS},
+	"delete":    {"<key>", "delete an item", 1, 1, runDelete},
+	"incr":      {"<key> [delta]", "increment a counter and print its value", 1, 2, runArithmetic(memcache.ModeIncrement)},
+	"decr":      {"<key> [delta]", "decrement a counter and print its value", 1, 2, runArithmetic(memcache.ModeDecrement)},
+	"touch":     {"<key> <ttl>", "update the TTL of an item", 2, 2, runTouch},
+	"debug":     {"<key>", "print the internal metadata of an item (me command)", 1, 1, runDebug},
+	"flush_all": {"[delay]", "invalidate all the items of every server, after delay seconds", 0, 1, runFlushAll},
+	"version":   {"", "print the version of every server", 0, 0, runVersion},
+	"locate":    {"<key>...", "print the server each key hashes to", 1, -1, runLocate},
+	"stats":     {"[-match regexp] [group]", "print the statistics of the servers side by side", 0, -1, runStats},
+	"keys":      {"[-limit n] [-offset n] [-match regexp] [prefix]", "list the keys of every server (lru_crawler metadump)", 0, -1, runKeys(false)},
+	"dump":      {"[-limit n] [-offset n] [-match regexp] [prefix]", "list the items of every server with their size and TTL", 0, -1, runKeys(true)},
+	"watch":     {"[-prefix p] [-match regexp] <stream>...", "stream the events of every server: fetchers, mutations, evictions, deletions or connevents", 1, -1, runWatch},
+	"meta":      {"[<cmd> <key> <flags>*]", "send a raw meta command and describe the response, or read them from stdin", 0, -1, runMeta},
+}
+
+// Outcomes of the commands, worded as the memcached text protocol.
+var (
+	errNotFound  = errors.New("NOT_FOUND")
+	errNotStored = errors.New("NOT_STORED")
+	errExists    = errors.New("EXISTS")
+)
+
+func runGet(ctx context.Context, c *cli, args []string) error {
+	item, err := c.client.Get(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	if !item.Found {
+		return errNotFound
+	}
+	fmt.Fprintf(c.out, "%s\n", item.Value)
+	return nil
+}
+
+func runGets(ctx context.Context, c *cli, args []string) error {
+	item, err := c.client.GetWithOptions(ctx, args[0], memcache.GetOptions{ReturnCAS: true})
+	if err != nil {
+		return err
+	}
+	if !item.Found {
+		return errNotFound
+	}
+	fmt.Fprintf(c.out, "%s\nCAS %d\n", item.Value, item.CAS)
+	return nil
+}
+
+func runGat(ctx context.Context, c *cli, args []string) error {
+	ttl, err := parseTTL(args[1])
+	if err != nil {
+		return err
+	}
+
+	req := meta.Get(args[0]).AddReturnValue().AddTTL(ttl)
+	resp, err := c.execute(ctx, req)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(c.out, "%s\n", resp.Data)
+	return nil
+}
+
+func runStore(mode string) func(ctx context.Context, c *cli, args []string) error {
+	return func(ctx context.Context, c *cli, args []string) error {
+		req := meta.Set(args[0], []byte(args[1])).AddMode(mode)
+		if len(args) > 2 {
+			ttl, err := parseTTL(args[2])
+			if err != nil {
+				return err
+			}
+			req.AddTTL(ttl)
+		}
+		return c.store(ctx, req)
+	}
+}
+
+func runCAS(ctx context.Context, c *cli, args []string) error {
+	cas, err := strconv.ParseUint(args[2], 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid CAS value %q", args[2])
+	}
+
+	req := meta.Set(args[0], []byte(args[1])).AddCAS(cas)
+	if len(args) > 3 {
+		ttl, err := parseTTL(args[3])
+		if err != nil {
+			return err
+		}
+		req.AddTTL(ttl)
+	}
+	return c.store(ctx, req)
+}
+
+// store executes an ms request and prints its outcome.
+func (c *cli) store(ctx context.Context, req *meta.Request) error {
+	if _, err := c.execute(ctx, req); err != nil {
+		return err
+	}
+	fmt.Fprintln(c.out, "STORED")
+	return nil
+}
+
+func runDelete(ctx context.Context, c *cli, args []string) error {
+	// Not client.Delete: it succeeds on a missing key, which an operator
+	// wants to know about.
+	if _, err := c.execute(ctx, meta.Delete(args[0])); err != nil {
+		return err
+	}
+	fmt.Fprintln(c.out, "DELETED")
+	return nil
+}
+
+func runArithmetic(mode memcache.IncrementMode) func(ctx context.Context, c *cli, args []string) error {
+	return func(ctx context.Context, c *cli, args []string) error {
+		opts := memcache.IncrementOptions{Delta: 1, Mode: mode}
+		if len(args) > 1 {
+			delta, err := strconv.ParseUint(args[1], 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid delta %q", args[1])
+			}
+			opts.Delta = delta
+		}
+
+		value, _, err := c.client.IncrementWithOptions(ctx, args[0], opts)
+		if errors.Is(err, memcache.ErrNotFound) {
+			return errNotFound
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(c.out, value)
+		return nil
+	}
+}
+
+func runTouch(ctx context.Context, c *cli, args []string) error {
+	ttl, err := parseTTL(args[1])
+	if err != nil {
+		return err
+	}
+
+	if _, err := c.execute(ctx, meta.Get(args[0]).AddTTL(ttl)); err != nil {
+		return err
+	}
+	fmt.Fprintln(c.out, "TOUCHED")
+	return nil
+}
+
+func runDebug(ctx context.Context, c *cli, args []string) error {
+	resp, err := c.execute(ctx, meta.NewRequest(meta.CmdDebug, args[0], nil))
+	if err != nil {
+		return err
+	}
+	for _, param := range strings.Fields(string(resp.Data)) {
+		key, value, _ := strings.Cut(param, "=")
+		fmt.Fprintf(c.out, "%-10s %s\n", key, value)
+	}
+	return nil
+}
+
+func runFlushAll(ctx context.Context, c *cli, args []string) error {
+	delay := 0
+	if len(args) > 0 {
+		var err error
+		if delay, err = strconv.Atoi(args[0]); err != nil || delay < 0 {
+			return fmt.Errorf("invalid delay %q", args[0])
+		}
+	}
+
+	return c.eachServer(ctx, func(r *bufio.Reader, w *bufio.Writer) (string, error) {
+		if err := meta.WriteRequest(w, meta.FlushAll(delay)); err != nil {
+			return "", err
+		}
+		if err := w.Flush(); err != nil {
+			return "", err
+		}
+		return "OK", meta.ReadOK(r)
+	})
+}
+
+func runVersion(ctx context.Context, c *cli, args []string) error {
+	return c.eachServer(ctx, func(r *bufio.Reader, w *bufio.Writer) (string, error) {
+		if err := meta.WriteRequest(w, meta.Version()); err != nil {
+			return "", err
+		}
+		if err := w.Flush(); err != nil {
+			return "", err
+		}
+		return meta.ReadVersion(r)
+	})
+}
+
+// execute executes a keyed request with the client, and returns the errors
+// and the unsuccessful statuses as errors.
+func (c *cli) execute(ctx context.Context, req *meta.Request) (*meta.Response, error) {
+	resp, err := c.client.Execute(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.HasError() {
+		return nil, resp.Error
+	}
+
+	switch resp.Status {
+	case meta.StatusHD, meta.StatusVA, meta.StatusME:
+		return resp, nil
+	case meta.StatusEN, meta.StatusNF:
+		return nil, errNotFound
+	case meta.StatusNS:
+		return nil, errNotStored
+	case meta.StatusEX:
+		return nil, errExists
+	default:
+		return nil, fmt.Errorf("unexpected response status %q", resp.Status)
+	}
+}
+
+// eachServer runs the text command of f on a new connection to each server,
+// and prints its result. The servers failing don't stop the others.
+func (c *cli) eachServer(ctx context.Context, f func(r *bufio.Reader, w *bufio.Writer) (string, error)) error {
+	var failed error
+	for _, addr := range c.servers {
+		result, err := c.onServer(ctx, addr, f)
+		if err != nil {
+			result = "ERROR " + err.Error()
+			failed = errors.New("some servers failed")
+		}
+		fmt.Fprintf(c.out, "%s: %s\n", addr, result)
+	}
+	return failed
+}
+
+func (c *cli) onServer(ctx context.Context, addr string, f func(r *bufio.Reader, w *bufio.Writer) (string, error)) (string, error) {
+	conn, err := c.dial(ctx, addr)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+
+	if err := conn.SetDeadline(time.Now().Add(c.timeout)); err != nil {
+		return "", err
+	}
+	return f(bufio.NewReader(conn), bufio.NewWriter(conn))
+}
+
+// dial opens a connection to a server, for the commands the client doesn't
+// support.
+func (c *cli) dial(ctx context.Context, addr string) (net.Conn, error) {
+	d := net.Dialer{Timeout: c.timeout}
+	return d.DialContext(ctx, "tcp", addr)
+}
+
+// parseTTL parses a TTL in seconds, or as a duration rounded to the second.
+func parseTTL(s string) (int, error) {
+	if seconds, err := strconv.Atoi(s); err == nil && seconds >= 0 {
+		return seconds, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid TTL %q", s)
+	}
+	return int(d.Round(time.Second) / time.Second), nil
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pior/memcache/meta"
+)
+
+// runKeys lists the keys of the servers with lru_crawler metadump, with their
+// metadata when detailed. The listing is bounded by -limit, and paginated
+// with -offset: the order of a metadump follows the LRU, so pages are only
+// consistent while the cache is idle.
+func runKeys(detailed bool) func(ctx context.Context, c *cli, args []string) error {
+	return func(ctx context.Context, c *cli, args []string) error {
+		flags := flag.NewFlagSet("keys", flag.ContinueOnError)
+		flags.SetOutput(c.errOut)
+		limit := flags.Int("limit", 1000, "maximum number of keys listed, 0 for no limit")
+		offset := flags.Int("offset", 0, "number of matching keys skipped, to list the next page")
+		match := flags.String("match", "", "regular expression the keys must match")
+		if err := flags.Parse(args); err != nil {
+			return err
+		}
+		if flags.NArg() > 1 {
+			return errors.New("usage: keys|dump [-limit n] [-offset n] [-match regexp] [prefix]")
+		}
+		prefix := flags.Arg(0)
+
+		var re *regexp.Regexp
+		if *match != "" {
+			var err error
+			if re, err = regexp.Compile(*match); err != nil {
+				return fmt.Errorf("invalid -match: %w", err)
+			}
+		}
+
+		l := &keyLister{
+			cli:      c,
+			detailed: detailed,
+			matches: func(key string) bool {
+				return strings.HasPrefix(key, prefix) && (re == nil || re.MatchString(key))
+			},
+			skip:  *offset,
+			limit: *limit,
+			tw:    tabwriter.NewWriter(c.out, 0, 8, 2, ' ', 0),
+			now:   time.Now(),
+		}
+		return l.run(ctx)
+	}
+}
+
+type keyLister struct {
+	cli      *cli
+	detailed bool
+	matches  func(key string) bool
+	skip     int // matching keys left to skip
+	limit    int
+	listed   int
+	tw       *tabwriter.Writer
+	now      time.Time
+}
+
+// errLimit stops the listing when the limit is reached.
+var errLimit = errors.New("limit reached")
+
+func (l *keyLister) run(ctx context.Context) error {
+	if l.detailed {
+		fmt.Fprint(l.tw, "KEY\tSIZE\tTTL\tLAST ACCESS\tFETCHED")
+		if len(l.cli.servers) > 1 {
+			fmt.Fprint(l.tw, "\tSERVER")
+		}
+		fmt.Fprintln(l.tw)
+	}
+
+	var err error
+	for _, addr := range l.cli.servers {
+		if err = l.list(ctx, addr); err != nil {
+			break
+		}
+	}
+	if flushErr := l.tw.Flush(); flushErr != nil {
+		return flushErr
+	}
+
+	if errors.Is(err, errLimit) {
+		fmt.Fprintf(l.cli.errOut, "limit of %d keys reached: list the next page with -offset %d\n", l.limit, l.skip+l.listed)
+		return nil
+	}
+	return err
+}
+
+// list lists the keys of a server. The read timeout applies to each read,
+// as a dump of a large cache takes longer than an operation.
+func (l *keyLister) list(ctx context.Context, addr string) error {
+	conn, err := l.cli.dial(ctx, addr)
+	if err != nil {
+		return fmt.Errorf("%s: %w", addr, err)
+	}
+	defer conn.Close()
+
+	timeout := l.cli.timeout
+	if err := conn.SetWriteDeadline(time.Now().Add(timeout)); err != nil {
+		return err
+	}
+	if err := meta.WriteRequest(conn, meta.Metadump()); err != nil {
+		return fmt.Errorf("%s: %w", addr, err)
+	}
+
+	r := bufio.NewReader(&idleTimeoutReader{conn: conn, timeout: timeout})
+	for item, err := range meta.ReadMetadump(r) {
+		if err != nil {
+			return fmt.Errorf("%s: %w", addr, err)
+		}
+		if !l.matches(item.Key) {
+			continue
+		}
+		if l.skip > 0 {
+			l.skip--
+			continue
+		}
+		if l.limit > 0 && l.listed == l.limit {
+			return errLimit
+		}
+		l.listed++
+		l.print(item, addr)
+	}
+	return nil
+}
+
+func (l *keyLister) print(item meta.KeyMetadata, addr string) {
+	if !l.detailed {
+		fmt.Fprintln(l.tw, item.Key)
+		return
+	}
+
+	ttl := "never"
+	if item.Expiration >= 0 {
+		ttl = time.Unix(item.Expiration, 0).Sub(l.now).Round(time.Second).String()
+	}
+	fetched := "no"
+	if item.Fetched {
+		fetched = "yes"
+	}
+	fmt.Fprintf(l.tw, "%s\t%d\t%s\t%s ago\t%s", item.Key, item.Size, ttl, l.now.Sub(item.LastAccess).Round(time.Second), fetched)
+	if len(l.cli.servers) > 1 {
+		fmt.Fprintf(l.tw, "\t%s", addr)
+	}
+	fmt.Fprintln(l.tw)
+}
+
+// idleTimeoutReader renews the read deadline of conn before each read.
+type idleTimeoutReader struct {
+	conn    net.Conn
+	timeout time.Duration
+}
+
+func (r *idleTimeoutReader) Read(b []byte) (int, error) {
+	if err := r.conn.SetReadDeadline(time.Now().Add(r.timeout)); err != nil {
+		return 0, err
+	}
+	return r.conn.Read(b)
+}
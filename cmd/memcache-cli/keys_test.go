@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/pior/memcache/memcachetest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeys(t *testing.T) {
+	srv := memcachetest.NewServer(t)
+	for _, key := range []string{"user:1", "user:2", "user:10", "session:1"} {
+		_, err := runCLI(t, srv, "set", key, "value")
+		require.NoError(t, err)
+	}
+
+	for name, tc := range map[string]struct {
+		args []string
+		out  string
+	}{
+		"all":    {args: []string{"keys"}, out: "session:1\nuser:1\nuser:10\nuser:2\n"},
+		"prefix": {args: []string{"keys", "user:"}, out: "user:1\nuser:10\nuser:2\n"},
+		"match":  {args: []string{"keys", "-match", `^user:\d$`}, out: "user:1\nuser:2\n"},
+		"limit":  {args: []string{"keys", "-limit", "2", "user:"}, out: "user:1\nuser:10\n"},
+		"offset": {args: []string{"keys", "-limit", "2", "-offset", "2", "user:"}, out: "user:2\n"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			out, err := runCLI(t, srv, tc.args...)
+			require.NoError(t, err)
+			assert.Equal(t, tc.out, out)
+		})
+	}
+
+	t.Run("limit notice", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		err := run(context.Background(), []string{"-servers", srv.Addr, "keys", "--limit", "3"}, strings.NewReader(""), &stdout, &stderr)
+		require.NoError(t, err)
+		assert.Equal(t, "limit of 3 keys reached: list the next page with -offset 3\n", stderr.String())
+	})
+}
+
+func TestDump(t *testing.T) {
+	srv := memcachetest.NewServer(t)
+	_, err := runCLI(t, srv, "set", "a", "value", "1h")
+	require.NoError(t, err)
+	_, err = runCLI(t, srv, "set", "b", "value")
+	require.NoError(t, err)
+
+	out, err := runCLI(t, srv, "dump")
+	require.NoError(t, err)
+	lines := strings.Split(out, "\n")
+	require.Len(t, lines, 4)
+	assert.Regexp(t, `^KEY +SIZE +TTL +LAST ACCESS +FETCHED$`, lines[0])
+	assert.Regexp(t, `^a +54 +(59m5\ds|1h0m0s) +\d+s ago +no$`, lines[1])
+	assert.Regexp(t, `^b +54 +never +\d+s ago +no$`, lines[2])
+}
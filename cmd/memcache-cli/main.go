@@ -0,0 +1,117 @@
+// Command memcache-cli runs memcache operations from the command line, for
+// operating and debugging memcached servers:
+//
+//	memcache-cli -servers cache1:11211,cache2:11211 get user:1
+//	memcache-cli set user:1 alice 1h
+//	memcache-cli debug user:1
+//
+// Keyed commands are routed to a server by the client, as the applications
+// using it do. Run memcache-cli -h for the list of commands.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/pior/memcache"
+)
+
+func main() {
+	// An interrupt stops the long-running commands (watch, meta) cleanly.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if err := run(ctx, os.Args[1:], os.Stdin, os.Stdout, os.Stderr); err != nil {
+		if !errors.Is(err, flag.ErrHelp) {
+			fmt.Fprintf(os.Stderr, "memcache-cli: %v\n", err)
+		}
+		stop()
+		os.Exit(1)
+	}
+}
+
+// cli holds the client and settings shared by the commands.
+type cli struct {
+	client  *memcache.Client
+	servers []string
+	timeout time.Duration
+	in      io.Reader
+	out     io.Writer
+	errOut  io.Writer // notices that would break the parsing of out
+
+	// interactive is set when the input is a terminal, to print prompts.
+	interactive bool
+}
+
+// run parses the flags and runs the command of args.
+func run(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("memcache-cli", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	servers := flags.String("servers", "127.0.0.1:11211", "comma-separated memcache server addresses")
+	timeout := flags.Duration("timeout", 2*time.Second, "timeout of each operation, and of each read of a key listing")
+	flags.Usage = func() { usage(flags) }
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return flag.ErrHelp
+	}
+	name, cmdArgs := flags.Arg(0), flags.Args()[1:]
+
+	cmd, ok := commands[name]
+	if !ok {
+		return fmt.Errorf("unknown command %q (see -h)", name)
+	}
+	if len(cmdArgs) < cmd.minArgs || (cmd.maxArgs >= 0 && len(cmdArgs) > cmd.maxArgs) {
+		return fmt.Errorf("usage: %s %s", name, cmd.usage)
+	}
+
+	c := &cli{
+		servers: strings.Split(*servers, ","),
+		timeout: *timeout,
+		in:      stdin,
+		out:     stdout,
+		errOut:  stderr,
+	}
+	if f, ok := stdin.(*os.File); ok {
+		if stat, err := f.Stat(); err == nil && stat.Mode()&os.ModeCharDevice != 0 {
+			c.interactive = true
+		}
+	}
+	c.client = memcache.NewClient(memcache.StaticServers(c.servers...), memcache.Config{
+		MaxSize:        1,
+		Timeout:        c.timeout,
+		ConnectTimeout: c.timeout,
+	})
+	defer c.client.Close()
+
+	return cmd.run(ctx, c, cmdArgs)
+}
+
+func usage(flags *flag.FlagSet) {
+	w := flags.Output()
+	fmt.Fprintf(w, "Usage: memcache-cli [flags] <command> [args]\n\nCommands:\n")
+
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		cmd := commands[name]
+		fmt.Fprintf(w, "  %-40s %s\n", name+" "+cmd.usage, cmd.help)
+	}
+
+	fmt.Fprintf(w, "\nTTLs are in seconds or durations (e.g. 90, 1h30m).\n\nFlags:\n")
+	flags.PrintDefaults()
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/pior/memcache/memcachetest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// runCLI runs a command against srv and returns its output.
+func runCLI(t *testing.T, srv *memcachetest.Server, args ...string) (string, error) {
+	t.Helper()
+	return runCLIWithInput(t, srv, "", args...)
+}
+
+func runCLIWithInput(t *testing.T, srv *memcachetest.Server, input string, args ...string) (string, error) {
+	t.Helper()
+
+	var stdout, stderr bytes.Buffer
+	err := run(context.Background(), append([]string{"-servers", srv.Addr}, args...), strings.NewReader(input), &stdout, &stderr)
+	return stdout.String(), err
+}
+
+func TestCommands(t *testing.T) {
+	srv := memcachetest.NewServer(t)
+
+	steps := []struct {
+		args []string
+		out  string
+		err  string
+	}{
+		{args: []string{"get", "key"}, err: "NOT_FOUND"},
+		{args: []string{"replace", "key", "v"}, err: "NOT_STORED"},
+		{args: []string{"append", "key", "v"}, err: "NOT_STORED"},
+		{args: []string{"set", "key", "hello", "1h"}, out: "STORED\n"},
+		{args: []string{"add", "key", "other"}, err: "NOT_STORED"},
+		{args: []string{"append", "key", "!"}, out: "STORED\n"},
+		{args: []string{"prepend", "key", ">"}, out: "STORED\n"},
+		{args: []string{"get", "key"}, out: ">hello!\n"},
+		{args: []string{"gets", "key"}, out: ">hello!\nCAS 3\n"},
+		{args: []string{"cas", "key", "stale", "2"}, err: "EXISTS"},
+		{args: []string{"cas", "key", "fresh", "3"}, out: "STORED\n"},
+		{args: []string{"gat", "key", "60"}, out: "fresh\n"},
+		{args: []string{"touch", "key", "90"}, out: "TOUCHED\n"},
+		{args: []string{"touch", "missing", "90"}, err: "NOT_FOUND"},
+		{args: []string{"delete", "key"}, out: "DELETED\n"},
+		{args: []string{"delete", "key"}, err: "NOT_FOUND"},
+		{args: []string{"incr", "counter"}, err: "NOT_FOUND"},
+		{args: []string{"set", "counter", "10"}, out: "STORED\n"},
+		{args: []string{"incr", "counter", "5"}, out: "15\n"},
+		{args: []string{"decr", "counter"}, out: "14\n"},
+		{args: []string{"version"}, out: srv.Addr + ": 1.6.0-memcachetest\n"},
+		{args: []string{"flush_all"}, out: srv.Addr + ": OK\n"},
+		{args: []string{"get", "counter"}, err: "NOT_FOUND"},
+	}
+
+	for _, step := range steps {
+		out, err := runCLI(t, srv, step.args...)
+		if step.err != "" {
+			require.EqualError(t, err, step.err, "%v", step.args)
+			continue
+		}
+		require.NoError(t, err, "%v", step.args)
+		assert.Equal(t, step.out, out, "%v", step.args)
+	}
+}
+
+func TestUsageErrors(t *testing.T) {
+	srv := memcachetest.NewServer(t)
+
+	_, err := runCLI(t, srv, "bogus")
+	assert.EqualError(t, err, `unknown command "bogus" (see -h)`)
+
+	_, err = runCLI(t, srv, "set", "key")
+	assert.EqualError(t, err, "usage: set <key> <value> [ttl]")
+
+	_, err = runCLI(t, srv, "set", "key", "value", "soon")
+	assert.EqualError(t, err, `invalid TTL "soon"`)
+}
+
+func TestParseTTL(t *testing.T) {
+	for input, want := range map[string]int{"0": 0, "90": 90, "1h30m": 5400, "1.6s": 2} {
+		got, err := parseTTL(input)
+		require.NoError(t, err, input)
+		assert.Equal(t, want, got, input)
+	}
+
+	_, err := parseTTL("-1")
+	assert.Error(t, err)
+}
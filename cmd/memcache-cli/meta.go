@@ -0,0 +1,267 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pior/memcache"
+	"github.com/pior/memcache/meta"
+)
+
+// runMeta sends raw meta commands and prints the parsed responses. The
+// command is read from the arguments, or line by line from the input when
+// there are none. The data block of ms is read from the next input line.
+func runMeta(ctx context.Context, c *cli, args []string) error {
+	s := &metaSession{cli: c, in: bufio.NewReader(c.in), conns: make(map[string]*metaConn)}
+	defer s.close()
+
+	if len(args) > 0 {
+		return s.do(ctx, strings.Join(args, " "))
+	}
+
+	for {
+		if c.interactive {
+			fmt.Fprint(c.out, "meta> ")
+		}
+		line, err := s.readLine()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		switch line = strings.TrimSpace(line); line {
+		case "":
+			continue
+		case "quit", "exit":
+			return nil
+		}
+
+		// The session goes on after an error: the user fixes the command.
+		if err := s.do(ctx, line); err != nil {
+			fmt.Fprintf(c.out, "error: %v\n", err)
+		}
+	}
+}
+
+// metaSession keeps a connection per server across the commands.
+type metaSession struct {
+	cli   *cli
+	in    *bufio.Reader
+	conns map[string]*metaConn
+}
+
+type metaConn struct {
+	conn net.Conn
+	r    *bufio.Reader
+	w    *bufio.Writer
+}
+
+func (s *metaSession) readLine() (string, error) {
+	line, err := s.in.ReadString('\n')
+	if err == io.EOF && line != "" {
+		err = nil
+	}
+	return strings.TrimRight(line, "\r\n"), err
+}
+
+// do sends a command line and prints its response.
+func (s *metaSession) do(ctx context.Context, line string) error {
+	req, err := s.parseRequest(line)
+	if err != nil {
+		return err
+	}
+
+	addr := s.cli.servers[0]
+	if req.Key != "" {
+		addr = s.cli.servers[memcache.DefaultServerSelector(req.Key, len(s.cli.servers))]
+	}
+	mc, err := s.conn(ctx, addr)
+	if err != nil {
+		return err
+	}
+
+	responses, err := mc.roundTrip(req, s.cli.timeout)
+	if err != nil {
+		// The stream may be out of sync: reconnect on next use.
+		_ = mc.conn.Close()
+		delete(s.conns, addr)
+		return err
+	}
+
+	if len(s.cli.servers) > 1 {
+		fmt.Fprintf(s.cli.out, "server: %s\n", addr)
+	}
+	if len(responses) == 0 {
+		fmt.Fprintln(s.cli.out, "(no response: suppressed by the q flag)")
+	}
+	for _, resp := range responses {
+		printResponse(s.cli.out, resp)
+	}
+	return nil
+}
+
+// parseRequest parses a meta command line: <cmd> <key> [<size>] <flags>*.
+func (s *metaSession) parseRequest(line string) (*meta.Request, error) {
+	fields := strings.Fields(line)
+	req := &meta.Request{Command: meta.CmdType(fields[0])}
+
+	switch req.Command {
+	case meta.CmdNoOp:
+		if len(fields) > 1 {
+			return nil, errors.New("usage: mn")
+		}
+		return req, nil
+	case meta.CmdGet, meta.CmdSet, meta.CmdDelete, meta.CmdArithmetic, meta.CmdDebug:
+	default:
+		return nil, fmt.Errorf("not a meta command: %q (mg, ms, md, ma, me or mn)", fields[0])
+	}
+
+	if len(fields) < 2 {
+		return nil, fmt.Errorf("usage: %s <key> <flags>*", req.Command)
+	}
+	req.Key, fields = fields[1], fields[2:]
+
+	if req.Command == meta.CmdSet {
+		if len(fields) == 0 {
+			return nil, errors.New("usage: ms <key> <size> <flags>*, followed by the data line")
+		}
+		size, err := strconv.Atoi(fields[0])
+		if err != nil || size < 0 {
+			return nil, fmt.Errorf("invalid size %q", fields[0])
+		}
+		fields = fields[1:]
+
+		data, err := s.readLine()
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		if len(data) != size {
+			return nil, fmt.Errorf("data is %d bytes, not %d", len(data), size)
+		}
+		req.Data = []byte(data)
+	}
+
+	for _, flag := range fields {
+		req.Flags = append(append(req.Flags, ' '), flag...)
+	}
+	return req, nil
+}
+
+func (s *metaSession) conn(ctx context.Context, addr string) (*metaConn, error) {
+	if mc, ok := s.conns[addr]; ok {
+		return mc, nil
+	}
+
+	conn, err := s.cli.dial(ctx, addr)
+	if err != nil {
+		return nil, err
+	}
+	mc := &metaConn{conn: conn, r: bufio.NewReader(conn), w: bufio.NewWriter(conn)}
+	s.conns[addr] = mc
+	return mc, nil
+}
+
+func (s *metaSession) close() {
+	for _, mc := range s.conns {
+		_ = mc.conn.Close()
+	}
+}
+
+// roundTrip sends req and reads its response. A quiet request may get no
+// response: it is followed by a noop, and the responses are read up to its
+// MN.
+func (mc *metaConn) roundTrip(req *meta.Request, timeout time.Duration) ([]*meta.Response, error) {
+	if err := mc.conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		return nil, err
+	}
+
+	quiet := req.HasFlag(meta.FlagQuiet)
+	if err := meta.WriteRequest(mc.w, req); err != nil {
+		return nil, err
+	}
+	if quiet {
+		if err := meta.WriteRequest(mc.w, meta.NoOp()); err != nil {
+			return nil, err
+		}
+	}
+	if err := mc.w.Flush(); err != nil {
+		return nil, err
+	}
+
+	var responses []*meta.Response
+	for {
+		resp := &meta.Response{}
+		if err := meta.ReadResponse(mc.r, resp); err != nil {
+			return nil, err
+		}
+		if quiet && resp.Status == meta.StatusMN {
+			return responses, nil
+		}
+		responses = append(responses, resp)
+		if !quiet {
+			return responses, nil
+		}
+	}
+}
+
+var statusNames = map[meta.StatusType]string{
+	meta.StatusHD: "success",
+	meta.StatusVA: "value",
+	meta.StatusEN: "miss",
+	meta.StatusNF: "not found",
+	meta.StatusNS: "not stored",
+	meta.StatusEX: "CAS mismatch",
+	meta.StatusMN: "no-op",
+	meta.StatusME: "debug",
+}
+
+var flagNames = map[meta.FlagType]string{
+	meta.FlagBase64Key:         "key is base64",
+	meta.FlagReturnCAS:         "CAS value",
+	meta.FlagReturnClientFlags: "client flags",
+	meta.FlagReturnHit:         "fetched before",
+	meta.FlagReturnKey:         "key",
+	meta.FlagReturnLastAccess:  "seconds since last access",
+	meta.FlagOpaque:            "opaque",
+	meta.FlagReturnSize:        "value size",
+	meta.FlagReturnTTL:         "remaining TTL (-1: never expires)",
+	meta.FlagWin:               "won the recache",
+	meta.FlagStale:             "stale",
+	meta.FlagAlreadyWon:        "recache already won by another client",
+}
+
+// printResponse prints resp with a description of its status and flags.
+func printResponse(w io.Writer, resp *meta.Response) {
+	if resp.HasError() {
+		fmt.Fprintf(w, "error: %v\n", resp.Error)
+		return
+	}
+
+	fmt.Fprintf(w, "status: %s (%s)\n", resp.Status, statusNames[resp.Status])
+	for _, flag := range strings.Fields(string(resp.Flags)) {
+		name := flagNames[meta.FlagType(flag[0])]
+		if name == "" {
+			name = "unknown flag"
+		}
+		fmt.Fprintf(w, "  %c %-20s %s\n", flag[0], flag[1:], name)
+	}
+
+	switch resp.Status {
+	case meta.StatusVA:
+		fmt.Fprintf(w, "data: %q (%d bytes)\n", resp.Data, len(resp.Data))
+	case meta.StatusME:
+		for _, param := range strings.Fields(string(resp.Data)) {
+			key, value, _ := strings.Cut(param, "=")
+			fmt.Fprintf(w, "  %-22s %s\n", key, value)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/pior/memcache/memcachetest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMeta(t *testing.T) {
+	srv := memcachetest.NewServer(t)
+
+	t.Run("command from the arguments", func(t *testing.T) {
+		out, err := runCLI(t, srv, "meta", "mg", "missing", "v")
+		require.NoError(t, err)
+		assert.Equal(t, "status: EN (miss)\n", out)
+	})
+
+	t.Run("session", func(t *testing.T) {
+		input := "ms key 5 T0 c\nhello\n\nmg key v t c\nmg missing v q\nbogus key\nms key 3\nhello\nmn\nquit\nmn\n"
+
+		out, err := runCLIWithInput(t, srv, input, "meta")
+		require.NoError(t, err)
+		assert.Equal(t, `status: HD (success)
+  c 1                    CAS value
+status: VA (value)
+  t -1                   remaining TTL (-1: never expires)
+  c 1                    CAS value
+data: "hello" (5 bytes)
+(no response: suppressed by the q flag)
+error: not a meta command: "bogus" (mg, ms, md, ma, me or mn)
+error: data is 5 bytes, not 3
+status: MN (no-op)
+`, out)
+	})
+
+	t.Run("server error", func(t *testing.T) {
+		out, err := runCLI(t, srv, "meta", "ma", "key")
+		require.NoError(t, err)
+		assert.Equal(t, "error: CLIENT_ERROR: cannot increment or decrement non-numeric value\n", out)
+	})
+}
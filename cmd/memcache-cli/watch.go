@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"maps"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/pior/memcache/meta"
+)
+
+var watchStreams = []string{
+	meta.WatchFetchers,
+	meta.WatchMutations,
+	meta.WatchEvictions,
+	meta.WatchDeletions,
+	meta.WatchConnEvents,
+}
+
+// runWatch streams the watcher events of every server until interrupted.
+// The events are filtered by key with -prefix and -match; the events without
+// a key are dropped when filtering.
+func runWatch(ctx context.Context, c *cli, args []string) error {
+	flags := flag.NewFlagSet("watch", flag.ContinueOnError)
+	flags.SetOutput(c.errOut)
+	prefix := flags.String("prefix", "", "prefix the keys of the events must have")
+	match := flags.String("match", "", "regular expression the keys of the events must match")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	streams := flags.Args()
+	if len(streams) == 0 {
+		return errors.New("usage: watch [-prefix p] [-match regexp] <stream>...")
+	}
+	for _, stream := range streams {
+		if !slices.Contains(watchStreams, stream) {
+			return fmt.Errorf("unknown stream %q (%s)", stream, strings.Join(watchStreams, ", "))
+		}
+	}
+
+	var re *regexp.Regexp
+	if *match != "" {
+		var err error
+		if re, err = regexp.Compile(*match); err != nil {
+			return fmt.Errorf("invalid -match: %w", err)
+		}
+	}
+	filtered := *prefix != "" || re != nil
+	matches := func(key string) bool {
+		return strings.HasPrefix(key, *prefix) && (re == nil || re.MatchString(key))
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	events := make(chan serverEvent)
+	for _, addr := range c.servers {
+		go c.watch(ctx, addr, streams, events)
+	}
+
+	var failed error
+	for running := len(c.servers); running > 0; {
+		ev := <-events
+		if ev.Err != nil {
+			running--
+			if ctx.Err() == nil {
+				fmt.Fprintf(c.errOut, "%s: %v\n", ev.addr, ev.Err)
+				failed = errors.New("some servers failed")
+			}
+			continue
+		}
+
+		key, hasKey := ev.Fields["key"]
+		if hasKey {
+			if decoded, err := url.PathUnescape(key); err == nil {
+				key = decoded
+			}
+		}
+		if filtered && (!hasKey || !matches(key)) {
+			continue
+		}
+		c.printEvent(ev, key)
+	}
+	return failed
+}
+
+// serverEvent is a watch event of a server. The last event of a server has
+// Err set.
+type serverEvent struct {
+	meta.WatchEvent
+	addr string
+}
+
+// watch forwards the events of a server to events, until ctx is done or the
+// stream ends.
+func (c *cli) watch(ctx context.Context, addr string, streams []string, events chan<- serverEvent) {
+	conn, err := c.dial(ctx, addr)
+	if err != nil {
+		events <- serverEvent{WatchEvent: meta.WatchEvent{Err: err}, addr: addr}
+		return
+	}
+	defer conn.Close()
+
+	// The timeout applies to the start of the watch only: events may be rare.
+	if err := conn.SetDeadline(time.Now().Add(c.timeout)); err != nil {
+		events <- serverEvent{WatchEvent: meta.WatchEvent{Err: err}, addr: addr}
+		return
+	}
+	stream, err := meta.Watch(conn, streams...)
+	if err != nil {
+		events <- serverEvent{WatchEvent: meta.WatchEvent{Err: err}, addr: addr}
+		return
+	}
+	if err := conn.SetDeadline(time.Time{}); err != nil {
+		events <- serverEvent{WatchEvent: meta.WatchEvent{Err: err}, addr: addr}
+		return
+	}
+
+	// Closing the connection ends the stream with an error event.
+	stop := context.AfterFunc(ctx, func() { _ = conn.Close() })
+	defer stop()
+
+	for ev := range stream {
+		events <- serverEvent{WatchEvent: ev, addr: addr}
+	}
+}
+
+// printEvent prints an event on a line: its time, server, type and key,
+// followed by the other fields sorted by name.
+func (c *cli) printEvent(ev serverEvent, key string) {
+	var b strings.Builder
+	if !ev.Time.IsZero() {
+		b.WriteString(ev.Time.Format("15:04:05.000000 "))
+	}
+	if len(c.servers) > 1 {
+		b.WriteString(ev.addr + " ")
+	}
+
+	if ev.Type == "skipped" {
+		fmt.Fprintf(&b, "skipped %s events: the watcher reads too slowly", ev.Fields["skipped"])
+		fmt.Fprintln(c.out, b.String())
+		return
+	}
+
+	b.WriteString(ev.Type)
+	if key != "" {
+		b.WriteString(" " + key)
+	}
+	for _, name := range slices.Sorted(maps.Keys(ev.Fields)) {
+		switch name {
+		case "ts", "gid", "type", "key":
+			continue
+		}
+		fmt.Fprintf(&b, " %s=%s", name, ev.Fields[name])
+	}
+	fmt.Fprintln(c.out, b.String())
+}
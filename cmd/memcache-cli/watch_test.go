@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// watchServer serves a single watch request with the events, then closes
+// the connection. It returns the address and the request line received.
+func watchServer(t *testing.T, events string) (string, <-chan string) {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { ln.Close() })
+
+	requests := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		line, _ := bufio.NewReader(conn).ReadString('\n')
+		requests <- line
+		_, _ = io.WriteString(conn, "OK\r\n"+events)
+	}()
+	return ln.Addr().String(), requests
+}
+
+var eventTime = regexp.MustCompile(`^\d\d:\d\d:\d\d\.\d{6} `)
+
+func TestWatch(t *testing.T) {
+	events := "ts=1728000000.123456 gid=1 type=item_get key=user%3A1 status=found clsid=1 cfd=20 size=3\n" +
+		"ts=1728000000.223456 gid=2 type=item_store key=session%3A1 status=stored cmd=set ttl=60 clsid=1 cfd=20 size=5\n" +
+		"[skipped: 4]\n" +
+		"ts=1728000000.323456 gid=3 type=conn_new rip=127.0.0.1 rport=5000 transport=tcp cfd=21\n"
+
+	for name, tc := range map[string]struct {
+		args []string
+		out  []string
+	}{
+		"all": {
+			args: []string{"fetchers", "mutations"},
+			out: []string{
+				"item_get user:1 cfd=20 clsid=1 size=3 status=found",
+				"item_store session:1 cfd=20 clsid=1 cmd=set size=5 status=stored ttl=60",
+				"skipped 4 events: the watcher reads too slowly",
+				"conn_new cfd=21 rip=127.0.0.1 rport=5000 transport=tcp",
+			},
+		},
+		"prefix": {
+			args: []string{"-prefix", "user:", "fetchers", "mutations"},
+			out:  []string{"item_get user:1 cfd=20 clsid=1 size=3 status=found"},
+		},
+		"match": {
+			args: []string{"-match", `^session:\d+$`, "fetchers", "mutations"},
+			out:  []string{"item_store session:1 cfd=20 clsid=1 cmd=set size=5 status=stored ttl=60"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			addr, requests := watchServer(t, events)
+
+			var stdout, stderr bytes.Buffer
+			args := append([]string{"-servers", addr, "watch"}, tc.args...)
+			err := run(context.Background(), args, strings.NewReader(""), &stdout, &stderr)
+			assert.EqualError(t, err, "some servers failed") // the stream ended
+			assert.Equal(t, addr+": EOF\n", stderr.String())
+			assert.Equal(t, "watch fetchers mutations\r\n", <-requests)
+
+			var lines []string
+			for line := range strings.Lines(stdout.String()) {
+				// The time is in the local time zone.
+				lines = append(lines, eventTime.ReplaceAllString(strings.TrimSuffix(line, "\n"), ""))
+			}
+			assert.Equal(t, tc.out, lines)
+		})
+	}
+}
+
+func TestWatch_Interrupt(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		_, _ = bufio.NewReader(conn).ReadString('\n')
+		_, _ = io.WriteString(conn, "OK\r\nts=1.5 gid=1 type=deleted key=a\n")
+		_, _ = io.Copy(io.Discard, conn) // until the watch stops
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r, w := io.Pipe()
+	go func() {
+		// Interrupt once the first event is printed.
+		line, _ := bufio.NewReader(r).ReadString('\n')
+		assert.True(t, strings.HasSuffix(line, " deleted a\n"), line)
+		cancel()
+		_, _ = io.Copy(io.Discard, r)
+	}()
+
+	err = run(ctx, []string{"-servers", ln.Addr().String(), "watch", "deletions"}, strings.NewReader(""), w, io.Discard)
+	assert.NoError(t, err)
+}
+
+func TestWatch_UnknownStream(t *testing.T) {
+	err := run(context.Background(), []string{"watch", "everything"}, strings.NewReader(""), io.Discard, io.Discard)
+	assert.EqualError(t, err, `unknown stream "everything" (fetchers, mutations, evictions, deletions, connevents)`)
+}
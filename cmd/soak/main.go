@@ -0,0 +1,188 @@
+// Command soak runs randomized operations against memcached servers for
+// hours, checking every read against a shadow in-memory model: a read
+// returns a value written for its key, or a legitimate miss, never a wrong
+// value. It catches the rare response mix-ups, stale reads and corruptions
+// that the quick tests miss:
+//
+//	soak -servers cache1:11211,cache2:11211 -duration 4h -workers 32
+//
+// Each worker owns its keys, so its model is exact. The values name their
+// key and version and are padded to a size derived from both, so a
+// violation tells what was read instead: the value of another key, an older
+// version, or a corrupted value. The violations are printed as they happen,
+// and soak exits with status 1 when there was any.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pior/memcache"
+)
+
+func main() {
+	// An interrupt ends the run early, with its summary.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if err := run(ctx, os.Args[1:], os.Stdout, os.Stderr); err != nil {
+		if !errors.Is(err, flag.ErrHelp) {
+			fmt.Fprintf(os.Stderr, "soak: %v\n", err)
+		}
+		stop()
+		os.Exit(1)
+	}
+}
+
+type options struct {
+	servers  []string
+	duration time.Duration
+	workers  int
+	keys     int
+	maxValue int
+	ttl      time.Duration
+	timeout  time.Duration
+	pipeline int
+	report   time.Duration
+	seed     uint64
+	prefix   string
+}
+
+// errViolations fails a run that found violations.
+var errViolations = errors.New("consistency violations found")
+
+func run(ctx context.Context, args []string, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("soak", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	servers := flags.String("servers", "127.0.0.1:11211", "comma-separated memcache server addresses")
+	var opts options
+	flags.DurationVar(&opts.duration, "duration", time.Hour, "duration of the run")
+	flags.IntVar(&opts.workers, "workers", 16, "concurrent workers, each on its own keys")
+	flags.IntVar(&opts.keys, "keys", 1000, "keys per worker")
+	flags.IntVar(&opts.maxValue, "max-value", 16<<10, "maximum padding of the values, in bytes")
+	flags.DurationVar(&opts.ttl, "ttl", time.Hour, "TTL of the items")
+	flags.DurationVar(&opts.timeout, "timeout", time.Second, "timeout of each operation")
+	pipeline := flags.Int("pipeline", 0, "connections per server in pipelined mode, 0 for the pooled mode")
+	flags.DurationVar(&opts.report, "report", 10*time.Second, "interval of the progress reports")
+	flags.Uint64Var(&opts.seed, "seed", uint64(time.Now().UnixNano()), "seed of the random operations")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() > 0 {
+		return fmt.Errorf("unexpected arguments: %s", strings.Join(flags.Args(), " "))
+	}
+	if opts.workers <= 0 || opts.keys <= 0 || opts.maxValue < 0 {
+		return errors.New("-workers and -keys must be positive, -max-value not negative")
+	}
+	opts.servers = strings.Split(*servers, ",")
+	opts.pipeline = *pipeline
+	// The keys of a run are not those of the previous runs, which may be
+	// left with other versions.
+	opts.prefix = "soak:" + strconv.FormatUint(opts.seed, 36)
+
+	client := memcache.NewClient(memcache.StaticServers(opts.servers...), memcache.Config{
+		Timeout:       opts.timeout,
+		PipelineConns: int32(opts.pipeline),
+	})
+	defer client.Close()
+
+	fmt.Fprintf(stdout, "soak: %d workers x %d keys on %s for %s, seed %d\n",
+		opts.workers, opts.keys, strings.Join(opts.servers, ","), opts.duration, opts.seed)
+
+	s := &stats{out: stdout, start: time.Now()}
+	ctx, cancel := context.WithTimeout(ctx, opts.duration)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	for id := range opts.workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w := newWorker(client, id, opts, s)
+			for ctx.Err() == nil {
+				w.step(ctx)
+			}
+		}()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	ticker := time.NewTicker(opts.report)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.report("progress")
+		case <-done:
+			s.report("done")
+			if s.violations.Load() > 0 {
+				return errViolations
+			}
+			return nil
+		}
+	}
+}
+
+// stats counts the operations of the workers.
+type stats struct {
+	out   io.Writer
+	start time.Time
+
+	ops, hits, misses, failures, violations atomic.Int64
+
+	mu        sync.Mutex // serializes the output
+	lastError string
+}
+
+// op counts an operation. The errors are expected under failures (timeouts,
+// restarts): they are counted, not violations. Those of the run ending (ctx
+// done) are not counted.
+func (s *stats) op(ctx context.Context, name string, err error) {
+	s.ops.Add(1)
+	if err == nil || ctx.Err() != nil {
+		return
+	}
+	s.failures.Add(1)
+	s.mu.Lock()
+	s.lastError = name + ": " + err.Error()
+	s.mu.Unlock()
+}
+
+func (s *stats) hit()  { s.hits.Add(1) }
+func (s *stats) miss() { s.misses.Add(1) }
+
+func (s *stats) violation(op, key, detail string) {
+	s.violations.Add(1)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fmt.Fprintf(s.out, "VIOLATION %s %s %q: %s\n", time.Now().Format(time.RFC3339), op, key, detail)
+}
+
+func (s *stats) report(label string) {
+	elapsed := time.Since(s.start)
+	ops := s.ops.Load()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fmt.Fprintf(s.out, "%s: elapsed=%s ops=%d (%.0f/s) hits=%d misses=%d errors=%d violations=%d\n",
+		label, elapsed.Round(time.Second), ops, float64(ops)/elapsed.Seconds(),
+		s.hits.Load(), s.misses.Load(), s.failures.Load(), s.violations.Load())
+	if s.lastError != "" {
+		fmt.Fprintf(s.out, "  last error: %s\n", s.lastError)
+		s.lastError = ""
+	}
+}
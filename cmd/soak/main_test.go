@@ -0,0 +1,53 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/pior/memcache/memcachetest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRun(t *testing.T) {
+	srv := memcachetest.NewServer(t)
+
+	var stdout, stderr bytes.Buffer
+	err := run(context.Background(), []string{
+		"-servers", srv.Addr,
+		"-duration", "300ms",
+		"-workers", "4",
+		"-keys", "20",
+		"-max-value", "512",
+		"-seed", "42",
+	}, &stdout, &stderr)
+
+	require.NoError(t, err, stdout.String())
+	assert.Contains(t, stdout.String(), "soak: 4 workers x 20 keys on "+srv.Addr+" for 300ms, seed 42\n")
+	assert.Contains(t, stdout.String(), "done: ")
+	assert.Contains(t, stdout.String(), " violations=0\n")
+	assert.NotContains(t, stdout.String(), "VIOLATION")
+}
+
+func TestRun_Pipelined(t *testing.T) {
+	srv := memcachetest.NewServer(t)
+
+	var stdout, stderr bytes.Buffer
+	err := run(context.Background(), []string{
+		"-servers", srv.Addr,
+		"-duration", "200ms",
+		"-workers", "8",
+		"-keys", "10",
+		"-pipeline", "2",
+	}, &stdout, &stderr)
+
+	require.NoError(t, err, stdout.String())
+	assert.Contains(t, stdout.String(), " violations=0\n")
+}
+
+func TestRun_InvalidFlags(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	err := run(context.Background(), []string{"-workers", "0"}, &stdout, &stderr)
+	require.ErrorContains(t, err, "-workers and -keys must be positive")
+}
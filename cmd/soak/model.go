@@ -0,0 +1,250 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math/rand/v2"
+	"slices"
+	"strconv"
+
+	"github.com/pior/memcache"
+)
+
+// entry is the state of a key in the shadow model: the versions a read may
+// return. A miss is always legitimate, as the server evicts and expires
+// items; a value outside of the versions is a violation.
+//
+// A version is possible once its write was sent: a write that failed may have
+// been applied anyway (a timeout after the server stored it, a write still in
+// the socket buffer of a closed connection), so it adds a version without
+// removing the others.
+type entry struct {
+	versions []uint64 // possible versions, increasing
+	written  uint64   // last version written, for the diagnostics
+}
+
+// stored accounts for a write of version v, applied when ok.
+func (e *entry) stored(v uint64, ok bool) {
+	if ok {
+		e.versions = append(e.versions[:0], v)
+	} else {
+		e.versions = append(e.versions, v)
+	}
+	e.written = v
+}
+
+// deleted accounts for a successful delete.
+func (e *entry) deleted() {
+	e.versions = e.versions[:0]
+}
+
+// read checks a hit of the key, returning the violation, if any. Reading a
+// version rules out the older ones, while the newer ones may still land.
+func (e *entry) read(key string, item memcache.Item, maxSize int) string {
+	for i, v := range e.versions {
+		if bytes.Equal(item.Value, value(key, v, maxSize)) {
+			if item.Flags != uint32(v) {
+				return fmt.Sprintf("version %d read with the flags %d", v, item.Flags)
+			}
+			e.versions = e.versions[i:]
+			return ""
+		}
+	}
+	return e.diagnose(key, item.Value)
+}
+
+// diagnose describes a value that is not a possible version of key.
+func (e *entry) diagnose(key string, got []byte) string {
+	if len(e.versions) == 0 {
+		if owner, v, ok := parseValue(got); ok && owner == key && v <= e.written {
+			return fmt.Sprintf("version %d read after it was deleted", v)
+		}
+	}
+
+	owner, v, ok := parseValue(got)
+	switch {
+	case !ok:
+		return fmt.Sprintf("corrupted value %s, expected versions %v", excerpt(got), e.versions)
+	case owner != key:
+		return fmt.Sprintf("value of the key %q (version %d): responses mixed up", owner, v)
+	case v < firstOf(e.versions):
+		return fmt.Sprintf("stale version %d, expected versions %v", v, e.versions)
+	default:
+		return fmt.Sprintf("unexpected version %d (%d bytes), expected versions %v", v, len(got), e.versions)
+	}
+}
+
+func firstOf(versions []uint64) uint64 {
+	if len(versions) == 0 {
+		return 0
+	}
+	return versions[0]
+}
+
+// value returns the value of version v of key: a header naming the key and
+// the version, padded to a size derived from both, so a value of another key
+// or version, truncated or overwritten, never matches.
+func value(key string, v uint64, maxSize int) []byte {
+	header := key + "|" + strconv.FormatUint(v, 10) + "|"
+
+	h := fnv.New64a()
+	h.Write([]byte(header))
+	seed := h.Sum64()
+
+	size := len(header) + int(seed%uint64(maxSize+1))
+	buf := make([]byte, 0, size)
+	buf = append(buf, header...)
+	for len(buf) < size {
+		buf = append(buf, byte('a'+(seed+uint64(len(buf)))%26))
+	}
+	return buf
+}
+
+// parseValue returns the key and the version named by the header of a value.
+func parseValue(b []byte) (key string, v uint64, ok bool) {
+	name, rest, ok := bytes.Cut(b, []byte("|"))
+	if !ok {
+		return "", 0, false
+	}
+	version, _, ok := bytes.Cut(rest, []byte("|"))
+	if !ok {
+		return "", 0, false
+	}
+	v, err := strconv.ParseUint(string(version), 10, 64)
+	return string(name), v, err == nil
+}
+
+func excerpt(b []byte) string {
+	if len(b) > 40 {
+		return strconv.Quote(string(b[:40])) + "..."
+	}
+	return strconv.Quote(string(b))
+}
+
+// worker runs random operations on its own keys, checked against its model.
+// The keys of a worker are not written by the others, so its model is exact.
+type worker struct {
+	client  *memcache.Client
+	batch   *memcache.BatchCommands
+	keys    []string
+	entries map[string]*entry
+	next    uint64 // next version
+	rng     *rand.Rand
+	opts    options
+	stats   *stats
+}
+
+func newWorker(client *memcache.Client, id int, opts options, stats *stats) *worker {
+	w := &worker{
+		client:  client,
+		batch:   memcache.NewBatchCommands(client),
+		entries: make(map[string]*entry),
+		next:    1,
+		rng:     rand.New(rand.NewPCG(opts.seed, uint64(id))),
+		opts:    opts,
+		stats:   stats,
+	}
+	for i := range opts.keys {
+		key := fmt.Sprintf("%s:%d:%d", opts.prefix, id, i)
+		w.keys = append(w.keys, key)
+		w.entries[key] = &entry{}
+	}
+	return w
+}
+
+// step runs a random operation.
+func (w *worker) step(ctx context.Context) {
+	key := w.keys[w.rng.IntN(len(w.keys))]
+	e := w.entries[key]
+
+	switch n := w.rng.IntN(100); {
+	case n < 40:
+		w.get(ctx, key, e)
+	case n < 50:
+		w.multiGet(ctx)
+	case n < 80:
+		v := w.version()
+		err := w.client.Set(ctx, w.item(key, v))
+		w.stats.op(ctx, "set", err)
+		e.stored(v, err == nil)
+	case n < 90:
+		v := w.version()
+		err := w.client.Add(ctx, w.item(key, v))
+		switch {
+		case errors.Is(err, memcache.ErrNotStored):
+			w.stats.op(ctx, "add", nil)
+			if len(e.versions) == 0 {
+				w.stats.violation("add", key, "not stored, while the key was deleted")
+			}
+		default:
+			w.stats.op(ctx, "add", err)
+			e.stored(v, err == nil)
+		}
+	default:
+		err := w.client.Delete(ctx, key)
+		w.stats.op(ctx, "delete", err)
+		if err == nil {
+			e.deleted()
+		}
+	}
+}
+
+func (w *worker) version() uint64 {
+	v := w.next
+	w.next++
+	return v
+}
+
+func (w *worker) item(key string, v uint64) memcache.Item {
+	return memcache.Item{
+		Key:   key,
+		Value: value(key, v, w.opts.maxValue),
+		Flags: uint32(v),
+		TTL:   memcache.ExpiresIn(w.opts.ttl),
+	}
+}
+
+func (w *worker) get(ctx context.Context, key string, e *entry) {
+	item, err := w.client.Get(ctx, key)
+	w.stats.op(ctx, "get", err)
+	if err == nil {
+		w.check("get", key, e, item)
+	}
+}
+
+func (w *worker) multiGet(ctx context.Context) {
+	keys := make([]string, 1+w.rng.IntN(10))
+	for i := range keys {
+		keys[i] = w.keys[w.rng.IntN(len(w.keys))]
+	}
+	slices.Sort(keys)
+	keys = slices.Compact(keys)
+
+	items, err := w.batch.MultiGet(ctx, keys)
+	w.stats.op(ctx, "multiget", err)
+	var partial *memcache.PartialError
+	if err != nil && !errors.As(err, &partial) {
+		return
+	}
+	for i, item := range items {
+		if item.Key != keys[i] {
+			w.stats.violation("multiget", keys[i], fmt.Sprintf("item of the key %q", item.Key))
+			continue
+		}
+		w.check("multiget", keys[i], w.entries[keys[i]], item)
+	}
+}
+
+func (w *worker) check(op, key string, e *entry, item memcache.Item) {
+	if !item.Found {
+		w.stats.miss()
+		return
+	}
+	w.stats.hit()
+	if violation := e.read(key, item, w.opts.maxValue); violation != "" {
+		w.stats.violation(op, key, violation)
+	}
+}
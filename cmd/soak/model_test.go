@@ -0,0 +1,79 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/pior/memcache"
+	"github.com/stretchr/testify/assert"
+)
+
+func item(key string, v uint64) memcache.Item {
+	return memcache.Item{Key: key, Value: value(key, v, 64), Flags: uint32(v), Found: true}
+}
+
+func TestValue(t *testing.T) {
+	v := value("k", 7, 64)
+	assert.Equal(t, v, value("k", 7, 64))
+	assert.NotEqual(t, v, value("k", 8, 64))
+	assert.LessOrEqual(t, len(v), len("k|7|")+64)
+
+	key, version, ok := parseValue(v)
+	assert.True(t, ok)
+	assert.Equal(t, "k", key)
+	assert.Equal(t, uint64(7), version)
+}
+
+func TestEntry(t *testing.T) {
+	t.Run("reads the version written", func(t *testing.T) {
+		var e entry
+		e.stored(1, true)
+		assert.Empty(t, e.read("k", item("k", 1), 64))
+	})
+
+	t.Run("failed writes may have been applied", func(t *testing.T) {
+		var e entry
+		e.stored(1, true)
+		e.stored(2, false)
+		e.stored(3, false)
+
+		assert.Empty(t, e.read("k", item("k", 2), 64))
+		assert.Equal(t, []uint64{2, 3}, e.versions, "version 1 is ruled out")
+		assert.Empty(t, e.read("k", item("k", 3), 64))
+		assert.Equal(t, "stale version 2, expected versions [3]", e.read("k", item("k", 2), 64))
+	})
+
+	t.Run("value of another key", func(t *testing.T) {
+		var e entry
+		e.stored(1, true)
+		got := item("k", 1)
+		got.Value = value("other", 5, 64)
+		assert.Equal(t, `value of the key "other" (version 5): responses mixed up`, e.read("k", got, 64))
+	})
+
+	t.Run("corrupted value", func(t *testing.T) {
+		var e entry
+		e.stored(1, true)
+		got := item("k", 1)
+		got.Value = append([]byte(nil), got.Value...)
+		got.Value[len(got.Value)-1] ^= 0xff
+		assert.Contains(t, e.read("k", got, 64), "unexpected version 1")
+
+		got.Value = []byte("garbage")
+		assert.Equal(t, `corrupted value "garbage", expected versions [1]`, e.read("k", got, 64))
+	})
+
+	t.Run("flags of another version", func(t *testing.T) {
+		var e entry
+		e.stored(1, true)
+		got := item("k", 1)
+		got.Flags = 2
+		assert.Equal(t, "version 1 read with the flags 2", e.read("k", got, 64))
+	})
+
+	t.Run("deleted", func(t *testing.T) {
+		var e entry
+		e.stored(1, true)
+		e.deleted()
+		assert.Equal(t, "version 1 read after it was deleted", e.read("k", item("k", 1), 64))
+	})
+}
@@ -0,0 +1,101 @@
+package memcache
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"sync"
+
+	"github.com/pior/memcache/meta"
+)
+
+// getCoalescer shares the gets in flight between the identical gets issued
+// meanwhile (Config.CoalesceGets).
+type getCoalescer struct {
+	mu    sync.Mutex
+	calls map[string]*coalescedGet // by request key and flags
+}
+
+// coalescedGet is a get in flight. resp, err and canceled are set before
+// done is closed.
+type coalescedGet struct {
+	done     chan struct{}
+	resp     *meta.Response
+	err      error
+	canceled bool // the context of the get was done
+}
+
+// isCoalescedGet reports whether req can share the response of an identical
+// request in flight: a plain mg, without the flags changing the item (vivify,
+// recache) or hiding the misses (quiet).
+func isCoalescedGet(req *meta.Request) bool {
+	return req.Command == meta.CmdGet &&
+		!req.HasFlag(meta.FlagVivify) &&
+		!req.HasFlag(meta.FlagRecache) &&
+		!req.HasFlag(meta.FlagQuiet)
+}
+
+// isCoalescedCall reports whether the get req, made with ctx, can share the
+// response of an identical request in flight: not the gets of a
+// WriteSession, which must not share a get sent before their writes, nor
+// those with their own timeout (WithTimeout).
+func isCoalescedCall(ctx context.Context, req *meta.Request) bool {
+	return isCoalescedGet(req) &&
+		ctx.Value(writeSessionKey{}) == nil &&
+		ctx.Value(timeoutKey{}) == nil
+}
+
+// do executes req with execute, unless an identical request is in flight: its
+// response is then shared, as a copy. When the shared get fails with its
+// context done (canceled or timed out), or with its socket deadline derived
+// from it, the requests sharing it are executed on their own.
+func (g *getCoalescer) do(ctx context.Context, sp *ServerPool, req *meta.Request, execute func() (*meta.Response, error)) (*meta.Response, error) {
+	key := req.Key + string(req.Flags)
+
+	g.mu.Lock()
+	if call, ok := g.calls[key]; ok {
+		g.mu.Unlock()
+		sp.coalescedGets.Add(1)
+
+		select {
+		case <-call.done:
+		case <-ctx.Done():
+			return nil, sp.wrapErr(string(req.Command), req.Key, ctx.Err())
+		}
+		if call.err != nil {
+			if call.canceled && ctx.Err() == nil {
+				return execute()
+			}
+			return nil, call.err
+		}
+		return copyResponse(call.resp), nil
+	}
+
+	call := &coalescedGet{done: make(chan struct{})}
+	if g.calls == nil {
+		g.calls = make(map[string]*coalescedGet)
+	}
+	g.calls[key] = call
+	g.mu.Unlock()
+
+	call.resp, call.err = execute()
+	// The socket deadline of the get, derived from its context, may expire
+	// before its context: the failure is the leader's too.
+	call.canceled = ctx.Err() != nil || errors.Is(call.err, os.ErrDeadlineExceeded)
+
+	g.mu.Lock()
+	delete(g.calls, key)
+	g.mu.Unlock()
+	close(call.done)
+
+	return call.resp, call.err
+}
+
+// copyResponse returns a copy of resp, not sharing its value and flags.
+func copyResponse(resp *meta.Response) *meta.Response {
+	c := *resp
+	c.Data = bytes.Clone(resp.Data)
+	c.Flags = bytes.Clone(resp.Flags)
+	return &c
+}
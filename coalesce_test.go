@@ -0,0 +1,206 @@
+package memcache
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pior/memcache/meta"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newSlowGetServer starts a server answering each get with the value "v"
+// after delay, and returns its address and the number of gets received.
+func newSlowGetServer(t *testing.T, delay time.Duration) (string, *atomic.Int32) {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = ln.Close() })
+
+	var gets atomic.Int32
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func(c net.Conn) {
+				defer c.Close()
+				r := bufio.NewReader(c)
+				for {
+					line, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if strings.HasPrefix(line, "mg") {
+						gets.Add(1)
+						time.Sleep(delay)
+						_, _ = c.Write([]byte("VA 1 f0\r\nv\r\n"))
+					}
+				}
+			}(conn)
+		}
+	}()
+
+	return ln.Addr().String(), &gets
+}
+
+func TestCoalesceGets(t *testing.T) {
+	addr, gets := newSlowGetServer(t, 50*time.Millisecond)
+	client := NewClient(StaticServers(addr), Config{CoalesceGets: true})
+	t.Cleanup(client.Close)
+
+	const callers = 10
+	items := make([]Item, callers)
+	var wg sync.WaitGroup
+	for i := range callers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var err error
+			items[i], err = client.Get(context.Background(), "key")
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(1), gets.Load())
+	for _, item := range items {
+		assert.Equal(t, "v", string(item.Value))
+	}
+	items[0].Value[0] = 'x' // the values are not shared
+	assert.Equal(t, "v", string(items[1].Value))
+	assert.Equal(t, uint64(callers-1), client.PoolMetrics()[0].CoalescedGets)
+
+	// The gets issued afterwards are sent.
+	_, err := client.Get(context.Background(), "key")
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), gets.Load())
+}
+
+func TestCoalesceGets_CanceledLeader(t *testing.T) {
+	addr, gets := newSlowGetServer(t, 50*time.Millisecond)
+	client := NewClient(StaticServers(addr), Config{CoalesceGets: true})
+	t.Cleanup(client.Close)
+
+	leaderCtx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		_, err := client.Get(leaderCtx, "key")
+		assert.Error(t, err)
+	}()
+	time.Sleep(5 * time.Millisecond) // let the leader send its get
+
+	item, err := client.Get(context.Background(), "key")
+	require.NoError(t, err)
+	assert.Equal(t, "v", string(item.Value))
+	assert.Equal(t, int32(2), gets.Load())
+	wg.Wait()
+}
+
+func TestCoalesceGets_LeaderDeadline(t *testing.T) {
+	sp := newIdleServerPool(t, Config{})
+	var g getCoalescer
+	req := meta.NewRequest(meta.CmdGet, "key", nil)
+	ctx := context.Background()
+
+	release := make(chan struct{})
+	leader := make(chan error, 1)
+	go func() {
+		_, err := g.do(ctx, sp, req, func() (*meta.Response, error) {
+			<-release
+			// The socket deadline of the leader expired before its context.
+			return nil, &OpError{Op: "mg", Key: "key", Err: os.ErrDeadlineExceeded}
+		})
+		leader <- err
+	}()
+	require.Eventually(t, func() bool {
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		return len(g.calls) == 1
+	}, time.Second, time.Millisecond)
+
+	waiter := make(chan *meta.Response, 1)
+	go func() {
+		resp, err := g.do(ctx, sp, req, func() (*meta.Response, error) {
+			return &meta.Response{Status: meta.StatusVA, Data: []byte("v")}, nil
+		})
+		assert.NoError(t, err)
+		waiter <- resp
+	}()
+	require.Eventually(t, func() bool { return sp.coalescedGets.Load() == 1 }, time.Second, time.Millisecond)
+	close(release)
+
+	require.ErrorIs(t, <-leader, os.ErrDeadlineExceeded)
+	assert.Equal(t, "v", string((<-waiter).Data), "the waiter executed its own get")
+}
+
+func TestCoalesceGets_WithTimeout(t *testing.T) {
+	addr, gets := newSlowGetServer(t, 20*time.Millisecond)
+	client := NewClient(StaticServers(addr), Config{CoalesceGets: true})
+	t.Cleanup(client.Close)
+
+	// The gets with their own timeout don't share a get.
+	var wg sync.WaitGroup
+	for range 3 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := client.Get(context.Background(), "key", WithTimeout(time.Second))
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(3), gets.Load())
+}
+
+func TestCoalesceGets_WriteSession(t *testing.T) {
+	addr, gets := newSlowGetServer(t, 20*time.Millisecond)
+	client := NewClient(StaticServers(addr), Config{CoalesceGets: true})
+	t.Cleanup(client.Close)
+
+	// The gets of a session read their writes: they don't share a get.
+	session := NewWriteSession()
+	var wg sync.WaitGroup
+	for range 3 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := client.Get(context.Background(), "key", WithWriteSession(session))
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(3), gets.Load())
+	assert.Zero(t, client.PoolMetrics()[0].CoalescedGets)
+}
+
+func TestCoalesceGets_Disabled(t *testing.T) {
+	addr, gets := newSlowGetServer(t, 20*time.Millisecond)
+	client := NewClient(StaticServers(addr), Config{})
+	t.Cleanup(client.Close)
+
+	var wg sync.WaitGroup
+	for range 3 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := client.Get(context.Background(), "key")
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(3), gets.Load())
+}
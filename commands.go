@@ -4,16 +4,17 @@ import (
 	"context"
 	"fmt"
 	"strconv"
+	"time"
 
 	"github.com/pior/memcache/meta"
 )
 
 type Querier interface {
-	Get(ctx context.Context, key string) (Item, error)
-	Set(ctx context.Context, item Item) error
-	Add(ctx context.Context, item Item) error
-	Delete(ctx context.Context, key string) error
-	Increment(ctx context.Context, key string, delta int64, ttl TTL) (int64, error)
+	Get(ctx context.Context, key string, opts ...CallOption) (Item, error)
+	Set(ctx context.Context, item Item, opts ...CallOption) error
+	Add(ctx context.Context, item Item, opts ...CallOption) error
+	Delete(ctx context.Context, key string, opts ...CallOption) error
+	Increment(ctx context.Context, key string, delta int64, ttl TTL, opts ...CallOption) (int64, error)
 }
 
 // Executor executes a memcache request for a given key.
@@ -53,13 +54,44 @@ func NewCommands(executor Executor) *Commands {
 }
 
 // Get retrieves a single item from memcache.
-func (c *Commands) Get(ctx context.Context, key string) (Item, error) {
-	req := meta.NewRequest(meta.CmdGet, key, nil).AddReturnValue()
-	resp, err := c.executor.Execute(ctx, req)
+func (c *Commands) Get(ctx context.Context, key string, opts ...CallOption) (Item, error) {
+	return c.GetWithOptions(ctx, key, GetOptions{}, opts...)
+}
+
+// GetWithOptions retrieves a single item from memcache, with the metadata
+// selected by opts (CAS, remaining TTL, last access, ...).
+func (c *Commands) GetWithOptions(ctx context.Context, key string, opts GetOptions, callOpts ...CallOption) (Item, error) {
+	resp, err := c.execute(ctx, newGetRequest(key, opts), callOpts)
 	if err != nil {
 		return Item{}, err
 	}
+	return itemFromGetResponse(key, resp)
+}
 
+// newGetRequest builds the mg request of GetWithOptions.
+func newGetRequest(key string, opts GetOptions) *meta.Request {
+	req := meta.NewRequest(meta.CmdGet, key, nil).AddReturnValue().AddReturnClientFlags()
+	if opts.ReturnCAS {
+		req.AddReturnCAS()
+	}
+	if opts.ReturnTTL {
+		req.AddReturnTTL()
+	}
+	if opts.ReturnLastAccess {
+		req.AddReturnLastAccess()
+	}
+	if opts.ReturnSize {
+		req.AddReturnSize()
+	}
+	if opts.ReturnHit {
+		req.AddReturnHit()
+	}
+	return req
+}
+
+// itemFromGetResponse converts the response of a request built by
+// newGetRequest.
+func itemFromGetResponse(key string, resp *meta.Response) (Item, error) {
 	if resp.IsMiss() {
 		return Item{Key: key, Found: false}, nil
 	}
@@ -69,26 +101,158 @@ func (c *Commands) Get(ctx context.Context, key string) (Item, error) {
 	}
 
 	if !resp.IsSuccess() {
-		return Item{}, fmt.Errorf("unexpected response status: %s", resp.Status)
+		return Item{}, &StatusError{Op: "get", Status: resp.Status}
 	}
 
-	return Item{
+	item := Item{
 		Key:   key,
 		Value: resp.Data,
 		Found: true,
-	}, nil
+	}
+	item.Flags, _ = resp.ClientFlags()
+	item.CAS, _ = resp.CAS()
+	item.Size, _ = resp.Size()
+	item.Hit, _ = resp.Hit()
+	item.LastAccess, _ = resp.GetFlagDuration(meta.FlagReturnLastAccess)
+	if remaining, ok := resp.GetFlagDuration(meta.FlagReturnTTL); ok {
+		switch {
+		case remaining == 0:
+			// Expiring within the second, not NoTTL: a write-back (Update)
+			// would store it without expiration.
+			item.TTL = ExpiresIn(time.Second)
+		case remaining > 0:
+			item.TTL = ExpiresIn(remaining)
+		} // -1 means no expiration: NoTTL
+	}
+	return item, nil
+}
+
+// GetIfNotModified revalidates an item read earlier with its CAS value (see
+// GetOptions.ReturnCAS), ETag-style. When the item still has the CAS value
+// cas, it returns modified false and the item without its value, which is
+// not transferred. Otherwise it reads the item again, in a second round
+// trip, and returns modified true with the current item and its CAS value;
+// Found is false when the item was removed.
+func (c *Commands) GetIfNotModified(ctx context.Context, key string, cas uint64, opts ...CallOption) (item Item, modified bool, err error) {
+	req := meta.NewRequest(meta.CmdGet, key, nil).AddReturnCAS().AddReturnClientFlags().AddReturnSize()
+
+	resp, err := c.execute(ctx, req, opts)
+	if err != nil {
+		return Item{}, false, err
+	}
+
+	if resp.HasError() {
+		return Item{}, false, resp.Error
+	}
+
+	if resp.IsMiss() {
+		return Item{Key: key, Found: false}, true, nil
+	}
+
+	if !resp.IsSuccess() {
+		return Item{}, false, &StatusError{Op: "get", Status: resp.Status}
+	}
+
+	if current, _ := resp.CAS(); current != cas {
+		item, err := c.GetWithOptions(ctx, key, GetOptions{ReturnCAS: true}, opts...)
+		return item, true, err
+	}
+
+	item = Item{Key: key, Found: true, CAS: cas}
+	item.Flags, _ = resp.ClientFlags()
+	item.Size, _ = resp.Size()
+	return item, false, nil
+}
+
+// TTL returns the remaining time to live of an item, without fetching its
+// value (mg with the t flag only), e.g. to audit the expirations. found is
+// false when the item doesn't exist. An item without expiration has a
+// negative TTL: -1s, as reported by the server.
+func (c *Commands) TTL(ctx context.Context, key string, opts ...CallOption) (ttl time.Duration, found bool, err error) {
+	req := meta.NewRequest(meta.CmdGet, key, nil).AddReturnTTL()
+
+	resp, err := c.execute(ctx, req, opts)
+	if err != nil {
+		return 0, false, err
+	}
+
+	if resp.IsMiss() {
+		return 0, false, nil
+	}
+
+	if resp.HasError() {
+		return 0, false, resp.Error
+	}
+
+	if !resp.IsSuccess() {
+		return 0, false, &StatusError{Op: "get", Status: resp.Status}
+	}
+
+	ttl, ok := resp.GetFlagDuration(meta.FlagReturnTTL)
+	if !ok {
+		return 0, false, &meta.ParseError{Message: "response missing the t flag"}
+	}
+	return ttl, true, nil
+}
+
+// Exists reports whether an item exists, without fetching its value (mg
+// without the v flag), e.g. to check a large item cheaply. Unlike a get, it
+// doesn't bump the item in the LRU.
+func (c *Commands) Exists(ctx context.Context, key string, opts ...CallOption) (bool, error) {
+	req := meta.NewRequest(meta.CmdGet, key, nil).AddNoLRUBump()
+
+	resp, err := c.execute(ctx, req, opts)
+	if err != nil {
+		return false, err
+	}
+
+	if resp.IsMiss() {
+		return false, nil
+	}
+
+	if resp.HasError() {
+		return false, resp.Error
+	}
+
+	if !resp.IsSuccess() {
+		return false, &StatusError{Op: "get", Status: resp.Status}
+	}
+	return true, nil
+}
+
+// Metadata returns an item without its value, which is not transferred: its
+// flags, CAS value, remaining TTL, time since the last access and value size.
+// Found is false when the item doesn't exist. Unlike a get, it doesn't bump
+// the item in the LRU, nor update its last access.
+func (c *Commands) Metadata(ctx context.Context, key string, opts ...CallOption) (Item, error) {
+	req := meta.NewRequest(meta.CmdGet, key, nil).
+		AddReturnClientFlags().
+		AddReturnCAS().
+		AddReturnTTL().
+		AddReturnLastAccess().
+		AddReturnSize().
+		AddNoLRUBump()
+
+	resp, err := c.execute(ctx, req, opts)
+	if err != nil {
+		return Item{}, err
+	}
+	return itemFromGetResponse(key, resp)
 }
 
 // Set stores an item in memcache.
-func (c *Commands) Set(ctx context.Context, item Item) error {
+func (c *Commands) Set(ctx context.Context, item Item, opts ...CallOption) error {
 	req := meta.NewRequest(meta.CmdSet, item.Key, item.Value)
 
 	// Add TTL flag if specified, otherwise use no expiration
 	if exptime := item.TTL.Expiration(); exptime != 0 {
 		req.AddTTL(exptime)
 	}
+	if item.Flags != 0 {
+		req.AddClientFlags(item.Flags)
+	}
 
-	resp, err := c.executor.Execute(ctx, req)
+	resp, err := c.execute(ctx, req, opts)
 	if err != nil {
 		return err
 	}
@@ -98,20 +262,23 @@ func (c *Commands) Set(ctx context.Context, item Item) error {
 	}
 
 	if !resp.IsSuccess() {
-		return fmt.Errorf("set failed with status: %s", resp.Status)
+		return &StatusError{Op: "set", Status: resp.Status}
 	}
 
 	return nil
 }
 
 // Add stores an item in memcache only if the key doesn't already exist.
-func (c *Commands) Add(ctx context.Context, item Item) error {
+func (c *Commands) Add(ctx context.Context, item Item, opts ...CallOption) error {
 	req := meta.NewRequest(meta.CmdSet, item.Key, item.Value).AddModeAdd()
 	if exptime := item.TTL.Expiration(); exptime != 0 {
 		req.AddTTL(exptime)
 	}
+	if item.Flags != 0 {
+		req.AddClientFlags(item.Flags)
+	}
 
-	resp, err := c.executor.Execute(ctx, req)
+	resp, err := c.execute(ctx, req, opts)
 	if err != nil {
 		return err
 	}
@@ -121,20 +288,36 @@ func (c *Commands) Add(ctx context.Context, item Item) error {
 	}
 
 	if resp.IsNotStored() {
-		return fmt.Errorf("%w: key already exists", ErrNotStored)
+		return ErrKeyExists
 	}
 
 	if !resp.IsSuccess() {
-		return fmt.Errorf("add failed with status: %s", resp.Status)
+		return &StatusError{Op: "add", Status: resp.Status}
 	}
 
 	return nil
 }
 
 // Delete removes an item from memcache.
-func (c *Commands) Delete(ctx context.Context, key string) error {
+func (c *Commands) Delete(ctx context.Context, key string, opts ...CallOption) error {
+	return c.DeleteWithOptions(ctx, key, DeleteOptions{}, opts...)
+}
+
+// DeleteWithOptions removes an item from memcache, or marks it as stale, as
+// selected by opts. Like Delete, it succeeds if the key doesn't exist.
+func (c *Commands) DeleteWithOptions(ctx context.Context, key string, opts DeleteOptions, callOpts ...CallOption) error {
 	req := meta.NewRequest(meta.CmdDelete, key, nil)
-	resp, err := c.executor.Execute(ctx, req)
+	if opts.CAS != 0 {
+		req.AddCAS(opts.CAS)
+	}
+	if opts.Invalidate {
+		req.AddInvalidate()
+		if opts.StaleTTL > 0 {
+			req.AddFlagDuration(meta.FlagTTL, opts.StaleTTL)
+		}
+	}
+
+	resp, err := c.execute(ctx, req, callOpts)
 	if err != nil {
 		return err
 	}
@@ -145,7 +328,7 @@ func (c *Commands) Delete(ctx context.Context, key string) error {
 
 	// Delete is successful even if key doesn't exist
 	if resp.Status != meta.StatusHD && resp.Status != meta.StatusNF {
-		return fmt.Errorf("delete failed with status: %s", resp.Status)
+		return &StatusError{Op: "delete", Status: resp.Status}
 	}
 
 	return nil
@@ -156,53 +339,104 @@ func (c *Commands) Delete(ctx context.Context, key string) error {
 // This uses auto-vivify (N flag) with initial value (J flag) set to the delta,
 // so the returned value is correct even on first call.
 // NoTTL means infinite TTL.
-func (c *Commands) Increment(ctx context.Context, key string, delta int64, ttl TTL) (int64, error) {
-	req := meta.NewRequest(meta.CmdArithmetic, key, nil).AddReturnValue()
-
-	// Encode the TTL for the vivify flag
-	exptime := ttl.Expiration()
-
-	if delta >= 0 {
-		// Positive delta - use increment mode (default)
-		req.AddDelta(uint64(delta))
-		req.AddInitialValue(uint64(delta)) // Initialize to delta on creation
-		req.AddVivify(exptime)             // Auto-create with specified TTL
-	} else {
+func (c *Commands) Increment(ctx context.Context, key string, delta int64, ttl TTL, callOpts ...CallOption) (int64, error) {
+	opts := IncrementOptions{
+		Delta:         uint64(delta),
+		AutoCreate:    true,
+		Initial:       uint64(delta), // Initialize to delta on creation
+		AutoCreateTTL: ttl,
+		TTL:           ttl, // Update the TTL of existing keys if an expiration is set
+	}
+	if delta < 0 {
 		// Negative delta - use decrement mode with absolute value
 		// For decrement, initialize to 0 since we can't have negative counters
-		req.AddDelta(uint64(-delta)) // Use absolute value
-		req.AddModeDecrement()
-		req.AddInitialValue(0) // Initialize to 0 on creation
-		req.AddVivify(exptime) // Auto-create with specified TTL
+		opts.Delta = uint64(-delta)
+		opts.Mode = ModeDecrement
+		opts.Initial = 0
 	}
 
-	// Add TTL flag to update the TTL of existing keys if an expiration is set
-	if exptime != 0 {
-		req.AddTTL(exptime)
+	resp, err := c.arithmetic(ctx, newArithmeticRequest(key, opts), callOpts)
+	if err != nil {
+		return 0, err
 	}
 
+	value, err := strconv.ParseInt(string(resp.Data), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse increment result: %w", err)
+	}
+
+	return value, nil
+}
+
+// IncrementWithOptions applies an arithmetic operation to a counter key, as
+// selected by opts, and returns the new value and CAS of the counter.
+// Without opts.AutoCreate, a missing key fails with ErrNotFound.
+func (c *Commands) IncrementWithOptions(ctx context.Context, key string, opts IncrementOptions, callOpts ...CallOption) (value, cas uint64, err error) {
+	resp, err := c.arithmetic(ctx, newArithmeticRequest(key, opts).AddReturnCAS(), callOpts)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	value, err = strconv.ParseUint(string(resp.Data), 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to parse increment result: %w", err)
+	}
+	cas, _ = resp.CAS()
+
+	return value, cas, nil
+}
+
+// execute executes req with the call options applied.
+func (c *Commands) execute(ctx context.Context, req *meta.Request, opts []CallOption) (*meta.Response, error) {
+	ctx = applyCallOptions(ctx, []*meta.Request{req}, opts)
 	resp, err := c.executor.Execute(ctx, req)
 	if err != nil {
-		return 0, err
+		return resp, err
+	}
+	if session, ok := ctx.Value(writeSessionKey{}).(*WriteSession); ok && session.tracks(req) {
+		if err := session.observe(req, resp); err != nil {
+			return nil, err
+		}
+	}
+	return resp, nil
+}
+
+// newArithmeticRequest builds the ma request for opts.
+func newArithmeticRequest(key string, opts IncrementOptions) *meta.Request {
+	req := meta.NewRequest(meta.CmdArithmetic, key, nil).AddReturnValue()
+	req.AddDelta(opts.Delta)
+	if opts.Mode == ModeDecrement {
+		req.AddModeDecrement()
+	}
+	if opts.AutoCreate {
+		req.AddInitialValue(opts.Initial)
+		req.AddVivify(opts.AutoCreateTTL.Expiration()) // Auto-create with specified TTL
+	}
+	if exptime := opts.TTL.Expiration(); exptime != 0 {
+		req.AddTTL(exptime)
+	}
+	return req
+}
+
+// arithmetic executes an ma request and checks that its response carries the
+// counter value.
+func (c *Commands) arithmetic(ctx context.Context, req *meta.Request, opts []CallOption) (*meta.Response, error) {
+	resp, err := c.execute(ctx, req, opts)
+	if err != nil {
+		return nil, err
 	}
 
 	if resp.HasError() {
-		return 0, resp.Error
+		return nil, resp.Error
 	}
 
 	if !resp.IsSuccess() {
-		return 0, fmt.Errorf("increment failed with status: %s", resp.Status)
+		return nil, &StatusError{Op: "increment", Status: resp.Status}
 	}
 
-	// Parse the returned value
 	if !resp.HasValue() {
-		return 0, fmt.Errorf("increment response missing value")
+		return nil, fmt.Errorf("increment response missing value")
 	}
 
-	value, err := strconv.ParseInt(string(resp.Data), 10, 64)
-	if err != nil {
-		return 0, fmt.Errorf("failed to parse increment result: %w", err)
-	}
-
-	return value, nil
+	return resp, nil
 }
@@ -2,6 +2,7 @@ package memcache
 
 import (
 	"bufio"
+	"bytes"
 	"context"
 	"fmt"
 	"net"
@@ -23,19 +24,62 @@ func NewConnection(conn net.Conn, timeout time.Duration) *Connection {
 	}
 }
 
+// newPooledConnection creates a connection of a ServerPool, with buffers of
+// the sizes of the config, recycled from the closed connections. Close
+// recycles its buffers up to config.MaxRetainedBufferSize bytes: the
+// connection must not be used concurrently with Close.
+func newPooledConnection(conn net.Conn, config Config) *Connection {
+	readSize, writeSize := config.ReadBufferSize, config.WriteBufferSize
+	if readSize <= 0 {
+		readSize = defaultBufferSize
+	}
+	if writeSize <= 0 {
+		writeSize = defaultBufferSize
+	}
+	return &Connection{
+		conn:              conn,
+		Reader:            connBuffers.getReader(conn, readSize),
+		Writer:            connBuffers.getWriter(conn, writeSize),
+		defaultTimeout:    config.Timeout,
+		maxRetainedBuffer: config.MaxRetainedBufferSize,
+		text:              config.Protocol == ProtocolText,
+	}
+}
+
+// defaultBufferSize is the default size of the bufio buffers of a
+// connection, the bufio default.
+const defaultBufferSize = 4096
+
 // Connection wraps a network connection with buffered reader and writer for efficient I/O.
 type Connection struct {
 	conn   net.Conn
 	Reader *bufio.Reader
 	Writer *bufio.Writer
 
+	// maxRetainedBuffer is the buffer size up to which Close recycles the
+	// buffers. Zero means they are not recycled.
+	maxRetainedBuffer int
+
 	// defaultTimeout is a per-operation upper bound on the deadline, capping
 	// even a context that has a later (or no) deadline. Zero means no cap.
 	defaultTimeout time.Duration
+
+	// lifetimeJitter extends MaxConnLifetime for this connection, so the
+	// connections of a pool don't all expire at once.
+	lifetimeJitter time.Duration
+
+	// text translates the requests to the text protocol (ProtocolText).
+	text bool
 }
 
 func (c *Connection) Close() error {
-	return c.conn.Close()
+	err := c.conn.Close()
+	if c.maxRetainedBuffer > 0 && c.Reader != nil {
+		connBuffers.putReader(c.Reader, c.maxRetainedBuffer)
+		connBuffers.putWriter(c.Writer, c.maxRetainedBuffer)
+		c.Reader, c.Writer = nil, nil
+	}
+	return err
 }
 
 // setDeadline sets the connection deadline to the earlier of the context
@@ -45,12 +89,13 @@ func (c *Connection) Close() error {
 // or job-scoped one), using the context deadline verbatim would leave the read
 // effectively unbounded and let a single unresponsive backend stall the client.
 // A zero defaultTimeout means "no cap, defer entirely to the context".
+// A timeout set for the call with WithTimeout replaces defaultTimeout.
 // Returns the deadline that was set (zero if no deadline).
 func (c *Connection) setDeadline(ctx context.Context) (time.Time, error) {
 	var deadline time.Time
 
-	if c.defaultTimeout > 0 {
-		deadline = time.Now().Add(c.defaultTimeout)
+	if timeout := callTimeout(ctx, c.defaultTimeout); timeout > 0 {
+		deadline = time.Now().Add(timeout)
 	}
 
 	// A context deadline that is sooner than the default-timeout cap wins; a
@@ -80,6 +125,10 @@ func (c *Connection) Execute(ctx context.Context, req *meta.Request) (*meta.Resp
 	// Clear deadline when done to avoid stale deadlines when connection is reused from pool
 	defer c.conn.SetDeadline(time.Time{})
 
+	if c.text {
+		return c.executeText(req)
+	}
+
 	// Write request to buffered writer
 	if err := meta.WriteRequest(c.Writer, req); err != nil {
 		return nil, err
@@ -94,6 +143,9 @@ func (c *Connection) Execute(ctx context.Context, req *meta.Request) (*meta.Resp
 	if err := meta.ReadResponse(c.Reader, &resp); err != nil {
 		return nil, err
 	}
+	if err := verifyOpaque(req, &resp); err != nil {
+		return nil, err
+	}
 	return &resp, nil
 }
 
@@ -117,6 +169,9 @@ func (c *Connection) ExecuteBatch(ctx context.Context, reqs []*meta.Request) ([]
 	if len(reqs) == 0 {
 		return nil, nil
 	}
+	if c.text {
+		return c.executeTextBatch(ctx, reqs)
+	}
 
 	// Validate all keys before writing anything, so a rejected request cannot
 	// leave earlier requests of the batch sitting in the write buffer.
@@ -193,10 +248,45 @@ func (c *Connection) ExecuteBatch(ctx context.Context, reqs []*meta.Request) ([]
 			Message: fmt.Sprintf("received %d responses for %d requests in batch", len(responses), len(reqs)),
 		}
 	}
+	if !hasQuiet {
+		if err := verifyOpaques(reqs, responses); err != nil {
+			return responses, err
+		}
+	}
 
 	return responses, nil
 }
 
+// verifyOpaques checks that the responses of a batch, matched by position,
+// carry the opaque tokens of their requests that have one. A mismatch means
+// that the connection is desynchronized. The protocol error responses carry
+// no opaque token.
+func verifyOpaques(reqs []*meta.Request, responses []*meta.Response) error {
+	for i, resp := range responses {
+		want, ok := reqs[i].GetFlagToken(meta.FlagOpaque)
+		if !ok || resp.HasError() {
+			continue
+		}
+		if got, _ := resp.Opaque(); !bytes.Equal(got, want) {
+			return fmt.Errorf("%w: response %d has opaque %q, want %q", ErrResponseMismatch, i, got, want)
+		}
+	}
+	return nil
+}
+
+// verifyOpaque checks that resp carries the opaque token of req, if it has
+// one, as verifyOpaques for a batch.
+func verifyOpaque(req *meta.Request, resp *meta.Response) error {
+	want, ok := req.GetFlagToken(meta.FlagOpaque)
+	if !ok || resp.HasError() {
+		return nil
+	}
+	if got, _ := resp.Opaque(); !bytes.Equal(got, want) {
+		return fmt.Errorf("%w: response has opaque %q, want %q", ErrResponseMismatch, got, want)
+	}
+	return nil
+}
+
 // ExecuteStats implements the StatsExecutor interface.
 // Executes the stats command and returns the stats as a map.
 func (c *Connection) ExecuteStats(ctx context.Context, args ...string) (map[string]string, error) {
@@ -57,6 +57,31 @@ func TestConnection_ExecuteBatch_ResponseCountMismatch(t *testing.T) {
 	assert.Len(t, resps, 1)
 }
 
+// A response carrying another opaque token than its request means the
+// connection is desynchronized.
+func TestConnection_ExecuteBatch_OpaqueMismatch(t *testing.T) {
+	conn, _ := newMockConnection("VA 1 O1\r\na\r\n", "VA 1 O1\r\nb\r\n", "MN\r\n")
+
+	reqs := []*meta.Request{getReq("k1").AddOpaque("1"), getReq("k2").AddOpaque("2")}
+	_, err := conn.ExecuteBatch(context.Background(), reqs)
+	require.ErrorIs(t, err, ErrResponseMismatch)
+	assert.True(t, meta.ShouldCloseConnection(err))
+}
+
+func TestConnection_Execute_OpaqueMismatch(t *testing.T) {
+	conn, _ := newMockConnection("VA 1 O2\r\na\r\n", "EN O3\r\n", "HD\r\n")
+
+	_, err := conn.Execute(context.Background(), getReq("k1").AddOpaque("1"))
+	require.ErrorIs(t, err, ErrResponseMismatch)
+
+	resp, err := conn.Execute(context.Background(), getReq("k2").AddOpaque("3"))
+	require.NoError(t, err)
+	assert.True(t, resp.IsMiss())
+
+	_, err = conn.Execute(context.Background(), getReq("k3")) // no token to verify
+	require.NoError(t, err)
+}
+
 // With quiet requests, suppressed responses are legal: no count check.
 func TestConnection_ExecuteBatch_QuietSuppressedResponses(t *testing.T) {
 	conn, _ := newMockConnection("VA 2\r\nv1\r\n", "MN\r\n") // miss response suppressed
@@ -0,0 +1,157 @@
+package memcache
+
+import (
+	"context"
+	"encoding/json"
+	"expvar"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// OpCounters is a Hook counting operations by type, for debug endpoints that
+// don't need a metrics system. See DebugHandler and PublishExpvar.
+//
+// Besides lifetime totals, it keeps per-second buckets over the last minute,
+// so a snapshot shows the current traffic rather than an average since start.
+type OpCounters struct {
+	mu  sync.Mutex
+	ops map[string]*opCounter
+	now func() time.Time
+}
+
+var _ Hook = (*OpCounters)(nil)
+
+const opCountersWindow = 60 // seconds
+
+type opCounter struct {
+	calls    uint64
+	errors   uint64
+	duration time.Duration
+
+	// window holds the last minute of calls, one bucket per second, indexed
+	// by unix second modulo the window size.
+	window [opCountersWindow]opCountersBucket
+}
+
+type opCountersBucket struct {
+	second int64
+	calls  uint64
+	errors uint64
+}
+
+// NewOpCounters creates empty operation counters.
+func NewOpCounters() *OpCounters {
+	return &OpCounters{
+		ops: make(map[string]*opCounter),
+		now: time.Now,
+	}
+}
+
+// BeforeOp implements Hook.
+func (c *OpCounters) BeforeOp(ctx context.Context, op OpInfo) context.Context {
+	return ctx
+}
+
+// AfterOp implements Hook.
+func (c *OpCounters) AfterOp(ctx context.Context, op OpInfo, result OpResult) {
+	second := c.now().Unix()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	counter, ok := c.ops[op.Op]
+	if !ok {
+		counter = &opCounter{}
+		c.ops[op.Op] = counter
+	}
+
+	counter.calls++
+	counter.duration += result.Duration
+
+	bucket := &counter.window[second%opCountersWindow]
+	if bucket.second != second {
+		*bucket = opCountersBucket{second: second}
+	}
+	bucket.calls++
+
+	if result.Err != nil {
+		counter.errors++
+		bucket.errors++
+	}
+}
+
+// OpCountersSnapshot is a point-in-time view of the counters of one operation type.
+type OpCountersSnapshot struct {
+	Calls       uint64        `json:"calls"`
+	Errors      uint64        `json:"errors"`
+	AvgDuration time.Duration `json:"avg_duration_ns"`
+
+	CallsLastMinute  uint64 `json:"calls_last_minute"`
+	ErrorsLastMinute uint64 `json:"errors_last_minute"`
+}
+
+// Snapshot returns the counters by operation ("mg", "ms", "batch", ...).
+func (c *OpCounters) Snapshot() map[string]OpCountersSnapshot {
+	oldest := c.now().Unix() - opCountersWindow
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	snapshot := make(map[string]OpCountersSnapshot, len(c.ops))
+	for op, counter := range c.ops {
+		s := OpCountersSnapshot{
+			Calls:       counter.calls,
+			Errors:      counter.errors,
+			AvgDuration: counter.duration / time.Duration(counter.calls),
+		}
+		for _, bucket := range counter.window {
+			if bucket.second > oldest {
+				s.CallsLastMinute += bucket.calls
+				s.ErrorsLastMinute += bucket.errors
+			}
+		}
+		snapshot[op] = s
+	}
+	return snapshot
+}
+
+// DebugStats is the document served by DebugHandler and PublishExpvar.
+type DebugStats struct {
+	Pools      []PoolMetrics                 `json:"pools"`
+	Operations map[string]OpCountersSnapshot `json:"operations,omitempty"`
+}
+
+func debugStats(client *Client, counters *OpCounters) DebugStats {
+	stats := DebugStats{Pools: client.PoolMetrics()}
+	if counters != nil {
+		stats.Operations = counters.Snapshot()
+	}
+	return stats
+}
+
+// DebugHandler returns an http.Handler serving the client's pool metrics and,
+// when counters is not nil, the operation counters as JSON.
+// The counters must be registered in Config.Hooks to be populated.
+//
+//	counters := memcache.NewOpCounters()
+//	client := memcache.NewClient(servers, memcache.Config{Hooks: []memcache.Hook{counters}})
+//	http.Handle("/debug/memcache", memcache.DebugHandler(client, counters))
+func DebugHandler(client *Client, counters *OpCounters) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(debugStats(client, counters))
+	})
+}
+
+// PublishExpvar publishes the client's pool metrics and, when counters is not
+// nil, the operation counters as an expvar variable, served on /debug/vars
+// by the expvar package.
+// Like expvar.Publish, it panics if the name is already registered.
+func PublishExpvar(name string, client *Client, counters *OpCounters) {
+	expvar.Publish(name, expvar.Func(func() any {
+		return debugStats(client, counters)
+	}))
+}
@@ -0,0 +1,76 @@
+package memcache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/pior/memcache/internal/testutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpCounters(t *testing.T) {
+	now := time.Unix(1_000_000, 0)
+	counters := NewOpCounters()
+	counters.now = func() time.Time { return now }
+
+	ctx := context.Background()
+	get := OpInfo{Op: "mg"}
+	counters.AfterOp(ctx, get, OpResult{Duration: 10 * time.Millisecond})
+	counters.AfterOp(ctx, get, OpResult{Duration: 30 * time.Millisecond, Err: errors.New("boom")})
+
+	now = now.Add(30 * time.Second)
+	counters.AfterOp(ctx, get, OpResult{Duration: 20 * time.Millisecond})
+	counters.AfterOp(ctx, OpInfo{Op: "ms"}, OpResult{Duration: time.Millisecond})
+
+	snapshot := counters.Snapshot()
+	assert.Equal(t, OpCountersSnapshot{
+		Calls:            3,
+		Errors:           1,
+		AvgDuration:      20 * time.Millisecond,
+		CallsLastMinute:  3,
+		ErrorsLastMinute: 1,
+	}, snapshot["mg"])
+	assert.Equal(t, uint64(1), snapshot["ms"].Calls)
+
+	// The first two calls leave the window
+	now = now.Add(45 * time.Second)
+	snapshot = counters.Snapshot()
+	assert.Equal(t, uint64(3), snapshot["mg"].Calls)
+	assert.Equal(t, uint64(1), snapshot["mg"].CallsLastMinute)
+	assert.Equal(t, uint64(0), snapshot["mg"].ErrorsLastMinute)
+}
+
+func TestDebugHandler(t *testing.T) {
+	counters := NewOpCounters()
+	client := newHookedClient(t, testutils.NewConnectionMock("EN\r\n"), counters)
+
+	_, err := client.Get(context.Background(), "key1")
+	require.NoError(t, err)
+
+	rec := httptest.NewRecorder()
+	DebugHandler(client, counters).ServeHTTP(rec, httptest.NewRequest("GET", "/debug/memcache", nil))
+
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+	var stats DebugStats
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &stats))
+	require.Len(t, stats.Pools, 1)
+	assert.Equal(t, "localhost:11211", stats.Pools[0].Addr)
+	assert.Equal(t, uint64(1), stats.Operations["mg"].Calls)
+}
+
+func TestPublishExpvar(t *testing.T) {
+	client := newTestClient(t, testutils.NewConnectionMock())
+
+	PublishExpvar("memcache_test_publish", client, nil)
+
+	v := expvar.Get("memcache_test_publish")
+	require.NotNil(t, v)
+	assert.JSONEq(t, `{"pools":[]}`, v.String())
+}
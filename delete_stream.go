@@ -0,0 +1,227 @@
+package memcache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/pior/memcache/meta"
+)
+
+// DeleteStreamOptions tunes MultiDeleteStreamWithOptions.
+type DeleteStreamOptions struct {
+	// Concurrency is the number of batches in flight.
+	// Default: 1
+	Concurrency int
+
+	// BatchSize is the maximum number of keys of a batch. A batch takes the
+	// keys available on the channel, without waiting for more.
+	// Default: 100
+	BatchSize int
+
+	// Rate limits the deletions, in keys per second, to spare the servers
+	// serving the traffic. A batch is capped to Rate keys.
+	// Default: 0 (unlimited)
+	Rate int
+
+	// OnProgress is called with the counts after each batch, one call at a
+	// time, e.g. to log the progress of a long job. It must not block.
+	// If nil, nothing is called.
+	OnProgress func(DeleteStreamProgress)
+}
+
+// DeleteStreamProgress counts the keys processed by a MultiDeleteStream.
+type DeleteStreamProgress struct {
+	Deleted  int64
+	NotFound int64 // already missing: not an error
+	Failed   int64 // the deletion failed, see the error returned
+}
+
+const defaultDeleteStreamBatchSize = 100
+
+// MultiDeleteStream deletes the keys received on keys, until it is closed,
+// with concurrency batches in flight. See MultiDeleteStreamWithOptions.
+func (b *BatchCommands) MultiDeleteStream(ctx context.Context, keys <-chan string, concurrency int, opts ...CallOption) (DeleteStreamProgress, error) {
+	return b.MultiDeleteStreamWithOptions(ctx, keys, DeleteStreamOptions{Concurrency: concurrency}, opts...)
+}
+
+// MultiDeleteStreamWithOptions deletes the keys received on keys, until it is
+// closed, in pipelined batches (as MultiDelete), e.g. to purge the millions of
+// keys of a metadump scan. It returns the counts of the keys processed.
+//
+// A failure doesn't stop the stream: the keys of the failed batches, or of
+// their failed servers, are counted as Failed and the first error is
+// returned once the channel is drained. When ctx is done, the stream stops
+// and returns the error of ctx: the producer should stop sending on ctx too.
+func (b *BatchCommands) MultiDeleteStreamWithOptions(ctx context.Context, keys <-chan string, opts DeleteStreamOptions, callOpts ...CallOption) (DeleteStreamProgress, error) {
+	concurrency := max(opts.Concurrency, 1)
+	batchSize := opts.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultDeleteStreamBatchSize
+	}
+
+	var pacer *ratePacer
+	if opts.Rate > 0 {
+		batchSize = min(batchSize, opts.Rate)
+		pacer = &ratePacer{interval: time.Second / time.Duration(opts.Rate)}
+	}
+
+	var (
+		mu       sync.Mutex
+		progress DeleteStreamProgress
+		firstErr error
+		wg       sync.WaitGroup
+	)
+
+	for range concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for {
+				batch := receiveBatch(ctx, keys, batchSize)
+				if len(batch) == 0 {
+					return
+				}
+				if pacer != nil {
+					if err := pacer.wait(ctx, len(batch)); err != nil {
+						return
+					}
+				}
+
+				p, err := b.deleteBatch(ctx, batch, callOpts)
+
+				mu.Lock()
+				progress.Deleted += p.Deleted
+				progress.NotFound += p.NotFound
+				progress.Failed += p.Failed
+				if err != nil && firstErr == nil {
+					firstErr = err
+				}
+				if opts.OnProgress != nil {
+					opts.OnProgress(progress)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return progress, err
+	}
+	return progress, firstErr
+}
+
+// receiveBatch receives up to size keys: it waits for the first one, then
+// takes those available. It returns no key when keys is closed or ctx done.
+func receiveBatch(ctx context.Context, keys <-chan string, size int) []string {
+	var batch []string
+
+	select {
+	case <-ctx.Done():
+		return nil
+	case key, ok := <-keys:
+		if !ok {
+			return nil
+		}
+		batch = append(batch, key)
+	}
+
+	for len(batch) < size {
+		select {
+		case key, ok := <-keys:
+			if !ok {
+				return batch
+			}
+			batch = append(batch, key)
+		default:
+			return batch
+		}
+	}
+	return batch
+}
+
+// deleteBatch deletes keys in a batch, and counts the outcome of each key.
+func (b *BatchCommands) deleteBatch(ctx context.Context, keys []string, opts []CallOption) (DeleteStreamProgress, error) {
+	var progress DeleteStreamProgress
+
+	reqs := make([]*meta.Request, len(keys))
+	for i, key := range keys {
+		reqs[i] = meta.NewRequest(meta.CmdDelete, key, nil)
+	}
+
+	ctx = applyCallOptions(ctx, reqs, opts)
+	responses, err := b.executor.ExecuteBatch(ctx, reqs)
+	var partial *PartialError
+	if err != nil && !errors.As(err, &partial) {
+		progress.Failed = int64(len(keys))
+		return progress, err
+	}
+	if len(responses) != len(keys) {
+		progress.Failed = int64(len(keys))
+		return progress, fmt.Errorf("memcache: got %d responses for %d keys", len(responses), len(keys))
+	}
+
+	var firstErr error
+	if partial != nil {
+		firstErr = partial
+	}
+	for i, resp := range responses {
+		switch {
+		case resp == nil: // its server failed: reported by the partial error
+			progress.Failed++
+		case resp.HasError():
+			progress.Failed++
+			if firstErr == nil {
+				firstErr = resp.Error
+			}
+		case resp.Status == meta.StatusHD:
+			progress.Deleted++
+		case resp.Status == meta.StatusNF:
+			progress.NotFound++
+		default:
+			progress.Failed++
+			if firstErr == nil {
+				firstErr = &StatusError{Op: "delete", Key: keys[i], Status: resp.Status}
+			}
+		}
+	}
+	return progress, firstErr
+}
+
+// ratePacer spaces out operations to a rate, shared by several goroutines.
+type ratePacer struct {
+	interval time.Duration // per unit
+
+	mu   sync.Mutex
+	next time.Time
+}
+
+// wait waits for the turn of n units, or until ctx is done.
+func (p *ratePacer) wait(ctx context.Context, n int) error {
+	p.mu.Lock()
+	now := time.Now()
+	if p.next.Before(now) {
+		p.next = now
+	}
+	at := p.next
+	p.next = p.next.Add(time.Duration(n) * p.interval)
+	p.mu.Unlock()
+
+	delay := time.Until(at)
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
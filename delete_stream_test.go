@@ -0,0 +1,81 @@
+package memcache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// keyStream returns a closed channel holding keys.
+func keyStream(keys ...string) <-chan string {
+	ch := make(chan string, len(keys))
+	for _, key := range keys {
+		ch <- key
+	}
+	close(ch)
+	return ch
+}
+
+func TestBatchCommands_MultiDeleteStream(t *testing.T) {
+	t.Run("batches and progress", func(t *testing.T) {
+		bc, mock := newBatchTestClient(t,
+			"HD\r\n", "NF\r\n", "MN\r\n",
+			"HD\r\n", "HD\r\n", "MN\r\n",
+			"HD\r\n", "MN\r\n",
+		)
+
+		var reports []DeleteStreamProgress
+		progress, err := bc.MultiDeleteStreamWithOptions(context.Background(), keyStream("k1", "k2", "k3", "k4", "k5"), DeleteStreamOptions{
+			BatchSize:  2,
+			OnProgress: func(p DeleteStreamProgress) { reports = append(reports, p) },
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, DeleteStreamProgress{Deleted: 4, NotFound: 1}, progress)
+		assert.Equal(t, []DeleteStreamProgress{
+			{Deleted: 1, NotFound: 1},
+			{Deleted: 3, NotFound: 1},
+			{Deleted: 4, NotFound: 1},
+		}, reports)
+		assert.Equal(t, "md k1\r\nmd k2\r\nmn\r\nmd k3\r\nmd k4\r\nmn\r\nmd k5\r\nmn\r\n", mock.GetWrittenRequest())
+	})
+
+	t.Run("failures are counted and the stream goes on", func(t *testing.T) {
+		client, _ := newPartialTestClient(t, "HD\r\n", "MN\r\n", "HD\r\n", "MN\r\n")
+
+		progress, err := NewBatchCommands(client).MultiDeleteStreamWithOptions(context.Background(), keyStream("good1", "bad1", "good2"), DeleteStreamOptions{
+			BatchSize: 2,
+		})
+
+		var partial *PartialError
+		require.ErrorAs(t, err, &partial)
+		require.ErrorIs(t, err, errPartialDial)
+		assert.Equal(t, DeleteStreamProgress{Deleted: 2, Failed: 1}, progress)
+	})
+
+	t.Run("rate limited", func(t *testing.T) {
+		bc, _ := newBatchTestClient(t, "HD\r\n", "MN\r\n", "HD\r\n", "MN\r\n", "HD\r\n", "MN\r\n")
+
+		start := time.Now()
+		progress, err := bc.MultiDeleteStreamWithOptions(context.Background(), keyStream("k1", "k2", "k3"), DeleteStreamOptions{
+			BatchSize: 1,
+			Rate:      20, // one key per 50ms
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, DeleteStreamProgress{Deleted: 3}, progress)
+		assert.GreaterOrEqual(t, time.Since(start), 100*time.Millisecond)
+	})
+
+	t.Run("context canceled", func(t *testing.T) {
+		bc, _ := newBatchTestClient(t)
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err := bc.MultiDeleteStream(ctx, make(chan string), 2)
+		require.ErrorIs(t, err, context.Canceled)
+	})
+}
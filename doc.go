@@ -15,6 +15,13 @@
 //	_ = client.Set(ctx, memcache.Item{Key: "mykey", Value: []byte("hello")})
 //	item, _ := client.Get(ctx, "mykey")
 //
+// # Errors
+//
+// Outcomes of conditional operations are sentinel errors, checked with
+// errors.Is: [ErrNotStored], [ErrKeyExists], [ErrCASConflict] and
+// [ErrNotFound]. An unexpected response status is a [StatusError], which
+// matches the sentinel of its status.
+//
 // # Building Blocks
 //
 // The client is assembled from smaller pieces that can be used on their own to
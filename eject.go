@@ -0,0 +1,155 @@
+package memcache
+
+import (
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// AutoEjectPolicy configures the auto-ejection of the failing servers
+// (Config.AutoEject): after Failures consecutive failures, a server is removed
+// from the server list for CoolDown, and its keys are spread over the other
+// servers instead of failing. The keys of the other servers don't move: the
+// servers are selected over the whole list, and only the keys of an ejected
+// server are selected again over the servers left. After CoolDown, the
+// server is added back; if it still fails, its next failure ejects it again.
+//
+// Like twemproxy's auto_eject_hosts, it suits the caches whose misses are
+// cheaper than the failures: the keys of an ejected server miss on the
+// servers taking them over, and move back when it is added back. The servers
+// are never all ejected: without any server left, the keys go to their
+// server as usual.
+//
+// The failures are those counted by the circuit breaker (see ServerState).
+type AutoEjectPolicy struct {
+	// Failures is the number of consecutive failures ejecting a server.
+	// Default: 3
+	Failures int
+
+	// CoolDown is how long a server stays ejected.
+	// Default: 30s
+	CoolDown time.Duration
+
+	// OnEject is called when a server is ejected, with its last error, and
+	// OnRestore when it is added back. They are called synchronously by the
+	// operations and must not block.
+	// If nil, nothing is called.
+	OnEject   func(addr string, err error)
+	OnRestore func(addr string)
+}
+
+const (
+	defaultEjectFailures = 3
+	defaultEjectCoolDown = 30 * time.Second
+)
+
+// serverEjections tracks the servers ejected by Config.AutoEject. It is keyed
+// by address, independently of the pools, so an ejection survives the pools
+// replaced by UpdateConfig.
+type serverEjections struct {
+	count atomic.Int32 // len(until), for the fast path
+
+	mu    sync.Mutex
+	until map[string]time.Time
+}
+
+// eject ejects addr until the given time, and reports whether it wasn't
+// ejected already.
+func (e *serverEjections) eject(addr string, until time.Time) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if _, ok := e.until[addr]; ok {
+		return false
+	}
+	if e.until == nil {
+		e.until = make(map[string]time.Time)
+	}
+	e.until[addr] = until
+	e.count.Store(int32(len(e.until)))
+	return true
+}
+
+// filter returns servers without the ejected ones, and the servers whose
+// cool-down is over, which are added back. servers is returned as is when no
+// server is ejected, or when they all are.
+func (e *serverEjections) filter(servers []string, now time.Time) (live, restored []string) {
+	if e.count.Load() == 0 {
+		return servers, nil
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for addr, until := range e.until {
+		if now.After(until) {
+			delete(e.until, addr)
+			restored = append(restored, addr)
+		}
+	}
+	e.count.Store(int32(len(e.until)))
+	if len(e.until) == 0 {
+		return servers, restored
+	}
+
+	live = slices.DeleteFunc(slices.Clone(servers), func(addr string) bool {
+		_, ejected := e.until[addr]
+		return ejected
+	})
+	if len(live) == 0 {
+		return servers, restored
+	}
+	return live, restored
+}
+
+// ejectedUntil returns the end of the cool-down of addr, or zero when it is
+// not ejected.
+func (e *serverEjections) ejectedUntil(addr string) time.Time {
+	if e.count.Load() == 0 {
+		return time.Time{}
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.until[addr]
+}
+
+// liveServers returns the servers not ejected by Config.AutoEject.
+func (c *Client) liveServers(servers []string) []string {
+	if c.config.AutoEject == nil {
+		return servers
+	}
+
+	live, restored := c.ejections.filter(servers, time.Now())
+	if c.config.AutoEject.OnRestore != nil {
+		for _, addr := range restored {
+			c.config.AutoEject.OnRestore(addr)
+		}
+	}
+	return live
+}
+
+// serverFailed ejects the server of sp after AutoEjectPolicy.Failures
+// consecutive failures. Called by the health tracking of the pool.
+func (c *Client) serverFailed(sp *ServerPool, failures int, err error) {
+	policy := c.config.AutoEject
+	threshold := policy.Failures
+	if threshold <= 0 {
+		threshold = defaultEjectFailures
+	}
+	if failures < threshold {
+		return
+	}
+
+	coolDown := policy.CoolDown
+	if coolDown <= 0 {
+		coolDown = defaultEjectCoolDown
+	}
+	if !c.ejections.eject(sp.addr, time.Now().Add(coolDown)) {
+		return
+	}
+	sp.health.ejections.Add(1)
+	if policy.OnEject != nil {
+		policy.OnEject(sp.addr, err)
+	}
+}
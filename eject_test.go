@@ -0,0 +1,144 @@
+package memcache
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pior/memcache/internal/testutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAutoEject(t *testing.T) {
+	var mu sync.Mutex
+	down := map[string]bool{"a:11211": true}
+	var ejected, restored []string
+
+	client := NewClient(StaticServers("a:11211", "b:11211"), Config{
+		Dialer: DialerFunc(func(ctx context.Context, network, address string) (net.Conn, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			if down[address] {
+				return nil, errors.New("connection refused")
+			}
+			mock := testutils.NewConnectionMock("HD\r\n")
+			mock.EnableCycling()
+			return mock, nil
+		}),
+		ServerSelector: func(key string, serverCount int) int { return 0 },
+		AutoEject: &AutoEjectPolicy{
+			Failures: 2,
+			CoolDown: 50 * time.Millisecond,
+			OnEject: func(addr string, err error) {
+				assert.ErrorContains(t, err, "connection refused")
+				ejected = append(ejected, addr)
+			},
+			OnRestore: func(addr string) { restored = append(restored, addr) },
+		},
+	})
+	t.Cleanup(client.Close)
+
+	ctx := context.Background()
+	for range 2 {
+		require.Error(t, client.Delete(ctx, "key"))
+	}
+	assert.Equal(t, []string{"a:11211"}, ejected)
+
+	// The keys of a go to b during the cool-down.
+	require.NoError(t, client.Delete(ctx, "key"))
+	state := client.ServerStates()[0]
+	assert.False(t, state.EjectedUntil.IsZero())
+	assert.Equal(t, 2, state.ConsecutiveFailures)
+
+	// Still failing after the cool-down: ejected again by the first failure.
+	time.Sleep(60 * time.Millisecond)
+	require.Error(t, client.Delete(ctx, "key"))
+	assert.Equal(t, []string{"a:11211"}, restored)
+	assert.Equal(t, []string{"a:11211", "a:11211"}, ejected)
+	require.NoError(t, client.Delete(ctx, "key"))
+
+	// Back up after the cool-down.
+	mu.Lock()
+	down["a:11211"] = false
+	mu.Unlock()
+	time.Sleep(60 * time.Millisecond)
+	require.NoError(t, client.Delete(ctx, "key"))
+	assert.Equal(t, []string{"a:11211", "a:11211"}, restored)
+	assert.True(t, client.ServerStates()[0].EjectedUntil.IsZero())
+
+	sp, err := client.getPoolForServer("a:11211")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), sp.Metrics().Ejections)
+}
+
+func TestAutoEject_StableSelection(t *testing.T) {
+	client := NewClient(StaticServers("a:11211", "b:11211", "c:11211"), Config{
+		Dialer: &mockDialer{conn: testutils.NewConnectionMock()},
+		// The key is the index of its server.
+		ServerSelector: func(key string, serverCount int) int { return int(key[0]-'0') % serverCount },
+		AutoEject:      &AutoEjectPolicy{CoolDown: time.Minute},
+	})
+	t.Cleanup(client.Close)
+	client.ejections.eject("b:11211", time.Now().Add(time.Minute))
+
+	for key, want := range map[string]string{
+		"0": "a:11211",
+		"1": "c:11211", // selected again over a and c
+		"2": "c:11211", // unmoved
+	} {
+		addr, err := client.selectServerForKey(key)
+		require.NoError(t, err)
+		assert.Equal(t, want, addr, key)
+	}
+}
+
+func TestAutoEject_NotAllServers(t *testing.T) {
+	client := NewClient(StaticServers("a:11211", "b:11211"), Config{
+		Dialer: DialerFunc(func(ctx context.Context, network, address string) (net.Conn, error) {
+			return nil, errors.New("connection refused")
+		}),
+		AutoEject: &AutoEjectPolicy{Failures: 1},
+	})
+	t.Cleanup(client.Close)
+
+	ctx := context.Background()
+	for _, key := range []string{"k1", "k2", "k3", "k4", "k5", "k6"} {
+		require.Error(t, client.Delete(ctx, key))
+	}
+
+	for _, state := range client.ServerStates() {
+		assert.False(t, state.EjectedUntil.IsZero(), state.Addr)
+	}
+	// Every server is ejected: the keys go to their server as usual.
+	require.ErrorContains(t, client.Delete(ctx, "key"), "connection refused")
+}
+
+func TestAutoEject_DialTimeout(t *testing.T) {
+	client := NewClient(StaticServers("a:11211", "b:11211"), Config{
+		Dialer: DialerFunc(func(ctx context.Context, network, address string) (net.Conn, error) {
+			if address == "a:11211" {
+				return blackholeDialer(ctx, network, address)
+			}
+			mock := testutils.NewConnectionMock("HD\r\n")
+			mock.EnableCycling()
+			return mock, nil
+		}),
+		ConnectTimeout: 10 * time.Millisecond,
+		ServerSelector: func(key string, serverCount int) int { return 0 },
+		AutoEject:      &AutoEjectPolicy{Failures: 2, CoolDown: time.Minute},
+	})
+	t.Cleanup(client.Close)
+
+	ctx := context.Background()
+	for range 2 {
+		require.ErrorIs(t, client.Delete(ctx, "key"), context.DeadlineExceeded)
+	}
+	assert.False(t, client.ServerStates()[0].EjectedUntil.IsZero())
+
+	// The blackholed server is ejected: its keys don't wait for a dial.
+	require.NoError(t, client.Delete(ctx, "key"))
+}
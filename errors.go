@@ -1,6 +1,13 @@
 package memcache
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/pior/memcache/meta"
+)
 
 // Sentinel errors returned by the client. Check them with errors.Is; they may
 // be wrapped with additional context.
@@ -9,6 +16,18 @@ var (
 	// Add on an existing key, or replace/append/prepend on a missing key.
 	ErrNotStored = errors.New("memcache: item not stored")
 
+	// ErrKeyExists is returned by Add when the key already exists. It
+	// matches ErrNotStored too.
+	ErrKeyExists = fmt.Errorf("%w: key already exists", ErrNotStored)
+
+	// ErrCASConflict is returned when a compare-and-swap is not applied
+	// because the item was modified since its CAS value was read (EX status).
+	ErrCASConflict = errors.New("memcache: CAS conflict")
+
+	// ErrNotFound is returned when an operation requiring an existing item
+	// finds none (NF status).
+	ErrNotFound = errors.New("memcache: item not found")
+
 	// ErrClientClosed is returned by operations issued after Client.Close.
 	ErrClientClosed = errors.New("memcache: client is closed")
 
@@ -17,8 +36,89 @@ var (
 
 	// ErrPoolClosed is returned by Pool.Acquire after the pool has been closed.
 	ErrPoolClosed = errors.New("memcache: pool is closed")
+
+	// ErrPoolExhausted is returned when no connection could be acquired:
+	// the pool wait queue is full (Config.MaxWaitQueue) or no connection was
+	// released within Config.AcquireTimeout.
+	ErrPoolExhausted = errors.New("memcache: connection pool exhausted")
+
+	// ErrResponseMismatch is returned for a request or a batch whose
+	// responses don't carry the opaque tokens of their requests: the
+	// responses can't be attributed to their requests, the connection is
+	// closed. See Config.VerifyOpaque and Config.VerifyBatchResponses.
+	ErrResponseMismatch = errors.New("memcache: response does not match its request")
+
+	// ErrChecksumMismatch is returned for a value read with a wrong
+	// checksum: it was corrupted, or written without its checksum. See
+	// Config.ValueChecksums.
+	ErrChecksumMismatch = errors.New("memcache: value checksum mismatch")
+
+	// ErrStaleRead is returned for a get that doesn't read the last write
+	// of its WriteSession. See WithWriteSession.
+	ErrStaleRead = errors.New("memcache: stale read")
+
+	// ErrShed is returned for the operations rejected by load shedding
+	// (Config.Shedding).
+	ErrShed = errors.New("memcache: operation shed")
+
+	// ErrPoolFull is returned by Pool.CreateIdle when the pool is at its
+	// maximum size.
+	ErrPoolFull = errors.New("memcache: pool is full")
+
+	// ErrValueTooLarge is returned, before anything is sent, for a store
+	// whose value is larger than Config.MaxItemSize.
+	ErrValueTooLarge = errors.New("memcache: value too large")
+
+	// ErrAsyncQueueFull is returned by SetAsync and DeleteAsync when
+	// Config.AsyncQueueSize writes are already waiting to be sent.
+	ErrAsyncQueueFull = errors.New("memcache: async write queue full")
+
+	// ErrLockHeld is returned by Lock when another holder has the lock.
+	ErrLockHeld = errors.New("memcache: lock is held")
+
+	// ErrLockNotHeld is returned by Lock.Renew and Lock.Unlock when the lock
+	// expired, or was released.
+	ErrLockNotHeld = errors.New("memcache: lock is not held")
 )
 
+// StatusError is returned when the server answers an operation with a status
+// the operation doesn't expect. It matches the sentinel of its status with
+// errors.Is: NS matches ErrNotStored, EX matches ErrCASConflict and NF matches
+// ErrNotFound.
+//
+//	if errors.Is(err, memcache.ErrCASConflict) { ... }
+type StatusError struct {
+	// Op is the client operation: "get", "set", "add", "delete", "increment".
+	Op string
+
+	// Key is set by the batch operations (BatchCommands), to tell which item
+	// failed. It is then part of the Error() message.
+	Key string
+
+	// Status is the response status.
+	Status meta.StatusType
+}
+
+func (e *StatusError) Error() string {
+	if e.Key != "" {
+		return "memcache: " + e.Op + " failed for key " + e.Key + " with status: " + string(e.Status)
+	}
+	return "memcache: " + e.Op + " failed with status: " + string(e.Status)
+}
+
+// Is reports whether target is the sentinel error of the status.
+func (e *StatusError) Is(target error) bool {
+	switch e.Status {
+	case meta.StatusNS:
+		return target == ErrNotStored
+	case meta.StatusEX:
+		return target == ErrCASConflict
+	case meta.StatusNF:
+		return target == ErrNotFound
+	}
+	return false
+}
+
 // Operation names used in OpError.Op for operations that are not a single
 // meta protocol request.
 const (
@@ -27,6 +127,9 @@ const (
 
 	// OpStats is the Op of stats retrievals.
 	OpStats = "stats"
+
+	// OpRaw is the Op of Client.WithRawConnection.
+	OpRaw = "raw"
 )
 
 // OpError records an operation that failed against a specific server,
@@ -43,7 +146,7 @@ const (
 //	if errors.Is(err, context.DeadlineExceeded) { ... }
 type OpError struct {
 	// Op is the operation that failed: a meta protocol command code
-	// ("mg", "ms", ...) or one of the Op* constants (OpBatch, OpStats).
+	// ("mg", "ms", ...) or one of the Op* constants (OpBatch, OpStats, OpRaw).
 	Op string
 
 	// Key is the cache key, when the operation targets a single key.
@@ -73,3 +176,65 @@ func (e *OpError) Error() string {
 func (e *OpError) Unwrap() error {
 	return e.Err
 }
+
+// PartialError is returned by a batch spanning several servers when some of
+// the servers failed while the others answered. The responses of the healthy
+// servers are returned along with it: Client.ExecuteBatch returns nil
+// responses for the requests of the failed servers, and MultiGet returns them
+// as not found.
+//
+//	items, err := batch.MultiGet(ctx, keys)
+//	var partial *memcache.PartialError
+//	if errors.As(err, &partial) {
+//	    // Use the items, except those of partial.Failed(i).
+//	}
+//
+// It matches the errors of the failed servers with errors.Is and errors.As.
+type PartialError struct {
+	// Failures are the failed servers, sorted by address.
+	Failures []ServerFailure
+}
+
+// ServerFailure is the failure of the requests of a batch sent to a server.
+type ServerFailure struct {
+	// Server is the address of the server.
+	Server string
+
+	// Indices are the positions in the batch of the requests routed to the
+	// server.
+	Indices []int
+
+	// Err is the error of the server batch.
+	Err error
+}
+
+// Failed reports whether the request at position i of the batch failed.
+func (e *PartialError) Failed(i int) bool {
+	for _, f := range e.Failures {
+		if slices.Contains(f.Indices, i) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *PartialError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "memcache: batch failed on %d server(s)", len(e.Failures))
+	for i, f := range e.Failures {
+		sep := "; "
+		if i == 0 {
+			sep = ": "
+		}
+		b.WriteString(sep + f.Server + ": " + f.Err.Error())
+	}
+	return b.String()
+}
+
+func (e *PartialError) Unwrap() []error {
+	errs := make([]error, len(e.Failures))
+	for i, f := range e.Failures {
+		errs[i] = f.Err
+	}
+	return errs
+}
@@ -0,0 +1,285 @@
+package memcache
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sony/gobreaker/v2"
+)
+
+// ServerHealth is the health of a server, as observed by the operations sent
+// to it. See Client.ServerStates.
+type ServerHealth int
+
+const (
+	// ServerHealthy: the last operation succeeded, or none was sent yet.
+	ServerHealthy ServerHealth = iota
+	// ServerDegraded: the last operations failed, or the circuit breaker is
+	// half-open, letting a few operations probe the server.
+	ServerDegraded
+	// ServerBreakerOpen: the circuit breaker is open, the operations fail
+	// fast with gobreaker.ErrOpenState.
+	ServerBreakerOpen
+)
+
+func (h ServerHealth) String() string {
+	switch h {
+	case ServerHealthy:
+		return "healthy"
+	case ServerDegraded:
+		return "degraded"
+	case ServerBreakerOpen:
+		return "breaker-open"
+	default:
+		return "unknown"
+	}
+}
+
+// ServerState is the health of a server with the outcome of its last
+// operations. The failures are those counted by the circuit breaker: not the
+// canceled operations, the invalid keys or a saturated pool.
+type ServerState struct {
+	Addr   string
+	Health ServerHealth
+
+	ConsecutiveFailures int
+	LastError           error
+	LastErrorTime       time.Time // zero when no operation failed
+	LastSuccess         time.Time // zero when no operation succeeded
+
+	// EjectedUntil is the end of the cool-down of a server ejected by
+	// Config.AutoEject, zero when it is not ejected.
+	EjectedUntil time.Time
+}
+
+// ServerTransition is a change of the health of a server, delivered to the
+// functions registered with Client.SubscribeServerStates.
+type ServerTransition struct {
+	Addr     string
+	From, To ServerHealth
+	Time     time.Time
+
+	// Err is the last error of the server when it is not healthy.
+	Err error
+}
+
+// serverHealth tracks the health of a server pool.
+type serverHealth struct {
+	addr        string
+	lastSuccess atomic.Int64 // Unix nanoseconds
+	failing     atomic.Bool  // failures > 0, for the success fast path
+
+	mu          sync.Mutex
+	health      ServerHealth
+	breaker     gobreaker.State
+	failures    int
+	lastErr     error
+	lastErrTime time.Time
+
+	// notify is called with the transitions, and failed with the failures
+	// (for Config.AutoEject). Set by the client before the pool is used.
+	notify func(ServerTransition)
+	failed func(failures int, err error)
+
+	ejections atomic.Uint64 // by Config.AutoEject
+}
+
+// record accounts for the outcome of an operation.
+func (h *serverHealth) record(err error) {
+	if err == nil {
+		h.lastSuccess.Store(time.Now().UnixNano())
+		if !h.failing.Load() {
+			return
+		}
+	} else if breakerError(err) == nil || isBreakerStateError(err) {
+		return // says nothing about the server
+	}
+
+	h.mu.Lock()
+	if err == nil {
+		h.failures = 0
+		h.failing.Store(false)
+	} else {
+		h.failures++
+		h.failing.Store(true)
+		h.lastErr = err
+		h.lastErrTime = time.Now()
+	}
+	failures := h.failures
+	t, changed := h.updateLocked()
+	h.mu.Unlock()
+
+	if changed {
+		h.notify(t)
+	}
+	if err != nil && h.failed != nil {
+		h.failed(failures, err)
+	}
+}
+
+// breakerChanged accounts for a state change of the circuit breaker. It is
+// called by the breaker, with its lock held: it must not call the breaker.
+func (h *serverHealth) breakerChanged(to gobreaker.State) {
+	h.mu.Lock()
+	h.breaker = to
+	t, changed := h.updateLocked()
+	h.mu.Unlock()
+
+	if changed {
+		h.notify(t)
+	}
+}
+
+// updateLocked updates the health and returns the transition, if any.
+func (h *serverHealth) updateLocked() (ServerTransition, bool) {
+	health := ServerHealthy
+	switch {
+	case h.breaker == gobreaker.StateOpen:
+		health = ServerBreakerOpen
+	case h.breaker == gobreaker.StateHalfOpen || h.failures > 0:
+		health = ServerDegraded
+	}
+	if health == h.health {
+		return ServerTransition{}, false
+	}
+
+	t := ServerTransition{Addr: h.addr, From: h.health, To: health, Time: time.Now()}
+	if health != ServerHealthy {
+		t.Err = h.lastErr
+	}
+	h.health = health
+	return t, h.notify != nil
+}
+
+func (h *serverHealth) state() ServerState {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	s := ServerState{
+		Addr:                h.addr,
+		Health:              h.health,
+		ConsecutiveFailures: h.failures,
+		LastError:           h.lastErr,
+		LastErrorTime:       h.lastErrTime,
+	}
+	if ns := h.lastSuccess.Load(); ns != 0 {
+		s.LastSuccess = time.Unix(0, ns)
+	}
+	return s
+}
+
+func isBreakerStateError(err error) bool {
+	return errors.Is(err, gobreaker.ErrOpenState) || errors.Is(err, gobreaker.ErrTooManyRequests)
+}
+
+// stateQueueSize is the number of transitions waiting to be delivered to the
+// subscribers. Beyond it, the transitions are dropped.
+const stateQueueSize = 64
+
+// stateSubscribers holds the functions registered with
+// Client.SubscribeServerStates. The transitions are delivered by a goroutine,
+// as they can be observed with a circuit breaker lock held.
+type stateSubscribers struct {
+	mu    sync.Mutex
+	next  int
+	subs  map[int]func(ServerTransition)
+	queue chan ServerTransition // nil until the first subscriber
+}
+
+func (s *stateSubscribers) add(fn func(ServerTransition), stop <-chan struct{}) (unsubscribe func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.subs == nil {
+		s.subs = make(map[int]func(ServerTransition))
+		s.queue = make(chan ServerTransition, stateQueueSize)
+		go s.deliver(stop)
+	}
+	id := s.next
+	s.next++
+	s.subs[id] = fn
+
+	return func() {
+		s.mu.Lock()
+		delete(s.subs, id)
+		s.mu.Unlock()
+	}
+}
+
+// notify queues a transition for the subscribers, without blocking.
+func (s *stateSubscribers) notify(t ServerTransition) {
+	s.mu.Lock()
+	queue := s.queue
+	s.mu.Unlock()
+
+	if queue == nil {
+		return
+	}
+	select {
+	case queue <- t:
+	default: // the subscribers are stuck
+	}
+}
+
+// deliver calls the subscribers with the queued transitions until stop is
+// closed.
+func (s *stateSubscribers) deliver(stop <-chan struct{}) {
+	for {
+		select {
+		case <-stop:
+			return
+		case t := <-s.queue:
+			s.mu.Lock()
+			subs := make([]func(ServerTransition), 0, len(s.subs))
+			for _, fn := range s.subs {
+				subs = append(subs, fn)
+			}
+			s.mu.Unlock()
+
+			for _, fn := range subs {
+				fn(t)
+			}
+		}
+	}
+}
+
+// ServerStates returns the health of each configured server, in the order of
+// the server list, for orchestration and alerting. The servers without
+// operations yet are healthy.
+func (c *Client) ServerStates() []ServerState {
+	servers := uniqueServers(c.servers.List())
+
+	c.mu.RLock()
+	pools := make([]*ServerPool, len(servers))
+	for i, addr := range servers {
+		pools[i] = c.pools[addr]
+	}
+	c.mu.RUnlock()
+
+	states := make([]ServerState, len(servers))
+	for i, sp := range pools {
+		if sp == nil {
+			states[i] = ServerState{Addr: servers[i]}
+			continue
+		}
+		if sp.circuitBreaker != nil {
+			sp.circuitBreaker.State() // applies the open -> half-open timeout
+		}
+		states[i] = sp.health.state()
+	}
+	for i := range states {
+		states[i].EjectedUntil = c.ejections.ejectedUntil(states[i].Addr)
+	}
+	return states
+}
+
+// SubscribeServerStates registers fn to be called with each health transition
+// of a server, e.g. to alert when a server goes down and back up. The
+// transitions are delivered in order by a background goroutine, until the
+// client is closed: a slow fn delays the next ones, and past 64 transitions
+// waiting, the new ones are dropped. Returns a function unregistering fn.
+func (c *Client) SubscribeServerStates(fn func(ServerTransition)) (unsubscribe func()) {
+	return c.stateSubs.add(fn, c.stopBackground)
+}
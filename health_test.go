@@ -0,0 +1,178 @@
+package memcache
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pior/memcache/internal/testutils"
+	"github.com/pior/memcache/meta"
+	"github.com/sony/gobreaker/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// flakyConn is a connection answering HD to everything, whose writes fail
+// while down is set.
+type flakyConn struct {
+	*testutils.ConnectionMock
+	down *atomic.Bool
+}
+
+func (c *flakyConn) Write(b []byte) (int, error) {
+	if c.down.Load() {
+		return 0, errors.New("connection reset")
+	}
+	return c.ConnectionMock.Write(b)
+}
+
+// flakyDialer dials flakyConns, and fails while down is set.
+func flakyDialer(down *atomic.Bool) Dialer {
+	return DialerFunc(func(ctx context.Context, network, address string) (net.Conn, error) {
+		if down.Load() {
+			return nil, errors.New("connection refused")
+		}
+		mock := testutils.NewConnectionMock("HD\r\n")
+		mock.EnableCycling()
+		return &flakyConn{ConnectionMock: mock, down: down}, nil
+	})
+}
+
+func TestServerStates(t *testing.T) {
+	var down atomic.Bool
+	client := NewClient(StaticServers("a:11211", "b:11211"), Config{Dialer: flakyDialer(&down)})
+	t.Cleanup(client.Close)
+
+	states := client.ServerStates()
+	require.Len(t, states, 2)
+	assert.Equal(t, ServerState{Addr: "a:11211"}, states[0])
+	assert.Equal(t, ServerState{Addr: "b:11211"}, states[1])
+
+	ctx := context.Background()
+	sp, err := client.getPoolForServer("a:11211")
+	require.NoError(t, err)
+
+	_, err = sp.Execute(ctx, meta.Delete("key"))
+	require.NoError(t, err)
+	state := client.ServerStates()[0]
+	assert.Equal(t, ServerHealthy, state.Health)
+	assert.False(t, state.LastSuccess.IsZero())
+
+	down.Store(true)
+	_, err = sp.Execute(ctx, meta.Delete("key"))
+	require.ErrorContains(t, err, "connection reset")
+	_, err = sp.Execute(ctx, meta.Delete("key"))
+	require.ErrorContains(t, err, "connection refused")
+
+	state = client.ServerStates()[0]
+	assert.Equal(t, ServerDegraded, state.Health)
+	assert.Equal(t, 2, state.ConsecutiveFailures)
+	assert.ErrorContains(t, state.LastError, "connection refused")
+	assert.False(t, state.LastErrorTime.IsZero())
+
+	down.Store(false)
+	_, err = sp.Execute(ctx, meta.Delete("key"))
+	require.NoError(t, err)
+	state = client.ServerStates()[0]
+	assert.Equal(t, ServerHealthy, state.Health)
+	assert.Equal(t, 0, state.ConsecutiveFailures)
+}
+
+func TestServerStates_DialTimeout(t *testing.T) {
+	client := NewClient(StaticServers("localhost:11211"), Config{
+		Dialer:         blackholeDialer,
+		ConnectTimeout: 10 * time.Millisecond,
+		CircuitBreakerSettings: &gobreaker.Settings{
+			ReadyToTrip: func(counts gobreaker.Counts) bool {
+				return counts.ConsecutiveFailures >= 2
+			},
+		},
+	})
+	t.Cleanup(client.Close)
+
+	ctx := context.Background()
+	_, err := client.Get(ctx, "key")
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	state := client.ServerStates()[0]
+	assert.Equal(t, ServerDegraded, state.Health)
+	assert.Equal(t, 1, state.ConsecutiveFailures)
+	assert.ErrorIs(t, state.LastError, context.DeadlineExceeded)
+
+	// The breaker trips on a server that never accepts the connections.
+	_, err = client.Get(ctx, "key")
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, ServerBreakerOpen, client.ServerStates()[0].Health)
+}
+
+func TestSubscribeServerStates(t *testing.T) {
+	var down atomic.Bool
+	down.Store(true)
+	client := NewClient(StaticServers("localhost:11211"), Config{
+		Dialer: flakyDialer(&down),
+		CircuitBreakerSettings: &gobreaker.Settings{
+			Timeout: 20 * time.Millisecond,
+			ReadyToTrip: func(counts gobreaker.Counts) bool {
+				return counts.ConsecutiveFailures >= 2
+			},
+		},
+	})
+	t.Cleanup(client.Close)
+
+	transitions := make(chan ServerTransition, 10)
+	unsubscribe := client.SubscribeServerStates(func(t ServerTransition) {
+		transitions <- t
+	})
+
+	next := func() ServerTransition {
+		t.Helper()
+		select {
+		case tr := <-transitions:
+			return tr
+		case <-time.After(time.Second):
+			t.Fatal("no transition")
+			return ServerTransition{}
+		}
+	}
+
+	ctx := context.Background()
+	for range 3 {
+		require.Error(t, client.Set(ctx, Item{Key: "key", Value: []byte("v")}))
+	}
+
+	tr := next()
+	assert.Equal(t, "localhost:11211", tr.Addr)
+	assert.Equal(t, ServerHealthy, tr.From)
+	assert.Equal(t, ServerDegraded, tr.To)
+	assert.ErrorContains(t, tr.Err, "connection refused")
+
+	tr = next()
+	assert.Equal(t, ServerDegraded, tr.From)
+	assert.Equal(t, ServerBreakerOpen, tr.To)
+	assert.Equal(t, ServerBreakerOpen, client.ServerStates()[0].Health)
+
+	down.Store(false)
+	time.Sleep(30 * time.Millisecond) // breaker timeout
+	require.NoError(t, client.Set(ctx, Item{Key: "key", Value: []byte("v")}))
+
+	assert.Equal(t, ServerDegraded, next().To) // half-open
+	assert.Equal(t, ServerHealthy, next().To)
+
+	unsubscribe()
+	down.Store(true)
+	require.Error(t, client.Set(ctx, Item{Key: "key", Value: []byte("v")}))
+	select {
+	case tr := <-transitions:
+		t.Fatalf("unexpected transition after unsubscribe: %+v", tr)
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+func TestServerHealth_String(t *testing.T) {
+	assert.Equal(t, "healthy", ServerHealthy.String())
+	assert.Equal(t, "degraded", ServerDegraded.String())
+	assert.Equal(t, "breaker-open", ServerBreakerOpen.String())
+}
@@ -7,10 +7,11 @@ import (
 
 	"github.com/pior/memcache/internal/testutils"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // fakeResource implements Resource with controllable times, to unit test the
-// health check decisions in checkPoolConnections.
+// health check decisions in checkPoolConnections and reapPool.
 type fakeResource struct {
 	conn         *Connection
 	creationTime time.Time
@@ -33,6 +34,7 @@ type fakePool struct {
 }
 
 func (p *fakePool) Acquire(ctx context.Context) (Resource, error) { panic("not used") }
+func (p *fakePool) CreateIdle(ctx context.Context) error          { return ErrPoolFull }
 func (p *fakePool) Close()                                        {}
 func (p *fakePool) Metrics() ConnPoolMetrics                      { return ConnPoolMetrics{} }
 
@@ -44,6 +46,17 @@ func (p *fakePool) AcquireAllIdle() []Resource {
 	return resources
 }
 
+// newFakeServerPool returns a ServerPool configured with config, whose pool
+// hands out the given idle resources.
+func newFakeServerPool(t *testing.T, config Config, idle ...*fakeResource) *ServerPool {
+	config.NewPool = func(func(ctx context.Context) (*Connection, error), int32) (Pool, error) {
+		return &fakePool{idle: idle}, nil
+	}
+	sp, err := NewServerPool("fake:11211", config)
+	require.NoError(t, err)
+	return sp
+}
+
 func newFakeResource(responses ...string) *fakeResource {
 	mock := testutils.NewConnectionMock(responses...)
 	return &fakeResource{
@@ -63,7 +76,7 @@ func TestCheckPoolConnections(t *testing.T) {
 		client := newClientWithConfig(Config{Timeout: time.Second})
 		res := newFakeResource("MN\r\n")
 
-		client.checkPoolConnections(&fakePool{idle: []*fakeResource{res}})
+		client.checkPoolConnections(newFakeServerPool(t, client.config, res))
 
 		assert.True(t, res.released)
 		assert.False(t, res.destroyed)
@@ -74,7 +87,7 @@ func TestCheckPoolConnections(t *testing.T) {
 		res := newFakeResource() // no response available: a ping would fail loudly
 		res.creationTime = time.Now().Add(-2 * time.Minute)
 
-		client.checkPoolConnections(&fakePool{idle: []*fakeResource{res}})
+		client.checkPoolConnections(newFakeServerPool(t, client.config, res))
 
 		assert.True(t, res.destroyed)
 		assert.False(t, res.released)
@@ -85,7 +98,7 @@ func TestCheckPoolConnections(t *testing.T) {
 		res := newFakeResource()
 		res.idleDuration = 2 * time.Minute
 
-		client.checkPoolConnections(&fakePool{idle: []*fakeResource{res}})
+		client.checkPoolConnections(newFakeServerPool(t, client.config, res))
 
 		assert.True(t, res.destroyed)
 	})
@@ -94,7 +107,7 @@ func TestCheckPoolConnections(t *testing.T) {
 		client := newClientWithConfig(Config{Timeout: time.Second})
 		res := newFakeResource() // empty read buffer -> ping gets EOF
 
-		client.checkPoolConnections(&fakePool{idle: []*fakeResource{res}})
+		client.checkPoolConnections(newFakeServerPool(t, client.config, res))
 
 		assert.True(t, res.destroyed)
 		assert.False(t, res.released)
@@ -110,9 +123,59 @@ func TestCheckPoolConnections(t *testing.T) {
 		res.creationTime = time.Now().Add(-time.Minute)
 		res.idleDuration = time.Minute
 
-		client.checkPoolConnections(&fakePool{idle: []*fakeResource{res}})
+		client.checkPoolConnections(newFakeServerPool(t, client.config, res))
 
 		assert.True(t, res.released)
 		assert.False(t, res.destroyed)
 	})
 }
+
+func TestReapPool(t *testing.T) {
+	config := Config{MaxConnLifetime: time.Hour, MaxConnIdleTime: time.Minute}
+
+	old := newFakeResource() // no response available: a ping would fail loudly
+	old.creationTime = time.Now().Add(-2 * time.Hour)
+	idle := newFakeResource()
+	idle.idleDuration = 2 * time.Minute
+	fresh := newFakeResource()
+
+	sp := newFakeServerPool(t, config, old, idle, fresh)
+	reapPool(sp)
+
+	assert.True(t, old.destroyed)
+	assert.True(t, idle.destroyed)
+	assert.True(t, fresh.released)
+	assert.False(t, fresh.destroyed)
+
+	metrics := sp.Metrics()
+	assert.Equal(t, uint64(1), metrics.PrunedLifetime)
+	assert.Equal(t, uint64(1), metrics.PrunedIdle)
+}
+
+func TestServerPool_LifetimeJitter(t *testing.T) {
+	sp := newFakeServerPool(t, Config{MaxConnLifetime: time.Minute})
+
+	res := newFakeResource()
+	res.creationTime = time.Now().Add(-90 * time.Second)
+	assert.True(t, sp.lifetimeExceeded(res, time.Now()))
+
+	res.conn.lifetimeJitter = time.Minute
+	assert.False(t, sp.lifetimeExceeded(res, time.Now()), "the jitter extends the lifetime")
+}
+
+func TestClient_Reaper(t *testing.T) {
+	client := NewClient(StaticServers("server1:11211"), Config{
+		MaxConnIdleTime: time.Millisecond,
+		ReapInterval:    5 * time.Millisecond,
+		Dialer:          &mockDialer{conn: testutils.NewConnectionMock("EN\r\n")},
+	})
+	t.Cleanup(client.Close)
+
+	_, err := client.Get(context.Background(), "key")
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		metrics := client.PoolMetrics()
+		return metrics[0].PrunedIdle == 1 && metrics[0].Conns.TotalConns == 0
+	}, time.Second, 5*time.Millisecond)
+}
@@ -0,0 +1,86 @@
+package memcache
+
+import (
+	"context"
+	"time"
+
+	"github.com/pior/memcache/meta"
+)
+
+// Hook observes the operations executed against the servers, to layer
+// metrics, logging or tracing on top of the client.
+//
+// BeforeOp is called before the operation starts and may return a derived
+// context (e.g. carrying a tracing span); the returned context is used for the
+// operation and passed to AfterOp. AfterOp is called once the operation
+// completed, successfully or not.
+//
+// With several hooks, BeforeOp is called in order and AfterOp in reverse
+// order, like nested middlewares. Hooks are called synchronously on the
+// operation path and must be safe for concurrent use.
+type Hook interface {
+	BeforeOp(ctx context.Context, op OpInfo) context.Context
+	AfterOp(ctx context.Context, op OpInfo, result OpResult)
+}
+
+// OpInfo describes an operation observed by a Hook.
+type OpInfo struct {
+	// Op is the operation: a meta protocol command code ("mg", "ms", ...) or
+	// one of the Op* constants (OpBatch, OpStats).
+	Op string
+
+	// Server is the address of the server the operation was routed to.
+	Server string
+
+	// Requests are the requests sent to the server: one for a single-key
+	// operation, the whole server batch for OpBatch, none for OpStats.
+	// Hooks must not modify them.
+	Requests []*meta.Request
+}
+
+// Key returns the key of a single-key operation, or "" for batches and stats.
+func (o OpInfo) Key() string {
+	if len(o.Requests) != 1 {
+		return ""
+	}
+	return o.Requests[0].Key
+}
+
+// Keys returns the keys of all the requests of the operation.
+func (o OpInfo) Keys() []string {
+	keys := make([]string, len(o.Requests))
+	for i, req := range o.Requests {
+		keys[i] = req.Key
+	}
+	return keys
+}
+
+// OpResult is the outcome of an operation observed by a Hook.
+type OpResult struct {
+	// Duration is the time spent in the operation, including acquiring a
+	// connection from the pool and the circuit breaker.
+	Duration time.Duration
+
+	// Responses are the responses received, in request order. Nil when the
+	// operation failed with Err. Hooks must not modify them.
+	Responses []*meta.Response
+
+	// Err is the error returned by the operation, if any.
+	Err error
+}
+
+// hookChain runs a list of hooks as nested middlewares.
+type hookChain []Hook
+
+func (h hookChain) before(ctx context.Context, op OpInfo) context.Context {
+	for _, hook := range h {
+		ctx = hook.BeforeOp(ctx, op)
+	}
+	return ctx
+}
+
+func (h hookChain) after(ctx context.Context, op OpInfo, result OpResult) {
+	for i := len(h) - 1; i >= 0; i-- {
+		h[i].AfterOp(ctx, op, result)
+	}
+}
@@ -0,0 +1,131 @@
+package memcache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/pior/memcache/internal/testutils"
+	"github.com/pior/memcache/meta"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type ctxKey string
+
+// recordingHook records the calls it receives, tagged with its name.
+type recordingHook struct {
+	name string
+
+	mu      sync.Mutex
+	calls   *[]string
+	ops     []OpInfo
+	results []OpResult
+}
+
+func (h *recordingHook) BeforeOp(ctx context.Context, op OpInfo) context.Context {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	*h.calls = append(*h.calls, h.name+".before")
+	return context.WithValue(ctx, ctxKey(h.name), true)
+}
+
+func (h *recordingHook) AfterOp(ctx context.Context, op OpInfo, result OpResult) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if ctx.Value(ctxKey(h.name)) == nil {
+		*h.calls = append(*h.calls, h.name+".after-without-context")
+		return
+	}
+	*h.calls = append(*h.calls, h.name+".after")
+	h.ops = append(h.ops, op)
+	h.results = append(h.results, result)
+}
+
+func newHookedClient(t *testing.T, mockConn *testutils.ConnectionMock, hooks ...Hook) *Client {
+	client := NewClient(StaticServers("localhost:11211"), Config{
+		Dialer: &mockDialer{conn: mockConn},
+		Hooks:  hooks,
+	})
+	t.Cleanup(client.Close)
+	return client
+}
+
+func TestHooks_Execute(t *testing.T) {
+	var calls []string
+	hook := &recordingHook{name: "a", calls: &calls}
+	client := newHookedClient(t, testutils.NewConnectionMock("VA 5\r\nhello\r\n"), hook)
+
+	_, err := client.Get(context.Background(), "key1")
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"a.before", "a.after"}, calls)
+	require.Len(t, hook.ops, 1)
+	assert.Equal(t, "mg", hook.ops[0].Op)
+	assert.Equal(t, "localhost:11211", hook.ops[0].Server)
+	assert.Equal(t, "key1", hook.ops[0].Key())
+
+	result := hook.results[0]
+	require.NoError(t, result.Err)
+	require.Len(t, result.Responses, 1)
+	assert.Equal(t, "hello", string(result.Responses[0].Data))
+	assert.Positive(t, result.Duration)
+}
+
+func TestHooks_Order(t *testing.T) {
+	var calls []string
+	a := &recordingHook{name: "a", calls: &calls}
+	b := &recordingHook{name: "b", calls: &calls}
+	client := newHookedClient(t, testutils.NewConnectionMock("HD\r\n"), a, b)
+
+	require.NoError(t, client.Delete(context.Background(), "key1"))
+
+	assert.Equal(t, []string{"a.before", "b.before", "b.after", "a.after"}, calls)
+}
+
+func TestHooks_Error(t *testing.T) {
+	var calls []string
+	hook := &recordingHook{name: "a", calls: &calls}
+	client := NewClient(StaticServers("localhost:11211"), Config{
+		Dialer: &mockDialer{error: errors.New("dial failed")},
+		Hooks:  []Hook{hook},
+	})
+	t.Cleanup(client.Close)
+
+	_, err := client.Get(context.Background(), "key1")
+	require.Error(t, err)
+
+	require.Len(t, hook.results, 1)
+	assert.ErrorContains(t, hook.results[0].Err, "dial failed")
+	assert.Nil(t, hook.results[0].Responses)
+}
+
+func TestHooks_Batch(t *testing.T) {
+	var calls []string
+	hook := &recordingHook{name: "a", calls: &calls}
+	client := newHookedClient(t, testutils.NewConnectionMock("VA 2\r\nv1\r\n", "EN\r\n", "MN\r\n"), hook)
+
+	_, err := NewBatchCommands(client).MultiGet(context.Background(), []string{"k1", "k2"})
+	require.NoError(t, err)
+
+	require.Len(t, hook.ops, 1)
+	assert.Equal(t, OpBatch, hook.ops[0].Op)
+	assert.Equal(t, "", hook.ops[0].Key())
+	assert.Equal(t, []string{"k1", "k2"}, hook.ops[0].Keys())
+	require.Len(t, hook.results[0].Responses, 2)
+	assert.Equal(t, string(meta.StatusEN), string(hook.results[0].Responses[1].Status))
+}
+
+func TestHooks_Stats(t *testing.T) {
+	var calls []string
+	hook := &recordingHook{name: "a", calls: &calls}
+	client := newHookedClient(t, testutils.NewConnectionMock("STAT pid 1\r\nEND\r\n"), hook)
+
+	_, err := client.Stats(context.Background())
+	require.NoError(t, err)
+
+	require.Len(t, hook.ops, 1)
+	assert.Equal(t, OpStats, hook.ops[0].Op)
+	assert.Empty(t, hook.ops[0].Requests)
+}
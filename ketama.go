@@ -0,0 +1,97 @@
+package memcache
+
+import (
+	"cmp"
+	"crypto/md5"
+	"fmt"
+	"net"
+	"slices"
+	"sort"
+	"strconv"
+)
+
+// KetamaCompat selects the client whose ketama continuum KetamaServerSelector
+// reproduces. The clients hash the same points, named differently.
+type KetamaCompat int
+
+const (
+	// KetamaLibmemcached is the continuum of libmemcached with weighted
+	// ketama (and of php-memcached with OPT_LIBKETAMA_COMPATIBLE): points
+	// named "host-i", or "host:port-i" for a port other than 11211.
+	KetamaLibmemcached KetamaCompat = iota
+
+	// KetamaSpymemcached is the continuum of spymemcached with
+	// KETAMA_HASH: points named "/host:port-i", as spymemcached names the
+	// nodes given as IP addresses.
+	KetamaSpymemcached
+)
+
+// ketamaPointsPerServer is the number of points of each server on the
+// continuum: 40 MD5 digests of 4 points each.
+const ketamaPointsPerServer = 160
+
+type ketamaPoint struct {
+	hash  uint32
+	index int // of the server
+}
+
+// KetamaServerSelector returns a HashServerSelector placing the keys as the
+// ketama clients do for the servers addrs, with KetamaKeyHash as
+// Config.KeyHash:
+//
+//	memcache.Config{
+//		KeyHash:            memcache.KetamaKeyHash,
+//		HashServerSelector: memcache.KetamaServerSelector(addrs, memcache.KetamaLibmemcached),
+//	}
+//
+// The selector is built for a fixed server list: addrs in the order of
+// Servers.List. When the number of servers differs from len(addrs) (the list
+// changed, or a server is ejected by Config.AutoEject), keys are placed with
+// JumpServerSelector instead.
+func KetamaServerSelector(addrs []string, compat KetamaCompat) HashServerSelector {
+	points := make([]ketamaPoint, 0, len(addrs)*ketamaPointsPerServer)
+	for index, addr := range addrs {
+		name := ketamaServerName(addr, compat)
+		for i := range ketamaPointsPerServer / 4 {
+			digest := md5.Sum([]byte(name + "-" + strconv.Itoa(i)))
+			for h := range 4 {
+				hash := uint32(digest[3+h*4])<<24 | uint32(digest[2+h*4])<<16 |
+					uint32(digest[1+h*4])<<8 | uint32(digest[h*4])
+				points = append(points, ketamaPoint{hash: hash, index: index})
+			}
+		}
+	}
+	slices.SortStableFunc(points, func(a, b ketamaPoint) int {
+		return cmp.Compare(a.hash, b.hash)
+	})
+
+	return func(keyHash uint64, serverCount int) int {
+		if serverCount != len(addrs) || len(points) == 0 {
+			return JumpServerSelector(keyHash, serverCount)
+		}
+		// The first point at or after the key, wrapping around.
+		hash := uint32(keyHash)
+		i := sort.Search(len(points), func(i int) bool { return points[i].hash >= hash })
+		if i == len(points) {
+			i = 0
+		}
+		return points[i].index
+	}
+}
+
+// ketamaServerName returns the name of addr in the point names.
+func ketamaServerName(addr string, compat KetamaCompat) string {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	switch compat {
+	case KetamaSpymemcached:
+		return fmt.Sprintf("/%s:%s", host, port)
+	default:
+		if port == "11211" {
+			return host
+		}
+		return addr
+	}
+}
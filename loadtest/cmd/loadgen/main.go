@@ -24,12 +24,14 @@ import (
 	"github.com/pior/memcache/loadtest/internal/oplog"
 	"github.com/pior/memcache/loadtest/internal/profile"
 	"github.com/pior/memcache/loadtest/internal/report"
+	"github.com/pior/memcache/loadtest/internal/workload"
 )
 
 func main() {
 	var (
 		serversFlag = flag.String("servers", "", "comma-separated server addresses (or set MEMCACHE_SERVERS)")
 		profileName = flag.String("profile", "top-perf", "resource profile: top-perf | efficiency")
+		patternName = flag.String("workload", workload.DefaultPattern, "traffic pattern: mixed | zipf | read-mostly | burst")
 		duration    = flag.Duration("duration", time.Hour, "run duration")
 		workers     = flag.Int("workers", 0, "override worker count (0 = profile default)")
 		conns       = flag.Int("conns", 0, "override max connections per server (0 = profile default)")
@@ -52,6 +54,10 @@ func main() {
 	if err != nil {
 		fatal(err)
 	}
+	pattern, err := workload.LookupPattern(*patternName)
+	if err != nil {
+		fatal(err)
+	}
 	if *workers > 0 {
 		prof.Workers = *workers
 	}
@@ -82,7 +88,7 @@ func main() {
 
 	log := slog.New(slog.NewTextHandler(os.Stderr, nil))
 	log.Info("loadgen starting",
-		"profile", prof.Name, "servers", len(servers.List()), "workers", prof.Workers,
+		"profile", prof.Name, "workload", pattern.Name, "servers", len(servers.List()), "workers", prof.Workers,
 		"keyspace", prof.Keyspace, "intensity", prof.Intensity, "duration", *duration, "gomaxprocs", runtime.GOMAXPROCS(0))
 
 	client := memcache.NewClient(servers, prof.ClientConfig())
@@ -112,6 +118,7 @@ func main() {
 	g := generator.New(client, m, generator.Config{
 		Workers:    prof.Workers,
 		Keyspace:   prof.Keyspace,
+		Pattern:    pattern,
 		Duration:   *duration,
 		Intensity:  prof.Intensity,
 		TargetRate: *rate,
@@ -145,7 +152,7 @@ func main() {
 			}
 		}
 		if *snapPath != "" {
-			if err := writeJSONAtomic(*snapPath, runResult(*runID, *vm, prof.Name, elapsed, snap, client)); err != nil {
+			if err := writeJSONAtomic(*snapPath, runResult(*runID, *vm, prof.Name, pattern.Name, elapsed, snap, client)); err != nil {
 				log.Warn("snapshot write failed", "err", err)
 			}
 		}
@@ -179,7 +186,7 @@ loop:
 			"acquire_waits", pm.Conns.AcquireWaitCount)
 	}
 
-	if err := writeResult(*out, runResult(*runID, *vm, prof.Name, elapsed, final, client)); err != nil {
+	if err := writeResult(*out, runResult(*runID, *vm, prof.Name, pattern.Name, elapsed, final, client)); err != nil {
 		fatal(err)
 	}
 
@@ -231,11 +238,12 @@ func writeResult(path string, r report.RunResult) error {
 
 // runResult assembles the per-VM result artifact from a metrics snapshot and the
 // client's pool stats, shared by the periodic snapshot file and the final -out.
-func runResult(runID, vm, profile string, elapsed time.Duration, snap metrics.Snapshot, client *memcache.Client) report.RunResult {
+func runResult(runID, vm, profile, pattern string, elapsed time.Duration, snap metrics.Snapshot, client *memcache.Client) report.RunResult {
 	return report.RunResult{
 		RunID:       runID,
 		VM:          vm,
 		Profile:     profile,
+		Workload:    pattern,
 		ElapsedSecs: elapsed.Seconds(),
 		Snapshot:    snap,
 		PoolMetrics: poolMetricsJSON(client),
@@ -76,6 +76,7 @@ func configFlags(fs *flag.FlagSet) *cloud.RunConfig {
 	fs.IntVar(&cfg.Conns, "conns", 0, "override max connections per server (0 = profile default)")
 	fs.DurationVar(&cfg.OpTimeout, "op-timeout", 0, "client per-op + connect timeout (0 = profile default)")
 	fs.IntVar(&cfg.Keyspace, "keyspace", 0, "override key space (0 = profile default)")
+	fs.StringVar(&cfg.Workload, "workload", "", "traffic pattern: mixed|zipf|read-mostly|burst (default mixed)")
 	fs.BoolVar(&cfg.OpLog, "oplog", false, "enable the full per-op compressed log")
 	fs.BoolVar(&cfg.Stress, "stress", false, "shorten connection time-constants")
 	fs.IntVar(&cfg.CPUQuotaPercent, "cpu-quota", 0, "client CPU cap percent (0 = unconstrained)")
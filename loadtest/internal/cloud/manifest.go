@@ -40,6 +40,7 @@ type ConfigSummary struct {
 	Conns          int    `json:"conns"`
 	OpTimeout      string `json:"op_timeout,omitempty"`
 	Keyspace       int    `json:"keyspace"`
+	Workload       string `json:"workload,omitempty"`
 	Stress         bool   `json:"stress"`
 	MachineClient  string `json:"machine_client"`
 	MachineServer  string `json:"machine_server"`
@@ -66,6 +67,7 @@ func NewRunManifest(cfg RunConfig, runID string, started time.Time) RunManifest
 			Conns:          cfg.Conns,
 			OpTimeout:      durStr(cfg.OpTimeout),
 			Keyspace:       cfg.Keyspace,
+			Workload:       cfg.Workload,
 			Stress:         cfg.Stress,
 			MachineClient:  cfg.MachineTypeClient,
 			MachineServer:  cfg.MachineTypeServer,
@@ -24,6 +24,7 @@ type RunConfig struct {
 	Conns           int
 	OpTimeout       time.Duration // per-op + connect timeout; 0 = profile default
 	Keyspace        int
+	Workload        string // traffic pattern; "" = loadgen default
 	OpLog           bool
 	Stress          bool
 	CPUQuotaPercent int // client CPU cap; 0 = unconstrained
@@ -133,6 +134,7 @@ func BuildClientVMs(cfg RunConfig, runID string, created int64, addresses []stri
 				Conns:           cfg.Conns,
 				OpTimeout:       cfg.OpTimeout,
 				Keyspace:        cfg.Keyspace,
+				Workload:        cfg.Workload,
 				OpLog:           cfg.OpLog,
 				Stress:          cfg.Stress,
 				CPUQuotaPercent: cfg.CPUQuotaPercent,
@@ -89,6 +89,7 @@ type ClientScriptParams struct {
 	Conns           int
 	OpTimeout       time.Duration
 	Keyspace        int
+	Workload        string
 	OpLog           bool
 	Stress          bool
 	CPUQuotaPercent int // 0 = unconstrained; e.g. 100 = one vCPU
@@ -125,6 +126,9 @@ func ClientStartupScript(p ClientScriptParams) string {
 	if p.Keyspace > 0 {
 		args = append(args, fmt.Sprintf("-keyspace %d", p.Keyspace))
 	}
+	if p.Workload != "" {
+		args = append(args, "-workload "+p.Workload)
+	}
 	if p.OpLog {
 		args = append(args, "-oplog /var/log/oplog.zst")
 	}
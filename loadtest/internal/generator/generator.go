@@ -31,6 +31,7 @@ const maxBatch = 50
 type Config struct {
 	Workers    int
 	Keyspace   int
+	Pattern    workload.Pattern // zero = the default pattern
 	Duration   time.Duration
 	Intensity  profile.Intensity
 	TargetRate int // total ops/sec across workers for FixedRate; 0 = unlimited
@@ -66,6 +67,9 @@ type Generator struct {
 
 // New creates a Generator. onDesync may be nil.
 func New(client *memcache.Client, m *metrics.Metrics, cfg Config, onDesync DesyncFunc) *Generator {
+	if cfg.Pattern.Name == "" {
+		cfg.Pattern, _ = workload.LookupPattern(workload.DefaultPattern)
+	}
 	return &Generator{
 		client:   client,
 		batch:    memcache.NewBatchCommands(client),
@@ -94,6 +98,7 @@ func (g *Generator) Run(ctx context.Context) {
 
 func (g *Generator) worker(ctx context.Context, id int) {
 	rng := rand.New(rand.NewPCG(uint64(id), rand.Uint64()))
+	nextKey := g.cfg.Pattern.KeyChooser(rng, g.cfg.Keyspace)
 
 	var ring *recorder.Ring
 	if g.cfg.FlightRing > 0 {
@@ -109,6 +114,14 @@ func (g *Generator) worker(ctx context.Context, id int) {
 	}
 
 	for {
+		if idle := g.cfg.Pattern.Idle(time.Since(g.start)); idle > 0 {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(idle):
+			}
+			continue
+		}
 		if pace != nil {
 			select {
 			case <-ctx.Done():
@@ -119,9 +132,9 @@ func (g *Generator) worker(ctx context.Context, id int) {
 			return
 		}
 
-		op := workload.SelectOp(rng)
+		op := g.cfg.Pattern.SelectOp(rng)
 		start := time.Now()
-		outcome, keyID, badValue := g.execOp(ctx, op, rng)
+		outcome, keyID, badValue := g.execOp(ctx, op, rng, nextKey)
 		lat := time.Since(start)
 		// Don't record the op that lost a cancellation race at shutdown.
 		if ctx.Err() != nil && outcome != metrics.OutcomeDesync {
@@ -156,35 +169,36 @@ func (g *Generator) worker(ctx context.Context, id int) {
 	}
 }
 
-// execOp runs one operation and returns its outcome, a representative key id
-// (for logging), and the offending value when the outcome is a desync.
-func (g *Generator) execOp(ctx context.Context, op workload.Op, rng *rand.Rand) (metrics.Outcome, int, []byte) {
+// execOp runs one operation on the keys picked by nextKey, and returns its
+// outcome, a representative key id (for logging), and the offending value when
+// the outcome is a desync.
+func (g *Generator) execOp(ctx context.Context, op workload.Op, rng *rand.Rand, nextKey func() int) (metrics.Outcome, int, []byte) {
 	switch op {
 	case workload.OpGet:
-		return g.doGet(ctx, rng.IntN(g.cfg.Keyspace))
+		return g.doGet(ctx, nextKey())
 	case workload.OpSet:
-		keyID := rng.IntN(g.cfg.Keyspace)
+		keyID := nextKey()
 		return classify(g.client.Set(ctx, g.item(keyID, rng))), keyID, nil
 	case workload.OpAdd:
-		keyID := rng.IntN(g.cfg.Keyspace)
+		keyID := nextKey()
 		err := g.client.Add(ctx, g.item(keyID, rng))
 		if errors.Is(err, memcache.ErrNotStored) {
 			return metrics.OutcomeOK, keyID, nil // key already present — expected
 		}
 		return classify(err), keyID, nil
 	case workload.OpDelete:
-		keyID := rng.IntN(g.cfg.Keyspace)
+		keyID := nextKey()
 		return classify(g.client.Delete(ctx, workload.Key(keyID))), keyID, nil
 	case workload.OpIncr:
 		id := rng.IntN(counterKeyspace)
 		_, err := g.client.Increment(ctx, workload.KeyPrefix+"ctr:"+itoa(id), 1, memcache.NoTTL)
 		return classify(err), id, nil
 	case workload.OpMetaGetTTL:
-		return g.doMetaGet(ctx, rng.IntN(g.cfg.Keyspace))
+		return g.doMetaGet(ctx, nextKey())
 	case workload.OpBatchGet:
-		return g.doBatchGet(ctx, rng)
+		return g.doBatchGet(ctx, rng, nextKey)
 	case workload.OpBatchSet:
-		return g.doBatchSet(ctx, rng)
+		return g.doBatchSet(ctx, rng, nextKey)
 	}
 	return metrics.OutcomeOK, 0, nil
 }
@@ -218,12 +232,12 @@ func (g *Generator) doMetaGet(ctx context.Context, keyID int) (metrics.Outcome,
 	return metrics.OutcomeHit, keyID, nil
 }
 
-func (g *Generator) doBatchGet(ctx context.Context, rng *rand.Rand) (metrics.Outcome, int, []byte) {
+func (g *Generator) doBatchGet(ctx context.Context, rng *rand.Rand, nextKey func() int) (metrics.Outcome, int, []byte) {
 	n := 1 + rng.IntN(maxBatch)
 	keyIDs := make([]int, n)
 	keys := make([]string, n)
 	for i := range keys {
-		keyIDs[i] = rng.IntN(g.cfg.Keyspace)
+		keyIDs[i] = nextKey()
 		keys[i] = workload.Key(keyIDs[i])
 	}
 	items, err := g.batch.MultiGet(ctx, keys)
@@ -245,13 +259,13 @@ func (g *Generator) doBatchGet(ctx context.Context, rng *rand.Rand) (metrics.Out
 	return metrics.OutcomeMiss, keyIDs[0], nil
 }
 
-func (g *Generator) doBatchSet(ctx context.Context, rng *rand.Rand) (metrics.Outcome, int, []byte) {
+func (g *Generator) doBatchSet(ctx context.Context, rng *rand.Rand, nextKey func() int) (metrics.Outcome, int, []byte) {
 	n := 1 + rng.IntN(maxBatch)
-	first := rng.IntN(g.cfg.Keyspace)
+	first := nextKey()
 	items := make([]memcache.Item, n)
 	items[0] = g.item(first, rng)
 	for i := 1; i < n; i++ {
-		items[i] = g.item(rng.IntN(g.cfg.Keyspace), rng)
+		items[i] = g.item(nextKey(), rng)
 	}
 	return classify(g.batch.MultiSet(ctx, items)), first, nil
 }
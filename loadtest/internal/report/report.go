@@ -20,6 +20,7 @@ type RunResult struct {
 	RunID       string           `json:"run_id,omitempty"`
 	VM          string           `json:"vm,omitempty"`
 	Profile     string           `json:"profile"`
+	Workload    string           `json:"workload,omitempty"`
 	ElapsedSecs float64          `json:"elapsed_secs"`
 	Snapshot    metrics.Snapshot `json:"metrics"`
 	PoolMetrics []PoolMetric     `json:"pool_stats"`
@@ -66,13 +66,8 @@ func buildCumulative(w [numOps]int) [numOps]int {
 	return c
 }
 
-// SelectOp picks an operation following the weighted distribution.
+// SelectOp picks an operation following the weighted distribution of the
+// default pattern.
 func SelectOp(rng *rand.Rand) Op {
-	r := rng.IntN(100)
-	for i := range cumulative {
-		if r < cumulative[i] {
-			return Op(i)
-		}
-	}
-	return OpGet
+	return patterns[DefaultPattern].SelectOp(rng)
 }
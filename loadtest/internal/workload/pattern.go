@@ -0,0 +1,85 @@
+package workload
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"time"
+)
+
+// DefaultPattern is the pattern of a run that doesn't select one.
+const DefaultPattern = "mixed"
+
+// Pattern is a named traffic pattern: the operation mix, the distribution of
+// the keys and the rhythm of the load.
+type Pattern struct {
+	Name       string
+	cumulative [numOps]int
+
+	// ZipfS skews the keys with a Zipf distribution of exponent ZipfS (> 1):
+	// the lowest key ids are the hottest. Zero picks the keys uniformly.
+	ZipfS float64
+
+	// BurstOn and BurstOff alternate the load: the workers run for BurstOn,
+	// then idle for BurstOff, all in phase. A zero BurstOn is a steady load.
+	BurstOn, BurstOff time.Duration
+}
+
+var patterns = map[string]Pattern{
+	// mixed: the default op mix on uniform keys.
+	"mixed": {Name: "mixed", cumulative: cumulative},
+	// zipf: the default op mix concentrated on a few hot keys, which land on
+	// a few servers and connections.
+	"zipf": {Name: "zipf", cumulative: cumulative, ZipfS: 1.1},
+	// read-mostly: 99% gets and 1% sets, the traffic of a warm cache.
+	"read-mostly": {Name: "read-mostly", cumulative: buildCumulative([numOps]int{
+		OpGet: 99,
+		OpSet: 1,
+	})},
+	// burst: the default op mix in 1s bursts every 5s, so the pools grow
+	// under a burst and idle in between.
+	"burst": {Name: "burst", cumulative: cumulative, BurstOn: time.Second, BurstOff: 4 * time.Second},
+}
+
+// LookupPattern returns a named pattern.
+func LookupPattern(name string) (Pattern, error) {
+	p, ok := patterns[name]
+	if !ok {
+		return Pattern{}, fmt.Errorf("unknown workload %q (have mixed, zipf, read-mostly, burst)", name)
+	}
+	return p, nil
+}
+
+// SelectOp picks an operation following the mix of the pattern.
+func (p Pattern) SelectOp(rng *rand.Rand) Op {
+	r := rng.IntN(100)
+	for i := range p.cumulative {
+		if r < p.cumulative[i] {
+			return Op(i)
+		}
+	}
+	return OpGet
+}
+
+// KeyChooser returns a function picking key ids in [0, keyspace) following
+// the key distribution of the pattern. It uses rng, so it is not safe for
+// concurrent use: each worker needs its own.
+func (p Pattern) KeyChooser(rng *rand.Rand, keyspace int) func() int {
+	if p.ZipfS == 0 || keyspace < 2 {
+		return func() int { return rng.IntN(keyspace) }
+	}
+	zipf := rand.NewZipf(rng, p.ZipfS, 1, uint64(keyspace-1))
+	return func() int { return int(zipf.Uint64()) }
+}
+
+// Idle returns how long the workers idle at elapsed since the start of the
+// run: the rest of the pause between two bursts, or zero while a burst runs.
+func (p Pattern) Idle(elapsed time.Duration) time.Duration {
+	if p.BurstOn <= 0 {
+		return 0
+	}
+	phase := elapsed % (p.BurstOn + p.BurstOff)
+	if phase < p.BurstOn {
+		return 0
+	}
+	return p.BurstOn + p.BurstOff - phase
+}
@@ -5,6 +5,7 @@ import (
 	"math/rand/v2"
 	"strings"
 	"testing"
+	"time"
 )
 
 func newRNG() *rand.Rand { return rand.New(rand.NewPCG(1, 2)) }
@@ -79,3 +80,84 @@ func TestSelectOpDistribution(t *testing.T) {
 		}
 	}
 }
+
+func TestLookupPattern(t *testing.T) {
+	for _, name := range []string{"mixed", "zipf", "read-mostly", "burst"} {
+		p, err := LookupPattern(name)
+		if err != nil {
+			t.Fatalf("LookupPattern(%q): %v", name, err)
+		}
+		if p.Name != name {
+			t.Errorf("LookupPattern(%q).Name = %q", name, p.Name)
+		}
+	}
+	if _, err := LookupPattern("uniform"); err == nil {
+		t.Error("LookupPattern accepted an unknown pattern")
+	}
+}
+
+func TestPatternReadMostly(t *testing.T) {
+	p, _ := LookupPattern("read-mostly")
+	rng := newRNG()
+	var counts [NumOps]int
+	const n = 100000
+	for range n {
+		counts[p.SelectOp(rng)]++
+	}
+	if counts[OpGet]+counts[OpSet] != n {
+		t.Errorf("read-mostly selected other ops than get and set: %v", counts)
+	}
+	if got := float64(counts[OpSet]) / n; got < 0.005 || got > 0.015 {
+		t.Errorf("set ratio = %.4f, want ~0.01", got)
+	}
+}
+
+func TestPatternZipfKeys(t *testing.T) {
+	const keyspace, n = 1000, 100000
+	count := func(name string) (hot int) {
+		p, _ := LookupPattern(name)
+		next := p.KeyChooser(newRNG(), keyspace)
+		for range n {
+			id := next()
+			if id < 0 || id >= keyspace {
+				t.Fatalf("%s: key id %d out of [0, %d)", name, id, keyspace)
+			}
+			if id < keyspace/100 {
+				hot++
+			}
+		}
+		return hot
+	}
+
+	// The hottest 1% of the keys get ~1% of the uniform traffic, and a large
+	// share of the zipf traffic.
+	if hot := count("mixed"); hot > n/50 {
+		t.Errorf("uniform keys: the hottest 1%% got %d of %d picks", hot, n)
+	}
+	if hot := count("zipf"); hot < n/4 {
+		t.Errorf("zipf keys: the hottest 1%% got %d of %d picks", hot, n)
+	}
+}
+
+func TestPatternIdle(t *testing.T) {
+	p := Pattern{BurstOn: time.Second, BurstOff: 4 * time.Second}
+	cases := map[time.Duration]time.Duration{
+		0:                       0,
+		999 * time.Millisecond:  0,
+		time.Second:             4 * time.Second,
+		3 * time.Second:         2 * time.Second,
+		5 * time.Second:         0,
+		5500 * time.Millisecond: 0,
+		7 * time.Second:         3 * time.Second,
+	}
+	for elapsed, want := range cases {
+		if got := p.Idle(elapsed); got != want {
+			t.Errorf("Idle(%s) = %s, want %s", elapsed, got, want)
+		}
+	}
+
+	steady, _ := LookupPattern("mixed")
+	if got := steady.Idle(3 * time.Second); got != 0 {
+		t.Errorf("steady pattern idles %s", got)
+	}
+}
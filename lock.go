@@ -0,0 +1,198 @@
+package memcache
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/pior/memcache/meta"
+)
+
+// LockOptions configures LockWithOptions.
+type LockOptions struct {
+	// TTL is the lifetime of the lock, rounded up to the second: a holder
+	// that crashed blocks the others for up to TTL.
+	// Required: must be > 0.
+	TTL time.Duration
+
+	// AutoRenew renews the lock every TTL/3 (at least 10ms) until Unlock, so
+	// that a holder working longer than TTL keeps it. Lock.Lost tells when a renewal found
+	// the lock expired.
+	AutoRenew bool
+}
+
+// minLockRenewInterval bounds the renewals of the locks with a tiny TTL
+// (LockOptions.AutoRenew), which is rounded up to the second anyway.
+const minLockRenewInterval = 10 * time.Millisecond
+
+// Lock is a lock acquired with Commands.Lock.
+//
+// A memcached lock is best effort: it is lost when it expires, when the
+// server evicts it or restarts, or when its server changes. Use it to avoid
+// duplicate work, not where correctness depends on mutual exclusion.
+type Lock struct {
+	commands *Commands
+	key      string
+	token    []byte
+	ttl      TTL
+
+	mu       sync.Mutex // serializes Renew and Unlock
+	cas      uint64
+	released bool
+
+	lost     chan struct{}
+	lostOnce sync.Once
+
+	stopRenew chan struct{} // nil without AutoRenew
+	stopOnce  sync.Once
+	renewDone chan struct{}
+}
+
+// Lock acquires the lock named key for ttl, failing with ErrLockHeld if
+// another holder has it. It doesn't wait for the lock: retry on ErrLockHeld.
+func (c *Commands) Lock(ctx context.Context, key string, ttl time.Duration) (*Lock, error) {
+	return c.LockWithOptions(ctx, key, LockOptions{TTL: ttl})
+}
+
+// LockWithOptions acquires the lock named key like Lock, with the options of
+// opts.
+//
+// The lock is an item added (ms with the add mode) with a random value. Its
+// CAS value identifies the holder: Renew and Unlock apply only if the item is
+// the one added, not the lock of a later holder after it expired.
+func (c *Commands) LockWithOptions(ctx context.Context, key string, opts LockOptions) (*Lock, error) {
+	if opts.TTL <= 0 {
+		return nil, errors.New("memcache: lock TTL must be > 0")
+	}
+
+	token := make([]byte, 16)
+	_, _ = rand.Read(token) // never fails
+	l := &Lock{
+		commands: c,
+		key:      key,
+		token:    []byte(hex.EncodeToString(token)),
+		ttl:      ExpiresIn(opts.TTL),
+		lost:     make(chan struct{}),
+	}
+
+	req := meta.NewRequest(meta.CmdSet, key, l.token).AddModeAdd().AddTTL(l.ttl.Expiration()).AddReturnCAS()
+	resp, err := c.execute(ctx, req, nil)
+	if err != nil {
+		return nil, err
+	}
+	if resp.HasError() {
+		return nil, resp.Error
+	}
+	if resp.IsNotStored() {
+		return nil, ErrLockHeld
+	}
+	if !resp.IsSuccess() {
+		return nil, &StatusError{Op: "lock", Status: resp.Status}
+	}
+	l.cas, _ = resp.CAS()
+
+	if opts.AutoRenew {
+		l.stopRenew = make(chan struct{})
+		l.renewDone = make(chan struct{})
+		go l.renewLoop(max(opts.TTL/3, minLockRenewInterval))
+	}
+	return l, nil
+}
+
+// Key returns the name of the lock.
+func (l *Lock) Key() string {
+	return l.key
+}
+
+// Lost returns a channel closed when a renewal found the lock expired, or
+// held by another holder. It is never closed by Unlock.
+func (l *Lock) Lost() <-chan struct{} {
+	return l.lost
+}
+
+// Renew extends the lock for its TTL, failing with ErrLockNotHeld if the lock
+// expired.
+func (l *Lock) Renew(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.released {
+		return ErrLockNotHeld
+	}
+
+	req := meta.NewRequest(meta.CmdSet, l.key, l.token).AddCAS(l.cas).AddTTL(l.ttl.Expiration()).AddReturnCAS()
+	resp, err := l.commands.execute(ctx, req, nil)
+	if err != nil {
+		return err
+	}
+	if resp.HasError() {
+		return resp.Error
+	}
+	if resp.Status == meta.StatusEX || resp.Status == meta.StatusNF {
+		l.lostOnce.Do(func() { close(l.lost) })
+		return ErrLockNotHeld
+	}
+	if !resp.IsSuccess() {
+		return &StatusError{Op: "lock", Status: resp.Status}
+	}
+	l.cas, _ = resp.CAS()
+	return nil
+}
+
+// Unlock releases the lock and stops its renewal. It fails with
+// ErrLockNotHeld if the lock expired, or was released already.
+func (l *Lock) Unlock(ctx context.Context) error {
+	if l.stopRenew != nil {
+		l.stopOnce.Do(func() { close(l.stopRenew) })
+		<-l.renewDone
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.released {
+		return ErrLockNotHeld
+	}
+
+	req := meta.NewRequest(meta.CmdDelete, l.key, nil).AddCAS(l.cas)
+	resp, err := l.commands.execute(ctx, req, nil)
+	if err != nil {
+		return err
+	}
+	l.released = true
+	if resp.HasError() {
+		return resp.Error
+	}
+	if resp.Status == meta.StatusEX || resp.Status == meta.StatusNF {
+		return ErrLockNotHeld
+	}
+	if !resp.IsSuccess() {
+		return &StatusError{Op: "unlock", Status: resp.Status}
+	}
+	return nil
+}
+
+// renewLoop renews the lock every interval until Unlock, or until the lock is
+// lost. A failed renewal is retried at the next interval: the lock survives
+// the failures lasting less than about 2/3 of its TTL.
+func (l *Lock) renewLoop(interval time.Duration) {
+	defer close(l.renewDone)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-l.stopRenew:
+			return
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), interval)
+			err := l.Renew(ctx)
+			cancel()
+			if errors.Is(err, ErrLockNotHeld) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package memcache_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pior/memcache"
+	"github.com/pior/memcache/memcachetest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newLockTestClient(t *testing.T) (*memcache.Client, *memcachetest.Server) {
+	srv := memcachetest.NewServer(t)
+	client := memcache.NewClient(memcache.StaticServers(srv.Addr), memcache.Config{})
+	t.Cleanup(client.Close)
+	return client, srv
+}
+
+func TestLock(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("exclusive", func(t *testing.T) {
+		client, _ := newLockTestClient(t)
+
+		lock, err := client.Lock(ctx, "lock", time.Minute)
+		require.NoError(t, err)
+		assert.Equal(t, "lock", lock.Key())
+
+		_, err = client.Lock(ctx, "lock", time.Minute)
+		require.ErrorIs(t, err, memcache.ErrLockHeld)
+
+		require.NoError(t, lock.Unlock(ctx))
+		require.ErrorIs(t, lock.Unlock(ctx), memcache.ErrLockNotHeld)
+
+		lock, err = client.Lock(ctx, "lock", time.Minute)
+		require.NoError(t, err)
+		require.NoError(t, lock.Unlock(ctx))
+	})
+
+	t.Run("expired lock", func(t *testing.T) {
+		client, srv := newLockTestClient(t)
+
+		lock, err := client.Lock(ctx, "lock", 10*time.Second)
+		require.NoError(t, err)
+		srv.Advance(11 * time.Second)
+
+		// A new holder takes the expired lock: the first one can't release it.
+		other, err := client.Lock(ctx, "lock", 10*time.Second)
+		require.NoError(t, err)
+		require.ErrorIs(t, lock.Renew(ctx), memcache.ErrLockNotHeld)
+		require.ErrorIs(t, lock.Unlock(ctx), memcache.ErrLockNotHeld)
+		select {
+		case <-lock.Lost():
+		default:
+			t.Fatal("the lock should be lost")
+		}
+
+		_, err = client.Lock(ctx, "lock", 10*time.Second)
+		require.ErrorIs(t, err, memcache.ErrLockHeld)
+		require.NoError(t, other.Unlock(ctx))
+	})
+
+	t.Run("renew", func(t *testing.T) {
+		client, srv := newLockTestClient(t)
+
+		lock, err := client.Lock(ctx, "lock", 10*time.Second)
+		require.NoError(t, err)
+		srv.Advance(6 * time.Second)
+		require.NoError(t, lock.Renew(ctx))
+		srv.Advance(6 * time.Second)
+
+		_, err = client.Lock(ctx, "lock", 10*time.Second)
+		require.ErrorIs(t, err, memcache.ErrLockHeld)
+		require.NoError(t, lock.Unlock(ctx))
+	})
+
+	t.Run("auto-renew", func(t *testing.T) {
+		client, _ := newLockTestClient(t)
+
+		lock, err := client.LockWithOptions(ctx, "lock", memcache.LockOptions{TTL: 30 * time.Millisecond, AutoRenew: true})
+		require.NoError(t, err)
+
+		// Renewed every 10ms: the renewal finds the lock gone.
+		require.NoError(t, client.Delete(ctx, "lock"))
+		select {
+		case <-lock.Lost():
+		case <-time.After(time.Second):
+			t.Fatal("the lost lock should be detected")
+		}
+		require.ErrorIs(t, lock.Unlock(ctx), memcache.ErrLockNotHeld)
+	})
+
+	t.Run("auto-renew with a tiny TTL", func(t *testing.T) {
+		client, _ := newLockTestClient(t)
+
+		lock, err := client.LockWithOptions(ctx, "lock", memcache.LockOptions{TTL: time.Nanosecond, AutoRenew: true})
+		require.NoError(t, err)
+		require.NoError(t, lock.Unlock(ctx))
+	})
+
+	t.Run("invalid TTL", func(t *testing.T) {
+		client, _ := newLockTestClient(t)
+		_, err := client.Lock(ctx, "lock", 0)
+		require.Error(t, err)
+	})
+}
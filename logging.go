@@ -0,0 +1,91 @@
+package memcache
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/pior/memcache/meta"
+	"github.com/zeebo/xxh3"
+)
+
+// KeyLogMode controls how cache keys appear in logs.
+//
+// Keys often carry user identifiers (PII), so they are omitted by default.
+type KeyLogMode int
+
+const (
+	// KeyLogNone omits keys from logs.
+	KeyLogNone KeyLogMode = iota
+
+	// KeyLogHash logs a hash of the key (xxh3, hex encoded): stable enough to
+	// correlate hot keys across log lines without revealing them.
+	KeyLogHash
+
+	// KeyLogPlain logs keys verbatim.
+	KeyLogPlain
+)
+
+func (m KeyLogMode) format(key string) string {
+	switch m {
+	case KeyLogHash:
+		return strconv.FormatUint(xxh3.HashString(key), 16)
+	case KeyLogPlain:
+		return key
+	default:
+		return ""
+	}
+}
+
+// slowOpHook logs the operations that take longer than a threshold.
+type slowOpHook struct {
+	logger    *slog.Logger
+	threshold time.Duration
+	keys      KeyLogMode
+}
+
+func (h *slowOpHook) BeforeOp(ctx context.Context, op OpInfo) context.Context {
+	return ctx
+}
+
+func (h *slowOpHook) AfterOp(ctx context.Context, op OpInfo, result OpResult) {
+	if result.Duration < h.threshold {
+		return
+	}
+
+	attrs := []slog.Attr{
+		slog.String("op", op.Op),
+		slog.String("server", op.Server),
+		slog.Duration("duration", result.Duration),
+	}
+
+	if h.keys != KeyLogNone {
+		if key := op.Key(); key != "" {
+			attrs = append(attrs, slog.String("key", h.keys.format(key)))
+		}
+	}
+	if len(op.Requests) > 1 {
+		attrs = append(attrs, slog.Int("requests", len(op.Requests)))
+	}
+
+	switch {
+	case result.Err != nil:
+		attrs = append(attrs, slog.String("status", "error"), slog.String("error", result.Err.Error()))
+	case len(result.Responses) == 1:
+		attrs = append(attrs, slog.String("status", responseStatus(result.Responses[0])))
+	default:
+		attrs = append(attrs, slog.String("status", "ok"))
+	}
+
+	h.logger.LogAttrs(ctx, slog.LevelWarn, "memcache: slow operation", attrs...)
+}
+
+// responseStatus returns the status of a response for logging: the status
+// code, or the protocol error.
+func responseStatus(resp *meta.Response) string {
+	if resp.Error != nil {
+		return resp.Error.Error()
+	}
+	return string(resp.Status)
+}
@@ -0,0 +1,87 @@
+package memcache
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/pior/memcache/internal/testutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zeebo/xxh3"
+)
+
+func newLoggedClient(t *testing.T, threshold time.Duration, keys KeyLogMode, responses ...string) (*Client, *bytes.Buffer) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey || a.Key == "duration" {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}))
+
+	client := NewClient(StaticServers("localhost:11211"), Config{
+		Dialer:          &mockDialer{conn: testutils.NewConnectionMock(responses...)},
+		Logger:          logger,
+		SlowOpThreshold: threshold,
+		SlowOpKeys:      keys,
+	})
+	t.Cleanup(client.Close)
+	return client, &buf
+}
+
+func TestSlowOpLog(t *testing.T) {
+	t.Run("key omitted by default", func(t *testing.T) {
+		client, buf := newLoggedClient(t, time.Nanosecond, KeyLogNone, "EN\r\n")
+
+		_, err := client.Get(context.Background(), "user:123")
+		require.NoError(t, err)
+
+		assert.Equal(t, `level=WARN msg="memcache: slow operation" op=mg server=localhost:11211 status=EN`+"\n", buf.String())
+	})
+
+	t.Run("hashed key", func(t *testing.T) {
+		client, buf := newLoggedClient(t, time.Nanosecond, KeyLogHash, "HD\r\n")
+
+		require.NoError(t, client.Set(context.Background(), Item{Key: "user:123", Value: []byte("v")}))
+
+		hash := strconv.FormatUint(xxh3.HashString("user:123"), 16)
+		assert.Contains(t, buf.String(), " key="+hash+" ")
+		assert.NotContains(t, buf.String(), "user:123")
+	})
+
+	t.Run("plain key and protocol error", func(t *testing.T) {
+		client, buf := newLoggedClient(t, time.Nanosecond, KeyLogPlain, "SERVER_ERROR out of memory\r\n")
+
+		_ = client.Set(context.Background(), Item{Key: "user:123", Value: []byte("v")})
+
+		assert.Contains(t, buf.String(), ` key=user:123 status="SERVER_ERROR: out of memory"`)
+	})
+
+	t.Run("fast operations are not logged", func(t *testing.T) {
+		client, buf := newLoggedClient(t, time.Hour, KeyLogNone, "EN\r\n")
+
+		_, err := client.Get(context.Background(), "key")
+		require.NoError(t, err)
+
+		assert.Empty(t, buf.String())
+	})
+}
+
+func TestSlowOpLog_DoesNotModifyConfigHooks(t *testing.T) {
+	hooks := make([]Hook, 0, 4)
+	client := NewClient(StaticServers("localhost:11211"), Config{
+		Hooks:           hooks,
+		Logger:          slog.Default(),
+		SlowOpThreshold: time.Second,
+	})
+	t.Cleanup(client.Close)
+
+	assert.Len(t, client.config.Hooks, 1)
+	assert.Nil(t, hooks[:1][0], "the slow operation hook must not be written in the caller's backing array")
+}
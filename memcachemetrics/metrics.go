@@ -0,0 +1,287 @@
+// Package memcachemetrics exports Prometheus metrics for a memcache.Client.
+//
+// Operation metrics (latency, hits and misses, hit ratio, bytes) are
+// collected with a memcache.Hook; connection pool and circuit breaker metrics
+// are read from Client.PoolMetrics at scrape time:
+//
+//	metrics := memcachemetrics.New(memcachemetrics.Options{Namespace: "myapp"})
+//	client := memcache.NewClient(servers, memcache.Config{
+//		Hooks: []memcache.Hook{metrics},
+//	})
+//	if err := metrics.Register(prometheus.DefaultRegisterer, client); err != nil {
+//		return err
+//	}
+//
+// It lives in its own module so the client does not depend on the Prometheus
+// client library.
+package memcachemetrics
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pior/memcache"
+	"github.com/pior/memcache/meta"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Options configures the metrics.
+type Options struct {
+	// Namespace is prepended to all metric names.
+	Namespace string
+
+	// DurationBuckets are the histogram buckets of the operation latency, in
+	// seconds. Defaults to buckets from 100µs to 2.5s.
+	DurationBuckets []float64
+
+	// NativeHistogramBucketFactor is the growth factor of the buckets of the
+	// native histogram of the operation latency, exposed alongside the
+	// classic buckets to the scrapers negotiating the protobuf format.
+	// Defaults to DefaultNativeHistogramBucketFactor; a factor <= 1 other
+	// than zero disables the native histogram.
+	NativeHistogramBucketFactor float64
+}
+
+// DefaultDurationBuckets are the default operation latency buckets, in seconds.
+var DefaultDurationBuckets = []float64{
+	.0001, .00025, .0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5,
+}
+
+// DefaultNativeHistogramBucketFactor is the default growth factor of the
+// native histogram buckets: each bucket is at most 10% wider than the
+// previous one.
+const DefaultNativeHistogramBucketFactor = 1.1
+
+// Metrics is a memcache.Hook and a prometheus.Collector.
+type Metrics struct {
+	client atomic.Pointer[memcache.Client]
+	gets   sync.Map // server address -> *getCounts, for the hit ratio
+
+	duration     *prometheus.HistogramVec
+	hits         *prometheus.CounterVec
+	misses       *prometheus.CounterVec
+	bytesWritten *prometheus.CounterVec
+	bytesRead    *prometheus.CounterVec
+
+	poolConns        *prometheus.Desc
+	poolCreated      *prometheus.Desc
+	poolDestroyed    *prometheus.Desc
+	poolAcquires     *prometheus.Desc
+	poolAcquireWaits *prometheus.Desc
+	poolWaitSeconds  *prometheus.Desc
+	poolAcquireErrs  *prometheus.Desc
+	poolPruned       *prometheus.Desc
+	shed             *prometheus.Desc
+	breakerState     *prometheus.Desc
+	hitRatio         *prometheus.Desc
+}
+
+// getCounts counts the get requests of a server by result.
+type getCounts struct {
+	hits   atomic.Uint64
+	misses atomic.Uint64
+}
+
+var (
+	_ memcache.Hook        = (*Metrics)(nil)
+	_ prometheus.Collector = (*Metrics)(nil)
+)
+
+// New creates the metrics. Pass it in memcache.Config.Hooks, then call
+// Register once the client is created.
+func New(opts Options) *Metrics {
+	buckets := opts.DurationBuckets
+	if buckets == nil {
+		buckets = DefaultDurationBuckets
+	}
+	factor := opts.NativeHistogramBucketFactor
+	if factor == 0 {
+		factor = DefaultNativeHistogramBucketFactor
+	}
+	ns := opts.Namespace
+
+	return &Metrics{
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: ns, Subsystem: "memcache", Name: "operation_duration_seconds",
+			Help:    "Duration of the operations sent to the memcache servers.",
+			Buckets: buckets,
+
+			NativeHistogramBucketFactor:     factor,
+			NativeHistogramMaxBucketNumber:  160,
+			NativeHistogramMinResetDuration: time.Hour,
+		}, []string{"op", "server", "result"}),
+		hits: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: ns, Subsystem: "memcache", Name: "hits_total",
+			Help: "Number of get requests that found the key.",
+		}, []string{"server"}),
+		misses: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: ns, Subsystem: "memcache", Name: "misses_total",
+			Help: "Number of get requests that missed the key.",
+		}, []string{"server"}),
+		bytesWritten: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: ns, Subsystem: "memcache", Name: "value_bytes_written_total",
+			Help: "Number of value bytes sent to the servers.",
+		}, []string{"server"}),
+		bytesRead: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: ns, Subsystem: "memcache", Name: "value_bytes_read_total",
+			Help: "Number of value bytes received from the servers.",
+		}, []string{"server"}),
+
+		poolConns: prometheus.NewDesc(prometheus.BuildFQName(ns, "memcache", "pool_connections"),
+			"Number of connections in the pool, by state.", []string{"server", "state"}, nil),
+		poolCreated: prometheus.NewDesc(prometheus.BuildFQName(ns, "memcache", "pool_connections_created_total"),
+			"Number of connections created.", []string{"server"}, nil),
+		poolDestroyed: prometheus.NewDesc(prometheus.BuildFQName(ns, "memcache", "pool_connections_destroyed_total"),
+			"Number of connections destroyed.", []string{"server"}, nil),
+		poolAcquires: prometheus.NewDesc(prometheus.BuildFQName(ns, "memcache", "pool_acquires_total"),
+			"Number of connection acquires.", []string{"server"}, nil),
+		poolAcquireWaits: prometheus.NewDesc(prometheus.BuildFQName(ns, "memcache", "pool_acquire_waits_total"),
+			"Number of connection acquires that had to wait.", []string{"server"}, nil),
+		poolWaitSeconds: prometheus.NewDesc(prometheus.BuildFQName(ns, "memcache", "pool_acquire_wait_seconds_total"),
+			"Time spent waiting for a connection.", []string{"server"}, nil),
+		poolAcquireErrs: prometheus.NewDesc(prometheus.BuildFQName(ns, "memcache", "pool_acquire_errors_total"),
+			"Number of failed connection acquires.", []string{"server"}, nil),
+		poolPruned: prometheus.NewDesc(prometheus.BuildFQName(ns, "memcache", "pool_connections_pruned_total"),
+			"Number of connections closed for exceeding their idle time or lifetime, by reason.", []string{"server", "reason"}, nil),
+		shed: prometheus.NewDesc(prometheus.BuildFQName(ns, "memcache", "operations_shed_total"),
+			"Number of operations rejected by load shedding.", []string{"server"}, nil),
+		breakerState: prometheus.NewDesc(prometheus.BuildFQName(ns, "memcache", "circuit_breaker_state"),
+			"Circuit breaker state: 1 for the current state, 0 otherwise.", []string{"server", "state"}, nil),
+		hitRatio: prometheus.NewDesc(prometheus.BuildFQName(ns, "memcache", "hit_ratio"),
+			"Ratio of the get requests that found the key, since the start.", []string{"server"}, nil),
+	}
+}
+
+// Register registers the metrics with reg. The client's pool and circuit
+// breaker metrics are exported when client is not nil.
+func (m *Metrics) Register(reg prometheus.Registerer, client *memcache.Client) error {
+	if client != nil {
+		m.client.Store(client)
+	}
+	return reg.Register(m)
+}
+
+// BeforeOp implements memcache.Hook.
+func (m *Metrics) BeforeOp(ctx context.Context, op memcache.OpInfo) context.Context {
+	return ctx
+}
+
+// AfterOp implements memcache.Hook.
+func (m *Metrics) AfterOp(ctx context.Context, op memcache.OpInfo, result memcache.OpResult) {
+	outcome := "ok"
+	if result.Err != nil {
+		outcome = "error"
+	}
+	m.duration.WithLabelValues(op.Op, op.Server, outcome).Observe(result.Duration.Seconds())
+
+	var written, read, hits, misses int
+	for _, req := range op.Requests {
+		written += len(req.Data)
+	}
+	for i, resp := range result.Responses {
+		read += len(resp.Data)
+		if i < len(op.Requests) && op.Requests[i].Command == meta.CmdGet && resp.Error == nil {
+			if resp.IsMiss() {
+				misses++
+			} else if resp.IsSuccess() {
+				hits++
+			}
+		}
+	}
+
+	if written > 0 {
+		m.bytesWritten.WithLabelValues(op.Server).Add(float64(written))
+	}
+	if read > 0 {
+		m.bytesRead.WithLabelValues(op.Server).Add(float64(read))
+	}
+	if hits > 0 {
+		m.hits.WithLabelValues(op.Server).Add(float64(hits))
+	}
+	if misses > 0 {
+		m.misses.WithLabelValues(op.Server).Add(float64(misses))
+	}
+	if hits > 0 || misses > 0 {
+		counts, ok := m.gets.Load(op.Server)
+		if !ok {
+			counts, _ = m.gets.LoadOrStore(op.Server, &getCounts{})
+		}
+		counts.(*getCounts).hits.Add(uint64(hits))
+		counts.(*getCounts).misses.Add(uint64(misses))
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (m *Metrics) Describe(ch chan<- *prometheus.Desc) {
+	m.duration.Describe(ch)
+	m.hits.Describe(ch)
+	m.misses.Describe(ch)
+	m.bytesWritten.Describe(ch)
+	m.bytesRead.Describe(ch)
+
+	ch <- m.poolConns
+	ch <- m.poolCreated
+	ch <- m.poolDestroyed
+	ch <- m.poolAcquires
+	ch <- m.poolAcquireWaits
+	ch <- m.poolWaitSeconds
+	ch <- m.poolAcquireErrs
+	ch <- m.poolPruned
+	ch <- m.shed
+	ch <- m.breakerState
+	ch <- m.hitRatio
+}
+
+var breakerStates = []string{"closed", "open", "half-open"}
+
+// Collect implements prometheus.Collector.
+func (m *Metrics) Collect(ch chan<- prometheus.Metric) {
+	m.duration.Collect(ch)
+	m.hits.Collect(ch)
+	m.misses.Collect(ch)
+	m.bytesWritten.Collect(ch)
+	m.bytesRead.Collect(ch)
+
+	m.gets.Range(func(server, counts any) bool {
+		hits := float64(counts.(*getCounts).hits.Load())
+		misses := float64(counts.(*getCounts).misses.Load())
+		if hits+misses == 0 {
+			return true // counted by a concurrent AfterOp
+		}
+		ch <- prometheus.MustNewConstMetric(m.hitRatio, prometheus.GaugeValue, hits/(hits+misses), server.(string))
+		return true
+	})
+
+	client := m.client.Load()
+	if client == nil {
+		return
+	}
+
+	for _, pm := range client.PoolMetrics() {
+		c := pm.Conns
+		ch <- prometheus.MustNewConstMetric(m.poolConns, prometheus.GaugeValue, float64(c.IdleConns), pm.Addr, "idle")
+		ch <- prometheus.MustNewConstMetric(m.poolConns, prometheus.GaugeValue, float64(c.ActiveConns), pm.Addr, "active")
+		ch <- prometheus.MustNewConstMetric(m.poolCreated, prometheus.CounterValue, float64(c.CreatedConns), pm.Addr)
+		ch <- prometheus.MustNewConstMetric(m.poolDestroyed, prometheus.CounterValue, float64(c.DestroyedConns), pm.Addr)
+		ch <- prometheus.MustNewConstMetric(m.poolAcquires, prometheus.CounterValue, float64(c.AcquireCount), pm.Addr)
+		ch <- prometheus.MustNewConstMetric(m.poolAcquireWaits, prometheus.CounterValue, float64(c.AcquireWaitCount), pm.Addr)
+		ch <- prometheus.MustNewConstMetric(m.poolWaitSeconds, prometheus.CounterValue, float64(c.AcquireWaitTimeNs)/1e9, pm.Addr)
+		ch <- prometheus.MustNewConstMetric(m.poolAcquireErrs, prometheus.CounterValue, float64(c.AcquireErrors), pm.Addr)
+		ch <- prometheus.MustNewConstMetric(m.poolPruned, prometheus.CounterValue, float64(pm.PrunedIdle), pm.Addr, "idle")
+		ch <- prometheus.MustNewConstMetric(m.poolPruned, prometheus.CounterValue, float64(pm.PrunedLifetime), pm.Addr, "lifetime")
+		ch <- prometheus.MustNewConstMetric(m.shed, prometheus.CounterValue, float64(pm.Shed), pm.Addr)
+
+		if pm.CircuitBreaker.State == "" {
+			continue // no circuit breaker configured
+		}
+		for _, state := range breakerStates {
+			value := 0.0
+			if state == pm.CircuitBreaker.State {
+				value = 1
+			}
+			ch <- prometheus.MustNewConstMetric(m.breakerState, prometheus.GaugeValue, value, pm.Addr, state)
+		}
+	}
+}
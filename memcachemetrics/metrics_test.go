@@ -0,0 +1,121 @@
+package memcachemetrics
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/pior/memcache"
+	"github.com/pior/memcache/internal/testutils"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/sony/gobreaker/v2"
+	"github.com/stretchr/testify/require"
+)
+
+type mockDialer struct {
+	conn net.Conn
+}
+
+func (d *mockDialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	return d.conn, nil
+}
+
+func TestMetrics(t *testing.T) {
+	metrics := New(Options{Namespace: "test"})
+
+	mockConn := testutils.NewConnectionMock("HD\r\n", "VA 5\r\nhello\r\n", "EN\r\n")
+	client := memcache.NewClient(memcache.StaticServers("server1:11211"), memcache.Config{
+		Dialer:                 &mockDialer{conn: mockConn},
+		Hooks:                  []memcache.Hook{metrics},
+		CircuitBreakerSettings: &gobreaker.Settings{},
+	})
+	t.Cleanup(client.Close)
+
+	reg := prometheus.NewPedanticRegistry()
+	require.NoError(t, metrics.Register(reg, client))
+
+	ctx := context.Background()
+	require.NoError(t, client.Set(ctx, memcache.Item{Key: "key1", Value: []byte("hello")}))
+	_, err := client.Get(ctx, "key1")
+	require.NoError(t, err)
+	_, err = client.Get(ctx, "key2")
+	require.NoError(t, err)
+
+	require.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(`
+# HELP test_memcache_hits_total Number of get requests that found the key.
+# TYPE test_memcache_hits_total counter
+test_memcache_hits_total{server="server1:11211"} 1
+# HELP test_memcache_hit_ratio Ratio of the get requests that found the key, since the start.
+# TYPE test_memcache_hit_ratio gauge
+test_memcache_hit_ratio{server="server1:11211"} 0.5
+# HELP test_memcache_misses_total Number of get requests that missed the key.
+# TYPE test_memcache_misses_total counter
+test_memcache_misses_total{server="server1:11211"} 1
+# HELP test_memcache_value_bytes_read_total Number of value bytes received from the servers.
+# TYPE test_memcache_value_bytes_read_total counter
+test_memcache_value_bytes_read_total{server="server1:11211"} 5
+# HELP test_memcache_value_bytes_written_total Number of value bytes sent to the servers.
+# TYPE test_memcache_value_bytes_written_total counter
+test_memcache_value_bytes_written_total{server="server1:11211"} 5
+# HELP test_memcache_pool_connections Number of connections in the pool, by state.
+# TYPE test_memcache_pool_connections gauge
+test_memcache_pool_connections{server="server1:11211",state="active"} 0
+test_memcache_pool_connections{server="server1:11211",state="idle"} 1
+# HELP test_memcache_circuit_breaker_state Circuit breaker state: 1 for the current state, 0 otherwise.
+# TYPE test_memcache_circuit_breaker_state gauge
+test_memcache_circuit_breaker_state{server="server1:11211",state="closed"} 1
+test_memcache_circuit_breaker_state{server="server1:11211",state="half-open"} 0
+test_memcache_circuit_breaker_state{server="server1:11211",state="open"} 0
+`),
+		"test_memcache_hits_total",
+		"test_memcache_hit_ratio",
+		"test_memcache_misses_total",
+		"test_memcache_value_bytes_read_total",
+		"test_memcache_value_bytes_written_total",
+		"test_memcache_pool_connections",
+		"test_memcache_circuit_breaker_state",
+	))
+
+	count, err := testutil.GatherAndCount(reg, "test_memcache_operation_duration_seconds")
+	require.NoError(t, err)
+	require.Equal(t, 2, count) // (ms, ok) and (mg, ok)
+}
+
+func TestMetrics_NativeHistogram(t *testing.T) {
+	gatherDuration := func(opts Options) *dto.Histogram {
+		metrics := New(opts)
+		mockConn := testutils.NewConnectionMock("EN\r\n")
+		client := memcache.NewClient(memcache.StaticServers("server1:11211"), memcache.Config{
+			Dialer: &mockDialer{conn: mockConn},
+			Hooks:  []memcache.Hook{metrics},
+		})
+		t.Cleanup(client.Close)
+
+		reg := prometheus.NewPedanticRegistry()
+		require.NoError(t, metrics.Register(reg, client))
+		_, err := client.Get(context.Background(), "key1")
+		require.NoError(t, err)
+
+		families, err := reg.Gather()
+		require.NoError(t, err)
+		for _, family := range families {
+			if family.GetName() == "memcache_operation_duration_seconds" {
+				require.Len(t, family.GetMetric(), 1)
+				return family.GetMetric()[0].GetHistogram()
+			}
+		}
+		t.Fatal("no operation duration histogram")
+		return nil
+	}
+
+	h := gatherDuration(Options{})
+	require.Len(t, h.GetBucket(), len(DefaultDurationBuckets), "the classic buckets are kept")
+	require.NotNil(t, h.Schema, "the native histogram is exposed")
+	require.Equal(t, uint64(1), h.GetSampleCount())
+
+	h = gatherDuration(Options{NativeHistogramBucketFactor: 1})
+	require.Nil(t, h.Schema, "a factor of 1 disables the native histogram")
+}
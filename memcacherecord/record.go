@@ -0,0 +1,184 @@
+// Package memcacherecord records the wire traffic of memcache connections and
+// replays it, to reproduce protocol anomalies seen in production offline.
+//
+// Recording wraps the dialer of the client: the traffic of each connection is
+// written to its own file.
+//
+//	client := memcache.NewClient(servers, memcache.Config{
+//		Dialer: memcacherecord.Dialer(&net.Dialer{}, "/tmp/memcache-traffic"),
+//	})
+//
+// A recording is a text file with a record per line: the time, the direction
+// ('>' sent to the server, '<' received from it, '!' read error) and the bytes
+// as a Go quoted string:
+//
+//	2026-10-17T09:30:00.123456789Z > "mg foo v f\r\n"
+//	2026-10-17T09:30:00.123901234Z < "VA 3 f0\r\nbar\r\n"
+//
+// ReplayDialer serves the received side of recordings back to a client.
+package memcacherecord
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pior/memcache"
+)
+
+// Direction is the direction of a record.
+type Direction byte
+
+const (
+	Sent      Direction = '>' // bytes written to the server
+	Received  Direction = '<' // bytes read from the server
+	ReadError Direction = '!' // a read failed: Data holds the error message
+)
+
+// Record is a read or write of a connection.
+type Record struct {
+	Time      time.Time
+	Direction Direction
+	Data      []byte
+}
+
+// String formats the record as a line of a recording, without the newline.
+func (r Record) String() string {
+	return r.Time.UTC().Format(time.RFC3339Nano) + " " + string(r.Direction) + " " + strconv.Quote(string(r.Data))
+}
+
+// ReadRecords parses a recording.
+func ReadRecords(r io.Reader) ([]Record, error) {
+	var records []Record
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 16<<20) // a record holds a whole read or write
+	for line := 1; scanner.Scan(); line++ {
+		record, err := parseRecord(scanner.Text())
+		if err != nil {
+			return nil, fmt.Errorf("memcacherecord: line %d: %w", line, err)
+		}
+		records = append(records, record)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("memcacherecord: %w", err)
+	}
+	return records, nil
+}
+
+func parseRecord(line string) (Record, error) {
+	ts, rest, _ := strings.Cut(line, " ")
+	dir, quoted, _ := strings.Cut(rest, " ")
+
+	t, err := time.Parse(time.RFC3339Nano, ts)
+	if err != nil {
+		return Record{}, err
+	}
+	if len(dir) != 1 || !strings.Contains("><!", dir) {
+		return Record{}, fmt.Errorf("invalid direction %q", dir)
+	}
+	data, err := strconv.Unquote(quoted)
+	if err != nil {
+		return Record{}, fmt.Errorf("invalid data: %w", err)
+	}
+	return Record{Time: t, Direction: Direction(dir[0]), Data: []byte(data)}, nil
+}
+
+// Dialer returns a dialer recording the connections dialed by base in dir,
+// created if needed. Each connection is recorded in its own file, named after
+// its sequence number and address, e.g. "0001-10.0.0.1_11211.log".
+func Dialer(base memcache.Dialer, dir string) memcache.Dialer {
+	var seq atomic.Int64
+
+	return memcache.DialerFunc(func(ctx context.Context, network, address string) (net.Conn, error) {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("memcacherecord: %w", err)
+		}
+
+		conn, err := base.DialContext(ctx, network, address)
+		if err != nil {
+			return nil, err
+		}
+
+		name := fmt.Sprintf("%04d-%s.log", seq.Add(1), sanitize(address))
+		f, err := os.Create(filepath.Join(dir, name))
+		if err != nil {
+			_ = conn.Close()
+			return nil, fmt.Errorf("memcacherecord: %w", err)
+		}
+		return NewConn(conn, f), nil
+	})
+}
+
+// sanitize makes an address usable in a file name.
+func sanitize(address string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '/' || r == ':' || r == '\\' {
+			return '_'
+		}
+		return r
+	}, strings.TrimPrefix(address, "/"))
+}
+
+// NewConn returns conn recording its traffic to w. Closing the connection
+// closes w if it is an io.Closer.
+func NewConn(conn net.Conn, w io.Writer) net.Conn {
+	return &recordingConn{Conn: conn, w: w}
+}
+
+type recordingConn struct {
+	net.Conn
+
+	mu  sync.Mutex // serializes the records of the read and write sides
+	w   io.Writer
+	err error // first error writing the recording, stops recording
+}
+
+func (c *recordingConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	if n > 0 {
+		c.record(Received, b[:n])
+	}
+	if err != nil {
+		c.record(ReadError, []byte(err.Error()))
+	}
+	return n, err
+}
+
+func (c *recordingConn) Write(b []byte) (int, error) {
+	n, err := c.Conn.Write(b)
+	if n > 0 {
+		c.record(Sent, b[:n])
+	}
+	return n, err
+}
+
+func (c *recordingConn) Close() error {
+	err := c.Conn.Close()
+	if closer, ok := c.w.(io.Closer); ok {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		err = errors.Join(err, closer.Close())
+	}
+	return err
+}
+
+func (c *recordingConn) record(dir Direction, data []byte) {
+	line := Record{Time: time.Now(), Direction: dir, Data: data}.String() + "\n"
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err == nil {
+		_, c.err = io.WriteString(c.w, line)
+	}
+}
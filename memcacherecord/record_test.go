@@ -0,0 +1,141 @@
+package memcacherecord
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pior/memcache"
+	"github.com/pior/memcache/memcachetest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecord_String(t *testing.T) {
+	record := Record{
+		Time:      time.Date(2026, 10, 17, 9, 30, 0, 123456789, time.UTC),
+		Direction: Received,
+		Data:      []byte("VA 3 f0\r\nbar\r\n"),
+	}
+
+	line := record.String()
+	assert.Equal(t, `2026-10-17T09:30:00.123456789Z < "VA 3 f0\r\nbar\r\n"`, line)
+
+	records, err := ReadRecords(strings.NewReader(line + "\n"))
+	require.NoError(t, err)
+	assert.Equal(t, []Record{record}, records)
+
+	_, err = ReadRecords(strings.NewReader("2026-10-17T09:30:00Z ? \"\"\n"))
+	assert.ErrorContains(t, err, `line 1: invalid direction "?"`)
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	ctx := context.Background()
+	dir := filepath.Join(t.TempDir(), "traffic")
+
+	// Record a session against a server.
+	srv := memcachetest.NewServer(t)
+	client := memcache.NewClient(memcache.StaticServers(srv.Addr), memcache.Config{
+		Dialer: Dialer(&net.Dialer{}, dir),
+	})
+	require.NoError(t, client.Set(ctx, memcache.Item{Key: "key", Value: []byte("value")}))
+	item, err := client.Get(ctx, "key")
+	require.NoError(t, err)
+	require.Equal(t, "value", string(item.Value))
+	client.Close()
+
+	paths, err := filepath.Glob(filepath.Join(dir, "*.log"))
+	require.NoError(t, err)
+	require.Len(t, paths, 1)
+	assert.True(t, strings.HasPrefix(filepath.Base(paths[0]), "0001-127.0.0.1_"), paths[0])
+
+	f, err := os.Open(paths[0])
+	require.NoError(t, err)
+	defer f.Close()
+	records, err := ReadRecords(f)
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, len(records), 4)
+	assert.Equal(t, Sent, records[0].Direction)
+	assert.Equal(t, "ms key 5\r\nvalue\r\n", string(records[0].Data))
+	assert.Equal(t, Received, records[1].Direction)
+	assert.Equal(t, "HD\r\n", string(records[1].Data))
+
+	// Replay it without the server.
+	srv.Close()
+	dialer, err := ReplayDialer(paths...)
+	require.NoError(t, err)
+	client = memcache.NewClient(memcache.StaticServers("replay"), memcache.Config{Dialer: dialer})
+	defer client.Close()
+
+	require.NoError(t, client.Set(ctx, memcache.Item{Key: "key", Value: []byte("value")}))
+	item, err = client.Get(ctx, "key")
+	require.NoError(t, err)
+	assert.Equal(t, "value", string(item.Value))
+}
+
+func TestReplayConn(t *testing.T) {
+	records := []Record{
+		{Direction: Sent, Data: []byte("mg key v\r\n")},
+		{Direction: Received, Data: []byte("VA 5\r\nhe")},
+		{Direction: Received, Data: []byte("llo\r\n")},
+		{Direction: ReadError, Data: []byte("read tcp 127.0.0.1:1->127.0.0.1:2: i/o timeout")},
+	}
+
+	t.Run("replays the reads", func(t *testing.T) {
+		conn := NewReplayConn(records)
+		_, err := conn.Write([]byte("mg key v\r\n"))
+		require.NoError(t, err)
+
+		buf := make([]byte, 64)
+		n, err := conn.Read(buf)
+		require.NoError(t, err)
+		assert.Equal(t, "VA 5\r\nhe", string(buf[:n]))
+		n, err = conn.Read(buf)
+		require.NoError(t, err)
+		assert.Equal(t, "llo\r\n", string(buf[:n]))
+
+		_, err = conn.Read(buf)
+		var netErr net.Error
+		require.ErrorAs(t, err, &netErr)
+		assert.True(t, netErr.Timeout())
+
+		_, err = conn.Read(buf)
+		assert.ErrorIs(t, err, io.EOF)
+	})
+
+	t.Run("waits for the request", func(t *testing.T) {
+		conn := NewReplayConn(records)
+		require.NoError(t, conn.SetReadDeadline(time.Now().Add(10*time.Millisecond)))
+
+		_, err := conn.Read(make([]byte, 64))
+		assert.ErrorIs(t, err, os.ErrDeadlineExceeded)
+	})
+
+	t.Run("diverging request", func(t *testing.T) {
+		conn := NewReplayConn(records)
+
+		_, err := conn.Write([]byte("mg other v\r\n"))
+		assert.ErrorContains(t, err, `diverges from the recording at byte 3: sent "other v\r\n", recorded "key v\r\n"`)
+	})
+
+	t.Run("small read buffer", func(t *testing.T) {
+		conn := NewReplayConn(records)
+		_, err := conn.Write([]byte("mg key v\r\n"))
+		require.NoError(t, err)
+
+		var got bytes.Buffer
+		buf := make([]byte, 3)
+		for got.Len() < len("VA 5\r\nhello\r\n") {
+			n, err := conn.Read(buf)
+			require.NoError(t, err)
+			got.Write(buf[:n])
+		}
+		assert.Equal(t, "VA 5\r\nhello\r\n", got.String())
+	})
+}
@@ -0,0 +1,215 @@
+package memcacherecord
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pior/memcache"
+)
+
+// ReplayDialer returns a dialer replaying the recordings at paths, one per
+// dialed connection, in order. Dialing more connections than recordings
+// fails.
+func ReplayDialer(paths ...string) (memcache.Dialer, error) {
+	recordings := make([][]Record, 0, len(paths))
+	for _, path := range paths {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("memcacherecord: %w", err)
+		}
+		records, err := ReadRecords(f)
+		_ = f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%w (%s)", err, path)
+		}
+		recordings = append(recordings, records)
+	}
+
+	var mu sync.Mutex
+	return memcache.DialerFunc(func(ctx context.Context, network, address string) (net.Conn, error) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if len(recordings) == 0 {
+			return nil, errors.New("memcacherecord: no recording left to replay")
+		}
+		records := recordings[0]
+		recordings = recordings[1:]
+		return NewReplayConn(records), nil
+	}), nil
+}
+
+// NewReplayConn returns a connection playing the server side of records.
+//
+// The client must send the recorded bytes: a write diverging from the
+// recording fails. Each received record is returned by a read once the
+// client sent the bytes preceding it, with the recorded read boundaries,
+// so a response split across reads is split the same way. A recorded read
+// error is returned as is (a timeout as a net.Error), and io.EOF follows the
+// last record. The timing of the recording isn't replayed.
+func NewReplayConn(records []Record) net.Conn {
+	c := &replayConn{notify: make(chan struct{})}
+	for _, r := range records {
+		switch r.Direction {
+		case Sent:
+			c.sent = append(c.sent, r.Data...)
+		case Received:
+			c.reads = append(c.reads, replayRead{after: len(c.sent), data: r.Data})
+		case ReadError:
+			c.reads = append(c.reads, replayRead{after: len(c.sent), err: replayError(r.Data)})
+		}
+	}
+	return c
+}
+
+type replayRead struct {
+	after int // bytes sent by the client before the read
+	data  []byte
+	err   error
+}
+
+type replayConn struct {
+	mu      sync.Mutex
+	sent    []byte // bytes the client is expected to send
+	written int    // bytes sent by the client so far
+	reads   []replayRead
+	partial []byte        // rest of a read larger than the buffer of the client
+	notify  chan struct{} // closed and replaced on writes, closed on Close
+
+	closed       bool
+	readDeadline time.Time
+}
+
+func (c *replayConn) Read(b []byte) (int, error) {
+	for {
+		c.mu.Lock()
+		if c.closed {
+			c.mu.Unlock()
+			return 0, net.ErrClosed
+		}
+		if len(c.partial) > 0 {
+			n := copy(b, c.partial)
+			c.partial = c.partial[n:]
+			c.mu.Unlock()
+			return n, nil
+		}
+		if len(c.reads) == 0 {
+			c.mu.Unlock()
+			return 0, io.EOF
+		}
+		if read := c.reads[0]; c.written >= read.after {
+			c.reads = c.reads[1:]
+			n := copy(b, read.data)
+			c.partial = read.data[n:]
+			c.mu.Unlock()
+			return n, read.err
+		}
+		notify, deadline := c.notify, c.readDeadline
+		c.mu.Unlock()
+
+		// Wait for the client to send the request of the response.
+		if !c.wait(notify, deadline) {
+			return 0, os.ErrDeadlineExceeded
+		}
+	}
+}
+
+// wait waits for notify until the deadline, and reports whether it was
+// notified.
+func (c *replayConn) wait(notify <-chan struct{}, deadline time.Time) bool {
+	if deadline.IsZero() {
+		<-notify
+		return true
+	}
+
+	timer := time.NewTimer(time.Until(deadline))
+	defer timer.Stop()
+	select {
+	case <-notify:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
+
+func (c *replayConn) Write(b []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return 0, net.ErrClosed
+	}
+
+	expected := c.sent[c.written:]
+	for i := range b {
+		if i >= len(expected) || b[i] != expected[i] {
+			recorded := expected[i:min(len(expected), i+64)]
+			return 0, fmt.Errorf("memcacherecord: request diverges from the recording at byte %d: sent %q, recorded %q",
+				c.written+i, b[i:min(len(b), i+64)], recorded)
+		}
+	}
+
+	c.written += len(b)
+	close(c.notify)
+	c.notify = make(chan struct{})
+	return len(b), nil
+}
+
+func (c *replayConn) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.closed {
+		c.closed = true
+		close(c.notify)
+	}
+	return nil
+}
+
+func (c *replayConn) SetDeadline(t time.Time) error {
+	return c.SetReadDeadline(t)
+}
+
+func (c *replayConn) SetReadDeadline(t time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.readDeadline = t
+	return nil
+}
+
+// SetWriteDeadline is a no-op: writes don't block.
+func (c *replayConn) SetWriteDeadline(time.Time) error { return nil }
+
+func (c *replayConn) LocalAddr() net.Addr  { return replayAddr{} }
+func (c *replayConn) RemoteAddr() net.Addr { return replayAddr{} }
+
+type replayAddr struct{}
+
+func (replayAddr) Network() string { return "replay" }
+func (replayAddr) String() string  { return "replay" }
+
+// replayError returns the recorded read error msg.
+func replayError(msg []byte) error {
+	switch s := string(msg); {
+	case s == io.EOF.Error():
+		return io.EOF
+	case strings.HasSuffix(s, os.ErrDeadlineExceeded.Error()):
+		return &timeoutError{msg: s}
+	default:
+		return errors.New(s)
+	}
+}
+
+// timeoutError is a recorded timeout.
+type timeoutError struct{ msg string }
+
+func (e *timeoutError) Error() string   { return e.msg }
+func (e *timeoutError) Timeout() bool   { return true }
+func (e *timeoutError) Temporary() bool { return true }
@@ -0,0 +1,403 @@
+package memcachetest
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/pior/memcache"
+	"github.com/pior/memcache/meta"
+)
+
+// Mock is a scripted memcached server: it answers the requests matching its
+// expectations with their scripted response, so tests can assert what a
+// client sends and how it handles each response:
+//
+//	mock := memcachetest.NewMock(t)
+//	mock.ExpectGet("user:1").RespondValue([]byte("alice"))
+//	mock.ExpectAnySet().RespondNS()
+//	client := memcache.NewClient(memcache.StaticServers(mock.Addr), memcache.Config{})
+//
+// By default, requests must come in the order of the expectations; Unordered
+// relaxes it. An unexpected request gets a SERVER_ERROR response. When the
+// test ends, the unexpected requests and the unmet expectations are reported
+// as test errors.
+//
+// The noop command (mn) ends the batches of the client: it is always answered
+// with MN, without expectation.
+type Mock struct {
+	// Network and Addr are the address the mock listens on: "tcp" and a
+	// "127.0.0.1:<port>" address.
+	Network string
+	Addr    string
+
+	ep *endpoint
+
+	mu           sync.Mutex
+	ordered      bool
+	expectations []*Expectation
+	next         int // in ordered mode, index of the current expectation
+	unexpected   []string
+}
+
+// NewMock starts a mock on a random local TCP port. It is closed when the
+// test ends, after reporting the unexpected requests and unmet expectations.
+func NewMock(tb testing.TB) *Mock {
+	tb.Helper()
+
+	ln := listenTCP(tb)
+	m := &Mock{
+		Network: ln.Addr().Network(),
+		Addr:    ln.Addr().String(),
+		ordered: true,
+	}
+	m.ep = serve(ln, m.handle)
+	tb.Cleanup(func() {
+		m.Close()
+		m.AssertExpectations(tb)
+	})
+	return m
+}
+
+// Dialer returns a dialer connecting to the mock whatever the address dialed.
+func (m *Mock) Dialer() memcache.Dialer {
+	return dialer(m.Network, m.Addr)
+}
+
+// Close stops the mock and closes its connections.
+func (m *Mock) Close() {
+	m.ep.close()
+}
+
+// Unordered lets the requests match the expectations in any order.
+func (m *Mock) Unordered() *Mock {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.ordered = false
+	return m
+}
+
+// Expect adds an expectation for a cmd request on key, answered once with HD
+// unless scripted otherwise.
+func (m *Mock) Expect(cmd meta.CmdType, key string) *Expectation {
+	return m.add(&Expectation{cmd: cmd, key: key})
+}
+
+// ExpectAny adds an expectation for a cmd request on any key.
+func (m *Mock) ExpectAny(cmd meta.CmdType) *Expectation {
+	return m.add(&Expectation{cmd: cmd, anyKey: true})
+}
+
+// ExpectGet adds an expectation for an mg request on key.
+func (m *Mock) ExpectGet(key string) *Expectation { return m.Expect(meta.CmdGet, key) }
+
+// ExpectSet adds an expectation for an ms request on key.
+func (m *Mock) ExpectSet(key string) *Expectation { return m.Expect(meta.CmdSet, key) }
+
+// ExpectDelete adds an expectation for an md request on key.
+func (m *Mock) ExpectDelete(key string) *Expectation { return m.Expect(meta.CmdDelete, key) }
+
+// ExpectArithmetic adds an expectation for an ma request on key.
+func (m *Mock) ExpectArithmetic(key string) *Expectation { return m.Expect(meta.CmdArithmetic, key) }
+
+// ExpectAnyGet adds an expectation for an mg request on any key.
+func (m *Mock) ExpectAnyGet() *Expectation { return m.ExpectAny(meta.CmdGet) }
+
+// ExpectAnySet adds an expectation for an ms request on any key.
+func (m *Mock) ExpectAnySet() *Expectation { return m.ExpectAny(meta.CmdSet) }
+
+// ExpectAnyDelete adds an expectation for an md request on any key.
+func (m *Mock) ExpectAnyDelete() *Expectation { return m.ExpectAny(meta.CmdDelete) }
+
+// ExpectAnyArithmetic adds an expectation for an ma request on any key.
+func (m *Mock) ExpectAnyArithmetic() *Expectation { return m.ExpectAny(meta.CmdArithmetic) }
+
+func (m *Mock) add(e *Expectation) *Expectation {
+	e.mock = m
+	e.times = 1
+	e.response = "HD\r\n"
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.expectations = append(m.expectations, e)
+	return e
+}
+
+// Unmet returns the description of the expectations not met yet.
+func (m *Mock) Unmet() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var unmet []string
+	for _, e := range m.expectations {
+		if !e.satisfied() {
+			unmet = append(unmet, fmt.Sprintf("%s: called %d of %d times", e, e.calls, e.times))
+		}
+	}
+	return unmet
+}
+
+// Unexpected returns the requests that matched no expectation, as sent
+// without their data block.
+func (m *Mock) Unexpected() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]string(nil), m.unexpected...)
+}
+
+// AssertExpectations reports the unexpected requests and the unmet
+// expectations as errors of tb.
+func (m *Mock) AssertExpectations(tb testing.TB) {
+	tb.Helper()
+
+	for _, req := range m.Unexpected() {
+		tb.Errorf("memcachetest: unexpected request: %s", req)
+	}
+	for _, e := range m.Unmet() {
+		tb.Errorf("memcachetest: unmet expectation: %s", e)
+	}
+}
+
+// match returns the expectation answering req, or nil.
+func (m *Mock) match(req *meta.Request) *Expectation {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.ordered {
+		for _, e := range m.expectations {
+			if !e.exhausted() && e.matches(req) {
+				e.calls++
+				return e
+			}
+		}
+		return nil
+	}
+
+	// The satisfied expectations can be skipped to match the next ones.
+	for i := m.next; i < len(m.expectations); i++ {
+		e := m.expectations[i]
+		if !e.exhausted() && e.matches(req) {
+			e.calls++
+			m.next = i
+			return e
+		}
+		if !e.satisfied() {
+			return nil
+		}
+	}
+	return nil
+}
+
+func (m *Mock) handle(line string, r *bufio.Reader, w *bufio.Writer) bool {
+	req, ok := parseMockRequest(line, r)
+	if !ok {
+		writeLine(w, errBadChunk)
+		return false
+	}
+
+	if req.Command == meta.CmdNoOp {
+		writeLine(w, "MN")
+		return true
+	}
+
+	e := m.match(req)
+	if e == nil {
+		m.mu.Lock()
+		m.unexpected = append(m.unexpected, line)
+		m.mu.Unlock()
+		writeLine(w, "SERVER_ERROR memcachetest: unexpected request")
+		return true
+	}
+
+	m.mu.Lock()
+	response := e.response
+	m.mu.Unlock()
+
+	if req.HasFlag(meta.FlagQuiet) && quietStatus(req.Command, response) {
+		return true
+	}
+	_, _ = w.WriteString(response)
+	return true
+}
+
+// parseMockRequest parses a request line, reading the data block of an ms
+// request from r.
+func parseMockRequest(line string, r *bufio.Reader) (*meta.Request, bool) {
+	fields := strings.Split(line, " ")
+	req := meta.NewRequest(meta.CmdType(fields[0]), "", nil)
+	if len(fields) > 1 {
+		req.Key = fields[1]
+		fields = fields[2:]
+	} else {
+		fields = nil
+	}
+
+	if req.Command == meta.CmdSet {
+		if len(fields) == 0 {
+			return nil, false
+		}
+		size, err := strconv.Atoi(fields[0])
+		if err != nil || size < 0 {
+			return nil, false
+		}
+		data := make([]byte, size+2)
+		if _, err := io.ReadFull(r, data); err != nil || string(data[size:]) != "\r\n" {
+			return nil, false
+		}
+		req.Data = data[:size]
+		fields = fields[1:]
+	}
+
+	for _, flag := range fields {
+		req.Flags = append(append(req.Flags, ' '), flag...)
+	}
+	return req, true
+}
+
+// quietStatus reports whether the response is suppressed by the q flag of a
+// cmd request.
+func quietStatus(cmd meta.CmdType, response string) bool {
+	status, _, _ := strings.Cut(strings.TrimSuffix(response, "\r\n"), " ")
+	switch cmd {
+	case meta.CmdGet:
+		return status == string(meta.StatusEN)
+	case meta.CmdDelete, meta.CmdArithmetic:
+		return status == string(meta.StatusHD) || status == string(meta.StatusNF)
+	default:
+		return status == string(meta.StatusHD)
+	}
+}
+
+// Expectation is a request expected by a Mock, and its scripted response.
+// The methods return the expectation, for chaining.
+type Expectation struct {
+	mock     *Mock
+	cmd      meta.CmdType
+	key      string
+	anyKey   bool
+	matchers []func(*meta.Request) bool
+	response string
+	times    int // -1: any number of times
+	calls    int
+}
+
+// String describes the expectation, e.g. "mg user:1".
+func (e *Expectation) String() string {
+	if e.anyKey {
+		return string(e.cmd) + " <any key>"
+	}
+	return string(e.cmd) + " " + e.key
+}
+
+// Match restricts the expectation to the requests accepted by f, e.g. to
+// check their flags:
+//
+//	mock.ExpectSet("key").Match(func(req *meta.Request) bool {
+//		ttl, _ := req.Flags.GetInt64(meta.FlagTTL)
+//		return ttl == 60
+//	})
+func (e *Expectation) Match(f func(req *meta.Request) bool) *Expectation {
+	e.mock.mu.Lock()
+	defer e.mock.mu.Unlock()
+	e.matchers = append(e.matchers, f)
+	return e
+}
+
+// Times expects n matching requests instead of one.
+func (e *Expectation) Times(n int) *Expectation {
+	e.mock.mu.Lock()
+	defer e.mock.mu.Unlock()
+	e.times = n
+	return e
+}
+
+// AnyTimes accepts any number of matching requests, including none.
+func (e *Expectation) AnyTimes() *Expectation {
+	return e.Times(-1)
+}
+
+// RespondValue answers with a hit returning value, and the return flags
+// given as tokens (e.g. "f0", "c42").
+func (e *Expectation) RespondValue(value []byte, flags ...string) *Expectation {
+	var b strings.Builder
+	b.WriteString("VA " + strconv.Itoa(len(value)))
+	for _, flag := range flags {
+		b.WriteString(" " + flag)
+	}
+	b.WriteString("\r\n")
+	b.Write(value)
+	b.WriteString("\r\n")
+	return e.RespondRaw(b.String())
+}
+
+// RespondHD answers with HD and the given return flags: a hit without value,
+// or a successful store, delete or arithmetic operation.
+func (e *Expectation) RespondHD(flags ...string) *Expectation {
+	return e.respondStatus(meta.StatusHD, flags)
+}
+
+// RespondMiss answers with EN, the miss of mg.
+func (e *Expectation) RespondMiss() *Expectation {
+	return e.respondStatus(meta.StatusEN, nil)
+}
+
+// RespondNS answers with NS: the item was not stored.
+func (e *Expectation) RespondNS() *Expectation {
+	return e.respondStatus(meta.StatusNS, nil)
+}
+
+// RespondEX answers with EX: the CAS value didn't match.
+func (e *Expectation) RespondEX() *Expectation {
+	return e.respondStatus(meta.StatusEX, nil)
+}
+
+// RespondNF answers with NF: the item was not found.
+func (e *Expectation) RespondNF() *Expectation {
+	return e.respondStatus(meta.StatusNF, nil)
+}
+
+// RespondServerError answers with SERVER_ERROR msg.
+func (e *Expectation) RespondServerError(msg string) *Expectation {
+	return e.RespondRaw("SERVER_ERROR " + msg + "\r\n")
+}
+
+// RespondRaw answers with the raw protocol bytes of wire, which must include
+// the line terminators.
+func (e *Expectation) RespondRaw(wire string) *Expectation {
+	e.mock.mu.Lock()
+	defer e.mock.mu.Unlock()
+	e.response = wire
+	return e
+}
+
+func (e *Expectation) respondStatus(status meta.StatusType, flags []string) *Expectation {
+	line := string(status)
+	for _, flag := range flags {
+		line += " " + flag
+	}
+	return e.RespondRaw(line + "\r\n")
+}
+
+func (e *Expectation) matches(req *meta.Request) bool {
+	if req.Command != e.cmd || (!e.anyKey && req.Key != e.key) {
+		return false
+	}
+	for _, match := range e.matchers {
+		if !match(req) {
+			return false
+		}
+	}
+	return true
+}
+
+func (e *Expectation) satisfied() bool {
+	return e.times < 0 || e.calls >= e.times
+}
+
+func (e *Expectation) exhausted() bool {
+	return e.times >= 0 && e.calls >= e.times
+}
@@ -0,0 +1,132 @@
+package memcachetest
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/pior/memcache"
+	"github.com/pior/memcache/meta"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recorderTB records the errors reported by a Mock.
+type recorderTB struct {
+	testing.TB
+	errors []string
+}
+
+func (r *recorderTB) Errorf(format string, args ...any) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func newMockClient(t *testing.T, mock *Mock) *memcache.Client {
+	client := memcache.NewClient(memcache.StaticServers(mock.Addr), memcache.Config{})
+	t.Cleanup(client.Close)
+	return client
+}
+
+func TestMock_Responses(t *testing.T) {
+	ctx := context.Background()
+	mock := NewMock(t)
+	mock.ExpectGet("user:1").RespondValue([]byte("alice"), "f3")
+	mock.ExpectGet("user:2").RespondMiss()
+	mock.ExpectAnySet().RespondNS()
+	mock.ExpectDelete("user:1").RespondServerError("out of memory")
+
+	client := newMockClient(t, mock)
+
+	item, err := client.Get(ctx, "user:1")
+	require.NoError(t, err)
+	assert.Equal(t, "alice", string(item.Value))
+	assert.Equal(t, uint32(3), item.Flags)
+
+	item, err = client.Get(ctx, "user:2")
+	require.NoError(t, err)
+	assert.False(t, item.Found)
+
+	err = client.Set(ctx, memcache.Item{Key: "user:3", Value: []byte("carol")})
+	assert.ErrorIs(t, err, memcache.ErrNotStored)
+
+	err = client.Delete(ctx, "user:1")
+	assert.ErrorContains(t, err, "out of memory")
+}
+
+func TestMock_Match(t *testing.T) {
+	ctx := context.Background()
+	mock := NewMock(t)
+	mock.ExpectSet("key").Match(func(req *meta.Request) bool {
+		ttl, _ := req.Flags.GetInt64(meta.FlagTTL)
+		return string(req.Data) == "value" && ttl == 60
+	})
+
+	client := newMockClient(t, mock)
+
+	err := client.Set(ctx, memcache.Item{Key: "key", Value: []byte("value"), TTL: memcache.ExpiresIn(time.Minute)})
+	require.NoError(t, err)
+}
+
+func TestMock_Ordered(t *testing.T) {
+	ctx := context.Background()
+	tb := &recorderTB{TB: t}
+	mock := NewMock(t)
+	mock.ExpectGet("first").RespondMiss()
+	mock.ExpectGet("second").RespondMiss()
+
+	client := newMockClient(t, mock)
+
+	_, err := client.Get(ctx, "second")
+	require.Error(t, err)
+	_, err = client.Get(ctx, "first")
+	require.NoError(t, err)
+	_, err = client.Get(ctx, "second")
+	require.NoError(t, err)
+
+	mock.Close()
+	mock.AssertExpectations(tb)
+	assert.Equal(t, []string{"memcachetest: unexpected request: mg second v f"}, tb.errors)
+
+	// Already reported above.
+	mock.unexpected = nil
+}
+
+func TestMock_Unordered(t *testing.T) {
+	ctx := context.Background()
+	mock := NewMock(t).Unordered()
+	mock.ExpectGet("first").RespondMiss()
+	mock.ExpectAnyGet().RespondMiss().Times(2)
+
+	batch := memcache.NewBatchCommands(newMockClient(t, mock))
+
+	items, err := batch.MultiGet(ctx, []string{"second", "third", "first"})
+	require.NoError(t, err)
+	assert.Len(t, items, 3)
+}
+
+func TestMock_Unmet(t *testing.T) {
+	ctx := context.Background()
+	tb := &recorderTB{TB: t}
+	mock := NewMock(t)
+	mock.ExpectAnyGet().AnyTimes()
+	mock.ExpectArithmetic("counter").RespondValue([]byte("1")).Times(2)
+	mock.ExpectDelete("key")
+
+	client := newMockClient(t, mock)
+
+	value, err := client.Increment(ctx, "counter", 1, memcache.NoTTL)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), value)
+
+	mock.AssertExpectations(tb)
+	assert.Equal(t, []string{
+		"memcachetest: unmet expectation: ma counter: called 1 of 2 times",
+		"memcachetest: unmet expectation: md key: called 0 of 1 times",
+	}, tb.errors)
+
+	// Meet them, as the mock reports again when the test ends.
+	_, err = client.Increment(ctx, "counter", 1, memcache.NoTTL)
+	require.NoError(t, err)
+	require.NoError(t, client.Delete(ctx, "key"))
+}
@@ -0,0 +1,693 @@
+package memcachetest
+
+import (
+	"bufio"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"maps"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Errors returned to the client, as memcached words them.
+const (
+	errBadFormat  = "CLIENT_ERROR bad command line format"
+	errBadChunk   = "CLIENT_ERROR bad data chunk"
+	errNonNumeric = "CLIENT_ERROR cannot increment or decrement non-numeric value"
+	errInvalidArg = "CLIENT_ERROR invalid numeric delta argument"
+	errTooLarge   = "SERVER_ERROR object too large for cache"
+)
+
+// version is the version the server reports.
+const version = "1.6.0-memcachetest"
+
+const (
+	maxKeyLength = 250
+	maxItemSize  = 1 << 20 // memcached -I default
+
+	// maxRelativeTTL is the largest TTL read as relative, in seconds (30
+	// days). Larger values are absolute unix timestamps.
+	maxRelativeTTL = 30 * 24 * 60 * 60
+)
+
+// item is a stored item.
+type item struct {
+	value      []byte
+	flags      uint32
+	cas        uint64
+	exp        time.Time // zero: never expires
+	lastAccess time.Time
+	fetched    bool
+	stale      bool // invalidated by md with I: served with the X flag
+	winSent    bool // a W flag was sent for the item: later fetches get Z
+}
+
+// request is a parsed meta command line.
+type request struct {
+	key    string // decoded with the b flag
+	rawKey string // as sent, returned by the k flag
+	flags  []string
+}
+
+// has reports whether the flag f was sent.
+func (rq *request) has(f byte) bool {
+	_, ok := rq.token(f)
+	return ok
+}
+
+// token returns the token of the flag f.
+func (rq *request) token(f byte) (string, bool) {
+	for _, flag := range rq.flags {
+		if flag[0] == f {
+			return flag[1:], true
+		}
+	}
+	return "", false
+}
+
+// int returns the numeric token of the flag f. A malformed token is reported
+// as invalid.
+func (rq *request) int(f byte) (n int64, ok, valid bool) {
+	tok, ok := rq.token(f)
+	if !ok {
+		return 0, false, true
+	}
+	n, err := strconv.ParseInt(tok, 10, 64)
+	return n, true, err == nil
+}
+
+// uint is int for unsigned tokens.
+func (rq *request) uint(f byte) (n uint64, ok, valid bool) {
+	tok, ok := rq.token(f)
+	if !ok {
+		return 0, false, true
+	}
+	n, err := strconv.ParseUint(tok, 10, 64)
+	return n, true, err == nil
+}
+
+// parseRequest parses the key and flags of a meta command.
+func parseRequest(fields []string) (*request, bool) {
+	if len(fields) == 0 {
+		return nil, false
+	}
+	rq := &request{rawKey: fields[0], key: fields[0]}
+	for _, flag := range fields[1:] {
+		if flag == "" {
+			return nil, false
+		}
+		rq.flags = append(rq.flags, flag)
+	}
+
+	if rq.has('b') {
+		key, err := base64.StdEncoding.DecodeString(rq.rawKey)
+		if err != nil {
+			return nil, false
+		}
+		rq.key = string(key)
+	}
+	if rq.key == "" || len(rq.key) > maxKeyLength {
+		return nil, false
+	}
+	if !rq.has('b') && strings.ContainsFunc(rq.key, func(r rune) bool { return r <= ' ' || r == 0x7f }) {
+		return nil, false
+	}
+	return rq, true
+}
+
+// handle is the handler of the server: it executes the meta commands on the
+// items.
+func (s *Server) handle(line string, r *bufio.Reader, w *bufio.Writer) bool {
+	fields := strings.Split(line, " ")
+
+	switch fields[0] {
+	case "mg", "md", "ma":
+		rq, ok := parseRequest(fields[1:])
+		if !ok {
+			writeLine(w, errBadFormat)
+			return true
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		switch fields[0] {
+		case "mg":
+			s.metaGet(rq, w)
+		case "md":
+			s.metaDelete(rq, w)
+		case "ma":
+			s.metaArithmetic(rq, w)
+		}
+		return true
+
+	case "ms":
+		if len(fields) < 3 {
+			writeLine(w, errBadFormat)
+			return true
+		}
+		size, err := strconv.Atoi(fields[2])
+		if err != nil || size < 0 {
+			// The data block can't be skipped: the stream is lost.
+			writeLine(w, errBadChunk)
+			return false
+		}
+		data := make([]byte, size+2)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return false
+		}
+		if string(data[size:]) != "\r\n" {
+			writeLine(w, errBadChunk)
+			return false
+		}
+
+		rq, ok := parseRequest(append([]string{fields[1]}, fields[3:]...))
+		if !ok {
+			writeLine(w, errBadFormat)
+			return true
+		}
+		if size > maxItemSize {
+			writeLine(w, errTooLarge)
+			return true
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.metaSet(rq, data[:size], w)
+		return true
+
+	case "mn":
+		writeLine(w, "MN")
+		return true
+
+	case "version":
+		writeLine(w, "VERSION "+version)
+		return true
+
+	case "stats":
+		if len(fields) != 1 {
+			writeLine(w, "ERROR") // no stats groups (slabs, items, ...)
+			return true
+		}
+		s.stats(w)
+		return true
+
+	case "lru_crawler":
+		if len(fields) != 3 || fields[1] != "metadump" {
+			writeLine(w, "CLIENT_ERROR bad command line format")
+			return true
+		}
+		s.metadump(w)
+		return true
+
+	case "flush_all":
+		s.Flush()
+		if fields[len(fields)-1] != "noreply" {
+			writeLine(w, "OK")
+		}
+		return true
+
+	case "quit":
+		return false
+
+	default:
+		writeLine(w, "ERROR")
+		return true
+	}
+}
+
+func (s *Server) metaGet(rq *request, w *bufio.Writer) {
+	vivifyTTL, vivify, valid := rq.int('N')
+	touchTTL, touch, validT := rq.int('T')
+	recache, hasRecache, validR := rq.int('R')
+	if !valid || !validT || !validR {
+		writeLine(w, errBadFormat)
+		return
+	}
+
+	it := s.lookup(rq.key)
+	created := false
+	if it == nil {
+		if !vivify {
+			if !rq.has('q') {
+				writeLine(w, "EN")
+			}
+			return
+		}
+		// A stub item: the client winning it recaches the value.
+		it = &item{exp: s.expiration(vivifyTTL), cas: s.nextCAS(rq), lastAccess: s.now}
+		s.items[rq.key] = it
+		created = true
+	}
+
+	// h and l report the state before the command.
+	hit, lastAccess := it.fetched, s.now.Sub(it.lastAccess)
+
+	if touch {
+		it.exp = s.expiration(touchTTL)
+	}
+
+	var extra []string
+	switch {
+	case created:
+		it.winSent = true
+		extra = append(extra, "W")
+	case it.stale:
+		extra = append(extra, "X", s.win(it))
+	case it.winSent:
+		extra = append(extra, "Z")
+	case hasRecache && !it.exp.IsZero() && s.remaining(it) < recache:
+		extra = append(extra, s.win(it))
+	}
+
+	if !rq.has('u') {
+		it.fetched = true
+		it.lastAccess = s.now
+	}
+
+	var flags []string
+	for _, flag := range rq.flags {
+		switch flag[0] {
+		case 'c':
+			flags = append(flags, "c"+strconv.FormatUint(it.cas, 10))
+		case 'f':
+			flags = append(flags, "f"+strconv.FormatUint(uint64(it.flags), 10))
+		case 'h':
+			flags = append(flags, "h"+strconv.Itoa(btoi(hit)))
+		case 'l':
+			flags = append(flags, "l"+strconv.FormatInt(int64(lastAccess/time.Second), 10))
+		case 's':
+			flags = append(flags, "s"+strconv.Itoa(len(it.value)))
+		case 't':
+			flags = append(flags, "t"+strconv.FormatInt(s.remaining(it), 10))
+		default:
+			flags = appendCommonFlag(flags, rq, flag)
+		}
+	}
+	flags = append(flags, extra...)
+
+	if rq.has('v') {
+		writeValue(w, it.value, flags)
+		return
+	}
+	writeLine(w, "HD", flags...)
+}
+
+// win returns the W flag for the first client asking for the item, and the Z
+// flag for the others.
+func (s *Server) win(it *item) string {
+	if it.winSent {
+		return "Z"
+	}
+	it.winSent = true
+	return "W"
+}
+
+func (s *Server) metaSet(rq *request, data []byte, w *bufio.Writer) {
+	clientFlags, _, valid := rq.uint('F')
+	ttl, _, validT := rq.int('T')
+	vivifyTTL, vivify, validN := rq.int('N')
+	compareCAS, compare, validC := rq.uint('C')
+	if !valid || clientFlags > 1<<32-1 || !validT || !validN || !validC {
+		writeLine(w, errBadFormat)
+		return
+	}
+
+	mode := byte('S')
+	if tok, ok := rq.token('M'); ok {
+		if len(tok) != 1 || !strings.Contains("SEAPRseapr", tok) {
+			writeLine(w, "CLIENT_ERROR invalid mode for ms STORE")
+			return
+		}
+		mode = strings.ToUpper(tok)[0]
+	}
+
+	it := s.lookup(rq.key)
+	stale := false
+	if compare {
+		if it == nil {
+			writeStatus(w, rq, "NF")
+			return
+		}
+		if compareCAS != it.cas {
+			// With I, an older CAS stores the item as stale.
+			if !rq.has('I') || compareCAS > it.cas {
+				writeStatus(w, rq, "EX")
+				return
+			}
+			stale = true
+		}
+	}
+
+	switch mode {
+	case 'E':
+		if it != nil {
+			writeStatus(w, rq, "NS")
+			return
+		}
+	case 'R':
+		if it == nil {
+			writeStatus(w, rq, "NS")
+			return
+		}
+	case 'A', 'P':
+		if it == nil {
+			if !vivify {
+				writeStatus(w, rq, "NS")
+				return
+			}
+			ttl, clientFlags = vivifyTTL, 0
+			break
+		}
+		// Append and prepend keep the flags and TTL of the item.
+		value := make([]byte, 0, len(it.value)+len(data))
+		if mode == 'A' {
+			value = append(append(value, it.value...), data...)
+		} else {
+			value = append(append(value, data...), it.value...)
+		}
+		it.value = value
+		it.cas = s.nextCAS(rq)
+		it.stale, it.winSent = false, false
+		writeStored(w, rq, it)
+		return
+	}
+
+	it = &item{
+		value:      append([]byte(nil), data...),
+		flags:      uint32(clientFlags),
+		cas:        s.nextCAS(rq),
+		exp:        s.expiration(ttl),
+		lastAccess: s.now,
+		stale:      stale,
+	}
+	s.items[rq.key] = it
+	writeStored(w, rq, it)
+}
+
+// writeStored writes the response of a successful ms.
+func writeStored(w *bufio.Writer, rq *request, it *item) {
+	if rq.has('q') {
+		return
+	}
+	var flags []string
+	for _, flag := range rq.flags {
+		switch flag[0] {
+		case 'c':
+			flags = append(flags, "c"+strconv.FormatUint(it.cas, 10))
+		case 's':
+			flags = append(flags, "s"+strconv.Itoa(len(it.value)))
+		default:
+			flags = appendCommonFlag(flags, rq, flag)
+		}
+	}
+	writeLine(w, "HD", flags...)
+}
+
+func (s *Server) metaDelete(rq *request, w *bufio.Writer) {
+	ttl, touch, valid := rq.int('T')
+	compareCAS, compare, validC := rq.uint('C')
+	if !valid || !validC {
+		writeLine(w, errBadFormat)
+		return
+	}
+
+	it := s.lookup(rq.key)
+	if it == nil {
+		if !rq.has('q') {
+			writeStatus(w, rq, "NF")
+		}
+		return
+	}
+	if compare && compareCAS != it.cas {
+		writeStatus(w, rq, "EX")
+		return
+	}
+
+	switch {
+	case rq.has('I'):
+		// Invalidation keeps the item, served as stale until recached.
+		it.stale, it.winSent = true, false
+		it.cas = s.nextCAS(rq)
+		if touch {
+			it.exp = s.expiration(ttl)
+		}
+		if rq.has('x') {
+			it.value = nil
+		}
+	case rq.has('x'):
+		it.value = nil
+		it.cas = s.nextCAS(rq)
+	default:
+		delete(s.items, rq.key)
+	}
+
+	if !rq.has('q') {
+		writeStatus(w, rq, "HD")
+	}
+}
+
+func (s *Server) metaArithmetic(rq *request, w *bufio.Writer) {
+	delta, hasDelta, valid := rq.uint('D')
+	initial, _, validJ := rq.uint('J')
+	vivifyTTL, vivify, validN := rq.int('N')
+	ttl, touch, validT := rq.int('T')
+	compareCAS, compare, validC := rq.uint('C')
+	if !valid || !validJ {
+		writeLine(w, errInvalidArg)
+		return
+	}
+	if !validN || !validT || !validC {
+		writeLine(w, errBadFormat)
+		return
+	}
+	if !hasDelta {
+		delta = 1
+	}
+
+	decrement := false
+	if tok, ok := rq.token('M'); ok {
+		switch tok {
+		case "I", "i", "+":
+		case "D", "d", "-":
+			decrement = true
+		default:
+			writeLine(w, "CLIENT_ERROR invalid mode for ma")
+			return
+		}
+	}
+
+	it := s.lookup(rq.key)
+	switch {
+	case it == nil && !vivify:
+		if !rq.has('q') {
+			writeStatus(w, rq, "NF")
+		}
+		return
+
+	case it == nil:
+		// The auto-created counter holds the initial value: the delta isn't
+		// applied.
+		it = &item{
+			value:      []byte(strconv.FormatUint(initial, 10)),
+			cas:        s.nextCAS(rq),
+			exp:        s.expiration(vivifyTTL),
+			lastAccess: s.now,
+		}
+		s.items[rq.key] = it
+
+	default:
+		if compare && compareCAS != it.cas {
+			writeStatus(w, rq, "EX")
+			return
+		}
+		value, err := strconv.ParseUint(string(it.value), 10, 64)
+		if err != nil {
+			writeLine(w, errNonNumeric)
+			return
+		}
+		switch {
+		case !decrement:
+			value += delta // overflows, as memcached
+		case delta > value:
+			value = 0
+		default:
+			value -= delta
+		}
+		it.value = []byte(strconv.FormatUint(value, 10))
+		it.cas = s.nextCAS(rq)
+		if touch {
+			it.exp = s.expiration(ttl)
+		}
+	}
+
+	var flags []string
+	for _, flag := range rq.flags {
+		switch flag[0] {
+		case 'c':
+			flags = append(flags, "c"+strconv.FormatUint(it.cas, 10))
+		case 't':
+			flags = append(flags, "t"+strconv.FormatInt(s.remaining(it), 10))
+		default:
+			flags = appendCommonFlag(flags, rq, flag)
+		}
+	}
+
+	if rq.has('v') {
+		writeValue(w, it.value, flags)
+		return
+	}
+	if !rq.has('q') {
+		writeLine(w, "HD", flags...)
+	}
+}
+
+// metadump lists the items as lru_crawler metadump does, sorted by key. All
+// the items are in the slab class 1.
+func (s *Server) metadump(w *bufio.Writer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	keys := slices.Sorted(maps.Keys(s.items))
+	for _, key := range keys {
+		it := s.lookup(key)
+		if it == nil {
+			continue
+		}
+		exp := int64(-1)
+		if !it.exp.IsZero() {
+			exp = it.exp.Unix()
+		}
+		fetch := "no"
+		if it.fetched {
+			fetch = "yes"
+		}
+		fmt.Fprintf(w, "key=%s exp=%d la=%d cas=%d fetch=%s cls=1 size=%d\r\n",
+			url.PathEscape(key), exp, it.lastAccess.Unix(), it.cas, fetch, itemSize(key, it))
+	}
+	writeLine(w, "END")
+}
+
+// stats writes the general statistics of the server that are derived from
+// the items. The sizes are counted as in metadump.
+func (s *Server) stats(w *bufio.Writer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var items, bytes int
+	for key := range s.items {
+		if it := s.lookup(key); it != nil {
+			items++
+			bytes += itemSize(key, it)
+		}
+	}
+
+	fmt.Fprintf(w, "STAT pid %d\r\n", os.Getpid())
+	fmt.Fprintf(w, "STAT version %s\r\n", version)
+	fmt.Fprintf(w, "STAT curr_items %d\r\n", items)
+	fmt.Fprintf(w, "STAT bytes %d\r\n", bytes)
+	fmt.Fprintf(w, "STAT limit_maxbytes %d\r\n", 64<<20)
+	writeLine(w, "END")
+}
+
+// itemSize is the size of an item as reported by memcached: the key, the
+// value and the item header.
+func itemSize(key string, it *item) int {
+	return len(key) + len(it.value) + 48
+}
+
+// lookup returns the item of key, or nil when it is missing or expired.
+func (s *Server) lookup(key string) *item {
+	it, ok := s.items[key]
+	if !ok {
+		return nil
+	}
+	if !it.exp.IsZero() && !s.now.Before(it.exp) {
+		delete(s.items, key)
+		return nil
+	}
+	return it
+}
+
+// expiration converts a TTL token: 0 never expires, a negative TTL is
+// already expired, and a TTL above 30 days is an absolute unix timestamp.
+func (s *Server) expiration(ttl int64) time.Time {
+	switch {
+	case ttl == 0:
+		return time.Time{}
+	case ttl < 0:
+		return s.now
+	case ttl > maxRelativeTTL:
+		return time.Unix(ttl, 0)
+	default:
+		return s.now.Add(time.Duration(ttl) * time.Second)
+	}
+}
+
+// remaining returns the remaining TTL of it in seconds, -1 if it never
+// expires.
+func (s *Server) remaining(it *item) int64 {
+	if it.exp.IsZero() {
+		return -1
+	}
+	return int64((it.exp.Sub(s.now) + time.Second - 1) / time.Second)
+}
+
+// nextCAS returns the CAS value of a modified item: the E token, or the next
+// value of the counter.
+func (s *Server) nextCAS(rq *request) uint64 {
+	if cas, ok, valid := rq.uint('E'); ok && valid {
+		return cas
+	}
+	s.cas++
+	return s.cas
+}
+
+// appendCommonFlag appends the returned flags shared by all the commands:
+// k (with b for a base64 key) and O.
+func appendCommonFlag(flags []string, rq *request, flag string) []string {
+	switch flag[0] {
+	case 'k':
+		return append(flags, "k"+rq.rawKey)
+	case 'b':
+		if rq.has('k') {
+			return append(flags, "b")
+		}
+	case 'O':
+		return append(flags, flag)
+	}
+	return flags
+}
+
+// writeStatus writes a status line with the k and O flags of the request.
+func writeStatus(w *bufio.Writer, rq *request, status string) {
+	var flags []string
+	for _, flag := range rq.flags {
+		flags = appendCommonFlag(flags, rq, flag)
+	}
+	writeLine(w, status, flags...)
+}
+
+func writeValue(w *bufio.Writer, value []byte, flags []string) {
+	writeLine(w, "VA "+strconv.Itoa(len(value)), flags...)
+	_, _ = w.Write(value)
+	_, _ = w.WriteString("\r\n")
+}
+
+func writeLine(w *bufio.Writer, status string, flags ...string) {
+	_, _ = w.WriteString(status)
+	for _, flag := range flags {
+		_ = w.WriteByte(' ')
+		_, _ = w.WriteString(flag)
+	}
+	_, _ = w.WriteString("\r\n")
+}
+
+func btoi(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
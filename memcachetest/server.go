@@ -0,0 +1,259 @@
+// Package memcachetest provides in-memory memcached servers for tests.
+//
+// The server implements the meta protocol commands (mg, ms, md, ma, mn) with
+// TTLs, CAS and stale items, plus version, stats, flush_all and lru_crawler
+// metadump, so tests of code using memcache.Client don't need a memcached
+// process:
+//
+//	srv := memcachetest.NewServer(t)
+//	client := memcache.NewClient(memcache.StaticServers(srv.Addr), memcache.Config{})
+//
+// Time is frozen for the items of the server unless advanced with Advance,
+// so expiration can be tested without sleeping.
+//
+// Mock is a scripted server instead, asserting the requests sent by a client.
+package memcachetest
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pior/memcache"
+)
+
+// Server is an in-memory memcached server listening on a local TCP port or
+// Unix socket. It is safe for concurrent use.
+type Server struct {
+	// Network and Addr are the address the server listens on: "tcp" and a
+	// "127.0.0.1:<port>" address, or "unix" and a socket path.
+	Network string
+	Addr    string
+
+	ep *endpoint
+
+	mu    sync.Mutex
+	items map[string]*item
+	cas   uint64    // last CAS value assigned
+	now   time.Time // clock of the items, moved by Advance
+}
+
+// NewServer starts a server on a random local TCP port. It is closed when
+// the test ends.
+func NewServer(tb testing.TB) *Server {
+	tb.Helper()
+	return newServer(tb, listenTCP(tb))
+}
+
+// NewUnixServer starts a server on a Unix socket in a temporary directory.
+// It is closed when the test ends. Use Dialer to connect a client to it.
+func NewUnixServer(tb testing.TB) *Server {
+	tb.Helper()
+	return newServer(tb, listenUnix(tb))
+}
+
+func newServer(tb testing.TB, ln net.Listener) *Server {
+	s := &Server{
+		Network: ln.Addr().Network(),
+		Addr:    ln.Addr().String(),
+		items:   make(map[string]*item),
+		now:     time.Now(),
+	}
+	s.ep = serve(ln, s.handle)
+	tb.Cleanup(s.Close)
+	return s
+}
+
+// Dialer returns a dialer connecting to the server whatever the address
+// dialed, e.g. for a Unix socket server:
+//
+//	client := memcache.NewClient(memcache.StaticServers("memcachetest"), memcache.Config{
+//		Dialer: srv.Dialer(),
+//	})
+func (s *Server) Dialer() memcache.Dialer {
+	return dialer(s.Network, s.Addr)
+}
+
+// Close stops the server and closes its connections.
+func (s *Server) Close() {
+	s.ep.close()
+}
+
+// Advance moves the clock of the items forward by d, expiring the items
+// whose TTL ran out.
+func (s *Server) Advance(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.now = s.now.Add(d)
+}
+
+// Flush removes all the items.
+func (s *Server) Flush() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	clear(s.items)
+}
+
+func listenTCP(tb testing.TB) net.Listener {
+	tb.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		tb.Fatalf("memcachetest: listen: %v", err)
+	}
+	return ln
+}
+
+func listenUnix(tb testing.TB) net.Listener {
+	tb.Helper()
+
+	// Not tb.TempDir: socket paths are limited to about 100 bytes, and
+	// TempDir includes the test name.
+	dir, err := os.MkdirTemp("", "memcachetest")
+	if err != nil {
+		tb.Fatalf("memcachetest: %v", err)
+	}
+	tb.Cleanup(func() { _ = os.RemoveAll(dir) })
+
+	ln, err := net.Listen("unix", filepath.Join(dir, "memcached.sock"))
+	if err != nil {
+		tb.Fatalf("memcachetest: listen: %v", err)
+	}
+	return ln
+}
+
+func dialer(network, addr string) memcache.Dialer {
+	return memcache.DialerFunc(func(ctx context.Context, _, _ string) (net.Conn, error) {
+		var d net.Dialer
+		return d.DialContext(ctx, network, addr)
+	})
+}
+
+// handler executes a request line, reading its data block from r, and writes
+// the response to w. It returns false when the connection must be closed.
+type handler func(line string, r *bufio.Reader, w *bufio.Writer) bool
+
+// endpoint accepts the connections of a listener and serves their requests
+// with a handler.
+type endpoint struct {
+	ln     net.Listener
+	handle handler
+	wg     sync.WaitGroup
+
+	mu     sync.Mutex
+	conns  map[net.Conn]struct{}
+	closed bool
+}
+
+func serve(ln net.Listener, handle handler) *endpoint {
+	e := &endpoint{
+		ln:     ln,
+		handle: handle,
+		conns:  make(map[net.Conn]struct{}),
+	}
+	e.wg.Add(1)
+	go e.acceptLoop()
+	return e
+}
+
+func (e *endpoint) close() {
+	e.mu.Lock()
+	if e.closed {
+		e.mu.Unlock()
+		return
+	}
+	e.closed = true
+	for conn := range e.conns {
+		_ = conn.Close()
+	}
+	e.mu.Unlock()
+
+	_ = e.ln.Close()
+	e.wg.Wait()
+}
+
+func (e *endpoint) acceptLoop() {
+	defer e.wg.Done()
+
+	for {
+		conn, err := e.ln.Accept()
+		if err != nil {
+			return
+		}
+
+		e.mu.Lock()
+		if e.closed {
+			e.mu.Unlock()
+			_ = conn.Close()
+			return
+		}
+		e.conns[conn] = struct{}{}
+		e.mu.Unlock()
+
+		e.wg.Add(1)
+		go e.serveConn(conn)
+	}
+}
+
+// serveConn handles the requests of a connection until it is closed.
+// Responses are flushed when no pipelined request is pending.
+func (e *endpoint) serveConn(conn net.Conn) {
+	defer e.wg.Done()
+	defer func() {
+		e.mu.Lock()
+		delete(e.conns, conn)
+		e.mu.Unlock()
+		_ = conn.Close()
+	}()
+
+	r := bufio.NewReaderSize(conn, 2*maxLineSize)
+	w := bufio.NewWriter(conn)
+	for {
+		line, err := readLine(r)
+		if err != nil {
+			if errors.Is(err, errLineTooLong) {
+				_, _ = w.WriteString("CLIENT_ERROR line too long\r\n")
+				_ = w.Flush()
+			}
+			return
+		}
+
+		if !e.handle(line, r, w) {
+			_ = w.Flush()
+			return
+		}
+
+		if r.Buffered() == 0 {
+			if err := w.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// maxLineSize bounds request lines, as memcached does.
+const maxLineSize = 8192
+
+var errLineTooLong = errors.New("line too long")
+
+// readLine reads a request line without its line terminator.
+func readLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadSlice('\n')
+	if errors.Is(err, bufio.ErrBufferFull) || len(line) > maxLineSize {
+		return "", errLineTooLong
+	}
+	if err != nil {
+		return "", err
+	}
+	line = line[:len(line)-1]
+	if n := len(line); n > 0 && line[n-1] == '\r' {
+		line = line[:n-1]
+	}
+	return string(line), nil
+}
@@ -0,0 +1,215 @@
+package memcachetest
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pior/memcache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newClient(t *testing.T, srv *Server) *memcache.Client {
+	client := memcache.NewClient(memcache.StaticServers(srv.Addr), memcache.Config{Dialer: srv.Dialer()})
+	t.Cleanup(client.Close)
+	return client
+}
+
+// roundTrip sends raw protocol lines and reads n response lines.
+func roundTrip(t *testing.T, srv *Server, request string, n int) []string {
+	conn, err := net.Dial(srv.Network, srv.Addr)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = conn.Write([]byte(request))
+	require.NoError(t, err)
+
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+	r := bufio.NewReader(conn)
+	lines := make([]string, n)
+	for i := range lines {
+		line, err := r.ReadString('\n')
+		require.NoError(t, err)
+		lines[i] = strings.TrimSuffix(line, "\r\n")
+	}
+	return lines
+}
+
+func TestServer_Commands(t *testing.T) {
+	ctx := context.Background()
+	srv := NewServer(t)
+	client := newClient(t, srv)
+
+	item, err := client.Get(ctx, "key")
+	require.NoError(t, err)
+	assert.False(t, item.Found)
+
+	require.NoError(t, client.Set(ctx, memcache.Item{Key: "key", Value: []byte("hello"), Flags: 42}))
+
+	item, err = client.Get(ctx, "key")
+	require.NoError(t, err)
+	assert.True(t, item.Found)
+	assert.Equal(t, "hello", string(item.Value))
+	assert.Equal(t, uint32(42), item.Flags)
+
+	err = client.Add(ctx, memcache.Item{Key: "key", Value: []byte("other")})
+	assert.ErrorIs(t, err, memcache.ErrNotStored)
+
+	value, err := client.Increment(ctx, "counter", 5, memcache.NoTTL)
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), value)
+
+	value, err = client.Increment(ctx, "counter", -7, memcache.NoTTL)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), value)
+
+	_, _, err = client.IncrementWithOptions(ctx, "missing", memcache.IncrementOptions{Delta: 1})
+	assert.ErrorIs(t, err, memcache.ErrNotFound)
+
+	require.NoError(t, client.Delete(ctx, "key"))
+	item, err = client.Get(ctx, "key")
+	require.NoError(t, err)
+	assert.False(t, item.Found)
+}
+
+func TestServer_CAS(t *testing.T) {
+	ctx := context.Background()
+	srv := NewServer(t)
+	client := newClient(t, srv)
+
+	require.NoError(t, client.Set(ctx, memcache.Item{Key: "key", Value: []byte("v")}))
+	item, err := client.GetWithOptions(ctx, "key", memcache.GetOptions{ReturnCAS: true})
+	require.NoError(t, err)
+	require.NotZero(t, item.CAS)
+
+	err = client.DeleteWithOptions(ctx, "key", memcache.DeleteOptions{CAS: item.CAS + 1})
+	assert.ErrorIs(t, err, memcache.ErrCASConflict)
+
+	require.NoError(t, client.DeleteWithOptions(ctx, "key", memcache.DeleteOptions{CAS: item.CAS}))
+}
+
+func TestServer_Expiration(t *testing.T) {
+	ctx := context.Background()
+	srv := NewServer(t)
+	client := newClient(t, srv)
+
+	require.NoError(t, client.Set(ctx, memcache.Item{Key: "key", Value: []byte("v"), TTL: memcache.ExpiresIn(time.Minute)}))
+
+	srv.Advance(30 * time.Second)
+	item, err := client.GetWithOptions(ctx, "key", memcache.GetOptions{ReturnTTL: true})
+	require.NoError(t, err)
+	require.True(t, item.Found)
+	assert.Equal(t, memcache.ExpiresIn(30*time.Second), item.TTL)
+
+	srv.Advance(30 * time.Second)
+	item, err = client.Get(ctx, "key")
+	require.NoError(t, err)
+	assert.False(t, item.Found)
+}
+
+func TestServer_Invalidate(t *testing.T) {
+	srv := NewServer(t)
+
+	lines := roundTrip(t, srv, "ms key 5\r\nhello\r\nmd key I\r\nmg key v\r\nmg key v\r\nms key 5\r\nfresh\r\nmg key v\r\n", 9)
+
+	assert.Equal(t, []string{
+		"HD",
+		"HD",
+		"VA 5 X W", "hello",
+		"VA 5 X Z", "hello",
+		"HD",
+		"VA 5", "fresh",
+	}, lines)
+}
+
+func TestServer_Vivify(t *testing.T) {
+	srv := NewServer(t)
+
+	lines := roundTrip(t, srv, "mg key s N30\r\nmg key s N30\r\nma counter v N0 J7\r\n", 4)
+
+	assert.Equal(t, []string{"HD s0 W", "HD s0 Z", "VA 1", "7"}, lines)
+}
+
+func TestServer_Errors(t *testing.T) {
+	srv := NewServer(t)
+
+	lines := roundTrip(t, srv, "bogus\r\nmg "+strings.Repeat("k", 251)+" v\r\nms key 1\r\nab\r\n", 3)
+
+	assert.Equal(t, []string{"ERROR", "CLIENT_ERROR bad command line format", "CLIENT_ERROR bad data chunk"}, lines)
+}
+
+func TestServer_MultiGet(t *testing.T) {
+	ctx := context.Background()
+	srv := NewServer(t)
+	batch := memcache.NewBatchCommands(newClient(t, srv))
+
+	require.NoError(t, batch.MultiSet(ctx, []memcache.Item{
+		{Key: "k1", Value: []byte("v1")},
+		{Key: "k2", Value: []byte("v2")},
+	}))
+
+	items, err := batch.MultiGet(ctx, []string{"k1", "missing", "k2"})
+	require.NoError(t, err)
+	require.Len(t, items, 3)
+	assert.Equal(t, "v1", string(items[0].Value))
+	assert.False(t, items[1].Found)
+	assert.Equal(t, "v2", string(items[2].Value))
+}
+
+func TestServer_Unix(t *testing.T) {
+	ctx := context.Background()
+	srv := NewUnixServer(t)
+	assert.Equal(t, "unix", srv.Network)
+
+	client := newClient(t, srv)
+
+	require.NoError(t, client.Set(ctx, memcache.Item{Key: "key", Value: []byte("v")}))
+	item, err := client.Get(ctx, "key")
+	require.NoError(t, err)
+	assert.Equal(t, "v", string(item.Value))
+
+	srv.Flush()
+	item, err = client.Get(ctx, "key")
+	require.NoError(t, err)
+	assert.False(t, item.Found)
+}
+
+func TestServer_Metadump(t *testing.T) {
+	srv := NewServer(t)
+
+	lines := roundTrip(t, srv, "ms b 1 T0\r\nx\r\nms a/1 2\r\nxy\r\nmg b v\r\nlru_crawler metadump all\r\n", 7)
+
+	la := strconv.FormatInt(srv.now.Unix(), 10)
+	assert.Equal(t, []string{
+		"HD",
+		"HD",
+		"VA 1", "x",
+		"key=a%2F1 exp=-1 la=" + la + " cas=2 fetch=no cls=1 size=53",
+		"key=b exp=-1 la=" + la + " cas=1 fetch=yes cls=1 size=50",
+		"END",
+	}, lines)
+}
+
+func TestServer_Stats(t *testing.T) {
+	srv := NewServer(t)
+	client := newClient(t, srv)
+	ctx := context.Background()
+
+	require.NoError(t, client.Set(ctx, memcache.Item{Key: "a", Value: []byte("xy")}))
+
+	stats, err := client.Stats(ctx)
+	require.NoError(t, err)
+	require.Len(t, stats, 1)
+	require.NoError(t, stats[0].Error)
+	assert.Equal(t, "1", stats[0].Stats["curr_items"])
+	assert.Equal(t, "51", stats[0].Stats["bytes"])
+	assert.Equal(t, "1.6.0-memcachetest", stats[0].Stats["version"])
+
+	lines := roundTrip(t, srv, "stats slabs\r\n", 1)
+	assert.Equal(t, []string{"ERROR"}, lines)
+}
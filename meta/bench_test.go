@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"bytes"
 	"io"
+	"strconv"
 	"testing"
 )
 
@@ -321,3 +322,17 @@ func BenchmarkRoundTrip_Set(b *testing.B) {
 
 // 	return conn
 // }
+
+func BenchmarkWriteRequests(b *testing.B) {
+	reqs := make([]*Request, 100)
+	for i := range reqs {
+		reqs[i] = NewRequest(CmdGet, "mykey"+strconv.Itoa(i), nil).AddReturnValue()
+	}
+
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := WriteRequests(io.Discard, reqs); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
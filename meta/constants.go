@@ -193,7 +193,7 @@ const (
 	//   - ME: Debug info follows
 	//
 	// Typical pattern:
-	//     NewRequest(CmdDebug, "mykey", nil)
+	//     Debug("mykey")
 	CmdDebug CmdType = "me"
 
 	// CmdNoOp returns a static response, useful for pipelining.
@@ -225,10 +225,80 @@ const (
 	//   - "settings": Server settings
 	//
 	// Typical pattern:
-	//     &Request{Command: CmdStats, Key: "items"} // Key carries the optional argument
+	//     StatsRequest("items") // Key carries the optional arguments
 	CmdStats CmdType = "stats"
+
+	// CmdWatch turns the connection into a stream of server log lines
+	// (standard text protocol).
+	//
+	// Wire format: watch [streams]\r\n
+	//
+	// The server answers "OK\r\n", then writes one line per logged event
+	// until the connection is closed: the connection can't be used for
+	// anything else. See Watch and ReadWatchEvent.
+	//
+	// Common streams: "fetchers", "mutations", "evictions", "deletions",
+	// "connevents". Without streams, the server defaults to "fetchers".
+	//
+	// Typical pattern:
+	//     &Request{Command: CmdWatch, Key: "fetchers mutations"} // Key carries the streams
+	CmdWatch CmdType = "watch"
+
+	// CmdLRUCrawler controls the LRU crawler (standard text protocol).
+	//
+	// Wire format: lru_crawler <subcommand> [args]\r\n
+	//
+	// The metadump subcommand lists the metadata of the items of the given
+	// slab classes ("all" or a comma separated list of class ids), one
+	// "key=<key> exp=<exp> la=<la> cas=<cas> fetch=<yes|no> cls=<id> size=<n>"
+	// line per item, followed by "END\r\n". See Metadump and ReadMetadump.
+	//
+	// Typical pattern:
+	//     &Request{Command: CmdLRUCrawler, Key: "metadump all"} // Key carries the arguments
+	CmdLRUCrawler CmdType = "lru_crawler"
+
+	// CmdFlushAll invalidates all the items (standard text protocol).
+	//
+	// Wire format: flush_all [delay]\r\n
+	//
+	// With a delay in seconds, the items are invalidated after it. The server
+	// answers "OK\r\n" (see ReadOK).
+	//
+	// Typical pattern:
+	//     FlushAll(0)
+	CmdFlushAll CmdType = "flush_all"
+
+	// CmdVerbosity sets the logging level of the server (standard text protocol).
+	//
+	// Wire format: verbosity <level>\r\n
+	//
+	// The server answers "OK\r\n" (see ReadOK).
+	//
+	// Typical pattern:
+	//     Verbosity(1)
+	CmdVerbosity CmdType = "verbosity"
+
+	// CmdVersion returns the server version (standard text protocol).
+	//
+	// Wire format: version\r\n
+	//
+	// The server answers "VERSION <version>\r\n" (see ReadVersion).
+	//
+	// Typical pattern:
+	//     Version()
+	CmdVersion CmdType = "version"
 )
 
+// isTextCommand reports whether cmd is a standard text protocol command. Their
+// Key carries the command arguments instead of a cache key.
+func isTextCommand(cmd CmdType) bool {
+	switch cmd {
+	case CmdStats, CmdWatch, CmdLRUCrawler, CmdFlushAll, CmdVerbosity, CmdVersion:
+		return true
+	}
+	return false
+}
+
 // Response status codes (2 characters)
 const (
 	// StatusHD indicates success with no value data returned (Header/Stored)
@@ -257,6 +327,13 @@ const (
 	StatusME StatusType = "ME"
 )
 
+// IsDefinitelyNotStored reports whether a write answered with the status was
+// not applied: NS (not stored), EX (CAS mismatch) or NF (not found, e.g. md or
+// ma on a missing key). Retrying it as is gets the same answer.
+func (s StatusType) IsDefinitelyNotStored() bool {
+	return s == StatusNS || s == StatusEX || s == StatusNF
+}
+
 // Non-meta error responses (legacy protocol compatibility)
 const (
 	// ErrorGeneric is returned for unknown command or generic errors
@@ -281,6 +358,16 @@ const (
 	EndMarker = "END"
 )
 
+// Maintenance command responses (standard text protocol)
+const (
+	// OKResponse acknowledges flush_all, verbosity and watch
+	OKResponse = "OK"
+
+	// VersionPrefix precedes the server version in a version response
+	// Format: VERSION <version>\r\n
+	VersionPrefix = "VERSION"
+)
+
 // Request flags (single character, optionally followed by token)
 
 // Universal flags (all commands)
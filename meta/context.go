@@ -0,0 +1,95 @@
+package meta
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"os"
+	"time"
+)
+
+// ReadResponseContext is ReadResponse bounded by ctx: the read deadline of
+// conn is set to the context deadline, and a cancellation interrupts the read.
+//
+// When the read fails because ctx is done, the context error is returned
+// (context.Canceled or context.DeadlineExceeded) instead of the I/O or parse
+// error it caused. The response may then be partially read: the connection
+// must be closed, as ShouldCloseConnection reports.
+//
+// The read deadline of conn is left set on return.
+func ReadResponseContext(ctx context.Context, conn net.Conn, r *bufio.Reader, resp *Response) error {
+	*resp = Response{}
+	return readContext(ctx, conn, 0, func() error {
+		return readResponse(r, resp, nil, defaultReadOptions)
+	})
+}
+
+// ReadResponsesContext reads the responses of a pipeline terminated by an mn
+// request, like Connection.ExecuteBatch, bounded by ctx as ReadResponseContext.
+//
+// With a timeout, the read deadline is extended to now+timeout before each
+// response, capped at the context deadline: a large batch isn't bounded by its
+// cumulative time, and a hung server is still detected. The mn response is not
+// returned.
+//
+// On error, the responses read so far are returned with it.
+func ReadResponsesContext(ctx context.Context, conn net.Conn, r *bufio.Reader, timeout time.Duration) ([]*Response, error) {
+	var responses []*Response
+	for {
+		resp := &Response{}
+		err := readContext(ctx, conn, timeout, func() error {
+			return readResponse(r, resp, nil, defaultReadOptions)
+		})
+		if err != nil {
+			return responses, err
+		}
+		if resp.Status == StatusMN {
+			return responses, nil
+		}
+		responses = append(responses, resp)
+	}
+}
+
+// longAgo is a deadline in the past, interrupting a pending read.
+var longAgo = time.Unix(1, 0)
+
+// readContext runs read with the read deadline of conn set to the earlier of
+// now+timeout (when timeout is not zero) and the context deadline, and
+// interrupts it when ctx is canceled.
+func readContext(ctx context.Context, conn net.Conn, timeout time.Duration, read func() error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	var deadline time.Time
+	if timeout > 0 {
+		deadline = time.Now().Add(timeout)
+	}
+	ctxDeadline, hasCtxDeadline := ctx.Deadline()
+	if hasCtxDeadline && (deadline.IsZero() || ctxDeadline.Before(deadline)) {
+		deadline = ctxDeadline
+	}
+	if err := conn.SetReadDeadline(deadline); err != nil {
+		return err
+	}
+
+	stop := context.AfterFunc(ctx, func() {
+		_ = conn.SetReadDeadline(longAgo)
+	})
+	err := read()
+	stop()
+
+	if err == nil {
+		return nil
+	}
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+	// The context deadline may expire on the connection slightly before the
+	// context itself reports it.
+	if hasCtxDeadline && errors.Is(err, os.ErrDeadlineExceeded) && !time.Now().Before(ctxDeadline) {
+		return context.DeadlineExceeded
+	}
+	return err
+}
@@ -0,0 +1,109 @@
+package meta
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"testing"
+	"time"
+)
+
+// pipeServer returns the client end of a pipe whose server end writes replies.
+func pipeServer(t *testing.T, replies ...string) net.Conn {
+	t.Helper()
+	client, server := net.Pipe()
+	t.Cleanup(func() {
+		client.Close()
+		server.Close()
+	})
+	go func() {
+		for _, reply := range replies {
+			if _, err := io.WriteString(server, reply); err != nil {
+				return
+			}
+		}
+	}()
+	return client
+}
+
+func TestReadResponseContext(t *testing.T) {
+	t.Run("reads a response", func(t *testing.T) {
+		conn := pipeServer(t, "VA 2\r\nhi\r\n")
+		var resp Response
+		if err := ReadResponseContext(context.Background(), conn, bufio.NewReader(conn), &resp); err != nil {
+			t.Fatalf("ReadResponseContext failed: %v", err)
+		}
+		if string(resp.Data) != "hi" {
+			t.Errorf("Data = %q", resp.Data)
+		}
+	})
+
+	t.Run("deadline", func(t *testing.T) {
+		conn := pipeServer(t) // never answers
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+
+		var resp Response
+		err := ReadResponseContext(ctx, conn, bufio.NewReader(conn), &resp)
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("error = %v, want context.DeadlineExceeded", err)
+		}
+		if !ShouldCloseConnection(err) {
+			t.Error("an interrupted read must close the connection")
+		}
+	})
+
+	t.Run("cancellation interrupts the read", func(t *testing.T) {
+		conn := pipeServer(t, "VA 10\r\nhal") // stalls in the data block
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(20*time.Millisecond, cancel)
+
+		var resp Response
+		err := ReadResponseContext(ctx, conn, bufio.NewReader(conn), &resp)
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("error = %v, want context.Canceled", err)
+		}
+	})
+
+	t.Run("already done", func(t *testing.T) {
+		conn := pipeServer(t, "HD\r\n")
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		var resp Response
+		err := ReadResponseContext(ctx, conn, bufio.NewReader(conn), &resp)
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("error = %v, want context.Canceled", err)
+		}
+	})
+}
+
+func TestReadResponsesContext(t *testing.T) {
+	t.Run("reads until mn", func(t *testing.T) {
+		conn := pipeServer(t, "HD\r\n", "EN\r\n", "MN\r\n")
+		responses, err := ReadResponsesContext(context.Background(), conn, bufio.NewReader(conn), time.Second)
+		if err != nil {
+			t.Fatalf("ReadResponsesContext failed: %v", err)
+		}
+		if len(responses) != 2 || responses[0].Status != StatusHD || responses[1].Status != StatusEN {
+			t.Errorf("responses = %v", responses)
+		}
+	})
+
+	t.Run("per response timeout", func(t *testing.T) {
+		conn := pipeServer(t, "HD\r\n") // hangs after the first response
+		responses, err := ReadResponsesContext(context.Background(), conn, bufio.NewReader(conn), 20*time.Millisecond)
+		if !errors.Is(err, os.ErrDeadlineExceeded) {
+			t.Errorf("error = %v, want a read timeout", err)
+		}
+		if errors.Is(err, context.DeadlineExceeded) {
+			t.Error("a timeout must not be reported as a context error")
+		}
+		if len(responses) != 1 {
+			t.Errorf("responses read before the error = %v", responses)
+		}
+	})
+}
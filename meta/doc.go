@@ -29,6 +29,12 @@ package meta
 //		AddReturnCAS().
 //		AddReturnTTL()
 //
+// Each command has a constructor, shorthand for NewRequest: Get, Set, Delete,
+// Arithmetic, Debug and NoOp for the meta commands, StatsRequest, FlushAll,
+// Verbosity, Version and Metadump for the text commands:
+//
+//	req := meta.Get("mykey").AddReturnValue().AddReturnCAS()
+//
 // # Parsing
 //
 // ReadResponse parses responses from wire format:
@@ -54,9 +60,12 @@ package meta
 //
 // The package defines error types that indicate connection state.
 // Use ShouldCloseConnection to determine whether the connection can be reused.
+// Use IsRetryable and IsDefinitelyNotStored to determine whether a failed
+// operation can be retried.
 //
 // # Performance
 //
 // Flags are stored in serialized form to minimize allocations and make request
 // writing fast (single append/write). ReadResponse parses flags into the same
-// serialized representation.
+// serialized representation. ReadResponseInto reuses the flags and a caller
+// buffer for the value, so that reading a response makes no allocation.
@@ -1,6 +1,7 @@
 package meta
 
 import (
+	"context"
 	"errors"
 	"fmt"
 )
@@ -104,6 +105,34 @@ func (e *InvalidKeyError) ShouldCloseConnection() bool {
 	return false
 }
 
+// InvalidRequestError represents a request rejected by Validate before being
+// sent to the server, which would answer it with a CLIENT_ERROR.
+//
+// Common causes:
+//   - Flag not supported by the command (e.g. D on mg)
+//   - Missing or malformed flag token (e.g. T without a number)
+//   - Duplicate flag (e.g. two mode flags)
+//
+// Connection handling: Connection is still valid, operation was rejected client-side
+type InvalidRequestError struct {
+	Command CmdType
+	Flag    FlagType // 0 when the error is not about a flag
+	Message string
+}
+
+func (e *InvalidRequestError) Error() string {
+	if e.Flag != 0 {
+		return fmt.Sprintf("invalid %s request: flag %c: %s", e.Command, e.Flag, e.Message)
+	}
+	return fmt.Sprintf("invalid %s request: %s", e.Command, e.Message)
+}
+
+// ShouldCloseConnection returns false - the request was rejected client-side,
+// before any byte was written: the connection is untouched and reusable.
+func (e *InvalidRequestError) ShouldCloseConnection() bool {
+	return false
+}
+
 // ParseError represents a client-side parsing error.
 // Indicates the client failed to parse the server response, which suggests
 // either a protocol violation by the server or a bug in the client parser.
@@ -186,6 +215,7 @@ type ErrorWithConnectionState interface {
 // Returns false for:
 //   - ServerError
 //   - InvalidKeyError
+//   - InvalidRequestError
 //   - nil
 //
 // Usage:
@@ -210,3 +240,51 @@ func ShouldCloseConnection(err error) bool {
 	// Unknown error type - be conservative and close connection
 	return true
 }
+
+// IsRetryable reports whether an operation that failed with err can succeed
+// when retried, on a new connection when ShouldCloseConnection(err): the
+// transient failures (I/O errors, SERVER_ERROR, unparsable responses) are,
+// the rejections of the request itself (CLIENT_ERROR, ERROR, invalid key or
+// request) and the cancellations of the caller are not.
+//
+// A retryable failure may have been applied by the server, e.g. a response
+// lost with its connection: retry the idempotent commands (mg, md, a plain
+// ms) on IsRetryable, and the others (ma, ms in append or prepend mode, ...)
+// only when IsDefinitelyNotStored holds too.
+func IsRetryable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var (
+		clientErr     *ClientError
+		genericErr    *GenericError
+		invalidKeyErr *InvalidKeyError
+		invalidReqErr *InvalidRequestError
+	)
+	switch {
+	case errors.As(err, &clientErr), errors.As(err, &genericErr),
+		errors.As(err, &invalidKeyErr), errors.As(err, &invalidReqErr):
+		return false
+	}
+	return true
+}
+
+// IsDefinitelyNotStored reports whether err guarantees that the server did
+// not apply the request: it was rejected before being sent (invalid key or
+// request) or by the server (CLIENT_ERROR, SERVER_ERROR, ERROR). The outcome
+// of a request failing with an I/O error, an unparsable response or a
+// cancellation is unknown: it may have been applied.
+//
+// See StatusType.IsDefinitelyNotStored for the responses.
+func IsDefinitelyNotStored(err error) bool {
+	var (
+		clientErr     *ClientError
+		serverErr     *ServerError
+		genericErr    *GenericError
+		invalidKeyErr *InvalidKeyError
+		invalidReqErr *InvalidRequestError
+	)
+	return errors.As(err, &clientErr) || errors.As(err, &serverErr) || errors.As(err, &genericErr) ||
+		errors.As(err, &invalidKeyErr) || errors.As(err, &invalidReqErr)
+}
@@ -1,6 +1,7 @@
 package meta
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -107,3 +108,49 @@ func TestShouldCloseConnection_SpecialCases(t *testing.T) {
 		}
 	})
 }
+
+func TestRetryClassification(t *testing.T) {
+	tests := []struct {
+		name          string
+		err           error
+		wantRetry     bool
+		wantNotStored bool
+	}{
+		{name: "nil", err: nil},
+		{name: "ClientError", err: &ClientError{Message: "bad data chunk"}, wantNotStored: true},
+		{name: "GenericError", err: &GenericError{Message: "ERROR"}, wantNotStored: true},
+		{name: "InvalidKeyError", err: &InvalidKeyError{Message: "key is empty"}, wantNotStored: true},
+		{name: "InvalidRequestError", err: &InvalidRequestError{Command: CmdGet, Message: "bad"}, wantNotStored: true},
+		{name: "ServerError", err: &ServerError{Message: "out of memory"}, wantRetry: true, wantNotStored: true},
+		{name: "wrapped ServerError", err: fmt.Errorf("op: %w", &ServerError{Message: "busy"}), wantRetry: true, wantNotStored: true},
+		{name: "ParseError", err: &ParseError{Message: "bad line"}, wantRetry: true},
+		{name: "ConnectionError", err: &ConnectionError{Op: "read", Err: io.EOF}, wantRetry: true},
+		{name: "I/O error", err: io.ErrUnexpectedEOF, wantRetry: true},
+		{name: "canceled", err: fmt.Errorf("op: %w", context.Canceled)},
+		{name: "deadline", err: context.DeadlineExceeded},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsRetryable(tt.err); got != tt.wantRetry {
+				t.Errorf("IsRetryable() = %v, want %v", got, tt.wantRetry)
+			}
+			if got := IsDefinitelyNotStored(tt.err); got != tt.wantNotStored {
+				t.Errorf("IsDefinitelyNotStored() = %v, want %v", got, tt.wantNotStored)
+			}
+		})
+	}
+}
+
+func TestStatusType_IsDefinitelyNotStored(t *testing.T) {
+	for _, s := range []StatusType{StatusNS, StatusEX, StatusNF} {
+		if !s.IsDefinitelyNotStored() {
+			t.Errorf("%s: IsDefinitelyNotStored() = false, want true", s)
+		}
+	}
+	for _, s := range []StatusType{StatusHD, StatusVA, StatusEN, StatusMN} {
+		if s.IsDefinitelyNotStored() {
+			t.Errorf("%s: IsDefinitelyNotStored() = true, want false", s)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package meta
+
+import (
+	"bytes"
+	"slices"
+)
+
+// The Set* methods of Flags replace the flags of their type instead of
+// appending another one, keeping at most one flag of each type: the Add*
+// methods are cheaper when the flags are built once, the Set* methods suit
+// the flags built in several steps, e.g. defaults then overrides.
+
+// Set sets the flag without token of the given type.
+func (f *Flags) Set(flagType FlagType) {
+	f.Remove(flagType)
+	f.Add(flagType)
+}
+
+// SetToken sets the flag of the given type with its token.
+func (f *Flags) SetToken(flagType FlagType, token string) {
+	f.Remove(flagType)
+	f.AddTokenString(flagType, token)
+}
+
+// SetTTL sets the 'T' flag to seconds.
+func (f *Flags) SetTTL(seconds int) {
+	f.Remove(FlagTTL)
+	f.AddInt(FlagTTL, seconds)
+}
+
+// SetCAS sets the 'C' flag to value.
+func (f *Flags) SetCAS(value uint64) {
+	f.Remove(FlagCAS)
+	f.AddUint64(FlagCAS, value)
+}
+
+// SetOpaque sets the 'O' flag to token.
+func (f *Flags) SetOpaque(token string) {
+	f.SetToken(FlagOpaque, token)
+}
+
+// Remove removes the flags of the given type.
+func (f *Flags) Remove(flagType FlagType) {
+	b := *f
+	out := b[:0] // never overtakes the reads: each flag is preceded by a space
+	for i := 0; i < len(b); {
+		i = flagsSkipSpaces(b, i)
+		if i >= len(b) {
+			break
+		}
+		start := i
+		for i < len(b) && b[i] != ' ' {
+			i++
+		}
+		if FlagType(b[start]) != flagType {
+			out = append(out, ' ')
+			out = append(out, b[start:i]...)
+		}
+	}
+	*f = out
+}
+
+// Sort orders the flags by type, keeping the order of the flags of the same
+// type, and normalizes their separators to single spaces. The flags set in
+// any order then serialize to the same bytes, for hashing or comparing
+// requests. The server ignores the order of the flags.
+func (f *Flags) Sort() {
+	var fields [][]byte
+	for field := range bytes.FieldsSeq(*f) {
+		fields = append(fields, field)
+	}
+	slices.SortStableFunc(fields, func(a, b []byte) int { return int(a[0]) - int(b[0]) })
+
+	sorted := make(Flags, 0, len(*f))
+	for _, field := range fields {
+		sorted = append(sorted, ' ')
+		sorted = append(sorted, field...)
+	}
+	*f = sorted
+}
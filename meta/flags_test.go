@@ -0,0 +1,54 @@
+package meta
+
+import "testing"
+
+func TestFlags_Set(t *testing.T) {
+	var f Flags
+	f.Add(FlagReturnValue)
+	f.AddInt(FlagTTL, 60)
+	f.AddTokenString(FlagOpaque, "a")
+	f.AddTokenString(FlagOpaque, "b")
+
+	f.SetTTL(300)
+	f.SetCAS(7)
+	f.SetCAS(8)
+	f.SetOpaque("c")
+	f.Set(FlagReturnValue)
+
+	if got, want := string(f), " T300 C8 Oc v"; got != want {
+		t.Errorf("flags = %q, want %q", got, want)
+	}
+}
+
+func TestFlags_Remove(t *testing.T) {
+	f := Flags("  v T60  v k")
+	f.Remove(FlagReturnValue)
+	if got, want := string(f), " T60 k"; got != want {
+		t.Errorf("flags = %q, want %q", got, want)
+	}
+
+	f.Remove(FlagCAS) // absent
+	if got, want := string(f), " T60 k"; got != want {
+		t.Errorf("flags = %q, want %q", got, want)
+	}
+}
+
+func TestFlags_Sort(t *testing.T) {
+	a := Flags(" v T60 Ox k")
+	b := Flags("k  Ox v T60")
+	a.Sort()
+	b.Sort()
+
+	if got, want := string(a), " Ox T60 k v"; got != want {
+		t.Errorf("flags = %q, want %q", got, want)
+	}
+	if string(a) != string(b) {
+		t.Errorf("flags %q and %q must sort the same", a, b)
+	}
+
+	var empty Flags
+	empty.Sort()
+	if !empty.IsEmpty() {
+		t.Errorf("flags = %q, want empty", empty)
+	}
+}
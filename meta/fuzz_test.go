@@ -3,6 +3,7 @@ package meta
 import (
 	"bufio"
 	"bytes"
+	"strings"
 	"testing"
 )
 
@@ -49,6 +50,13 @@ func FuzzReadResponse(f *testing.F) {
 	f.Add([]byte("VA 5 c1 c2 c3\r\nhello\r\n"))     // Multiple CAS flags
 	f.Add([]byte("HD flag1 flag2 flag3 flag4\r\n")) // Multiple flags
 
+	// Seed corpus with hostile input
+	f.Add([]byte("VA 1073741824\r\nabc"))                      // Maximum size, truncated data
+	f.Add([]byte("VA 99999999999999999999\r\n"))               // Size overflowing int
+	f.Add([]byte("HD O" + strings.Repeat("x", MaxLineSize+1))) // Line without terminator
+	f.Add([]byte("\n\n\n"))                                    // Bare line feeds
+	f.Add([]byte("VA 3\r\nabc\r\r\n"))                         // Extra CR after data
+
 	f.Fuzz(func(t *testing.T, data []byte) {
 		// Create a bufio.Reader from the fuzz input
 		r := bufio.NewReader(bytes.NewReader(data))
@@ -97,3 +105,70 @@ func FuzzReadResponse(f *testing.F) {
 		// If we reach here without panicking, the test passes
 	})
 }
+
+// FuzzFlagsGet checks that flag lookups never panic on arbitrary flag bytes,
+// and that a found token is a slice of the flags.
+func FuzzFlagsGet(f *testing.F) {
+	f.Add([]byte(" v c t"), byte('c'))
+	f.Add([]byte(" T60 Oopaque"), byte('O'))
+	f.Add([]byte("   "), byte('v'))
+	f.Add([]byte("c123"), byte('c'))
+	f.Add([]byte(" c-1 c"), byte('c'))
+	f.Add([]byte(""), byte('v'))
+
+	f.Fuzz(func(t *testing.T, flags []byte, flag byte) {
+		fl := Flags(flags)
+		token, ok := fl.Get(FlagType(flag))
+		if !ok && token != nil {
+			t.Errorf("token %q returned for a missing flag", token)
+		}
+		if len(token) > len(flags) {
+			t.Errorf("token %q longer than the flags", token)
+		}
+		if bytes.IndexByte(token, ' ') >= 0 {
+			t.Errorf("token %q contains a space", token)
+		}
+		if fl.Has(FlagType(flag)) != ok {
+			t.Error("Has and Get disagree")
+		}
+
+		// The numeric accessors parse the same token.
+		fl.GetUint64(FlagType(flag))
+		fl.GetInt64(FlagType(flag))
+		fl.GetDuration(FlagType(flag))
+	})
+}
+
+// FuzzParser checks that Parser never panics, and that it parses a stream
+// like ReadResponse does.
+func FuzzParser(f *testing.F) {
+	f.Add([]byte("HD\r\nVA 5\r\nhello\r\nEN\r\nMN\r\n"))
+	f.Add([]byte("VA 5\r\nhel"))
+	f.Add([]byte("SERVER_ERROR busy\r\nHD c1\r\n"))
+	f.Add([]byte("ME key a=b\r\nVA 0\r\n\r\n"))
+	f.Add([]byte("VA 2\r\nhiXXHD\r\n"))
+	f.Add([]byte("VA 999999999999\r\n"))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var p Parser
+		responses, n, err := p.Feed(data)
+		if n < 0 || n > len(data) {
+			t.Fatalf("n = %d out of range [0, %d]", n, len(data))
+		}
+
+		// ReadResponse reads the same responses from the consumed bytes.
+		r := bufio.NewReader(bytes.NewReader(data[:n]))
+		for i, want := range responses {
+			var resp Response
+			if rerr := ReadResponse(r, &resp); rerr != nil {
+				t.Fatalf("response %d: ReadResponse failed: %v (Feed: %+v)", i, rerr, want)
+			}
+			if resp.Status != want.Status || !bytes.Equal(resp.Data, want.Data) || !bytes.Equal(resp.Flags, want.Flags) {
+				t.Fatalf("response %d: ReadResponse = %+v, Feed = %+v", i, resp, want)
+			}
+		}
+		if err == nil && r.Buffered() != 0 {
+			t.Errorf("%d consumed bytes left unparsed", r.Buffered())
+		}
+	})
+}
@@ -0,0 +1,56 @@
+package meta
+
+import (
+	"bufio"
+	"strconv"
+	"strings"
+)
+
+// FlushAll creates a flush_all request invalidating all the items after
+// delay seconds, or immediately when delay is 0.
+func FlushAll(delay int) *Request {
+	req := &Request{Command: CmdFlushAll}
+	if delay > 0 {
+		req.Key = strconv.Itoa(delay)
+	}
+	return req
+}
+
+// Verbosity creates a verbosity request setting the logging level of the
+// server.
+func Verbosity(level int) *Request {
+	return &Request{Command: CmdVerbosity, Key: strconv.Itoa(level)}
+}
+
+// Version creates a version request.
+func Version() *Request {
+	return &Request{Command: CmdVersion}
+}
+
+// ReadOK reads the "OK" response of flush_all, verbosity or watch.
+// Error responses are returned as ClientError, ServerError or GenericError,
+// and any other line as a ParseError.
+func ReadOK(r *bufio.Reader) error {
+	line, err := readTextLine(r)
+	if err != nil {
+		return err
+	}
+	if line != OKResponse {
+		return textLineError(line, "unexpected response: ")
+	}
+	return nil
+}
+
+// ReadVersion reads the "VERSION <version>" response of version and returns
+// the version, e.g. "1.6.39".
+func ReadVersion(r *bufio.Reader) (string, error) {
+	line, err := readTextLine(r)
+	if err != nil {
+		return "", err
+	}
+	version, ok := strings.CutPrefix(line, VersionPrefix+" ")
+	if !ok {
+		return "", textLineError(line, "unexpected version response: ")
+	}
+	return version, nil
+}
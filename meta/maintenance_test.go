@@ -0,0 +1,64 @@
+package meta
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestMaintenanceRequests(t *testing.T) {
+	tests := []struct {
+		req  *Request
+		want string
+	}{
+		{FlushAll(0), "flush_all\r\n"},
+		{FlushAll(30), "flush_all 30\r\n"},
+		{Verbosity(1), "verbosity 1\r\n"},
+		{Version(), "version\r\n"},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		if err := WriteRequest(&buf, tt.req); err != nil {
+			t.Fatalf("WriteRequest failed: %v", err)
+		}
+		if got := buf.String(); got != tt.want {
+			t.Errorf("wire = %q, want %q", got, tt.want)
+		}
+		if err := Validate(tt.req); err != nil {
+			t.Errorf("Validate(%q) = %v", tt.want, err)
+		}
+	}
+}
+
+func TestReadOK(t *testing.T) {
+	if err := ReadOK(bufio.NewReader(strings.NewReader("OK\r\n"))); err != nil {
+		t.Errorf("ReadOK failed: %v", err)
+	}
+
+	err := ReadOK(bufio.NewReader(strings.NewReader("ERROR\r\n")))
+	var genericErr *GenericError
+	if !errors.As(err, &genericErr) {
+		t.Errorf("error = %v, want GenericError", err)
+	}
+
+	err = ReadOK(bufio.NewReader(strings.NewReader("STORED\r\n")))
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) {
+		t.Errorf("error = %v, want ParseError", err)
+	}
+}
+
+func TestReadVersion(t *testing.T) {
+	version, err := ReadVersion(bufio.NewReader(strings.NewReader("VERSION 1.6.39\r\n")))
+	if err != nil || version != "1.6.39" {
+		t.Errorf("ReadVersion = %q/%v, want 1.6.39", version, err)
+	}
+
+	_, err = ReadVersion(bufio.NewReader(strings.NewReader("SERVER_ERROR out of memory\r\n")))
+	var serverErr *ServerError
+	if !errors.As(err, &serverErr) {
+		t.Errorf("error = %v, want ServerError", err)
+	}
+}
@@ -0,0 +1,88 @@
+package meta
+
+import "bufio"
+
+// ReadAndMatch reads the responses of a pipeline terminated by an mn request,
+// and matches them to reqs by opaque token (O flag).
+//
+// Each request of reqs must carry a unique O flag; mn requests are skipped.
+// This is checked before reading: on an InvalidRequestError, the responses
+// are left unread and the connection must be closed.
+//
+// matched maps the opaque token of each request to its response, and missing
+// lists the requests without a response, in order: the nominal responses
+// suppressed by the q flag (e.g. EN for a quiet get).
+//
+//	reqs := []*meta.Request{
+//		meta.Get("a").AddReturnValue().AddOpaque("1").AddQuiet(),
+//		meta.Get("b").AddReturnValue().AddOpaque("2").AddQuiet(),
+//	}
+//	// write reqs followed by meta.NoOp()
+//	matched, missing, err := meta.ReadAndMatch(r, reqs)
+//
+// A response carrying an unknown opaque token, or a nominal response without
+// one, returns a ParseError. A protocol error response (CLIENT_ERROR,
+// SERVER_ERROR, ERROR) can't be matched since it carries no flags: it is
+// returned as err, right away when it must close the connection and after the
+// mn response otherwise.
+func ReadAndMatch(r *bufio.Reader, reqs []*Request) (matched map[string]*Response, missing []*Request, err error) {
+	byOpaque := make(map[string]*Request, len(reqs))
+	for _, req := range reqs {
+		if req.Command == CmdNoOp {
+			continue
+		}
+		opaque, ok := req.Flags.Get(FlagOpaque)
+		if !ok {
+			return nil, nil, &InvalidRequestError{Command: req.Command, Flag: FlagOpaque, Message: "required to match the response"}
+		}
+		if _, dup := byOpaque[string(opaque)]; dup {
+			return nil, nil, &InvalidRequestError{Command: req.Command, Flag: FlagOpaque, Message: "duplicate token " + string(opaque)}
+		}
+		byOpaque[string(opaque)] = req
+	}
+
+	matched = make(map[string]*Response, len(byOpaque))
+	var protocolErr error
+	for {
+		resp := &Response{}
+		if err := ReadResponse(r, resp); err != nil {
+			return matched, nil, err
+		}
+		if resp.Status == StatusMN {
+			break
+		}
+
+		if resp.Error != nil {
+			if ShouldCloseConnection(resp.Error) {
+				return matched, nil, resp.Error
+			}
+			if protocolErr == nil {
+				protocolErr = resp.Error
+			}
+			continue
+		}
+
+		opaque, ok := resp.Opaque()
+		if !ok {
+			return matched, nil, &ParseError{Message: "response without opaque token: " + string(resp.Status)}
+		}
+		if _, ok := byOpaque[string(opaque)]; !ok {
+			return matched, nil, &ParseError{Message: "response with unknown opaque token: " + string(opaque)}
+		}
+		if _, dup := matched[string(opaque)]; dup {
+			return matched, nil, &ParseError{Message: "duplicate response for opaque token: " + string(opaque)}
+		}
+		matched[string(opaque)] = resp
+	}
+
+	for _, req := range reqs {
+		if req.Command == CmdNoOp {
+			continue
+		}
+		opaque, _ := req.Flags.Get(FlagOpaque)
+		if _, ok := matched[string(opaque)]; !ok {
+			missing = append(missing, req)
+		}
+	}
+	return matched, missing, protocolErr
+}
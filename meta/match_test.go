@@ -0,0 +1,74 @@
+package meta
+
+import (
+	"bufio"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func readAndMatch(input string, reqs ...*Request) (map[string]*Response, []*Request, error) {
+	return ReadAndMatch(bufio.NewReader(strings.NewReader(input)), reqs)
+}
+
+func TestReadAndMatch(t *testing.T) {
+	a := Get("a").AddReturnValue().AddOpaque("1").AddQuiet()
+	b := Get("b").AddReturnValue().AddOpaque("2").AddQuiet()
+	c := Get("c").AddReturnValue().AddOpaque("3").AddQuiet()
+
+	t.Run("out of order with suppressed responses", func(t *testing.T) {
+		matched, missing, err := readAndMatch("VA 1 O3\r\nc\r\nVA 1 O1\r\na\r\nMN\r\n", a, b, c, NoOp())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(matched) != 2 || string(matched["1"].Data) != "a" || string(matched["3"].Data) != "c" {
+			t.Errorf("matched = %v", matched)
+		}
+		if len(missing) != 1 || missing[0] != b {
+			t.Errorf("missing = %v, want [b]", missing)
+		}
+	})
+
+	t.Run("request without opaque", func(t *testing.T) {
+		_, _, err := readAndMatch("MN\r\n", a, Get("x"))
+		var reqErr *InvalidRequestError
+		if !errors.As(err, &reqErr) || reqErr.Flag != FlagOpaque {
+			t.Errorf("error = %v, want InvalidRequestError for flag O", err)
+		}
+	})
+
+	t.Run("duplicate opaque", func(t *testing.T) {
+		_, _, err := readAndMatch("MN\r\n", a, Get("x").AddOpaque("1"))
+		var reqErr *InvalidRequestError
+		if !errors.As(err, &reqErr) {
+			t.Errorf("error = %v, want InvalidRequestError", err)
+		}
+	})
+
+	t.Run("unknown opaque", func(t *testing.T) {
+		_, _, err := readAndMatch("HD O9\r\nMN\r\n", a)
+		var parseErr *ParseError
+		if !errors.As(err, &parseErr) {
+			t.Errorf("error = %v, want ParseError", err)
+		}
+	})
+
+	t.Run("server error is returned after mn", func(t *testing.T) {
+		matched, missing, err := readAndMatch("SERVER_ERROR out of memory\r\nHD O2\r\nMN\r\n", a, b)
+		var serverErr *ServerError
+		if !errors.As(err, &serverErr) {
+			t.Fatalf("error = %v, want ServerError", err)
+		}
+		if len(matched) != 1 || len(missing) != 1 {
+			t.Errorf("matched = %v, missing = %v", matched, missing)
+		}
+	})
+
+	t.Run("client error is returned right away", func(t *testing.T) {
+		_, _, err := readAndMatch("CLIENT_ERROR bad data chunk\r\n", a)
+		var clientErr *ClientError
+		if !errors.As(err, &clientErr) {
+			t.Errorf("error = %v, want ClientError", err)
+		}
+	})
+}
@@ -4,6 +4,8 @@ import (
 	"bufio"
 	"bytes"
 	"errors"
+	"io"
+	"runtime"
 	"strings"
 	"testing"
 )
@@ -917,3 +919,280 @@ func TestReadResponse_VASizeTooLarge(t *testing.T) {
 		t.Fatalf("ReadResponse error = %v, want ParseError", err)
 	}
 }
+
+func TestReadResponseInto(t *testing.T) {
+	input := "VA 5 c123 t60\r\nhello\r\nHD f1\r\nVA 3\r\nabc\r\nEN\r\n"
+	r := bufio.NewReader(strings.NewReader(input))
+	buf := make([]byte, 64)
+	var resp Response
+
+	if err := ReadResponseInto(r, &resp, buf); err != nil {
+		t.Fatalf("ReadResponseInto failed: %v", err)
+	}
+	if resp.Status != StatusVA || string(resp.Data) != "hello" || string(resp.Flags) != " c123 t60" {
+		t.Errorf("response = %+v", resp)
+	}
+	if &resp.Data[0] != &buf[0] {
+		t.Error("Data should be read into buf")
+	}
+
+	if err := ReadResponseInto(r, &resp, buf); err != nil {
+		t.Fatalf("ReadResponseInto failed: %v", err)
+	}
+	if resp.Status != StatusHD || resp.Data != nil || string(resp.Flags) != " f1" {
+		t.Errorf("response = %+v", resp)
+	}
+
+	// A value larger than buf is allocated.
+	if err := ReadResponseInto(r, &resp, buf[:0:4]); err != nil {
+		t.Fatalf("ReadResponseInto failed: %v", err)
+	}
+	if string(resp.Data) != "abc" || &resp.Data[0] == &buf[0] {
+		t.Errorf("Data = %q, want a new slice", resp.Data)
+	}
+
+	if err := ReadResponseInto(r, &resp, buf); err != nil {
+		t.Fatalf("ReadResponseInto failed: %v", err)
+	}
+	if resp.Status != StatusEN || !resp.Flags.IsEmpty() {
+		t.Errorf("response = %+v", resp)
+	}
+}
+
+func TestReadResponseInto_NoAllocs(t *testing.T) {
+	r := bufio.NewReader(&loopReader{data: append(makeVA(100, "c12345 t3600 f30"), "HD c1\r\nEN\r\n"...)})
+	buf := make([]byte, 256)
+	var resp Response
+
+	allocs := testing.AllocsPerRun(100, func() {
+		for range 3 {
+			if err := ReadResponseInto(r, &resp, buf); err != nil {
+				t.Fatal(err)
+			}
+		}
+	})
+	if allocs != 0 {
+		t.Errorf("ReadResponseInto allocated %v times per run, want 0", allocs)
+	}
+}
+
+func TestReadResponse_LongLine(t *testing.T) {
+	// Lines longer than the bufio.Reader buffer are still parsed.
+	flags := " O" + strings.Repeat("x", 64)
+	r := bufio.NewReaderSize(strings.NewReader("HD"+flags+"\r\n"), 16)
+	var resp Response
+	if err := ReadResponse(r, &resp); err != nil {
+		t.Fatalf("ReadResponse failed: %v", err)
+	}
+	if string(resp.Flags) != flags {
+		t.Errorf("Flags = %q, want %q", resp.Flags, flags)
+	}
+}
+
+func TestReadResponse_LineTooLong(t *testing.T) {
+	r := bufio.NewReaderSize(strings.NewReader("HD O"+strings.Repeat("x", 2*MaxLineSize)), 16)
+	var resp Response
+	err := ReadResponse(r, &resp)
+
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) {
+		t.Fatalf("ReadResponse error = %v, want ParseError", err)
+	}
+}
+
+func TestReadResponseLimit(t *testing.T) {
+	r := bufio.NewReader(strings.NewReader("VA 3\r\nabc\r\nVA 5\r\nhello\r\n"))
+	var resp Response
+
+	if err := ReadResponseLimit(r, &resp, 4); err != nil || string(resp.Data) != "abc" {
+		t.Fatalf("ReadResponseLimit = %v, Data %q", err, resp.Data)
+	}
+
+	err := ReadResponseLimit(r, &resp, 4)
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) {
+		t.Fatalf("ReadResponseLimit error = %v, want ParseError", err)
+	}
+}
+
+func TestReadResponseWithOptions(t *testing.T) {
+	opts := ReadOptions{MaxLineSize: 16, MaxDataSize: 4}
+	var parseErr *ParseError
+
+	r := bufio.NewReader(strings.NewReader("VA 3 t60\r\nabc\r\n"))
+	var resp Response
+	if err := ReadResponseWithOptions(r, &resp, opts); err != nil || string(resp.Data) != "abc" {
+		t.Fatalf("ReadResponseWithOptions = %v, Data %q", err, resp.Data)
+	}
+
+	// A forged size is rejected before reading the value.
+	r = bufio.NewReader(strings.NewReader("VA 999999999\r\n"))
+	if err := ReadResponseWithOptions(r, &resp, opts); !errors.As(err, &parseErr) {
+		t.Fatalf("ReadResponseWithOptions error = %v, want ParseError", err)
+	}
+
+	// A line within the buffer of the reader, but over the limit.
+	r = bufio.NewReader(strings.NewReader("HD O" + strings.Repeat("x", 16) + "\r\n"))
+	if err := ReadResponseWithOptions(r, &resp, opts); !errors.As(err, &parseErr) {
+		t.Fatalf("ReadResponseWithOptions error = %v, want ParseError", err)
+	}
+
+	// The zero options are the defaults.
+	r = bufio.NewReader(strings.NewReader("HD O" + strings.Repeat("x", 1000) + "\r\n"))
+	if err := ReadResponseWithOptions(r, &resp, ReadOptions{}); err != nil {
+		t.Fatalf("ReadResponseWithOptions failed: %v", err)
+	}
+}
+
+func TestReadResponse_LineEndings(t *testing.T) {
+	inputs := []string{"HD t60\n", "VA 3\r\nabc\n", "VA 3\nabc\n", "EN\n"}
+
+	t.Run("strict", func(t *testing.T) {
+		for _, input := range inputs {
+			var resp Response
+			err := ReadResponse(bufio.NewReader(strings.NewReader(input)), &resp)
+			var parseErr *ParseError
+			if !errors.As(err, &parseErr) {
+				t.Errorf("ReadResponse(%q) error = %v, want ParseError", input, err)
+			}
+		}
+	})
+
+	t.Run("lenient", func(t *testing.T) {
+		r := bufio.NewReader(strings.NewReader(strings.Join(inputs, "") + "VA 2\r\nhi\r\n"))
+		opts := ReadOptions{LenientLineEndings: true}
+		want := []StatusType{StatusHD, StatusVA, StatusVA, StatusEN, StatusVA}
+		for i, status := range want {
+			var resp Response
+			if err := ReadResponseWithOptions(r, &resp, opts); err != nil {
+				t.Fatalf("response %d: ReadResponseWithOptions failed: %v", i, err)
+			}
+			if resp.Status != status {
+				t.Errorf("response %d: Status = %s, want %s", i, resp.Status, status)
+			}
+			if status == StatusVA && len(resp.Data) == 0 {
+				t.Errorf("response %d: Data is empty", i)
+			}
+		}
+
+		var resp Response
+		err := ReadResponseWithOptions(bufio.NewReader(strings.NewReader("VA 3\r\nabcX")), &resp, opts)
+		var parseErr *ParseError
+		if !errors.As(err, &parseErr) {
+			t.Errorf("bad terminator: error = %v, want ParseError", err)
+		}
+	})
+}
+
+// A corrupted size within MaxDataSize only allocates for the bytes received.
+func TestReadResponse_TruncatedLargeValue(t *testing.T) {
+	input := "VA 1000000000\r\n" + strings.Repeat("x", 100000)
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	var resp Response
+	err := ReadResponse(bufio.NewReader(strings.NewReader(input)), &resp)
+	runtime.ReadMemStats(&after)
+
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("ReadResponse error = %v, want io.ErrUnexpectedEOF", err)
+	}
+	if allocated := after.TotalAlloc - before.TotalAlloc; allocated > 1<<20 {
+		t.Errorf("allocated %d bytes for 100 kB of data", allocated)
+	}
+}
+
+func TestRequest_Reset(t *testing.T) {
+	req := NewRequest(CmdSet, "key", []byte("value")).AddTTL(60)
+	flags := req.Flags
+
+	req.Reset()
+
+	if req.Command != "" || req.Key != "" || req.Data != nil || len(req.Flags) != 0 {
+		t.Errorf("request not reset: %+v", req)
+	}
+	if cap(req.Flags) != cap(flags) {
+		t.Error("Reset should keep the Flags capacity")
+	}
+}
+
+func TestAcquireReleaseRequest(t *testing.T) {
+	req := AcquireRequest()
+	req.Command = CmdGet
+	req.Key = "key"
+	req.AddReturnValue()
+
+	var buf bytes.Buffer
+	if err := WriteRequest(&buf, req); err != nil {
+		t.Fatalf("WriteRequest failed: %v", err)
+	}
+	if got := buf.String(); got != "mg key v\r\n" {
+		t.Errorf("request = %q", got)
+	}
+	ReleaseRequest(req)
+
+	req = AcquireRequest()
+	if req.Command != "" || req.Key != "" || len(req.Flags) != 0 {
+		t.Errorf("acquired request is not empty: %+v", req)
+	}
+	ReleaseRequest(req)
+}
+
+func TestAcquireReleaseResponse(t *testing.T) {
+	resp := AcquireResponse()
+	r := bufio.NewReader(strings.NewReader("VA 2 c5\r\nhi\r\n"))
+	if err := ReadResponseInto(r, resp, nil); err != nil {
+		t.Fatalf("ReadResponseInto failed: %v", err)
+	}
+	if string(resp.Data) != "hi" {
+		t.Errorf("Data = %q", resp.Data)
+	}
+	ReleaseResponse(resp)
+
+	resp = AcquireResponse()
+	if resp.Status != "" || resp.Data != nil || len(resp.Flags) != 0 || resp.Error != nil {
+		t.Errorf("acquired response is not empty: %+v", resp)
+	}
+	ReleaseResponse(resp)
+}
+
+func TestReadResponseHeader(t *testing.T) {
+	r := bufio.NewReader(strings.NewReader("VA 11 c5\r\nhello world\r\nHD\r\n"))
+	var resp Response
+
+	size, err := ReadResponseHeader(r, &resp)
+	if err != nil {
+		t.Fatalf("ReadResponseHeader failed: %v", err)
+	}
+	if resp.Status != StatusVA || size != 11 || resp.Data != nil || string(resp.Flags) != " c5" {
+		t.Errorf("response = %+v, size = %d", resp, size)
+	}
+
+	var value bytes.Buffer
+	if err := ReadData(r, &value, size); err != nil {
+		t.Fatalf("ReadData failed: %v", err)
+	}
+	if value.String() != "hello world" {
+		t.Errorf("value = %q", value.String())
+	}
+
+	// The stream is in sync for the next response.
+	size, err = ReadResponseHeader(r, &resp)
+	if err != nil || resp.Status != StatusHD || size != 0 {
+		t.Errorf("next response = %+v, size = %d, err = %v", resp, size, err)
+	}
+}
+
+func TestReadData_Errors(t *testing.T) {
+	var parseErr *ParseError
+
+	r := bufio.NewReader(strings.NewReader("abc"))
+	if err := ReadData(r, io.Discard, 5); !errors.As(err, &parseErr) {
+		t.Errorf("short data: error = %v, want ParseError", err)
+	}
+
+	r = bufio.NewReader(strings.NewReader("abcXX"))
+	if err := ReadData(r, io.Discard, 3); !errors.As(err, &parseErr) {
+		t.Errorf("bad terminator: error = %v, want ParseError", err)
+	}
+}
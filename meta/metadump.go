@@ -0,0 +1,110 @@
+package meta
+
+import (
+	"bufio"
+	"iter"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// KeyMetadata is an item listed by lru_crawler metadump.
+type KeyMetadata struct {
+	// Key is the item key, decoded from the URI encoding used by the server.
+	Key string
+
+	// Expiration is the unix time the item expires at, or -1 for no expiration.
+	Expiration int64
+
+	// LastAccess is the time of the last access to the item.
+	LastAccess time.Time
+
+	CAS     uint64
+	Fetched bool // whether the item was fetched since it was stored
+	Class   int  // slab class id
+	Size    int  // total item size in bytes, including its metadata
+}
+
+// Metadump creates an "lru_crawler metadump" request for the given slab
+// classes, or for all of them when no class is given.
+func Metadump(classes ...int) *Request {
+	args := []byte("metadump ")
+	if len(classes) == 0 {
+		args = append(args, "all"...)
+	}
+	for i, class := range classes {
+		if i > 0 {
+			args = append(args, ',')
+		}
+		args = strconv.AppendInt(args, int64(class), 10)
+	}
+	return &Request{Command: CmdLRUCrawler, Key: string(args)}
+}
+
+// ReadMetadump returns an iterator over the items of an lru_crawler metadump
+// response, read from r until END.
+//
+// The iteration stops at the first error, yielded with a zero KeyMetadata. A
+// "BUSY" response, returned when the crawler is already running, is a
+// ServerError: the request can be retried later on the same connection.
+// Breaking out of the loop early leaves the rest of the dump unread on r.
+func ReadMetadump(r *bufio.Reader) iter.Seq2[KeyMetadata, error] {
+	return func(yield func(KeyMetadata, error) bool) {
+		for {
+			line, err := readTextLine(r)
+			if err != nil {
+				yield(KeyMetadata{}, err)
+				return
+			}
+			if line == EndMarker {
+				return
+			}
+			if msg, ok := strings.CutPrefix(line, "BUSY "); ok {
+				yield(KeyMetadata{}, &ServerError{Message: msg})
+				return
+			}
+			if !strings.HasPrefix(line, "key=") {
+				yield(KeyMetadata{}, textLineError(line, "invalid metadump line: "))
+				return
+			}
+
+			item, err := parseMetadumpLine(line)
+			if !yield(item, err) || err != nil {
+				return
+			}
+		}
+	}
+}
+
+// parseMetadumpLine parses a metadump line. Unknown fields are ignored.
+func parseMetadumpLine(line string) (KeyMetadata, error) {
+	var m KeyMetadata
+	for field := range strings.FieldsSeq(line) {
+		name, value, _ := strings.Cut(field, "=")
+
+		var err error
+		switch name {
+		case "key":
+			m.Key, err = url.PathUnescape(value)
+		case "exp":
+			m.Expiration, err = strconv.ParseInt(value, 10, 64)
+		case "la":
+			var la int64
+			la, err = strconv.ParseInt(value, 10, 64)
+			m.LastAccess = time.Unix(la, 0)
+		case "cas":
+			m.CAS, err = strconv.ParseUint(value, 10, 64)
+		case "fetch":
+			m.Fetched = value == "yes"
+		case "cls":
+			m.Class, err = strconv.Atoi(value)
+		case "size":
+			m.Size, err = strconv.Atoi(value)
+		}
+		if err != nil {
+			return KeyMetadata{}, &ParseError{Message: "invalid metadump field: " + field}
+		}
+	}
+	return m, nil
+}
@@ -0,0 +1,110 @@
+package meta
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMetadump(t *testing.T) {
+	tests := []struct {
+		req  *Request
+		want string
+	}{
+		{Metadump(), "lru_crawler metadump all\r\n"},
+		{Metadump(1), "lru_crawler metadump 1\r\n"},
+		{Metadump(1, 5, 12), "lru_crawler metadump 1,5,12\r\n"},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		if err := WriteRequest(&buf, tt.req); err != nil {
+			t.Fatalf("WriteRequest failed: %v", err)
+		}
+		if got := buf.String(); got != tt.want {
+			t.Errorf("wire = %q, want %q", got, tt.want)
+		}
+		if err := Validate(tt.req); err != nil {
+			t.Errorf("Validate(%q) = %v", tt.want, err)
+		}
+	}
+}
+
+func readMetadump(input string) ([]KeyMetadata, error) {
+	var items []KeyMetadata
+	for item, err := range ReadMetadump(bufio.NewReader(strings.NewReader(input))) {
+		if err != nil {
+			return items, err
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+func TestReadMetadump(t *testing.T) {
+	t.Run("items", func(t *testing.T) {
+		items, err := readMetadump(
+			"key=foo exp=-1 la=1728000000 cas=2 fetch=no cls=1 size=63\n" +
+				"key=a%20b exp=1728003600 la=1728000010 cas=3 fetch=yes cls=5 size=1024 ext_page=1\n" +
+				"END\r\n")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		want := []KeyMetadata{
+			{Key: "foo", Expiration: -1, LastAccess: time.Unix(1728000000, 0), CAS: 2, Class: 1, Size: 63},
+			{Key: "a b", Expiration: 1728003600, LastAccess: time.Unix(1728000010, 0), CAS: 3, Fetched: true, Class: 5, Size: 1024},
+		}
+		if len(items) != len(want) {
+			t.Fatalf("items = %+v, want %+v", items, want)
+		}
+		for i := range want {
+			if items[i] != want[i] {
+				t.Errorf("items[%d] = %+v, want %+v", i, items[i], want[i])
+			}
+		}
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		items, err := readMetadump("END\r\n")
+		if err != nil || len(items) != 0 {
+			t.Errorf("items = %v, err = %v", items, err)
+		}
+	})
+
+	t.Run("busy", func(t *testing.T) {
+		_, err := readMetadump("BUSY currently processing crawler request\r\n")
+		var serverErr *ServerError
+		if !errors.As(err, &serverErr) || ShouldCloseConnection(err) {
+			t.Errorf("error = %v, want a ServerError that keeps the connection", err)
+		}
+	})
+
+	t.Run("invalid field", func(t *testing.T) {
+		items, err := readMetadump("key=foo exp=-1 cls=1\nkey=bar exp=soon\nEND\r\n")
+		var parseErr *ParseError
+		if !errors.As(err, &parseErr) {
+			t.Fatalf("error = %v, want ParseError", err)
+		}
+		if len(items) != 1 {
+			t.Errorf("items before the error = %v", items)
+		}
+	})
+
+	t.Run("error response", func(t *testing.T) {
+		_, err := readMetadump("CLIENT_ERROR bad command line format\r\n")
+		var clientErr *ClientError
+		if !errors.As(err, &clientErr) {
+			t.Errorf("error = %v, want ClientError", err)
+		}
+	})
+
+	t.Run("EOF before END", func(t *testing.T) {
+		_, err := readMetadump("key=foo exp=-1\n")
+		if !errors.Is(err, io.EOF) {
+			t.Errorf("error = %v, want io.EOF", err)
+		}
+	})
+}
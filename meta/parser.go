@@ -0,0 +1,103 @@
+package meta
+
+import "bytes"
+
+// Parser parses responses from bytes pushed by the caller, for event loops
+// that own the reads (io_uring, netpoll-style runtimes) and can't hand a
+// bufio.Reader to ReadResponse.
+//
+// The zero value is ready to use. A Parser is not safe for concurrent use.
+type Parser struct {
+	// MaxLineSize is the maximum length of a response line.
+	// Zero means MaxLineSize.
+	MaxLineSize int
+
+	// MaxDataSize is the maximum value size accepted in a VA response.
+	// Zero means MaxDataSize.
+	MaxDataSize int
+
+	// LenientLineEndings accepts the bare LF terminators, as
+	// ReadOptions.LenientLineEndings.
+	LenientLineEndings bool
+
+	responses []Response
+}
+
+// Feed parses the complete responses at the start of buf, and returns them
+// with n, the number of bytes they used. A response split at the end of buf
+// is left unparsed: the caller keeps buf[n:] and feeds it again with the bytes
+// that follow.
+//
+//	buf = append(buf, received...)
+//	responses, n, err := p.Feed(buf)
+//	buf = buf[:copy(buf, buf[n:])]
+//
+// The responses don't reference buf. The returned slice is reused by the next
+// call to Feed. On error, the stream is corrupted: the responses before the
+// faulty one are returned, and the connection must be closed.
+func (p *Parser) Feed(buf []byte) (responses []Response, n int, err error) {
+	clear(p.responses)
+	p.responses = p.responses[:0]
+
+	for n < len(buf) {
+		size, err := p.parseOne(buf[n:])
+		if err != nil {
+			return p.responses, n, err
+		}
+		if size == 0 {
+			break // incomplete response
+		}
+		n += size
+	}
+	return p.responses, n, nil
+}
+
+// parseOne parses the response at the start of buf and appends it to
+// p.responses. It returns its size, or 0 if the response is incomplete.
+func (p *Parser) parseOne(buf []byte) (int, error) {
+	opts := ReadOptions{
+		MaxLineSize:        p.MaxLineSize,
+		MaxDataSize:        p.MaxDataSize,
+		LenientLineEndings: p.LenientLineEndings,
+	}.withDefaults()
+
+	end := bytes.IndexByte(buf, '\n')
+	if end < 0 {
+		if len(buf) > opts.MaxLineSize {
+			return 0, lineTooLongError(opts.MaxLineSize)
+		}
+		return 0, nil
+	}
+	lineSize := end + 1
+	if lineSize > opts.MaxLineSize {
+		return 0, lineTooLongError(opts.MaxLineSize)
+	}
+
+	var resp Response
+	dataSize, err := parseResponseLine(buf[:lineSize], &resp, nil, opts)
+	if err != nil {
+		return 0, err
+	}
+
+	if resp.Status != StatusVA {
+		p.responses = append(p.responses, resp)
+		return lineSize, nil
+	}
+
+	// The data block and its CRLF terminator follow the line.
+	dataEnd := lineSize + dataSize
+	size := dataEnd + len(CRLF)
+	if opts.LenientLineEndings && len(buf) > dataEnd && buf[dataEnd] == '\n' {
+		size = dataEnd + 1
+	}
+	if len(buf) < size {
+		return 0, nil
+	}
+	if size == dataEnd+len(CRLF) && string(buf[dataEnd:size]) != CRLF {
+		return 0, &ParseError{Message: "invalid data block terminator"}
+	}
+	resp.Data = bytes.Clone(buf[lineSize:dataEnd])
+
+	p.responses = append(p.responses, resp)
+	return size, nil
+}
@@ -0,0 +1,150 @@
+package meta
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+const parserStream = "HD c123\r\n" +
+	"VA 5 f7 t-1\r\nhello\r\n" +
+	"EN\r\n" +
+	"SERVER_ERROR out of memory\r\n" +
+	"VA 0\r\n\r\n" +
+	"ME key exp=-1 la=3\r\n" +
+	"MN\r\n"
+
+func TestParser_Feed(t *testing.T) {
+	var p Parser
+	responses, n, err := p.Feed([]byte(parserStream))
+	if err != nil {
+		t.Fatalf("Feed failed: %v", err)
+	}
+	if n != len(parserStream) {
+		t.Errorf("n = %d, want %d", n, len(parserStream))
+	}
+
+	want := []StatusType{StatusHD, StatusVA, StatusEN, "", StatusVA, StatusME, StatusMN}
+	if len(responses) != len(want) {
+		t.Fatalf("got %d responses, want %d", len(responses), len(want))
+	}
+	for i, status := range want {
+		if responses[i].Status != status {
+			t.Errorf("responses[%d].Status = %q, want %q", i, responses[i].Status, status)
+		}
+	}
+	if string(responses[1].Data) != "hello" || string(responses[1].Flags) != " f7 t-1" {
+		t.Errorf("VA response = %+v", responses[1])
+	}
+	if !responses[4].HasValue() || len(responses[4].Data) != 0 {
+		t.Errorf("empty VA response = %+v", responses[4])
+	}
+	var serverErr *ServerError
+	if !errors.As(responses[3].Error, &serverErr) {
+		t.Errorf("responses[3].Error = %v, want ServerError", responses[3].Error)
+	}
+	if string(responses[5].Data) != "exp=-1 la=3" {
+		t.Errorf("ME data = %q", responses[5].Data)
+	}
+}
+
+// Feeding the stream in chunks of any size yields the same responses.
+func TestParser_FeedChunks(t *testing.T) {
+	for chunk := 1; chunk <= len(parserStream); chunk++ {
+		var p Parser
+		var buf []byte
+		var statuses []StatusType
+		var values []string
+
+		for i := 0; i < len(parserStream); i += chunk {
+			buf = append(buf, parserStream[i:min(i+chunk, len(parserStream))]...)
+			responses, n, err := p.Feed(buf)
+			if err != nil {
+				t.Fatalf("chunk %d: Feed failed: %v", chunk, err)
+			}
+			for _, resp := range responses {
+				statuses = append(statuses, resp.Status)
+				values = append(values, string(resp.Data))
+			}
+			buf = buf[:copy(buf, buf[n:])]
+		}
+
+		if len(buf) != 0 || len(statuses) != 7 || values[1] != "hello" {
+			t.Fatalf("chunk %d: statuses = %v, values = %q, leftover %q", chunk, statuses, values, buf)
+		}
+	}
+}
+
+func TestParser_Errors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"unknown status", "HD\r\nXX\r\n"},
+		{"bad data terminator", "VA 2\r\nhiXX"},
+		{"negative size", "VA -1\r\n"},
+		{"line too long", "HD " + strings.Repeat("O", MaxLineSize)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var p Parser
+			_, _, err := p.Feed([]byte(tt.input))
+			var parseErr *ParseError
+			if !errors.As(err, &parseErr) {
+				t.Errorf("error = %v, want ParseError", err)
+			}
+		})
+	}
+}
+
+func TestParser_Limits(t *testing.T) {
+	p := Parser{MaxLineSize: 16, MaxDataSize: 4}
+	var parseErr *ParseError
+
+	if _, _, err := p.Feed([]byte("HD O" + strings.Repeat("x", 16) + "\r\n")); !errors.As(err, &parseErr) {
+		t.Errorf("long line: error = %v, want ParseError", err)
+	}
+	if _, _, err := p.Feed([]byte("VA 5\r\n")); !errors.As(err, &parseErr) {
+		t.Errorf("large value: error = %v, want ParseError", err)
+	}
+	if responses, _, err := p.Feed([]byte("VA 4\r\nabcd\r\n")); err != nil || len(responses) != 1 {
+		t.Errorf("Feed = %d responses, %v", len(responses), err)
+	}
+}
+
+func TestParser_LineEndings(t *testing.T) {
+	input := []byte("HD\nVA 2\nhi\nVA 2\r\nhi\r\n")
+
+	var strict Parser
+	var parseErr *ParseError
+	if _, _, err := strict.Feed(input); !errors.As(err, &parseErr) {
+		t.Errorf("strict: error = %v, want ParseError", err)
+	}
+
+	lenient := Parser{LenientLineEndings: true}
+	responses, n, err := lenient.Feed(input)
+	if err != nil {
+		t.Fatalf("lenient: Feed failed: %v", err)
+	}
+	if len(responses) != 3 || n != len(input) {
+		t.Fatalf("lenient: Feed = %d responses, %d bytes, want 3, %d", len(responses), n, len(input))
+	}
+	if string(responses[1].Data) != "hi" || string(responses[2].Data) != "hi" {
+		t.Errorf("lenient: Data = %q, %q", responses[1].Data, responses[2].Data)
+	}
+}
+
+func TestParser_ResponsesDontAliasInput(t *testing.T) {
+	var p Parser
+	buf := []byte("VA 2\r\nhi\r\n")
+	responses, _, err := p.Feed(buf)
+	if err != nil {
+		t.Fatalf("Feed failed: %v", err)
+	}
+	copy(buf, bytes.Repeat([]byte("x"), len(buf)))
+	if string(responses[0].Data) != "hi" {
+		t.Errorf("Data = %q after overwriting the input", responses[0].Data)
+	}
+}
@@ -0,0 +1,52 @@
+package meta
+
+import "sync"
+
+// Request and Response pools for high-throughput clients constructing
+// millions of requests per second, to avoid the GC churn of allocating
+// each of them.
+//
+// A released object must not be used anymore, including slices obtained
+// from it (Flags, and Data when it was read with ReadResponseInto).
+
+// maxPooledFlagsSize bounds the Flags capacity kept by pooled objects, so a
+// single oversized request doesn't pin its memory in the pool.
+const maxPooledFlagsSize = 256
+
+var requestPool = sync.Pool{
+	New: func() any { return &Request{} },
+}
+
+var responsePool = sync.Pool{
+	New: func() any { return &Response{} },
+}
+
+// AcquireRequest returns an empty Request from the pool.
+// Return it with ReleaseRequest when it is not used anymore.
+func AcquireRequest() *Request {
+	return requestPool.Get().(*Request)
+}
+
+// ReleaseRequest resets a Request and returns it to the pool.
+func ReleaseRequest(req *Request) {
+	if cap(req.Flags) > maxPooledFlagsSize {
+		return
+	}
+	req.Reset()
+	requestPool.Put(req)
+}
+
+// AcquireResponse returns an empty Response from the pool.
+// Return it with ReleaseResponse when it is not used anymore.
+func AcquireResponse() *Response {
+	return responsePool.Get().(*Response)
+}
+
+// ReleaseResponse resets a Response and returns it to the pool.
+func ReleaseResponse(resp *Response) {
+	if cap(resp.Flags) > maxPooledFlagsSize {
+		return
+	}
+	resp.Reset()
+	responsePool.Put(resp)
+}
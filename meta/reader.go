@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"bytes"
 	"io"
+	"slices"
 	"strconv"
 	"strings"
 )
@@ -14,6 +15,46 @@ import (
 // allocating memory for it.
 const MaxDataSize = 1 << 30
 
+// MaxLineSize is the maximum length of a response line. Meta response lines
+// hold a status, a size and flags with short tokens: a longer line means a
+// corrupted stream, rejected instead of buffering it without bound.
+const MaxLineSize = 64 * 1024
+
+// ReadOptions are the limits of ReadResponseWithOptions, protecting the
+// client from a misbehaving or malicious server. A response exceeding them
+// returns a ParseError: the connection must be closed.
+type ReadOptions struct {
+	// MaxLineSize is the maximum length of a response line, terminator
+	// included. Zero means MaxLineSize.
+	MaxLineSize int
+
+	// MaxDataSize is the maximum value size accepted in a VA response, e.g.
+	// the item size limit of the server (-I option). A larger size is
+	// rejected before anything is allocated for the value.
+	// Zero means MaxDataSize, which also caps larger values.
+	MaxDataSize int
+
+	// LenientLineEndings accepts the lines and data blocks terminated by a
+	// bare LF instead of CRLF, as emitted by some proxies. By default, they
+	// are rejected with a ParseError.
+	LenientLineEndings bool
+}
+
+// defaultReadOptions are the limits of ReadResponse.
+var defaultReadOptions = ReadOptions{MaxLineSize: MaxLineSize, MaxDataSize: MaxDataSize}
+
+// withDefaults returns the options with the defaults for the zero fields.
+func (o ReadOptions) withDefaults() ReadOptions {
+	if o.MaxLineSize <= 0 {
+		o.MaxLineSize = MaxLineSize
+	}
+	if o.MaxDataSize <= 0 {
+		o.MaxDataSize = MaxDataSize
+	}
+	o.MaxDataSize = min(o.MaxDataSize, MaxDataSize)
+	return o
+}
+
 // ReadResponse reads and parses a single response from r into resp.
 // Response format: <status> [<flags>*]\r\n[<data>\r\n]
 //
@@ -36,34 +77,205 @@ const MaxDataSize = 1 << 30
 func ReadResponse(r *bufio.Reader, resp *Response) error {
 	// Reset response for reuse
 	*resp = Response{}
+	return readResponse(r, resp, nil, defaultReadOptions)
+}
 
-	// Read response line
-	line, err := r.ReadString('\n')
-	if err != nil {
+// ReadResponseWithOptions is ReadResponse with other limits than MaxLineSize
+// and MaxDataSize.
+func ReadResponseWithOptions(r *bufio.Reader, resp *Response, opts ReadOptions) error {
+	*resp = Response{}
+	return readResponse(r, resp, nil, opts.withDefaults())
+}
+
+// ReadResponseLimit is ReadResponse with a lower maximum value size than
+// MaxDataSize, e.g. the item size limit of the server (-I option): a VA
+// response announcing a larger value returns a ParseError before anything is
+// allocated for it.
+func ReadResponseLimit(r *bufio.Reader, resp *Response, maxDataSize int) error {
+	return ReadResponseWithOptions(r, resp, ReadOptions{MaxDataSize: maxDataSize})
+}
+
+// ReadResponseInto is ReadResponse for tight loops: it reuses memory so that
+// reading a response makes no allocation.
+//
+// The flags are parsed into the existing capacity of resp.Flags, and the value
+// of a VA response into buf when cap(buf) is at least the value size + 2 (a
+// larger value is allocated). resp.Data therefore aliases buf, and both
+// resp.Flags and resp.Data are only valid until the next call with the same
+// resp and buf. Protocol errors (resp.Error) still allocate.
+func ReadResponseInto(r *bufio.Reader, resp *Response, buf []byte) error {
+	*resp = Response{Flags: resp.Flags[:0]}
+	return readResponse(r, resp, buf, defaultReadOptions)
+}
+
+// ReadResponseHeader reads the response line into resp, leaving the data block
+// of a VA response in r, so a large value can be streamed (copied to a file,
+// hashed, ...) without allocating it:
+//
+//	size, err := meta.ReadResponseHeader(r, &resp)
+//	if err != nil {
+//		return err
+//	}
+//	if resp.Status == meta.StatusVA {
+//		err = meta.ReadData(r, file, size)
+//	}
+//
+// size is the length of the value of a VA response, and 0 otherwise. The data
+// block of a VA response must be consumed with ReadData before reading the
+// next response. resp.Data is only set for ME responses.
+func ReadResponseHeader(r *bufio.Reader, resp *Response) (size int, err error) {
+	*resp = Response{}
+	return readResponseLine(r, resp, nil, defaultReadOptions)
+}
+
+// ReadData reads the data block of a VA response whose line was read with
+// ReadResponseHeader: it copies the size bytes of the value to w, then
+// consumes the terminator.
+func ReadData(r *bufio.Reader, w io.Writer, size int) error {
+	if _, err := io.CopyN(w, r, int64(size)); err != nil {
+		return &ParseError{Message: "failed to read data block", Err: err}
+	}
+
+	var terminator [2]byte
+	if _, err := io.ReadFull(r, terminator[:]); err != nil {
+		return &ParseError{Message: "failed to read data block", Err: err}
+	}
+	if string(terminator[:]) != CRLF {
+		return &ParseError{Message: "invalid data block terminator"}
+	}
+	return nil
+}
+
+// readResponse parses a response into a reset resp, reading the data block
+// into buf when it is large enough. The responses exceeding the limits of opts
+// are rejected.
+func readResponse(r *bufio.Reader, resp *Response, buf []byte, opts ReadOptions) error {
+	dataSize, err := readResponseLine(r, resp, buf, opts)
+	if err != nil || resp.Status != StatusVA {
 		return err
 	}
+	if opts.LenientLineEndings {
+		return readLenientDataBlock(r, resp, dataSize, buf)
+	}
+
+	// Read data + CRLF together
+	data, err := readDataBlock(r, dataSize+2, buf)
+	if err != nil {
+		return &ParseError{Message: "failed to read data block", Err: err}
+	}
 
-	// Trim CRLF
-	line = strings.TrimSuffix(line, CRLF)
-	line = strings.TrimSuffix(line, "\n") // Handle LF-only (lenient)
+	// Verify CRLF suffix
+	if !bytes.HasSuffix(data, []byte(CRLF)) {
+		return &ParseError{Message: "invalid data block terminator"}
+	}
+
+	// Truncate CRLF
+	resp.Data = data[:dataSize]
+	return nil
+}
+
+// readLenientDataBlock is the end of readResponse for a data block terminated
+// by CRLF or a bare LF.
+func readLenientDataBlock(r *bufio.Reader, resp *Response, dataSize int, buf []byte) error {
+	data, err := readDataBlock(r, dataSize+1, buf)
+	if err != nil {
+		return &ParseError{Message: "failed to read data block", Err: err}
+	}
+
+	switch data[dataSize] {
+	case '\n':
+	case '\r':
+		if b, err := r.ReadByte(); err != nil || b != '\n' {
+			return &ParseError{Message: "invalid data block terminator", Err: err}
+		}
+	default:
+		return &ParseError{Message: "invalid data block terminator"}
+	}
+
+	resp.Data = data[:dataSize]
+	return nil
+}
+
+// eagerAllocSize is the data block size up to which readDataBlock allocates
+// the whole block upfront.
+const eagerAllocSize = 64 * 1024
+
+// readDataBlock reads size bytes into buf when it is large enough. Otherwise,
+// a block larger than eagerAllocSize is read into a buffer grown as the bytes
+// arrive: a corrupted or malicious size can't allocate more than twice the
+// bytes actually received.
+func readDataBlock(r *bufio.Reader, size int, buf []byte) ([]byte, error) {
+	if cap(buf) >= size {
+		data := buf[:size]
+		_, err := io.ReadFull(r, data)
+		return data, err
+	}
+	if size <= eagerAllocSize {
+		data := make([]byte, size)
+		_, err := io.ReadFull(r, data)
+		return data, err
+	}
+
+	data := make([]byte, 0, eagerAllocSize)
+	for len(data) < size {
+		if len(data) == cap(data) {
+			data = slices.Grow(data, min(cap(data), size-len(data)))
+		}
+		n, err := r.Read(data[len(data):min(cap(data), size)])
+		data = data[:len(data)+n]
+		if err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return data, err
+		}
+	}
+	return data, nil
+}
+
+// readResponseLine parses the response line into a reset resp, and returns the
+// size of the data block of a VA response, left in r. ME debug data is
+// appended to buf.
+func readResponseLine(r *bufio.Reader, resp *Response, buf []byte, opts ReadOptions) (dataSize int, err error) {
+	// Read response line. The returned slice points into the bufio.Reader
+	// buffer: it is only valid until the next read.
+	line, err := readLine(r, opts.MaxLineSize)
+	if err != nil {
+		return 0, err
+	}
+	return parseResponseLine(line, resp, buf, opts)
+}
+
+// parseResponseLine parses a response line, with its terminator, into a reset
+// resp. See readResponseLine.
+func parseResponseLine(line []byte, resp *Response, buf []byte, opts ReadOptions) (dataSize int, err error) {
+	// Trim CRLF, or a bare LF in lenient mode
+	if trimmed, ok := bytes.CutSuffix(line, []byte(CRLF)); ok {
+		line = trimmed
+	} else if trimmed, ok := bytes.CutSuffix(line, []byte("\n")); ok {
+		if !opts.LenientLineEndings {
+			return 0, &ParseError{Message: "response line not terminated by CRLF"}
+		}
+		line = trimmed
+	}
 
 	// Check for protocol errors first
-	if msg, ok := strings.CutPrefix(line, ErrorClientPrefix+" "); ok {
+	if msg, ok := bytes.CutPrefix(line, []byte(ErrorClientPrefix+" ")); ok {
 		// CLIENT_ERROR - connection should be closed
-		resp.Error = &ClientError{Message: msg}
-		return nil
+		resp.Error = &ClientError{Message: string(msg)}
+		return 0, nil
 	}
 
-	if msg, ok := strings.CutPrefix(line, ErrorServerPrefix+" "); ok {
+	if msg, ok := bytes.CutPrefix(line, []byte(ErrorServerPrefix+" ")); ok {
 		// SERVER_ERROR - server-side error
-		resp.Error = &ServerError{Message: msg}
-		return nil
+		resp.Error = &ServerError{Message: string(msg)}
+		return 0, nil
 	}
 
-	if line == ErrorGeneric {
+	if string(line) == ErrorGeneric {
 		// ERROR - generic error or unknown command
 		resp.Error = &GenericError{Message: "ERROR"}
-		return nil
+		return 0, nil
 	}
 
 	// Parse the response line in place: <status> [<size>] [<flags>*].
@@ -71,22 +283,19 @@ func ReadResponse(r *bufio.Reader, resp *Response) error {
 	sc := lineScanner{line: line}
 	status, ok := sc.next()
 	if !ok {
-		return &ParseError{Message: "empty response line"}
+		return 0, &ParseError{Message: "empty response line"}
 	}
 
-	resp.Status = StatusType(status)
-
-	switch resp.Status {
-	case StatusHD, StatusVA, StatusEN, StatusNF, StatusNS, StatusEX, StatusMN, StatusME:
-	default:
+	resp.Status, ok = parseStatus(status)
+	if !ok {
 		// An unknown status means the stream is desynchronized (or the server
 		// speaks a protocol we don't understand): fail so the connection gets closed.
-		return &ParseError{Message: "unknown response status: " + status}
+		return 0, &ParseError{Message: "unknown response status: " + string(status)}
 	}
 
 	// MN response has no additional data
 	if resp.Status == StatusMN {
-		return nil
+		return 0, nil
 	}
 
 	// ME response format: ME <key> <key>=<value>*\r\n
@@ -94,35 +303,28 @@ func ReadResponse(r *bufio.Reader, resp *Response) error {
 	// raw remainder in Data (the key is known by the caller) and skip flag parsing.
 	if resp.Status == StatusME {
 		sc.next() // skip the key
-		if rest := sc.rest(); rest != "" {
-			resp.Data = []byte(rest)
+		if rest := sc.rest(); len(rest) > 0 {
+			resp.Data = append(buf[:0], rest...)
 		}
-		return nil
+		return 0, nil
 	}
 
 	// VA response has size as second field
-	var dataSize int
 	if resp.Status == StatusVA {
 		sizeField, ok := sc.next()
 		if !ok {
-			return &ParseError{Message: "VA response missing size"}
+			return 0, &ParseError{Message: "VA response missing size"}
 		}
 
-		dataSize, err = strconv.Atoi(sizeField)
+		dataSize, err = parseSize(sizeField, opts.MaxDataSize)
 		if err != nil {
-			return &ParseError{Message: "invalid size in VA response", Err: err}
-		}
-		if dataSize < 0 {
-			return &ParseError{Message: "negative size in VA response"}
-		}
-		if dataSize > MaxDataSize {
-			return &ParseError{Message: "size in VA response exceeds maximum: " + sizeField}
+			return 0, err
 		}
 	}
 
 	// Parse flags. Size the buffer once from the remaining line so the repeated
-	// AddTokenString appends don't grow it incrementally.
-	if n := sc.remaining(); n > 0 {
+	// appends don't grow it incrementally.
+	if n := sc.remaining(); n > cap(resp.Flags) {
 		resp.Flags = make(Flags, 0, n)
 	}
 	for {
@@ -133,44 +335,96 @@ func ReadResponse(r *bufio.Reader, resp *Response) error {
 
 		flagType := FlagType(flagField[0])
 		if len(flagField) > 1 {
-			resp.Flags.AddTokenString(flagType, flagField[1:])
+			resp.Flags.AddTokenBytes(flagType, flagField[1:])
 		} else {
 			resp.Flags.Add(flagType)
 		}
 	}
 
-	// Read data block for VA responses
-	if resp.Status == StatusVA {
-		// Read data + CRLF together in single read
-		data := make([]byte, dataSize+2)
-		_, err = io.ReadFull(r, data)
-		if err != nil {
-			return &ParseError{Message: "failed to read data block", Err: err}
+	return dataSize, nil
+}
+
+// readLine reads a line without allocating: the returned slice points into
+// the buffer of r. A line longer than the buffer is copied to a new slice, up
+// to maxLine.
+func readLine(r *bufio.Reader, maxLine int) ([]byte, error) {
+	line, err := r.ReadSlice('\n')
+	if err != bufio.ErrBufferFull {
+		if len(line) > maxLine {
+			return nil, lineTooLongError(maxLine)
 		}
+		return line, err
+	}
 
-		// Verify CRLF suffix
-		if !bytes.HasSuffix(data, []byte(CRLF)) {
-			return &ParseError{Message: "invalid data block terminator"}
+	long := bytes.Clone(line)
+	for err == bufio.ErrBufferFull {
+		if len(long) > maxLine {
+			return nil, lineTooLongError(maxLine)
 		}
+		line, err = r.ReadSlice('\n')
+		long = append(long, line...)
+	}
+	if len(long) > maxLine {
+		return nil, lineTooLongError(maxLine)
+	}
+	return long, err
+}
+
+func lineTooLongError(maxLine int) error {
+	return &ParseError{Message: "response line longer than " + strconv.Itoa(maxLine) + " bytes"}
+}
 
-		// Truncate CRLF
-		resp.Data = data[:dataSize]
+// parseStatus returns the status constant matching a status field, without
+// allocating a string.
+func parseStatus(field []byte) (StatusType, bool) {
+	switch string(field) {
+	case string(StatusHD):
+		return StatusHD, true
+	case string(StatusVA):
+		return StatusVA, true
+	case string(StatusEN):
+		return StatusEN, true
+	case string(StatusNF):
+		return StatusNF, true
+	case string(StatusNS):
+		return StatusNS, true
+	case string(StatusEX):
+		return StatusEX, true
+	case string(StatusMN):
+		return StatusMN, true
+	case string(StatusME):
+		return StatusME, true
+	default:
+		return "", false
 	}
+}
 
-	return nil
+// parseSize parses the size of a VA response.
+func parseSize(field []byte, maxSize int) (int, error) {
+	size, err := strconv.Atoi(string(field))
+	if err != nil {
+		return 0, &ParseError{Message: "invalid size in VA response", Err: err}
+	}
+	if size < 0 {
+		return 0, &ParseError{Message: "negative size in VA response"}
+	}
+	if size > maxSize {
+		return 0, &ParseError{Message: "size in VA response exceeds maximum: " + string(field)}
+	}
+	return size, nil
 }
 
 // lineScanner walks a response line field by field, in place. It avoids the
-// per-response []string that strings.Fields would allocate.
+// per-response [][]byte that bytes.Fields would allocate.
 type lineScanner struct {
-	line string
+	line []byte
 	pos  int
 }
 
 // next returns the next space-separated field and advances past it. Leading
 // spaces are skipped and empty fields are never returned; ok is false once only
 // spaces (or nothing) remain.
-func (s *lineScanner) next() (field string, ok bool) {
+func (s *lineScanner) next() (field []byte, ok bool) {
 	i := s.pos
 	for i < len(s.line) && s.line[i] == ' ' {
 		i++
@@ -184,8 +438,8 @@ func (s *lineScanner) next() (field string, ok bool) {
 }
 
 // rest returns the unscanned remainder of the line with leading spaces trimmed.
-func (s *lineScanner) rest() string {
-	return strings.TrimLeft(s.line[s.pos:], " ")
+func (s *lineScanner) rest() []byte {
+	return bytes.TrimLeft(s.line[s.pos:], " ")
 }
 
 // remaining reports the number of unscanned bytes, used to size buffers before
@@ -194,61 +448,29 @@ func (s *lineScanner) remaining() int {
 	return len(s.line) - s.pos
 }
 
-// ReadStatsResponse reads a stats response from the server.
-// Stats responses consist of multiple "STAT <name> <value>\r\n" lines
-// followed by "END\r\n".
-//
-// Returns a map of stat names to values and any error encountered.
-//
-// Example response:
-//
-//	STAT pid 12345
-//	STAT uptime 3600
-//	STAT time 1609459200
-//	END
-func ReadStatsResponse(r *bufio.Reader) (map[string]string, error) {
-	stats := make(map[string]string)
-
-	for {
-		line, err := r.ReadString('\n')
-		if err != nil {
-			return stats, err
-		}
-
-		// Trim CRLF
-		line = strings.TrimSuffix(line, CRLF)
-		line = strings.TrimSuffix(line, "\n")
-
-		// Check for END marker
-		if line == EndMarker {
-			return stats, nil
-		}
-
-		// Check for errors
-		if msg, ok := strings.CutPrefix(line, ErrorClientPrefix+" "); ok {
-			return stats, &ClientError{Message: msg}
-		}
-		if msg, ok := strings.CutPrefix(line, ErrorServerPrefix+" "); ok {
-			return stats, &ServerError{Message: msg}
-		}
-		if line == ErrorGeneric {
-			return stats, &GenericError{Message: "ERROR"}
-		}
-
-		// Parse STAT line: STAT <name> <value>
-		if !strings.HasPrefix(line, StatPrefix+" ") {
-			return stats, &ParseError{Message: "invalid stats response line: " + line}
-		}
-
-		// Remove "STAT " prefix
-		statLine := strings.TrimPrefix(line, StatPrefix+" ")
-
-		// Split into name and value (value may contain spaces)
-		parts := strings.SplitN(statLine, " ", 2)
-		if len(parts) != 2 {
-			return stats, &ParseError{Message: "invalid STAT line format: " + line}
-		}
+// readTextLine reads a line of a text protocol response, without its line
+// terminator (CRLF or LF).
+func readTextLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	line = strings.TrimSuffix(line, "\n")
+	return strings.TrimSuffix(line, "\r"), nil
+}
 
-		stats[parts[0]] = parts[1]
+// textLineError returns the error for an unexpected line of a text protocol
+// response: the error responses map to their error types, and any other line
+// to a ParseError prefixed with context.
+func textLineError(line, context string) error {
+	if msg, ok := strings.CutPrefix(line, ErrorClientPrefix+" "); ok {
+		return &ClientError{Message: msg}
+	}
+	if msg, ok := strings.CutPrefix(line, ErrorServerPrefix+" "); ok {
+		return &ServerError{Message: msg}
+	}
+	if line == ErrorGeneric {
+		return &GenericError{Message: "ERROR"}
 	}
+	return &ParseError{Message: context + line}
 }
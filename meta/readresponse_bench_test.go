@@ -91,3 +91,15 @@ func BenchmarkReadResponseReuse_SmallValueWithFlags(b *testing.B) {
 func BenchmarkReadResponseReuse_LargeValue(b *testing.B) {
 	benchReadResponse(b, makeVA(10*1024, ""))
 }
+
+func BenchmarkReadResponseInto_SmallValueWithFlags(b *testing.B) {
+	r := bufio.NewReader(&loopReader{data: makeVA(100, "c12345 t3600 f30")})
+	buf := make([]byte, 1024)
+	var resp Response
+	b.ReportAllocs()
+	for b.Loop() {
+		if err := ReadResponseInto(r, &resp, buf); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
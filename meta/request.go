@@ -1,6 +1,9 @@
 package meta
 
-import "strconv"
+import (
+	"strconv"
+	"time"
+)
 
 // Request represents a meta protocol request.
 // This is a low-level container for request data without serialization logic.
@@ -25,6 +28,9 @@ type Request struct {
 	// It contains the exact bytes that appear after the key/size on the wire,
 	// including the leading spaces (e.g. " v c t" or " T60 Oopaque").
 	Flags Flags
+
+	// wire is the serialized request, set by Prepare.
+	wire []byte
 }
 
 // Flags is a serialized representation of meta protocol flags.
@@ -129,6 +135,44 @@ func (f Flags) Get(flagType FlagType) (token []byte, ok bool) {
 	return nil, false
 }
 
+// GetUint64 parses the token of the first flag of the given type as an
+// unsigned integer. ok is false if the flag is absent or the token is invalid.
+func (f Flags) GetUint64(flagType FlagType) (v uint64, ok bool) {
+	token, ok := f.Get(flagType)
+	if !ok {
+		return 0, false
+	}
+	v, err := strconv.ParseUint(string(token), 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return v, true
+}
+
+// GetInt64 parses the token of the first flag of the given type as a signed
+// integer. ok is false if the flag is absent or the token is invalid.
+func (f Flags) GetInt64(flagType FlagType) (v int64, ok bool) {
+	token, ok := f.Get(flagType)
+	if !ok {
+		return 0, false
+	}
+	v, err := strconv.ParseInt(string(token), 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return v, true
+}
+
+// GetDuration parses the token of the first flag of the given type as a
+// number of seconds. ok is false if the flag is absent or the token is invalid.
+func (f Flags) GetDuration(flagType FlagType) (d time.Duration, ok bool) {
+	v, ok := f.GetInt64(flagType)
+	if !ok {
+		return 0, false
+	}
+	return time.Duration(v) * time.Second, true
+}
+
 func flagsSkipSpaces(b []byte, idx int) int {
 	for idx < len(b) && b[idx] == ' ' {
 		idx++
@@ -169,6 +213,58 @@ func NewRequest(cmd CmdType, key string, data []byte) *Request {
 	}
 }
 
+// Command constructors, shorthand for NewRequest. The returned request is
+// ready to send and chains with the Add* methods:
+//
+//	req := Get("mykey").AddReturnValue().AddTTL(60)
+//	req = Set("mykey", value).AddModeAdd().AddCAS(cas)
+
+// Get creates an mg request for key.
+func Get(key string) *Request { return NewRequest(CmdGet, key, nil) }
+
+// Set creates an ms request storing data under key.
+func Set(key string, data []byte) *Request { return NewRequest(CmdSet, key, data) }
+
+// Delete creates an md request for key.
+func Delete(key string) *Request { return NewRequest(CmdDelete, key, nil) }
+
+// Arithmetic creates an ma request for key. It increments by 1 unless
+// AddModeDecrement or AddDelta change it.
+func Arithmetic(key string) *Request { return NewRequest(CmdArithmetic, key, nil) }
+
+// Debug creates an me request for key.
+func Debug(key string) *Request { return NewRequest(CmdDebug, key, nil) }
+
+// NoOp creates an mn request.
+func NoOp() *Request { return NewRequest(CmdNoOp, "", nil) }
+
+// Reset clears the request for reuse, keeping the capacity of Flags.
+func (r *Request) Reset() {
+	*r = Request{Flags: r.Flags[:0]}
+}
+
+// Prepare serializes the request once, for a request sent many times
+// unchanged (e.g. the get of a hot key): WriteRequest, AppendRequest and
+// WriteRequests then write the stored bytes, skipping the validation and the
+// formatting. It fails, like WriteRequest, for an invalid key.
+//
+// A prepared request must not be modified: the changes would not be sent.
+// It can be sent concurrently.
+func (r *Request) Prepare() error {
+	wire, err := AppendRequest(nil, r)
+	if err != nil {
+		return err
+	}
+	r.wire = wire
+	return nil
+}
+
+// Clone returns a copy of the request whose flags can be changed without
+// changing r. The copy is not prepared, even if r is. Data is shared.
+func (r *Request) Clone() *Request {
+	return &Request{Command: r.Command, Key: r.Key, Data: r.Data, Flags: r.Flags.Clone()}
+}
+
 // HasFlag checks if the request contains a flag of the given type.
 func (r *Request) HasFlag(flagType FlagType) bool {
 	return r.Flags.Has(flagType)
@@ -179,6 +275,35 @@ func (r *Request) GetFlagToken(flagType FlagType) (token []byte, ok bool) {
 	return r.Flags.Get(flagType)
 }
 
+// AddFlagUint64 adds a flag with an unsigned integer token, e.g. " C12345".
+// The flag is unconditionally added, even if already present.
+func (r *Request) AddFlagUint64(flagType FlagType, v uint64) *Request {
+	r.Flags.AddUint64(flagType, v)
+	return r
+}
+
+// AddFlagInt64 adds a flag with a signed integer token, e.g. " T-1".
+// The flag is unconditionally added, even if already present.
+func (r *Request) AddFlagInt64(flagType FlagType, v int64) *Request {
+	r.Flags.AddInt64(flagType, v)
+	return r
+}
+
+// AddFlagDuration adds a flag with a token in seconds, e.g. " T60".
+// d is rounded away from zero to whole seconds, so a sub-second duration is
+// never encoded as 0, which means "no expiration" for T.
+// The flag is unconditionally added, even if already present.
+func (r *Request) AddFlagDuration(flagType FlagType, d time.Duration) *Request {
+	seconds := d / time.Second
+	if rem := d % time.Second; rem > 0 {
+		seconds++
+	} else if rem < 0 {
+		seconds--
+	}
+	r.Flags.AddInt64(flagType, int64(seconds))
+	return r
+}
+
 // --- Typed flag methods ---
 //
 // All Add* methods return *Request for fluent chaining:
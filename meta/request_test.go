@@ -3,6 +3,7 @@ package meta
 import (
 	"bytes"
 	"testing"
+	"time"
 )
 
 // Every typed flag method, asserted against the exact wire bytes it produces.
@@ -150,3 +151,84 @@ func TestFlags_Methods(t *testing.T) {
 		}
 	})
 }
+
+func TestRequest_CommandConstructors(t *testing.T) {
+	tests := []struct {
+		req  *Request
+		want string
+	}{
+		{Get("key").AddReturnValue().AddTTL(60), "mg key v T60\r\n"},
+		{Set("key", []byte("hi")).AddModeAdd().AddCAS(123), "ms key 2 ME C123\r\nhi\r\n"},
+		{Delete("key").AddInvalidate(), "md key I\r\n"},
+		{Arithmetic("key").AddDelta(5), "ma key D5\r\n"},
+		{Debug("key"), "me key\r\n"},
+		{NoOp(), "mn\r\n"},
+		{StatsRequest(), "stats\r\n"},
+		{StatsRequest("items"), "stats items\r\n"},
+		{StatsRequest("cachedump", "1", "10"), "stats cachedump 1 10\r\n"},
+	}
+
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		if err := WriteRequest(&buf, tt.req); err != nil {
+			t.Fatalf("WriteRequest(%q) failed: %v", tt.want, err)
+		}
+		if got := buf.String(); got != tt.want {
+			t.Errorf("wire = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestRequest_FlagMethods_NoAllocs(t *testing.T) {
+	req := Get("key")
+	req.Flags = make(Flags, 0, 64)
+
+	allocs := testing.AllocsPerRun(100, func() {
+		req.Flags.Reset()
+		req.AddReturnValue().AddTTL(12345).AddCAS(1 << 40).AddOpaque("op")
+	})
+	if allocs != 0 {
+		t.Errorf("building flags allocated %v times, want 0", allocs)
+	}
+}
+
+func TestRequest_NumericFlagSetters(t *testing.T) {
+	req := Get("key").
+		AddFlagUint64(FlagCAS, 1<<63).
+		AddFlagInt64(FlagTTL, -1).
+		AddFlagDuration(FlagRecache, 90*time.Second).
+		AddFlagDuration(FlagVivify, 1500*time.Millisecond).
+		AddFlagDuration(FlagTTL, -time.Millisecond)
+
+	want := " C9223372036854775808 T-1 R90 N2 T-1"
+	if got := string(req.Flags); got != want {
+		t.Errorf("Flags = %q, want %q", got, want)
+	}
+
+	if d, ok := req.Flags.GetDuration(FlagRecache); !ok || d != 90*time.Second {
+		t.Errorf("GetDuration(R) = %v/%v, want 90s/true", d, ok)
+	}
+}
+
+func TestRequest_Clone(t *testing.T) {
+	req := NewRequest(CmdGet, "key", nil).AddReturnValue()
+	if err := req.Prepare(); err != nil {
+		t.Fatal(err)
+	}
+
+	clone := req.Clone().AddOpaque("1")
+	if got := string(req.Flags); got != " v" {
+		t.Errorf("original flags = %q, want %q", got, " v")
+	}
+	if got := string(clone.Flags); got != " v O1" {
+		t.Errorf("clone flags = %q, want %q", got, " v O1")
+	}
+
+	var buf bytes.Buffer
+	if err := WriteRequest(&buf, clone); err != nil {
+		t.Fatal(err)
+	}
+	if got := buf.String(); got != "mg key v O1\r\n" {
+		t.Errorf("clone wire = %q, want %q", got, "mg key v O1\r\n")
+	}
+}
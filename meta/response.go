@@ -3,6 +3,7 @@ package meta
 import (
 	"strconv"
 	"strings"
+	"time"
 )
 
 // Response represents a parsed meta protocol response.
@@ -26,6 +27,11 @@ type Response struct {
 	Error error
 }
 
+// Reset clears the response for reuse, keeping the capacity of Flags.
+func (r *Response) Reset() {
+	*r = Response{Flags: r.Flags[:0]}
+}
+
 // IsSuccess returns true if the response indicates a successful operation.
 // Success statuses: HD, VA, MN, ME
 func (r *Response) IsSuccess() bool {
@@ -79,6 +85,30 @@ func (r *Response) GetFlagToken(flagType FlagType) (token []byte, ok bool) {
 	return r.Flags.Get(flagType)
 }
 
+// GetFlagUint64 parses the token of the first flag of the given type as an
+// unsigned integer, e.g. the c (CAS) or s (size) flags.
+//
+// ok is false if the flag is absent or its token isn't a valid uint64.
+func (r *Response) GetFlagUint64(flagType FlagType) (v uint64, ok bool) {
+	return r.Flags.GetUint64(flagType)
+}
+
+// GetFlagInt64 parses the token of the first flag of the given type as a
+// signed integer, e.g. the t (TTL) flag which is -1 for no expiration.
+//
+// ok is false if the flag is absent or its token isn't a valid int64.
+func (r *Response) GetFlagInt64(flagType FlagType) (v int64, ok bool) {
+	return r.Flags.GetInt64(flagType)
+}
+
+// GetFlagDuration parses the token of the first flag of the given type as a
+// number of seconds, e.g. the t (TTL) or l (last access) flags.
+//
+// ok is false if the flag is absent or its token isn't a valid number.
+func (r *Response) GetFlagDuration(flagType FlagType) (d time.Duration, ok bool) {
+	return r.Flags.GetDuration(flagType)
+}
+
 // --- Typed flag getters ---
 
 // Boolean flags (presence check)
@@ -105,15 +135,7 @@ func (r *Response) AlreadyWon() bool {
 
 // CAS returns the CAS token value from the response.
 func (r *Response) CAS() (uint64, bool) {
-	token, ok := r.Flags.Get(FlagReturnCAS)
-	if !ok {
-		return 0, false
-	}
-	v, err := strconv.ParseUint(string(token), 10, 64)
-	if err != nil {
-		return 0, false
-	}
-	return v, true
+	return r.Flags.GetUint64(FlagReturnCAS)
 }
 
 // TTL returns the remaining TTL in seconds from the response.
@@ -2,6 +2,7 @@ package meta
 
 import (
 	"testing"
+	"time"
 )
 
 // responseWithFlags builds a Response carrying the given raw flags string.
@@ -124,3 +125,26 @@ func TestParseDebugParams_Malformed(t *testing.T) {
 		t.Error("token without '=' must be skipped")
 	}
 }
+
+func TestResponse_NumericFlagAccessors(t *testing.T) {
+	resp := responseWithFlags(" c18446744073709551615 t-1 l30 sabc")
+
+	if v, ok := resp.GetFlagUint64(FlagReturnCAS); !ok || v != 18446744073709551615 {
+		t.Errorf("GetFlagUint64(c) = %d/%v", v, ok)
+	}
+	if v, ok := resp.GetFlagInt64(FlagReturnTTL); !ok || v != -1 {
+		t.Errorf("GetFlagInt64(t) = %d/%v, want -1/true", v, ok)
+	}
+	if _, ok := resp.GetFlagUint64(FlagReturnTTL); ok {
+		t.Error("GetFlagUint64 must reject a negative token")
+	}
+	if d, ok := resp.GetFlagDuration(FlagReturnLastAccess); !ok || d != 30*time.Second {
+		t.Errorf("GetFlagDuration(l) = %v/%v, want 30s/true", d, ok)
+	}
+	if _, ok := resp.GetFlagInt64(FlagReturnSize); ok {
+		t.Error("GetFlagInt64 must reject a non-numeric token")
+	}
+	if _, ok := resp.GetFlagDuration(FlagReturnHit); ok {
+		t.Error("GetFlagDuration must return false for a missing flag")
+	}
+}
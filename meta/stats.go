@@ -0,0 +1,138 @@
+package meta
+
+import (
+	"bufio"
+	"strconv"
+	"strings"
+)
+
+// Stat is a single "STAT <name> <value>" line of a stats response.
+type Stat struct {
+	Name  string
+	Value string
+}
+
+// Stats holds the lines of a stats response in server order.
+//
+// The same reader handles all the stats sections, which only differ by the
+// shape of their names:
+//
+//	stats            STAT pid 12345
+//	stats settings   STAT maxbytes 67108864
+//	stats items      STAT items:1:number 5
+//	stats slabs      STAT 1:chunk_size 96 (and STAT active_slabs 1)
+//	stats sizes      STAT 96 1
+type Stats []Stat
+
+// StatsRequest creates a stats request for the section named by args, e.g.
+// "settings", "items", "slabs" or "sizes", or for the general statistics
+// without args. The response is read with ReadStats.
+func StatsRequest(args ...string) *Request {
+	return &Request{Command: CmdStats, Key: strings.Join(args, " ")}
+}
+
+// Get returns the value of the first stat with the given name.
+func (s Stats) Get(name string) (value string, ok bool) {
+	for _, stat := range s {
+		if stat.Name == name {
+			return stat.Value, true
+		}
+	}
+	return "", false
+}
+
+// Map returns the stats as a map. For duplicate names, the last value wins.
+func (s Stats) Map() map[string]string {
+	m := make(map[string]string, len(s))
+	for _, stat := range s {
+		m[stat.Name] = stat.Value
+	}
+	return m
+}
+
+// ByClass groups the per slab class stats of "stats items" and "stats slabs"
+// by class id, e.g. "items:1:number 5" and "1:chunk_size 96" become
+// m[1]["number"] and m[1]["chunk_size"]. The other stats are skipped.
+func (s Stats) ByClass() map[int]map[string]string {
+	classes := make(map[int]map[string]string)
+	for _, stat := range s {
+		name := strings.TrimPrefix(stat.Name, "items:")
+		idStr, field, ok := strings.Cut(name, ":")
+		if !ok {
+			continue
+		}
+		id, err := strconv.Atoi(idStr)
+		if err != nil {
+			continue
+		}
+		if classes[id] == nil {
+			classes[id] = make(map[string]string)
+		}
+		classes[id][field] = stat.Value
+	}
+	return classes
+}
+
+// ReadStats reads a stats response from the server, keeping the order of the
+// lines. See ReadStatsResponse for the format and the errors.
+//
+// On error, the stats read so far are returned with the error.
+func ReadStats(r *bufio.Reader) (Stats, error) {
+	var stats Stats
+	err := readStatLines(r, func(name, value string) {
+		stats = append(stats, Stat{Name: name, Value: value})
+	})
+	return stats, err
+}
+
+// ReadStatsResponse reads a stats response from the server.
+// Stats responses consist of multiple "STAT <name> <value>\r\n" lines
+// followed by "END\r\n".
+//
+// Returns a map of stat names to values and any error encountered.
+// Use ReadStats to keep the order of the lines.
+//
+// Example response:
+//
+//	STAT pid 12345
+//	STAT uptime 3600
+//	STAT time 1609459200
+//	END
+func ReadStatsResponse(r *bufio.Reader) (map[string]string, error) {
+	stats := make(map[string]string)
+	err := readStatLines(r, func(name, value string) {
+		stats[name] = value
+	})
+	return stats, err
+}
+
+// readStatLines reads the lines of a stats response until END, calling fn for
+// each STAT line. Error responses are returned as ClientError, ServerError or
+// GenericError, and any other line as a ParseError.
+func readStatLines(r *bufio.Reader, fn func(name, value string)) error {
+	for {
+		line, err := readTextLine(r)
+		if err != nil {
+			return err
+		}
+
+		// Check for END marker
+		if line == EndMarker {
+			return nil
+		}
+
+		// Parse STAT line: STAT <name> <value>
+		statLine, ok := strings.CutPrefix(line, StatPrefix+" ")
+		if !ok {
+			return textLineError(line, "invalid stats response line: ")
+		}
+
+		// Split into name and value (value may contain spaces)
+		name, value, ok := strings.Cut(statLine, " ")
+		if !ok {
+			return &ParseError{Message: "invalid STAT line format: " + line}
+		}
+
+		fn(name, value)
+	}
+}
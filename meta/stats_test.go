@@ -111,3 +111,64 @@ func TestReadStatsResponse_Errors(t *testing.T) {
 		}
 	})
 }
+
+func TestReadStats(t *testing.T) {
+	t.Run("keeps server order", func(t *testing.T) {
+		stats, err := ReadStats(bufio.NewReader(strings.NewReader(
+			"STAT pid 1\r\nSTAT uptime 2\r\nSTAT version 1.6.39\r\nEND\r\n")))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		want := Stats{{"pid", "1"}, {"uptime", "2"}, {"version", "1.6.39"}}
+		if len(stats) != len(want) {
+			t.Fatalf("stats = %v, want %v", stats, want)
+		}
+		for i := range want {
+			if stats[i] != want[i] {
+				t.Errorf("stats[%d] = %v, want %v", i, stats[i], want[i])
+			}
+		}
+		if v, ok := stats.Get("uptime"); !ok || v != "2" {
+			t.Errorf("Get(uptime) = %q/%v", v, ok)
+		}
+		if _, ok := stats.Get("missing"); ok {
+			t.Error("Get(missing) must return false")
+		}
+		if m := stats.Map(); len(m) != 3 || m["version"] != "1.6.39" {
+			t.Errorf("Map() = %v", m)
+		}
+	})
+
+	t.Run("items and slabs by class", func(t *testing.T) {
+		stats, err := ReadStats(bufio.NewReader(strings.NewReader(
+			"STAT items:1:number 5\r\nSTAT items:1:age 10\r\nSTAT items:12:number 1\r\n" +
+				"STAT 1:chunk_size 96\r\nSTAT active_slabs 2\r\nSTAT 96 1\r\nEND\r\n")))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		classes := stats.ByClass()
+		if len(classes) != 2 {
+			t.Fatalf("ByClass() = %v, want classes 1 and 12", classes)
+		}
+		if got := classes[1]; got["number"] != "5" || got["age"] != "10" || got["chunk_size"] != "96" {
+			t.Errorf("class 1 = %v", got)
+		}
+		if got := classes[12]["number"]; got != "1" {
+			t.Errorf("class 12 number = %q, want 1", got)
+		}
+		if v, _ := stats.Get("96"); v != "1" {
+			t.Errorf("stats sizes line = %q, want 1", v)
+		}
+	})
+
+	t.Run("error keeps the stats read so far", func(t *testing.T) {
+		stats, err := ReadStats(bufio.NewReader(strings.NewReader("STAT pid 1\r\nSERVER_ERROR busy\r\n")))
+		var serverErr *ServerError
+		if !errors.As(err, &serverErr) {
+			t.Fatalf("error = %v, want ServerError", err)
+		}
+		if len(stats) != 1 || stats[0].Name != "pid" {
+			t.Errorf("stats = %v", stats)
+		}
+	})
+}
@@ -0,0 +1,131 @@
+package meta
+
+import (
+	"io"
+	"strconv"
+	"strings"
+)
+
+// The package doesn't validate flags when writing requests: the server
+// rejects invalid ones with a CLIENT_ERROR, which forces closing the
+// connection. Validate catches these mistakes client-side instead, at the cost
+// of a pass over the flags.
+
+// commandFlags lists the flags supported by each meta command.
+var commandFlags = map[CmdType]string{
+	CmdGet:        "bcfhklOqstuvENRT",
+	CmdSet:        "bcCEFIkMOqT",
+	CmdDelete:     "bCEIkOqTx",
+	CmdArithmetic: "bcCDEJkMNOqtTv",
+	CmdDebug:      "b",
+}
+
+// maxOpaqueLength is the maximum length of an opaque token.
+const maxOpaqueLength = 32
+
+// Validate checks a request against the meta protocol: the command is known,
+// the key is valid, each flag is supported by the command, appears once, and
+// carries a well-formed token when it takes one. Errors are *InvalidKeyError
+// or *InvalidRequestError.
+func Validate(req *Request) error {
+	if req.Command == CmdNoOp {
+		if req.Key != "" || len(req.Flags) > 0 || len(req.Data) > 0 {
+			return &InvalidRequestError{Command: req.Command, Message: "takes no key, flags or data"}
+		}
+		return nil
+	}
+	if isTextCommand(req.Command) {
+		if strings.ContainsAny(req.Key, "\r\n") {
+			return &InvalidRequestError{Command: req.Command, Message: "arguments contain a line break"}
+		}
+		return nil
+	}
+
+	allowed, ok := commandFlags[req.Command]
+	if !ok {
+		return &InvalidRequestError{Command: req.Command, Message: "unknown command"}
+	}
+
+	if err := ValidateKey(req.Key, req.HasFlag(FlagBase64Key)); err != nil {
+		return err
+	}
+
+	if req.Command != CmdSet && len(req.Data) > 0 {
+		return &InvalidRequestError{Command: req.Command, Message: "data is only sent with ms"}
+	}
+
+	var seen [256]bool
+	f := req.Flags
+	for i := flagsSkipSpaces(f, 0); i < len(f); i = flagsSkipSpaces(f, i) {
+		flag := FlagType(f[i])
+		start := i + 1
+		i = start
+		for i < len(f) && f[i] != ' ' {
+			i++
+		}
+		token := string(f[start:i])
+
+		if !strings.ContainsRune(allowed, rune(flag)) {
+			return &InvalidRequestError{Command: req.Command, Flag: flag, Message: "not supported by this command"}
+		}
+		if seen[flag] {
+			return &InvalidRequestError{Command: req.Command, Flag: flag, Message: "duplicate flag"}
+		}
+		seen[flag] = true
+
+		if msg := validateToken(req.Command, flag, token); msg != "" {
+			return &InvalidRequestError{Command: req.Command, Flag: flag, Message: msg}
+		}
+	}
+
+	if req.Command == CmdDelete && seen[FlagTTL] && !seen[FlagInvalidate] {
+		return &InvalidRequestError{Command: req.Command, Flag: FlagTTL, Message: "requires the I flag"}
+	}
+
+	return nil
+}
+
+// validateToken checks the token of a flag, and returns a message describing
+// the problem, or "" when the token is valid.
+func validateToken(cmd CmdType, flag FlagType, token string) string {
+	switch flag {
+	case FlagTTL, FlagRecache, FlagVivify:
+		if _, err := strconv.ParseInt(token, 10, 32); err != nil {
+			return "token must be a number of seconds"
+		}
+	case FlagClientFlags:
+		if _, err := strconv.ParseUint(token, 10, 32); err != nil {
+			return "token must be a 32-bit unsigned integer"
+		}
+	case FlagCAS, FlagExplicitCAS, FlagDelta, FlagInitialValue:
+		if _, err := strconv.ParseUint(token, 10, 64); err != nil {
+			return "token must be a 64-bit unsigned integer"
+		}
+	case FlagOpaque:
+		if token == "" || len(token) > maxOpaqueLength {
+			return "token must be 1 to 32 bytes"
+		}
+	case FlagMode:
+		modes := ModeSet + ModeAdd + ModeReplace + ModeAppend + ModePrepend
+		if cmd == CmdArithmetic {
+			modes = ModeIncrement + ModeIncrementAlt + ModeDecrement + ModeDecrementAlt
+		}
+		if len(token) != 1 || !strings.Contains(modes, strings.ToUpper(token)) {
+			return "invalid mode " + strconv.Quote(token)
+		}
+	default:
+		if token != "" {
+			return "takes no token"
+		}
+	}
+	return ""
+}
+
+// WriteRequestStrict validates a request with Validate, then writes it with
+// WriteRequest. An invalid request is not written.
+func WriteRequestStrict(w io.Writer, req *Request) error {
+	if err := Validate(req); err != nil {
+		return err
+	}
+	return WriteRequest(w, req)
+}
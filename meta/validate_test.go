@@ -0,0 +1,75 @@
+package meta
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		req     *Request
+		wantErr string // empty for a valid request
+	}{
+		{"get", NewRequest(CmdGet, "key", nil).AddReturnValue().AddReturnCAS().AddTTL(60), ""},
+		{"set", NewRequest(CmdSet, "key", []byte("v")).AddModeAdd().AddTTL(-1).AddClientFlags(30), ""},
+		{"set lowercase mode", NewRequest(CmdSet, "key", nil).AddMode("e"), ""},
+		{"delete invalidate", NewRequest(CmdDelete, "key", nil).AddInvalidate().AddTTL(30), ""},
+		{"arithmetic", NewRequest(CmdArithmetic, "key", nil).AddModeDecrement().AddDelta(5).AddInitialValue(10), ""},
+		{"noop", NewRequest(CmdNoOp, "", nil), ""},
+		{"stats", &Request{Command: CmdStats, Key: "items"}, ""},
+
+		{"unknown command", NewRequest("mx", "key", nil), "invalid mx request: unknown command"},
+		{"invalid key", NewRequest(CmdGet, "bad key", nil), "key contains whitespace"},
+		{"unsupported flag", NewRequest(CmdGet, "key", nil).AddDelta(1), "invalid mg request: flag D: not supported by this command"},
+		{"duplicate flag", NewRequest(CmdSet, "key", nil).AddModeAdd().AddModeReplace(), "invalid ms request: flag M: duplicate flag"},
+		{"missing token", &Request{Command: CmdGet, Key: "key", Flags: Flags(" v T")}, "invalid mg request: flag T: token must be a number of seconds"},
+		{"invalid token", &Request{Command: CmdSet, Key: "key", Flags: Flags(" Fabc")}, "invalid ms request: flag F: token must be a 32-bit unsigned integer"},
+		{"unexpected token", &Request{Command: CmdGet, Key: "key", Flags: Flags(" v1")}, "invalid mg request: flag v: takes no token"},
+		{"invalid set mode", NewRequest(CmdSet, "key", nil).AddMode("I"), `invalid ms request: flag M: invalid mode "I"`},
+		{"invalid arithmetic mode", NewRequest(CmdArithmetic, "key", nil).AddModeAppend(), `invalid ma request: flag M: invalid mode "A"`},
+		{"long opaque", NewRequest(CmdGet, "key", nil).AddOpaque(string(bytes.Repeat([]byte("o"), 33))), "invalid mg request: flag O: token must be 1 to 32 bytes"},
+		{"data on get", NewRequest(CmdGet, "key", []byte("v")), "invalid mg request: data is only sent with ms"},
+		{"delete ttl without invalidate", NewRequest(CmdDelete, "key", nil).AddTTL(30), "invalid md request: flag T: requires the I flag"},
+		{"noop with key", NewRequest(CmdNoOp, "key", nil), "invalid mn request: takes no key, flags or data"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(tt.req)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Validate() = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("Validate() = %v, want %q", err, tt.wantErr)
+			}
+			if ShouldCloseConnection(err) {
+				t.Error("a request rejected client-side must not close the connection")
+			}
+		})
+	}
+}
+
+func TestWriteRequestStrict(t *testing.T) {
+	var buf bytes.Buffer
+
+	err := WriteRequestStrict(&buf, NewRequest(CmdGet, "key", nil).AddDelta(1))
+	var reqErr *InvalidRequestError
+	if !errors.As(err, &reqErr) || reqErr.Flag != FlagDelta {
+		t.Fatalf("WriteRequestStrict error = %v, want InvalidRequestError for flag D", err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("wrote %q, want nothing", buf.String())
+	}
+
+	if err := WriteRequestStrict(&buf, NewRequest(CmdGet, "key", nil).AddReturnValue()); err != nil {
+		t.Fatalf("WriteRequestStrict failed: %v", err)
+	}
+	if got := buf.String(); got != "mg key v\r\n" {
+		t.Errorf("wire = %q", got)
+	}
+}
@@ -0,0 +1,145 @@
+package meta
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Log streams of the watch command.
+const (
+	WatchFetchers   = "fetchers"   // item fetches: type=item_get
+	WatchMutations  = "mutations"  // item stores: type=item_store
+	WatchEvictions  = "evictions"  // items evicted from the LRU: type=eviction
+	WatchDeletions  = "deletions"  // item deletes: type=deleted
+	WatchConnEvents = "connevents" // connections opened and closed
+)
+
+// WatchEvent is a line of the watch log stream, e.g.
+//
+//	ts=1728000000.123456 gid=12 type=item_get key=foo status=found clsid=1 cfd=20 size=3
+//
+// Keys are URI-encoded by the server.
+type WatchEvent struct {
+	// Time is the ts field, when present.
+	Time time.Time
+
+	// Type is the type field, e.g. "item_get", "item_store" or "eviction".
+	// Lines reporting events dropped by a slow watcher have the type "skipped",
+	// with the count in Fields["skipped"].
+	Type string
+
+	// Fields holds all the key=value fields of the line, including ts, gid and
+	// type.
+	Fields map[string]string
+
+	// Err is set on the last event sent by Watch, when the stream ended.
+	Err error
+}
+
+// Watch starts a watch on conn for the given streams (WatchFetchers,
+// WatchMutations, ...) and returns the events on a channel.
+//
+// The connection is dedicated to the watch from then on. To stop, close conn:
+// the channel receives a last event with Err set, then it is closed. The
+// channel must be drained for the reading goroutine to exit.
+func Watch(conn io.ReadWriter, streams ...string) (<-chan WatchEvent, error) {
+	r := bufio.NewReader(conn)
+	if err := StartWatch(conn, r, streams...); err != nil {
+		return nil, err
+	}
+
+	events := make(chan WatchEvent, 64)
+	go func() {
+		defer close(events)
+		for {
+			var ev WatchEvent
+			if err := ReadWatchEvent(r, &ev); err != nil {
+				events <- WatchEvent{Err: err}
+				return
+			}
+			events <- ev
+		}
+	}()
+	return events, nil
+}
+
+// StartWatch writes a watch request for the given streams to w and reads the
+// server acknowledgment from r. The events are then read with ReadWatchEvent.
+func StartWatch(w io.Writer, r *bufio.Reader, streams ...string) error {
+	req := &Request{Command: CmdWatch, Key: strings.Join(streams, " ")}
+	if err := WriteRequest(w, req); err != nil {
+		return err
+	}
+
+	return ReadOK(r)
+}
+
+// ReadWatchEvent reads a line of the watch log stream into ev. The Fields map
+// of ev is reused when it is not nil.
+//
+// An error in a field (e.g. an invalid ts) returns a ParseError: the stream
+// stays in sync since each event is a single line.
+func ReadWatchEvent(r *bufio.Reader, ev *WatchEvent) error {
+	line, err := readTextLine(r)
+	if err != nil {
+		return err
+	}
+
+	fields := ev.Fields
+	if fields == nil {
+		fields = make(map[string]string)
+	} else {
+		clear(fields)
+	}
+	*ev = WatchEvent{Fields: fields}
+
+	// Dropped events: "[skipped: 12]"
+	if count, ok := strings.CutPrefix(line, "[skipped: "); ok {
+		ev.Type = "skipped"
+		fields["skipped"] = strings.TrimSuffix(count, "]")
+		return nil
+	}
+
+	for field := range strings.FieldsSeq(line) {
+		name, value, _ := strings.Cut(field, "=")
+		fields[name] = value
+	}
+	ev.Type = fields["type"]
+
+	if ts, ok := fields["ts"]; ok {
+		t, err := parseWatchTime(ts)
+		if err != nil {
+			return &ParseError{Message: "invalid watch event timestamp: " + ts}
+		}
+		ev.Time = t
+	}
+	return nil
+}
+
+// parseWatchTime parses a "<seconds>.<fraction>" timestamp.
+func parseWatchTime(ts string) (time.Time, error) {
+	secStr, fracStr, _ := strings.Cut(ts, ".")
+	sec, err := strconv.ParseInt(secStr, 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	var nsec int64
+	if fracStr != "" {
+		if len(fracStr) > 9 {
+			fracStr = fracStr[:9]
+		}
+		frac, err := strconv.ParseUint(fracStr, 10, 32)
+		if err != nil {
+			return time.Time{}, err
+		}
+		nsec = int64(frac)
+		for range 9 - len(fracStr) {
+			nsec *= 10
+		}
+	}
+	return time.Unix(sec, nsec), nil
+}
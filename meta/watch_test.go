@@ -0,0 +1,100 @@
+package meta
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStartWatch(t *testing.T) {
+	var w bytes.Buffer
+	err := StartWatch(&w, bufio.NewReader(strings.NewReader("OK\r\n")), WatchFetchers, WatchMutations)
+	if err != nil {
+		t.Fatalf("StartWatch failed: %v", err)
+	}
+	if got := w.String(); got != "watch fetchers mutations\r\n" {
+		t.Errorf("wire = %q", got)
+	}
+
+	err = StartWatch(io.Discard, bufio.NewReader(strings.NewReader("CLIENT_ERROR watch not allowed\r\n")))
+	var clientErr *ClientError
+	if !errors.As(err, &clientErr) {
+		t.Errorf("error = %v, want ClientError", err)
+	}
+
+	err = StartWatch(io.Discard, bufio.NewReader(strings.NewReader("WATCHER_TOO_MANY\r\n")))
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) {
+		t.Errorf("error = %v, want ParseError", err)
+	}
+}
+
+func TestReadWatchEvent(t *testing.T) {
+	r := bufio.NewReader(strings.NewReader(
+		"ts=1728000000.123456 gid=12 type=item_get key=foo status=found clsid=1 cfd=20 size=3\n" +
+			"[skipped: 7]\n" +
+			"ts=bad gid=13 type=eviction\n"))
+
+	var ev WatchEvent
+	if err := ReadWatchEvent(r, &ev); err != nil {
+		t.Fatalf("ReadWatchEvent failed: %v", err)
+	}
+	if ev.Type != "item_get" || ev.Fields["key"] != "foo" || ev.Fields["gid"] != "12" {
+		t.Errorf("event = %+v", ev)
+	}
+	if want := time.Unix(1728000000, 123456000); !ev.Time.Equal(want) {
+		t.Errorf("Time = %v, want %v", ev.Time, want)
+	}
+
+	if err := ReadWatchEvent(r, &ev); err != nil {
+		t.Fatalf("ReadWatchEvent failed: %v", err)
+	}
+	if ev.Type != "skipped" || ev.Fields["skipped"] != "7" || len(ev.Fields) != 1 {
+		t.Errorf("event = %+v, want a skipped event with reset fields", ev)
+	}
+
+	err := ReadWatchEvent(r, &ev)
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) {
+		t.Fatalf("error = %v, want ParseError", err)
+	}
+
+	if err := ReadWatchEvent(r, &ev); !errors.Is(err, io.EOF) {
+		t.Errorf("error = %v, want io.EOF", err)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	client, server := net.Pipe()
+	go func() {
+		r := bufio.NewReader(server)
+		if _, err := r.ReadString('\n'); err != nil {
+			return
+		}
+		io.WriteString(server, "OK\r\nts=1.5 gid=1 type=item_store key=a\n")
+		server.Close()
+	}()
+
+	events, err := Watch(client, WatchMutations)
+	if err != nil {
+		t.Fatalf("Watch failed: %v", err)
+	}
+
+	ev := <-events
+	if ev.Err != nil || ev.Type != "item_store" || !ev.Time.Equal(time.Unix(1, 5e8)) {
+		t.Errorf("event = %+v", ev)
+	}
+
+	ev = <-events
+	if !errors.Is(ev.Err, io.EOF) {
+		t.Errorf("last event Err = %v, want io.EOF", ev.Err)
+	}
+	if _, ok := <-events; ok {
+		t.Error("channel must be closed after the last event")
+	}
+}
@@ -2,7 +2,9 @@ package meta
 
 import (
 	"bytes"
+	"fmt"
 	"io"
+	"net"
 	"strconv"
 	"strings"
 	"sync"
@@ -67,58 +69,23 @@ func ValidateKey(key string, hasBase64Flag bool) error {
 //   - Single write call for header reduces syscalls
 //   - Data block written directly (no buffering for large values)
 func WriteRequest(w io.Writer, req *Request) error {
-	// Get buffer from pool
-	buf := getBuffer()
-	defer putBuffer(buf)
-
-	// mn command has no key or flags
-	if req.Command == CmdNoOp {
-		buf.WriteString(string(req.Command))
-		buf.WriteString(CRLF)
-		_, err := w.Write(buf.Bytes())
-		return err
-	}
-
-	// stats command has optional args but no key or flags
-	if req.Command == CmdStats {
-		buf.WriteString(string(req.Command))
-		if req.Key != "" {
-			buf.WriteString(Space)
-			buf.WriteString(req.Key)
-		}
-		buf.WriteString(CRLF)
-		_, err := w.Write(buf.Bytes())
+	if req.wire != nil {
+		_, err := w.Write(req.wire)
 		return err
 	}
 
 	// Validate key before writing
-	hasBase64Flag := req.HasFlag(FlagBase64Key)
-	if err := ValidateKey(req.Key, hasBase64Flag); err != nil {
+	if err := validateRequest(req); err != nil {
 		return err
 	}
 
-	// Build command line in buffer
-	buf.WriteString(string(req.Command))
-	buf.WriteString(Space)
-	buf.WriteString(req.Key)
-
-	// Add size for ms command
-	if req.Command == CmdSet {
-		buf.WriteString(Space)
-		buf.WriteString(strconv.Itoa(len(req.Data)))
-	}
-
-	// Add flags.
-	// Flags already include their leading spaces.
-	if len(req.Flags) > 0 {
-		buf.Write(req.Flags)
-	}
-
-	// Add command line terminator
-	buf.WriteString(CRLF)
+	// Build command line in a pooled buffer
+	buf := getBuffer()
+	defer putBuffer(buf)
+	header := appendHeader(buf.AvailableBuffer(), req)
 
 	// Write command line
-	_, err := w.Write(buf.Bytes())
+	_, err := w.Write(header)
 	if err != nil {
 		return err
 	}
@@ -141,3 +108,177 @@ func WriteRequest(w io.Writer, req *Request) error {
 
 	return nil
 }
+
+// AppendRequest appends the wire format of a request to dst and returns the
+// extended buffer, like the strconv Append functions. It lets callers that
+// manage their own buffers serialize without an io.Writer.
+//
+// The key is validated first: on error, dst is returned unchanged.
+func AppendRequest(dst []byte, req *Request) ([]byte, error) {
+	if req.wire != nil {
+		return append(dst, req.wire...), nil
+	}
+	if err := validateRequest(req); err != nil {
+		return dst, err
+	}
+
+	dst = appendHeader(dst, req)
+	if req.Command == CmdSet {
+		dst = append(dst, req.Data...)
+		dst = append(dst, CRLF...)
+	}
+	return dst, nil
+}
+
+// WriteRequestHeader writes the command line of a request to w, declaring a
+// value of size bytes for the ms command (req.Data is ignored). Together with
+// WriteData, it streams a large value from a file or a network source to the
+// connection without holding it in memory:
+//
+//	req := meta.NewRequest(meta.CmdSet, key, nil).AddTTL(3600)
+//	if err := meta.WriteRequestHeader(w, req, size); err != nil {
+//		return err
+//	}
+//	return meta.WriteData(w, file, size)
+//
+// For other commands, size is ignored and the command line is the one written
+// by WriteRequest.
+func WriteRequestHeader(w io.Writer, req *Request, size int) error {
+	if err := validateRequest(req); err != nil {
+		return err
+	}
+	if size < 0 {
+		return fmt.Errorf("meta: negative value size: %d", size)
+	}
+
+	buf := getBuffer()
+	defer putBuffer(buf)
+
+	_, err := w.Write(appendHeaderSize(buf.AvailableBuffer(), req, size))
+	return err
+}
+
+// WriteData writes the data block following a command line written with
+// WriteRequestHeader: exactly size bytes copied from r, then the terminator.
+//
+// If r ends before size bytes, the error wraps io.ErrUnexpectedEOF. The request
+// is then partially written, and the connection must be closed.
+func WriteData(w io.Writer, r io.Reader, size int) error {
+	n, err := io.CopyN(w, r, int64(size))
+	if err != nil {
+		if err == io.EOF {
+			return fmt.Errorf("meta: value is %d bytes, declared %d: %w", n, size, io.ErrUnexpectedEOF)
+		}
+		return err
+	}
+
+	_, err = io.WriteString(w, CRLF)
+	return err
+}
+
+// vectoredDataSize is the value size from which WriteRequests passes the value
+// to the writer as is, instead of copying it into the batch buffer.
+const vectoredDataSize = 8 << 10
+
+// WriteRequests serializes a batch of requests and writes it to w at once, so
+// a pipeline costs a single write (and a single syscall on a net.Conn).
+// Values of at least 8 KiB are not copied: the batch is written as
+// net.Buffers, which uses vectored I/O (writev) on network connections.
+//
+// The returned offsets locate the requests in the written bytes: request i
+// spans offsets[i] to offsets[i+1], and offsets[len(reqs)] is the total size.
+//
+// All keys are validated before anything is written: an invalid request fails
+// the whole batch with nothing written.
+func WriteRequests(w io.Writer, reqs []*Request) (offsets []int, err error) {
+	size := 0
+	for _, req := range reqs {
+		if err := validateRequest(req); err != nil {
+			return nil, err
+		}
+		size += len(req.Key) + len(req.Flags) + 16
+		if len(req.Data) < vectoredDataSize {
+			size += len(req.Data)
+		}
+	}
+
+	offsets = make([]int, len(reqs)+1)
+	buf := make([]byte, 0, size)
+	var vecs net.Buffers
+	chunk := 0 // start of the buf bytes not yet in vecs
+
+	total := 0
+	for i, req := range reqs {
+		offsets[i] = total
+		start := len(buf)
+
+		if req.wire != nil {
+			buf = append(buf, req.wire...)
+			total += len(req.wire)
+			continue
+		}
+		buf = appendHeader(buf, req)
+		if req.Command == CmdSet {
+			if len(req.Data) >= vectoredDataSize {
+				vecs = append(vecs, buf[chunk:], req.Data)
+				chunk = len(buf)
+				total += len(req.Data)
+			} else {
+				buf = append(buf, req.Data...)
+			}
+			buf = append(buf, CRLF...)
+		}
+		total += len(buf) - start
+	}
+	offsets[len(reqs)] = total
+
+	if vecs == nil {
+		_, err = w.Write(buf)
+	} else {
+		vecs = append(vecs, buf[chunk:])
+		_, err = vecs.WriteTo(w)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return offsets, nil
+}
+
+// validateRequest validates the key of a request, when it has one.
+func validateRequest(req *Request) error {
+	if req.Command == CmdNoOp || isTextCommand(req.Command) {
+		return nil
+	}
+	return ValidateKey(req.Key, req.HasFlag(FlagBase64Key))
+}
+
+// appendHeader appends the command line of a request to dst, without
+// validating it.
+func appendHeader(dst []byte, req *Request) []byte {
+	return appendHeaderSize(dst, req, len(req.Data))
+}
+
+// appendHeaderSize is appendHeader with an explicit ms value size.
+func appendHeaderSize(dst []byte, req *Request, size int) []byte {
+	if req.Command == CmdNoOp {
+		return append(dst, string(req.Command)+CRLF...)
+	}
+	if isTextCommand(req.Command) {
+		dst = append(dst, req.Command...)
+		if req.Key != "" {
+			dst = append(dst, Space...)
+			dst = append(dst, req.Key...)
+		}
+		return append(dst, CRLF...)
+	}
+
+	dst = append(dst, req.Command...)
+	dst = append(dst, Space...)
+	dst = append(dst, req.Key...)
+	if req.Command == CmdSet {
+		dst = append(dst, Space...)
+		dst = strconv.AppendInt(dst, int64(size), 10)
+	}
+	dst = append(dst, req.Flags...) // flags include their leading spaces
+	return append(dst, CRLF...)
+}
@@ -3,6 +3,7 @@ package meta
 import (
 	"bytes"
 	"errors"
+	"io"
 	"strings"
 	"testing"
 )
@@ -116,3 +117,233 @@ func TestResponse_TypedGetters_InvalidTokens(t *testing.T) {
 		t.Error("ClientFlags with negative token must return false")
 	}
 }
+
+func TestWriteRequests(t *testing.T) {
+	large := bytes.Repeat([]byte("x"), vectoredDataSize)
+	reqs := []*Request{
+		NewRequest(CmdGet, "key1", nil).AddReturnValue(),
+		NewRequest(CmdSet, "key2", []byte("value")).AddTTL(60),
+		NewRequest(CmdSet, "key3", large),
+		NewRequest(CmdDelete, "key4", nil),
+		NewRequest(CmdNoOp, "", nil),
+	}
+
+	// Same bytes as WriteRequest, request by request.
+	var want bytes.Buffer
+	wantOffsets := []int{0}
+	for _, req := range reqs {
+		if err := WriteRequest(&want, req); err != nil {
+			t.Fatalf("WriteRequest failed: %v", err)
+		}
+		wantOffsets = append(wantOffsets, want.Len())
+	}
+
+	var got bytes.Buffer
+	offsets, err := WriteRequests(&got, reqs)
+	if err != nil {
+		t.Fatalf("WriteRequests failed: %v", err)
+	}
+	if got.String() != want.String() {
+		t.Errorf("wire = %q, want %q", got.String(), want.String())
+	}
+	if len(offsets) != len(wantOffsets) {
+		t.Fatalf("offsets = %v, want %v", offsets, wantOffsets)
+	}
+	for i := range offsets {
+		if offsets[i] != wantOffsets[i] {
+			t.Errorf("offsets = %v, want %v", offsets, wantOffsets)
+			break
+		}
+	}
+}
+
+func TestWriteRequests_SingleWrite(t *testing.T) {
+	w := &countingWriter{}
+	reqs := []*Request{
+		NewRequest(CmdGet, "key1", nil),
+		NewRequest(CmdSet, "key2", []byte("value")),
+		NewRequest(CmdNoOp, "", nil),
+	}
+	if _, err := WriteRequests(w, reqs); err != nil {
+		t.Fatalf("WriteRequests failed: %v", err)
+	}
+	if w.writes != 1 {
+		t.Errorf("writes = %d, want 1", w.writes)
+	}
+}
+
+func TestWriteRequests_InvalidKeyWritesNothing(t *testing.T) {
+	var buf bytes.Buffer
+	reqs := []*Request{
+		NewRequest(CmdGet, "key1", nil),
+		NewRequest(CmdGet, "bad key", nil),
+	}
+
+	_, err := WriteRequests(&buf, reqs)
+
+	var keyErr *InvalidKeyError
+	if !errors.As(err, &keyErr) {
+		t.Fatalf("WriteRequests error = %v, want InvalidKeyError", err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("wrote %q, want nothing", buf.String())
+	}
+}
+
+type countingWriter struct {
+	writes int
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.writes++
+	return len(p), nil
+}
+
+func TestAppendRequest(t *testing.T) {
+	reqs := []*Request{
+		NewRequest(CmdGet, "key1", nil).AddReturnValue().AddReturnCAS(),
+		NewRequest(CmdSet, "key2", []byte("value")).AddTTL(60),
+		NewRequest(CmdSet, "key3", nil),
+		NewRequest(CmdNoOp, "", nil),
+		{Command: CmdStats, Key: "slabs"},
+	}
+
+	for _, req := range reqs {
+		var want bytes.Buffer
+		if err := WriteRequest(&want, req); err != nil {
+			t.Fatalf("WriteRequest failed: %v", err)
+		}
+
+		got, err := AppendRequest([]byte("prefix"), req)
+		if err != nil {
+			t.Fatalf("AppendRequest failed: %v", err)
+		}
+		if string(got) != "prefix"+want.String() {
+			t.Errorf("AppendRequest = %q, want %q", got, "prefix"+want.String())
+		}
+	}
+}
+
+func TestAppendRequest_InvalidKey(t *testing.T) {
+	dst := []byte("prefix")
+	got, err := AppendRequest(dst, NewRequest(CmdGet, "bad key", nil))
+
+	var keyErr *InvalidKeyError
+	if !errors.As(err, &keyErr) {
+		t.Fatalf("AppendRequest error = %v, want InvalidKeyError", err)
+	}
+	if string(got) != "prefix" {
+		t.Errorf("AppendRequest = %q, want dst unchanged", got)
+	}
+}
+
+func TestAppendRequest_NoAllocs(t *testing.T) {
+	req := NewRequest(CmdSet, "mykey", []byte("value")).AddTTL(60).AddClientFlags(30)
+	dst := make([]byte, 0, 256)
+
+	allocs := testing.AllocsPerRun(100, func() {
+		var err error
+		if dst, err = AppendRequest(dst[:0], req); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if allocs != 0 {
+		t.Errorf("AppendRequest allocated %v times per run, want 0", allocs)
+	}
+}
+
+func TestRequest_Prepare(t *testing.T) {
+	reqs := []*Request{
+		NewRequest(CmdGet, "key1", nil).AddReturnValue().AddReturnCAS(),
+		NewRequest(CmdSet, "key2", []byte("value")).AddTTL(60),
+	}
+
+	var want bytes.Buffer
+	if _, err := WriteRequests(&want, reqs); err != nil {
+		t.Fatalf("WriteRequests failed: %v", err)
+	}
+
+	for _, req := range reqs {
+		if err := req.Prepare(); err != nil {
+			t.Fatalf("Prepare failed: %v", err)
+		}
+	}
+	var got bytes.Buffer
+	for _, req := range reqs {
+		if err := WriteRequest(&got, req); err != nil {
+			t.Fatalf("WriteRequest failed: %v", err)
+		}
+	}
+	if got.String() != want.String() {
+		t.Errorf("WriteRequest = %q, want %q", got.String(), want.String())
+	}
+
+	got.Reset()
+	offsets, err := WriteRequests(&got, reqs)
+	if err != nil {
+		t.Fatalf("WriteRequests failed: %v", err)
+	}
+	if got.String() != want.String() || offsets[len(reqs)] != want.Len() {
+		t.Errorf("WriteRequests = %q (%v), want %q", got.String(), offsets, want.String())
+	}
+
+	appended, err := AppendRequest(nil, reqs[0])
+	if err != nil || string(appended) != "mg key1 v c\r\n" {
+		t.Errorf("AppendRequest = %q, %v", appended, err)
+	}
+}
+
+func TestRequest_Prepare_InvalidKey(t *testing.T) {
+	var keyErr *InvalidKeyError
+	if err := NewRequest(CmdGet, "bad key", nil).Prepare(); !errors.As(err, &keyErr) {
+		t.Fatalf("Prepare error = %v, want InvalidKeyError", err)
+	}
+}
+
+func TestWriteRequestHeader_Streaming(t *testing.T) {
+	value := strings.Repeat("v", 10000)
+	req := NewRequest(CmdSet, "key", nil).AddTTL(60)
+
+	var got bytes.Buffer
+	if err := WriteRequestHeader(&got, req, len(value)); err != nil {
+		t.Fatalf("WriteRequestHeader failed: %v", err)
+	}
+	if err := WriteData(&got, strings.NewReader(value), len(value)); err != nil {
+		t.Fatalf("WriteData failed: %v", err)
+	}
+
+	// Same bytes as WriteRequest with the value in memory.
+	var want bytes.Buffer
+	if err := WriteRequest(&want, NewRequest(CmdSet, "key", []byte(value)).AddTTL(60)); err != nil {
+		t.Fatalf("WriteRequest failed: %v", err)
+	}
+	if got.String() != want.String() {
+		t.Errorf("streamed request differs from WriteRequest: %q", got.String()[:40])
+	}
+}
+
+func TestWriteData_ShortReader(t *testing.T) {
+	var buf bytes.Buffer
+	err := WriteData(&buf, strings.NewReader("abc"), 5)
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("WriteData error = %v, want io.ErrUnexpectedEOF", err)
+	}
+	if !ShouldCloseConnection(err) {
+		t.Error("a partially written request must close the connection")
+	}
+}
+
+func TestWriteRequestHeader_Invalid(t *testing.T) {
+	var buf bytes.Buffer
+
+	var keyErr *InvalidKeyError
+	if err := WriteRequestHeader(&buf, NewRequest(CmdSet, "bad key", nil), 1); !errors.As(err, &keyErr) {
+		t.Errorf("WriteRequestHeader error = %v, want InvalidKeyError", err)
+	}
+	if err := WriteRequestHeader(&buf, NewRequest(CmdSet, "key", nil), -1); err == nil {
+		t.Error("WriteRequestHeader should reject a negative size")
+	}
+	if buf.Len() != 0 {
+		t.Errorf("wrote %q, want nothing", buf.String())
+	}
+}
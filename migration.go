@@ -0,0 +1,124 @@
+package memcache
+
+import (
+	"bytes"
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/pior/memcache/meta"
+)
+
+// serverMigration tracks the changes of the server list for the dual-read
+// migration mode (Config.MigrationWindow).
+type serverMigration struct {
+	mu       sync.Mutex
+	current  []string
+	previous []string  // the list before the last change
+	until    time.Time // end of the migration window of the last change
+}
+
+// observe records servers as the current server list. A change opens a
+// migration window of duration window.
+func (m *serverMigration) observe(servers []string, window time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	switch {
+	case m.current == nil:
+		m.current = slices.Clone(servers)
+	case !slices.Equal(m.current, servers):
+		m.previous = m.current
+		m.current = slices.Clone(servers)
+		m.until = time.Now().Add(window)
+	}
+}
+
+// previousServers returns the server list before the last change while its
+// migration window is open, nil otherwise.
+func (m *serverMigration) previousServers() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.previous == nil || time.Now().After(m.until) {
+		return nil
+	}
+	return m.previous
+}
+
+// isMigrationRead reports whether req is a get that can be read from the
+// previous placement of its key: a plain mg, without the flags changing the
+// item on a miss (vivify) or hiding the misses (quiet).
+func isMigrationRead(req *meta.Request) bool {
+	return req.Command == meta.CmdGet &&
+		!req.HasFlag(meta.FlagVivify) &&
+		!req.HasFlag(meta.FlagRecache) &&
+		!req.HasFlag(meta.FlagQuiet)
+}
+
+// executeMigrationRead executes a get on sp, the server of its key, and on a
+// miss during a migration window, on the server of the key in the previous
+// server list. A hit there is returned, and copied to sp by an async add
+// (see backfill).
+//
+// The previous server is best-effort: when it fails, the miss is returned.
+func (c *Client) executeMigrationRead(ctx context.Context, sp *ServerPool, req *meta.Request) (*meta.Response, error) {
+	resp, err := sp.Execute(ctx, req)
+	if err != nil || !resp.IsMiss() {
+		return resp, err
+	}
+
+	previous := c.migration.previousServers()
+	if previous == nil {
+		return resp, nil
+	}
+	addr, err := c.selectServer(req.Key, previous)
+	if err != nil || addr == sp.addr {
+		return resp, nil
+	}
+	oldPool, err := c.getPoolForServer(addr)
+	if err != nil {
+		return resp, nil
+	}
+
+	// The backfill needs the value, client flags and remaining TTL.
+	oldReq := &meta.Request{Command: req.Command, Key: req.Key, Flags: req.Flags.Clone()}
+	for _, flag := range []meta.FlagType{meta.FlagReturnValue, meta.FlagReturnClientFlags, meta.FlagReturnTTL} {
+		if !oldReq.HasFlag(flag) {
+			oldReq.Flags.Add(flag)
+		}
+	}
+
+	oldResp, err := oldPool.Execute(ctx, oldReq)
+	if err != nil || oldResp.HasError() || !oldResp.IsSuccess() {
+		return resp, nil
+	}
+
+	c.backfill(ctx, req, oldResp)
+	return oldResp, nil
+}
+
+// backfill queues the async add of an item read from the previous placement
+// of its key to its current placement. The add mode never overwrites an item
+// written there in the meantime. The failures are reported to
+// Config.AsyncErrorHandler, as for SetAsync; a full queue skips the backfill.
+func (c *Client) backfill(ctx context.Context, req *meta.Request, resp *meta.Response) {
+	remaining, hasTTL := resp.GetFlagDuration(meta.FlagReturnTTL)
+	if hasTTL && remaining == 0 {
+		return // expiring: T0 would store it without expiration
+	}
+
+	// The value is returned to the caller too: the add gets its own copy.
+	add := meta.NewRequest(meta.CmdSet, req.Key, bytes.Clone(resp.Data)).AddModeAdd()
+	if req.HasFlag(meta.FlagBase64Key) {
+		add.AddBase64Key()
+	}
+	if flags, _ := resp.ClientFlags(); flags != 0 {
+		add.AddClientFlags(flags)
+	}
+	if hasTTL && remaining > 0 { // -1 means no expiration
+		add.AddTTL(int(remaining / time.Second))
+	}
+	_ = c.enqueueAsync(ctx, asyncWrite{op: "add", req: add})
+}
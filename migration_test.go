@@ -0,0 +1,103 @@
+package memcache_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pior/memcache"
+	"github.com/pior/memcache/memcachetest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mutableServers is a Servers whose list can be changed.
+type mutableServers struct {
+	mu    sync.Mutex
+	addrs []string
+}
+
+func (s *mutableServers) List() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.addrs
+}
+
+func (s *mutableServers) Set(addrs ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.addrs = addrs
+}
+
+// movedKey returns a key placed on the second of two servers.
+func movedKey(t *testing.T) string {
+	for i := range 100 {
+		key := fmt.Sprintf("key-%d", i)
+		if memcache.DefaultServerSelector(key, 2) == 1 {
+			return key
+		}
+	}
+	t.Fatal("no key placed on the second server")
+	return ""
+}
+
+func TestClient_MigrationWindow(t *testing.T) {
+	ctx := context.Background()
+	key := movedKey(t)
+
+	setup := func(t *testing.T, window time.Duration) (*memcache.Client, *memcache.Client) {
+		oldSrv, newSrv := memcachetest.NewServer(t), memcachetest.NewServer(t)
+		servers := &mutableServers{addrs: []string{oldSrv.Addr}}
+		client := memcache.NewClient(servers, memcache.Config{MigrationWindow: window})
+		t.Cleanup(client.Close)
+
+		require.NoError(t, client.Set(ctx, memcache.Item{Key: key, Value: []byte("value"), Flags: 7, TTL: memcache.ExpiresIn(time.Minute)}))
+		servers.Set(oldSrv.Addr, newSrv.Addr)
+
+		direct := memcache.NewClient(memcache.StaticServers(newSrv.Addr), memcache.Config{})
+		t.Cleanup(direct.Close)
+		return client, direct
+	}
+
+	t.Run("reads the previous placement and backfills", func(t *testing.T) {
+		client, newServer := setup(t, time.Minute)
+
+		item, err := client.Get(ctx, key)
+		require.NoError(t, err)
+		assert.True(t, item.Found)
+		assert.Equal(t, "value", string(item.Value))
+		assert.Equal(t, uint32(7), item.Flags)
+
+		require.Eventually(t, func() bool {
+			item, err := newServer.GetWithOptions(ctx, key, memcache.GetOptions{ReturnTTL: true})
+			return err == nil && item.Found
+		}, time.Second, time.Millisecond, "the item should be copied to its new server")
+
+		item, err = newServer.GetWithOptions(ctx, key, memcache.GetOptions{ReturnTTL: true})
+		require.NoError(t, err)
+		assert.Equal(t, "value", string(item.Value))
+		assert.Equal(t, uint32(7), item.Flags)
+		assert.NotEqual(t, memcache.NoTTL, item.TTL, "the TTL should be kept")
+	})
+
+	t.Run("after the window", func(t *testing.T) {
+		client, _ := setup(t, time.Millisecond)
+		_, err := client.Get(ctx, "other") // observes the change
+		require.NoError(t, err)
+		time.Sleep(5 * time.Millisecond)
+
+		item, err := client.Get(ctx, key)
+		require.NoError(t, err)
+		assert.False(t, item.Found)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		client, _ := setup(t, 0)
+
+		item, err := client.Get(ctx, key)
+		require.NoError(t, err)
+		assert.False(t, item.Found)
+	})
+}
@@ -0,0 +1,184 @@
+package memcache
+
+import (
+	"context"
+	"math/bits"
+	"sync/atomic"
+	"time"
+
+	"github.com/pior/memcache/meta"
+)
+
+// OpMetrics contains per-operation statistics of a server pool, collected
+// when Config.CollectOpMetrics is enabled.
+type OpMetrics struct {
+	// Latency is the latency distribution by operation: a meta protocol
+	// command code ("mg", "ms", ...) or one of the Op* constants.
+	// Operations that never ran are omitted.
+	Latency map[string]LatencyMetrics
+
+	// Hits and Misses count the get requests (mg) that found or missed
+	// their key, including those sent in batches.
+	Hits   uint64
+	Misses uint64
+}
+
+// HitRatio returns the ratio of get requests that found their key, or 0 when
+// no get request completed.
+func (m OpMetrics) HitRatio() float64 {
+	total := m.Hits + m.Misses
+	if total == 0 {
+		return 0
+	}
+	return float64(m.Hits) / float64(total)
+}
+
+// LatencyMetrics summarizes a latency distribution. The percentiles are
+// estimated from a log-linear histogram, within 12.5% of the actual values.
+type LatencyMetrics struct {
+	Count uint64
+	P50   time.Duration
+	P90   time.Duration
+	P99   time.Duration
+	Max   time.Duration
+}
+
+// opMetricsOps are the operations tracked by opMetricsHook.
+var opMetricsOps = []string{
+	string(meta.CmdGet), string(meta.CmdSet), string(meta.CmdDelete), string(meta.CmdArithmetic),
+	string(meta.CmdDebug), string(meta.CmdNoOp), OpBatch, OpStats,
+}
+
+// opMetricsHook is a Hook recording the latency and hit ratio of the
+// operations of a single server pool.
+type opMetricsHook struct {
+	latency map[string]*latencyHistogram // read-only after creation
+	hits    atomic.Uint64
+	misses  atomic.Uint64
+}
+
+func newOpMetricsHook() *opMetricsHook {
+	h := &opMetricsHook{latency: make(map[string]*latencyHistogram, len(opMetricsOps))}
+	for _, op := range opMetricsOps {
+		h.latency[op] = &latencyHistogram{}
+	}
+	return h
+}
+
+func (h *opMetricsHook) BeforeOp(ctx context.Context, op OpInfo) context.Context {
+	return ctx
+}
+
+func (h *opMetricsHook) AfterOp(ctx context.Context, op OpInfo, result OpResult) {
+	if hist, ok := h.latency[op.Op]; ok {
+		hist.record(result.Duration)
+	}
+
+	for i, resp := range result.Responses {
+		if i >= len(op.Requests) || op.Requests[i].Command != meta.CmdGet || resp.Error != nil {
+			continue
+		}
+		if resp.IsMiss() {
+			h.misses.Add(1)
+		} else if resp.IsSuccess() {
+			h.hits.Add(1)
+		}
+	}
+}
+
+func (h *opMetricsHook) snapshot() OpMetrics {
+	m := OpMetrics{
+		Latency: make(map[string]LatencyMetrics),
+		Hits:    h.hits.Load(),
+		Misses:  h.misses.Load(),
+	}
+	for op, hist := range h.latency {
+		if lm := hist.snapshot(); lm.Count > 0 {
+			m.Latency[op] = lm
+		}
+	}
+	return m
+}
+
+// Log-linear histogram: each power of two is split in 1<<histSubBits linear
+// sub-buckets, bounding the relative error to 1/(1<<histSubBits). Durations
+// are recorded in nanoseconds, clamped to histMaxValue (~18 minutes).
+const (
+	histSubBits  = 3
+	histMaxBits  = 40
+	histMaxValue = 1<<histMaxBits - 1
+	histBuckets  = (histMaxBits - histSubBits + 1) << histSubBits
+)
+
+// latencyHistogram is a lock-free latency histogram.
+type latencyHistogram struct {
+	counts [histBuckets]atomic.Uint64
+	max    atomic.Int64
+}
+
+func histBucket(v uint64) int {
+	if v < 1<<histSubBits {
+		return int(v)
+	}
+	exp := bits.Len64(v) - 1 - histSubBits
+	mantissa := int(v>>exp) & (1<<histSubBits - 1)
+	return (exp+1)<<histSubBits + mantissa
+}
+
+// histBucketValue returns the middle of the range covered by a bucket.
+func histBucketValue(idx int) uint64 {
+	if idx < 1<<histSubBits {
+		return uint64(idx)
+	}
+	exp := idx>>histSubBits - 1
+	mantissa := uint64(idx & (1<<histSubBits - 1))
+	lower := (1<<histSubBits + mantissa) << exp
+	return lower + (uint64(1)<<exp)/2
+}
+
+func (h *latencyHistogram) record(d time.Duration) {
+	v := max(d.Nanoseconds(), 0)
+	h.counts[histBucket(uint64(min(v, histMaxValue)))].Add(1)
+
+	for {
+		current := h.max.Load()
+		if v <= current || h.max.CompareAndSwap(current, v) {
+			return
+		}
+	}
+}
+
+func (h *latencyHistogram) snapshot() LatencyMetrics {
+	var counts [histBuckets]uint64
+	var total uint64
+	for i := range h.counts {
+		counts[i] = h.counts[i].Load()
+		total += counts[i]
+	}
+	if total == 0 {
+		return LatencyMetrics{}
+	}
+
+	maxValue := time.Duration(h.max.Load())
+
+	// The bucket middle can exceed the largest recorded value: clamp to it.
+	quantile := func(q float64) time.Duration {
+		rank := uint64(q * float64(total))
+		var seen uint64
+		for i, c := range counts {
+			seen += c
+			if seen > rank {
+				return min(time.Duration(histBucketValue(i)), maxValue)
+			}
+		}
+		return maxValue
+	}
+
+	return LatencyMetrics{
+		Count: total,
+		P50:   quantile(0.50),
+		P90:   quantile(0.90),
+		P99:   quantile(0.99),
+		Max:   maxValue,
+	}
+}
@@ -0,0 +1,87 @@
+package memcache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pior/memcache/internal/testutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHistBucket(t *testing.T) {
+	// Buckets are contiguous and increasing, and every value falls within
+	// 1/8 of its bucket value.
+	prev := 0
+	for v := uint64(1); v < 1<<20; v = v*9/8 + 1 {
+		idx := histBucket(v)
+		require.GreaterOrEqual(t, idx, prev, "value %d", v)
+		prev = idx
+
+		got := float64(histBucketValue(idx))
+		assert.InEpsilon(t, float64(v), got, 0.125, "value %d", v)
+	}
+
+	assert.Less(t, histBucket(histMaxValue), histBuckets)
+}
+
+func TestLatencyHistogram(t *testing.T) {
+	var h latencyHistogram
+	assert.Equal(t, LatencyMetrics{}, h.snapshot())
+
+	for i := 1; i <= 100; i++ {
+		h.record(time.Duration(i) * time.Millisecond)
+	}
+	h.record(time.Hour) // beyond the histogram range
+
+	lm := h.snapshot()
+	assert.Equal(t, uint64(101), lm.Count)
+	assert.InEpsilon(t, 50*time.Millisecond, lm.P50, 0.125)
+	assert.InEpsilon(t, 90*time.Millisecond, lm.P90, 0.125)
+	assert.InEpsilon(t, 100*time.Millisecond, lm.P99, 0.125)
+	assert.Equal(t, time.Hour, lm.Max)
+}
+
+func TestClient_CollectOpMetrics(t *testing.T) {
+	mockConn := testutils.NewConnectionMock("VA 1\r\nv\r\n", "EN\r\n", "HD\r\n", "VA 1\r\nv\r\n", "EN\r\n", "MN\r\n")
+	client := NewClient(StaticServers("localhost:11211"), Config{
+		Dialer:           &mockDialer{conn: mockConn},
+		CollectOpMetrics: true,
+	})
+	t.Cleanup(client.Close)
+
+	ctx := context.Background()
+	_, err := client.Get(ctx, "key1")
+	require.NoError(t, err)
+	_, err = client.Get(ctx, "key2")
+	require.NoError(t, err)
+	require.NoError(t, client.Set(ctx, Item{Key: "key3", Value: []byte("v")}))
+	_, err = NewBatchCommands(client).MultiGet(ctx, []string{"key1", "key2"})
+	require.NoError(t, err)
+
+	metrics := client.PoolMetrics()
+	require.Len(t, metrics, 1)
+	ops := metrics[0].Ops
+
+	assert.Equal(t, uint64(2), ops.Latency["mg"].Count)
+	assert.Equal(t, uint64(1), ops.Latency["ms"].Count)
+	assert.Equal(t, uint64(1), ops.Latency[OpBatch].Count)
+	assert.NotContains(t, ops.Latency, "md")
+
+	assert.Equal(t, uint64(2), ops.Hits)
+	assert.Equal(t, uint64(2), ops.Misses)
+	assert.InDelta(t, 0.5, ops.HitRatio(), 0.001)
+}
+
+func TestClient_OpMetricsDisabled(t *testing.T) {
+	client := newTestClient(t, testutils.NewConnectionMock("EN\r\n"))
+
+	_, err := client.Get(context.Background(), "key1")
+	require.NoError(t, err)
+
+	metrics := client.PoolMetrics()
+	require.Len(t, metrics, 1)
+	assert.Empty(t, metrics[0].Ops.Latency)
+	assert.Zero(t, metrics[0].Ops.HitRatio())
+}
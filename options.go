@@ -0,0 +1,115 @@
+package memcache
+
+import (
+	"context"
+	"time"
+
+	"github.com/pior/memcache/meta"
+)
+
+// CallOption overrides the client configuration for a single call, e.g.
+//
+//	item, err := client.Get(ctx, key, memcache.WithTimeout(50*time.Millisecond), memcache.WithNoLRUBump())
+type CallOption func(*callOptions)
+
+type callOptions struct {
+	timeout   time.Duration
+	noLRUBump bool
+	priority  Priority
+	batch     *BatchOptions
+	session   *WriteSession
+}
+
+// WithTimeout replaces Config.Timeout for the call: it is the per-operation
+// bound on the network I/O, capping the context deadline as Config.Timeout
+// does. Unlike a context deadline, it can be longer than Config.Timeout.
+func WithTimeout(d time.Duration) CallOption {
+	return func(o *callOptions) { o.timeout = d }
+}
+
+// WithNoLRUBump fetches items without bumping them in the LRU (u flag), so a
+// scan or a background refresh doesn't keep cold items in the cache.
+// It applies to Get, GetWithOptions and MultiGet, and is ignored by the other
+// commands.
+func WithNoLRUBump() CallOption {
+	return func(o *callOptions) { o.noLRUBump = true }
+}
+
+// WithBatchOptions tunes the execution of a batch (MultiGet, MultiSet,
+// MultiDelete) by the Client, as ExecuteBatchWithOptions does. It is ignored
+// by the other commands.
+func WithBatchOptions(opts BatchOptions) CallOption {
+	return func(o *callOptions) { o.batch = &opts }
+}
+
+// applyCallOptions applies opts to the requests of a call, and returns the
+// context carrying the overrides read by the connection.
+func applyCallOptions(ctx context.Context, reqs []*meta.Request, opts []CallOption) context.Context {
+	if len(opts) == 0 {
+		return ctx
+	}
+
+	var o callOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if o.noLRUBump {
+		for _, req := range reqs {
+			if req.Command == meta.CmdGet && !req.HasFlag(meta.FlagNoLRUBump) {
+				req.AddNoLRUBump()
+			}
+		}
+	}
+	if o.timeout > 0 {
+		ctx = context.WithValue(ctx, timeoutKey{}, o.timeout)
+	}
+	if o.priority != PriorityNormal {
+		ctx = context.WithValue(ctx, priorityKey{}, o.priority)
+	}
+	if o.batch != nil {
+		ctx = context.WithValue(ctx, batchOptionsKey{}, *o.batch)
+	}
+	if o.session != nil {
+		for _, req := range reqs {
+			if o.session.tracks(req) && o.session.needsCAS(req) && !req.HasFlag(meta.FlagReturnCAS) {
+				req.AddReturnCAS()
+			}
+		}
+		ctx = context.WithValue(ctx, writeSessionKey{}, o.session)
+	}
+	return ctx
+}
+
+// batchOptionsKey is the context key of the options set by WithBatchOptions.
+type batchOptionsKey struct{}
+
+// batchOptions returns the options set for the batch with WithBatchOptions.
+func batchOptions(ctx context.Context) BatchOptions {
+	opts, _ := ctx.Value(batchOptionsKey{}).(BatchOptions)
+	return opts
+}
+
+// timeoutKey is the context key of the timeout set by WithTimeout, or by the
+// timeout class of the operation (Config.ReadTimeout, ...).
+type timeoutKey struct{}
+
+// withDefaultTimeout returns ctx carrying the timeout d, unless it is zero or
+// a timeout was already set for the call with WithTimeout.
+func withDefaultTimeout(ctx context.Context, d time.Duration) context.Context {
+	if d <= 0 {
+		return ctx
+	}
+	if _, ok := ctx.Value(timeoutKey{}).(time.Duration); ok {
+		return ctx
+	}
+	return context.WithValue(ctx, timeoutKey{}, d)
+}
+
+// callTimeout returns the timeout set for the call, or defaultTimeout.
+func callTimeout(ctx context.Context, defaultTimeout time.Duration) time.Duration {
+	if d, ok := ctx.Value(timeoutKey{}).(time.Duration); ok {
+		return d
+	}
+	return defaultTimeout
+}
@@ -0,0 +1,71 @@
+package memcache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pior/memcache/internal/testutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCallOptions_NoLRUBump(t *testing.T) {
+	t.Run("get", func(t *testing.T) {
+		mockConn := testutils.NewConnectionMock("VA 5\r\nhello\r\n")
+		client := newTestClient(t, mockConn)
+
+		_, err := client.Get(context.Background(), "key", WithNoLRUBump())
+
+		require.NoError(t, err)
+		assertRequest(t, mockConn, "mg key v f u\r\n")
+	})
+
+	t.Run("multi get", func(t *testing.T) {
+		bc, mock := newBatchTestClient(t, "EN\r\n", "EN\r\n", "MN\r\n")
+
+		_, err := bc.MultiGet(context.Background(), []string{"k1", "k2"}, WithNoLRUBump())
+
+		require.NoError(t, err)
+		assert.Equal(t, "mg k1 v f u\r\nmg k2 v f u\r\nmn\r\n", mock.GetWrittenRequest())
+	})
+
+	t.Run("ignored by set", func(t *testing.T) {
+		mockConn := testutils.NewConnectionMock("HD\r\n")
+		client := newTestClient(t, mockConn)
+
+		err := client.Set(context.Background(), Item{Key: "key", Value: []byte("v")}, WithNoLRUBump())
+
+		require.NoError(t, err)
+		assertRequest(t, mockConn, "ms key 1\r\nv\r\n")
+	})
+}
+
+func TestCallOptions_Timeout(t *testing.T) {
+	conn, _ := newMockConnection()
+
+	t.Run("default", func(t *testing.T) {
+		deadline, err := conn.setDeadline(context.Background())
+		require.NoError(t, err)
+		assert.WithinDuration(t, time.Now().Add(time.Second), deadline, 100*time.Millisecond)
+	})
+
+	t.Run("longer than the default", func(t *testing.T) {
+		ctx := applyCallOptions(context.Background(), nil, []CallOption{WithTimeout(time.Minute)})
+
+		deadline, err := conn.setDeadline(ctx)
+		require.NoError(t, err)
+		assert.WithinDuration(t, time.Now().Add(time.Minute), deadline, 100*time.Millisecond)
+	})
+
+	t.Run("capped by the context deadline", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		ctx = applyCallOptions(ctx, nil, []CallOption{WithTimeout(time.Minute)})
+
+		deadline, err := conn.setDeadline(ctx)
+		require.NoError(t, err)
+		ctxDeadline, _ := ctx.Deadline()
+		assert.Equal(t, ctxDeadline, deadline)
+	})
+}
@@ -0,0 +1,481 @@
+package memcache
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pior/memcache/meta"
+)
+
+// errQuietPipelined rejects quiet requests in pipelined mode: a suppressed
+// response would shift the responses of all the operations that follow.
+var errQuietPipelined = errors.New("memcache: quiet flag is not supported in pipelined mode: responses are matched to requests by position")
+
+// pipelineSet holds the connections of a server in pipelined mode
+// (Config.PipelineConns). Operations are spread over them round-robin.
+type pipelineSet struct {
+	dial  func(ctx context.Context) (*Connection, error)
+	slots []pipelineSlot
+	next  atomic.Uint32
+
+	created   atomic.Uint64
+	destroyed atomic.Uint64
+}
+
+// pipelineSlot holds one connection of a pipelineSet. The connection is
+// established on first use, and again after it broke.
+type pipelineSlot struct {
+	mu     sync.Mutex
+	conn   *pipelineConn
+	closed bool
+}
+
+func newPipelineSet(size int32, dial func(ctx context.Context) (*Connection, error)) *pipelineSet {
+	return &pipelineSet{
+		dial:  dial,
+		slots: make([]pipelineSlot, size),
+	}
+}
+
+// get returns a connection, establishing it if needed.
+func (s *pipelineSet) get(ctx context.Context) (*pipelineConn, error) {
+	slot := &s.slots[s.next.Add(1)%uint32(len(s.slots))]
+
+	slot.mu.Lock()
+	defer slot.mu.Unlock()
+
+	if slot.closed {
+		return nil, ErrPoolClosed
+	}
+	if slot.conn != nil && slot.conn.broken() == nil {
+		return slot.conn, nil
+	}
+	if slot.conn != nil {
+		s.destroyed.Add(1)
+		slot.conn = nil
+	}
+
+	conn, err := s.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s.created.Add(1)
+
+	slot.conn = newPipelineConn(conn)
+	return slot.conn, nil
+}
+
+func (s *pipelineSet) close() {
+	for i := range s.slots {
+		slot := &s.slots[i]
+		slot.mu.Lock()
+		slot.closed = true
+		if slot.conn != nil {
+			slot.conn.fail(ErrPoolClosed)
+			slot.conn = nil
+			s.destroyed.Add(1)
+		}
+		slot.mu.Unlock()
+	}
+}
+
+func (s *pipelineSet) metrics() ConnPoolMetrics {
+	var live int32
+	for i := range s.slots {
+		slot := &s.slots[i]
+		slot.mu.Lock()
+		if slot.conn != nil && slot.conn.broken() == nil {
+			live++
+		}
+		slot.mu.Unlock()
+	}
+	return ConnPoolMetrics{
+		CreatedConns:   s.created.Load(),
+		DestroyedConns: s.destroyed.Load(),
+		TotalConns:     live,
+		ActiveConns:    live,
+	}
+}
+
+func (s *pipelineSet) execute(ctx context.Context, req *meta.Request) (*meta.Response, error) {
+	conn, err := s.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return conn.execute(ctx, req)
+}
+
+func (s *pipelineSet) executeBatch(ctx context.Context, reqs []*meta.Request) ([]*meta.Response, error) {
+	conn, err := s.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return conn.executeBatch(ctx, reqs)
+}
+
+func (s *pipelineSet) executeStats(ctx context.Context, args ...string) (map[string]string, error) {
+	conn, err := s.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return conn.executeStats(ctx, args...)
+}
+
+// pipelineConn multiplexes operations over a single connection. Requests are
+// written as they are issued, and a reader goroutine matches the responses to
+// them in order: memcached answers the requests of a connection in the order
+// it receives them.
+//
+// Any I/O or parse error breaks the connection: the operations in flight fail
+// with that error.
+type pipelineConn struct {
+	conn *Connection
+
+	writeMu sync.Mutex // serializes the writes, and so the order of the queue
+
+	mu           sync.Mutex
+	queue        []*pipelineCall // operations waiting for their responses, in order
+	err          error           // set when the connection is broken
+	notify       chan struct{}   // wakes up the reader when queue or err changes
+	readDeadline time.Time       // of the read in progress, zero for none
+}
+
+// pipelineCall is an operation waiting for its responses.
+type pipelineCall struct {
+	// read reads the responses of the operation. It returns false when the
+	// connection can't be reused after them (e.g. after a CLIENT_ERROR).
+	read     func(r *bufio.Reader) (reusable bool, err error)
+	timeout  time.Duration // Timeout, or the one set with WithTimeout
+	deadline time.Time     // of the responses, zero for none (see sendDeadline)
+	done     chan error
+}
+
+func newPipelineConn(conn *Connection) *pipelineConn {
+	// The reader goroutine may still use the buffers when the connection is
+	// closed: they can't be recycled.
+	conn.maxRetainedBuffer = 0
+
+	p := &pipelineConn{
+		conn:   conn,
+		notify: make(chan struct{}, 1),
+	}
+	go p.readLoop()
+	return p
+}
+
+// broken returns the error that broke the connection, or nil.
+func (p *pipelineConn) broken() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.err
+}
+
+// fail breaks the connection: it is closed and the operations in flight fail
+// with err.
+func (p *pipelineConn) fail(err error) {
+	p.mu.Lock()
+	if p.err != nil {
+		p.mu.Unlock()
+		return
+	}
+	p.err = err
+	queue := p.queue
+	p.queue = nil
+	p.mu.Unlock()
+
+	_ = p.conn.Close()
+	for _, call := range queue {
+		call.done <- err
+	}
+	p.wakeReader()
+}
+
+func (p *pipelineConn) wakeReader() {
+	select {
+	case p.notify <- struct{}{}:
+	default:
+	}
+}
+
+// roundTrip writes a request with write, then waits for the reader goroutine
+// to read its responses with read.
+//
+// The operation returns early when ctx is done; its responses are then read
+// and discarded when they arrive, so the connection stays usable.
+func (p *pipelineConn) roundTrip(ctx context.Context, write func(w *bufio.Writer) error, read func(r *bufio.Reader) (bool, error)) error {
+	call := &pipelineCall{
+		read:    read,
+		timeout: callTimeout(ctx, p.conn.defaultTimeout),
+		done:    make(chan error, 1),
+	}
+
+	if err := p.send(ctx, call, write); err != nil {
+		return err
+	}
+
+	select {
+	case err := <-call.done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (p *pipelineConn) send(ctx context.Context, call *pipelineCall, write func(w *bufio.Writer) error) error {
+	p.writeMu.Lock()
+	defer p.writeMu.Unlock()
+
+	if err := p.broken(); err != nil {
+		return err
+	}
+
+	if err := p.conn.conn.SetWriteDeadline(sendDeadline(ctx, call.timeout)); err != nil {
+		p.fail(err)
+		return err
+	}
+
+	err := write(p.conn.Writer)
+	if err == nil {
+		err = p.conn.Writer.Flush()
+	}
+	if err != nil {
+		// The request may be partially written.
+		p.fail(err)
+		return err
+	}
+
+	call.deadline = sendDeadline(ctx, call.timeout)
+
+	p.mu.Lock()
+	if p.err != nil {
+		p.mu.Unlock()
+		return p.err
+	}
+	p.queue = append(p.queue, call)
+	// The read in progress blocks this operation too: it can't last past
+	// its deadline.
+	if earlier(call.deadline, p.readDeadline) {
+		p.readDeadline = call.deadline
+		_ = p.conn.conn.SetReadDeadline(call.deadline)
+	}
+	p.mu.Unlock()
+
+	p.wakeReader()
+	return nil
+}
+
+// sendDeadline returns the deadline of a request, for its write and its
+// responses: now+timeout, so a short context deadline doesn't break a
+// connection shared with other operations. Without timeout, the context
+// deadline still bounds it: a write blocked on a partitioned network, or a
+// read from a hung server, would otherwise stall the connection forever, and
+// every operation queued behind it.
+func sendDeadline(ctx context.Context, timeout time.Duration) time.Time {
+	if timeout > 0 {
+		return time.Now().Add(timeout)
+	}
+	deadline, _ := ctx.Deadline()
+	return deadline
+}
+
+// readLoop reads the responses of the operations, in order, until the
+// connection breaks.
+func (p *pipelineConn) readLoop() {
+	for {
+		call, err := p.nextCall()
+		if err != nil {
+			return
+		}
+
+		if err := p.setReadDeadline(call.deadline); err != nil {
+			call.done <- err
+			p.fail(err)
+			return
+		}
+
+		reusable, err := call.read(p.conn.Reader)
+		call.done <- err
+		if err != nil {
+			p.fail(err)
+			return
+		}
+		if !reusable {
+			p.fail(errors.New("memcache: connection closed after a protocol error"))
+			return
+		}
+	}
+}
+
+// setReadDeadline sets the deadline of the read in progress to deadline, the
+// one of the operation read, or to the earliest deadline of the operations
+// waiting, as they all wait for this read. Timeout bounds each operation from
+// the time its request was written; without Timeout, the context deadline
+// bounds it, so a hung server can't block the connection forever.
+func (p *pipelineConn) setReadDeadline(deadline time.Time) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, next := range p.queue {
+		if earlier(next.deadline, deadline) {
+			deadline = next.deadline
+		}
+	}
+	p.readDeadline = deadline
+	return p.conn.conn.SetReadDeadline(deadline)
+}
+
+// earlier reports whether the deadline a is earlier than b, zero being no
+// deadline.
+func earlier(a, b time.Time) bool {
+	return !a.IsZero() && (b.IsZero() || a.Before(b))
+}
+
+// nextCall waits for an operation to read the responses of.
+func (p *pipelineConn) nextCall() (*pipelineCall, error) {
+	for {
+		p.mu.Lock()
+		if p.err != nil {
+			p.mu.Unlock()
+			return nil, p.err
+		}
+		if len(p.queue) > 0 {
+			call := p.queue[0]
+			p.queue[0] = nil
+			p.queue = p.queue[1:]
+			p.mu.Unlock()
+			return call, nil
+		}
+		p.mu.Unlock()
+
+		<-p.notify
+	}
+}
+
+func (p *pipelineConn) execute(ctx context.Context, req *meta.Request) (*meta.Response, error) {
+	if req.HasFlag(meta.FlagQuiet) {
+		return nil, errQuietPipelined
+	}
+	if err := validateRequestKey(req); err != nil {
+		return nil, err
+	}
+
+	var resp meta.Response
+	err := p.roundTrip(ctx,
+		func(w *bufio.Writer) error {
+			return meta.WriteRequest(w, req)
+		},
+		func(r *bufio.Reader) (bool, error) {
+			if err := meta.ReadResponse(r, &resp); err != nil {
+				return false, err
+			}
+			if err := verifyOpaque(req, &resp); err != nil {
+				return false, err
+			}
+			return resp.Error == nil || !meta.ShouldCloseConnection(resp.Error), nil
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// executeBatch pipelines the requests followed by a NoOp marker, like
+// Connection.ExecuteBatch.
+func (p *pipelineConn) executeBatch(ctx context.Context, reqs []*meta.Request) ([]*meta.Response, error) {
+	for _, req := range reqs {
+		if req.HasFlag(meta.FlagQuiet) {
+			return nil, errQuietPipelined
+		}
+		if err := validateRequestKey(req); err != nil {
+			return nil, err
+		}
+	}
+
+	timeout := callTimeout(ctx, p.conn.defaultTimeout)
+	responses := make([]*meta.Response, 0, len(reqs))
+	err := p.roundTrip(ctx,
+		func(w *bufio.Writer) error {
+			for _, req := range reqs {
+				if err := meta.WriteRequest(w, req); err != nil {
+					return err
+				}
+			}
+			return meta.WriteRequest(w, meta.NewRequest(meta.CmdNoOp, "", nil))
+		},
+		func(r *bufio.Reader) (bool, error) {
+			reusable := true
+			for {
+				var resp meta.Response
+				if err := meta.ReadResponse(r, &resp); err != nil {
+					return false, err
+				}
+				if resp.Status == meta.StatusMN {
+					break
+				}
+				if resp.Error != nil && meta.ShouldCloseConnection(resp.Error) {
+					reusable = false
+				}
+				responses = append(responses, &resp)
+
+				// Extend the deadline for each response, as Connection.ExecuteBatch,
+				// up to the deadlines of the operations waiting.
+				if timeout > 0 {
+					if err := p.setReadDeadline(time.Now().Add(timeout)); err != nil {
+						return false, err
+					}
+				}
+			}
+			if len(responses) != len(reqs) {
+				return false, &meta.ParseError{
+					Message: fmt.Sprintf("received %d responses for %d requests in batch", len(responses), len(reqs)),
+				}
+			}
+			if err := verifyOpaques(reqs, responses); err != nil {
+				return false, err
+			}
+			return reusable, nil
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+	return responses, nil
+}
+
+func (p *pipelineConn) executeStats(ctx context.Context, args ...string) (map[string]string, error) {
+	req := &meta.Request{Command: meta.CmdStats}
+	if len(args) > 0 {
+		req.Key = args[0] // stats uses Key field for optional args
+	}
+
+	var stats map[string]string
+	err := p.roundTrip(ctx,
+		func(w *bufio.Writer) error {
+			return meta.WriteRequest(w, req)
+		},
+		func(r *bufio.Reader) (bool, error) {
+			var err error
+			stats, err = meta.ReadStatsResponse(r)
+			return err == nil, err
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// validateRequestKey validates the key of a request before anything is
+// written, so an invalid request can't leave a partial write behind.
+func validateRequestKey(req *meta.Request) error {
+	if req.Command == meta.CmdNoOp || req.Command == meta.CmdStats {
+		return nil
+	}
+	return meta.ValidateKey(req.Key, req.HasFlag(meta.FlagBase64Key))
+}
@@ -0,0 +1,289 @@
+package memcache
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pior/memcache/meta"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newEchoServer starts a minimal meta protocol server answering mg with the
+// key as value, ms with HD and mn with MN. A get of the key "slow" is answered
+// after 100ms. Returns the address and the number of accepted connections.
+func newEchoServer(t *testing.T) (string, *atomic.Int32) {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = ln.Close() })
+
+	var accepted atomic.Int32
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			accepted.Add(1)
+			go serveEcho(conn)
+		}
+	}()
+
+	return ln.Addr().String(), &accepted
+}
+
+func serveEcho(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	w := bufio.NewWriter(conn)
+
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		switch fields[0] {
+		case "mg":
+			if fields[1] == "slow" {
+				time.Sleep(100 * time.Millisecond)
+			}
+			fmt.Fprintf(w, "VA %d\r\n%s\r\n", len(fields[1]), fields[1])
+		case "ms":
+			if _, err := r.ReadString('\n'); err != nil { // data block
+				return
+			}
+			w.WriteString("HD\r\n")
+		case "mn":
+			w.WriteString("MN\r\n")
+		default:
+			w.WriteString("ERROR\r\n")
+		}
+
+		// Flush once the pipelined requests received so far are answered.
+		if r.Buffered() == 0 {
+			if err := w.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func newPipelinedClient(t *testing.T, addr string) *Client {
+	client := NewClient(StaticServers(addr), Config{
+		PipelineConns: 1,
+		Timeout:       time.Second,
+	})
+	t.Cleanup(client.Close)
+	return client
+}
+
+func TestPipelined_Concurrent(t *testing.T) {
+	addr, accepted := newEchoServer(t)
+	client := newPipelinedClient(t, addr)
+
+	var wg sync.WaitGroup
+	for i := range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			key := fmt.Sprintf("key%d", i)
+			for range 20 {
+				item, err := client.Get(context.Background(), key)
+				if assert.NoError(t, err) {
+					assert.Equal(t, key, string(item.Value))
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(1), accepted.Load(), "all operations share a single connection")
+
+	metrics := client.PoolMetrics()
+	require.Len(t, metrics, 1)
+	assert.Equal(t, int32(1), metrics[0].Conns.TotalConns)
+	assert.Equal(t, uint64(1), metrics[0].Conns.CreatedConns)
+}
+
+func TestPipelined_Batch(t *testing.T) {
+	addr, _ := newEchoServer(t)
+	client := newPipelinedClient(t, addr)
+
+	results, err := NewBatchCommands(client).MultiGet(context.Background(), []string{"a", "bb", "ccc"})
+	require.NoError(t, err)
+	require.Len(t, results, 3)
+	for i, key := range []string{"a", "bb", "ccc"} {
+		assert.Equal(t, key, string(results[i].Value))
+	}
+
+	require.NoError(t, client.Set(context.Background(), Item{Key: "a", Value: []byte("v")}))
+}
+
+// The echo server doesn't return the opaque tokens: the batch fails.
+func TestPipelined_BatchOpaqueMismatch(t *testing.T) {
+	addr, _ := newEchoServer(t)
+	client := NewClient(StaticServers(addr), Config{
+		PipelineConns:        1,
+		Timeout:              time.Second,
+		VerifyBatchResponses: true,
+	})
+	t.Cleanup(client.Close)
+
+	_, err := NewBatchCommands(client).MultiGet(context.Background(), []string{"a", "bb"})
+	require.ErrorIs(t, err, ErrResponseMismatch)
+}
+
+func TestPipelined_ContextDeadline(t *testing.T) {
+	addr, accepted := newEchoServer(t)
+	client := newPipelinedClient(t, addr)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := client.Get(ctx, "slow")
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// The late response is discarded: the connection stays in sync.
+	item, err := client.Get(context.Background(), "key")
+	require.NoError(t, err)
+	assert.Equal(t, "key", string(item.Value))
+	assert.Equal(t, int32(1), accepted.Load())
+}
+
+func TestPipelined_Timeout(t *testing.T) {
+	addr := newHungServer(t)
+	client := NewClient(StaticServers(addr), Config{
+		PipelineConns: 1,
+		Timeout:       50 * time.Millisecond,
+	})
+	t.Cleanup(client.Close)
+
+	start := time.Now()
+	_, err := client.Get(context.Background(), "key")
+	require.Error(t, err)
+
+	var netErr net.Error
+	require.True(t, errors.As(err, &netErr), "expected a network error, got %v", err)
+	assert.True(t, netErr.Timeout())
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestPipelined_NoTimeout(t *testing.T) {
+	addr := newHungServer(t)
+	client := NewClient(StaticServers(addr), Config{PipelineConns: 1})
+	t.Cleanup(client.Close)
+
+	// An operation without deadline waits on the hung server...
+	blocked := make(chan error, 1)
+	go func() {
+		_, err := client.Get(context.Background(), "a")
+		blocked <- err
+	}()
+	require.Eventually(t, func() bool {
+		sp, err := client.getPoolForServer(addr)
+		return err == nil && sp.pipelines.metrics().TotalConns == 1
+	}, time.Second, time.Millisecond)
+
+	// ...until the deadline of an operation queued behind it breaks the
+	// connection, instead of blocking it forever.
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := client.Get(ctx, "b")
+	require.Error(t, err)
+
+	select {
+	case err := <-blocked:
+		var netErr net.Error
+		require.ErrorAs(t, err, &netErr)
+		assert.True(t, netErr.Timeout())
+	case <-time.After(time.Second):
+		t.Fatal("the operation without deadline is still blocked")
+	}
+}
+
+func TestPipelined_BatchDeadlineExtension(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = ln.Close() })
+
+	// Answers the first get of the batch once the get queued behind the batch
+	// is received, then hangs.
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		for i := 0; ; i++ {
+			if _, err := r.ReadString('\n'); err != nil {
+				return
+			}
+			if i == 3 { // mg a, mg b, mn, mg c
+				_, _ = conn.Write([]byte("EN\r\n"))
+			}
+		}
+	}()
+
+	client := NewClient(StaticServers(ln.Addr().String()), Config{
+		PipelineConns: 1,
+		Timeout:       5 * time.Second,
+	})
+	t.Cleanup(client.Close)
+
+	batchDone := make(chan error, 1)
+	go func() {
+		_, err := NewBatchCommands(client).MultiGet(context.Background(), []string{"a", "b"})
+		batchDone <- err
+	}()
+	require.Eventually(t, func() bool {
+		sp, err := client.getPoolForServer(ln.Addr().String())
+		return err == nil && sp.pipelines.metrics().TotalConns == 1
+	}, time.Second, time.Millisecond)
+
+	// The response of a extends the read deadline of the batch, but not past
+	// the deadline of the get queued behind it.
+	start := time.Now()
+	_, err = client.Get(context.Background(), "c", WithTimeout(50*time.Millisecond))
+	require.Error(t, err)
+	assert.Less(t, time.Since(start), time.Second)
+	require.Error(t, <-batchDone)
+}
+
+func TestPipelined_RejectsQuietFlag(t *testing.T) {
+	addr, _ := newEchoServer(t)
+	client := newPipelinedClient(t, addr)
+
+	req := meta.NewRequest(meta.CmdGet, "key", nil).AddQuiet()
+	_, err := client.Execute(context.Background(), req)
+	require.ErrorIs(t, err, errQuietPipelined)
+}
+
+func TestPipelined_Close(t *testing.T) {
+	addr, _ := newEchoServer(t)
+	sp, err := NewServerPool(addr, Config{PipelineConns: 1, MaxSize: 1, Dialer: &net.Dialer{}, NewPool: NewPuddlePool})
+	require.NoError(t, err)
+
+	_, err = sp.Execute(context.Background(), meta.NewRequest(meta.CmdGet, "key", nil))
+	require.NoError(t, err)
+
+	sp.close()
+
+	_, err = sp.Execute(context.Background(), meta.NewRequest(meta.CmdGet, "key", nil))
+	require.ErrorIs(t, err, ErrPoolClosed)
+	assert.Equal(t, uint64(1), sp.Metrics().Conns.DestroyedConns)
+}
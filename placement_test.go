@@ -0,0 +1,88 @@
+package memcache
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// placementFixture is a testdata/placement file: the server of each key for a
+// server list, as placed by another client. See testdata/placement/README.md.
+type placementFixture struct {
+	Compat    string            `json:"compat"`
+	Servers   []string          `json:"servers"`
+	Placement map[string]string `json:"placement"`
+}
+
+func TestPlacementCompatibility(t *testing.T) {
+	paths, err := filepath.Glob(filepath.Join("testdata", "placement", "*.json"))
+	require.NoError(t, err)
+	require.NotEmpty(t, paths)
+
+	compats := map[string]KetamaCompat{
+		"libmemcached": KetamaLibmemcached,
+		"spymemcached": KetamaSpymemcached,
+	}
+
+	for _, path := range paths {
+		t.Run(strings.TrimSuffix(filepath.Base(path), ".json"), func(t *testing.T) {
+			data, err := os.ReadFile(path)
+			require.NoError(t, err)
+			var fixture placementFixture
+			require.NoError(t, json.Unmarshal(data, &fixture))
+
+			compat, ok := compats[fixture.Compat]
+			require.True(t, ok, "unknown compat %q", fixture.Compat)
+
+			client := NewClient(StaticServers(fixture.Servers...), Config{
+				KeyHash:            KetamaKeyHash,
+				HashServerSelector: KetamaServerSelector(fixture.Servers, compat),
+			})
+			t.Cleanup(client.Close)
+
+			for key, want := range fixture.Placement {
+				addr, err := client.selectServerForKey(key)
+				require.NoError(t, err)
+				assert.Equal(t, want, addr, "key %q", key)
+			}
+		})
+	}
+}
+
+func TestKetamaServerSelector(t *testing.T) {
+	addrs := []string{"10.0.1.1:11211", "10.0.1.2:11211", "10.0.1.3:11211", "10.0.1.4:11211"}
+	selector := KetamaServerSelector(addrs, KetamaLibmemcached)
+
+	t.Run("distribution", func(t *testing.T) {
+		distribution := make(map[int]int)
+		for i := range 1000 {
+			distribution[selector(KetamaKeyHash(fmt.Sprintf("key-%d", i)), len(addrs))]++
+		}
+		require.Len(t, distribution, len(addrs))
+		for index, count := range distribution {
+			require.Greater(t, count, 150, "server %d", index)
+		}
+	})
+
+	t.Run("consistency", func(t *testing.T) {
+		// Removing a server only moves its own keys.
+		smaller := KetamaServerSelector(addrs[:3], KetamaLibmemcached)
+		for i := range 1000 {
+			hash := KetamaKeyHash(fmt.Sprintf("key-%d", i))
+			if index := selector(hash, 4); index != 3 {
+				require.Equal(t, index, smaller(hash, 3))
+			}
+		}
+	})
+
+	t.Run("server count mismatch", func(t *testing.T) {
+		hash := KetamaKeyHash("key")
+		require.Equal(t, JumpServerSelector(hash, 2), selector(hash, 2))
+	})
+}
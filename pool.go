@@ -33,6 +33,11 @@ type Pool interface {
 	// Blocks until a connection is available or context is canceled.
 	Acquire(ctx context.Context) (Resource, error)
 
+	// CreateIdle creates a new connection and adds it to the pool as idle,
+	// without acquiring it. Used to keep warm connections (Config.MinSize).
+	// Returns ErrPoolFull if the pool is at its maximum size.
+	CreateIdle(ctx context.Context) error
+
 	// AcquireAllIdle acquires all idle connections from the pool.
 	// Used for health checks and maintenance.
 	AcquireAllIdle() []Resource
@@ -134,6 +134,40 @@ func (p *channelPool) Acquire(ctx context.Context) (Resource, error) {
 	}
 }
 
+func (p *channelPool) CreateIdle(ctx context.Context) error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return ErrPoolClosed
+	}
+	if p.size >= p.maxSize {
+		p.mu.Unlock()
+		return ErrPoolFull
+	}
+	p.size++
+	p.mu.Unlock()
+
+	conn, err := p.constructor(ctx)
+	if err != nil {
+		p.mu.Lock()
+		p.size--
+		p.mu.Unlock()
+		return err
+	}
+
+	p.stats.recordCreate()
+	p.stats.recordActivate() // put accounts the release to idle
+
+	now := coarsetime.Now()
+	p.put(&channelResource{
+		conn:         conn,
+		pool:         p,
+		creationTime: now,
+		lastUsedTime: now,
+	})
+	return nil
+}
+
 func (p *channelPool) put(res *channelResource) {
 	// The send must happen under the lock: a check-then-send without it races
 	// with Close, which would leave the connection stranded in the channel.
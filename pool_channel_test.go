@@ -196,3 +196,32 @@ func TestChannelPool_StatsConsistency(t *testing.T) {
 	pool.Close()
 	assertGauges(0, 0, 0)
 }
+
+func TestPool_CreateIdle(t *testing.T) {
+	constructor := func(ctx context.Context) (*Connection, error) {
+		return NewConnection(idleNetConn{}, 0), nil
+	}
+
+	for name, newPool := range map[string]func(func(context.Context) (*Connection, error), int32) (Pool, error){
+		"channel": NewChannelPool,
+		"puddle":  NewPuddlePool,
+	} {
+		t.Run(name, func(t *testing.T) {
+			pool, err := newPool(constructor, 2)
+			require.NoError(t, err)
+
+			require.NoError(t, pool.CreateIdle(context.Background()))
+			require.NoError(t, pool.CreateIdle(context.Background()))
+			require.ErrorIs(t, pool.CreateIdle(context.Background()), ErrPoolFull)
+
+			metrics := pool.Metrics()
+			assert.Equal(t, int32(2), metrics.TotalConns)
+			assert.Equal(t, int32(2), metrics.IdleConns)
+			assert.Equal(t, int32(0), metrics.ActiveConns)
+			assert.Equal(t, uint64(2), metrics.CreatedConns)
+
+			pool.Close()
+			require.ErrorIs(t, pool.CreateIdle(context.Background()), ErrPoolClosed)
+		})
+	}
+}
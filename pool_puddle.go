@@ -2,6 +2,7 @@ package memcache
 
 import (
 	"context"
+	"errors"
 	"sync/atomic"
 
 	"github.com/jackc/puddle/v2"
@@ -46,6 +47,17 @@ func (p *puddlePool) Acquire(ctx context.Context) (Resource, error) {
 	return p.pool.Acquire(ctx)
 }
 
+func (p *puddlePool) CreateIdle(ctx context.Context) error {
+	err := p.pool.CreateResource(ctx)
+	switch {
+	case errors.Is(err, puddle.ErrNotAvailable):
+		return ErrPoolFull
+	case errors.Is(err, puddle.ErrClosedPool):
+		return ErrPoolClosed
+	}
+	return err
+}
+
 func (p *puddlePool) AcquireAllIdle() []Resource {
 	puddleResources := p.pool.AcquireAllIdle()
 	resources := make([]Resource, len(puddleResources))
@@ -0,0 +1,52 @@
+package memcache
+
+import (
+	"context"
+
+	"github.com/pior/memcache/meta"
+)
+
+// PreparedGet is a Get of a fixed key whose request is serialized once (see
+// meta.Request.Prepare). It is safe for concurrent use.
+type PreparedGet struct {
+	commands *Commands
+	key      string
+	req      *meta.Request
+	err      error // invalid key
+}
+
+// PrepareGet returns a Get of key whose request is serialized once, to remove
+// the per-call formatting costs for extremely hot keys:
+//
+//	hot := client.PrepareGet("config")
+//	item, err := hot.Do(ctx)
+//
+// An invalid key fails Do.
+func (c *Commands) PrepareGet(key string) *PreparedGet {
+	return c.PrepareGetWithOptions(key, GetOptions{})
+}
+
+// PrepareGetWithOptions is PrepareGet with the metadata selected by opts, as
+// for GetWithOptions.
+func (c *Commands) PrepareGetWithOptions(key string, opts GetOptions) *PreparedGet {
+	req := newGetRequest(key, opts)
+	return &PreparedGet{
+		commands: c,
+		key:      key,
+		req:      req,
+		err:      req.Prepare(),
+	}
+}
+
+// Do retrieves the item, like GetWithOptions. The call options are not
+// supported, as they change the request: set a timeout with the context.
+func (p *PreparedGet) Do(ctx context.Context) (Item, error) {
+	if p.err != nil {
+		return Item{}, p.err
+	}
+	resp, err := p.commands.executor.Execute(ctx, p.req)
+	if err != nil {
+		return Item{}, err
+	}
+	return itemFromGetResponse(p.key, resp)
+}
@@ -0,0 +1,110 @@
+package memcache
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"net"
+	"time"
+)
+
+// proxySignature starts the PROXY protocol v2 header.
+const proxySignature = "\r\n\r\n\x00\r\nQUIT\n"
+
+// dialServer dials a server for a ServerPool, sending the PROXY protocol
+// header first with Config.ProxyProtocol.
+func dialServer(ctx context.Context, config Config, addr string) (net.Conn, error) {
+	if !config.ProxyProtocol {
+		return config.Dialer.DialContext(ctx, "tcp", addr)
+	}
+
+	// The header precedes the TLS handshake, which tls.Dialer does itself.
+	if tlsDialer, ok := config.Dialer.(*tls.Dialer); ok {
+		return dialTLSWithProxyHeader(ctx, tlsDialer, addr)
+	}
+
+	conn, err := config.Dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	if err := writeProxyHeader(ctx, conn); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// dialTLSWithProxyHeader is tlsDialer.DialContext with the PROXY protocol
+// header sent before the TLS handshake.
+func dialTLSWithProxyHeader(ctx context.Context, tlsDialer *tls.Dialer, addr string) (net.Conn, error) {
+	netDialer := tlsDialer.NetDialer
+	if netDialer == nil {
+		netDialer = &net.Dialer{}
+	}
+	conn, err := netDialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	if err := writeProxyHeader(ctx, conn); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+
+	// As tls.Dialer, verify the server against the host of addr by default.
+	config := &tls.Config{}
+	if tlsDialer.Config != nil {
+		config = tlsDialer.Config.Clone()
+	}
+	if config.ServerName == "" {
+		if host, _, err := net.SplitHostPort(addr); err == nil {
+			config.ServerName = host
+		}
+	}
+
+	tlsConn := tls.Client(conn, config)
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	return tlsConn, nil
+}
+
+// writeProxyHeader sends the PROXY protocol v2 header of conn.
+func writeProxyHeader(ctx context.Context, conn net.Conn) error {
+	deadline, _ := ctx.Deadline()
+	if err := conn.SetWriteDeadline(deadline); err != nil {
+		return err
+	}
+	if _, err := conn.Write(proxyHeaderV2(conn.LocalAddr(), conn.RemoteAddr())); err != nil {
+		return err
+	}
+	return conn.SetWriteDeadline(time.Time{})
+}
+
+// proxyHeaderV2 returns the PROXY protocol v2 header of a TCP connection
+// from src to dst. For other addresses, it is a LOCAL header: the receiver
+// uses the addresses of the connection.
+func proxyHeaderV2(src, dst net.Addr) []byte {
+	header := []byte(proxySignature)
+
+	srcTCP, srcOK := src.(*net.TCPAddr)
+	dstTCP, dstOK := dst.(*net.TCPAddr)
+	if !srcOK || !dstOK {
+		return append(header, 0x20, 0x00, 0, 0) // v2 LOCAL, UNSPEC, no addresses
+	}
+
+	srcIP, dstIP := srcTCP.IP.To4(), dstTCP.IP.To4()
+	family := byte(0x11) // TCP over IPv4
+	if srcIP == nil || dstIP == nil {
+		srcIP, dstIP = srcTCP.IP.To16(), dstTCP.IP.To16()
+		family = 0x21 // TCP over IPv6
+	}
+
+	header = append(header, 0x21, family) // v2 PROXY
+	header = binary.BigEndian.AppendUint16(header, uint16(2*len(srcIP)+4))
+	header = append(header, srcIP...)
+	header = append(header, dstIP...)
+	header = binary.BigEndian.AppendUint16(header, uint16(srcTCP.Port))
+	header = binary.BigEndian.AppendUint16(header, uint16(dstTCP.Port))
+	return header
+}
@@ -0,0 +1,118 @@
+package memcache
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProxyHeaderV2(t *testing.T) {
+	t.Run("ipv4", func(t *testing.T) {
+		header := proxyHeaderV2(
+			&net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 50000},
+			&net.TCPAddr{IP: net.ParseIP("10.0.0.2"), Port: 11211},
+		)
+		want := proxySignature + "\x21\x11\x00\x0c" +
+			"\x0a\x00\x00\x01" + "\x0a\x00\x00\x02" + "\xc3\x50" + "\x2b\xcb"
+		assert.Equal(t, []byte(want), header)
+	})
+
+	t.Run("ipv6", func(t *testing.T) {
+		header := proxyHeaderV2(
+			&net.TCPAddr{IP: net.ParseIP("::1"), Port: 50000},
+			&net.TCPAddr{IP: net.ParseIP("10.0.0.2"), Port: 11211},
+		)
+		require.Len(t, header, 16+36)
+		assert.Equal(t, []byte("\x21\x21\x00\x24"), header[12:16])
+		assert.Equal(t, net.ParseIP("::1").To16(), net.IP(header[16:32]))
+		assert.Equal(t, net.ParseIP("10.0.0.2").To16(), net.IP(header[32:48]))
+	})
+
+	t.Run("local", func(t *testing.T) {
+		header := proxyHeaderV2(&net.UnixAddr{Name: "a"}, &net.UnixAddr{Name: "b"})
+		assert.Equal(t, []byte(proxySignature+"\x20\x00\x00\x00"), header)
+	})
+}
+
+// proxyListener is a listener reading the PROXY protocol v2 header of each
+// accepted connection, sending it to headers.
+type proxyListener struct {
+	net.Listener
+	headers chan []byte
+}
+
+func (l *proxyListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, 16)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return nil, err
+	}
+	addresses := make([]byte, int(header[14])<<8|int(header[15]))
+	if _, err := io.ReadFull(conn, addresses); err != nil {
+		return nil, err
+	}
+	l.headers <- append(header, addresses...)
+	return conn, nil
+}
+
+func newProxyListener(t *testing.T) *proxyListener {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = ln.Close() })
+	return &proxyListener{Listener: ln, headers: make(chan []byte, 1)}
+}
+
+func pingServer(t *testing.T, addr string, config Config) {
+	t.Helper()
+	client := NewClient(StaticServers(addr), config)
+	t.Cleanup(client.Close)
+
+	sp, err := client.getPoolForServer(addr)
+	require.NoError(t, err)
+	res, err := sp.pool.Acquire(context.Background())
+	require.NoError(t, err)
+	defer res.ReleaseUnused()
+	require.NoError(t, res.Value().Ping(context.Background()))
+}
+
+func TestProxyProtocol(t *testing.T) {
+	ln := newProxyListener(t)
+	go serveNoop(ln)
+
+	addr := ln.Addr().String()
+	pingServer(t, addr, Config{Timeout: 2 * time.Second, ProxyProtocol: true})
+
+	header := <-ln.headers
+	assert.Equal(t, proxySignature, string(header[:12]))
+	assert.Equal(t, []byte{0x21, 0x11, 0x00, 0x0c}, header[12:16])
+	assert.Equal(t, net.ParseIP("127.0.0.1").To4(), net.IP(header[20:24]))
+	assert.Equal(t, uint16(ln.Addr().(*net.TCPAddr).Port), binary.BigEndian.Uint16(header[26:28]))
+}
+
+func TestProxyProtocol_TLS(t *testing.T) {
+	cert, roots := newSelfSignedCert(t)
+	ln := newProxyListener(t)
+	go serveNoop(tls.NewListener(ln, &tls.Config{Certificates: []tls.Certificate{cert}}))
+
+	addr := ln.Addr().String()
+	pingServer(t, addr, Config{
+		Timeout:       2 * time.Second,
+		Dialer:        &tls.Dialer{Config: &tls.Config{RootCAs: roots}},
+		ProxyProtocol: true,
+	})
+
+	header := <-ln.headers
+	assert.Equal(t, proxySignature, string(header[:12]))
+}
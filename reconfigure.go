@@ -0,0 +1,122 @@
+package memcache
+
+import "time"
+
+// retiredPoolPoll is how often a pool replaced by UpdateConfig is checked for
+// operations in flight. The first check waits one period, for the operations
+// that looked the pool up just before the replacement.
+const retiredPoolPoll = 100 * time.Millisecond
+
+// UpdateConfig changes the pool settings of a running client, for config-push
+// systems: the non-zero fields of partial among Timeout, ReadTimeout,
+// WriteTimeout, BatchTimeout, ConnectTimeout, MaxSize, MinSize,
+// AcquireTimeout, MaxWaitQueue, MaxConnLifetime, MaxConnIdleTime and
+// CircuitBreakerSettings replace the current ones. The other fields of
+// partial are ignored.
+//
+// The timeouts, MinSize, AcquireTimeout, MaxWaitQueue, MaxConnLifetime and
+// MaxConnIdleTime change in place, for the next operations: the connections,
+// circuit breakers and metrics of the server pools are kept. A change of
+// ConnectTimeout, MaxSize or CircuitBreakerSettings replaces the server pools
+// by pools with the new settings: their connections are dialed again, and
+// their circuit breakers and metrics start afresh. The replaced pools are
+// closed once their operations in flight are done.
+func (c *Client) UpdateConfig(partial Config) error {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return ErrClientClosed
+	}
+
+	replace := c.config.replacesPools(partial)
+	c.config.merge(partial)
+
+	var retired map[string]*ServerPool
+	if replace {
+		retired = c.pools
+		c.pools = make(map[string]*ServerPool)
+	} else {
+		for _, sp := range c.pools {
+			sp.setSettings(c.config)
+			if partial.MinSize > 0 {
+				go c.fillPool(sp)
+			}
+		}
+	}
+	c.mu.Unlock()
+
+	for addr, sp := range retired {
+		go c.retirePool(sp)
+		_, _ = c.getPoolForServer(addr) // warm up, as NewClient does
+	}
+	return nil
+}
+
+// replacesPools reports whether partial changes a setting fixed at the
+// creation of the server pools.
+func (c *Config) replacesPools(partial Config) bool {
+	return (partial.ConnectTimeout > 0 && partial.ConnectTimeout != c.ConnectTimeout) ||
+		(partial.MaxSize > 0 && partial.MaxSize != c.MaxSize) ||
+		partial.CircuitBreakerSettings != nil
+}
+
+// merge sets the non-zero fields of partial supported by UpdateConfig. The
+// other fields are not written: they are read without the client lock.
+func (c *Config) merge(partial Config) {
+	if partial.Timeout > 0 {
+		c.Timeout = partial.Timeout
+	}
+	if partial.ReadTimeout > 0 {
+		c.ReadTimeout = partial.ReadTimeout
+	}
+	if partial.WriteTimeout > 0 {
+		c.WriteTimeout = partial.WriteTimeout
+	}
+	if partial.BatchTimeout > 0 {
+		c.BatchTimeout = partial.BatchTimeout
+	}
+	if partial.ConnectTimeout > 0 {
+		c.ConnectTimeout = partial.ConnectTimeout
+	}
+	if partial.MaxSize > 0 {
+		c.MaxSize = partial.MaxSize
+	}
+	if partial.MinSize > 0 {
+		c.MinSize = partial.MinSize
+	}
+	if partial.AcquireTimeout > 0 {
+		c.AcquireTimeout = partial.AcquireTimeout
+	}
+	if partial.MaxWaitQueue > 0 {
+		c.MaxWaitQueue = partial.MaxWaitQueue
+	}
+	if partial.MaxConnLifetime > 0 {
+		c.MaxConnLifetime = partial.MaxConnLifetime
+	}
+	if partial.MaxConnIdleTime > 0 {
+		c.MaxConnIdleTime = partial.MaxConnIdleTime
+	}
+	if partial.CircuitBreakerSettings != nil {
+		c.CircuitBreakerSettings = partial.CircuitBreakerSettings
+	}
+}
+
+// retirePool closes a pool replaced by UpdateConfig once it has no operation
+// in flight, or when the client is closed. Closing a pool waits for its
+// connections in use.
+func (c *Client) retirePool(sp *ServerPool) {
+	ticker := time.NewTicker(retiredPoolPoll)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if sp.active.Load() > 0 {
+				continue
+			}
+		case <-c.stopBackground:
+		}
+		sp.close()
+		return
+	}
+}
@@ -0,0 +1,90 @@
+package memcache
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/pior/memcache/internal/testutils"
+	"github.com/pior/memcache/meta"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newReconfigureTestClient(t *testing.T) *Client {
+	client := NewClient(StaticServers("localhost:11211"), Config{
+		Dialer: DialerFunc(func(ctx context.Context, network, address string) (net.Conn, error) {
+			mock := testutils.NewConnectionMock("EN\r\n")
+			mock.EnableCycling()
+			return mock, nil
+		}),
+		MaxSize: 2,
+		Timeout: time.Second,
+	})
+	t.Cleanup(client.Close)
+	return client
+}
+
+func TestClient_UpdateConfig(t *testing.T) {
+	client := newReconfigureTestClient(t)
+
+	_, err := client.Get(context.Background(), "key")
+	require.NoError(t, err)
+	old, err := client.getPoolForServer("localhost:11211")
+	require.NoError(t, err)
+
+	require.NoError(t, client.UpdateConfig(Config{Timeout: 2 * time.Second, AcquireTimeout: 50 * time.Millisecond}))
+
+	sp, err := client.getPoolForServer("localhost:11211")
+	require.NoError(t, err)
+	assert.Same(t, old, sp, "the pool is updated in place")
+	assert.Equal(t, 2*time.Second, sp.settings.Load().timeout)
+	assert.Equal(t, 50*time.Millisecond, sp.settings.Load().acquireTimeout)
+	assert.Equal(t, int32(1), sp.pool.Metrics().TotalConns, "the connections are kept")
+	assert.Equal(t, int32(2), client.config.MaxSize, "the zero fields are unchanged")
+
+	require.NoError(t, client.UpdateConfig(Config{MaxSize: 5}))
+
+	sp, err = client.getPoolForServer("localhost:11211")
+	require.NoError(t, err)
+	assert.NotSame(t, old, sp, "the pool is replaced")
+	assert.Equal(t, int32(5), sp.maxSize)
+	assert.Equal(t, 2*time.Second, sp.settings.Load().timeout)
+
+	_, err = client.Get(context.Background(), "key")
+	require.NoError(t, err)
+
+	client.Close()
+	assert.ErrorIs(t, client.UpdateConfig(Config{MaxSize: 1}), ErrClientClosed)
+}
+
+func TestClient_UpdateConfig_RetiresDrainedPool(t *testing.T) {
+	client := newReconfigureTestClient(t)
+	old, err := client.getPoolForServer("localhost:11211")
+	require.NoError(t, err)
+
+	done := make(chan error)
+	release := make(chan struct{})
+	go func() {
+		done <- old.WithConnection(context.Background(), "key", func(conn *Connection) error {
+			<-release
+			return nil
+		})
+	}()
+	require.Eventually(t, func() bool { return old.active.Load() == 1 }, time.Second, time.Millisecond)
+
+	require.NoError(t, client.UpdateConfig(Config{MaxSize: 5}))
+
+	time.Sleep(3 * retiredPoolPoll)
+	_, err = old.Execute(context.Background(), meta.NewRequest(meta.CmdGet, "key", nil))
+	require.NoError(t, err, "the pool stays open while an operation is in flight")
+
+	close(release)
+	require.NoError(t, <-done)
+
+	require.Eventually(t, func() bool {
+		return errors.Is(old.pool.CreateIdle(context.Background()), ErrPoolClosed)
+	}, time.Second, 10*time.Millisecond, "the pool is closed once drained")
+}
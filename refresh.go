@@ -0,0 +1,121 @@
+package memcache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// RefreshAheadOptions configures a RefreshAhead.
+type RefreshAheadOptions struct {
+	// Threshold is the remaining TTL below which a Get refreshes the item.
+	// Required: must be > 0.
+	Threshold time.Duration
+
+	// Refresh loads the fresh item of key, stored with Set when it returns.
+	// The Key of the item is set to key. It runs in a background goroutine,
+	// at most once at a time per key.
+	// Required.
+	Refresh func(ctx context.Context, key string) (Item, error)
+
+	// Timeout bounds a refresh: the Refresh call and the Set.
+	// Default: 10s
+	Timeout time.Duration
+
+	// ErrorHandler is called with the failed refreshes, from the background
+	// goroutine. If nil, the failures are ignored: the item is refreshed by a
+	// later Get, or expires.
+	ErrorHandler func(key string, err error)
+}
+
+// RefreshAhead keeps the hot items warm: a Get of an item about to expire
+// returns the current value immediately, and refreshes the item in the
+// background. Unlike a reload on a miss, the refresh is off the request path.
+//
+// Only the items read through RefreshAhead.Get are refreshed, and only when
+// they have a TTL: items stored with NoTTL never are. A miss is returned as
+// is, loading a missing item is up to the caller.
+type RefreshAhead struct {
+	commands *Commands
+	opts     RefreshAheadOptions
+
+	mu       sync.Mutex
+	inflight map[string]struct{} // keys being refreshed
+	wg       sync.WaitGroup
+}
+
+// NewRefreshAhead returns a RefreshAhead reading and storing the items with
+// commands, e.g. the Commands of a Client.
+func NewRefreshAhead(commands *Commands, opts RefreshAheadOptions) (*RefreshAhead, error) {
+	if opts.Threshold <= 0 {
+		return nil, errors.New("memcache: refresh-ahead threshold must be > 0")
+	}
+	if opts.Refresh == nil {
+		return nil, errors.New("memcache: refresh-ahead requires a Refresh function")
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 10 * time.Second
+	}
+	return &RefreshAhead{
+		commands: commands,
+		opts:     opts,
+		inflight: make(map[string]struct{}),
+	}, nil
+}
+
+// Get retrieves an item, with its remaining TTL, and starts a refresh of the
+// item when its remaining TTL is below the threshold. It doesn't wait for the
+// refresh: the current value is returned.
+func (r *RefreshAhead) Get(ctx context.Context, key string, opts ...CallOption) (Item, error) {
+	item, err := r.commands.GetWithOptions(ctx, key, GetOptions{ReturnTTL: true}, opts...)
+	if err != nil || !item.Found {
+		return item, err
+	}
+
+	// The remaining TTL is relative: NoTTL has a zero duration.
+	if remaining := item.TTL.duration; remaining > 0 && remaining < r.opts.Threshold {
+		r.startRefresh(key)
+	}
+	return item, nil
+}
+
+// Wait waits for the refreshes in progress, e.g. before closing the client.
+func (r *RefreshAhead) Wait() {
+	r.wg.Wait()
+}
+
+// startRefresh refreshes key in a background goroutine, unless it is already
+// being refreshed.
+func (r *RefreshAhead) startRefresh(key string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.inflight[key]; ok {
+		return
+	}
+	r.inflight[key] = struct{}{}
+
+	r.wg.Go(func() {
+		defer func() {
+			r.mu.Lock()
+			delete(r.inflight, key)
+			r.mu.Unlock()
+		}()
+
+		if err := r.refresh(key); err != nil && r.opts.ErrorHandler != nil {
+			r.opts.ErrorHandler(key, err)
+		}
+	})
+}
+
+func (r *RefreshAhead) refresh(key string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), r.opts.Timeout)
+	defer cancel()
+
+	item, err := r.opts.Refresh(ctx, key)
+	if err != nil {
+		return err
+	}
+	item.Key = key
+	return r.commands.Set(ctx, item)
+}
@@ -0,0 +1,112 @@
+package memcache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pior/memcache/internal/testutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestRefreshAhead(t *testing.T, mock *testutils.ConnectionMock, opts RefreshAheadOptions) *RefreshAhead {
+	r, err := NewRefreshAhead(newTestClient(t, mock).Commands, opts)
+	require.NoError(t, err)
+	return r
+}
+
+func TestRefreshAhead(t *testing.T) {
+	ctx := context.Background()
+	fresh := func(ctx context.Context, key string) (Item, error) {
+		return Item{Value: []byte("new"), TTL: ExpiresIn(time.Minute)}, nil
+	}
+
+	t.Run("refreshes an item about to expire", func(t *testing.T) {
+		mock := testutils.NewConnectionMock("VA 3 t5\r\nold\r\n", "HD\r\n")
+		r := newTestRefreshAhead(t, mock, RefreshAheadOptions{Threshold: 10 * time.Second, Refresh: fresh})
+
+		item, err := r.Get(ctx, "k1")
+		require.NoError(t, err)
+		assert.Equal(t, "old", string(item.Value))
+
+		r.Wait()
+		assert.Equal(t, "mg k1 v f t\r\nms k1 3 T60\r\nnew\r\n", mock.GetWrittenRequest())
+	})
+
+	t.Run("doesn't refresh other items", func(t *testing.T) {
+		mock := testutils.NewConnectionMock("VA 3 t60\r\nold\r\n", "VA 3 t-1\r\nold\r\n", "EN\r\n")
+		r := newTestRefreshAhead(t, mock, RefreshAheadOptions{
+			Threshold: 10 * time.Second,
+			Refresh: func(ctx context.Context, key string) (Item, error) {
+				t.Errorf("unexpected refresh of %s", key)
+				return Item{}, nil
+			},
+		})
+
+		_, err := r.Get(ctx, "fresh") // 60s left
+		require.NoError(t, err)
+		_, err = r.Get(ctx, "forever") // no TTL
+		require.NoError(t, err)
+		item, err := r.Get(ctx, "missing")
+		require.NoError(t, err)
+		assert.False(t, item.Found)
+		r.Wait()
+	})
+
+	t.Run("refreshes a key once at a time", func(t *testing.T) {
+		mock := testutils.NewConnectionMock("VA 3 t5\r\nold\r\n", "VA 3 t5\r\nold\r\n", "HD\r\n")
+		release := make(chan struct{})
+		var calls atomic.Int32
+		r := newTestRefreshAhead(t, mock, RefreshAheadOptions{
+			Threshold: 10 * time.Second,
+			Refresh: func(ctx context.Context, key string) (Item, error) {
+				calls.Add(1)
+				<-release
+				return fresh(ctx, key)
+			},
+		})
+
+		for range 2 {
+			_, err := r.Get(ctx, "k1")
+			require.NoError(t, err)
+		}
+		close(release)
+		r.Wait()
+		assert.Equal(t, int32(1), calls.Load())
+	})
+
+	t.Run("reports the failures", func(t *testing.T) {
+		mock := testutils.NewConnectionMock("VA 3 t5\r\nold\r\n")
+		errLoad := errors.New("load failed")
+		var mu sync.Mutex
+		var failed []string
+		r := newTestRefreshAhead(t, mock, RefreshAheadOptions{
+			Threshold: 10 * time.Second,
+			Refresh: func(ctx context.Context, key string) (Item, error) {
+				return Item{}, errLoad
+			},
+			ErrorHandler: func(key string, err error) {
+				mu.Lock()
+				defer mu.Unlock()
+				assert.ErrorIs(t, err, errLoad)
+				failed = append(failed, key)
+			},
+		})
+
+		_, err := r.Get(ctx, "k1")
+		require.NoError(t, err)
+		r.Wait()
+		assert.Equal(t, []string{"k1"}, failed)
+	})
+
+	t.Run("invalid options", func(t *testing.T) {
+		_, err := NewRefreshAhead(nil, RefreshAheadOptions{Refresh: fresh})
+		assert.Error(t, err)
+		_, err = NewRefreshAhead(nil, RefreshAheadOptions{Threshold: time.Second})
+		assert.Error(t, err)
+	})
+}
@@ -1,8 +1,15 @@
 package memcache
 
 import (
+	"cmp"
 	"context"
 	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"slices"
+	"strconv"
+	"sync/atomic"
 	"time"
 
 	"github.com/pior/memcache/meta"
@@ -19,12 +26,16 @@ func NewServerPool(addr string, config Config) (*ServerPool, error) {
 			defer cancel()
 		}
 
-		netConn, err := config.Dialer.DialContext(dialCtx, "tcp", addr)
+		netConn, err := dialServer(dialCtx, config, addr)
 		if err != nil {
 			return nil, err
 		}
 
-		return NewConnection(netConn, config.Timeout), nil
+		conn := newPooledConnection(netConn, config)
+		if config.MaxConnLifetimeJitter > 0 {
+			conn.lifetimeJitter = rand.N(config.MaxConnLifetimeJitter)
+		}
+		return conn, nil
 	}
 
 	pool, err := config.NewPool(constructor, config.MaxSize)
@@ -32,28 +43,139 @@ func NewServerPool(addr string, config Config) (*ServerPool, error) {
 		return nil, err
 	}
 
+	if config.Protocol == ProtocolText &&
+		(config.PipelineConns > 0 || config.UDP != nil || config.VerifyOpaque || config.VerifyBatchResponses) {
+		pool.Close()
+		return nil, errTextUnsupportedMode
+	}
+
+	var pipelines *pipelineSet
+	if config.PipelineConns > 0 {
+		pipelines = newPipelineSet(config.PipelineConns, constructor)
+	}
+
+	health := &serverHealth{addr: addr}
+
 	var breaker *gobreaker.CircuitBreaker[bool]
 	if config.CircuitBreakerSettings != nil {
 		settings := *config.CircuitBreakerSettings
 		settings.Name = addr
+		onStateChange := settings.OnStateChange
+		settings.OnStateChange = func(name string, from, to gobreaker.State) {
+			health.breakerChanged(to)
+			if onStateChange != nil {
+				onStateChange(name, from, to)
+			}
+		}
 
 		breaker = gobreaker.NewCircuitBreaker[bool](settings)
 	}
 
-	return &ServerPool{
-		addr:            addr,
-		pool:            pool,
-		circuitBreaker:  breaker,
+	var udp *udpTransport
+	if config.UDP != nil {
+		if udp, err = newUDPTransport(addr, config); err != nil {
+			pool.Close()
+			return nil, err
+		}
+	}
+
+	hooks := hookChain(config.Hooks)
+	var opMetrics *opMetricsHook
+	if config.CollectOpMetrics {
+		opMetrics = newOpMetricsHook()
+		hooks = append(slices.Clip(hooks), opMetrics)
+	}
+
+	sp := &ServerPool{
+		addr:           addr,
+		pool:           pool,
+		circuitBreaker: breaker,
+		health:         health,
+		maxSize:        config.MaxSize,
+		pipelines:      pipelines,
+		udp:            udp,
+		hooks:          hooks,
+		opMetrics:      opMetrics,
+		shedding:       config.Shedding,
+		verifyBatches:  config.VerifyBatchResponses || config.VerifyOpaque,
+		verifyOpaque:   config.VerifyOpaque,
+		checksums:      config.ValueChecksums,
+		logger:         config.Logger,
+	}
+	sp.setSettings(config)
+	return sp, nil
+}
+
+// poolSettings are the settings of a ServerPool that UpdateConfig changes in
+// place.
+type poolSettings struct {
+	timeout         time.Duration // Config.Timeout
+	readTimeout     time.Duration // Config.ReadTimeout, zero for Timeout
+	writeTimeout    time.Duration // Config.WriteTimeout, zero for Timeout
+	batchTimeout    time.Duration // Config.BatchTimeout, zero for Timeout
+	acquireTimeout  time.Duration
+	minSize         int32
+	maxWaiters      int32
+	maxConnLifetime time.Duration
+	maxConnIdleTime time.Duration
+}
+
+// setSettings sets the settings of config that can change in place.
+func (sp *ServerPool) setSettings(config Config) {
+	minSize := config.MinSize
+	if sp.pipelines != nil {
+		minSize = 0 // the pool is not used
+	}
+	sp.settings.Store(&poolSettings{
+		timeout:         config.Timeout,
+		readTimeout:     config.ReadTimeout,
+		writeTimeout:    config.WriteTimeout,
+		batchTimeout:    config.BatchTimeout,
+		acquireTimeout:  config.AcquireTimeout,
+		minSize:         minSize,
+		maxWaiters:      config.MaxWaitQueue,
 		maxConnLifetime: config.MaxConnLifetime,
-	}, nil
+		maxConnIdleTime: config.MaxConnIdleTime,
+	})
 }
 
 // ServerPool wraps a pool, a circuit breaker with its server address.
 type ServerPool struct {
-	addr            string
-	pool            Pool
-	circuitBreaker  *gobreaker.CircuitBreaker[bool]
-	maxConnLifetime time.Duration
+	addr             string
+	pool             Pool
+	circuitBreaker   *gobreaker.CircuitBreaker[bool]
+	health           *serverHealth
+	maxSize          int32
+	settings         atomic.Pointer[poolSettings]
+	pending          atomic.Int32  // connections in use + callers in acquire
+	active           atomic.Int32  // operations in flight
+	pipelines        *pipelineSet  // nil unless Config.PipelineConns
+	udp              *udpTransport // nil unless Config.UDP
+	hooks            hookChain
+	opMetrics        *opMetricsHook // nil unless Config.CollectOpMetrics
+	shedding         *SheddingPolicy
+	verifyBatches    bool // Config.VerifyBatchResponses or VerifyOpaque
+	verifyOpaque     bool // Config.VerifyOpaque
+	opaqueSeq        atomic.Uint64
+	mismatches       atomic.Uint64
+	checksums        bool // Config.ValueChecksums
+	checksumFailures atomic.Uint64
+	logger           *slog.Logger // nil to not log
+	shedOps          atomic.Uint64
+	coalescedGets    atomic.Uint64
+	prunedIdle       atomic.Uint64
+	prunedLifetime   atomic.Uint64
+}
+
+// close closes the pool and all connections.
+func (sp *ServerPool) close() {
+	sp.pool.Close()
+	if sp.pipelines != nil {
+		sp.pipelines.close()
+	}
+	if sp.udp != nil {
+		sp.udp.close()
+	}
 }
 
 // release returns a connection to the pool, or destroys it if it has
@@ -61,13 +183,79 @@ type ServerPool struct {
 // health check loop) matters under sustained load: a saturated pool never has
 // idle connections, so the health check alone would never recycle them.
 func (sp *ServerPool) release(resource Resource) {
-	if sp.maxConnLifetime > 0 && time.Since(resource.CreationTime()) > sp.maxConnLifetime {
+	sp.pending.Add(-1)
+	if sp.lifetimeExceeded(resource, time.Now()) {
+		sp.prunedLifetime.Add(1)
 		resource.Destroy()
 		return
 	}
 	resource.Release()
 }
 
+// lifetimeExceeded reports whether a connection has outlived MaxConnLifetime,
+// extended by the connection's share of MaxConnLifetimeJitter.
+func (sp *ServerPool) lifetimeExceeded(resource Resource, now time.Time) bool {
+	maxConnLifetime := sp.settings.Load().maxConnLifetime
+	if maxConnLifetime <= 0 {
+		return false
+	}
+	lifetime := maxConnLifetime + resource.Value().lifetimeJitter
+	return now.Sub(resource.CreationTime()) > lifetime
+}
+
+// prune destroys an idle connection that has exceeded MaxConnLifetime or
+// MaxConnIdleTime, and reports whether it did.
+func (sp *ServerPool) prune(resource Resource, now time.Time) bool {
+	maxConnIdleTime := sp.settings.Load().maxConnIdleTime
+	switch {
+	case sp.lifetimeExceeded(resource, now):
+		sp.prunedLifetime.Add(1)
+	case maxConnIdleTime > 0 && resource.IdleDuration() > maxConnIdleTime:
+		sp.prunedIdle.Add(1)
+	default:
+		return false
+	}
+	resource.Destroy()
+	return true
+}
+
+// destroy closes a connection acquired with acquire and removes it from the pool.
+func (sp *ServerPool) destroy(resource Resource) {
+	sp.pending.Add(-1)
+	resource.Destroy()
+}
+
+// acquire gets a connection from the pool, enforcing the wait queue limit and
+// the acquire timeout. Connections must be returned with release or destroy.
+func (sp *ServerPool) acquire(ctx context.Context) (Resource, error) {
+	// pending counts the connections in use plus the callers in acquire: past
+	// the pool size, the excess is the number of callers waiting.
+	settings := sp.settings.Load()
+	if n := sp.pending.Add(1); settings.maxWaiters > 0 && n > sp.maxSize+settings.maxWaiters {
+		sp.pending.Add(-1)
+		return nil, fmt.Errorf("%w: wait queue is full", ErrPoolExhausted)
+	}
+
+	acquireCtx := ctx
+	if settings.acquireTimeout > 0 {
+		var cancel context.CancelFunc
+		acquireCtx, cancel = context.WithTimeout(ctx, settings.acquireTimeout)
+		defer cancel()
+	}
+
+	resource, err := sp.pool.Acquire(acquireCtx)
+	if err != nil {
+		sp.pending.Add(-1)
+		// Only the acquire deadline means waiting for a free connection: a
+		// dial timing out (ConnectTimeout) is the server's failure.
+		if settings.acquireTimeout > 0 && acquireCtx.Err() != nil && ctx.Err() == nil {
+			return nil, fmt.Errorf("%w: no connection available within %s", ErrPoolExhausted, settings.acquireTimeout)
+		}
+		return nil, err
+	}
+	return resource, nil
+}
+
 func (sp *ServerPool) Address() string {
 	return sp.addr
 }
@@ -77,6 +265,38 @@ type PoolMetrics struct {
 	Addr           string
 	Conns          ConnPoolMetrics
 	CircuitBreaker CircuitBreakerStats
+
+	// Ops contains the per-operation latency and hit ratio. Empty unless
+	// Config.CollectOpMetrics is enabled.
+	Ops OpMetrics
+
+	// PrunedIdle and PrunedLifetime count the connections closed for
+	// exceeding MaxConnIdleTime and MaxConnLifetime respectively.
+	PrunedIdle     uint64
+	PrunedLifetime uint64
+
+	// Shed counts the operations rejected by load shedding (Config.Shedding).
+	Shed uint64
+
+	// UDPGets counts the gets sent over UDP (Config.UDP), and UDPFallbacks
+	// those of them sent again over TCP: lost, truncated or failed.
+	UDPGets      uint64
+	UDPFallbacks uint64
+
+	// Ejections counts the times the server was ejected by Config.AutoEject.
+	Ejections uint64
+
+	// CoalescedGets counts the gets that shared the response of an identical
+	// get in flight (Config.CoalesceGets).
+	CoalescedGets uint64
+
+	// ResponseMismatches counts the operations failed with
+	// ErrResponseMismatch.
+	ResponseMismatches uint64
+
+	// ChecksumFailures counts the values read with a wrong checksum
+	// (Config.ValueChecksums).
+	ChecksumFailures uint64
 }
 
 // CircuitBreakerStats is a snapshot of a server's circuit breaker, decoupled
@@ -93,8 +313,15 @@ type CircuitBreakerStats struct {
 
 func (sp *ServerPool) Metrics() PoolMetrics {
 	metrics := PoolMetrics{
-		Addr:  sp.addr,
-		Conns: sp.pool.Metrics(),
+		Addr:               sp.addr,
+		Conns:              sp.pool.Metrics(),
+		PrunedIdle:         sp.prunedIdle.Load(),
+		PrunedLifetime:     sp.prunedLifetime.Load(),
+		Shed:               sp.shedOps.Load(),
+		Ejections:          sp.health.ejections.Load(),
+		CoalescedGets:      sp.coalescedGets.Load(),
+		ResponseMismatches: sp.mismatches.Load(),
+		ChecksumFailures:   sp.checksumFailures.Load(),
 	}
 	if sp.circuitBreaker != nil {
 		counts := sp.circuitBreaker.Counts()
@@ -107,6 +334,16 @@ func (sp *ServerPool) Metrics() PoolMetrics {
 			ConsecutiveFailures:  counts.ConsecutiveFailures,
 		}
 	}
+	if sp.pipelines != nil {
+		metrics.Conns = sp.pipelines.metrics()
+	}
+	if sp.opMetrics != nil {
+		metrics.Ops = sp.opMetrics.snapshot()
+	}
+	if sp.udp != nil {
+		metrics.UDPGets = sp.udp.gets.Load()
+		metrics.UDPFallbacks = sp.udp.fallbacks.Load()
+	}
 	return metrics
 }
 
@@ -117,8 +354,55 @@ func (sp *ServerPool) Metrics() PoolMetrics {
 //
 // Failures are returned as *OpError carrying the operation, key, and server address.
 func (sp *ServerPool) Execute(ctx context.Context, req *meta.Request) (*meta.Response, error) {
+	sp.active.Add(1)
+	defer sp.active.Add(-1)
+
+	if len(sp.hooks) == 0 {
+		return sp.execute(ctx, req)
+	}
+
+	op := OpInfo{Op: string(req.Command), Server: sp.addr, Requests: []*meta.Request{req}}
+	start := time.Now()
+	ctx = sp.hooks.before(ctx, op)
+
+	resp, err := sp.execute(ctx, req)
+
+	result := OpResult{Duration: time.Since(start), Err: err}
+	if resp != nil {
+		result.Responses = []*meta.Response{resp}
+	}
+	sp.hooks.after(ctx, op, result)
+	return resp, err
+}
+
+// execute runs a single request, with its value checksum if enabled (see
+// Config.ValueChecksums).
+func (sp *ServerPool) execute(ctx context.Context, req *meta.Request) (*meta.Response, error) {
+	if !sp.checksums {
+		return sp.execBreaker(ctx, req)
+	}
+	sealed, err := sealRequest(req)
+	if err != nil {
+		return nil, sp.wrapErr(string(req.Command), req.Key, err)
+	}
+	resp, err := sp.execBreaker(ctx, sealed)
+	if err == nil {
+		sp.openResponse(ctx, req, resp)
+	}
+	return resp, err
+}
+
+// execBreaker runs a single request through the circuit breaker, if any.
+func (sp *ServerPool) execBreaker(ctx context.Context, req *meta.Request) (*meta.Response, error) {
+	ctx = withDefaultTimeout(ctx, sp.requestTimeout(req))
+	if err := sp.shed(ctx); err != nil {
+		return nil, sp.wrapErr(string(req.Command), req.Key, err)
+	}
+
 	if sp.circuitBreaker == nil {
-		return sp.execRequestDirect(ctx, req)
+		resp, err := sp.execRequestDirect(ctx, req)
+		sp.health.record(err)
+		return resp, err
 	}
 
 	var resp *meta.Response
@@ -126,6 +410,7 @@ func (sp *ServerPool) Execute(ctx context.Context, req *meta.Request) (*meta.Res
 
 	_, err := sp.circuitBreaker.Execute(func() (bool, error) {
 		resp, execErr = sp.execRequestDirect(ctx, req)
+		sp.health.record(execErr)
 		return execErr == nil, breakerError(execErr)
 	})
 
@@ -137,6 +422,20 @@ func (sp *ServerPool) Execute(ctx context.Context, req *meta.Request) (*meta.Res
 	return resp, execErr
 }
 
+// requestTimeout returns the timeout of the class of req: ReadTimeout or
+// WriteTimeout, else Timeout.
+func (sp *ServerPool) requestTimeout(req *meta.Request) time.Duration {
+	settings := sp.settings.Load()
+	var timeout time.Duration
+	switch req.Command {
+	case meta.CmdGet:
+		timeout = settings.readTimeout
+	case meta.CmdSet, meta.CmdDelete, meta.CmdArithmetic:
+		timeout = settings.writeTimeout
+	}
+	return cmp.Or(timeout, settings.timeout)
+}
+
 // wrapErr wraps an error with operation and server context, unless it
 // already carries it.
 func (sp *ServerPool) wrapErr(op, key string, err error) error {
@@ -154,6 +453,11 @@ func breakerError(err error) error {
 	if err == nil || errors.Is(err, context.Canceled) {
 		return nil
 	}
+	// A saturated pool is local back-pressure: the slow operations holding
+	// the connections are accounted for on their own.
+	if errors.Is(err, ErrPoolExhausted) {
+		return nil
+	}
 	var invalidKey *meta.InvalidKeyError
 	if errors.As(err, &invalidKey) {
 		return nil
@@ -165,7 +469,30 @@ func breakerError(err error) error {
 func (sp *ServerPool) execRequestDirect(ctx context.Context, req *meta.Request) (*meta.Response, error) {
 	op := string(req.Command)
 
-	resource, err := sp.pool.Acquire(ctx)
+	if sp.udp != nil && isUDPGet(req) {
+		resp, err := sp.udp.get(ctx, req)
+		if err == nil {
+			return resp, nil
+		}
+		if ctx.Err() != nil {
+			return nil, sp.wrapErr(op, req.Key, err)
+		}
+	}
+
+	if sp.verifyOpaque {
+		req = sp.tagRequest(req)
+	}
+
+	if sp.pipelines != nil {
+		resp, err := sp.pipelines.execute(ctx, req)
+		if err != nil {
+			sp.checkMismatch(ctx, op, err)
+			return nil, sp.wrapErr(op, req.Key, err)
+		}
+		return resp, nil
+	}
+
+	resource, err := sp.acquire(ctx)
 	if err != nil {
 		return nil, sp.wrapErr(op, req.Key, err)
 	}
@@ -174,8 +501,9 @@ func (sp *ServerPool) execRequestDirect(ctx context.Context, req *meta.Request)
 
 	resp, err := conn.Execute(ctx, req)
 	if err != nil {
+		sp.checkMismatch(ctx, op, err)
 		if meta.ShouldCloseConnection(err) {
-			resource.Destroy()
+			sp.destroy(resource)
 		} else {
 			sp.release(resource)
 		}
@@ -186,13 +514,125 @@ func (sp *ServerPool) execRequestDirect(ctx context.Context, req *meta.Request)
 	// some of them (e.g. CLIENT_ERROR) corrupt the protocol state and require
 	// closing the connection instead of returning it to the pool.
 	if resp.Error != nil && meta.ShouldCloseConnection(resp.Error) {
-		resource.Destroy()
+		sp.destroy(resource)
 	} else {
 		sp.release(resource)
 	}
 	return resp, nil
 }
 
+// tagBatch returns the requests of a batch with an opaque token each, their
+// position, for the connection to verify the responses (see verifyOpaques).
+// The requests with an opaque token are kept, the others are copied.
+func tagBatch(reqs []*meta.Request) []*meta.Request {
+	tagged := make([]*meta.Request, len(reqs))
+	for i, req := range reqs {
+		if req.HasFlag(meta.FlagOpaque) || req.Command == meta.CmdNoOp {
+			tagged[i] = req
+			continue
+		}
+		tagged[i] = req.Clone().AddOpaque(strconv.Itoa(i))
+	}
+	return tagged
+}
+
+// tagRequest returns req with an opaque token for the connection to verify
+// the response (see verifyOpaque): a copy of req, unless it has one.
+func (sp *ServerPool) tagRequest(req *meta.Request) *meta.Request {
+	if req.HasFlag(meta.FlagOpaque) || req.Command == meta.CmdNoOp {
+		return req
+	}
+	return req.Clone().AddOpaque(strconv.FormatUint(sp.opaqueSeq.Add(1), 36))
+}
+
+// checkMismatch counts and logs the ErrResponseMismatch errors.
+func (sp *ServerPool) checkMismatch(ctx context.Context, op string, err error) {
+	if !errors.Is(err, ErrResponseMismatch) {
+		return
+	}
+	sp.mismatches.Add(1)
+	if sp.logger != nil {
+		sp.logger.LogAttrs(ctx, slog.LevelError, "memcache: response mismatch",
+			slog.String("op", op), slog.String("server", sp.addr), slog.String("error", err.Error()))
+	}
+}
+
+// errRawPipelined rejects WithConnection in pipelined mode, where the
+// connections are shared.
+var errRawPipelined = errors.New("memcache: raw connections are not supported in pipelined mode")
+
+// WithConnection calls fn with a connection of the pool, through the circuit
+// breaker and the hooks: see Client.WithRawConnection. key is reported in
+// OpError.
+func (sp *ServerPool) WithConnection(ctx context.Context, key string, fn func(conn *Connection) error) error {
+	sp.active.Add(1)
+	defer sp.active.Add(-1)
+
+	if sp.pipelines != nil {
+		return errRawPipelined
+	}
+	if len(sp.hooks) == 0 {
+		return sp.withConnection(ctx, key, fn)
+	}
+
+	op := OpInfo{Op: OpRaw, Server: sp.addr}
+	start := time.Now()
+	ctx = sp.hooks.before(ctx, op)
+
+	err := sp.withConnection(ctx, key, fn)
+
+	sp.hooks.after(ctx, op, OpResult{Duration: time.Since(start), Err: err})
+	return err
+}
+
+// withConnection runs fn through the circuit breaker, if any.
+func (sp *ServerPool) withConnection(ctx context.Context, key string, fn func(conn *Connection) error) error {
+	if sp.circuitBreaker == nil {
+		err := sp.withConnectionDirect(ctx, key, fn)
+		sp.health.record(err)
+		return err
+	}
+
+	var execErr error
+	_, err := sp.circuitBreaker.Execute(func() (bool, error) {
+		execErr = sp.withConnectionDirect(ctx, key, fn)
+		sp.health.record(execErr)
+		return execErr == nil, breakerError(execErr)
+	})
+	if err != nil && execErr == nil {
+		// A breaker state error (open, too many requests).
+		return sp.wrapErr(OpRaw, key, err)
+	}
+	return execErr
+}
+
+// withConnectionDirect calls fn with an acquired connection, then releases
+// the connection, or destroys it when fn failed or panicked and the
+// connection can't be reused. The errors of fn are returned as is.
+func (sp *ServerPool) withConnectionDirect(ctx context.Context, key string, fn func(conn *Connection) error) error {
+	resource, err := sp.acquire(ctx)
+	if err != nil {
+		return sp.wrapErr(OpRaw, key, err)
+	}
+
+	returned := false
+	defer func() {
+		if !returned { // fn panicked: the connection state is unknown
+			sp.destroy(resource)
+		}
+	}()
+
+	err = fn(resource.Value())
+	returned = true
+
+	if meta.ShouldCloseConnection(err) {
+		sp.destroy(resource)
+	} else {
+		sp.release(resource)
+	}
+	return err
+}
+
 // ExecuteBatch executes multiple requests in a pipeline using the NoOp marker strategy.
 // Sends all requests followed by a NoOp command, then reads responses until the NoOp response.
 // This leverages memcached's FIFO guarantee for optimal performance.
@@ -206,9 +646,56 @@ func (sp *ServerPool) ExecuteBatch(ctx context.Context, reqs []*meta.Request) ([
 	if len(reqs) == 0 {
 		return nil, nil
 	}
+	sp.active.Add(1)
+	defer sp.active.Add(-1)
+
+	if len(sp.hooks) == 0 {
+		return sp.executeBatch(ctx, reqs)
+	}
+
+	op := OpInfo{Op: OpBatch, Server: sp.addr, Requests: reqs}
+	start := time.Now()
+	ctx = sp.hooks.before(ctx, op)
+
+	responses, err := sp.executeBatch(ctx, reqs)
+
+	sp.hooks.after(ctx, op, OpResult{Duration: time.Since(start), Responses: responses, Err: err})
+	return responses, err
+}
+
+// executeBatch runs a batch, with the value checksums if enabled (see
+// Config.ValueChecksums).
+func (sp *ServerPool) executeBatch(ctx context.Context, reqs []*meta.Request) ([]*meta.Response, error) {
+	if !sp.checksums {
+		return sp.execBatchBreaker(ctx, reqs)
+	}
+	sealed, err := sealBatch(reqs)
+	if err != nil {
+		return nil, sp.wrapErr(OpBatch, "", err)
+	}
+	responses, err := sp.execBatchBreaker(ctx, sealed)
+	if err == nil {
+		for i, resp := range responses {
+			if resp != nil && i < len(reqs) {
+				sp.openResponse(ctx, reqs[i], resp)
+			}
+		}
+	}
+	return responses, err
+}
+
+// execBatchBreaker runs a batch through the circuit breaker, if any.
+func (sp *ServerPool) execBatchBreaker(ctx context.Context, reqs []*meta.Request) ([]*meta.Response, error) {
+	settings := sp.settings.Load()
+	ctx = withDefaultTimeout(ctx, cmp.Or(settings.batchTimeout, settings.timeout))
+	if err := sp.shed(ctx); err != nil {
+		return nil, sp.wrapErr(OpBatch, "", err)
+	}
 
 	if sp.circuitBreaker == nil {
-		return sp.execBatchDirect(ctx, reqs)
+		responses, err := sp.execBatchDirect(ctx, reqs)
+		sp.health.record(err)
+		return responses, err
 	}
 
 	var responses []*meta.Response
@@ -216,6 +703,7 @@ func (sp *ServerPool) ExecuteBatch(ctx context.Context, reqs []*meta.Request) ([
 
 	_, err := sp.circuitBreaker.Execute(func() (bool, error) {
 		responses, execErr = sp.execBatchDirect(ctx, reqs)
+		sp.health.record(execErr)
 		return execErr == nil, breakerError(execErr)
 	})
 
@@ -227,7 +715,20 @@ func (sp *ServerPool) ExecuteBatch(ctx context.Context, reqs []*meta.Request) ([
 
 // execBatchDirect performs the actual batch execution without circuit breaker.
 func (sp *ServerPool) execBatchDirect(ctx context.Context, reqs []*meta.Request) ([]*meta.Response, error) {
-	resource, err := sp.pool.Acquire(ctx)
+	if sp.verifyBatches {
+		reqs = tagBatch(reqs)
+	}
+
+	if sp.pipelines != nil {
+		responses, err := sp.pipelines.executeBatch(ctx, reqs)
+		if err != nil {
+			sp.checkMismatch(ctx, OpBatch, err)
+			return nil, sp.wrapErr(OpBatch, "", err)
+		}
+		return responses, nil
+	}
+
+	resource, err := sp.acquire(ctx)
 	if err != nil {
 		return nil, sp.wrapErr(OpBatch, "", err)
 	}
@@ -236,8 +737,9 @@ func (sp *ServerPool) execBatchDirect(ctx context.Context, reqs []*meta.Request)
 
 	responses, err := conn.ExecuteBatch(ctx, reqs)
 	if err != nil {
+		sp.checkMismatch(ctx, OpBatch, err)
 		if meta.ShouldCloseConnection(err) {
-			resource.Destroy()
+			sp.destroy(resource)
 		} else {
 			sp.release(resource)
 		}
@@ -254,9 +756,58 @@ func (sp *ServerPool) execBatchDirect(ctx context.Context, reqs []*meta.Request)
 		}
 	}
 	if destroy {
-		resource.Destroy()
+		sp.destroy(resource)
 	} else {
 		sp.release(resource)
 	}
 	return responses, nil
 }
+
+// ExecuteStats retrieves the server statistics with the stats command.
+// The stats command is not wrapped with the circuit breaker.
+func (sp *ServerPool) ExecuteStats(ctx context.Context, args ...string) (map[string]string, error) {
+	sp.active.Add(1)
+	defer sp.active.Add(-1)
+
+	if len(sp.hooks) == 0 {
+		return sp.executeStats(ctx, args...)
+	}
+
+	op := OpInfo{Op: OpStats, Server: sp.addr}
+	start := time.Now()
+	ctx = sp.hooks.before(ctx, op)
+
+	stats, err := sp.executeStats(ctx, args...)
+
+	sp.hooks.after(ctx, op, OpResult{Duration: time.Since(start), Err: err})
+	return stats, err
+}
+
+func (sp *ServerPool) executeStats(ctx context.Context, args ...string) (map[string]string, error) {
+	ctx = withDefaultTimeout(ctx, sp.settings.Load().timeout)
+	if sp.pipelines != nil {
+		stats, err := sp.pipelines.executeStats(ctx, args...)
+		if err != nil {
+			return nil, sp.wrapErr(OpStats, "", err)
+		}
+		return stats, nil
+	}
+
+	resource, err := sp.acquire(ctx)
+	if err != nil {
+		return nil, sp.wrapErr(OpStats, "", err)
+	}
+
+	stats, err := resource.Value().ExecuteStats(ctx, args...)
+	if err != nil {
+		if meta.ShouldCloseConnection(err) {
+			sp.destroy(resource)
+		} else {
+			sp.release(resource)
+		}
+		return nil, sp.wrapErr(OpStats, "", err)
+	}
+
+	sp.release(resource)
+	return stats, nil
+}
@@ -1,12 +1,15 @@
 package memcache
 
 import (
+	"bytes"
 	"context"
 	"errors"
+	"log/slog"
 	"net"
 	"testing"
 	"time"
 
+	"github.com/pior/memcache/internal/testutils"
 	"github.com/pior/memcache/meta"
 	"github.com/sony/gobreaker/v2"
 	"github.com/stretchr/testify/assert"
@@ -203,3 +206,199 @@ func TestOpError_Wrapping(t *testing.T) {
 		assert.False(t, stillWrapped, "the cause must not be another OpError")
 	})
 }
+
+func newIdleServerPool(t *testing.T, config Config) *ServerPool {
+	t.Helper()
+	config.MaxSize = 1
+	config.Dialer = DialerFunc(func(ctx context.Context, network, address string) (net.Conn, error) {
+		return idleNetConn{}, nil
+	})
+	config.NewPool = NewChannelPool
+	sp, err := NewServerPool("test:11211", config)
+	require.NoError(t, err)
+	t.Cleanup(sp.pool.Close)
+	return sp
+}
+
+func TestServerPool_AcquireTimeout(t *testing.T) {
+	sp := newIdleServerPool(t, Config{AcquireTimeout: 20 * time.Millisecond})
+
+	held, err := sp.acquire(context.Background())
+	require.NoError(t, err)
+
+	start := time.Now()
+	_, err = sp.acquire(context.Background())
+	require.ErrorIs(t, err, ErrPoolExhausted)
+	assert.GreaterOrEqual(t, time.Since(start), 20*time.Millisecond)
+
+	// The caller's own deadline is not reported as pool exhaustion.
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+	defer cancel()
+	_, err = sp.acquire(ctx)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.NotErrorIs(t, err, ErrPoolExhausted)
+
+	sp.release(held)
+	res, err := sp.acquire(context.Background())
+	require.NoError(t, err)
+	sp.release(res)
+	assert.Equal(t, int32(0), sp.pending.Load())
+}
+
+// blackholeDialer dials a server that never accepts the connections: the dial
+// lasts until its context is done.
+var blackholeDialer = DialerFunc(func(ctx context.Context, network, address string) (net.Conn, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+})
+
+func TestServerPool_DialTimeout(t *testing.T) {
+	for _, acquireTimeout := range []time.Duration{0, time.Second} {
+		sp, err := NewServerPool("test:11211", Config{
+			MaxSize:        1,
+			ConnectTimeout: 10 * time.Millisecond,
+			AcquireTimeout: acquireTimeout,
+			Dialer:         blackholeDialer,
+			NewPool:        NewChannelPool,
+		})
+		require.NoError(t, err)
+		t.Cleanup(sp.pool.Close)
+
+		// A dial timing out is the server's failure, not pool exhaustion.
+		_, err = sp.acquire(context.Background())
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+		require.NotErrorIs(t, err, ErrPoolExhausted)
+		assert.Error(t, breakerError(&OpError{Err: err}))
+		assert.Equal(t, int32(0), sp.pending.Load())
+	}
+}
+
+func TestServerPool_MaxWaitQueue(t *testing.T) {
+	sp := newIdleServerPool(t, Config{MaxWaitQueue: 1})
+
+	held, err := sp.acquire(context.Background())
+	require.NoError(t, err)
+
+	waiterDone := make(chan error)
+	go func() {
+		res, err := sp.acquire(context.Background())
+		if err == nil {
+			sp.release(res)
+		}
+		waiterDone <- err
+	}()
+	require.Eventually(t, func() bool { return sp.pending.Load() == 2 }, time.Second, time.Millisecond)
+
+	// The queue is full: fail fast.
+	_, err = sp.acquire(context.Background())
+	require.ErrorIs(t, err, ErrPoolExhausted)
+
+	// Exhaustion doesn't count against the server in the circuit breaker.
+	assert.NoError(t, breakerError(&OpError{Err: err}))
+
+	sp.release(held)
+	require.NoError(t, <-waiterDone)
+	assert.Equal(t, int32(0), sp.pending.Load())
+}
+
+// deadlineConn records the deadlines set on a mock connection.
+type deadlineConn struct {
+	*testutils.ConnectionMock
+	deadlines []time.Time
+}
+
+func (c *deadlineConn) SetDeadline(t time.Time) error {
+	if !t.IsZero() {
+		c.deadlines = append(c.deadlines, t)
+	}
+	return nil
+}
+
+func TestServerPool_TimeoutClasses(t *testing.T) {
+	conn := &deadlineConn{ConnectionMock: testutils.NewConnectionMock(
+		"EN\r\n",       // get
+		"HD\r\n",       // set
+		"EN\r\nMN\r\n", // multi get
+		"MN\r\n",       // ping
+		"EN\r\n",       // get with timeout
+	)}
+	client := NewClient(StaticServers("localhost:11211"), Config{
+		Dialer:       &mockDialer{conn: conn},
+		Timeout:      time.Second,
+		ReadTimeout:  time.Hour,
+		WriteTimeout: 2 * time.Hour,
+		BatchTimeout: 3 * time.Hour,
+	})
+	t.Cleanup(client.Close)
+
+	lastTimeout := func() time.Duration {
+		t.Helper()
+		require.NotEmpty(t, conn.deadlines)
+		return time.Until(conn.deadlines[len(conn.deadlines)-1]).Round(time.Second)
+	}
+	ctx := context.Background()
+
+	_, err := client.Get(ctx, "key")
+	require.NoError(t, err)
+	assert.Equal(t, time.Hour, lastTimeout())
+
+	require.NoError(t, client.Set(ctx, Item{Key: "key", Value: []byte("v")}))
+	assert.Equal(t, 2*time.Hour, lastTimeout())
+
+	_, err = NewBatchCommands(client).MultiGet(ctx, []string{"key"})
+	require.NoError(t, err)
+	assert.Equal(t, 3*time.Hour, lastTimeout())
+
+	_, err = client.Ping(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, time.Second, lastTimeout(), "other commands use Timeout")
+
+	_, err = client.Get(ctx, "key", WithTimeout(4*time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, 4*time.Hour, lastTimeout(), "WithTimeout wins over the class")
+}
+
+func TestVerifyOpaque(t *testing.T) {
+	mock := testutils.NewConnectionMock("VA 1 f0 O1\r\na\r\n", "HD O2\r\n", "VA 1 f0 O0\r\nb\r\n", "EN O1\r\n", "MN\r\n")
+	client := NewClient(StaticServers("localhost:11211"), Config{
+		Dialer:       &mockDialer{conn: mock},
+		VerifyOpaque: true,
+	})
+	t.Cleanup(client.Close)
+
+	ctx := context.Background()
+	item, err := client.Get(ctx, "a")
+	require.NoError(t, err)
+	assert.Equal(t, "a", string(item.Value))
+	require.NoError(t, client.Delete(ctx, "a"))
+
+	// Batches are tagged by position, as with VerifyBatchResponses.
+	items, err := NewBatchCommands(client).MultiGet(ctx, []string{"b", "c"})
+	require.NoError(t, err)
+	assert.Equal(t, "b", string(items[0].Value))
+	assert.False(t, items[1].Found)
+
+	assertRequest(t, mock, "mg a v f O1\r\nmd a O2\r\nmg b v f O0\r\nmg c v f O1\r\nmn\r\n")
+}
+
+func TestVerifyOpaque_Mismatch(t *testing.T) {
+	var logs bytes.Buffer
+	mock := testutils.NewConnectionMock("VA 1 f0 O7\r\nx\r\n")
+	client := NewClient(StaticServers("localhost:11211"), Config{
+		Dialer:       &mockDialer{conn: mock},
+		VerifyOpaque: true,
+		Logger:       slog.New(slog.NewTextHandler(&logs, nil)),
+	})
+	t.Cleanup(client.Close)
+
+	_, err := client.Get(context.Background(), "a")
+	require.ErrorIs(t, err, ErrResponseMismatch)
+	assertRequest(t, mock, "mg a v f O1\r\n")
+
+	metrics := client.PoolMetrics()[0]
+	assert.Equal(t, uint64(1), metrics.ResponseMismatches)
+	assert.Eventually(t, func() bool {
+		return client.PoolMetrics()[0].Conns.DestroyedConns == 1
+	}, time.Second, 10*time.Millisecond)
+	assert.Contains(t, logs.String(), "level=ERROR msg=\"memcache: response mismatch\" op=mg server=localhost:11211")
+}
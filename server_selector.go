@@ -1,19 +1,129 @@
 package memcache
 
 import (
+	"crypto/md5"
+	"encoding/binary"
+	"hash/crc32"
+	"math/bits"
+
 	"github.com/pior/memcache/internal"
 	"github.com/zeebo/xxh3"
 )
 
 // ServerSelector picks which server to use for a given key.
-// It receives the key and the current list of server addresses.
-// Returns empty string and error if no server can be selected.
+// It receives the key and the current number of servers, and returns the
+// index of the selected server.
 type ServerSelector func(key string, serverCount int) int
 
-// JumpSelectServer uses Jump Hash for consistent server selection.
+// HashServerSelector picks which server to use for a key from its hash (see
+// KeyHash), computed once by the client. It receives the hash and the current
+// number of servers, and returns the index of the selected server.
+type HashServerSelector func(keyHash uint64, serverCount int) int
+
+// KeyHash hashes a key for a HashServerSelector (Config.KeyHash). Matching
+// the key placement of another client sharing the servers takes the same hash
+// function and the same selector: e.g. CRC32KeyHash with ModuloServerSelector
+// for the clients hashing keys with CRC32 modulo the number of servers.
+type KeyHash func(key string) uint64
+
+// HashKey is the default KeyHash: the 64-bit xxh3 hash of the key bytes.
+func HashKey(key string) uint64 {
+	return xxh3.HashString(key)
+}
+
+// FNV1aKeyHash is the 32-bit FNV-1a hash of the key bytes (FNV1A_32 in
+// libmemcached and spymemcached).
+func FNV1aKeyHash(key string) uint64 {
+	h := uint32(2166136261)
+	for i := 0; i < len(key); i++ {
+		h ^= uint32(key[i])
+		h *= 16777619
+	}
+	return uint64(h)
+}
+
+// FNV1a64KeyHash is the 64-bit FNV-1a hash of the key bytes.
+func FNV1a64KeyHash(key string) uint64 {
+	h := uint64(14695981039346656037)
+	for i := 0; i < len(key); i++ {
+		h ^= uint64(key[i])
+		h *= 1099511628211
+	}
+	return h
+}
+
+// CRC32KeyHash is the CRC-32 (IEEE) checksum of the key bytes, as hashed by
+// gomemcache and PHP's memcache extension.
+func CRC32KeyHash(key string) uint64 {
+	return uint64(crc32.ChecksumIEEE([]byte(key)))
+}
+
+// KetamaKeyHash is the ketama hash of the key: the first 4 bytes of its MD5
+// digest, little-endian (KETAMA_HASH in spymemcached, MD5 in libmemcached).
+func KetamaKeyHash(key string) uint64 {
+	sum := md5.Sum([]byte(key))
+	return uint64(binary.LittleEndian.Uint32(sum[:4]))
+}
+
+// Murmur3KeyHash is the 32-bit MurmurHash3 (x86) of the key bytes, with seed 0.
+func Murmur3KeyHash(key string) uint64 {
+	const (
+		c1 = 0xcc9e2d51
+		c2 = 0x1b873593
+	)
+
+	var h uint32
+	n := len(key) / 4 * 4
+	for i := 0; i < n; i += 4 {
+		k := uint32(key[i]) | uint32(key[i+1])<<8 | uint32(key[i+2])<<16 | uint32(key[i+3])<<24
+		k *= c1
+		k = bits.RotateLeft32(k, 15)
+		k *= c2
+		h ^= k
+		h = bits.RotateLeft32(h, 13)
+		h = h*5 + 0xe6546b64
+	}
+
+	var k uint32
+	switch len(key) - n {
+	case 3:
+		k ^= uint32(key[n+2]) << 16
+		fallthrough
+	case 2:
+		k ^= uint32(key[n+1]) << 8
+		fallthrough
+	case 1:
+		k ^= uint32(key[n])
+		k *= c1
+		k = bits.RotateLeft32(k, 15)
+		k *= c2
+		h ^= k
+	}
+
+	h ^= uint32(len(key))
+	h ^= h >> 16
+	h *= 0x85ebca6b
+	h ^= h >> 13
+	h *= 0xc2b2ae35
+	h ^= h >> 16
+	return uint64(h)
+}
+
+// DefaultServerSelector uses Jump Hash for consistent server selection.
 // Jump Hash provides better distribution and fewer key movements when servers are added/removed.
-// For a single server, it returns that server directly.
-// Returns error if no servers are available.
 func DefaultServerSelector(key string, serverCount int) int {
-	return internal.JumpHash(xxh3.HashString(key), serverCount)
+	return JumpServerSelector(HashKey(key), serverCount)
+}
+
+// JumpServerSelector is DefaultServerSelector as a HashServerSelector: it
+// selects the same server for the hash of a key.
+func JumpServerSelector(keyHash uint64, serverCount int) int {
+	return internal.JumpHash(keyHash, serverCount)
+}
+
+// ModuloServerSelector selects the server at the index of the key hash modulo
+// the number of servers, like most of the clients without consistent
+// hashing. Unlike Jump Hash, changing the number of servers moves most keys.
+func ModuloServerSelector(keyHash uint64, serverCount int) int {
+	return int(keyHash % uint64(serverCount))
 }
@@ -57,6 +57,13 @@ func TestDefaultServerSelector(t *testing.T) {
 	})
 }
 
+func TestJumpServerSelector(t *testing.T) {
+	for i := range 100 {
+		key := fmt.Sprintf("key-%d", i)
+		require.Equal(t, DefaultServerSelector(key, 10), JumpServerSelector(HashKey(key), 10), "key %s", key)
+	}
+}
+
 func BenchmarkDefaultServerSelector(b *testing.B) {
 	key := "benchmark-key-123"
 	serverCount := 10
@@ -65,3 +72,43 @@ func BenchmarkDefaultServerSelector(b *testing.B) {
 		DefaultServerSelector(key, serverCount)
 	}
 }
+
+func TestKeyHashes(t *testing.T) {
+	tests := []struct {
+		name string
+		hash KeyHash
+		key  string
+		want uint64
+	}{
+		{"fnv1a", FNV1aKeyHash, "", 0x811c9dc5},
+		{"fnv1a", FNV1aKeyHash, "a", 0xe40c292c},
+		{"fnv1a64", FNV1a64KeyHash, "a", 0xaf63dc4c8601ec8c},
+		{"crc32", CRC32KeyHash, "123456789", 0xcbf43926},
+		{"ketama", KetamaKeyHash, "hello", 0x2a40415d},
+		{"murmur3", Murmur3KeyHash, "", 0},
+		{"murmur3", Murmur3KeyHash, "hello", 0x248bfa47},
+		{"murmur3", Murmur3KeyHash, "The quick brown fox jumps over the lazy dog", 0x2e4ff723},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name+"/"+tt.key, func(t *testing.T) {
+			require.Equal(t, tt.want, tt.hash(tt.key))
+		})
+	}
+}
+
+func TestClient_KeyHash(t *testing.T) {
+	servers := StaticServers("server1:11211", "server2:11211", "server3:11211")
+	client := NewClient(servers, Config{
+		KeyHash:            CRC32KeyHash,
+		HashServerSelector: ModuloServerSelector,
+	})
+	t.Cleanup(client.Close)
+
+	for i := range 20 {
+		key := fmt.Sprintf("key-%d", i)
+		addr, err := client.selectServerForKey(key)
+		require.NoError(t, err)
+		require.Equal(t, servers.List()[CRC32KeyHash(key)%3], addr, "key %s", key)
+	}
+}
@@ -3,6 +3,8 @@ package memcache
 import (
 	"fmt"
 	"os"
+	"slices"
+	"strconv"
 	"strings"
 )
 
@@ -10,6 +12,8 @@ import (
 // Implementations must be safe for concurrent use.
 type Servers interface {
 	// List returns the current list of server addresses.
+	// An address listed several times gets a share of the keys per entry
+	// (see WeightedServers).
 	List() []string
 }
 
@@ -24,22 +28,95 @@ func (s servers) List() []string {
 	return []string(s)
 }
 
+// WeightedServer is a server address with its weight (see WeightedServers).
+type WeightedServer struct {
+	Addr   string
+	Weight int // Weights <= 0 count as 1
+}
+
+// WeightedServers returns a Servers where each server gets a share of the
+// keys proportional to its weight, for heterogeneous fleets: e.g. a weight
+// proportional to the memory of the server.
+//
+// The selectors place keys on the entries of the server list, so a server of
+// weight N is listed N times, like the virtual nodes of a hash ring. The
+// entries are listed round by round: every server once, then the servers of
+// weight 2 or more, and so on. Raising the weight of the heaviest server
+// then only appends entries: with Jump Hash, the default selector, the keys
+// that move all move to that server.
+func WeightedServers(weighted ...WeightedServer) servers {
+	var list []string
+	for round := 1; ; round++ {
+		n := len(list)
+		for _, s := range weighted {
+			if round == 1 || s.Weight >= round {
+				list = append(list, s.Addr)
+			}
+		}
+		if len(list) == n {
+			return servers(list)
+		}
+	}
+}
+
+// ParseServer parses a server declaration: an address, optionally followed
+// by its weight, as in "10.0.0.1:11211 weight=2".
+func ParseServer(s string) (WeightedServer, error) {
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return WeightedServer{}, fmt.Errorf("empty server declaration")
+	}
+
+	server := WeightedServer{Addr: fields[0], Weight: 1}
+	for _, field := range fields[1:] {
+		value, ok := strings.CutPrefix(field, "weight=")
+		if !ok {
+			return WeightedServer{}, fmt.Errorf("server %s: unknown option %q", server.Addr, field)
+		}
+		weight, err := strconv.Atoi(value)
+		if err != nil || weight < 1 {
+			return WeightedServer{}, fmt.Errorf("server %s: invalid weight %q", server.Addr, value)
+		}
+		server.Weight = weight
+	}
+	return server, nil
+}
+
 // ServersFromEnv creates a Servers instance from a comma-separated list of
-// server addresses stored in the specified environment variable.
+// server declarations stored in the specified environment variable. Each
+// declaration is an address, optionally with a weight (see ParseServer):
+// "10.0.0.1:11211 weight=2,10.0.0.2:11211".
 func ServersFromEnv(envVar string) (Servers, error) {
 	value := os.Getenv(envVar)
 	if value == "" {
 		return nil, fmt.Errorf("environment variable %s not set", envVar)
 	}
 
-	var addrs []string
-	for addr := range strings.SplitSeq(value, ",") {
-		if addr = strings.TrimSpace(addr); addr != "" {
-			addrs = append(addrs, addr)
+	var weighted []WeightedServer
+	for decl := range strings.SplitSeq(value, ",") {
+		if strings.TrimSpace(decl) == "" {
+			continue
 		}
+		server, err := ParseServer(decl)
+		if err != nil {
+			return nil, fmt.Errorf("environment variable %s: %w", envVar, err)
+		}
+		weighted = append(weighted, server)
 	}
-	if len(addrs) == 0 {
+	if len(weighted) == 0 {
 		return nil, fmt.Errorf("environment variable %s contains no server address", envVar)
 	}
-	return StaticServers(addrs...), nil
+	return WeightedServers(weighted...), nil
+}
+
+// uniqueServers returns the distinct addresses of a server list, in order.
+func uniqueServers(list []string) []string {
+	seen := make(map[string]bool, len(list))
+	return slices.DeleteFunc(slices.Clone(list), func(addr string) bool {
+		if seen[addr] {
+			return true
+		}
+		seen[addr] = true
+		return false
+	})
 }
@@ -2,6 +2,7 @@ package memcache
 
 import (
 	"context"
+	"fmt"
 	"sync"
 	"testing"
 
@@ -41,6 +42,45 @@ func TestStaticServers_SingleServer(t *testing.T) {
 	assert.Equal(t, "localhost:11211", list[0])
 }
 
+func TestWeightedServers(t *testing.T) {
+	servers := WeightedServers(
+		WeightedServer{Addr: "server1:11211", Weight: 3},
+		WeightedServer{Addr: "server2:11211"},
+		WeightedServer{Addr: "server3:11211", Weight: 2},
+	)
+	assert.Equal(t, []string{
+		"server1:11211", "server2:11211", "server3:11211",
+		"server1:11211", "server3:11211",
+		"server1:11211",
+	}, servers.List())
+
+	// The keys are shared in proportion to the weights.
+	counts := map[string]int{}
+	list := servers.List()
+	for i := range 6000 {
+		counts[list[DefaultServerSelector(fmt.Sprintf("key-%d", i), len(list))]]++
+	}
+	assert.InDelta(t, 3000, counts["server1:11211"], 300)
+	assert.InDelta(t, 1000, counts["server2:11211"], 300)
+	assert.InDelta(t, 2000, counts["server3:11211"], 300)
+}
+
+func TestParseServer(t *testing.T) {
+	server, err := ParseServer("10.0.0.1:11211")
+	require.NoError(t, err)
+	assert.Equal(t, WeightedServer{Addr: "10.0.0.1:11211", Weight: 1}, server)
+
+	server, err = ParseServer(" 10.0.0.1:11211  weight=2 ")
+	require.NoError(t, err)
+	assert.Equal(t, WeightedServer{Addr: "10.0.0.1:11211", Weight: 2}, server)
+
+	_, err = ParseServer("10.0.0.1:11211 weight=0")
+	require.ErrorContains(t, err, "invalid weight")
+
+	_, err = ParseServer("10.0.0.1:11211 size=2")
+	require.ErrorContains(t, err, "unknown option")
+}
+
 // =============================================================================
 // Concurrent Access Tests
 // =============================================================================
@@ -116,6 +156,25 @@ func TestClient_SelectServerForKey_CustomSelector(t *testing.T) {
 	assert.Equal(t, "server1:11211", addr2)
 }
 
+func TestClient_SelectServerForKey_HashSelector(t *testing.T) {
+	servers := StaticServers("server1:11211", "server2:11211", "server3:11211")
+
+	var hashes []uint64
+	client := NewClient(servers, Config{
+		MaxSize: 1,
+		HashServerSelector: func(keyHash uint64, serverCount int) int {
+			hashes = append(hashes, keyHash)
+			return 2
+		},
+	})
+	t.Cleanup(func() { client.Close() })
+
+	addr, err := client.selectServerForKey("any-key")
+	require.NoError(t, err)
+	assert.Equal(t, "server3:11211", addr)
+	assert.Equal(t, []uint64{HashKey("any-key")}, hashes)
+}
+
 func TestClient_SingleServer(t *testing.T) {
 	servers := StaticServers("localhost:11211")
 
@@ -180,6 +239,17 @@ func TestServersFromEnv_Parsing(t *testing.T) {
 		assert.Equal(t, []string{"server1:11211", "server2:11211"}, servers.List())
 	})
 
+	t.Run("weights", func(t *testing.T) {
+		t.Setenv("MEMCACHE_SERVERS", "server1:11211 weight=2,server2:11211")
+		servers, err := ServersFromEnv("MEMCACHE_SERVERS")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"server1:11211", "server2:11211", "server1:11211"}, servers.List())
+
+		t.Setenv("MEMCACHE_SERVERS", "server1:11211 weight=x")
+		_, err = ServersFromEnv("MEMCACHE_SERVERS")
+		require.ErrorContains(t, err, "invalid weight")
+	})
+
 	t.Run("only separators is an error", func(t *testing.T) {
 		t.Setenv("MEMCACHE_SERVERS", " , ,")
 		_, err := ServersFromEnv("MEMCACHE_SERVERS")
@@ -0,0 +1,105 @@
+package memcache
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/pior/memcache/meta"
+)
+
+// WriteSession tracks the writes of a request scope (an HTTP request, a job,
+// ...) for read-your-writes consistency: the calls made with WithWriteSession
+// record the CAS value of each item they store (Set, Increment, ...) and
+// forget the items they delete, and a get of a stored item that doesn't
+// return this very write fails with ErrStaleRead. The caller can then bypass
+// the cache and read the source of truth.
+//
+// A stale read happens when the server answering the get is not the one that
+// took the write: a server ejected then added back (Config.AutoEject), a
+// server list changed meanwhile, a mirror or replica behind a proxy. A miss of
+// a stored item (evicted, expired) is stale too. The CAS values are counters
+// of each server, unrelated between servers, so any other CAS value is
+// reported: a write of the key by another writer fails the get as well.
+//
+// It applies to the single-key commands (Set, Add, Get, GetWithOptions, ...),
+// not to the batches, and is not supported by ProtocolText. A WriteSession is
+// safe for concurrent use.
+type WriteSession struct {
+	mu  sync.Mutex
+	cas map[string]uint64
+}
+
+// NewWriteSession returns an empty WriteSession.
+func NewWriteSession() *WriteSession {
+	return &WriteSession{cas: make(map[string]uint64)}
+}
+
+// WithWriteSession tracks the call in session: the stores request the CAS
+// value of the item they write, and the gets verify they read the last write
+// of the session. See WriteSession.
+func WithWriteSession(session *WriteSession) CallOption {
+	return func(o *callOptions) { o.session = session }
+}
+
+// writeSessionKey is the context key of the session set by WithWriteSession.
+type writeSessionKey struct{}
+
+// tracks reports whether req is tracked by a session: a store or an
+// arithmetic records its CAS value, a delete forgets it, a get verifies it.
+func (s *WriteSession) tracks(req *meta.Request) bool {
+	switch req.Command {
+	case meta.CmdSet, meta.CmdArithmetic, meta.CmdDelete, meta.CmdGet:
+		return true
+	default:
+		return false
+	}
+}
+
+// needsCAS reports whether the tracked request req returns the CAS value:
+// all but the deletes, for which the c flag would be a compare-and-swap.
+func (s *WriteSession) needsCAS(req *meta.Request) bool {
+	return req.Command != meta.CmdDelete
+}
+
+// observe records the CAS value of a store or an arithmetic, forgets the key
+// of a delete, and verifies the CAS value of a get.
+func (s *WriteSession) observe(req *meta.Request, resp *meta.Response) error {
+	if resp.HasError() {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch req.Command {
+	case meta.CmdSet, meta.CmdArithmetic:
+		if cas, ok := resp.CAS(); ok && resp.IsSuccess() {
+			s.cas[req.Key] = cas
+		}
+	case meta.CmdDelete:
+		// Deleted, or already gone: a miss is then expected.
+		if resp.IsSuccess() || resp.Status == meta.StatusNF {
+			delete(s.cas, req.Key)
+		}
+	case meta.CmdGet:
+		written, ok := s.cas[req.Key]
+		if !ok {
+			return nil
+		}
+		if resp.IsMiss() {
+			return s.staleRead(req, fmt.Errorf("%w: item missing", ErrStaleRead))
+		}
+		// The CAS values of another server are unrelated: a higher one is not
+		// a later write.
+		if cas, _ := resp.CAS(); cas != written {
+			return s.staleRead(req, fmt.Errorf("%w: CAS %d, written with %d", ErrStaleRead, cas, written))
+		}
+	}
+	return nil
+}
+
+// staleRead returns the stale read error of req, carrying its key in OpError,
+// out of the message.
+func (s *WriteSession) staleRead(req *meta.Request, err error) error {
+	return &OpError{Op: string(req.Command), Key: req.Key, Err: err}
+}
@@ -0,0 +1,95 @@
+package memcache
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pior/memcache/internal/testutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteSession(t *testing.T) {
+	mock := testutils.NewConnectionMock(
+		"HD c10\r\n",
+		"VA 1 f0 c10\r\nv\r\n", // the write
+		"VA 1 f0 c5\r\nu\r\n",  // an older version
+		"VA 1 f0 c12\r\nw\r\n", // another server after a failover, or another writer
+		"EN\r\n",               // lost
+		"EN\r\n",               // not written in the session
+	)
+	client := newTestClient(t, mock)
+	session := NewWriteSession()
+	ctx := context.Background()
+
+	require.NoError(t, client.Set(ctx, Item{Key: "key", Value: []byte("v")}, WithWriteSession(session)))
+
+	item, err := client.Get(ctx, "key", WithWriteSession(session))
+	require.NoError(t, err)
+	assert.Equal(t, "v", string(item.Value))
+
+	_, err = client.Get(ctx, "key", WithWriteSession(session))
+	require.ErrorIs(t, err, ErrStaleRead)
+
+	_, err = client.Get(ctx, "key", WithWriteSession(session))
+	require.ErrorIs(t, err, ErrStaleRead, "a higher CAS value is not a later write")
+
+	_, err = client.Get(ctx, "key", WithWriteSession(session))
+	require.ErrorIs(t, err, ErrStaleRead)
+
+	item, err = client.Get(ctx, "other", WithWriteSession(session))
+	require.NoError(t, err)
+	assert.False(t, item.Found)
+
+	assertRequest(t, mock, "ms key 1 c\r\nv\r\n"+
+		"mg key v f c\r\nmg key v f c\r\nmg key v f c\r\nmg key v f c\r\nmg other v f c\r\n")
+}
+
+func TestWriteSession_Untracked(t *testing.T) {
+	mock := testutils.NewConnectionMock("HD\r\n", "EN\r\n")
+	client := newTestClient(t, mock)
+	session := NewWriteSession()
+	ctx := context.Background()
+
+	// Without the option, a call is not tracked.
+	require.NoError(t, client.Set(ctx, Item{Key: "key", Value: []byte("v")}))
+	item, err := client.Get(ctx, "key", WithWriteSession(session))
+	require.NoError(t, err)
+	assert.False(t, item.Found)
+}
+
+func TestWriteSession_DeleteAndIncrement(t *testing.T) {
+	mock := testutils.NewConnectionMock(
+		"HD c10\r\n",
+		"HD\r\n",
+		"EN\r\n", // deleted in the session
+		"VA 1 c11\r\n1\r\n",
+		"VA 1 f0 c11\r\n1\r\n", // incremented in the session
+		"VA 1 f0 c12\r\n2\r\n",
+	)
+	client := newTestClient(t, mock)
+	session := NewWriteSession()
+	ctx := context.Background()
+
+	require.NoError(t, client.Set(ctx, Item{Key: "key", Value: []byte("v")}, WithWriteSession(session)))
+	require.NoError(t, client.Delete(ctx, "key", WithWriteSession(session)))
+	item, err := client.Get(ctx, "key", WithWriteSession(session))
+	require.NoError(t, err)
+	assert.False(t, item.Found)
+
+	_, err = client.Increment(ctx, "key", 1, NoTTL, WithWriteSession(session))
+	require.NoError(t, err)
+	_, err = client.Get(ctx, "key", WithWriteSession(session))
+	require.NoError(t, err)
+
+	// The key is carried by OpError, out of the message.
+	_, err = client.Get(ctx, "key", WithWriteSession(session))
+	require.ErrorIs(t, err, ErrStaleRead)
+	var opErr *OpError
+	require.ErrorAs(t, err, &opErr)
+	assert.Equal(t, "key", opErr.Key)
+	assert.NotContains(t, err.Error(), "key")
+
+	assertRequest(t, mock, "ms key 1 c\r\nv\r\nmd key\r\nmg key v f c\r\n"+
+		"ma key v D1 J1 N0 c\r\nmg key v f c\r\nmg key v f c\r\n")
+}
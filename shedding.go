@@ -0,0 +1,79 @@
+package memcache
+
+import (
+	"context"
+
+	"github.com/sony/gobreaker/v2"
+)
+
+// Priority is the priority of a call, set with WithPriority, for load
+// shedding (Config.Shedding).
+type Priority int
+
+const (
+	// PriorityLow is for the calls that can be dropped first: prefetches,
+	// background refreshes, analytics, ...
+	PriorityLow Priority = -1
+
+	// PriorityNormal is the priority of the calls without WithPriority.
+	PriorityNormal Priority = 0
+
+	// PriorityCritical is for the calls to protect the most.
+	PriorityCritical Priority = 1
+)
+
+// WithPriority sets the priority of the call, for load shedding
+// (Config.Shedding). The calls default to PriorityNormal.
+func WithPriority(p Priority) CallOption {
+	return func(o *callOptions) { o.priority = p }
+}
+
+// priorityKey is the context key of the priority set by WithPriority.
+type priorityKey struct{}
+
+// callPriority returns the priority set for the call.
+func callPriority(ctx context.Context) Priority {
+	p, _ := ctx.Value(priorityKey{}).(Priority)
+	return p
+}
+
+// SheddingPolicy rejects the low-priority operations on a server under
+// pressure, with ErrShed, to protect the critical traffic during incidents.
+// An operation is shed before it waits for a connection or goes through the
+// circuit breaker: a shed operation doesn't count as a failure.
+type SheddingPolicy struct {
+	// ShedBelow is the priority below which operations can be shed.
+	// Default: PriorityNormal (the PriorityLow operations are shed).
+	ShedBelow Priority
+
+	// MaxWaiters sheds when at least MaxWaiters callers are waiting for a
+	// connection of the server.
+	// Zero disables this criterion.
+	MaxWaiters int32
+
+	// HalfOpen sheds while the circuit breaker of the server is half-open,
+	// leaving its trial requests to the higher-priority operations.
+	HalfOpen bool
+}
+
+// shed returns ErrShed when the policy rejects the operation of ctx.
+func (sp *ServerPool) shed(ctx context.Context) error {
+	policy := sp.shedding
+	if policy == nil || callPriority(ctx) >= policy.ShedBelow {
+		return nil
+	}
+
+	overloaded := false
+	if policy.MaxWaiters > 0 && sp.pipelines == nil {
+		overloaded = sp.pending.Load()-sp.maxSize >= policy.MaxWaiters
+	}
+	if policy.HalfOpen && sp.circuitBreaker != nil {
+		overloaded = overloaded || sp.circuitBreaker.State() == gobreaker.StateHalfOpen
+	}
+	if !overloaded {
+		return nil
+	}
+
+	sp.shedOps.Add(1)
+	return ErrShed
+}
@@ -0,0 +1,72 @@
+package memcache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pior/memcache/internal/testutils"
+	"github.com/sony/gobreaker/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShedding_MaxWaiters(t *testing.T) {
+	ctx := context.Background()
+	client := NewClient(StaticServers("localhost:11211"), Config{
+		Dialer:   &mockDialer{conn: testutils.NewConnectionMock("EN\r\n")},
+		MaxSize:  1,
+		Shedding: &SheddingPolicy{MaxWaiters: 1},
+	})
+	t.Cleanup(client.Close)
+
+	// Hold the only connection, with a caller waiting for it.
+	release, done := startInflightOp(t, client)
+	waiting := make(chan error, 1)
+	go func() {
+		_, err := client.Get(ctx, "key")
+		waiting <- err
+	}()
+	sp, err := client.getPoolForServer("localhost:11211")
+	require.NoError(t, err)
+	require.Eventually(t, func() bool { return sp.pending.Load() == 2 }, time.Second, time.Millisecond)
+
+	_, err = client.Get(ctx, "key", WithPriority(PriorityLow))
+	assert.ErrorIs(t, err, ErrShed)
+	var opErr *OpError
+	require.ErrorAs(t, err, &opErr)
+	assert.Equal(t, "localhost:11211", opErr.Server)
+	assert.Equal(t, uint64(1), client.PoolMetrics()[0].Shed)
+
+	close(release)
+	require.NoError(t, <-done)
+	require.NoError(t, <-waiting, "the normal priority operation is not shed")
+
+	_, err = client.Get(ctx, "key", WithPriority(PriorityLow))
+	assert.NotErrorIs(t, err, ErrShed, "no pressure")
+}
+
+func TestShedding_HalfOpen(t *testing.T) {
+	ctx := context.Background()
+	dialErr := errors.New("connection refused")
+	client := NewClient(StaticServers("localhost:11211"), Config{
+		Dialer: &mockDialer{error: dialErr},
+		CircuitBreakerSettings: &gobreaker.Settings{
+			Timeout:     time.Millisecond,
+			ReadyToTrip: func(counts gobreaker.Counts) bool { return counts.ConsecutiveFailures >= 1 },
+		},
+		Shedding: &SheddingPolicy{HalfOpen: true},
+	})
+	t.Cleanup(client.Close)
+
+	_, err := client.Get(ctx, "key")
+	require.ErrorIs(t, err, dialErr)
+	time.Sleep(5 * time.Millisecond) // the breaker turns half-open
+
+	_, err = client.Get(ctx, "key", WithPriority(PriorityLow))
+	assert.ErrorIs(t, err, ErrShed)
+
+	_, err = client.Get(ctx, "key", WithPriority(PriorityCritical))
+	assert.ErrorIs(t, err, dialErr, "the trial request is left to the higher priorities")
+}
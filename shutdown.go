@@ -0,0 +1,63 @@
+package memcache
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// inflightOps counts the operations in flight, for Shutdown to wait for them.
+type inflightOps struct {
+	n       atomic.Int64
+	closing atomic.Bool
+	idle    chan struct{} // signaled when the last operation ends while closing
+}
+
+// begin registers an operation, or fails with ErrClientClosed once the client
+// is closing. A successful begin must be followed by end.
+func (c *Client) begin() error {
+	c.inflight.n.Add(1)
+	if c.inflight.closing.Load() {
+		c.end()
+		return ErrClientClosed
+	}
+	return nil
+}
+
+// end unregisters an operation.
+func (c *Client) end() {
+	if c.inflight.n.Add(-1) == 0 && c.inflight.closing.Load() {
+		select {
+		case c.inflight.idle <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Shutdown closes the client gracefully: the new operations fail with
+// ErrClientClosed at once, while the operations in flight are given until ctx
+// is done to finish. The client is then closed, as by Close, even if ctx
+// expired first, in which case Shutdown returns the error of ctx.
+//
+// Use Shutdown instead of Close when stopping a process under traffic: with
+// Close, the operations in flight waiting for a connection fail.
+func (c *Client) Shutdown(ctx context.Context) error {
+	c.inflight.closing.Store(true)
+
+	var err error
+	for err == nil && c.inflight.n.Load() != 0 {
+		select {
+		case <-c.inflight.idle:
+		case <-ctx.Done():
+			err = ctx.Err()
+		}
+	}
+
+	c.Close()
+	return err
+}
+
+// IsClosed reports whether Close or Shutdown was called. The operations of a
+// closed client fail with ErrClientClosed.
+func (c *Client) IsClosed() bool {
+	return c.inflight.closing.Load()
+}
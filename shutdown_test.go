@@ -0,0 +1,73 @@
+package memcache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pior/memcache/internal/testutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// startInflightOp starts an operation that runs until release is closed.
+func startInflightOp(t *testing.T, client *Client) (release chan struct{}, done chan error) {
+	started := make(chan struct{})
+	release = make(chan struct{})
+	done = make(chan error, 1)
+	go func() {
+		done <- client.WithRawConnection(context.Background(), "key", func(conn *Connection) error {
+			close(started)
+			<-release
+			return nil
+		})
+	}()
+	<-started
+	return release, done
+}
+
+func TestClient_Shutdown(t *testing.T) {
+	client := newTestClient(t, testutils.NewConnectionMock())
+	release, done := startInflightOp(t, client)
+
+	shutdown := make(chan error, 1)
+	go func() { shutdown <- client.Shutdown(context.Background()) }()
+
+	require.Eventually(t, client.IsClosed, time.Second, time.Millisecond)
+	_, err := client.Get(context.Background(), "key")
+	assert.ErrorIs(t, err, ErrClientClosed, "new operations are rejected")
+
+	select {
+	case <-shutdown:
+		t.Fatal("Shutdown should wait for the operation in flight")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	close(release)
+	require.NoError(t, <-done)
+	require.NoError(t, <-shutdown)
+}
+
+func TestClient_Shutdown_Timeout(t *testing.T) {
+	client := newTestClient(t, testutils.NewConnectionMock())
+	release, _ := startInflightOp(t, client)
+	// Closing the pools waits for the connections in use.
+	time.AfterFunc(50*time.Millisecond, func() { close(release) })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	err := client.Shutdown(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.True(t, client.IsClosed())
+}
+
+func TestClient_IsClosed(t *testing.T) {
+	client := newTestClient(t, testutils.NewConnectionMock())
+	assert.False(t, client.IsClosed())
+
+	client.Close()
+	assert.True(t, client.IsClosed())
+	_, err := client.Get(context.Background(), "key")
+	assert.ErrorIs(t, err, ErrClientClosed)
+}
@@ -13,6 +13,9 @@
 //
 //	STRESS_DURATION  duration of each scenario (default 5s)
 //	STRESS_WORKERS   concurrent workers per scenario (default 16)
+//	STRESS_SERVERS   comma-separated servers (default 127.0.0.1:11211); the
+//	                 scenarios without failure injection shard their keys
+//	                 across them, the others proxy the first one
 //
 // The core invariant: every stored value embeds its key, so any response
 // returning a value that doesn't match the requested key proves the
@@ -21,7 +24,8 @@
 // never is.
 //
 // Network failures are injected in-process: flakyProxy kills connections
-// mid-stream, and an embedded toxiproxy adds latency and jitter (no
+// mid-stream, chaosDialer kills them from the client side, and an embedded
+// toxiproxy adds latency, jitter, bandwidth caps and trickled data (no
 // toxiproxy daemon required).
 package stress
 
@@ -33,6 +37,8 @@ import (
 	"math/rand/v2"
 	"net"
 	"os"
+	"slices"
+	"strconv"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -41,6 +47,7 @@ import (
 
 	toxiproxy "github.com/Shopify/toxiproxy/v2"
 	"github.com/rs/zerolog"
+	"github.com/sony/gobreaker/v2"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
@@ -48,7 +55,18 @@ import (
 	"github.com/pior/memcache/meta"
 )
 
-const stressMemcacheAddr = "127.0.0.1:11211"
+// stressServers returns the servers of STRESS_SERVERS.
+func stressServers() []string {
+	if v := os.Getenv("STRESS_SERVERS"); v != "" {
+		return strings.Split(v, ",")
+	}
+	return []string{"127.0.0.1:11211"}
+}
+
+// stressServer returns the server behind the failure-injection proxies.
+func stressServer() string {
+	return stressServers()[0]
+}
 
 func stressDuration() time.Duration {
 	if v := os.Getenv("STRESS_DURATION"); v != "" {
@@ -86,6 +104,50 @@ func checkValue(t *testing.T, key string, value []byte) {
 	}
 }
 
+// flushAll invalidates all the items of a server with flush_all.
+func flushAll(addr string) error {
+	conn, err := net.DialTimeout("tcp", addr, time.Second)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if err := conn.SetDeadline(time.Now().Add(time.Second)); err != nil {
+		return err
+	}
+
+	if _, err := io.WriteString(conn, "flush_all\r\n"); err != nil {
+		return err
+	}
+	reply := make([]byte, 4)
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		return err
+	}
+	if string(reply) != "OK\r\n" {
+		return fmt.Errorf("flush_all on %s: unexpected reply %q", addr, reply)
+	}
+	return nil
+}
+
+// breakerSettings trips the circuit breaker of a server after a few
+// consecutive failures, and probes it again shortly after.
+func breakerSettings() *gobreaker.Settings {
+	return &gobreaker.Settings{
+		MaxRequests: 1,
+		Timeout:     250 * time.Millisecond,
+		ReadyToTrip: func(counts gobreaker.Counts) bool {
+			return counts.ConsecutiveFailures >= 5
+		},
+	}
+}
+
+// assertBreakersClosed checks that no circuit breaker is left open.
+func assertBreakersClosed(t *testing.T, client *memcache.Client) {
+	t.Helper()
+	for _, pm := range client.PoolMetrics() {
+		assert.Equal(t, "closed", pm.CircuitBreaker.State, "circuit breaker of %s", pm.Addr)
+	}
+}
+
 func truncate(s string, n int) string {
 	if len(s) > n {
 		return s[:n] + "..."
@@ -128,7 +190,7 @@ func runWorkers(t *testing.T, workers int, d time.Duration, fn func(t *testing.T
 // shared key space and verifies that no operation ever observes data
 // belonging to another key.
 func TestStress_MixedWorkload(t *testing.T) {
-	client := memcache.NewClient(memcache.StaticServers(stressMemcacheAddr), memcache.Config{
+	client := memcache.NewClient(memcache.StaticServers(stressServers()...), memcache.Config{
 		MaxSize: 8,
 		Timeout: time.Second,
 	})
@@ -186,7 +248,7 @@ func TestStress_MixedWorkload(t *testing.T) {
 // TestStress_BatchWorkload runs concurrent pipelined batches and verifies
 // positional integrity: response i must belong to key i.
 func TestStress_BatchWorkload(t *testing.T) {
-	client := memcache.NewClient(memcache.StaticServers(stressMemcacheAddr), memcache.Config{
+	client := memcache.NewClient(memcache.StaticServers(stressServers()...), memcache.Config{
 		MaxSize: 8,
 		Timeout: 2 * time.Second,
 	})
@@ -238,7 +300,7 @@ func TestStress_BatchWorkload(t *testing.T) {
 // CLIENT_ERROR responses (arithmetic on non-numeric values) with normal
 // operations. The protocol errors must never desynchronize other requests.
 func TestStress_ErrorInjection(t *testing.T) {
-	client := memcache.NewClient(memcache.StaticServers(stressMemcacheAddr), memcache.Config{
+	client := memcache.NewClient(memcache.StaticServers(stressServers()...), memcache.Config{
 		MaxSize: 4,
 		Timeout: time.Second,
 	})
@@ -312,7 +374,7 @@ func TestStress_ErrorInjection(t *testing.T) {
 // MaxConnLifetime must be enforced at release time, not only on idle
 // connections by the health check loop.
 func TestStress_ConnectionChurn(t *testing.T) {
-	client := memcache.NewClient(memcache.StaticServers(stressMemcacheAddr), memcache.Config{
+	client := memcache.NewClient(memcache.StaticServers(stressServers()...), memcache.Config{
 		MaxSize:             4,
 		Timeout:             time.Second,
 		MaxConnLifetime:     100 * time.Millisecond,
@@ -356,7 +418,7 @@ func TestStress_ConnectionChurn(t *testing.T) {
 // TestStress_Counters runs concurrent increments and verifies the final
 // counter values are exact: lost or duplicated arithmetic would show here.
 func TestStress_Counters(t *testing.T) {
-	client := memcache.NewClient(memcache.StaticServers(stressMemcacheAddr), memcache.Config{
+	client := memcache.NewClient(memcache.StaticServers(stressServers()...), memcache.Config{
 		MaxSize: 8,
 		Timeout: time.Second,
 	})
@@ -389,6 +451,154 @@ func TestStress_Counters(t *testing.T) {
 	}
 }
 
+// TestStress_ShardPlacement verifies that the placement of the keys on the
+// servers is deterministic: every key lives on the server the selector picks,
+// and stays readable through the sharded client while its connections are
+// constantly recycled, and through a new client. A nondeterministic selector
+// would show as misses on keys that exist on another server.
+func TestStress_ShardPlacement(t *testing.T) {
+	servers := stressServers()
+	if len(servers) < 2 {
+		t.Skip("needs at least 2 servers in STRESS_SERVERS (docker compose --profile pool up -d)")
+	}
+	ctx := context.Background()
+
+	newClient := func() *memcache.Client {
+		client := memcache.NewClient(memcache.StaticServers(servers...), memcache.Config{
+			MaxSize:         4,
+			Timeout:         time.Second,
+			MaxConnLifetime: 50 * time.Millisecond,
+		})
+		t.Cleanup(client.Close)
+		return client
+	}
+
+	const keySpace = 1000
+	prefix := fmt.Sprintf("stress:shard:%d:", rand.Uint32())
+	key := func(i int) string { return prefix + strconv.Itoa(i) }
+
+	client := newClient()
+	rng := rand.New(rand.NewPCG(1, 2))
+	for i := range keySpace {
+		require.NoError(t, client.Set(ctx, memcache.Item{Key: key(i), Value: stressValue(key(i), rng), TTL: memcache.ExpiresIn(time.Minute)}))
+	}
+
+	// Each key is on the server of the selector, and only there.
+	perServer := make([]int, len(servers))
+	for s, addr := range servers {
+		direct := memcache.NewClient(memcache.StaticServers(addr), memcache.Config{MaxSize: 1, Timeout: time.Second})
+		for i := range keySpace {
+			item, err := direct.Get(ctx, key(i))
+			require.NoError(t, err)
+
+			want := memcache.DefaultServerSelector(key(i), len(servers)) == s
+			if item.Found != want {
+				t.Errorf("key %q found=%v on %s, want found=%v", key(i), item.Found, addr, want)
+			}
+			if item.Found {
+				perServer[s]++
+			}
+		}
+		direct.Close()
+	}
+	t.Logf("keys per server: %v", perServer)
+
+	var stats stressStats
+	runWorkers(t, stressWorkers(), stressDuration(), func(t *testing.T, workerID int, rng *rand.Rand) {
+		k := key(rng.IntN(keySpace))
+		stats.ops.Add(1)
+
+		item, err := client.Get(ctx, k)
+		if err != nil {
+			stats.errors.Add(1)
+			return
+		}
+		if !item.Found {
+			t.Errorf("key %q missed across reconnects: the placement changed", k)
+			return
+		}
+		checkValue(t, k, item.Value)
+	})
+	stats.report(t)
+	assert.Zero(t, stats.errors.Load(), "no errors expected against healthy local servers")
+
+	// A new client places the keys on the same servers.
+	fresh := newClient()
+	for i := range keySpace {
+		item, err := fresh.Get(ctx, key(i))
+		require.NoError(t, err)
+		assert.True(t, item.Found, "key %q missed by a new client", key(i))
+	}
+}
+
+// TestStress_FlushDuringLoad flushes the servers repeatedly under load: the
+// operations must miss, never fail, and the circuit breakers stay closed.
+func TestStress_FlushDuringLoad(t *testing.T) {
+	client := memcache.NewClient(memcache.StaticServers(stressServers()...), memcache.Config{
+		MaxSize:                8,
+		Timeout:                time.Second,
+		CircuitBreakerSettings: breakerSettings(),
+	})
+	t.Cleanup(client.Close)
+	ctx := context.Background()
+
+	d := stressDuration()
+	var flushes atomic.Int64
+	done := make(chan struct{})
+	flushed := make(chan struct{})
+	go func() {
+		defer close(flushed)
+		ticker := time.NewTicker(d / 10)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				for _, addr := range stressServers() {
+					assert.NoError(t, flushAll(addr))
+				}
+				flushes.Add(1)
+			}
+		}
+	}()
+
+	const keySpace = 100
+	var stats stressStats
+	var misses atomic.Int64
+
+	runWorkers(t, stressWorkers(), d, func(t *testing.T, workerID int, rng *rand.Rand) {
+		key := fmt.Sprintf("stress:flush:%d", rng.IntN(keySpace))
+		stats.ops.Add(1)
+
+		if rng.IntN(2) == 0 {
+			if err := client.Set(ctx, memcache.Item{Key: key, Value: stressValue(key, rng), TTL: memcache.ExpiresIn(time.Minute)}); err != nil {
+				stats.errors.Add(1)
+			}
+			return
+		}
+		item, err := client.Get(ctx, key)
+		if err != nil {
+			stats.errors.Add(1)
+			return
+		}
+		if !item.Found {
+			misses.Add(1)
+			return
+		}
+		checkValue(t, key, item.Value)
+	})
+	close(done)
+	<-flushed
+
+	stats.report(t)
+	t.Logf("flushes=%d misses=%d", flushes.Load(), misses.Load())
+	require.Positive(t, flushes.Load(), "the servers must actually be flushed")
+	assert.Positive(t, misses.Load(), "the flushes must show as misses")
+	assert.Zero(t, stats.errors.Load(), "a flush must not fail operations")
+	assertBreakersClosed(t, client)
+}
+
 // =============================================================================
 // Failure injection via a flaky TCP proxy
 // =============================================================================
@@ -402,6 +612,7 @@ type flakyProxy struct {
 	mu        sync.Mutex
 	conns     map[net.Conn]struct{}
 	accepting atomic.Bool
+	down      atomic.Bool  // the server is down: new connections are closed on accept
 	killRate  atomic.Int64 // per-mille chance to kill the connection after each chunk
 }
 
@@ -436,6 +647,22 @@ func (p *flakyProxy) Stop() {
 	}
 }
 
+// Restart simulates a server restart on the same address: the connections
+// die, new ones are closed on accept for the downtime, and the server comes
+// back empty.
+func (p *flakyProxy) Restart(downtime time.Duration) error {
+	p.down.Store(true)
+	p.mu.Lock()
+	for conn := range p.conns {
+		conn.Close()
+	}
+	p.mu.Unlock()
+
+	time.Sleep(downtime)
+	defer p.down.Store(false)
+	return flushAll(p.backend)
+}
+
 func (p *flakyProxy) track(conn net.Conn) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
@@ -460,6 +687,9 @@ func (p *flakyProxy) acceptLoop() {
 
 func (p *flakyProxy) handle(client net.Conn) {
 	defer client.Close()
+	if p.down.Load() {
+		return
+	}
 	p.track(client)
 	defer p.untrack(client)
 
@@ -508,7 +738,7 @@ func (p *flakyProxy) pump(src, dst net.Conn, done chan<- struct{}) {
 // kills connections. Operations may fail — but a returned value must always
 // belong to the requested key, and the client must recover on its own.
 func TestStress_FlakyNetwork(t *testing.T) {
-	proxy := newFlakyProxy(t, stressMemcacheAddr)
+	proxy := newFlakyProxy(t, stressServer())
 	proxy.SetKillRatePerMille(20) // 2% of forwarded chunks kill the connection
 
 	client := memcache.NewClient(memcache.StaticServers(proxy.Addr()), memcache.Config{
@@ -577,8 +807,261 @@ func TestStress_FlakyNetwork(t *testing.T) {
 	}
 }
 
+// TestStress_ServerRestart restarts the server mid-workload: the connections
+// die, the server refuses connections for a while and comes back empty. The
+// circuit breaker trips during the downtime; once the server is back, the
+// error rate must drop within a bound and no breaker may stay open.
+func TestStress_ServerRestart(t *testing.T) {
+	d := stressDuration()
+	if d < 3*time.Second {
+		t.Skip("needs a STRESS_DURATION of at least 3s to observe the recovery")
+	}
+	const (
+		downtime      = 500 * time.Millisecond
+		recoveryBound = 1500 * time.Millisecond
+	)
+
+	proxy := newFlakyProxy(t, stressServer())
+
+	client := memcache.NewClient(memcache.StaticServers(proxy.Addr()), memcache.Config{
+		MaxSize:                4,
+		Timeout:                300 * time.Millisecond,
+		ConnectTimeout:         300 * time.Millisecond,
+		CircuitBreakerSettings: breakerSettings(),
+	})
+	t.Cleanup(client.Close)
+	ctx := context.Background()
+
+	start := time.Now()
+	restartAt := d / 4
+	recoveredAt := restartAt + downtime + recoveryBound
+
+	var tripped atomic.Bool
+	go func() {
+		time.Sleep(restartAt)
+		assert.NoError(t, proxy.Restart(downtime))
+	}()
+	go func() {
+		for time.Since(start) < recoveredAt {
+			for _, pm := range client.PoolMetrics() {
+				if pm.CircuitBreaker.State == "open" {
+					tripped.Store(true)
+				}
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}()
+
+	const keySpace = 100
+	var stats, recovered stressStats
+
+	runWorkers(t, stressWorkers(), d, func(t *testing.T, workerID int, rng *rand.Rand) {
+		key := fmt.Sprintf("stress:restart:%d", rng.IntN(keySpace))
+		var err error
+		if rng.IntN(2) == 0 {
+			err = client.Set(ctx, memcache.Item{Key: key, Value: stressValue(key, rng), TTL: memcache.ExpiresIn(time.Minute)})
+		} else {
+			var item memcache.Item
+			item, err = client.Get(ctx, key)
+			if err == nil && item.Found {
+				checkValue(t, key, item.Value)
+			}
+		}
+
+		s := &stats
+		if time.Since(start) >= recoveredAt {
+			s = &recovered
+		}
+		s.ops.Add(1)
+		if err != nil {
+			s.errors.Add(1)
+			if errors.Is(err, gobreaker.ErrOpenState) {
+				// The breaker fails fast: don't spin on it.
+				time.Sleep(time.Millisecond)
+			}
+		}
+	})
+
+	stats.report(t)
+	t.Logf("after the recovery bound of %s:", recoveryBound)
+	recovered.report(t)
+
+	require.Positive(t, stats.errors.Load(), "the restart must actually fail operations")
+	assert.True(t, tripped.Load(), "the circuit breaker must trip while the server is down")
+	require.Greater(t, recovered.ops.Load(), int64(100), "the workload must run after the recovery")
+	assert.LessOrEqual(t, float64(recovered.errors.Load()), 0.01*float64(recovered.ops.Load()),
+		"the error rate must drop below 1%% within %s of the restart", recoveryBound)
+	assertBreakersClosed(t, client)
+}
+
 // =============================================================================
-// Latency injection via an embedded toxiproxy
+// Connection kills injected in the client connections
+// =============================================================================
+
+// chaosDialer is a test hook in the client: it tracks the connections it
+// dials, and kills them on demand from the client side. A killed connection
+// delivers part of its next read, or sends part of its next write, then
+// closes: the client is left with a partial response or request.
+type chaosDialer struct {
+	mu    sync.Mutex
+	conns []*chaosConn
+	kills atomic.Int64
+}
+
+func (d *chaosDialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	var nd net.Dialer
+	conn, err := nd.DialContext(ctx, network, address)
+	if err != nil {
+		return nil, err
+	}
+	c := &chaosConn{Conn: conn}
+	d.mu.Lock()
+	d.conns = append(d.conns, c)
+	d.mu.Unlock()
+	return c, nil
+}
+
+// killRandom dooms a random live connection.
+func (d *chaosDialer) killRandom() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.conns = slices.DeleteFunc(d.conns, func(c *chaosConn) bool { return c.closed.Load() })
+	if len(d.conns) == 0 {
+		return
+	}
+	if d.conns[rand.IntN(len(d.conns))].doomed.CompareAndSwap(false, true) {
+		d.kills.Add(1)
+	}
+}
+
+type chaosConn struct {
+	net.Conn
+	doomed atomic.Bool
+	closed atomic.Bool
+}
+
+func (c *chaosConn) Read(b []byte) (int, error) {
+	if !c.doomed.Load() {
+		return c.Conn.Read(b)
+	}
+	n, err := c.Conn.Read(b[:min(len(b), 1+rand.IntN(16))])
+	c.Close()
+	return n, err
+}
+
+func (c *chaosConn) Write(b []byte) (int, error) {
+	if !c.doomed.Load() {
+		return c.Conn.Write(b)
+	}
+	n, _ := c.Conn.Write(b[:rand.IntN(len(b)+1)])
+	c.Close()
+	return n, net.ErrClosed
+}
+
+func (c *chaosConn) Close() error {
+	c.closed.Store(true)
+	return c.Conn.Close()
+}
+
+// TestStress_ConnectionKill kills pooled connections mid-stream from the
+// client side while the workload runs: a response cut in the middle must
+// fail its operation, and never leak into the next operation of the
+// connection. Returned values must always belong to the requested key.
+func TestStress_ConnectionKill(t *testing.T) {
+	dialer := &chaosDialer{}
+	client := memcache.NewClient(memcache.StaticServers(stressServers()...), memcache.Config{
+		MaxSize:        4,
+		Timeout:        500 * time.Millisecond,
+		ConnectTimeout: time.Second,
+		Dialer:         dialer,
+	})
+	t.Cleanup(client.Close)
+	ctx := context.Background()
+
+	const keySpace = 100
+	key := func(i int) string { return fmt.Sprintf("stress:kill:%d", i) }
+	rng := rand.New(rand.NewPCG(1, 2))
+	for i := range keySpace {
+		require.NoError(t, client.Set(ctx, memcache.Item{Key: key(i), Value: stressValue(key(i), rng), TTL: memcache.ExpiresIn(time.Minute)}))
+	}
+
+	stop := make(chan struct{})
+	chaosDone := make(chan struct{})
+	go func() {
+		defer close(chaosDone)
+		ticker := time.NewTicker(2 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				dialer.killRandom()
+			}
+		}
+	}()
+
+	var stats stressStats
+	runWorkers(t, stressWorkers(), stressDuration(), func(t *testing.T, workerID int, rng *rand.Rand) {
+		k := key(rng.IntN(keySpace))
+		stats.ops.Add(1)
+
+		switch rng.IntN(3) {
+		case 0:
+			if err := client.Set(ctx, memcache.Item{Key: k, Value: stressValue(k, rng), TTL: memcache.ExpiresIn(time.Minute)}); err != nil {
+				stats.errors.Add(1)
+			}
+		case 1:
+			item, err := client.Get(ctx, k)
+			if err != nil {
+				stats.errors.Add(1)
+				return
+			}
+			if item.Found {
+				checkValue(t, k, item.Value)
+			}
+		case 2:
+			keys := make([]string, 1+rng.IntN(10))
+			for i := range keys {
+				keys[i] = key(rng.IntN(keySpace))
+			}
+			items, err := memcache.NewBatchCommands(client).MultiGet(ctx, keys)
+			if err != nil {
+				stats.errors.Add(1)
+				return
+			}
+			for i, item := range items {
+				if item.Found {
+					checkValue(t, keys[i], item.Value)
+				}
+			}
+		}
+	})
+	close(stop)
+	<-chaosDone
+
+	stats.report(t)
+	t.Logf("connections killed: %d", dialer.kills.Load())
+	require.Greater(t, stats.ops.Load(), int64(100), "the workload must actually run")
+	require.Positive(t, dialer.kills.Load(), "the dialer must actually kill connections")
+	assert.Positive(t, stats.errors.Load(), "the kills must fail some operations")
+
+	// Once the kills stop, every key reads back intact.
+	for i := range keySpace {
+		var item memcache.Item
+		require.Eventually(t, func() bool {
+			var err error
+			item, err = client.Get(ctx, key(i))
+			return err == nil
+		}, 5*time.Second, 10*time.Millisecond, "client must recover after the kills stop")
+		require.True(t, item.Found, "key %q must survive the kills", key(i))
+		checkValue(t, key(i), item.Value)
+	}
+}
+
+// =============================================================================
+// Network degradation via an embedded toxiproxy
 // =============================================================================
 
 // newToxiproxy starts an in-process toxiproxy forwarding to backend.
@@ -605,23 +1088,35 @@ func newToxiproxy(t *testing.T, backend string) *toxiproxy.Proxy {
 // delay() read never touch the same memory.
 func setLatency(t *testing.T, proxy *toxiproxy.Proxy, latency, jitter time.Duration) {
 	t.Helper()
-	spec := fmt.Sprintf(
-		`{"name":"latency","type":"latency","stream":"downstream","toxicity":1,"attributes":{"latency":%d,"jitter":%d}}`,
-		latency.Milliseconds(), jitter.Milliseconds())
+	setToxic(t, proxy, "latency", fmt.Sprintf(`{"latency":%d,"jitter":%d}`, latency.Milliseconds(), jitter.Milliseconds()))
+}
 
-	if proxy.Toxics.GetToxic("latency") != nil {
-		assert.NoError(t, proxy.Toxics.RemoveToxic(context.Background(), "latency"))
-	}
+// setToxic installs or replaces the toxic of a type on the response stream,
+// as setLatency does.
+func setToxic(t *testing.T, proxy *toxiproxy.Proxy, toxicType, attributes string) {
+	t.Helper()
+	spec := fmt.Sprintf(`{"name":%q,"type":%q,"stream":"downstream","toxicity":1,"attributes":%s}`,
+		toxicType, toxicType, attributes)
+
+	removeToxic(t, proxy, toxicType)
 	_, err := proxy.Toxics.AddToxicJson(strings.NewReader(spec))
 	assert.NoError(t, err)
 }
 
+// removeToxic removes the toxic of a type, if installed.
+func removeToxic(t *testing.T, proxy *toxiproxy.Proxy, toxicType string) {
+	t.Helper()
+	if proxy.Toxics.GetToxic(toxicType) != nil {
+		assert.NoError(t, proxy.Toxics.RemoveToxic(context.Background(), toxicType))
+	}
+}
+
 // TestStress_SlowNetwork runs the workload over a connection with significant
 // latency and jitter, below the client timeout. High RTT changes how responses
 // split across reads and how deeply requests pipeline; correctness must not
 // depend on packet timing, and no operation may fail.
 func TestStress_SlowNetwork(t *testing.T) {
-	proxy := newToxiproxy(t, stressMemcacheAddr)
+	proxy := newToxiproxy(t, stressServer())
 	setLatency(t, proxy, 20*time.Millisecond, 10*time.Millisecond)
 
 	client := memcache.NewClient(memcache.StaticServers(proxy.Listen), memcache.Config{
@@ -683,7 +1178,7 @@ func TestStress_SlowNetwork(t *testing.T) {
 // Errors during spikes are expected; wrong data never, and the client must
 // recover on its own once latency subsides.
 func TestStress_LatencySpikes(t *testing.T) {
-	proxy := newToxiproxy(t, stressMemcacheAddr)
+	proxy := newToxiproxy(t, stressServer())
 
 	const calm = 2 * time.Millisecond
 	const timeout = 150 * time.Millisecond
@@ -770,7 +1265,7 @@ func TestStress_LatencySpikes(t *testing.T) {
 // unreachable mid-workload and comes back: errors during the outage,
 // full recovery after, and never wrong data.
 func TestStress_ServerOutage(t *testing.T) {
-	proxy := newFlakyProxy(t, stressMemcacheAddr)
+	proxy := newFlakyProxy(t, stressServer())
 
 	client := memcache.NewClient(memcache.StaticServers(proxy.Addr()), memcache.Config{
 		MaxSize:        4,
@@ -791,7 +1286,7 @@ func TestStress_ServerOutage(t *testing.T) {
 
 	// Recovery through a fresh proxy on a new address is not possible (the
 	// client holds the address), so verify it against the real server.
-	direct := memcache.NewClient(memcache.StaticServers(stressMemcacheAddr), memcache.Config{MaxSize: 2, Timeout: time.Second})
+	direct := memcache.NewClient(memcache.StaticServers(stressServer()), memcache.Config{MaxSize: 2, Timeout: time.Second})
 	t.Cleanup(direct.Close)
 
 	item, err := direct.Get(ctx, key)
@@ -813,7 +1308,7 @@ func TestStress_ServerOutage(t *testing.T) {
 // hangs (every worker blocks on an unbounded read); with it, every op fails fast
 // within Config.Timeout and the client recovers once the server responds again.
 func TestStress_HungServer(t *testing.T) {
-	proxy := newToxiproxy(t, stressMemcacheAddr)
+	proxy := newToxiproxy(t, stressServer())
 	// Hold responses far beyond any operation timeout: the connection is healthy
 	// but the server never answers in time — a hung node.
 	setLatency(t, proxy, time.Hour, 0)
@@ -887,3 +1382,206 @@ func TestStress_HungServer(t *testing.T) {
 		t.Log("client recovered after the hung server resumed")
 	}
 }
+
+// TestStress_BandwidthLimit caps the bandwidth of the responses: large values
+// take a while to arrive, over many partial reads. Within the timeout, every
+// value must arrive whole and no operation may fail.
+func TestStress_BandwidthLimit(t *testing.T) {
+	proxy := newToxiproxy(t, stressServer())
+	setToxic(t, proxy, "bandwidth", `{"rate":512}`) // KB/s per connection
+
+	client := memcache.NewClient(memcache.StaticServers(proxy.Listen), memcache.Config{
+		MaxSize: 8,
+		Timeout: 2 * time.Second,
+	})
+	t.Cleanup(client.Close)
+	ctx := context.Background()
+
+	// Large values, up to 64KB: 125ms each at the capped rate.
+	const keySpace = 100
+	largeValue := func(key string, rng *rand.Rand) []byte {
+		return []byte(key + "|" + strings.Repeat("x", rng.IntN(64<<10)))
+	}
+	var stats stressStats
+
+	runWorkers(t, stressWorkers(), stressDuration(), func(t *testing.T, workerID int, rng *rand.Rand) {
+		key := fmt.Sprintf("stress:bandwidth:%d", rng.IntN(keySpace))
+		stats.ops.Add(1)
+
+		if rng.IntN(4) == 0 {
+			if err := client.Set(ctx, memcache.Item{Key: key, Value: largeValue(key, rng), TTL: memcache.ExpiresIn(time.Minute)}); err != nil {
+				stats.errors.Add(1)
+			}
+			return
+		}
+		req := meta.NewRequest(meta.CmdGet, key, nil).AddReturnValue().AddReturnSize()
+		resp, err := client.Execute(ctx, req)
+		if err != nil {
+			stats.errors.Add(1)
+			return
+		}
+		if resp.Status == meta.StatusVA {
+			checkValue(t, key, resp.Data)
+			size, _ := resp.GetFlagUint64(meta.FlagReturnSize)
+			assert.Len(t, resp.Data, int(size), "value of key %q must arrive whole", key)
+		}
+	})
+
+	stats.report(t)
+	require.Greater(t, stats.ops.Load(), int64(100), "the workload must actually run")
+	assert.Zero(t, stats.errors.Load(), "a bandwidth cap within the timeout must not cause errors")
+}
+
+// TestStress_SlowLoris trickles the responses in small slices with a delay
+// between them. A trickle within the timeout must be reassembled correctly.
+// A trickle beyond it must fail within the timeout: the deadline of an
+// operation is not extended by the bytes that trickle in, so a slow-loris
+// server can't hold a caller.
+func TestStress_SlowLoris(t *testing.T) {
+	proxy := newToxiproxy(t, stressServer())
+
+	const opTimeout = time.Second
+	client := memcache.NewClient(memcache.StaticServers(proxy.Listen), memcache.Config{
+		MaxSize: 8,
+		Timeout: opTimeout,
+	})
+	t.Cleanup(client.Close)
+	ctx := context.Background()
+
+	// Slices of 8 to 24 bytes, 1ms apart: a value takes up to ~40ms.
+	setToxic(t, proxy, "slicer", `{"average_size":16,"size_variation":8,"delay":1000}`)
+
+	const keySpace = 100
+	var stats stressStats
+
+	runWorkers(t, stressWorkers(), stressDuration(), func(t *testing.T, workerID int, rng *rand.Rand) {
+		key := fmt.Sprintf("stress:slowloris:%d", rng.IntN(keySpace))
+		stats.ops.Add(1)
+
+		switch rng.IntN(3) {
+		case 0:
+			if err := client.Set(ctx, memcache.Item{Key: key, Value: stressValue(key, rng), TTL: memcache.ExpiresIn(time.Minute)}); err != nil {
+				stats.errors.Add(1)
+			}
+		case 1:
+			item, err := client.Get(ctx, key)
+			if err != nil {
+				stats.errors.Add(1)
+				return
+			}
+			if item.Found {
+				checkValue(t, key, item.Value)
+			}
+		case 2:
+			keys := make([]string, 1+rng.IntN(10))
+			for i := range keys {
+				keys[i] = fmt.Sprintf("stress:slowloris:%d", rng.IntN(keySpace))
+			}
+			items, err := memcache.NewBatchCommands(client).MultiGet(ctx, keys)
+			if err != nil {
+				stats.errors.Add(1)
+				return
+			}
+			for i, item := range items {
+				if item.Found {
+					checkValue(t, keys[i], item.Value)
+				}
+			}
+		}
+	})
+
+	stats.report(t)
+	require.Greater(t, stats.ops.Load(), int64(100), "the workload must actually run")
+	assert.Zero(t, stats.errors.Load(), "a trickle within the timeout must not cause errors")
+
+	// A 32KB value trickled 16 bytes per millisecond takes ~2s.
+	key := "stress:slowloris:large"
+	value := []byte(key + "|" + strings.Repeat("x", 32<<10))
+	direct := memcache.NewClient(memcache.StaticServers(stressServer()), memcache.Config{MaxSize: 1, Timeout: time.Second})
+	t.Cleanup(direct.Close)
+	require.NoError(t, direct.Set(ctx, memcache.Item{Key: key, Value: value, TTL: memcache.ExpiresIn(time.Minute)}))
+
+	start := time.Now()
+	_, err := client.Get(ctx, key)
+	elapsed := time.Since(start)
+	require.Error(t, err, "a trickle beyond the timeout must fail")
+	assert.Less(t, elapsed, 2*opTimeout, "the trickle must not extend the deadline (took %s)", elapsed)
+
+	// The rest of the trickled value must not leak into the next operation.
+	small := "stress:slowloris:small"
+	require.NoError(t, direct.Set(ctx, memcache.Item{Key: small, Value: []byte(small + "|v"), TTL: memcache.ExpiresIn(time.Minute)}))
+	item, err := client.Get(ctx, small)
+	require.NoError(t, err)
+	require.True(t, item.Found)
+	assert.Equal(t, small+"|v", string(item.Value))
+}
+
+// TestStress_LimitData closes every connection after a few kilobytes of
+// responses, cutting a response anywhere: in its header, in its value, or
+// between the responses of a batch. The operations caught by a cut fail;
+// the others must get whole, correct data.
+func TestStress_LimitData(t *testing.T) {
+	proxy := newToxiproxy(t, stressServer())
+	setToxic(t, proxy, "limit_data", `{"bytes":4000}`)
+
+	client := memcache.NewClient(memcache.StaticServers(proxy.Listen), memcache.Config{
+		MaxSize:        4,
+		Timeout:        500 * time.Millisecond,
+		ConnectTimeout: time.Second,
+	})
+	t.Cleanup(client.Close)
+	ctx := context.Background()
+
+	const keySpace = 100
+	var stats stressStats
+
+	runWorkers(t, stressWorkers(), stressDuration(), func(t *testing.T, workerID int, rng *rand.Rand) {
+		key := fmt.Sprintf("stress:limitdata:%d", rng.IntN(keySpace))
+		stats.ops.Add(1)
+
+		switch rng.IntN(3) {
+		case 0:
+			if err := client.Set(ctx, memcache.Item{Key: key, Value: stressValue(key, rng), TTL: memcache.ExpiresIn(time.Minute)}); err != nil {
+				stats.errors.Add(1)
+			}
+		case 1:
+			item, err := client.Get(ctx, key)
+			if err != nil {
+				stats.errors.Add(1)
+				return
+			}
+			if item.Found {
+				checkValue(t, key, item.Value)
+			}
+		case 2:
+			keys := make([]string, 1+rng.IntN(10))
+			for i := range keys {
+				keys[i] = fmt.Sprintf("stress:limitdata:%d", rng.IntN(keySpace))
+			}
+			items, err := memcache.NewBatchCommands(client).MultiGet(ctx, keys)
+			if err != nil {
+				stats.errors.Add(1)
+				return
+			}
+			for i, item := range items {
+				if item.Found {
+					checkValue(t, keys[i], item.Value)
+				}
+			}
+		}
+	})
+
+	stats.report(t)
+	require.Greater(t, stats.ops.Load(), int64(100), "the workload must actually run")
+	assert.Positive(t, stats.errors.Load(), "the cuts must actually fail operations")
+
+	removeToxic(t, proxy, "limit_data")
+	assert.Eventually(t, func() bool {
+		key := "stress:limitdata:recovery"
+		if err := client.Set(ctx, memcache.Item{Key: key, Value: []byte(key + "|done")}); err != nil {
+			return false
+		}
+		item, err := client.Get(ctx, key)
+		return err == nil && item.Found
+	}, 5*time.Second, 100*time.Millisecond, "client must recover once the connections are no longer cut")
+}
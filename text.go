@@ -0,0 +1,467 @@
+package memcache
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pior/memcache/meta"
+)
+
+// Protocol is the protocol dialect spoken with the servers (Config.Protocol).
+type Protocol int
+
+const (
+	// ProtocolMeta is the meta protocol of memcached 1.6+.
+	ProtocolMeta Protocol = iota
+
+	// ProtocolText is the legacy text protocol (get, gets, set, add,
+	// replace, append, prepend, cas, delete, incr, decr), for the servers
+	// and proxies without the meta commands. The meta requests of the client
+	// are translated, and the responses translated back: the Client API is
+	// the same. The meta flags without a text equivalent (vivify, recache,
+	// touch on get, TTL remaining, invalidate, base64 keys, ...) fail with
+	// meta.InvalidRequestError before anything is sent. The pipelined mode,
+	// UDP, the me command, VerifyOpaque and VerifyBatchResponses are not
+	// available: the text responses carry no opaque token to verify.
+	ProtocolText
+)
+
+// errTextUnsupportedMode rejects the text protocol with the pipelined mode or
+// UDP, which send meta requests, and with the opaque verifications, which
+// the text responses can't support.
+var errTextUnsupportedMode = errors.New("memcache: the text protocol is not supported in pipelined mode, with UDP nor with the response verifications")
+
+// executeText is Execute with the text protocol.
+func (c *Connection) executeText(req *meta.Request) (*meta.Response, error) {
+	if req.Command == meta.CmdArithmetic && (req.HasFlag(meta.FlagVivify) || req.HasFlag(meta.FlagTTL)) {
+		return c.executeTextArithmetic(req)
+	}
+
+	buf, err := appendTextRequest(c.Writer.AvailableBuffer(), req)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := c.Writer.Write(buf); err != nil {
+		return nil, err
+	}
+	if err := c.Writer.Flush(); err != nil {
+		return nil, err
+	}
+	return readTextResponse(c.Reader, req)
+}
+
+// executeTextArithmetic emulates the auto-create (N and J flags) and the TTL
+// update (T flag) of an ma request, which incr and decr lack: a missing
+// counter is created with add, and the TTL is updated with touch. Increment
+// works this way, with extra round trips.
+func (c *Connection) executeTextArithmetic(req *meta.Request) (*meta.Response, error) {
+	vivifyTTL, vivify := req.Flags.Get(meta.FlagVivify)
+	initial, hasInitial := req.Flags.Get(meta.FlagInitialValue)
+	ttl, hasTTL := req.Flags.Get(meta.FlagTTL)
+	if !hasInitial {
+		initial = []byte("0")
+	}
+
+	base := req.Clone()
+	base.Flags.Remove(meta.FlagVivify)
+	base.Flags.Remove(meta.FlagInitialValue)
+	base.Flags.Remove(meta.FlagTTL)
+
+	resp, err := c.executeText(base)
+	if err != nil || resp.Status != meta.StatusNF || !vivify {
+		if err == nil && hasTTL && resp.IsSuccess() {
+			err = c.textTouch(req.Key, ttl)
+		}
+		return resp, err
+	}
+
+	add := meta.NewRequest(meta.CmdSet, req.Key, initial).AddModeAdd()
+	add.Flags.AddTokenBytes(meta.FlagTTL, vivifyTTL)
+	created, err := c.executeText(add)
+	if err != nil || created.Error != nil {
+		return created, err
+	}
+	if created.Status == meta.StatusNS {
+		return c.executeText(base) // created concurrently
+	}
+
+	resp.Status = meta.StatusHD
+	if req.HasFlag(meta.FlagReturnValue) {
+		resp.Status = meta.StatusVA
+		resp.Data = initial
+	}
+	return resp, nil
+}
+
+// textTouch sets the TTL of key.
+func (c *Connection) textTouch(key string, ttl []byte) error {
+	_, _ = c.Writer.WriteString("touch " + key + " " + string(ttl) + meta.CRLF)
+	if err := c.Writer.Flush(); err != nil {
+		return err
+	}
+	line, err := readTextResponseLine(c.Reader)
+	if err != nil {
+		return err
+	}
+	switch line {
+	case "TOUCHED", "NOT_FOUND":
+		return nil
+	case meta.ErrorGeneric:
+		return &meta.GenericError{Message: meta.ErrorGeneric}
+	}
+	if msg, ok := strings.CutPrefix(line, meta.ErrorServerPrefix+" "); ok {
+		return &meta.ServerError{Message: msg}
+	}
+	if msg, ok := strings.CutPrefix(line, meta.ErrorClientPrefix+" "); ok {
+		return &meta.ClientError{Message: msg}
+	}
+	return &meta.ParseError{Message: "unexpected text protocol response: " + line}
+}
+
+// executeTextBatch is ExecuteBatch with the text protocol: the requests are
+// pipelined, and their responses read one by one. The text protocol has no
+// quiet mode: the nominal responses of the quiet requests are dropped.
+func (c *Connection) executeTextBatch(ctx context.Context, reqs []*meta.Request) ([]*meta.Response, error) {
+	// Translate all the requests before writing anything, as ExecuteBatch
+	// validates them.
+	var wire []byte
+	for _, req := range reqs {
+		var err error
+		if wire, err = appendTextRequest(wire, req); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := c.setDeadline(ctx); err != nil {
+		return nil, err
+	}
+	defer c.conn.SetDeadline(time.Time{})
+
+	if _, err := c.Writer.Write(wire); err != nil {
+		return nil, err
+	}
+	if err := c.Writer.Flush(); err != nil {
+		return nil, err
+	}
+
+	responses := make([]*meta.Response, 0, len(reqs))
+	for _, req := range reqs {
+		if _, err := c.setDeadline(ctx); err != nil {
+			return responses, err
+		}
+		resp, err := readTextResponse(c.Reader, req)
+		if err != nil {
+			return responses, err
+		}
+		if req.HasFlag(meta.FlagQuiet) && isQuietResponse(req, resp) {
+			continue
+		}
+		responses = append(responses, resp)
+	}
+	return responses, nil
+}
+
+// isQuietResponse reports whether the quiet flag suppresses resp: the misses
+// of mg, the successes of the other commands.
+func isQuietResponse(req *meta.Request, resp *meta.Response) bool {
+	if req.Command == meta.CmdGet {
+		return resp.Status == meta.StatusEN
+	}
+	return resp.Status == meta.StatusHD
+}
+
+// errTextUnsupported returns the error of a request without text protocol
+// equivalent.
+func errTextUnsupported(req *meta.Request, flag meta.FlagType) error {
+	return &meta.InvalidRequestError{Command: req.Command, Flag: flag, Message: "not supported by the text protocol"}
+}
+
+// appendTextRequest appends the text protocol command of req to dst.
+func appendTextRequest(dst []byte, req *meta.Request) ([]byte, error) {
+	if req.Command != meta.CmdNoOp {
+		if req.HasFlag(meta.FlagBase64Key) {
+			return dst, errTextUnsupported(req, meta.FlagBase64Key)
+		}
+		if err := meta.ValidateKey(req.Key, false); err != nil {
+			return dst, err
+		}
+	}
+
+	switch req.Command {
+	case meta.CmdGet:
+		cmd := "get"
+		for field := range bytes.FieldsSeq(req.Flags) {
+			switch flag := meta.FlagType(field[0]); flag {
+			case meta.FlagReturnCAS:
+				cmd = "gets"
+			case meta.FlagReturnValue, meta.FlagReturnClientFlags, meta.FlagReturnSize,
+				meta.FlagReturnKey, meta.FlagOpaque, meta.FlagQuiet:
+			default:
+				return dst, errTextUnsupported(req, flag)
+			}
+		}
+		dst = append(dst, cmd...)
+		dst = append(dst, ' ')
+		dst = append(dst, req.Key...)
+
+	case meta.CmdSet:
+		cmd, clientFlags, exptime, cas := "set", []byte("0"), []byte("0"), []byte(nil)
+		for field := range bytes.FieldsSeq(req.Flags) {
+			switch flag, token := meta.FlagType(field[0]), field[1:]; flag {
+			case meta.FlagTTL:
+				exptime = token
+			case meta.FlagClientFlags:
+				clientFlags = token
+			case meta.FlagCAS:
+				cas = token
+			case meta.FlagMode:
+				switch string(token) {
+				case meta.ModeSet:
+					cmd = "set"
+				case meta.ModeAdd:
+					cmd = "add"
+				case meta.ModeReplace:
+					cmd = "replace"
+				case meta.ModeAppend:
+					cmd = "append"
+				case meta.ModePrepend:
+					cmd = "prepend"
+				default:
+					return dst, errTextUnsupported(req, flag)
+				}
+			case meta.FlagReturnKey, meta.FlagOpaque, meta.FlagQuiet:
+			default:
+				return dst, errTextUnsupported(req, flag)
+			}
+		}
+		if cas != nil {
+			if cmd != "set" {
+				return dst, errTextUnsupported(req, meta.FlagCAS)
+			}
+			cmd = "cas"
+		}
+		dst = append(dst, cmd...)
+		dst = append(dst, ' ')
+		dst = append(dst, req.Key...)
+		dst = append(dst, ' ')
+		dst = append(dst, clientFlags...)
+		dst = append(dst, ' ')
+		dst = append(dst, exptime...)
+		dst = append(dst, ' ')
+		dst = strconv.AppendInt(dst, int64(len(req.Data)), 10)
+		if cas != nil {
+			dst = append(dst, ' ')
+			dst = append(dst, cas...)
+		}
+		dst = append(dst, meta.CRLF...)
+		dst = append(dst, req.Data...)
+
+	case meta.CmdDelete:
+		for field := range bytes.FieldsSeq(req.Flags) {
+			switch flag := meta.FlagType(field[0]); flag {
+			case meta.FlagReturnKey, meta.FlagOpaque, meta.FlagQuiet:
+			default:
+				return dst, errTextUnsupported(req, flag)
+			}
+		}
+		dst = append(dst, "delete "...)
+		dst = append(dst, req.Key...)
+
+	case meta.CmdArithmetic:
+		cmd, delta := "incr", []byte("1")
+		for field := range bytes.FieldsSeq(req.Flags) {
+			switch flag, token := meta.FlagType(field[0]), field[1:]; flag {
+			case meta.FlagMode:
+				switch string(token) {
+				case meta.ModeIncrement, meta.ModeIncrementAlt:
+					cmd = "incr"
+				case meta.ModeDecrement, meta.ModeDecrementAlt:
+					cmd = "decr"
+				default:
+					return dst, errTextUnsupported(req, flag)
+				}
+			case meta.FlagDelta:
+				delta = token
+			case meta.FlagReturnValue, meta.FlagReturnKey, meta.FlagOpaque, meta.FlagQuiet:
+			default:
+				return dst, errTextUnsupported(req, flag)
+			}
+		}
+		dst = append(dst, cmd...)
+		dst = append(dst, ' ')
+		dst = append(dst, req.Key...)
+		dst = append(dst, ' ')
+		dst = append(dst, delta...)
+
+	case meta.CmdNoOp:
+		dst = append(dst, "version"...) // the cheapest command with a response
+
+	default:
+		return dst, errTextUnsupported(req, 0)
+	}
+
+	return append(dst, meta.CRLF...), nil
+}
+
+// readTextResponse reads the text protocol response of req, translated to the
+// meta response the server would have returned. The k and O flags of req are
+// echoed.
+func readTextResponse(r *bufio.Reader, req *meta.Request) (*meta.Response, error) {
+	line, err := readTextResponseLine(r)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &meta.Response{}
+	switch {
+	case line == meta.ErrorGeneric:
+		resp.Error = &meta.GenericError{Message: meta.ErrorGeneric}
+		return resp, nil
+	case strings.HasPrefix(line, meta.ErrorClientPrefix+" "):
+		resp.Error = &meta.ClientError{Message: line[len(meta.ErrorClientPrefix)+1:]}
+		return resp, nil
+	case strings.HasPrefix(line, meta.ErrorServerPrefix+" "):
+		resp.Error = &meta.ServerError{Message: line[len(meta.ErrorServerPrefix)+1:]}
+		return resp, nil
+	}
+
+	switch req.Command {
+	case meta.CmdGet:
+		if line == "END" {
+			resp.Status = meta.StatusEN
+			break
+		}
+		return readTextValue(r, req, line)
+
+	case meta.CmdSet:
+		switch line {
+		case "STORED":
+			resp.Status = meta.StatusHD
+		case "NOT_STORED":
+			resp.Status = meta.StatusNS
+		case "EXISTS":
+			resp.Status = meta.StatusEX
+		case "NOT_FOUND":
+			resp.Status = meta.StatusNF
+		}
+
+	case meta.CmdDelete:
+		switch line {
+		case "DELETED":
+			resp.Status = meta.StatusHD
+		case "NOT_FOUND":
+			resp.Status = meta.StatusNF
+		}
+
+	case meta.CmdArithmetic:
+		if line == "NOT_FOUND" {
+			resp.Status = meta.StatusNF
+			break
+		}
+		if _, err := strconv.ParseUint(line, 10, 64); err != nil {
+			break
+		}
+		resp.Status = meta.StatusHD
+		if req.HasFlag(meta.FlagReturnValue) {
+			resp.Status = meta.StatusVA
+			resp.Data = []byte(line)
+		}
+
+	case meta.CmdNoOp:
+		if strings.HasPrefix(line, "VERSION ") {
+			resp.Status = meta.StatusMN
+		}
+	}
+
+	if resp.Status == "" {
+		return nil, &meta.ParseError{Message: "unexpected text protocol response: " + line}
+	}
+	if resp.Status != meta.StatusMN {
+		echoTextFlags(resp, req, nil)
+	}
+	return resp, nil
+}
+
+// readTextValue reads the value of a get hit, announced by line:
+// VALUE <key> <flags> <bytes> [<cas unique>], then the END line.
+func readTextValue(r *bufio.Reader, req *meta.Request, line string) (*meta.Response, error) {
+	fields := strings.Fields(line)
+	if len(fields) < 4 || len(fields) > 5 || fields[0] != "VALUE" {
+		return nil, &meta.ParseError{Message: "unexpected text protocol response: " + line}
+	}
+	size, err := strconv.Atoi(fields[3])
+	if err != nil || size < 0 || size > meta.MaxDataSize {
+		return nil, &meta.ParseError{Message: "invalid value size: " + line}
+	}
+
+	data := make([]byte, size+len(meta.CRLF))
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, &meta.ParseError{Message: "failed to read data block", Err: err}
+	}
+	if !bytes.HasSuffix(data, []byte(meta.CRLF)) {
+		return nil, &meta.ParseError{Message: "invalid data block terminator"}
+	}
+	end, err := readTextResponseLine(r)
+	if err != nil {
+		return nil, err
+	}
+	if end != "END" {
+		return nil, &meta.ParseError{Message: "unexpected text protocol response: " + end}
+	}
+
+	resp := &meta.Response{Status: meta.StatusHD}
+	if req.HasFlag(meta.FlagReturnValue) {
+		resp.Status = meta.StatusVA
+		resp.Data = data[:size]
+	}
+	echoTextFlags(resp, req, fields)
+	return resp, nil
+}
+
+// echoTextFlags sets the flags of resp requested by req, in the order of req:
+// k and O, and for a get hit, the f, c and s flags from the fields of its
+// VALUE line.
+func echoTextFlags(resp *meta.Response, req *meta.Request, value []string) {
+	for field := range bytes.FieldsSeq(req.Flags) {
+		switch flag := meta.FlagType(field[0]); flag {
+		case meta.FlagReturnKey:
+			resp.Flags.AddTokenString(flag, req.Key)
+		case meta.FlagOpaque:
+			resp.Flags.AddTokenBytes(flag, field[1:])
+		case meta.FlagReturnClientFlags, meta.FlagReturnSize, meta.FlagReturnCAS:
+			if value == nil {
+				continue
+			}
+			switch {
+			case flag == meta.FlagReturnClientFlags:
+				resp.Flags.AddTokenString(flag, value[2])
+			case flag == meta.FlagReturnSize:
+				resp.Flags.AddTokenString(flag, value[3])
+			case len(value) == 5:
+				resp.Flags.AddTokenString(flag, value[4])
+			}
+		}
+	}
+}
+
+// readTextResponseLine reads a response line without its CRLF terminator.
+func readTextResponseLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadSlice('\n')
+	if err != nil {
+		if err == bufio.ErrBufferFull {
+			return "", &meta.ParseError{Message: "response line too long"}
+		}
+		return "", err
+	}
+	trimmed, ok := bytes.CutSuffix(line, []byte(meta.CRLF))
+	if !ok {
+		return "", &meta.ParseError{Message: "response line not terminated by CRLF"}
+	}
+	return string(trimmed), nil
+}
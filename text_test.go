@@ -0,0 +1,169 @@
+package memcache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pior/memcache/internal/testutils"
+	"github.com/pior/memcache/meta"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTextTestClient(t *testing.T, mock *testutils.ConnectionMock) *Client {
+	t.Helper()
+	client := NewClient(StaticServers("localhost:11211"), Config{
+		Dialer:   &mockDialer{conn: mock},
+		Protocol: ProtocolText,
+	})
+	t.Cleanup(client.Close)
+	return client
+}
+
+func TestText_Get(t *testing.T) {
+	mock := testutils.NewConnectionMock("VALUE key 5 2\r\nhi\r\nEND\r\n", "END\r\n")
+	client := newTextTestClient(t, mock)
+
+	item, err := client.Get(context.Background(), "key")
+	require.NoError(t, err)
+	assert.True(t, item.Found)
+	assert.Equal(t, "hi", string(item.Value))
+	assert.Equal(t, uint32(5), item.Flags)
+
+	item, err = client.Get(context.Background(), "missing")
+	require.NoError(t, err)
+	assert.False(t, item.Found)
+
+	assertRequest(t, mock, "get key\r\nget missing\r\n")
+}
+
+func TestText_Set(t *testing.T) {
+	mock := testutils.NewConnectionMock("STORED\r\n", "NOT_STORED\r\n")
+	client := newTextTestClient(t, mock)
+
+	require.NoError(t, client.Set(context.Background(), Item{Key: "key", Value: []byte("v"), TTL: ExpiresIn(60 * time.Second), Flags: 3}))
+	err := client.Add(context.Background(), Item{Key: "key", Value: []byte("v")})
+	require.ErrorIs(t, err, ErrKeyExists)
+
+	assertRequest(t, mock, "set key 3 60 1\r\nv\r\nadd key 0 0 1\r\nv\r\n")
+}
+
+func TestText_Delete(t *testing.T) {
+	mock := testutils.NewConnectionMock("DELETED\r\n")
+	client := newTextTestClient(t, mock)
+
+	require.NoError(t, client.Delete(context.Background(), "key"))
+	assertRequest(t, mock, "delete key\r\n")
+}
+
+func TestText_Increment(t *testing.T) {
+	t.Run("existing", func(t *testing.T) {
+		mock := testutils.NewConnectionMock("15\r\n")
+		client := newTextTestClient(t, mock)
+
+		value, err := client.Increment(context.Background(), "counter", 5, NoTTL)
+		require.NoError(t, err)
+		assert.Equal(t, int64(15), value)
+		assertRequest(t, mock, "incr counter 5\r\n")
+	})
+
+	t.Run("created", func(t *testing.T) {
+		mock := testutils.NewConnectionMock("NOT_FOUND\r\n", "STORED\r\n")
+		client := newTextTestClient(t, mock)
+
+		value, err := client.Increment(context.Background(), "counter", 5, NoTTL)
+		require.NoError(t, err)
+		assert.Equal(t, int64(5), value)
+		assertRequest(t, mock, "incr counter 5\r\nadd counter 0 0 1\r\n5\r\n")
+	})
+
+	t.Run("decrement with TTL", func(t *testing.T) {
+		mock := testutils.NewConnectionMock("3\r\n", "TOUCHED\r\n")
+		client := newTextTestClient(t, mock)
+
+		value, err := client.Increment(context.Background(), "counter", -2, ExpiresIn(60*time.Second))
+		require.NoError(t, err)
+		assert.Equal(t, int64(3), value)
+		assertRequest(t, mock, "decr counter 2\r\ntouch counter 60\r\n")
+	})
+}
+
+func TestText_Batch(t *testing.T) {
+	mock := testutils.NewConnectionMock("VALUE a 0 1\r\nx\r\nEND\r\n", "END\r\n", "VALUE c 0 1\r\nz\r\nEND\r\n")
+	client := newTextTestClient(t, mock)
+
+	items, err := NewBatchCommands(client).MultiGet(context.Background(), []string{"a", "b", "c"})
+	require.NoError(t, err)
+	require.Len(t, items, 3)
+	assert.Equal(t, "x", string(items[0].Value))
+	assert.False(t, items[1].Found)
+	assert.Equal(t, "z", string(items[2].Value))
+	assertRequest(t, mock, "get a\r\nget b\r\nget c\r\n")
+}
+
+func TestText_Translation(t *testing.T) {
+	tests := []struct {
+		req       *meta.Request
+		wire      string
+		response  string
+		want      meta.StatusType
+		wantFlags string
+		wantData  string
+	}{
+		{meta.Get("k").AddReturnCAS().AddReturnKey().AddOpaque("1"), "gets k\r\n", "VALUE k 0 1 99\r\nx\r\nEND\r\n", meta.StatusHD, " c99 kk O1", ""},
+		{meta.Get("k").AddReturnValue().AddReturnSize(), "get k\r\n", "VALUE k 0 1\r\nx\r\nEND\r\n", meta.StatusVA, " s1", "x"},
+		{meta.Get("k").AddOpaque("2"), "get k\r\n", "END\r\n", meta.StatusEN, " O2", ""},
+		{meta.Set("k", []byte("v")).AddCAS(7), "cas k 0 0 1 7\r\nv\r\n", "EXISTS\r\n", meta.StatusEX, "", ""},
+		{meta.Set("k", []byte("v")).AddModeAppend(), "append k 0 0 1\r\nv\r\n", "NOT_STORED\r\n", meta.StatusNS, "", ""},
+		{meta.Delete("k"), "delete k\r\n", "NOT_FOUND\r\n", meta.StatusNF, "", ""},
+		{meta.Arithmetic("k"), "incr k 1\r\n", "2\r\n", meta.StatusHD, "", ""},
+		{meta.NoOp(), "version\r\n", "VERSION 1.4.39\r\n", meta.StatusMN, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.wire, func(t *testing.T) {
+			mock := testutils.NewConnectionMock(tt.response)
+			client := newTextTestClient(t, mock)
+
+			resp, err := client.Execute(context.Background(), tt.req)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, resp.Status)
+			assert.Equal(t, tt.wantFlags, string(resp.Flags))
+			assert.Equal(t, tt.wantData, string(resp.Data))
+			assertRequest(t, mock, tt.wire)
+		})
+	}
+}
+
+func TestText_ErrorResponse(t *testing.T) {
+	mock := testutils.NewConnectionMock("SERVER_ERROR out of memory storing object\r\n")
+	client := newTextTestClient(t, mock)
+
+	err := client.Set(context.Background(), Item{Key: "key", Value: []byte("v")})
+	var serverErr *meta.ServerError
+	require.ErrorAs(t, err, &serverErr)
+	assert.Equal(t, "out of memory storing object", serverErr.Message)
+}
+
+func TestText_Unsupported(t *testing.T) {
+	mock := testutils.NewConnectionMock()
+	client := newTextTestClient(t, mock)
+
+	_, err := client.Execute(context.Background(), meta.Get("key").AddReturnValue().AddReturnTTL())
+	var invalidErr *meta.InvalidRequestError
+	require.ErrorAs(t, err, &invalidErr)
+	assert.Equal(t, meta.FlagReturnTTL, invalidErr.Flag)
+	assertRequest(t, mock, "") // nothing sent
+
+	for _, config := range []Config{
+		{Protocol: ProtocolText, PipelineConns: 1},
+		{Protocol: ProtocolText, VerifyOpaque: true},
+		{Protocol: ProtocolText, VerifyBatchResponses: true},
+	} {
+		unsupported := NewClient(StaticServers("localhost:11211"), config)
+		t.Cleanup(unsupported.Close)
+		_, err = unsupported.Get(context.Background(), "key")
+		require.ErrorIs(t, err, errTextUnsupportedMode)
+	}
+}
@@ -0,0 +1,99 @@
+package tiered
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"github.com/pior/memcache"
+)
+
+// LRU is a Local cache holding up to a number of items, evicting the least
+// recently used one beyond it. It is safe for concurrent use.
+type LRU struct {
+	maxItems int
+	now      func() time.Time
+
+	mu    sync.Mutex
+	order *list.List // of *lruEntry, the most recently used first
+	items map[string]*list.Element
+}
+
+type lruEntry struct {
+	item    memcache.Item
+	expires time.Time // zero for no expiration
+}
+
+var _ Local = (*LRU)(nil)
+
+// NewLRU returns an LRU holding up to maxItems items (at least one).
+func NewLRU(maxItems int) *LRU {
+	return &LRU{
+		maxItems: max(maxItems, 1),
+		now:      time.Now,
+		order:    list.New(),
+		items:    make(map[string]*list.Element),
+	}
+}
+
+// Get returns the item of key, unless it is missing or expired.
+func (l *LRU) Get(key string) (memcache.Item, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	elem, ok := l.items[key]
+	if !ok {
+		return memcache.Item{}, false
+	}
+	entry := elem.Value.(*lruEntry)
+	if !entry.expires.IsZero() && !l.now().Before(entry.expires) {
+		l.remove(elem)
+		return memcache.Item{}, false
+	}
+	l.order.MoveToFront(elem)
+	return entry.item, true
+}
+
+// Set stores an item for ttl. A non-positive ttl never expires.
+func (l *LRU) Set(item memcache.Item, ttl time.Duration) {
+	entry := &lruEntry{item: item}
+	if ttl > 0 {
+		entry.expires = l.now().Add(ttl)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if elem, ok := l.items[item.Key]; ok {
+		elem.Value = entry
+		l.order.MoveToFront(elem)
+		return
+	}
+	l.items[item.Key] = l.order.PushFront(entry)
+	if l.order.Len() > l.maxItems {
+		l.remove(l.order.Back())
+	}
+}
+
+// Delete removes the item of key.
+func (l *LRU) Delete(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if elem, ok := l.items[key]; ok {
+		l.remove(elem)
+	}
+}
+
+// Len returns the number of items, including the expired ones not yet
+// removed.
+func (l *LRU) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.order.Len()
+}
+
+func (l *LRU) remove(elem *list.Element) {
+	l.order.Remove(elem)
+	delete(l.items, elem.Value.(*lruEntry).item.Key)
+}
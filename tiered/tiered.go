@@ -0,0 +1,192 @@
+// Package tiered combines a local in-process cache (L1) with memcached (L2):
+// the hot items are served from memory, without a round trip, while memcached
+// holds the shared copy.
+//
+//	cache, err := tiered.New(client, tiered.Options{
+//		Local:    tiered.NewLRU(10000),
+//		LocalTTL: 10 * time.Second,
+//	})
+//
+//	item, err := cache.Get(ctx, "mykey")
+//
+// The local copies are not coherent across processes: an item changed by
+// another process is stale in L1 for up to LocalTTL. To shorten it, propagate
+// the changes with Options.OnInvalidate (e.g. over a pub/sub channel) and
+// call Cache.Invalidate on the receiving side.
+package tiered
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"time"
+
+	"github.com/pior/memcache"
+)
+
+// Local is the local cache (L1). Implementations must be safe for concurrent
+// use. LRU is the implementation of this package.
+type Local interface {
+	// Get returns the item of key, if present.
+	Get(key string) (memcache.Item, bool)
+	// Set stores an item for ttl.
+	Set(item memcache.Item, ttl time.Duration)
+	// Delete removes the item of key.
+	Delete(key string)
+}
+
+// WritePolicy selects how Set updates the local cache.
+type WritePolicy int
+
+const (
+	// WriteThrough stores the item in memcached, then in the local cache:
+	// the writer reads its own write from memory.
+	WriteThrough WritePolicy = iota
+
+	// WriteAround stores the item in memcached and drops the local copy:
+	// the item is cached locally when it is read, which keeps the items
+	// written but rarely read out of the local cache.
+	WriteAround
+)
+
+// ReadPolicy selects how Get fills the local cache.
+type ReadPolicy int
+
+const (
+	// ReadFill caches locally the items read from memcached.
+	ReadFill ReadPolicy = iota
+
+	// ReadNoFill caches locally only the items written by Set (with
+	// WriteThrough): the reads of the other items always go to memcached.
+	ReadNoFill
+)
+
+// Options configures a Cache.
+type Options struct {
+	// Local is the local cache (L1).
+	// Required.
+	Local Local
+
+	// LocalTTL is how long an item stays in the local cache, bounding how
+	// long a change made by another process is not seen. It should be
+	// shorter than the TTL of the items: the local copies of the items read
+	// from memcached don't expire with them (the items written by Set do).
+	// Default: 1 minute
+	LocalTTL time.Duration
+
+	WritePolicy WritePolicy
+	ReadPolicy  ReadPolicy
+
+	// OnInvalidate is called after Set or Delete changed a key, to propagate
+	// the change to the local caches of the other processes, which call
+	// Cache.Invalidate. If nil, the other processes see the change after
+	// LocalTTL.
+	OnInvalidate func(key string)
+}
+
+// maxRelativeExptime is the largest exptime memcached reads as relative
+// seconds (30 days): the larger ones are unix timestamps.
+const maxRelativeExptime = 30 * 24 * 60 * 60
+
+// Cache is a two-tier cache: a local cache in front of memcached.
+type Cache struct {
+	remote memcache.Querier
+	local  Local
+	opts   Options
+}
+
+// New returns a Cache in front of remote, e.g. a memcache.Client.
+func New(remote memcache.Querier, opts Options) (*Cache, error) {
+	if opts.Local == nil {
+		return nil, errors.New("tiered: a Local cache is required")
+	}
+	if opts.LocalTTL <= 0 {
+		opts.LocalTTL = time.Minute
+	}
+	return &Cache{
+		remote: remote,
+		local:  opts.Local,
+		opts:   opts,
+	}, nil
+}
+
+// Get returns the item of key from the local cache, or from memcached. A miss
+// is not cached locally.
+//
+// The returned Value may be shared with the local cache: it must not be
+// modified.
+func (c *Cache) Get(ctx context.Context, key string, opts ...memcache.CallOption) (memcache.Item, error) {
+	if item, ok := c.local.Get(key); ok {
+		return item, nil
+	}
+
+	item, err := c.remote.Get(ctx, key, opts...)
+	if err != nil || !item.Found {
+		return item, err
+	}
+	if c.opts.ReadPolicy == ReadFill {
+		c.local.Set(item, c.opts.LocalTTL)
+	}
+	return item, nil
+}
+
+// Set stores an item in memcached, and updates the local cache following the
+// write policy. The local copy expires after LocalTTL, or with the item if
+// its TTL is shorter. When memcached fails, the local copy is dropped: it may
+// be stale.
+func (c *Cache) Set(ctx context.Context, item memcache.Item, opts ...memcache.CallOption) error {
+	if err := c.remote.Set(ctx, item, opts...); err != nil {
+		c.local.Delete(item.Key)
+		return err
+	}
+
+	ttl := c.localTTL(item.TTL)
+	if c.opts.WritePolicy == WriteThrough && ttl > 0 {
+		// The caller may reuse its buffer after Set.
+		item.Value = bytes.Clone(item.Value)
+		item.Found = true
+		c.local.Set(item, ttl)
+	} else {
+		c.local.Delete(item.Key)
+	}
+	c.invalidated(item.Key)
+	return nil
+}
+
+// localTTL returns LocalTTL, capped to ttl, the time to live of an item in
+// memcached: the local copy must not outlive it. A non-positive result means
+// the item is already expired.
+func (c *Cache) localTTL(ttl memcache.TTL) time.Duration {
+	exptime := ttl.Expiration()
+	if exptime == 0 {
+		return c.opts.LocalTTL
+	}
+	remaining := time.Duration(exptime) * time.Second
+	if exptime > maxRelativeExptime {
+		remaining = time.Until(time.Unix(int64(exptime), 0))
+	}
+	return min(c.opts.LocalTTL, remaining)
+}
+
+// Delete removes the item of key from memcached and from the local cache.
+func (c *Cache) Delete(ctx context.Context, key string, opts ...memcache.CallOption) error {
+	err := c.remote.Delete(ctx, key, opts...)
+	c.local.Delete(key)
+	if err != nil {
+		return err
+	}
+	c.invalidated(key)
+	return nil
+}
+
+// Invalidate drops the local copy of key, e.g. when another process reported
+// a change with Options.OnInvalidate. It doesn't call OnInvalidate.
+func (c *Cache) Invalidate(key string) {
+	c.local.Delete(key)
+}
+
+func (c *Cache) invalidated(key string) {
+	if c.opts.OnInvalidate != nil {
+		c.opts.OnInvalidate(key)
+	}
+}
@@ -0,0 +1,209 @@
+package tiered
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pior/memcache"
+	"github.com/pior/memcache/memcachetest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestCache(t *testing.T, opts Options) (*Cache, *memcache.Client) {
+	srv := memcachetest.NewServer(t)
+	client := memcache.NewClient(memcache.StaticServers(srv.Addr), memcache.Config{})
+	t.Cleanup(client.Close)
+
+	if opts.Local == nil {
+		opts.Local = NewLRU(100)
+	}
+	cache, err := New(client, opts)
+	require.NoError(t, err)
+	return cache, client
+}
+
+func TestCache_Get(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("fills the local cache", func(t *testing.T) {
+		cache, client := newTestCache(t, Options{})
+		require.NoError(t, client.Set(ctx, memcache.Item{Key: "k1", Value: []byte("v1"), Flags: 3}))
+
+		item, err := cache.Get(ctx, "k1")
+		require.NoError(t, err)
+		assert.Equal(t, memcache.Item{Key: "k1", Value: []byte("v1"), Flags: 3, Found: true}, item)
+
+		// Served locally, until invalidated.
+		require.NoError(t, client.Delete(ctx, "k1"))
+		item, err = cache.Get(ctx, "k1")
+		require.NoError(t, err)
+		assert.True(t, item.Found)
+
+		cache.Invalidate("k1")
+		item, err = cache.Get(ctx, "k1")
+		require.NoError(t, err)
+		assert.False(t, item.Found)
+	})
+
+	t.Run("no fill", func(t *testing.T) {
+		cache, client := newTestCache(t, Options{ReadPolicy: ReadNoFill})
+		require.NoError(t, client.Set(ctx, memcache.Item{Key: "k1", Value: []byte("v1")}))
+
+		_, err := cache.Get(ctx, "k1")
+		require.NoError(t, err)
+		_, ok := cache.local.Get("k1")
+		assert.False(t, ok)
+	})
+
+	t.Run("misses are not cached", func(t *testing.T) {
+		cache, client := newTestCache(t, Options{})
+
+		item, err := cache.Get(ctx, "k1")
+		require.NoError(t, err)
+		assert.False(t, item.Found)
+
+		require.NoError(t, client.Set(ctx, memcache.Item{Key: "k1", Value: []byte("v1")}))
+		item, err = cache.Get(ctx, "k1")
+		require.NoError(t, err)
+		assert.True(t, item.Found)
+	})
+}
+
+// ttlRecorder records the TTL of the last Set.
+type ttlRecorder struct {
+	*LRU
+	ttl time.Duration
+}
+
+func (r *ttlRecorder) Set(item memcache.Item, ttl time.Duration) {
+	r.ttl = ttl
+	r.LRU.Set(item, ttl)
+}
+
+func TestCache_Set(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("write-through", func(t *testing.T) {
+		var invalidated []string
+		cache, client := newTestCache(t, Options{OnInvalidate: func(key string) { invalidated = append(invalidated, key) }})
+
+		require.NoError(t, cache.Set(ctx, memcache.Item{Key: "k1", Value: []byte("v1")}))
+		local, ok := cache.local.Get("k1")
+		require.True(t, ok)
+		assert.Equal(t, "v1", string(local.Value))
+		assert.True(t, local.Found)
+
+		remote, err := client.Get(ctx, "k1")
+		require.NoError(t, err)
+		assert.Equal(t, "v1", string(remote.Value))
+		assert.Equal(t, []string{"k1"}, invalidated)
+	})
+
+	t.Run("write-through copies the value", func(t *testing.T) {
+		cache, _ := newTestCache(t, Options{})
+
+		value := []byte("v1")
+		require.NoError(t, cache.Set(ctx, memcache.Item{Key: "k1", Value: value}))
+		copy(value, "xx")
+
+		local, ok := cache.local.Get("k1")
+		require.True(t, ok)
+		assert.Equal(t, "v1", string(local.Value))
+	})
+
+	t.Run("write-through caps the local TTL", func(t *testing.T) {
+		local := &ttlRecorder{LRU: NewLRU(100)}
+		cache, _ := newTestCache(t, Options{Local: local, LocalTTL: time.Minute})
+
+		require.NoError(t, cache.Set(ctx, memcache.Item{Key: "k1", Value: []byte("v1"), TTL: memcache.ExpiresIn(10 * time.Second)}))
+		assert.Equal(t, 10*time.Second, local.ttl)
+
+		require.NoError(t, cache.Set(ctx, memcache.Item{Key: "k1", Value: []byte("v1"), TTL: memcache.ExpiresIn(time.Hour)}))
+		assert.Equal(t, time.Minute, local.ttl)
+
+		require.NoError(t, cache.Set(ctx, memcache.Item{Key: "k1", Value: []byte("v1")}))
+		assert.Equal(t, time.Minute, local.ttl, "no TTL")
+
+		require.NoError(t, cache.Set(ctx, memcache.Item{Key: "k1", Value: []byte("v1"), TTL: memcache.ExpiresAt(time.Now().Add(-time.Hour))}))
+		_, ok := local.Get("k1")
+		assert.False(t, ok, "an expired item is not cached")
+	})
+
+	t.Run("write-around", func(t *testing.T) {
+		cache, client := newTestCache(t, Options{WritePolicy: WriteAround})
+		cache.local.Set(memcache.Item{Key: "k1", Value: []byte("old"), Found: true}, time.Minute)
+
+		require.NoError(t, cache.Set(ctx, memcache.Item{Key: "k1", Value: []byte("v1")}))
+		_, ok := cache.local.Get("k1")
+		assert.False(t, ok)
+
+		remote, err := client.Get(ctx, "k1")
+		require.NoError(t, err)
+		assert.Equal(t, "v1", string(remote.Value))
+	})
+
+	t.Run("failure drops the local copy", func(t *testing.T) {
+		var invalidated []string
+		cache, client := newTestCache(t, Options{OnInvalidate: func(key string) { invalidated = append(invalidated, key) }})
+		cache.local.Set(memcache.Item{Key: "k1", Value: []byte("old"), Found: true}, time.Minute)
+
+		client.Close()
+		require.ErrorIs(t, cache.Set(ctx, memcache.Item{Key: "k1", Value: []byte("v1")}), memcache.ErrClientClosed)
+		_, ok := cache.local.Get("k1")
+		assert.False(t, ok)
+		assert.Empty(t, invalidated)
+	})
+}
+
+func TestCache_Delete(t *testing.T) {
+	ctx := context.Background()
+	var invalidated []string
+	cache, client := newTestCache(t, Options{OnInvalidate: func(key string) { invalidated = append(invalidated, key) }})
+
+	require.NoError(t, cache.Set(ctx, memcache.Item{Key: "k1", Value: []byte("v1")}))
+	require.NoError(t, cache.Delete(ctx, "k1"))
+
+	_, ok := cache.local.Get("k1")
+	assert.False(t, ok)
+	remote, err := client.Get(ctx, "k1")
+	require.NoError(t, err)
+	assert.False(t, remote.Found)
+	assert.Equal(t, []string{"k1", "k1"}, invalidated)
+}
+
+func TestNew(t *testing.T) {
+	_, err := New(nil, Options{})
+	assert.Error(t, err)
+}
+
+func TestLRU(t *testing.T) {
+	now := time.Now()
+	lru := NewLRU(2)
+	lru.now = func() time.Time { return now }
+
+	lru.Set(memcache.Item{Key: "k1"}, 0)
+	lru.Set(memcache.Item{Key: "k2"}, time.Second)
+	_, ok := lru.Get("k1") // k2 is now the least recently used
+	require.True(t, ok)
+
+	lru.Set(memcache.Item{Key: "k3"}, 0)
+	assert.Equal(t, 2, lru.Len())
+	_, ok = lru.Get("k2")
+	assert.False(t, ok, "k2 should be evicted")
+
+	lru.Set(memcache.Item{Key: "k3", Value: []byte("new")}, time.Second)
+	item, ok := lru.Get("k3")
+	require.True(t, ok)
+	assert.Equal(t, "new", string(item.Value))
+
+	now = now.Add(time.Second)
+	_, ok = lru.Get("k3")
+	assert.False(t, ok, "k3 should be expired")
+	_, ok = lru.Get("k1")
+	assert.True(t, ok, "k1 never expires")
+
+	lru.Delete("k1")
+	assert.Equal(t, 0, lru.Len())
+}
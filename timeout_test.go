@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"os"
 	"strings"
 	"sync"
 	"testing"
@@ -705,6 +706,78 @@ func TestTimeout_BareCancellationDoesNotInterruptOp(t *testing.T) {
 	})
 }
 
+// newStuckWriteDialer returns a dialer of connections whose peer never reads,
+// so writes block as on a partitioned network once the socket buffers are
+// full. The peers are closed when the test ends.
+func newStuckWriteDialer(t *testing.T) Dialer {
+	t.Helper()
+
+	var mu sync.Mutex
+	var peers []net.Conn
+	t.Cleanup(func() {
+		mu.Lock()
+		for _, c := range peers {
+			_ = c.Close()
+		}
+		mu.Unlock()
+	})
+
+	return DialerFunc(func(ctx context.Context, network, address string) (net.Conn, error) {
+		conn, peer := net.Pipe()
+		mu.Lock()
+		peers = append(peers, peer)
+		mu.Unlock()
+		return conn, nil
+	})
+}
+
+// TestTimeout_StuckWriteInterrupted verifies that a write blocked in the
+// kernel (e.g. a large Set during a network partition) is interrupted by the
+// write deadline, in both connection modes, instead of wedging the goroutine.
+func TestTimeout_StuckWriteInterrupted(t *testing.T) {
+	largeValue := make([]byte, 1<<20)
+
+	run := func(t *testing.T, config Config, ctx context.Context) {
+		t.Helper()
+		config.Dialer = newStuckWriteDialer(t)
+		client := NewClient(StaticServers("stuck:11211"), config)
+		t.Cleanup(client.Close)
+
+		done := make(chan error, 1)
+		go func() {
+			done <- client.Set(ctx, Item{Key: "test:stuck", Value: largeValue})
+		}()
+
+		select {
+		case err := <-done:
+			require.Error(t, err)
+			assert.True(t, errors.Is(err, os.ErrDeadlineExceeded), "expected a deadline error, got: %v", err)
+		case <-time.After(5 * time.Second):
+			t.Fatal("a stuck write was not interrupted by its deadline")
+		}
+	}
+
+	t.Run("pooled, Timeout", func(t *testing.T) {
+		run(t, Config{Timeout: 50 * time.Millisecond}, context.Background())
+	})
+
+	t.Run("pooled, context deadline", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		run(t, Config{}, ctx)
+	})
+
+	t.Run("pipelined, Timeout", func(t *testing.T) {
+		run(t, Config{PipelineConns: 1, Timeout: 50 * time.Millisecond}, context.Background())
+	})
+
+	t.Run("pipelined, context deadline", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		run(t, Config{PipelineConns: 1}, ctx)
+	})
+}
+
 func TestStats_UnreachableServer(t *testing.T) {
 	client := NewClient(StaticServers("127.0.0.1:1"), Config{
 		MaxSize: 1,
@@ -0,0 +1,253 @@
+package memcache
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"net"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pior/memcache/meta"
+)
+
+// UDPConfig enables memcached's UDP transport (memcached -U) for the small
+// gets (Config.UDP): a get is sent in a single datagram and its response is
+// awaited for Timeout, saving the connection pool round trip. A get whose
+// response is lost, truncated (larger than a datagram) or an error is sent
+// again over TCP, so UDP only suits the loss-tolerant, latency-critical gets
+// of small values.
+//
+// Only the plain gets go through UDP: not the quiet gets, whose miss has no
+// response, nor the gets with the vivify or recache flags, which must not be
+// applied twice.
+//
+// The datagrams are plaintext, sent with the *net.Dialer of Config.Dialer:
+// UDP is not available with another Dialer (e.g. a *tls.Dialer) nor with
+// Config.ProxyProtocol, and the server pools fail with an error. The UDP
+// responses are matched to their gets by request ID, without the opaque
+// tokens of Config.VerifyOpaque.
+type UDPConfig struct {
+	// Port is the UDP port of the servers.
+	// Default: the port of the server address.
+	Port int
+
+	// Timeout is how long a get waits for its UDP response before falling
+	// back to TCP: a lost datagram costs Timeout on top of the TCP get.
+	// Default: 10ms
+	Timeout time.Duration
+}
+
+const defaultUDPTimeout = 10 * time.Millisecond
+
+// udpHeaderSize is the size of the frame header of memcached's UDP protocol:
+// request ID, sequence number, number of datagrams and a reserved field, 16
+// bits each.
+const udpHeaderSize = 8
+
+// maxUDPDatagramSize is the maximum size of a UDP datagram.
+const maxUDPDatagramSize = 64 << 10
+
+// errUDPUnsupportedDialer rejects UDP with a Dialer or ProxyProtocol that
+// the datagrams would bypass.
+var errUDPUnsupportedDialer = errors.New("memcache: UDP is only supported with a *net.Dialer, without the PROXY protocol")
+
+// errUDPFallback is returned by udpTransport.get when the get must be sent
+// over TCP.
+var errUDPFallback = errors.New("memcache: get must be sent over tcp")
+
+// udpTransport sends the gets of a server over UDP. The responses are read by
+// a goroutine and matched to their gets by request ID.
+type udpTransport struct {
+	addr    string
+	timeout time.Duration
+	dialer  *net.Dialer
+
+	mu      sync.Mutex
+	conn    net.Conn // dialed on first use
+	closed  bool
+	waiters map[uint16]chan []byte
+	nextID  uint16
+
+	gets      atomic.Uint64
+	fallbacks atomic.Uint64
+}
+
+// newUDPTransport returns the UDP transport of the server addr, dialing with
+// the *net.Dialer of config.
+func newUDPTransport(addr string, config Config) (*udpTransport, error) {
+	dialer, ok := config.Dialer.(*net.Dialer)
+	if !ok || config.ProxyProtocol {
+		return nil, errUDPUnsupportedDialer
+	}
+	udp := *config.UDP
+
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	if udp.Port > 0 {
+		port = strconv.Itoa(udp.Port)
+	}
+	if udp.Timeout <= 0 {
+		udp.Timeout = defaultUDPTimeout
+	}
+	return &udpTransport{
+		addr:    net.JoinHostPort(host, port),
+		timeout: udp.Timeout,
+		dialer:  dialer,
+		waiters: make(map[uint16]chan []byte),
+	}, nil
+}
+
+// isUDPGet reports whether req can be sent over UDP. See UDPConfig.
+func isUDPGet(req *meta.Request) bool {
+	return req.Command == meta.CmdGet &&
+		!req.HasFlag(meta.FlagQuiet) &&
+		!req.HasFlag(meta.FlagVivify) &&
+		!req.HasFlag(meta.FlagRecache)
+}
+
+// get sends req over UDP and returns its response. It returns errUDPFallback,
+// or another error, when the get must be sent over TCP, and the error of ctx
+// when it is done.
+func (u *udpTransport) get(ctx context.Context, req *meta.Request) (*meta.Response, error) {
+	u.gets.Add(1)
+	resp, err := u.roundTrip(ctx, req)
+	if err != nil && ctx.Err() == nil {
+		u.fallbacks.Add(1)
+	}
+	return resp, err
+}
+
+func (u *udpTransport) roundTrip(ctx context.Context, req *meta.Request) (*meta.Response, error) {
+	datagram := make([]byte, udpHeaderSize, udpHeaderSize+64)
+	datagram, err := meta.AppendRequest(datagram, req)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, id, ch, err := u.register(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer u.unregister(id)
+
+	binary.BigEndian.PutUint16(datagram[0:], id)
+	binary.BigEndian.PutUint16(datagram[2:], 0) // sequence number
+	binary.BigEndian.PutUint16(datagram[4:], 1) // number of datagrams
+	if _, err := conn.Write(datagram); err != nil {
+		return nil, err
+	}
+
+	timer := time.NewTimer(u.timeout)
+	defer timer.Stop()
+
+	select {
+	case payload := <-ch:
+		return parseUDPResponse(payload)
+	case <-timer.C:
+		return nil, errUDPFallback
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// register allocates a request ID and the channel receiving its response,
+// dialing the socket if needed.
+func (u *udpTransport) register(ctx context.Context) (net.Conn, uint16, chan []byte, error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if u.closed {
+		return nil, 0, nil, ErrPoolClosed
+	}
+	if u.conn == nil {
+		conn, err := u.dialer.DialContext(ctx, "udp", u.addr)
+		if err != nil {
+			return nil, 0, nil, err
+		}
+		u.conn = conn
+		go u.readLoop(conn)
+	}
+	if len(u.waiters) > 1<<16-1 {
+		return nil, 0, nil, errUDPFallback // every request ID is in use
+	}
+
+	for {
+		u.nextID++
+		if _, used := u.waiters[u.nextID]; !used {
+			break
+		}
+	}
+	ch := make(chan []byte, 1)
+	u.waiters[u.nextID] = ch
+	return u.conn, u.nextID, ch, nil
+}
+
+func (u *udpTransport) unregister(id uint16) {
+	u.mu.Lock()
+	delete(u.waiters, id)
+	u.mu.Unlock()
+}
+
+// readLoop delivers the responses received on conn until it is closed. The
+// payload of a response spanning several datagrams is delivered as nil: the
+// get falls back to TCP.
+func (u *udpTransport) readLoop(conn net.Conn) {
+	buf := make([]byte, maxUDPDatagramSize)
+	for {
+		n, err := conn.Read(buf)
+		if errors.Is(err, net.ErrClosed) {
+			return
+		}
+		if err != nil || n < udpHeaderSize {
+			continue // e.g. the ICMP error of an unreachable port
+		}
+
+		id := binary.BigEndian.Uint16(buf[0:])
+		count := binary.BigEndian.Uint16(buf[4:])
+
+		u.mu.Lock()
+		ch := u.waiters[id]
+		delete(u.waiters, id)
+		u.mu.Unlock()
+		if ch == nil {
+			continue // late or duplicate
+		}
+
+		var payload []byte
+		if count == 1 {
+			payload = bytes.Clone(buf[udpHeaderSize:n])
+		}
+		ch <- payload
+	}
+}
+
+// parseUDPResponse parses the payload of a single-datagram response.
+func parseUDPResponse(payload []byte) (*meta.Response, error) {
+	if payload == nil {
+		return nil, errUDPFallback
+	}
+
+	var p meta.Parser
+	responses, n, err := p.Feed(payload)
+	if err != nil || len(responses) != 1 || n != len(payload) || responses[0].Error != nil {
+		return nil, errUDPFallback
+	}
+	resp := responses[0]
+	return &resp, nil
+}
+
+func (u *udpTransport) close() {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	u.closed = true
+	if u.conn != nil {
+		_ = u.conn.Close()
+	}
+}
@@ -0,0 +1,193 @@
+package memcache
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pior/memcache/internal/testutils"
+	"github.com/pior/memcache/meta"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newUDPServer starts a fake memcached UDP server answering each get with the
+// datagrams returned by answer for its request line (without the frame
+// header), one datagram per payload. Returns its port.
+func newUDPServer(t *testing.T, answer func(line string) []string) int {
+	t.Helper()
+
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = pc.Close() })
+
+	go func() {
+		buf := make([]byte, maxUDPDatagramSize)
+		for {
+			n, addr, err := pc.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			if n < udpHeaderSize {
+				continue
+			}
+			id := binary.BigEndian.Uint16(buf[0:])
+			payloads := answer(string(buf[udpHeaderSize:n]))
+			for seq, payload := range payloads {
+				datagram := make([]byte, udpHeaderSize, udpHeaderSize+len(payload))
+				binary.BigEndian.PutUint16(datagram[0:], id)
+				binary.BigEndian.PutUint16(datagram[2:], uint16(seq))
+				binary.BigEndian.PutUint16(datagram[4:], uint16(len(payloads)))
+				datagram = append(datagram, payload...)
+				_, _ = pc.WriteTo(datagram, addr)
+			}
+		}
+	}()
+
+	return pc.LocalAddr().(*net.UDPAddr).Port
+}
+
+// tcpServer is a fake memcached TCP server answering each read of a
+// connection with the next of its responses, recording the requests.
+type tcpServer struct {
+	addr string
+
+	mu       sync.Mutex
+	requests strings.Builder
+}
+
+func newTCPServer(t *testing.T, responses ...string) *tcpServer {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = ln.Close() })
+
+	srv := &tcpServer{addr: ln.Addr().String()}
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		buf := make([]byte, 4096)
+		for _, resp := range responses {
+			n, err := conn.Read(buf)
+			if err != nil {
+				return
+			}
+			srv.mu.Lock()
+			srv.requests.Write(buf[:n])
+			srv.mu.Unlock()
+			if _, err := conn.Write([]byte(resp)); err != nil {
+				return
+			}
+		}
+	}()
+	return srv
+}
+
+// written returns the requests received so far.
+func (s *tcpServer) written() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests.String()
+}
+
+func newUDPClient(t *testing.T, port int, srv *tcpServer) *Client {
+	t.Helper()
+	client := NewClient(StaticServers(srv.addr), Config{
+		UDP: &UDPConfig{Port: port, Timeout: 50 * time.Millisecond},
+	})
+	t.Cleanup(client.Close)
+	return client
+}
+
+func TestUDP_Get(t *testing.T) {
+	port := newUDPServer(t, func(line string) []string {
+		assert.Equal(t, "mg key v f\r\n", line)
+		return []string{"VA 2 f0\r\nhi\r\n"}
+	})
+	srv := newTCPServer(t)
+	client := newUDPClient(t, port, srv)
+
+	item, err := client.Get(context.Background(), "key")
+	require.NoError(t, err)
+	assert.Equal(t, "hi", string(item.Value))
+	assert.Empty(t, srv.written(), "nothing sent over TCP")
+
+	metrics := client.PoolMetrics()[0]
+	assert.Equal(t, uint64(1), metrics.UDPGets)
+	assert.Equal(t, uint64(0), metrics.UDPFallbacks)
+}
+
+func TestUDP_FallbackToTCP(t *testing.T) {
+	tests := []struct {
+		name   string
+		answer []string
+	}{
+		{"truncated", []string{"VA 2 f0\r\n", "hi\r\n"}},
+		{"lost", nil},
+		{"error", []string{"SERVER_ERROR out of memory\r\n"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			port := newUDPServer(t, func(string) []string { return tt.answer })
+			srv := newTCPServer(t, "VA 3 f0\r\ntcp\r\n")
+			client := newUDPClient(t, port, srv)
+
+			item, err := client.Get(context.Background(), "key")
+			require.NoError(t, err)
+			assert.Equal(t, "tcp", string(item.Value))
+			assert.Equal(t, "mg key v f\r\n", srv.written())
+
+			metrics := client.PoolMetrics()[0]
+			assert.Equal(t, uint64(1), metrics.UDPGets)
+			assert.Equal(t, uint64(1), metrics.UDPFallbacks)
+		})
+	}
+}
+
+func TestUDP_OnlyPlainGets(t *testing.T) {
+	port := newUDPServer(t, func(line string) []string {
+		t.Errorf("unexpected UDP request %q", line)
+		return nil
+	})
+	srv := newTCPServer(t, "HD\r\n", "HD\r\n")
+	client := newUDPClient(t, port, srv)
+
+	require.NoError(t, client.Set(context.Background(), Item{Key: "key", Value: []byte("v")}))
+	_, err := client.Execute(context.Background(), meta.Get("key").AddReturnValue().AddVivify(30))
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(srv.written(), "ms key 1"))
+	assert.Equal(t, uint64(0), client.PoolMetrics()[0].UDPGets)
+}
+
+func TestUDP_UnsupportedDialer(t *testing.T) {
+	tests := []struct {
+		name   string
+		config Config
+	}{
+		{"custom dialer", Config{Dialer: &mockDialer{conn: testutils.NewConnectionMock()}}},
+		{"tls dialer", Config{Dialer: &tls.Dialer{}}},
+		{"proxy protocol", Config{ProxyProtocol: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.config.UDP = &UDPConfig{}
+			client := NewClient(StaticServers("127.0.0.1:11211"), tt.config)
+			t.Cleanup(client.Close)
+
+			_, err := client.Get(context.Background(), "key")
+			require.ErrorIs(t, err, errUDPUnsupportedDialer)
+		})
+	}
+}
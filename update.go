@@ -0,0 +1,124 @@
+package memcache
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"github.com/pior/memcache/meta"
+)
+
+// UpdateOptions configures UpdateWithOptions.
+type UpdateOptions struct {
+	// MaxAttempts bounds the read-modify-write attempts. When the item was
+	// modified concurrently at every attempt, the update fails with
+	// ErrCASConflict.
+	// Default: 10
+	MaxAttempts int
+
+	// Backoff is the wait before the second attempt, doubled at each
+	// following attempt, with a random jitter of up to the same duration.
+	// Default: 1ms
+	Backoff time.Duration
+
+	// TTL, when set, is the TTL of the updated item. NoTTL keeps the
+	// remaining TTL of the item; a created item then never expires.
+	TTL TTL
+}
+
+// Update modifies the value of key with fn, retrying when the item is
+// modified concurrently. It reads the item with its CAS value, calls fn with
+// the value (nil for a missing key) and stores the value returned by fn only
+// if the item didn't change since it was read: a missing key is added, an
+// existing item is stored with its CAS value. On a conflict, it tries again,
+// calling fn with the new value.
+//
+// An error returned by fn aborts the update and is returned as is. fn may be
+// called several times: it must not have side effects.
+func (c *Commands) Update(ctx context.Context, key string, fn func(old []byte) ([]byte, error)) error {
+	return c.UpdateWithOptions(ctx, key, UpdateOptions{}, fn)
+}
+
+// UpdateWithOptions modifies the value of key with fn like Update, with the
+// retries and TTL selected by opts.
+func (c *Commands) UpdateWithOptions(ctx context.Context, key string, opts UpdateOptions, fn func(old []byte) ([]byte, error)) error {
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = 10
+	}
+	if opts.Backoff <= 0 {
+		opts.Backoff = time.Millisecond
+	}
+
+	backoff := opts.Backoff
+	for attempt := 1; ; attempt++ {
+		updated, err := c.tryUpdate(ctx, key, opts.TTL, fn)
+		if err != nil || updated {
+			return err
+		}
+		if attempt == opts.MaxAttempts {
+			return fmt.Errorf("%w: key modified concurrently at each of %d attempts", ErrCASConflict, attempt)
+		}
+
+		timer := time.NewTimer(backoff + rand.N(backoff))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+		backoff *= 2
+	}
+}
+
+// tryUpdate makes a read-modify-write attempt. It returns false when the item
+// was modified concurrently.
+func (c *Commands) tryUpdate(ctx context.Context, key string, ttl TTL, fn func(old []byte) ([]byte, error)) (bool, error) {
+	item, err := c.GetWithOptions(ctx, key, GetOptions{ReturnCAS: true, ReturnTTL: true})
+	if err != nil {
+		return false, err
+	}
+
+	var old []byte
+	if item.Found {
+		old = item.Value
+	}
+	value, err := fn(old)
+	if err != nil {
+		return false, err
+	}
+
+	req := meta.NewRequest(meta.CmdSet, key, value)
+	if item.Found {
+		req.AddCAS(item.CAS)
+	} else {
+		req.AddModeAdd()
+	}
+	if ttl == NoTTL {
+		ttl = item.TTL // the remaining TTL, NoTTL for a created item
+	}
+	if exptime := ttl.Expiration(); exptime != 0 {
+		req.AddTTL(exptime)
+	}
+	if item.Flags != 0 {
+		req.AddClientFlags(item.Flags)
+	}
+
+	resp, err := c.execute(ctx, req, nil)
+	if err != nil {
+		return false, err
+	}
+	if resp.HasError() {
+		return false, resp.Error
+	}
+
+	switch resp.Status {
+	case meta.StatusHD:
+		return true, nil
+	case meta.StatusEX, meta.StatusNF, meta.StatusNS:
+		// Modified (EX), deleted (NF) or created (NS) since the read.
+		return false, nil
+	default:
+		return false, &StatusError{Op: "update", Status: resp.Status}
+	}
+}
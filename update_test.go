@@ -0,0 +1,84 @@
+package memcache_test
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pior/memcache"
+	"github.com/pior/memcache/memcachetest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpdate(t *testing.T) {
+	ctx := context.Background()
+	newClient := func(t *testing.T) *memcache.Client {
+		srv := memcachetest.NewServer(t)
+		client := memcache.NewClient(memcache.StaticServers(srv.Addr), memcache.Config{})
+		t.Cleanup(client.Close)
+		return client
+	}
+	increment := func(old []byte) ([]byte, error) {
+		n, _ := strconv.Atoi(string(old)) // 0 when missing
+		return []byte(strconv.Itoa(n + 1)), nil
+	}
+
+	t.Run("concurrent updates", func(t *testing.T) {
+		client := newClient(t)
+
+		var wg sync.WaitGroup
+		for range 10 {
+			wg.Go(func() {
+				for range 10 {
+					assert.NoError(t, client.UpdateWithOptions(ctx, "counter", memcache.UpdateOptions{MaxAttempts: 1000}, increment))
+				}
+			})
+		}
+		wg.Wait()
+
+		item, err := client.Get(ctx, "counter")
+		require.NoError(t, err)
+		assert.Equal(t, "100", string(item.Value))
+	})
+
+	t.Run("keeps the TTL and flags", func(t *testing.T) {
+		client := newClient(t)
+		require.NoError(t, client.Set(ctx, memcache.Item{Key: "k1", Value: []byte("1"), Flags: 7, TTL: memcache.ExpiresIn(time.Hour)}))
+
+		require.NoError(t, client.Update(ctx, "k1", increment))
+
+		item, err := client.GetWithOptions(ctx, "k1", memcache.GetOptions{ReturnTTL: true})
+		require.NoError(t, err)
+		assert.Equal(t, "2", string(item.Value))
+		assert.Equal(t, uint32(7), item.Flags)
+		assert.Equal(t, memcache.ExpiresIn(time.Hour), item.TTL)
+	})
+
+	t.Run("gives up on conflicts", func(t *testing.T) {
+		client := newClient(t)
+		attempts := 0
+		err := client.UpdateWithOptions(ctx, "k1", memcache.UpdateOptions{MaxAttempts: 3}, func(old []byte) ([]byte, error) {
+			attempts++
+			// Modified by someone else after each read.
+			require.NoError(t, client.Set(ctx, memcache.Item{Key: "k1", Value: []byte("other")}))
+			return []byte("mine"), nil
+		})
+		require.ErrorIs(t, err, memcache.ErrCASConflict)
+		assert.Equal(t, 3, attempts)
+	})
+
+	t.Run("aborted by fn", func(t *testing.T) {
+		client := newClient(t)
+		errAbort := errors.New("abort")
+		err := client.Update(ctx, "k1", func(old []byte) ([]byte, error) { return nil, errAbort })
+		require.ErrorIs(t, err, errAbort)
+
+		item, err := client.Get(ctx, "k1")
+		require.NoError(t, err)
+		assert.False(t, item.Found)
+	})
+}